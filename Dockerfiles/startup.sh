@@ -12,10 +12,18 @@ set -e
 
 # Optional environment variables:
 # - FORGEJO_RUNNER_NAME: Custom runner name (defaults to auto-generated)
+# - FORGEJO_REGISTER_TIMEOUT: Seconds to wait for registration before giving up (defaults to 120)
+# - FORGEJO_IDLE_TIMEOUT: Seconds to wait for a task before stopping the runner (no timeout if unset)
+# - ISTIO_QUIT_ON_EXIT: Set to "true" to stop the Istio envoy sidecar when the runner exits
 
 # Docker socket path (must match the mount path in the pod spec)
 DOCKER_SOCKET="/var/docker/docker.sock"
 
+# In an Istio mesh, the envoy sidecar keeps the pod running after the runner exits; ask it to quit
+if [ "$ISTIO_QUIT_ON_EXIT" = "true" ]; then
+    trap 'curl -fsS -X POST http://127.0.0.1:15020/quitquitquit >/dev/null 2>&1 || true' EXIT
+fi
+
 # Function to check if Docker daemon is accessible
 check_docker_socket() {
     local attempt=$1
@@ -104,32 +112,94 @@ fi
 # Register the runner
 FORGEJO_RUNNER="/usr/local/bin/forgejo-runner"
 
+REGISTER_ARGS=(--no-interactive --instance "$FORGEJO_SERVER" --token "$TOKEN" --name "$RUNNER_NAME")
 if [ -n "$FORGEJO_LABELS" ]; then
-    "$FORGEJO_RUNNER" register \
-        --no-interactive \
-        --instance "$FORGEJO_SERVER" \
-        --token "$TOKEN" \
-        --name "$RUNNER_NAME" \
-        --labels "$FORGEJO_LABELS"
-else
-    "$FORGEJO_RUNNER" register \
-        --no-interactive \
-        --instance "$FORGEJO_SERVER" \
-        --token "$TOKEN" \
-        --name "$RUNNER_NAME"
+    REGISTER_ARGS+=(--labels "$FORGEJO_LABELS")
 fi
 
-REGISTER_EXIT_CODE=$?
+# On failure, write the error to the termination message and exit with 78 so the controller can
+# tell a registration failure apart from a failed job and fail the runner right away
+REGISTER_EXIT_CODE=0
+REGISTER_OUTPUT=$(timeout "${FORGEJO_REGISTER_TIMEOUT:-120}" "$FORGEJO_RUNNER" register "${REGISTER_ARGS[@]}" 2>&1) || REGISTER_EXIT_CODE=$?
+echo "$REGISTER_OUTPUT"
 
 if [ $REGISTER_EXIT_CODE -ne 0 ]; then
     echo "ERROR: Failed to register runner (exit code: $REGISTER_EXIT_CODE)" >&2
-    exit $REGISTER_EXIT_CODE
+    if [ -w /dev/termination-log ]; then
+        printf 'registration failed (exit code %d): %s' "$REGISTER_EXIT_CODE" "$(echo "$REGISTER_OUTPUT" | tail -n 5)" > /dev/termination-log
+    fi
+    exit 78
 fi
 
 echo "✔ Runner registered successfully"
 echo "✔ Runner is ready to execute jobs"
 echo "---------------------------------"
 
+# Fetch only the job this runner was created for when the controller passed a job handle and the
+# runner supports it, so it can't pick up a different queued job with the same labels
+ONE_JOB_ARGS=()
+if [ -n "$FORGEJO_JOB_HANDLE" ]; then
+    if "$FORGEJO_RUNNER" one-job --help 2>&1 | grep -q -- "--handle"; then
+        ONE_JOB_ARGS+=(--handle "$FORGEJO_JOB_HANDLE")
+        echo "  Job handle: $FORGEJO_JOB_HANDLE"
+    else
+        echo "  forgejo-runner doesn't support --handle, taking any job matching the labels"
+    fi
+fi
+
+# Keep the runner output, so the task the runner executed can be recorded in the termination message and the
+# operator can tell when it was not the job the runner was created for
+RUNNER_LOG=$(mktemp)
+
+report_task() {
+    local task
+    task=$(grep -oE "task [0-9]+ repo is [^ ]+" "$RUNNER_LOG" | head -n 1)
+    if [ -n "$task" ] && [ -w /dev/termination-log ]; then
+        printf 'executed %s' "$task" > /dev/termination-log
+    fi
+}
+
+# finish waits for the runner to exit, reports its task and exits with the runner's exit code
+finish() {
+    local exit_code=0
+    wait "$RUNNER_PID" || exit_code=$?
+    # A forwarded signal interrupts wait while the runner is still shutting down
+    while kill -0 "$RUNNER_PID" 2>/dev/null; do
+        exit_code=0
+        wait "$RUNNER_PID" || exit_code=$?
+    done
+    report_task
+    exit $exit_code
+}
+
 # Execute a single job
-exec "$FORGEJO_RUNNER" one-job
+"$FORGEJO_RUNNER" one-job "${ONE_JOB_ARGS[@]}" > >(tee "$RUNNER_LOG") 2>&1 &
+RUNNER_PID=$!
+
+# The runner doesn't replace this script, so pass pod termination on to it
+trap 'kill -TERM "$RUNNER_PID" 2>/dev/null || true' TERM INT
+
+if [ -z "$FORGEJO_IDLE_TIMEOUT" ]; then
+    finish
+fi
+
+# With an idle timeout, watch the runner output for a received task and stop the runner with exit
+# code 75 if none arrives in time, so the controller can tell an idle runner apart from a failed job
+DEADLINE=$(( $(date +%s) + FORGEJO_IDLE_TIMEOUT ))
+while kill -0 "$RUNNER_PID" 2>/dev/null; do
+    if grep -qE "received task|task [0-9]+ repo is" "$RUNNER_LOG"; then
+        finish
+    fi
+    if [ "$(date +%s)" -ge "$DEADLINE" ]; then
+        echo "No task received within ${FORGEJO_IDLE_TIMEOUT}s, stopping runner" >&2
+        kill -TERM "$RUNNER_PID" 2>/dev/null || true
+        wait "$RUNNER_PID" || true
+        if [ -w /dev/termination-log ]; then
+            printf 'idle timeout: no task received within %ss' "$FORGEJO_IDLE_TIMEOUT" > /dev/termination-log
+        fi
+        exit 75
+    fi
+    sleep 2
+done
 
+finish
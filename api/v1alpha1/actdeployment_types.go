@@ -18,6 +18,8 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,28 +27,104 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // ActDeploymentSpec defines the desired state of ActDeployment
+// +kubebuilder:validation:XValidation:rule="(has(self.labels) && size(self.labels) > 0) || (has(self.runnerLabels) && size(self.runnerLabels) > 0)",message="either labels or runnerLabels must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.backend) || self.backend != 'KubeVirt' || has(self.virtualMachine)",message="virtualMachine is required for the KubeVirt backend"
+// +kubebuilder:validation:XValidation:rule="!has(self.defaultResourceProfile) || (has(self.resourceProfiles) && self.resourceProfiles.exists(p, p.name == self.defaultResourceProfile))",message="defaultResourceProfile must name one of the resourceProfiles"
+// +kubebuilder:validation:XValidation:rule="has(self.actOrgRef) || (has(self.forgejoServer) && has(self.organization) && has(self.tokenSecretRef) && has(self.tokenSecretRef.name))",message="forgejoServer, organization and tokenSecretRef are required unless actOrgRef is set"
+// +kubebuilder:validation:XValidation:rule="!has(self.network) || !has(self.network.hostNetwork) || !self.network.hostNetwork || !has(self.mesh) || !has(self.mesh.istio) || !self.mesh.istio",message="network.hostNetwork is not supported with mesh.istio"
+// +kubebuilder:validation:XValidation:rule="!has(self.network) || !has(self.network.hostNetwork) || !self.network.hostNetwork || !has(self.securityProfile) || self.securityProfile != 'sysbox'",message="network.hostNetwork is not supported with the sysbox securityProfile"
 type ActDeploymentSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	// The following markers will use OpenAPI v3 schema to validate the value
 	// More info: https://book.kubebuilder.io/reference/markers/crd-validation.html
 
+	// ActOrgRef references a cluster-scoped ActOrg providing the Forgejo server, organization and token
+	// When set, ForgejoServer, Organization and TokenSecretRef are taken from the ActOrg and the
+	// token is copied into a Secret owned by this ActDeployment
+	// +optional
+	ActOrgRef *corev1.LocalObjectReference `json:"actOrgRef,omitempty"`
+
 	// ForgejoServer is the base URL of the Forgejo server (e.g., "https://git.cloud.danmanners.com")
-	// +kubebuilder:validation:Required
+	// Required unless ActOrgRef is set
 	// +kubebuilder:validation:Pattern=`^https?://`
-	ForgejoServer string `json:"forgejoServer"`
+	// +optional
+	ForgejoServer string `json:"forgejoServer,omitempty"`
 
 	// Organization is the Forgejo organization name to monitor for jobs
-	// +kubebuilder:validation:Required
-	Organization string `json:"organization"`
+	// Required unless ActOrgRef is set
+	// +optional
+	Organization string `json:"organization,omitempty"`
 
 	// Labels is the label filter for jobs (e.g., "docker" or "ubuntu-22.04:docker://node:20-bullseye")
-	// +kubebuilder:validation:Required
-	Labels string `json:"labels"`
+	// Multiple labels are comma-separated. Ignored if RunnerLabels is set
+	// +optional
+	Labels string `json:"labels,omitempty"`
+
+	// ReportUnservedJobs makes this ActDeployment check the queued jobs of its organization against all
+	// ActDeployments in the cluster, and report jobs whose runs-on labels none of them serves
+	// Enable it on one ActDeployment per organization
+	// +optional
+	ReportUnservedJobs bool `json:"reportUnservedJobs,omitempty"`
+
+	// RunnerLabels is the structured form of Labels
+	// Jobs are only picked up if every runs-on entry matches one of these label names
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	RunnerLabels []RunnerLabel `json:"runnerLabels,omitempty"`
+
+	// RegistrationLabels are the labels runners register with, independent of the labels used to filter jobs
+	// Use them to register custom labels (e.g., "self-hosted-large") that jobs don't request
+	// Jobs whose runs-on entries are not all among them are skipped, since the runner couldn't take them
+	// Defaults to the job's runs-on entries, formatted with the RunnerLabels definitions
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	RegistrationLabels []RunnerLabel `json:"registrationLabels,omitempty"`
+
+	// LogArchive uploads the logs of finished runners to S3-compatible object storage
+	// +optional
+	LogArchive *LogArchiveSpec `json:"logArchive,omitempty"`
+
+	// PodRetention controls how long the pods of finished runners are kept, e.g. to inspect failed ones
+	// The workloads of finished runners are deleted along with their ActRunner if not specified
+	// +optional
+	PodRetention *PodRetentionSpec `json:"podRetention,omitempty"`
+
+	// ResourceRecommendations records the peak CPU and memory usage of runner pods (from metrics-server) and
+	// derives suggested requests per runs-on label set in status.resourceRecommendations
+	// +optional
+	ResourceRecommendations *ResourceRecommendationsSpec `json:"resourceRecommendations,omitempty"`
+
+	// ResourceProfiles are named runner sizes that workflows select with a runs-on label (e.g. "size-large")
+	// Profile labels are served in addition to the runner labels
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ResourceProfiles []ResourceProfile `json:"resourceProfiles,omitempty"`
+
+	// DefaultResourceProfile is the profile of jobs that don't select one
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	DefaultResourceProfile string `json:"defaultResourceProfile,omitempty"`
 
 	// TokenSecretRef is a reference to a Secret containing the Forgejo API token
 	// The secret should contain a key named "token" with the API token value
-	TokenSecretRef corev1.SecretReference `json:"tokenSecretRef"`
+	// Required unless ActOrgRef is set
+	// +optional
+	TokenSecretRef corev1.SecretReference `json:"tokenSecretRef,omitempty"`
+
+	// ClientCertSecretRef references a Secret of type kubernetes.io/tls whose tls.crt and tls.key are presented
+	// as client certificate to the Forgejo API (e.g., behind a reverse proxy requiring mutual TLS)
+	// +optional
+	ClientCertSecretRef *corev1.LocalObjectReference `json:"clientCertSecretRef,omitempty"`
+
+	// ExtraAPIHeaders are sent with every Forgejo API request (e.g., CF-Access-Client-Id for authenticating proxies)
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ExtraAPIHeaders []APIHeader `json:"extraAPIHeaders,omitempty"`
 
 	// PollInterval is the interval at which the listener pod polls Forgejo for pending jobs
 	// Defaults to 10s if not specified
@@ -67,6 +145,16 @@ type ActDeploymentSpec struct {
 	// +optional
 	MaxRunners *int32 `json:"maxRunners,omitempty"`
 
+	// Priority ranks the runners of this ActDeployment against those of others when capacity is tight
+	// Runner pods get the PriorityClass forgejo-runner-priority-<priority>, which the operator creates, so the
+	// scheduler places higher-priority runners first; in the same namespace, runners of lower priority also wait
+	// for higher-priority runners to get their workloads, e.g. under a ResourceQuota
+	// A priorityClassName in the runnerTemplate takes precedence
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000000000
+	// +optional
+	Priority *int32 `json:"priority,omitempty"`
+
 	// ListenerTemplate is the pod template for the listener pod that polls Forgejo API
 	// +optional
 	ListenerTemplate corev1.PodTemplateSpec `json:"listenerTemplate,omitempty"`
@@ -80,6 +168,13 @@ type ActDeploymentSpec struct {
 	// +optional
 	RunnerImage string `json:"runnerImage,omitempty"`
 
+	// RunnerImageFrom tracks a tag of the runner image in its registry
+	// The operator resolves the tag to a digest periodically and records it in status.resolvedRunnerImage;
+	// pending runners pick up new digests automatically
+	// Takes precedence over RunnerImage
+	// +optional
+	RunnerImageFrom *RunnerImageSource `json:"runnerImageFrom,omitempty"`
+
 	// DockerInDockerImage is the Docker-in-Docker sidecar image for runner pods
 	// Defaults to "docker.io/library/docker:29.1.3-dind-alpine3.23" if not specified
 	// +optional
@@ -88,8 +183,840 @@ type ActDeploymentSpec struct {
 	// DockerConfigMapRef is an optional reference to a ConfigMap containing Docker config.json
 	// If specified, the config.json will be mounted at ~/.docker/config.json in the runner container
 	// The ConfigMap should contain a key named "config.json" with the Docker configuration
+	// With registryAuth or cloud registries it is the base of the generated Docker config instead
 	// +optional
 	DockerConfigMapRef *corev1.LocalObjectReference `json:"dockerConfigMapRef,omitempty"`
+
+	// RegistryAuth adds the credentials of kubernetes.io/dockerconfigjson Secrets to the runners' Docker config
+	// The operator merges them into a generated <name>-docker-config Secret mounted in the runner and DinD containers;
+	// entries of later Secrets take precedence for the same registry
+	// +optional
+	RegistryAuth []RegistryAuthSpec `json:"registryAuth,omitempty"`
+
+	// DockerConfigMountPath is the directory the Docker config is mounted at in the runner container, for runner
+	// images whose user isn't root; DOCKER_CONFIG is set to it
+	// Defaults to the runnerTemplate's DOCKER_CONFIG, $HOME/.docker of its HOME env var, or /root/.docker
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	DockerConfigMountPath string `json:"dockerConfigMountPath,omitempty"`
+
+	// CloudIdentity runs runner pods under a ServiceAccount bound to a cloud identity, so workflows can push to
+	// cloud registries without static Docker credentials
+	// +optional
+	CloudIdentity *CloudIdentitySpec `json:"cloudIdentity,omitempty"`
+
+	// IdleTimeout stops runners that haven't received a task within this duration after registering
+	// Runners can register after their job was taken by another runner and would otherwise wait forever
+	// The ActRunner gets the IdleTimedOut condition and the listener removes the runner from Forgejo;
+	// no timeout applies if not specified
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+
+	// RunnerPodLabels are added to runner pods (e.g., for policy engine exceptions)
+	// Labels managed by the controller (forgejo.actions.io/*) take precedence
+	// +optional
+	RunnerPodLabels map[string]string `json:"runnerPodLabels,omitempty"`
+
+	// RunnerPodAnnotations are added to runner pods (e.g., sidecar injection opt-out, Prometheus scraping)
+	// Annotations managed by the controller take precedence
+	// +optional
+	RunnerPodAnnotations map[string]string `json:"runnerPodAnnotations,omitempty"`
+
+	// DNS configures name resolution of runner pods (e.g., internal Forgejo hostnames in air-gapped setups)
+	// Values from the RunnerTemplate take precedence
+	// +optional
+	DNS *DNSSpec `json:"dns,omitempty"`
+
+	// AirGapped requires explicit listener, runner and DinD images and rejects images from Docker Hub
+	// The listener is not deployed until all images are explicit
+	// +optional
+	AirGapped *AirGappedSpec `json:"airGapped,omitempty"`
+
+	// SecurityProfile hardens the DinD sidecar of runner pods
+	// sysbox sets runtimeClassName sysbox-runc (unless the RunnerTemplate sets one), drops the privileged flag
+	// and runs dockerd with overlay2, for clusters with Sysbox nodes
+	// The operator config's securityProfile is used if not specified, which defaults to privileged DinD
+	// +optional
+	SecurityProfile SecurityProfile `json:"securityProfile,omitempty"`
+
+	// Mesh configures runner pods for namespaces with service mesh sidecar injection
+	// +optional
+	Mesh *MeshSpec `json:"mesh,omitempty"`
+
+	// Network adjusts the networking of runner pods and their DinD daemon, for clusters where the defaults
+	// conflict with the CNI
+	// +optional
+	Network *RunnerNetworkSpec `json:"network,omitempty"`
+
+	// DinDDaemonConfig is a daemon.json the DinD daemon of runner pods is started with (--config-file), for
+	// settings beyond the structured options, e.g. the cgroup driver, default runtime or features
+	// It must not set the options the operator passes as flags: hosts, storage-driver, registry-mirrors and
+	// the spec.network settings
+	// +optional
+	DinDDaemonConfig *DinDDaemonConfigSource `json:"dindDaemonConfig,omitempty"`
+
+	// ExtraVolumes are added to runner pods, e.g. CA bundles, shared caches or host libraries
+	// Volumes of the RunnerTemplate and the operator with the same name take precedence
+	// +optional
+	ExtraVolumes []corev1.Volume `json:"extraVolumes,omitempty"`
+
+	// ExtraVolumeMounts mount volumes into the runner and DinD containers
+	// Mounts of the RunnerTemplate and the operator at the same path take precedence
+	// +optional
+	ExtraVolumeMounts []ExtraVolumeMount `json:"extraVolumeMounts,omitempty"`
+
+	// WorkspaceInit runs an init container before the runner that pre-populates the work volume, e.g. clones a
+	// mirror, warms caches or fetches large fixtures, so jobs of large repositories start sooner
+	// +optional
+	WorkspaceInit *WorkspaceInitSpec `json:"workspaceInit,omitempty"`
+
+	// PodCompliance enforces and checks cluster admission policies (OPA Gatekeeper, Kyverno) on generated
+	// runner pods, so a non-compliant pod fails the runner with a clear message instead of an admission error
+	// +optional
+	PodCompliance *PodComplianceSpec `json:"podCompliance,omitempty"`
+
+	// Scheduling sets common scheduling fields on runner pods without a full RunnerTemplate
+	// Values from the RunnerTemplate take precedence
+	// +optional
+	Scheduling *SchedulingSpec `json:"scheduling,omitempty"`
+
+	// RetryPolicy bounds how often a runner is replaced when it finished without picking up its job
+	// +optional
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
+	// PendingTimeout fails runners that are still Pending after this duration, e.g. because their pod is
+	// unschedulable or references a missing secret; the workload is deleted and the reason recorded in the
+	// runner's Failed condition. A replacement runner is created according to RetryPolicy
+	// A scheduled pod only counts as stuck while a container can't start (image pull errors, a missing
+	// secret or config, a crashing init container), so long init containers and image pulls don't time out
+	// Pending runners are kept indefinitely if not specified
+	// +optional
+	PendingTimeout *metav1.Duration `json:"pendingTimeout,omitempty"`
+
+	// RegistrationTokenTTL is how long the secret holding a runner's registration token is kept
+	// Expired secrets are deleted once the runner's pod started, or unused, and renewed for runners still waiting for a pod
+	// Defaults to 1h if not specified
+	// +optional
+	RegistrationTokenTTL *metav1.Duration `json:"registrationTokenTTL,omitempty"`
+
+	// TerminationGracePeriodSeconds is the termination grace period of runner pods
+	// When set, the runner and DinD containers get a preStop hook that waits for the job's containers to finish,
+	// up to 10 seconds before the grace period ends, so node drains let the job complete and upload its logs
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// DisruptionProtection configures how runner pods are protected from voluntary disruptions
+	// (cluster-autoscaler scale-down, node drains) while a job is in flight
+	// +optional
+	DisruptionProtection *DisruptionProtectionSpec `json:"disruptionProtection,omitempty"`
+
+	// NodePool schedules runner pods onto a dedicated, autoscaled node pool
+	// The preset adds the nodeSelector, tolerations and extended-resource requests the node
+	// provisioner needs to scale the pool up from zero for pending runner pods
+	// +optional
+	NodePool *NodePoolSpec `json:"nodePool,omitempty"`
+
+	// DedicatedNodes keeps runner pods and other workloads on separate nodes
+	// Runner pods select and tolerate the forgejo.actions.io/dedicated label and taint of the ActDeployment's node
+	// group, which the node provisioner must set; status.dedicatedNodes lists the node configuration
+	// +optional
+	DedicatedNodes *DedicatedNodesSpec `json:"dedicatedNodes,omitempty"`
+
+	// CacheCleanup runs a janitor CronJob that prunes stale act cache and work directories from a
+	// PersistentVolumeClaim shared by the runner pods, so the volume doesn't fill up
+	// +optional
+	CacheCleanup *CacheCleanupSpec `json:"cacheCleanup,omitempty"`
+
+	// JobQueue bounds how many waiting jobs the listener considers per poll and in which order
+	// Without it every waiting job is considered, oldest first
+	// +optional
+	JobQueue *JobQueueSpec `json:"jobQueue,omitempty"`
+
+	// MaintenanceWindows are recurring periods during which the listener creates no new runners
+	// Existing runners keep running and are allowed to drain, which makes scheduled Forgejo or
+	// cluster maintenance possible without killing in-flight jobs
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// SecretAudit checks the listener and runner templates for the values of the token and header Secrets
+	// If a value shows up in labels, annotations, container args or literal env vars, the SecretAuditPassed
+	// condition is False and the listener is not deployed
+	// +optional
+	SecretAudit bool `json:"secretAudit,omitempty"`
+
+	// PollFailureThreshold is the number of consecutive failed listener polls after which the Degraded condition is True
+	// Defaults to 3 if not specified
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PollFailureThreshold *int32 `json:"pollFailureThreshold,omitempty"`
+
+	// Backend selects the workload runners are executed in: a bare Pod, a batch/v1 Job, a KubeVirt VM,
+	// or an external VM (not implemented yet)
+	// Defaults to Pod if not specified
+	// +optional
+	Backend RunnerBackend `json:"backend,omitempty"`
+
+	// VirtualMachine configures the runner VMs of the KubeVirt backend
+	// Only the runnerTemplate's labels, annotations, nodeSelector and tolerations apply to VMs
+	// +optional
+	VirtualMachine *VirtualMachineSpec `json:"virtualMachine,omitempty"`
+
+	// RolloutStrategy controls how runners pick up changes to the runner template, images and other runner settings
+	// OnCompletion only applies changes to runners whose workload doesn't exist yet; existing runners finish with
+	// the configuration they were created with. Immediate also replaces workloads that have not started running yet
+	// Running runners are never interrupted
+	// Defaults to OnCompletion if not specified
+	// +optional
+	RolloutStrategy RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// Metrics exposes the listener's Prometheus metrics through a Service
+	// +optional
+	Metrics *MetricsSpec `json:"metrics,omitempty"`
+
+	// ListenerMode selects where the listener loop runs: embedded in the manager started with --mode=all,
+	// or dedicated in a listener Deployment of its own
+	// Defaults to embedded when the manager runs with --mode=all, dedicated otherwise
+	// +optional
+	ListenerMode ListenerMode `json:"listenerMode,omitempty"`
+
+	// ListenerAutoRestart rolls the listener Deployment when its effective configuration changes,
+	// including the contents of the token Secret (the listener only reads the token at startup)
+	// Defaults to true if not specified
+	// +optional
+	ListenerAutoRestart *bool `json:"listenerAutoRestart,omitempty"`
+
+	// RestartWedgedListener deletes a listener pod whose poll loop stopped renewing its heartbeat Lease,
+	// so the Deployment replaces it; otherwise the ListenerResponsive condition only reports it
+	// Defaults to true if not specified
+	// +optional
+	RestartWedgedListener *bool `json:"restartWedgedListener,omitempty"`
+
+	// ListenerRBAC extends the listener's Role, which only grants what the enabled listener features use
+	// +optional
+	ListenerRBAC *ListenerRBACSpec `json:"listenerRBAC,omitempty"`
+}
+
+// ListenerRBACSpec configures the listener's Role
+type ListenerRBACSpec struct {
+	// AdditionalRules are appended to the generated rules, e.g. for sidecars in the listenerTemplate
+	// The operator can only grant permissions it holds itself
+	// +optional
+	AdditionalRules []rbacv1.PolicyRule `json:"additionalRules,omitempty"`
+}
+
+// RolloutStatus reports the progress of runner configuration changes
+type RolloutStatus struct {
+	// TemplateHash identifies the current runner configuration
+	// ActRunners carry the hash of the configuration they were created from in the
+	// forgejo.actions.io/runner-template-hash annotation
+	// +optional
+	TemplateHash string `json:"templateHash,omitempty"`
+
+	// UpdatedRunners is the number of active runners using the current runner configuration
+	// +optional
+	UpdatedRunners int32 `json:"updatedRunners"`
+
+	// OutdatedRunners is the number of active runners still using a previous runner configuration
+	// +optional
+	OutdatedRunners int32 `json:"outdatedRunners"`
+}
+
+// MaintenanceWindow defines a recurring period during which no new runners are created
+type MaintenanceWindow struct {
+	// Schedule is a standard 5-field cron expression for the start of the window (e.g., "0 2 * * SUN")
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window lasts after each start
+	// +kubebuilder:validation:Required
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the IANA time zone the schedule is evaluated in (e.g., "Europe/Berlin")
+	// Defaults to UTC if not specified
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// NodePoolProvider identifies the node provisioner that manages a node pool
+// +kubebuilder:validation:Enum=karpenter;eks;gke;aks;custom
+type NodePoolProvider string
+
+const (
+	// NodePoolProviderKarpenter selects nodes of a Karpenter NodePool (karpenter.sh/nodepool label)
+	NodePoolProviderKarpenter NodePoolProvider = "karpenter"
+
+	// NodePoolProviderEKS selects nodes of an EKS managed node group (eks.amazonaws.com/nodegroup label)
+	NodePoolProviderEKS NodePoolProvider = "eks"
+
+	// NodePoolProviderGKE selects nodes of a GKE node pool (cloud.google.com/gke-nodepool label)
+	NodePoolProviderGKE NodePoolProvider = "gke"
+
+	// NodePoolProviderAKS selects nodes of an AKS agent pool (kubernetes.azure.com/agentpool label)
+	NodePoolProviderAKS NodePoolProvider = "aks"
+
+	// NodePoolProviderCustom adds no preset labels; only NodeSelector and Tolerations are used
+	NodePoolProviderCustom NodePoolProvider = "custom"
+)
+
+// ListenerMode identifies where the listener loop of an ActDeployment runs
+// +kubebuilder:validation:Enum=embedded;dedicated
+type ListenerMode string
+
+const (
+	// ListenerModeEmbedded runs the listener loop inside the manager; it needs a manager started with --mode=all
+	ListenerModeEmbedded ListenerMode = "embedded"
+
+	// ListenerModeDedicated runs the listener in a Deployment of its own
+	ListenerModeDedicated ListenerMode = "dedicated"
+)
+
+// DNSSpec holds the DNS fields set on runner pods
+// They apply to all containers of the pod, including the DinD sidecar
+// +kubebuilder:validation:XValidation:rule="!has(self.policy) || self.policy != 'None' || (has(self.config) && has(self.config.nameservers) && size(self.config.nameservers) > 0)",message="config.nameservers is required when policy is None"
+type DNSSpec struct {
+	// Policy is the runner pod's dnsPolicy
+	// +kubebuilder:validation:Enum=ClusterFirstWithHostNet;ClusterFirst;Default;None
+	// +optional
+	Policy corev1.DNSPolicy `json:"policy,omitempty"`
+
+	// Config is the runner pod's dnsConfig
+	// +optional
+	Config *corev1.PodDNSConfig `json:"config,omitempty"`
+
+	// HostAliases are added to the /etc/hosts file of the runner pod's containers
+	// +optional
+	HostAliases []corev1.HostAlias `json:"hostAliases,omitempty"`
+}
+
+// APIHeader is an HTTP header added to Forgejo API requests
+// +kubebuilder:validation:XValidation:rule="has(self.value) != has(self.valueFrom)",message="exactly one of value or valueFrom must be set"
+type APIHeader struct {
+	// Name is the header name
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9-]+$`
+	// +required
+	Name string `json:"name"`
+
+	// Value is the literal header value
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// ValueFrom reads the header value from a key of a Secret in the ActDeployment's namespace
+	// +optional
+	ValueFrom *corev1.SecretKeySelector `json:"valueFrom,omitempty"`
+}
+
+// AirGappedSpec configures ActDeployments in clusters without access to public registries
+type AirGappedSpec struct {
+	// RegistryMirrors are passed to the DinD daemon as --registry-mirror, so job images resolve from internal registries
+	// +kubebuilder:validation:items:Pattern=`^https?://[A-Za-z0-9.:/_-]+$`
+	// +optional
+	RegistryMirrors []string `json:"registryMirrors,omitempty"`
+}
+
+// MeshSpec configures service mesh compatibility for runner pods
+type MeshSpec struct {
+	// Istio holds the runner until the envoy sidecar is ready, keeps DinD traffic out of the proxy
+	// and stops the sidecar when the runner exits, so runner pods can complete
+	// +optional
+	Istio bool `json:"istio,omitempty"`
+}
+
+// RunnerNetworkSpec configures the network of runner pods and the DinD daemon's bridge and listeners
+// +kubebuilder:validation:XValidation:rule="!has(self.fixedCIDRv6) || (has(self.ipv6) && self.ipv6)",message="fixedCIDRv6 requires ipv6"
+// +kubebuilder:validation:XValidation:rule="!has(self.hostNetwork) || !self.hostNetwork || !has(self.dindTCPPort)",message="dindTCPPort would expose the DinD daemon on the node with hostNetwork"
+type RunnerNetworkSpec struct {
+	// HostNetwork runs runner pods in the node's network namespace, for CNIs nested bridges don't work with
+	// The DinD daemon then creates its bridges and iptables rules on the node
+	// Runner pods are refused when podCompliance.forbiddenFields has HostNetwork
+	// +optional
+	HostNetwork bool `json:"hostNetwork,omitempty"`
+
+	// IPv6 enables IPv6 on the DinD daemon's networks (--ipv6), so job containers get IPv6 on dual-stack clusters
+	// +optional
+	IPv6 bool `json:"ipv6,omitempty"`
+
+	// FixedCIDRv6 is the IPv6 subnet of the DinD default bridge (--fixed-cidr-v6)
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F:]+/[0-9]{1,3}$`
+	// +optional
+	FixedCIDRv6 string `json:"fixedCIDRv6,omitempty"`
+
+	// BridgeIP is the address and prefix of the DinD default bridge (--bip), e.g. to stay clear of the pod and
+	// service CIDRs
+	// +kubebuilder:validation:Pattern=`^[0-9]{1,3}(\.[0-9]{1,3}){3}/[0-9]{1,2}$`
+	// +optional
+	BridgeIP string `json:"bridgeIP,omitempty"`
+
+	// DefaultAddressPools are the subnets the DinD daemon allocates job networks from (--default-address-pool)
+	// +optional
+	DefaultAddressPools []DinDAddressPool `json:"defaultAddressPools,omitempty"`
+
+	// MTU is the MTU of the DinD default bridge (--mtu); set it to the pod network's MTU on overlay CNIs
+	// +kubebuilder:validation:Minimum=68
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	MTU int32 `json:"mtu,omitempty"`
+
+	// DinDTCPPort makes the DinD daemon also listen on tcp://127.0.0.1:<port> without TLS, for tools in the runner
+	// container that expect a TCP DOCKER_HOST; DOCKER_HOST keeps pointing at the socket
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	DinDTCPPort int32 `json:"dindTCPPort,omitempty"`
+}
+
+// DinDDaemonConfigSource references a daemon.json in a ConfigMap or Secret of the ActDeployment's namespace
+// +kubebuilder:validation:XValidation:rule="has(self.configMapRef) != has(self.secretRef)",message="exactly one of configMapRef or secretRef must be set"
+type DinDDaemonConfigSource struct {
+	// ConfigMapRef is the ConfigMap holding the daemon.json
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+
+	// SecretRef is the Secret holding the daemon.json, for configs with credentials
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// Key is the key of the daemon.json in the ConfigMap or Secret
+	// Defaults to "daemon.json" if not specified
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// ExtraVolumeMount mounts a volume into some of the containers of runner pods
+type ExtraVolumeMount struct {
+	corev1.VolumeMount `json:",inline"`
+
+	// Containers are the containers the volume is mounted in
+	// Defaults to the runner container if not specified
+	// +kubebuilder:validation:items:Enum=runner;dind
+	// +optional
+	Containers []string `json:"containers,omitempty"`
+}
+
+// WorkspaceInitSpec configures the init container that prepares the work volume of runner pods
+type WorkspaceInitSpec struct {
+	// Image is the init container image
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// Command is the entrypoint of the init container
+	// It gets the FORGEJO_JOB_* env vars and metadata files of the job, e.g. to clone its repository
+	// +kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+
+	// Args are the arguments of the command
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Env are additional env vars of the init container
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Resources are the resource requirements of the init container
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// VolumeName is the volume the init container populates; an emptyDir is added if the pod has none of this
+	// name, e.g. from extraVolumes or the RunnerTemplate
+	// Defaults to "workspace" if not specified
+	// +optional
+	VolumeName string `json:"volumeName,omitempty"`
+
+	// MountPath is where the volume is mounted in the init and runner containers
+	// Defaults to "/workspace" if not specified
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// DinDAddressPool is a subnet the DinD daemon splits into job networks
+type DinDAddressPool struct {
+	// Base is the subnet of the pool
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F.:]+/[0-9]{1,3}$`
+	Base string `json:"base"`
+
+	// Size is the prefix length of the networks allocated from the pool
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=128
+	Size int32 `json:"size"`
+}
+
+// SchedulingSpec holds the scheduling fields merged into runner pods
+type SchedulingSpec struct {
+	// NodeSelector is merged into the runner pod's nodeSelector
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are added to the runner pod's tolerations
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity is used if the RunnerTemplate sets no affinity
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints are added to the runner pod's topology spread constraints
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+}
+
+// RetryPolicy configures replacement runners for jobs that are still queued after their runner finished
+type RetryPolicy struct {
+	// MaxRetries is how many replacement runners are created per job
+	// Defaults to 1 if not specified; 0 disables replacements
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+}
+
+// UnservedJob is a queued job whose runs-on labels no ActDeployment serves
+type UnservedJob struct {
+	// ID is the Forgejo job ID
+	ID int64 `json:"id"`
+
+	// Name is the job name
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// RepoID is the ID of the repository the job belongs to
+	// +optional
+	RepoID int64 `json:"repoID,omitempty"`
+
+	// RunsOn are the labels the job requests
+	// +optional
+	RunsOn []string `json:"runsOn,omitempty"`
+
+	// FirstSeen is when the job was first reported
+	FirstSeen metav1.Time `json:"firstSeen"`
+}
+
+// ResourceProfile is a runner size that jobs select with a runs-on label
+type ResourceProfile struct {
+	// Name is the profile name (e.g., "large")
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Label is the runs-on label selecting the profile; defaults to "size-<name>"
+	// +optional
+	Label string `json:"label,omitempty"`
+
+	// Resources replace the requests and limits of the runner container for the resources they list
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector is merged into the runner pod's node selector, overriding keys the template sets
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are added to the runner pod
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// ResourceRecommendationsSpec configures resource recommendations for runner pods
+type ResourceRecommendationsSpec struct {
+	// Apply sets the CPU and memory requests of the runner container of new runners to the recommendation for
+	// their runs-on labels, capped at the container's limits
+	// +optional
+	Apply bool `json:"apply,omitempty"`
+
+	// MinSamples is how many finished runners a recommendation has to be based on before it is applied
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	MinSamples *int32 `json:"minSamples,omitempty"`
+}
+
+// ResourceRecommendation is the suggested runner pod requests for jobs with the same runs-on labels
+type ResourceRecommendation struct {
+	// Labels are the runs-on labels of the jobs, sorted and comma separated
+	Labels string `json:"labels"`
+
+	// CPU is the suggested CPU request
+	CPU resource.Quantity `json:"cpu"`
+
+	// Memory is the suggested memory request
+	Memory resource.Quantity `json:"memory"`
+
+	// Samples is the number of finished runners the recommendation is based on
+	Samples int32 `json:"samples"`
+
+	// UpdatedAt is when a finished runner last updated the recommendation
+	UpdatedAt metav1.Time `json:"updatedAt"`
+}
+
+// RunnerLabelSchema is how the runner executes jobs selecting a label
+// +kubebuilder:validation:Enum=docker;host;lxc
+type RunnerLabelSchema string
+
+const (
+	// RunnerLabelSchemaDocker runs jobs in a container (the default)
+	RunnerLabelSchemaDocker RunnerLabelSchema = "docker"
+
+	// RunnerLabelSchemaHost runs jobs directly in the runner container
+	RunnerLabelSchemaHost RunnerLabelSchema = "host"
+
+	// RunnerLabelSchemaLXC runs jobs in an LXC container
+	RunnerLabelSchemaLXC RunnerLabelSchema = "lxc"
+)
+
+// RunnerLabel is a label the runners register with, and which jobs select with runs-on
+// +kubebuilder:validation:XValidation:rule="!has(self.container) || !has(self.schema) || self.schema != 'host'",message="container is not supported with the host schema"
+type RunnerLabel struct {
+	// Name is the label name jobs select with runs-on (e.g., "ubuntu-22.04")
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[^:,\s]+$`
+	Name string `json:"name"`
+
+	// Schema is how the runner executes jobs with this label
+	// Defaults to "docker" if not specified
+	// +optional
+	Schema RunnerLabelSchema `json:"schema,omitempty"`
+
+	// Container is the default job container image (e.g., "node:20-bullseye") when the job sets none
+	// +optional
+	Container string `json:"container,omitempty"`
+}
+
+// NodePoolSpec describes the node pool runner pods are scheduled onto
+// +kubebuilder:validation:XValidation:rule="!has(self.provider) || self.provider == 'custom' || (has(self.name) && size(self.name) > 0)",message="name is required for the karpenter, eks, gke and aks providers"
+type NodePoolSpec struct {
+	// Provider selects the preset matching the node provisioner
+	// Defaults to "custom" if not specified
+	// +optional
+	Provider NodePoolProvider `json:"provider,omitempty"`
+
+	// Name is the name of the node pool (Karpenter NodePool, EKS node group, GKE node pool or AKS agent pool)
+	// Required for all providers except "custom"
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// NodeSelector is merged with the preset node selector of the provider
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are added to runner pods, e.g. for the taint that keeps other workloads off the pool
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// ExtendedResources are requested (and limited) by the runner container, e.g. nvidia.com/gpu: 1
+	// Autoscalers use these requests to pick a node group able to satisfy the pod when scaling from zero
+	// +optional
+	ExtendedResources corev1.ResourceList `json:"extendedResources,omitempty"`
+
+	// CapacityReservation runs low-priority placeholder pods on the pool to keep warm nodes around
+	// Runner pods preempt the placeholders, which the autoscaler then reschedules onto new nodes
+	// +optional
+	CapacityReservation *CapacityReservationSpec `json:"capacityReservation,omitempty"`
+}
+
+// DedicatedNodesSpec names the node group runner pods have to themselves
+type DedicatedNodesSpec struct {
+	// Group is the value of the forgejo.actions.io/dedicated node label and taint
+	// Defaults to <namespace>.<name> of the ActDeployment, so each ActDeployment scales a node group of its own
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// TaintEffect is the effect of the node group's taint
+	// Defaults to NoSchedule if not specified
+	// +kubebuilder:validation:Enum=NoSchedule;PreferNoSchedule;NoExecute
+	// +optional
+	TaintEffect corev1.TaintEffect `json:"taintEffect,omitempty"`
+}
+
+// DedicatedNodesStatus describes how the nodes of a dedicated node group must be configured
+type DedicatedNodesStatus struct {
+	// NodeLabel is the label the nodes must carry, as key=value
+	NodeLabel string `json:"nodeLabel"`
+
+	// NodeTaint is the taint the nodes must carry, as key=value:effect
+	NodeTaint string `json:"nodeTaint"`
+
+	// AutoscalerTags are the cluster-autoscaler node template tags of a node group that scales from zero
+	// +optional
+	AutoscalerTags map[string]string `json:"autoscalerTags,omitempty"`
+}
+
+// JobOrder is the order in which the listener handles waiting jobs
+// +kubebuilder:validation:Enum=Oldest;RepoFair
+type JobOrder string
+
+const (
+	// JobOrderOldest handles the jobs with the lowest IDs first
+	JobOrderOldest JobOrder = "Oldest"
+
+	// JobOrderRepoFair takes turns between repositories, each oldest first, so a repository with a
+	// large backlog can't starve the others
+	JobOrderRepoFair JobOrder = "RepoFair"
+)
+
+// JobQueueSpec configures how the listener processes large queues of waiting jobs
+type JobQueueSpec struct {
+	// MaxJobsPerPoll is the number of waiting jobs the listener considers per poll; the rest wait for later polls
+	// The waiting jobs are streamed from Forgejo and only the selected ones are kept in memory
+	// 0 means unlimited
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxJobsPerPoll int32 `json:"maxJobsPerPoll,omitempty"`
+
+	// Order decides which jobs make the cut and are handled first
+	// Defaults to Oldest if not specified
+	// +optional
+	Order JobOrder `json:"order,omitempty"`
+}
+
+// CacheCleanupSpec configures the janitor CronJob that prunes a shared cache volume
+type CacheCleanupSpec struct {
+	// ClaimName is the PersistentVolumeClaim holding the act cache and work directories
+	// The janitor mounts it while runners may use it, so it needs an access mode that allows this (e.g. ReadWriteMany)
+	// +kubebuilder:validation:MinLength=1
+	ClaimName string `json:"claimName"`
+
+	// Paths are the directories on the volume whose entries are pruned, relative to its root
+	// Defaults to the root of the volume if not specified
+	// +optional
+	Paths []string `json:"paths,omitempty"`
+
+	// MaxAge is how long an entry may go unmodified before it is deleted
+	// Defaults to 24h if not specified
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+
+	// Schedule is the cron schedule of the janitor
+	// Defaults to "0 * * * *" (hourly) if not specified
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Image is the janitor container image; it needs a shell with find and rm
+	// Defaults to "busybox:1.37" if not specified
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// CapacityReservationSpec configures placeholder pods that pre-warm nodes before bursts of jobs
+type CapacityReservationSpec struct {
+	// Replicas is the number of placeholder pods to run
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas"`
+
+	// Resources are the resource requests of each placeholder pod, usually sized like a runner pod
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// PriorityClassName is the priority class of the placeholder pods
+	// It should have a negative priority so runner pods can preempt the placeholders
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Image is the placeholder container image
+	// Defaults to "registry.k8s.io/pause:3.10" if not specified
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// DisruptionProtectionSpec configures protection of runner pods against voluntary disruptions
+type DisruptionProtectionSpec struct {
+	// SafeToEvict sets the cluster-autoscaler.kubernetes.io/safe-to-evict annotation on runner pods
+	// Set to false to prevent the cluster autoscaler from scaling down nodes that run a runner pod
+	// The annotation is not set if this field is omitted
+	// +optional
+	SafeToEvict *bool `json:"safeToEvict,omitempty"`
+
+	// PodDisruptionBudget creates a PodDisruptionBudget with maxUnavailable=0 covering the running runner pods
+	// of this ActDeployment, so node drains wait for in-flight jobs to finish
+	// Runner pods that are still Pending are not covered, so they never block a drain
+	// +optional
+	PodDisruptionBudget bool `json:"podDisruptionBudget,omitempty"`
+}
+
+// RunnerImageSource is a registry tag the runner image is resolved from
+type RunnerImageSource struct {
+	// Image is the tracked image tag (e.g., "registry.example.com/ci/runner:latest")
+	// Defaults to the latest tag if the reference has none
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^[^@]+$`
+	Image string `json:"image"`
+
+	// PollInterval is how often the registry is checked for a new digest
+	// Set the forgejo.actions.io/check-runner-image annotation to any new value to check right away,
+	// e.g. from a registry webhook
+	// Defaults to 5m if not specified
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+
+	// PullSecretRef references a kubernetes.io/dockerconfigjson Secret with credentials for the registry
+	// +optional
+	PullSecretRef *corev1.LocalObjectReference `json:"pullSecretRef,omitempty"`
+}
+
+// RegistryAuthSpec references registry credentials for the runners' Docker config
+type RegistryAuthSpec struct {
+	// SecretRef is a kubernetes.io/dockerconfigjson Secret in the ActDeployment's namespace
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+
+	// Registries limits the Secret's auths entries added to the Docker config; all are added if not specified
+	// +optional
+	Registries []string `json:"registries,omitempty"`
+}
+
+// CloudIdentitySpec binds the runner pods to cloud identities
+// The operator creates the <name>-runner ServiceAccount carrying the identity annotations; a serviceAccountName in
+// the RunnerTemplate takes precedence
+type CloudIdentitySpec struct {
+	// AWS binds an IAM role through IAM Roles for Service Accounts (IRSA)
+	// +optional
+	AWS *AWSIdentitySpec `json:"aws,omitempty"`
+
+	// GCP binds a Google service account through GKE Workload Identity
+	// +optional
+	GCP *GCPIdentitySpec `json:"gcp,omitempty"`
+}
+
+// AWSIdentitySpec configures IRSA for runner pods
+type AWSIdentitySpec struct {
+	// RoleARN is the IAM role the runner pods assume (e.g., "arn:aws:iam::123456789012:role/ci-runner")
+	// +kubebuilder:validation:Pattern=`^arn:aws[a-z-]*:iam::[0-9]{12}:role/.+$`
+	RoleARN string `json:"roleARN"`
+
+	// ECRRegistries are the ECR registries Docker logs in to with the ecr-login credential helper
+	// (e.g., "123456789012.dkr.ecr.eu-west-1.amazonaws.com"); the runner image must contain
+	// docker-credential-ecr-login
+	// +optional
+	ECRRegistries []string `json:"ecrRegistries,omitempty"`
+}
+
+// GCPIdentitySpec configures GKE Workload Identity for runner pods
+type GCPIdentitySpec struct {
+	// ServiceAccount is the Google service account the runner pods act as
+	// (e.g., "ci-runner@my-project.iam.gserviceaccount.com")
+	// +kubebuilder:validation:Pattern=`^[^@]+@[^@]+\.iam\.gserviceaccount\.com$`
+	ServiceAccount string `json:"serviceAccount"`
+
+	// ArtifactRegistries are the Artifact Registry hosts Docker logs in to with the gcloud credential helper
+	// (e.g., "europe-docker.pkg.dev"); the runner image must contain docker-credential-gcloud
+	// +optional
+	ArtifactRegistries []string `json:"artifactRegistries,omitempty"`
+}
+
+// MetricsSpec configures the listener metrics endpoint
+type MetricsSpec struct {
+	// ServiceMonitor creates a monitoring.coreos.com/v1 ServiceMonitor for the listener metrics Service
+	// Skipped if the Prometheus Operator CRDs are not installed
+	// +optional
+	ServiceMonitor bool `json:"serviceMonitor,omitempty"`
+
+	// ServiceMonitorLabels are added to the ServiceMonitor, e.g. to match a Prometheus serviceMonitorSelector
+	// +optional
+	ServiceMonitorLabels map[string]string `json:"serviceMonitorLabels,omitempty"`
+
+	// Interval is the scrape interval set on the ServiceMonitor endpoint
+	// Uses the Prometheus default if not specified
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
 }
 
 // ActDeploymentStatus defines the observed state of ActDeployment.
@@ -114,25 +1041,155 @@ type ActDeploymentStatus struct {
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
-	// ListenerPodName is the name of the listener pod created for this ActDeployment
+	// ListenerPodName is the name of the current listener pod of this ActDeployment
+	// It is resolved from the listener Deployment's pods, preferring a ready pod
 	// +optional
 	ListenerPodName string `json:"listenerPodName,omitempty"`
 
+	// ListenerPodReady reports whether the listener pod named in ListenerPodName is ready
+	// +optional
+	ListenerPodReady bool `json:"listenerPodReady,omitempty"`
+
+	// ListenerRestarts is the total container restart count of the current listener pods
+	// +optional
+	ListenerRestarts int32 `json:"listenerRestarts,omitempty"`
+
+	// ResolvedRunnerImage is the digest reference RunnerImageFrom last resolved to (e.g., "ghcr.io/org/runner@sha256:...")
+	// +optional
+	ResolvedRunnerImage string `json:"resolvedRunnerImage,omitempty"`
+
+	// RunnerImageCheckedAt is when the registry was last checked for RunnerImageFrom
+	// +optional
+	RunnerImageCheckedAt *metav1.Time `json:"runnerImageCheckedAt,omitempty"`
+
+	// RunnerImageCheck is the value of the forgejo.actions.io/check-runner-image annotation at the last check
+	// +optional
+	RunnerImageCheck string `json:"runnerImageCheck,omitempty"`
+
+	// Rollout reports how many active runners use the current runner configuration
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// RenderedTemplateHash is the hash of the runner pod rendered for a sample job, including the operator's defaults
+	// and sidecars; unlike the rollout's template hash, it also changes with the operator config and version
+	// +optional
+	RenderedTemplateHash string `json:"renderedTemplateHash,omitempty"`
+
+	// ListenerConfigHash is the hash of the effective listener configuration last applied to the listener Deployment
+	// +optional
+	ListenerConfigHash string `json:"listenerConfigHash,omitempty"`
+
+	// ListenerMode is where the listener loop currently runs
+	// +optional
+	ListenerMode ListenerMode `json:"listenerMode,omitempty"`
+
+	// DedicatedNodes is the node configuration spec.dedicatedNodes expects
+	// +optional
+	DedicatedNodes *DedicatedNodesStatus `json:"dedicatedNodes,omitempty"`
+
+	// TokenCheck records the last check of the token's permissions, reported in the TokenPermissionsValid condition
+	// +optional
+	TokenCheck *TokenCheckStatus `json:"tokenCheck,omitempty"`
+
 	// LastPollTime is the timestamp of the last successful poll from the listener
+	// The listener writes it at most once a minute while polls succeed
 	// +optional
 	LastPollTime *metav1.Time `json:"lastPollTime,omitempty"`
 
+	// LastPollError is the error of the last failed poll; it is cleared by the next successful poll
+	// +optional
+	LastPollError string `json:"lastPollError,omitempty"`
+
+	// ConsecutivePollFailures is the number of listener polls that failed in a row
+	// +optional
+	ConsecutivePollFailures int32 `json:"consecutivePollFailures,omitempty"`
+
+	// LastShutdown is when the listener last shut down gracefully, e.g. on SIGTERM
+	// +optional
+	LastShutdown *metav1.Time `json:"lastShutdown,omitempty"`
+
 	// ActiveActRunners is the count of active ActRunner resources created by this deployment
 	// +optional
 	ActiveActRunners int32 `json:"activeActRunners,omitempty"`
 
+	// UnservedJobs lists queued jobs of the organization that no ActDeployment serves
+	// Only set if ReportUnservedJobs is enabled
+	// +listType=map
+	// +listMapKey=id
+	// +optional
+	UnservedJobs []UnservedJob `json:"unservedJobs,omitempty"`
+
+	// UnservedJobsCheckedAt is when the queued jobs were last checked for UnservedJobs
+	// +optional
+	UnservedJobsCheckedAt *metav1.Time `json:"unservedJobsCheckedAt,omitempty"`
+
+	// ResourceRecommendations are the suggested runner pod requests per runs-on label set
+	// Only set if spec.resourceRecommendations is set
+	// +listType=map
+	// +listMapKey=labels
+	// +optional
+	ResourceRecommendations []ResourceRecommendation `json:"resourceRecommendations,omitempty"`
+
 	// ObservedGeneration is the generation of the ActDeployment that was last reconciled
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
+// TokenCheckStatus records when and for which token Secret version the token's permissions were checked
+type TokenCheckStatus struct {
+	// CheckedAt is when the permissions were last checked
+	CheckedAt metav1.Time `json:"checkedAt"`
+
+	// SecretVersion is the resourceVersion of the token Secret at the last check
+	// +optional
+	SecretVersion string `json:"secretVersion,omitempty"`
+
+	// Request is the value of the forgejo.actions.io/check-token annotation at the last check
+	// +optional
+	Request string `json:"request,omitempty"`
+}
+
+// Condition types reported in ActDeploymentStatus.Conditions
+const (
+	// ConditionReady summarizes the health of an ActDeployment, ActRunner or ActOrg for GitOps tools
+	// On ActDeployments it is True when the listener runs and no other condition reports a problem
+	ConditionReady = "Ready"
+
+	// ConditionMaintenanceWindowActive is True while a maintenance window pauses runner creation
+	ConditionMaintenanceWindowActive = "MaintenanceWindowActive"
+
+	// ConditionListenerReady is True when the listener Deployment has an available, healthy pod
+	ConditionListenerReady = "ListenerReady"
+
+	// ConditionListenerResponsive is True while the listener's poll loop renews its heartbeat Lease in time
+	ConditionListenerResponsive = "ListenerResponsive"
+
+	// ConditionActOrgResolved is True when the referenced ActOrg was found and its token could be synced
+	ConditionActOrgResolved = "ActOrgResolved"
+
+	// ConditionAirGappedImagesValid is True when an air-gapped ActDeployment only uses explicit, non-public images
+	ConditionAirGappedImagesValid = "AirGappedImagesValid"
+
+	// ConditionRunnerImageResolved is True when the RunnerImageFrom tag was resolved to a digest at the last check
+	ConditionRunnerImageResolved = "RunnerImageResolved"
+
+	// ConditionSecretAuditPassed is True when SecretAudit found no secret values in the templates
+	ConditionSecretAuditPassed = "SecretAuditPassed"
+
+	// ConditionTokenPermissionsValid is True when the token may list the organization's jobs and create registration tokens
+	ConditionTokenPermissionsValid = "TokenPermissionsValid"
+
+	// ConditionDegraded is True when at least PollFailureThreshold listener polls failed in a row
+	ConditionDegraded = "Degraded"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason",priority=1
+// +kubebuilder:printcolumn:name="Active",type="integer",JSONPath=".status.activeActRunners"
+// +kubebuilder:printcolumn:name="Outdated",type="integer",JSONPath=".status.rollout.outdatedRunners",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // ActDeployment is the Schema for the actdeployments API
 type ActDeployment struct {
@@ -0,0 +1,115 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ActOrgSpec defines the desired state of ActOrg
+type ActOrgSpec struct {
+	// ForgejoServer is the base URL of the Forgejo server (e.g., "https://git.cloud.danmanners.com")
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?://`
+	ForgejoServer string `json:"forgejoServer"`
+
+	// Organization is the Forgejo organization name
+	// +kubebuilder:validation:Required
+	Organization string `json:"organization"`
+
+	// TokenSecretRef is a reference to a Secret containing the Forgejo API token
+	// The secret should contain a key named "token" with the API token value
+	// The namespace is required; it is usually a platform namespace app teams cannot read
+	TokenSecretRef corev1.SecretReference `json:"tokenSecretRef"`
+
+	// AllowedNamespaces lists the namespaces whose ActDeployments may reference this ActOrg
+	// No namespace may reference it if empty; "*" allows all namespaces
+	// Each referencing ActDeployment gets a copy of the token in its namespace, readable by anyone who can read Secrets there
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
+	// RunnerImage is the default runner image for ActDeployments that don't set one
+	// +optional
+	RunnerImage string `json:"runnerImage,omitempty"`
+
+	// DockerInDockerImage is the default Docker-in-Docker image for ActDeployments that don't set one
+	// +optional
+	DockerInDockerImage string `json:"dockerInDockerImage,omitempty"`
+
+	// PollInterval is the default poll interval for ActDeployments that don't set one
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+}
+
+// ActOrgStatus defines the observed state of ActOrg
+type ActOrgStatus struct {
+	// Conditions represent the current state of the ActOrg resource
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ReferencingDeployments is the number of ActDeployments that currently use this ActOrg
+	// +optional
+	ReferencingDeployments int32 `json:"referencingDeployments,omitempty"`
+
+	// ObservedGeneration is the generation of the ActOrg that was last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Server",type="string",JSONPath=".spec.forgejoServer"
+// +kubebuilder:printcolumn:name="Organization",type="string",JSONPath=".spec.organization"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Deployments",type="integer",JSONPath=".status.referencingDeployments"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ActOrg is the Schema for the actorgs API
+// An ActOrg holds the Forgejo connection and credentials of an organization centrally, so
+// ActDeployments in tenant namespaces can reference it without access to the token Secret
+type ActOrg struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the desired state of ActOrg
+	// +required
+	Spec ActOrgSpec `json:"spec"`
+
+	// status defines the observed state of ActOrg
+	// +optional
+	Status ActOrgStatus `json:"status,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// ActOrgList contains a list of ActOrg
+type ActOrgList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []ActOrg `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ActOrg{}, &ActOrgList{})
+}
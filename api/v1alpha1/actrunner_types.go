@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -47,6 +48,16 @@ type JobData struct {
 
 	// Status is the job status (e.g., "waiting", "running", "success", "failure")
 	Status string `json:"status"`
+
+	// Handle identifies the job so the runner can fetch exactly this job instead of any queued job
+	// matching its labels. Empty when the Forgejo server doesn't report job handles
+	// +optional
+	Handle string `json:"handle,omitempty"`
+
+	// Attempt is the run attempt of the job; a re-run of the job gets a new ActRunner for its attempt.
+	// Zero when the Forgejo server doesn't report attempts
+	// +optional
+	Attempt int64 `json:"attempt,omitempty"`
 }
 
 // ActRunnerSpec defines the desired state of ActRunner
@@ -82,6 +93,109 @@ type ActRunnerSpec struct {
 	// +optional
 	DockerConfigMapRef *corev1.LocalObjectReference `json:"dockerConfigMapRef,omitempty"`
 
+	// DockerConfigSecretRef is an optional reference to a Secret containing a generated Docker config under the
+	// .dockerconfigjson key; it is mounted in the runner and DinD containers and replaces dockerConfigMapRef
+	// +optional
+	DockerConfigSecretRef *corev1.LocalObjectReference `json:"dockerConfigSecretRef,omitempty"`
+
+	// DockerConfigMountPath is the directory the Docker config is mounted at in the runner container
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	DockerConfigMountPath string `json:"dockerConfigMountPath,omitempty"`
+
+	// DisruptionProtection configures how the runner pod is protected from voluntary disruptions
+	// +optional
+	DisruptionProtection *DisruptionProtectionSpec `json:"disruptionProtection,omitempty"`
+
+	// SecurityProfile selects how the DinD sidecar is isolated
+	// +optional
+	SecurityProfile SecurityProfile `json:"securityProfile,omitempty"`
+
+	// PodCompliance is enforced on and checked against the runner pod before it is created
+	// +optional
+	PodCompliance *PodComplianceSpec `json:"podCompliance,omitempty"`
+
+	// Mesh configures the runner pod for service mesh sidecar injection
+	// +optional
+	Mesh *MeshSpec `json:"mesh,omitempty"`
+
+	// Network configures the runner pod's network and the DinD daemon's bridge and listeners
+	// +optional
+	Network *RunnerNetworkSpec `json:"network,omitempty"`
+
+	// DinDDaemonConfig is the daemon.json the DinD daemon is started with
+	// +optional
+	DinDDaemonConfig *DinDDaemonConfigSource `json:"dindDaemonConfig,omitempty"`
+
+	// ExtraVolumes are added to the runner pod unless a volume of the same name exists
+	// +optional
+	ExtraVolumes []corev1.Volume `json:"extraVolumes,omitempty"`
+
+	// ExtraVolumeMounts are added to the runner and DinD containers unless they mount something at the path already
+	// +optional
+	ExtraVolumeMounts []ExtraVolumeMount `json:"extraVolumeMounts,omitempty"`
+
+	// WorkspaceInit runs an init container pre-populating the work volume before the runner starts
+	// +optional
+	WorkspaceInit *WorkspaceInitSpec `json:"workspaceInit,omitempty"`
+
+	// RegistryMirrors are passed to the DinD daemon as --registry-mirror
+	// +optional
+	RegistryMirrors []string `json:"registryMirrors,omitempty"`
+
+	// IdleTimeout stops the runner if it hasn't received a task within this duration after registering
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+
+	// PendingTimeout fails the runner if it is still Pending after this duration
+	// +optional
+	PendingTimeout *metav1.Duration `json:"pendingTimeout,omitempty"`
+
+	// TerminationGracePeriodSeconds is the termination grace period of the runner pod, drained by a preStop hook
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// Priority of the runner; its workload is not created while a higher-priority runner in the namespace
+	// is still waiting for its own
+	// +optional
+	Priority *int32 `json:"priority,omitempty"`
+
+	// Backend selects the workload that executes the runner
+	// Defaults to Pod if not specified
+	// +optional
+	Backend RunnerBackend `json:"backend,omitempty"`
+
+	// VirtualMachine configures the VM of the KubeVirt backend
+	// +optional
+	VirtualMachine *VirtualMachineSpec `json:"virtualMachine,omitempty"`
+
+	// RolloutStrategy controls whether a workload that has not started running yet is replaced
+	// when the listener updates the runner to a newer configuration
+	// Defaults to OnCompletion if not specified
+	// +optional
+	RolloutStrategy RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// RunnerLabels are the label definitions of the ActDeployment
+	// Used to register the runner with the schema and default container of the job's runs-on labels
+	// +optional
+	RunnerLabels []RunnerLabel `json:"runnerLabels,omitempty"`
+
+	// RegistrationLabels are the labels the runner registers with; empty means the job's runs-on entries
+	// +optional
+	RegistrationLabels []RunnerLabel `json:"registrationLabels,omitempty"`
+
+	// LogArchive uploads the runner's logs to object storage once it finished
+	// +optional
+	LogArchive *LogArchiveSpec `json:"logArchive,omitempty"`
+
+	// PodRetention controls how long the runner's workload is kept once it finished
+	// +optional
+	PodRetention *PodRetentionSpec `json:"podRetention,omitempty"`
+
+	// RecordUsage samples the runner pod's CPU and memory usage into status.peakUsage while it runs
+	// +optional
+	RecordUsage bool `json:"recordUsage,omitempty"`
+
 	// JobData is the full job payload from Forgejo API
 	JobData JobData `json:"jobData"`
 
@@ -90,7 +204,121 @@ type ActRunnerSpec struct {
 	JobTemplate corev1.PodTemplateSpec `json:"jobTemplate,omitempty"`
 }
 
+// SecurityProfile identifies how the DinD sidecar of a runner pod is isolated
+// +kubebuilder:validation:Enum=privileged;sysbox
+type SecurityProfile string
+
+const (
+	// SecurityProfilePrivileged runs the DinD sidecar as a privileged container
+	SecurityProfilePrivileged SecurityProfile = "privileged"
+
+	// SecurityProfileSysbox runs the runner pod with the sysbox-runc runtime, which lets the DinD sidecar
+	// run unprivileged in a user namespace
+	SecurityProfileSysbox SecurityProfile = "sysbox"
+)
+
+// PodComplianceSpec describes the admission policies generated runner pods have to satisfy
+type PodComplianceSpec struct {
+	// RequiredLabels are label keys every runner pod must carry, e.g. from runnerPodLabels
+	// +optional
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+
+	// ForbiddenFields are pod features runner pods must not use
+	// +optional
+	ForbiddenFields []PodComplianceField `json:"forbiddenFields,omitempty"`
+
+	// SecurityContext is enforced on every container of runner pods: the fields it sets replace those of the
+	// generated containers (including the DinD sidecar and init containers)
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+}
+
+// PodComplianceField is a pod feature admission policies commonly forbid
+// +kubebuilder:validation:Enum=HostNetwork;HostPID;HostIPC;HostPorts;HostPathVolumes;PrivilegedContainers;PrivilegeEscalation
+type PodComplianceField string
+
+const (
+	// PodComplianceFieldHostNetwork forbids pods in the host network namespace
+	PodComplianceFieldHostNetwork PodComplianceField = "HostNetwork"
+
+	// PodComplianceFieldHostPID forbids pods in the host PID namespace
+	PodComplianceFieldHostPID PodComplianceField = "HostPID"
+
+	// PodComplianceFieldHostIPC forbids pods in the host IPC namespace
+	PodComplianceFieldHostIPC PodComplianceField = "HostIPC"
+
+	// PodComplianceFieldHostPorts forbids container ports bound to host ports
+	PodComplianceFieldHostPorts PodComplianceField = "HostPorts"
+
+	// PodComplianceFieldHostPathVolumes forbids hostPath volumes
+	PodComplianceFieldHostPathVolumes PodComplianceField = "HostPathVolumes"
+
+	// PodComplianceFieldPrivilegedContainers forbids privileged containers, such as the default DinD sidecar
+	PodComplianceFieldPrivilegedContainers PodComplianceField = "PrivilegedContainers"
+
+	// PodComplianceFieldPrivilegeEscalation forbids containers that don't set allowPrivilegeEscalation to false
+	PodComplianceFieldPrivilegeEscalation PodComplianceField = "PrivilegeEscalation"
+)
+
+// RolloutStrategy identifies how runners pick up runner configuration changes
+// +kubebuilder:validation:Enum=Immediate;OnCompletion
+type RolloutStrategy string
+
+const (
+	// RolloutStrategyImmediate replaces runner workloads that have not started running yet
+	RolloutStrategyImmediate RolloutStrategy = "Immediate"
+
+	// RolloutStrategyOnCompletion leaves existing runner workloads alone; new runners use the new configuration
+	RolloutStrategyOnCompletion RolloutStrategy = "OnCompletion"
+)
+
+// RunnerBackend identifies the kind of workload an ActRunner is executed in
+// +kubebuilder:validation:Enum=Pod;Job;KubeVirt;ExternalVM
+type RunnerBackend string
+
+const (
+	// RunnerBackendPod runs the runner in a bare Pod owned by the ActRunner
+	RunnerBackendPod RunnerBackend = "Pod"
+
+	// RunnerBackendJob runs the runner in a batch/v1 Job, for clusters whose policies or quotas target Jobs
+	RunnerBackendJob RunnerBackend = "Job"
+
+	// RunnerBackendKubeVirt runs the runner in a disposable KubeVirt VirtualMachineInstance, for untrusted workloads
+	RunnerBackendKubeVirt RunnerBackend = "KubeVirt"
+
+	// RunnerBackendExternalVM is reserved for runners in virtual machines outside the cluster; it is not implemented yet
+	RunnerBackendExternalVM RunnerBackend = "ExternalVM"
+)
+
+// VirtualMachineSpec configures the virtual machines of the KubeVirt backend
+type VirtualMachineSpec struct {
+	// Image is the containerDisk image the VM boots
+	// It must run cloud-init and contain forgejo-runner and a Docker daemon
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// CPU is the number of virtual CPU cores
+	// Defaults to 2 if not specified
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CPU int32 `json:"cpu,omitempty"`
+
+	// Memory is the guest memory
+	// Defaults to 4Gi if not specified
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// CloudInitTemplate is a Go template for the cloud-init user data that registers and runs the runner
+	// It can use .Server, .Organization, .Name, .Labels and .Token, and the quote function for shell quoting
+	// The rendered user data is stored in a Secret, as it contains the registration token
+	// Defaults to a template that registers the runner, runs one job and powers off if not specified
+	// +optional
+	CloudInitTemplate string `json:"cloudInitTemplate,omitempty"`
+}
+
 // ActRunnerPhase represents the phase of an ActRunner
+// The allowed transitions between phases are defined in internal/runnerphase
+// +kubebuilder:validation:Enum=Pending;Running;Terminating;Unknown;Succeeded;Failed;Cancelled
 type ActRunnerPhase string
 
 const (
@@ -105,15 +333,182 @@ const (
 
 	// ActRunnerPhaseFailed means the ActRunner failed
 	ActRunnerPhaseFailed ActRunnerPhase = "Failed"
+
+	// ActRunnerPhaseTerminating means the ActRunner's pod is being deleted before the runner finished
+	ActRunnerPhaseTerminating ActRunnerPhase = "Terminating"
+
+	// ActRunnerPhaseCancelled means the ActRunner's pod was deleted before the runner finished
+	ActRunnerPhaseCancelled ActRunnerPhase = "Cancelled"
+
+	// ActRunnerPhaseUnknown means the state of the ActRunner's pod can't be determined, e.g. its node is unreachable
+	ActRunnerPhaseUnknown ActRunnerPhase = "Unknown"
+)
+
+const (
+	// ConditionRegistrationFailed is True when the runner could not register with Forgejo (e.g., bad token, clock skew)
+	ConditionRegistrationFailed = "RegistrationFailed"
+
+	// ConditionIdleTimedOut is True when the runner stopped because it received no task within its idle timeout
+	ConditionIdleTimedOut = "IdleTimedOut"
+
+	// ConditionDeregistered is True once the listener removed the runner of an idle-timed-out ActRunner from Forgejo
+	ConditionDeregistered = "Deregistered"
+
+	// ConditionOOMKilled is True when the runner or DinD container was killed for exceeding its memory limit
+	ConditionOOMKilled = "OOMKilled"
+
+	// ConditionFailed is True when the runner failed; the reason tells which container failed
+	// (FailureReasonRunnerFailed or FailureReasonDinDFailed)
+	ConditionFailed = "Failed"
+
+	// ConditionQuotaExceeded is True while the runner's workload is rejected by a ResourceQuota or LimitRange
+	// The reason tells which one (ResourceQuota or LimitRange); creation is retried with a backoff
+	ConditionQuotaExceeded = "QuotaExceeded"
+
+	// ConditionJobMismatch is True when the runner executed a different job than the one it was created for,
+	// which happens when several waiting jobs match its labels
+	ConditionJobMismatch = "JobMismatch"
+
+	// ConditionLogsArchived tells whether the logs of the finished runner were uploaded to the log archive
+	ConditionLogsArchived = "LogsArchived"
+
+	// ConditionAdmissionRejected is True while the runner's workload is rejected by an admission webhook or policy
+	// Creation is not retried until the ActRunner spec changes
+	ConditionAdmissionRejected = "AdmissionRejected"
+)
+
+const (
+	// FailureReasonRunnerFailed means the runner container exited with an error, usually a failing workflow
+	FailureReasonRunnerFailed = "RunnerFailed"
+
+	// FailureReasonDinDFailed means the Docker-in-Docker sidecar crashed, an infrastructure failure
+	FailureReasonDinDFailed = "DinDFailed"
+
+	// FailureReasonBackendUnavailable means the selected backend cannot run the runner
+	FailureReasonBackendUnavailable = "BackendUnavailable"
+
+	// FailureReasonPendingTimeout means the runner was still Pending after its pending timeout
+	FailureReasonPendingTimeout = "PendingTimeout"
+
+	// FailureReasonPodNonCompliant means the generated runner pod violates spec.podCompliance
+	FailureReasonPodNonCompliant = "PodNonCompliant"
 )
 
+// MaxDinDRetries is how often a runner pod is recreated after its DinD sidecar crashed
+const MaxDinDRetries = 1
+
+// IdleTimeoutExitCode is the runner container exit code that signals the runner received no task within its idle timeout
+const IdleTimeoutExitCode = 75
+
+// RegistrationFailedExitCode is the runner container exit code that signals a registration failure
+// The runner image's startup script exits with it and writes the error to the termination message
+const RegistrationFailedExitCode = 78
+
+// AdmissionRejection is a rejection of a runner workload by an admission webhook or policy
+type AdmissionRejection struct {
+	// Message is the rejection message of the API server
+	Message string `json:"message"`
+
+	// Generation is the ActRunner generation whose workload was rejected
+	Generation int64 `json:"generation"`
+
+	// RejectedAt is when the workload was rejected
+	RejectedAt metav1.Time `json:"rejectedAt"`
+}
+
+// ResourceUsage is the resource usage of all containers of a runner pod
+type ResourceUsage struct {
+	// CPU is the CPU usage
+	CPU resource.Quantity `json:"cpu"`
+
+	// Memory is the memory usage
+	Memory resource.Quantity `json:"memory"`
+}
+
+// PodRetentionPolicy identifies which finished runners keep their workload
+// +kubebuilder:validation:Enum=Always;OnFailure;Never
+type PodRetentionPolicy string
+
+const (
+	// PodRetentionAlways keeps the workloads of all finished runners
+	PodRetentionAlways PodRetentionPolicy = "Always"
+
+	// PodRetentionOnFailure keeps the workloads of failed and cancelled runners and deletes those of succeeded runners
+	PodRetentionOnFailure PodRetentionPolicy = "OnFailure"
+
+	// PodRetentionNever deletes the workloads of all runners once they finished
+	PodRetentionNever PodRetentionPolicy = "Never"
+)
+
+// PodRetentionSpec controls how long the workloads (pods, Jobs) of finished runners are kept, independent of
+// how long the finished ActRunners are kept
+type PodRetentionSpec struct {
+	// Policy selects the finished runners whose workload is kept; the others are deleted once the runner finished
+	// Defaults to Always if not specified
+	// +optional
+	Policy PodRetentionPolicy `json:"policy,omitempty"`
+
+	// TTL is how long a kept workload stays after the runner finished, even after the ActRunner is deleted
+	// Kept workloads are deleted along with the ActRunner if not specified
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// LogArchiveSpec configures the upload of runner logs to an S3-compatible bucket (AWS S3, GCS with HMAC keys, MinIO)
+type LogArchiveSpec struct {
+	// Endpoint is the URL of the object storage, e.g. "https://s3.eu-central-1.amazonaws.com" or
+	// "https://storage.googleapis.com". Objects are addressed path-style
+	// +kubebuilder:validation:Pattern=`^https?://`
+	Endpoint string `json:"endpoint"`
+
+	// Bucket is the bucket the logs are uploaded to
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+
+	// Region is the region requests are signed for (e.g., "eu-central-1", "auto" for GCS)
+	// +kubebuilder:default=us-east-1
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Prefix is prepended to the object keys, which are <prefix>/<namespace>/<actrunner>/<container>.log
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// CredentialsSecretName is a Secret in the runner's namespace with the HMAC key pair
+	// in the accessKeyID and secretAccessKey keys
+	// +kubebuilder:validation:MinLength=1
+	CredentialsSecretName string `json:"credentialsSecretName"`
+}
+
+// ContainerTermination is the terminated state of a runner pod container
+type ContainerTermination struct {
+	// ExitCode is the exit status of the container
+	ExitCode int32 `json:"exitCode"`
+
+	// Signal is the signal that terminated the container, if any
+	// +optional
+	Signal int32 `json:"signal,omitempty"`
+
+	// Reason is the reason for the termination (e.g., "OOMKilled", "Error", "Completed")
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is the termination message of the container
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// FinishedAt is when the container terminated
+	// +optional
+	FinishedAt *metav1.Time `json:"finishedAt,omitempty"`
+}
+
 // ActRunnerStatus defines the observed state of ActRunner
 type ActRunnerStatus struct {
 	// Phase represents the current phase of the ActRunner
 	// +optional
 	Phase ActRunnerPhase `json:"phase,omitempty"`
 
-	// KubernetesJobName is the name of the Kubernetes Job created for this ActRunner
+	// KubernetesJobName is the name of the Pod or Job created for this ActRunner, depending on the backend
 	// +optional
 	KubernetesJobName string `json:"kubernetesJobName,omitempty"`
 
@@ -141,11 +536,79 @@ type ActRunnerStatus struct {
 	// +optional
 	TriggerEvent string `json:"triggerEvent,omitempty"`
 
+	// ConcurrencyGroup is the workflow concurrency group of the run this runner was created for
+	// The listener serializes runner creation for jobs in the same concurrency group
+	// +optional
+	ConcurrencyGroup string `json:"concurrencyGroup,omitempty"`
+
+	// RunID is the Forgejo run the runner's job belongs to
+	// Jobs of the run holding a concurrency group don't wait for its runners
+	// +optional
+	RunID int64 `json:"runID,omitempty"`
+
+	// RunnerContainer is the terminated state of the runner container
+	// +optional
+	RunnerContainer *ContainerTermination `json:"runnerContainer,omitempty"`
+
+	// DinDContainer is the terminated state of the Docker-in-Docker sidecar
+	// +optional
+	DinDContainer *ContainerTermination `json:"dindContainer,omitempty"`
+
+	// DinDRetries is the number of times the runner pod was recreated after the DinD sidecar crashed
+	// +optional
+	DinDRetries int32 `json:"dindRetries,omitempty"`
+
+	// TemplateHash is the runner template hash of the configuration the current workload was created from
+	// +optional
+	TemplateHash string `json:"templateHash,omitempty"`
+
+	// RunnerImage is the runner container image of the current workload, after defaulting
+	// +optional
+	RunnerImage string `json:"runnerImage,omitempty"`
+
+	// DinDImage is the Docker-in-Docker sidecar image of the current workload, after defaulting
+	// +optional
+	DinDImage string `json:"dindImage,omitempty"`
+
+	// RunnerName is the name the runner registers with in Forgejo
+	// +optional
+	RunnerName string `json:"runnerName,omitempty"`
+
+	// RunnerID is the ID Forgejo assigned to the registered runner, looked up by the listener from the
+	// organization's runner list
+	// +optional
+	RunnerID int64 `json:"runnerID,omitempty"`
+
+	// ExecutedTaskID is the Forgejo task the runner executed, when it reported one and it was not the task of
+	// the job the runner was created for
+	// +optional
+	ExecutedTaskID int64 `json:"executedTaskID,omitempty"`
+
+	// ExecutedRepository is the repository of the task in ExecutedTaskID (e.g., "owner/repo")
+	// +optional
+	ExecutedRepository string `json:"executedRepository,omitempty"`
+
+	// AdmissionRejection records the last rejection of the runner's workload by admission control
+	// +optional
+	AdmissionRejection *AdmissionRejection `json:"admissionRejection,omitempty"`
+
+	// PeakUsage is the highest CPU and memory usage of the runner pod seen while it ran
+	// +optional
+	PeakUsage *ResourceUsage `json:"peakUsage,omitempty"`
+
+	// LogArchiveURL is the URL of the archived runner container log; the DinD log is stored next to it
+	// +optional
+	LogArchiveURL string `json:"logArchiveURL,omitempty"`
+
 	// Conditions represent the current state of the ActRunner resource
 	// +listType=map
 	// +listMapKey=type
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of the ActRunner that was last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -157,6 +620,9 @@ type ActRunnerStatus struct {
 // +kubebuilder:printcolumn:name="Ref",type="string",JSONPath=".status.prettyRef"
 // +kubebuilder:printcolumn:name="Event",type="string",JSONPath=".status.triggerEvent"
 // +kubebuilder:printcolumn:name="K8s Pod",type="string",JSONPath=".status.kubernetesJobName"
+// +kubebuilder:printcolumn:name="Runner ID",type="integer",JSONPath=".status.runnerID",priority=1
+// +kubebuilder:printcolumn:name="Image",type="string",JSONPath=".status.runnerImage",priority=1
+// +kubebuilder:printcolumn:name="Exit Code",type="integer",JSONPath=".status.runnerContainer.exitCode",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // ActRunner is the Schema for the actrunners API
@@ -21,11 +21,52 @@ limitations under the License.
 package v1alpha1
 
 import (
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIHeader) DeepCopyInto(out *APIHeader) {
+	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIHeader.
+func (in *APIHeader) DeepCopy() *APIHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(APIHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSIdentitySpec) DeepCopyInto(out *AWSIdentitySpec) {
+	*out = *in
+	if in.ECRRegistries != nil {
+		in, out := &in.ECRRegistries, &out.ECRRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSIdentitySpec.
+func (in *AWSIdentitySpec) DeepCopy() *AWSIdentitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSIdentitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActDeployment) DeepCopyInto(out *ActDeployment) {
 	*out = *in
@@ -88,10 +129,59 @@ func (in *ActDeploymentList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActDeploymentSpec) DeepCopyInto(out *ActDeploymentSpec) {
 	*out = *in
+	if in.ActOrgRef != nil {
+		in, out := &in.ActOrgRef, &out.ActOrgRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.RunnerLabels != nil {
+		in, out := &in.RunnerLabels, &out.RunnerLabels
+		*out = make([]RunnerLabel, len(*in))
+		copy(*out, *in)
+	}
+	if in.RegistrationLabels != nil {
+		in, out := &in.RegistrationLabels, &out.RegistrationLabels
+		*out = make([]RunnerLabel, len(*in))
+		copy(*out, *in)
+	}
+	if in.LogArchive != nil {
+		in, out := &in.LogArchive, &out.LogArchive
+		*out = new(LogArchiveSpec)
+		**out = **in
+	}
+	if in.PodRetention != nil {
+		in, out := &in.PodRetention, &out.PodRetention
+		*out = new(PodRetentionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResourceRecommendations != nil {
+		in, out := &in.ResourceRecommendations, &out.ResourceRecommendations
+		*out = new(ResourceRecommendationsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResourceProfiles != nil {
+		in, out := &in.ResourceProfiles, &out.ResourceProfiles
+		*out = make([]ResourceProfile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	out.TokenSecretRef = in.TokenSecretRef
+	if in.ClientCertSecretRef != nil {
+		in, out := &in.ClientCertSecretRef, &out.ClientCertSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.ExtraAPIHeaders != nil {
+		in, out := &in.ExtraAPIHeaders, &out.ExtraAPIHeaders
+		*out = make([]APIHeader, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.PollInterval != nil {
 		in, out := &in.PollInterval, &out.PollInterval
-		*out = new(v1.Duration)
+		*out = new(metav1.Duration)
 		**out = **in
 	}
 	if in.MinRunners != nil {
@@ -104,13 +194,188 @@ func (in *ActDeploymentSpec) DeepCopyInto(out *ActDeploymentSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Priority != nil {
+		in, out := &in.Priority, &out.Priority
+		*out = new(int32)
+		**out = **in
+	}
 	in.ListenerTemplate.DeepCopyInto(&out.ListenerTemplate)
 	in.RunnerTemplate.DeepCopyInto(&out.RunnerTemplate)
+	if in.RunnerImageFrom != nil {
+		in, out := &in.RunnerImageFrom, &out.RunnerImageFrom
+		*out = new(RunnerImageSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.DockerConfigMapRef != nil {
 		in, out := &in.DockerConfigMapRef, &out.DockerConfigMapRef
-		*out = new(corev1.LocalObjectReference)
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.RegistryAuth != nil {
+		in, out := &in.RegistryAuth, &out.RegistryAuth
+		*out = make([]RegistryAuthSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CloudIdentity != nil {
+		in, out := &in.CloudIdentity, &out.CloudIdentity
+		*out = new(CloudIdentitySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IdleTimeout != nil {
+		in, out := &in.IdleTimeout, &out.IdleTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RunnerPodLabels != nil {
+		in, out := &in.RunnerPodLabels, &out.RunnerPodLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RunnerPodAnnotations != nil {
+		in, out := &in.RunnerPodAnnotations, &out.RunnerPodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(DNSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AirGapped != nil {
+		in, out := &in.AirGapped, &out.AirGapped
+		*out = new(AirGappedSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Mesh != nil {
+		in, out := &in.Mesh, &out.Mesh
+		*out = new(MeshSpec)
+		**out = **in
+	}
+	if in.Network != nil {
+		in, out := &in.Network, &out.Network
+		*out = new(RunnerNetworkSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DinDDaemonConfig != nil {
+		in, out := &in.DinDDaemonConfig, &out.DinDDaemonConfig
+		*out = new(DinDDaemonConfigSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExtraVolumes != nil {
+		in, out := &in.ExtraVolumes, &out.ExtraVolumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtraVolumeMounts != nil {
+		in, out := &in.ExtraVolumeMounts, &out.ExtraVolumeMounts
+		*out = make([]ExtraVolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WorkspaceInit != nil {
+		in, out := &in.WorkspaceInit, &out.WorkspaceInit
+		*out = new(WorkspaceInitSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodCompliance != nil {
+		in, out := &in.PodCompliance, &out.PodCompliance
+		*out = new(PodComplianceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Scheduling != nil {
+		in, out := &in.Scheduling, &out.Scheduling
+		*out = new(SchedulingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PendingTimeout != nil {
+		in, out := &in.PendingTimeout, &out.PendingTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RegistrationTokenTTL != nil {
+		in, out := &in.RegistrationTokenTTL, &out.RegistrationTokenTTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DisruptionProtection != nil {
+		in, out := &in.DisruptionProtection, &out.DisruptionProtection
+		*out = new(DisruptionProtectionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodePool != nil {
+		in, out := &in.NodePool, &out.NodePool
+		*out = new(NodePoolSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DedicatedNodes != nil {
+		in, out := &in.DedicatedNodes, &out.DedicatedNodes
+		*out = new(DedicatedNodesSpec)
+		**out = **in
+	}
+	if in.CacheCleanup != nil {
+		in, out := &in.CacheCleanup, &out.CacheCleanup
+		*out = new(CacheCleanupSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JobQueue != nil {
+		in, out := &in.JobQueue, &out.JobQueue
+		*out = new(JobQueueSpec)
+		**out = **in
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.PollFailureThreshold != nil {
+		in, out := &in.PollFailureThreshold, &out.PollFailureThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.VirtualMachine != nil {
+		in, out := &in.VirtualMachine, &out.VirtualMachine
+		*out = new(VirtualMachineSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = new(MetricsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ListenerAutoRestart != nil {
+		in, out := &in.ListenerAutoRestart, &out.ListenerAutoRestart
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RestartWedgedListener != nil {
+		in, out := &in.RestartWedgedListener, &out.RestartWedgedListener
+		*out = new(bool)
 		**out = **in
 	}
+	if in.ListenerRBAC != nil {
+		in, out := &in.ListenerRBAC, &out.ListenerRBAC
+		*out = new(ListenerRBACSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActDeploymentSpec.
@@ -128,15 +393,56 @@ func (in *ActDeploymentStatus) DeepCopyInto(out *ActDeploymentStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RunnerImageCheckedAt != nil {
+		in, out := &in.RunnerImageCheckedAt, &out.RunnerImageCheckedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		**out = **in
+	}
+	if in.DedicatedNodes != nil {
+		in, out := &in.DedicatedNodes, &out.DedicatedNodes
+		*out = new(DedicatedNodesStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TokenCheck != nil {
+		in, out := &in.TokenCheck, &out.TokenCheck
+		*out = new(TokenCheckStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.LastPollTime != nil {
 		in, out := &in.LastPollTime, &out.LastPollTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastShutdown != nil {
+		in, out := &in.LastShutdown, &out.LastShutdown
+		*out = (*in).DeepCopy()
+	}
+	if in.UnservedJobs != nil {
+		in, out := &in.UnservedJobs, &out.UnservedJobs
+		*out = make([]UnservedJob, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UnservedJobsCheckedAt != nil {
+		in, out := &in.UnservedJobsCheckedAt, &out.UnservedJobsCheckedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ResourceRecommendations != nil {
+		in, out := &in.ResourceRecommendations, &out.ResourceRecommendations
+		*out = make([]ResourceRecommendation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActDeploymentStatus.
@@ -149,6 +455,113 @@ func (in *ActDeploymentStatus) DeepCopy() *ActDeploymentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActOrg) DeepCopyInto(out *ActOrg) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActOrg.
+func (in *ActOrg) DeepCopy() *ActOrg {
+	if in == nil {
+		return nil
+	}
+	out := new(ActOrg)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ActOrg) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActOrgList) DeepCopyInto(out *ActOrgList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ActOrg, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActOrgList.
+func (in *ActOrgList) DeepCopy() *ActOrgList {
+	if in == nil {
+		return nil
+	}
+	out := new(ActOrgList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ActOrgList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActOrgSpec) DeepCopyInto(out *ActOrgSpec) {
+	*out = *in
+	out.TokenSecretRef = in.TokenSecretRef
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActOrgSpec.
+func (in *ActOrgSpec) DeepCopy() *ActOrgSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ActOrgSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActOrgStatus) DeepCopyInto(out *ActOrgStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActOrgStatus.
+func (in *ActOrgStatus) DeepCopy() *ActOrgStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ActOrgStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActRunner) DeepCopyInto(out *ActRunner) {
 	*out = *in
@@ -215,9 +628,108 @@ func (in *ActRunnerSpec) DeepCopyInto(out *ActRunnerSpec) {
 	out.RegistrationTokenSecretRef = in.RegistrationTokenSecretRef
 	if in.DockerConfigMapRef != nil {
 		in, out := &in.DockerConfigMapRef, &out.DockerConfigMapRef
-		*out = new(corev1.LocalObjectReference)
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.DockerConfigSecretRef != nil {
+		in, out := &in.DockerConfigSecretRef, &out.DockerConfigSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.DisruptionProtection != nil {
+		in, out := &in.DisruptionProtection, &out.DisruptionProtection
+		*out = new(DisruptionProtectionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodCompliance != nil {
+		in, out := &in.PodCompliance, &out.PodCompliance
+		*out = new(PodComplianceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Mesh != nil {
+		in, out := &in.Mesh, &out.Mesh
+		*out = new(MeshSpec)
+		**out = **in
+	}
+	if in.Network != nil {
+		in, out := &in.Network, &out.Network
+		*out = new(RunnerNetworkSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DinDDaemonConfig != nil {
+		in, out := &in.DinDDaemonConfig, &out.DinDDaemonConfig
+		*out = new(DinDDaemonConfigSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExtraVolumes != nil {
+		in, out := &in.ExtraVolumes, &out.ExtraVolumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtraVolumeMounts != nil {
+		in, out := &in.ExtraVolumeMounts, &out.ExtraVolumeMounts
+		*out = make([]ExtraVolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WorkspaceInit != nil {
+		in, out := &in.WorkspaceInit, &out.WorkspaceInit
+		*out = new(WorkspaceInitSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RegistryMirrors != nil {
+		in, out := &in.RegistryMirrors, &out.RegistryMirrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IdleTimeout != nil {
+		in, out := &in.IdleTimeout, &out.IdleTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PendingTimeout != nil {
+		in, out := &in.PendingTimeout, &out.PendingTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Priority != nil {
+		in, out := &in.Priority, &out.Priority
+		*out = new(int32)
+		**out = **in
+	}
+	if in.VirtualMachine != nil {
+		in, out := &in.VirtualMachine, &out.VirtualMachine
+		*out = new(VirtualMachineSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RunnerLabels != nil {
+		in, out := &in.RunnerLabels, &out.RunnerLabels
+		*out = make([]RunnerLabel, len(*in))
+		copy(*out, *in)
+	}
+	if in.RegistrationLabels != nil {
+		in, out := &in.RegistrationLabels, &out.RegistrationLabels
+		*out = make([]RunnerLabel, len(*in))
+		copy(*out, *in)
+	}
+	if in.LogArchive != nil {
+		in, out := &in.LogArchive, &out.LogArchive
+		*out = new(LogArchiveSpec)
 		**out = **in
 	}
+	if in.PodRetention != nil {
+		in, out := &in.PodRetention, &out.PodRetention
+		*out = new(PodRetentionSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	in.JobData.DeepCopyInto(&out.JobData)
 	in.JobTemplate.DeepCopyInto(&out.JobTemplate)
 }
@@ -243,9 +755,29 @@ func (in *ActRunnerStatus) DeepCopyInto(out *ActRunnerStatus) {
 		in, out := &in.CompletedAt, &out.CompletedAt
 		*out = (*in).DeepCopy()
 	}
+	if in.RunnerContainer != nil {
+		in, out := &in.RunnerContainer, &out.RunnerContainer
+		*out = new(ContainerTermination)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DinDContainer != nil {
+		in, out := &in.DinDContainer, &out.DinDContainer
+		*out = new(ContainerTermination)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdmissionRejection != nil {
+		in, out := &in.AdmissionRejection, &out.AdmissionRejection
+		*out = new(AdmissionRejection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PeakUsage != nil {
+		in, out := &in.PeakUsage, &out.PeakUsage
+		*out = new(ResourceUsage)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -263,12 +795,298 @@ func (in *ActRunnerStatus) DeepCopy() *ActRunnerStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *JobData) DeepCopyInto(out *JobData) {
+func (in *AdmissionRejection) DeepCopyInto(out *AdmissionRejection) {
 	*out = *in
-	if in.Needs != nil {
-		in, out := &in.Needs, &out.Needs
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	in.RejectedAt.DeepCopyInto(&out.RejectedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionRejection.
+func (in *AdmissionRejection) DeepCopy() *AdmissionRejection {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionRejection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AirGappedSpec) DeepCopyInto(out *AirGappedSpec) {
+	*out = *in
+	if in.RegistryMirrors != nil {
+		in, out := &in.RegistryMirrors, &out.RegistryMirrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AirGappedSpec.
+func (in *AirGappedSpec) DeepCopy() *AirGappedSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AirGappedSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CacheCleanupSpec) DeepCopyInto(out *CacheCleanupSpec) {
+	*out = *in
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheCleanupSpec.
+func (in *CacheCleanupSpec) DeepCopy() *CacheCleanupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CacheCleanupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityReservationSpec) DeepCopyInto(out *CapacityReservationSpec) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservationSpec.
+func (in *CapacityReservationSpec) DeepCopy() *CapacityReservationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityReservationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudIdentitySpec) DeepCopyInto(out *CloudIdentitySpec) {
+	*out = *in
+	if in.AWS != nil {
+		in, out := &in.AWS, &out.AWS
+		*out = new(AWSIdentitySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GCP != nil {
+		in, out := &in.GCP, &out.GCP
+		*out = new(GCPIdentitySpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudIdentitySpec.
+func (in *CloudIdentitySpec) DeepCopy() *CloudIdentitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudIdentitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerTermination) DeepCopyInto(out *ContainerTermination) {
+	*out = *in
+	if in.FinishedAt != nil {
+		in, out := &in.FinishedAt, &out.FinishedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerTermination.
+func (in *ContainerTermination) DeepCopy() *ContainerTermination {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerTermination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSSpec) DeepCopyInto(out *DNSSpec) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(v1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostAliases != nil {
+		in, out := &in.HostAliases, &out.HostAliases
+		*out = make([]v1.HostAlias, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSSpec.
+func (in *DNSSpec) DeepCopy() *DNSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DedicatedNodesSpec) DeepCopyInto(out *DedicatedNodesSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DedicatedNodesSpec.
+func (in *DedicatedNodesSpec) DeepCopy() *DedicatedNodesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DedicatedNodesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DedicatedNodesStatus) DeepCopyInto(out *DedicatedNodesStatus) {
+	*out = *in
+	if in.AutoscalerTags != nil {
+		in, out := &in.AutoscalerTags, &out.AutoscalerTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DedicatedNodesStatus.
+func (in *DedicatedNodesStatus) DeepCopy() *DedicatedNodesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DedicatedNodesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DinDAddressPool) DeepCopyInto(out *DinDAddressPool) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DinDAddressPool.
+func (in *DinDAddressPool) DeepCopy() *DinDAddressPool {
+	if in == nil {
+		return nil
+	}
+	out := new(DinDAddressPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DinDDaemonConfigSource) DeepCopyInto(out *DinDDaemonConfigSource) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DinDDaemonConfigSource.
+func (in *DinDDaemonConfigSource) DeepCopy() *DinDDaemonConfigSource {
+	if in == nil {
+		return nil
+	}
+	out := new(DinDDaemonConfigSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DisruptionProtectionSpec) DeepCopyInto(out *DisruptionProtectionSpec) {
+	*out = *in
+	if in.SafeToEvict != nil {
+		in, out := &in.SafeToEvict, &out.SafeToEvict
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DisruptionProtectionSpec.
+func (in *DisruptionProtectionSpec) DeepCopy() *DisruptionProtectionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DisruptionProtectionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtraVolumeMount) DeepCopyInto(out *ExtraVolumeMount) {
+	*out = *in
+	in.VolumeMount.DeepCopyInto(&out.VolumeMount)
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtraVolumeMount.
+func (in *ExtraVolumeMount) DeepCopy() *ExtraVolumeMount {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtraVolumeMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCPIdentitySpec) DeepCopyInto(out *GCPIdentitySpec) {
+	*out = *in
+	if in.ArtifactRegistries != nil {
+		in, out := &in.ArtifactRegistries, &out.ArtifactRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCPIdentitySpec.
+func (in *GCPIdentitySpec) DeepCopy() *GCPIdentitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GCPIdentitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JobData) DeepCopyInto(out *JobData) {
+	*out = *in
+	if in.Needs != nil {
+		in, out := &in.Needs, &out.Needs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 	if in.RunsOn != nil {
 		in, out := &in.RunsOn, &out.RunsOn
@@ -286,3 +1104,540 @@ func (in *JobData) DeepCopy() *JobData {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JobQueueSpec) DeepCopyInto(out *JobQueueSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JobQueueSpec.
+func (in *JobQueueSpec) DeepCopy() *JobQueueSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JobQueueSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ListenerRBACSpec) DeepCopyInto(out *ListenerRBACSpec) {
+	*out = *in
+	if in.AdditionalRules != nil {
+		in, out := &in.AdditionalRules, &out.AdditionalRules
+		*out = make([]rbacv1.PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ListenerRBACSpec.
+func (in *ListenerRBACSpec) DeepCopy() *ListenerRBACSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ListenerRBACSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogArchiveSpec) DeepCopyInto(out *LogArchiveSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogArchiveSpec.
+func (in *LogArchiveSpec) DeepCopy() *LogArchiveSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LogArchiveSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MeshSpec) DeepCopyInto(out *MeshSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MeshSpec.
+func (in *MeshSpec) DeepCopy() *MeshSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MeshSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsSpec) DeepCopyInto(out *MetricsSpec) {
+	*out = *in
+	if in.ServiceMonitorLabels != nil {
+		in, out := &in.ServiceMonitorLabels, &out.ServiceMonitorLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsSpec.
+func (in *MetricsSpec) DeepCopy() *MetricsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePoolSpec) DeepCopyInto(out *NodePoolSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtendedResources != nil {
+		in, out := &in.ExtendedResources, &out.ExtendedResources
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.CapacityReservation != nil {
+		in, out := &in.CapacityReservation, &out.CapacityReservation
+		*out = new(CapacityReservationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePoolSpec.
+func (in *NodePoolSpec) DeepCopy() *NodePoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodComplianceSpec) DeepCopyInto(out *PodComplianceSpec) {
+	*out = *in
+	if in.RequiredLabels != nil {
+		in, out := &in.RequiredLabels, &out.RequiredLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ForbiddenFields != nil {
+		in, out := &in.ForbiddenFields, &out.ForbiddenFields
+		*out = make([]PodComplianceField, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodComplianceSpec.
+func (in *PodComplianceSpec) DeepCopy() *PodComplianceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodComplianceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodRetentionSpec) DeepCopyInto(out *PodRetentionSpec) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodRetentionSpec.
+func (in *PodRetentionSpec) DeepCopy() *PodRetentionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodRetentionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryAuthSpec) DeepCopyInto(out *RegistryAuthSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryAuthSpec.
+func (in *RegistryAuthSpec) DeepCopy() *RegistryAuthSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryAuthSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceProfile) DeepCopyInto(out *ResourceProfile) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceProfile.
+func (in *ResourceProfile) DeepCopy() *ResourceProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceRecommendation) DeepCopyInto(out *ResourceRecommendation) {
+	*out = *in
+	out.CPU = in.CPU.DeepCopy()
+	out.Memory = in.Memory.DeepCopy()
+	in.UpdatedAt.DeepCopyInto(&out.UpdatedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceRecommendation.
+func (in *ResourceRecommendation) DeepCopy() *ResourceRecommendation {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceRecommendation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceRecommendationsSpec) DeepCopyInto(out *ResourceRecommendationsSpec) {
+	*out = *in
+	if in.MinSamples != nil {
+		in, out := &in.MinSamples, &out.MinSamples
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceRecommendationsSpec.
+func (in *ResourceRecommendationsSpec) DeepCopy() *ResourceRecommendationsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceRecommendationsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceUsage) DeepCopyInto(out *ResourceUsage) {
+	*out = *in
+	out.CPU = in.CPU.DeepCopy()
+	out.Memory = in.Memory.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceUsage.
+func (in *ResourceUsage) DeepCopy() *ResourceUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerImageSource) DeepCopyInto(out *RunnerImageSource) {
+	*out = *in
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PullSecretRef != nil {
+		in, out := &in.PullSecretRef, &out.PullSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerImageSource.
+func (in *RunnerImageSource) DeepCopy() *RunnerImageSource {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerImageSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerLabel) DeepCopyInto(out *RunnerLabel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerLabel.
+func (in *RunnerLabel) DeepCopy() *RunnerLabel {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerLabel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerNetworkSpec) DeepCopyInto(out *RunnerNetworkSpec) {
+	*out = *in
+	if in.DefaultAddressPools != nil {
+		in, out := &in.DefaultAddressPools, &out.DefaultAddressPools
+		*out = make([]DinDAddressPool, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerNetworkSpec.
+func (in *RunnerNetworkSpec) DeepCopy() *RunnerNetworkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerNetworkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchedulingSpec) DeepCopyInto(out *SchedulingSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulingSpec.
+func (in *SchedulingSpec) DeepCopy() *SchedulingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SchedulingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenCheckStatus) DeepCopyInto(out *TokenCheckStatus) {
+	*out = *in
+	in.CheckedAt.DeepCopyInto(&out.CheckedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenCheckStatus.
+func (in *TokenCheckStatus) DeepCopy() *TokenCheckStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenCheckStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnservedJob) DeepCopyInto(out *UnservedJob) {
+	*out = *in
+	if in.RunsOn != nil {
+		in, out := &in.RunsOn, &out.RunsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.FirstSeen.DeepCopyInto(&out.FirstSeen)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnservedJob.
+func (in *UnservedJob) DeepCopy() *UnservedJob {
+	if in == nil {
+		return nil
+	}
+	out := new(UnservedJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineSpec) DeepCopyInto(out *VirtualMachineSpec) {
+	*out = *in
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineSpec.
+func (in *VirtualMachineSpec) DeepCopy() *VirtualMachineSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceInitSpec) DeepCopyInto(out *WorkspaceInitSpec) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceInitSpec.
+func (in *WorkspaceInitSpec) DeepCopy() *WorkspaceInitSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceInitSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -19,7 +19,10 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -27,16 +30,26 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/cloudevents"
 	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/controller"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/diagnostics"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/eventagg"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/faultinject"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/operatorconfig"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -54,39 +67,111 @@ func init() {
 
 // nolint:gocyclo
 func main() {
+	// Helpers to get flag defaults from the environment, so packaging tools (Helm, OLM) can configure the
+	// manager through env vars without changing its args
+	getEnvOrDefault := func(key, defaultValue string) string {
+		if val := os.Getenv(key); val != "" {
+			return val
+		}
+		return defaultValue
+	}
+	getEnvOrBool := func(key string, defaultValue bool) bool {
+		if val := os.Getenv(key); val != "" {
+			return val == "true" || val == "1" || val == "yes"
+		}
+		return defaultValue
+	}
+	getEnvOrInt := func(key string, defaultValue int) int {
+		if val, err := strconv.Atoi(os.Getenv(key)); err == nil {
+			return val
+		}
+		return defaultValue
+	}
+
 	var metricsAddr string
 	var metricsCertPath, metricsCertName, metricsCertKey string
 	var webhookCertPath, webhookCertName, webhookCertKey string
+	var webhookPort int
 	var enableLeaderElection bool
+	var leaderElectionNamespace string
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var operatorConfigPath string
+	var watchNamespaces string
+	var defaultListenerImage, defaultRunnerImage, defaultDinDImage string
+	var cloudEventsSink string
+	var mode string
+	var pprofAddr string
 	var tlsOpts []func(*tls.Config)
-	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
-		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
+	flag.StringVar(&metricsAddr, "metrics-bind-address", getEnvOrDefault("METRICS_BIND_ADDRESS", "0"),
+		"The address the metrics endpoint binds to. "+
+			"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service. (env METRICS_BIND_ADDRESS)")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", getEnvOrDefault("HEALTH_PROBE_BIND_ADDRESS", ":8081"),
+		"The address the probe endpoint binds to. (env HEALTH_PROBE_BIND_ADDRESS)")
+	flag.BoolVar(&enableLeaderElection, "leader-elect", getEnvOrBool("LEADER_ELECT", false),
 		"Enable leader election for controller manager. "+
-			"Enabling this will ensure there is only one active controller manager.")
-	flag.BoolVar(&secureMetrics, "metrics-secure", true,
-		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
-	flag.StringVar(&webhookCertPath, "webhook-cert-path", "", "The directory that contains the webhook certificate.")
-	flag.StringVar(&webhookCertName, "webhook-cert-name", "tls.crt", "The name of the webhook certificate file.")
-	flag.StringVar(&webhookCertKey, "webhook-cert-key", "tls.key", "The name of the webhook key file.")
-	flag.StringVar(&metricsCertPath, "metrics-cert-path", "",
-		"The directory that contains the metrics server certificate.")
-	flag.StringVar(&metricsCertName, "metrics-cert-name", "tls.crt", "The name of the metrics server certificate file.")
-	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
-	flag.BoolVar(&enableHTTP2, "enable-http2", false,
-		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+			"Enabling this will ensure there is only one active controller manager. (env LEADER_ELECT)")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", getEnvOrDefault("LEADER_ELECTION_NAMESPACE", ""),
+		"The namespace of the leader election lease. Defaults to the manager's namespace. (env LEADER_ELECTION_NAMESPACE)")
+	flag.BoolVar(&secureMetrics, "metrics-secure", getEnvOrBool("METRICS_SECURE", true),
+		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead. "+
+			"(env METRICS_SECURE)")
+	flag.IntVar(&webhookPort, "webhook-port", getEnvOrInt("WEBHOOK_PORT", webhook.DefaultPort),
+		"The port the webhook server listens on. (env WEBHOOK_PORT)")
+	flag.StringVar(&webhookCertPath, "webhook-cert-path", getEnvOrDefault("WEBHOOK_CERT_PATH", ""),
+		"The directory that contains the webhook certificate. (env WEBHOOK_CERT_PATH)")
+	flag.StringVar(&webhookCertName, "webhook-cert-name", getEnvOrDefault("WEBHOOK_CERT_NAME", "tls.crt"),
+		"The name of the webhook certificate file. (env WEBHOOK_CERT_NAME)")
+	flag.StringVar(&webhookCertKey, "webhook-cert-key", getEnvOrDefault("WEBHOOK_CERT_KEY", "tls.key"),
+		"The name of the webhook key file. (env WEBHOOK_CERT_KEY)")
+	flag.StringVar(&metricsCertPath, "metrics-cert-path", getEnvOrDefault("METRICS_CERT_PATH", ""),
+		"The directory that contains the metrics server certificate. (env METRICS_CERT_PATH)")
+	flag.StringVar(&metricsCertName, "metrics-cert-name", getEnvOrDefault("METRICS_CERT_NAME", "tls.crt"),
+		"The name of the metrics server certificate file. (env METRICS_CERT_NAME)")
+	flag.StringVar(&metricsCertKey, "metrics-cert-key", getEnvOrDefault("METRICS_CERT_KEY", "tls.key"),
+		"The name of the metrics server key file. (env METRICS_CERT_KEY)")
+	flag.BoolVar(&enableHTTP2, "enable-http2", getEnvOrBool("ENABLE_HTTP2", false),
+		"If set, HTTP/2 will be enabled for the metrics and webhook servers (env ENABLE_HTTP2)")
+	flag.StringVar(&operatorConfigPath, "operator-config", getEnvOrDefault("OPERATOR_CONFIG", ""),
+		"Path to the operator configuration file (default images, rate limits). It is reloaded when it changes. "+
+			"(env OPERATOR_CONFIG)")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", getEnvOrDefault("WATCH_NAMESPACES", ""),
+		"Comma-separated namespaces to watch. All namespaces are watched if empty. (env WATCH_NAMESPACES)")
+	flag.StringVar(&defaultListenerImage, "default-listener-image", getEnvOrDefault("DEFAULT_LISTENER_IMAGE", ""),
+		"Default listener image; the operator config file takes precedence. (env DEFAULT_LISTENER_IMAGE)")
+	flag.StringVar(&defaultRunnerImage, "default-runner-image", getEnvOrDefault("DEFAULT_RUNNER_IMAGE", ""),
+		"Default runner image; the operator config file takes precedence. (env DEFAULT_RUNNER_IMAGE)")
+	flag.StringVar(&defaultDinDImage, "default-dind-image", getEnvOrDefault("DEFAULT_DIND_IMAGE", ""),
+		"Default Docker-in-Docker image; the operator config file takes precedence. (env DEFAULT_DIND_IMAGE)")
+	flag.StringVar(&cloudEventsSink, "cloudevents-sink", getEnvOrDefault("CLOUDEVENTS_SINK", ""),
+		"Publish ActRunner lifecycle CloudEvents to this http(s):// endpoint or nats://host:port/subject. "+
+			"Disabled if empty. (env CLOUDEVENTS_SINK)")
+	flag.StringVar(&mode, "mode", getEnvOrDefault("MODE", "controller"),
+		"controller runs the controllers only; all also runs the listener loops of ActDeployments "+
+			"whose spec.listenerMode isn't dedicated, instead of a listener Deployment each. (env MODE)")
+	flag.StringVar(&pprofAddr, "pprof-bind-address", getEnvOrDefault("PPROF_BIND_ADDRESS", "0"),
+		"The address serving pprof profiles under /debug/pprof/ and a JSON state dump under /debug/state, "+
+			"or 0 to disable. Bind it to localhost and use kubectl port-forward. (env PPROF_BIND_ADDRESS)")
+	podFaults := faultinject.PodFaults{}
+	podFaults.BindFlags(flag.CommandLine)
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		faultinject.PrintDefaults(flag.CommandLine)
+	}
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if mode != "controller" && mode != "all" {
+		setupLog.Error(fmt.Errorf("unknown mode %q", mode), "--mode must be controller or all")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -105,6 +190,7 @@ func main() {
 	// Initial webhook TLS options
 	webhookTLSOpts := tlsOpts
 	webhookServerOptions := webhook.Options{
+		Port:    webhookPort,
 		TLSOpts: webhookTLSOpts,
 	}
 
@@ -154,13 +240,29 @@ func main() {
 		metricsServerOptions.KeyName = metricsCertKey
 	}
 
+	// Restrict the cache to the watched namespaces; cluster-scoped objects such as ActOrgs are unaffected
+	cacheOptions := cache.Options{}
+	for _, ns := range strings.Split(watchNamespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			if cacheOptions.DefaultNamespaces == nil {
+				cacheOptions.DefaultNamespaces = map[string]cache.Config{}
+			}
+			cacheOptions.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+	if len(cacheOptions.DefaultNamespaces) > 0 {
+		setupLog.Info("restricting the manager to namespaces", "namespaces", watchNamespaces)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		Metrics:                metricsServerOptions,
-		WebhookServer:          webhookServer,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "3c379d25.github.com",
+		Scheme:                  scheme,
+		Metrics:                 metricsServerOptions,
+		WebhookServer:           webhookServer,
+		Cache:                   controller.CacheOptions(cacheOptions),
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        "3c379d25.github.com",
+		LeaderElectionNamespace: leaderElectionNamespace,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -178,19 +280,108 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Flag defaults replace the compiled-in defaults; the config file still overrides both
+	configDefaults := operatorconfig.Defaults()
+	if defaultListenerImage != "" {
+		configDefaults.ListenerImage = defaultListenerImage
+	}
+	if defaultRunnerImage != "" {
+		configDefaults.RunnerImage = defaultRunnerImage
+	}
+	if defaultDinDImage != "" {
+		configDefaults.DockerInDockerImage = defaultDinDImage
+	}
+	operatorConfig, err := operatorconfig.NewStore(operatorConfigPath, configDefaults)
+	if err != nil {
+		setupLog.Error(err, "unable to load operator config")
+		os.Exit(1)
+	}
+	if err := mgr.Add(operatorConfig); err != nil {
+		setupLog.Error(err, "unable to set up operator config reloading")
+		os.Exit(1)
+	}
+
+	var runnerEvents cloudevents.Publisher
+	if cloudEventsSink != "" {
+		if runnerEvents, err = cloudevents.NewPublisher(cloudEventsSink); err != nil {
+			setupLog.Error(err, "unable to set up CloudEvents publishing")
+			os.Exit(1)
+		}
+	}
+
+	// Pod logs are not served by the controller-runtime client
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create Kubernetes clientset")
+		os.Exit(1)
+	}
+
+	// In all mode the listener loops run in the manager; they read the API server directly like a listener pod
+	var embeddedListeners *controller.EmbeddedListeners
+	if mode == "all" {
+		listenerClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			setupLog.Error(err, "unable to create listener client")
+			os.Exit(1)
+		}
+		embeddedListeners = &controller.EmbeddedListeners{
+			Client:   listenerClient,
+			Recorder: eventagg.New(mgr.GetEventRecorderFor("forgejo-listener"), eventagg.DefaultInterval),
+		}
+		if err := mgr.Add(embeddedListeners); err != nil {
+			setupLog.Error(err, "unable to set up embedded listeners")
+			os.Exit(1)
+		}
+		if err := listener.RegisterMetrics(metrics.Registry); err != nil {
+			setupLog.Error(err, "unable to register listener metrics")
+			os.Exit(1)
+		}
+		setupLog.Info("running listener loops in the manager")
+	}
+
+	if pprofAddr != "0" {
+		if err := mgr.Add(&diagnostics.Server{
+			Addr:   pprofAddr,
+			State:  controller.DiagnosticState(mgr.GetClient(), embeddedListeners),
+			Logger: ctrl.Log.WithName("diagnostics"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up diagnostics endpoint")
+			os.Exit(1)
+		}
+	}
+
 	if err := (&controller.ActDeploymentReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  eventagg.New(mgr.GetEventRecorderFor("actdeployment-controller"), eventagg.DefaultInterval),
+		Config:    operatorConfig,
+		APIReader: mgr.GetAPIReader(),
+		Listeners: embeddedListeners,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ActDeployment")
 		os.Exit(1)
 	}
 
+	if podFaults.Enabled() {
+		setupLog.Info("injecting runner pod creation faults", "errorRate", podFaults.CreateErrorRate)
+	}
 	if err := (&controller.ActRunnerReconciler{
+		Client:    podFaults.Client(mgr.GetClient()),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  eventagg.New(mgr.GetEventRecorderFor("actrunner-controller"), eventagg.DefaultInterval),
+		Config:    operatorConfig,
+		Events:    runnerEvents,
+		Clientset: clientset,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ActRunner")
+		os.Exit(1)
+	}
+
+	if err := (&controller.ActOrgReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "ActRunner")
+		setupLog.Error(err, "unable to create controller", "controller", "ActOrg")
 		os.Exit(1)
 	}
 	// +kubebuilder:scaffold:builder
@@ -0,0 +1,59 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package airgap checks the images of air-gapped ActDeployments
+package airgap
+
+import "strings"
+
+// dockerHubDomains are the registry hosts that resolve to Docker Hub
+var dockerHubDomains = map[string]bool{
+	"docker.io":            true,
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+}
+
+// IsDockerHub reports whether an image reference is pulled from Docker Hub
+// References without a registry host (e.g., "docker:dind" or "library/alpine") default to Docker Hub
+func IsDockerHub(image string) bool {
+	domain, _, found := strings.Cut(image, "/")
+	if !found {
+		return true
+	}
+	if !strings.ContainsAny(domain, ".:") && domain != "localhost" {
+		return true
+	}
+	return dockerHubDomains[strings.ToLower(domain)]
+}
+
+// ImageProblems returns a message for every image that is missing or pulled from Docker Hub
+// The map keys name the image (e.g., "runner") and the values are the effective image references
+func ImageProblems(images map[string]string) []string {
+	var problems []string
+	for _, name := range []string{"listener", "runner", "dind"} {
+		image, ok := images[name]
+		if !ok {
+			continue
+		}
+		switch {
+		case image == "":
+			problems = append(problems, name+" image is not set")
+		case IsDockerHub(image):
+			problems = append(problems, name+" image "+image+" is pulled from Docker Hub")
+		}
+	}
+	return problems
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package airgap
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IsDockerHub", func() {
+	DescribeTable("detects Docker Hub references",
+		func(image string, expected bool) {
+			Expect(IsDockerHub(image)).To(Equal(expected))
+		},
+		Entry("short name", "docker:29-dind", true),
+		Entry("user repository", "gitea/act_runner:latest", true),
+		Entry("docker.io", "docker.io/library/docker:29.1.3-dind-alpine3.23", true),
+		Entry("index.docker.io", "index.docker.io/library/alpine", true),
+		Entry("internal registry", "harbor.example.com/library/docker:dind", false),
+		Entry("registry with port", "registry:5000/runner:1.0", false),
+		Entry("localhost", "localhost/runner:1.0", false),
+	)
+})
+
+var _ = Describe("ImageProblems", func() {
+	It("reports missing and public images in a stable order", func() {
+		problems := ImageProblems(map[string]string{
+			"dind":     "docker:dind",
+			"runner":   "",
+			"listener": "harbor.example.com/forgejo/listener:1.0",
+		})
+		Expect(problems).To(Equal([]string{
+			"runner image is not set",
+			"dind image docker:dind is pulled from Docker Hub",
+		}))
+	})
+
+	It("returns nothing for explicit internal images", func() {
+		Expect(ImageProblems(map[string]string{
+			"listener": "harbor.example.com/forgejo/listener:1.0",
+			"runner":   "harbor.example.com/forgejo/runner:1.0",
+			"dind":     "harbor.example.com/library/docker:dind",
+		})).To(BeEmpty())
+	})
+})
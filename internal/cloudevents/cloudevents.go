@@ -0,0 +1,189 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cloudevents publishes CloudEvents in structured JSON mode to an HTTP endpoint or a NATS subject
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/natsclient"
+)
+
+// Event types of the ActRunner lifecycle
+const (
+	TypeRunnerCreated   = "io.forgejo.actions.runner.created"
+	TypeRunnerStarted   = "io.forgejo.actions.runner.started"
+	TypeRunnerSucceeded = "io.forgejo.actions.runner.succeeded"
+	TypeRunnerFailed    = "io.forgejo.actions.runner.failed"
+	TypeRunnerCancelled = "io.forgejo.actions.runner.cancelled"
+)
+
+// Event is a CloudEvent in the JSON event format
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+	Data            any       `json:"data,omitempty"`
+}
+
+// NewEvent creates a CloudEvent with JSON data
+// The ID should be stable for the same occurrence, so consumers can drop duplicates
+func NewEvent(id, source, eventType, subject string, data any) Event {
+	return Event{
+		SpecVersion:     "1.0",
+		ID:              id,
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// Publisher sends CloudEvents to a sink
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// NewPublisher creates a publisher for the sink URL
+// http:// and https:// URLs receive the events as POST requests; nats://[user:password@]host:port/subject
+// publishes them to the subject (a user without password is sent as token)
+func NewPublisher(sink string) (Publisher, error) {
+	u, err := url.Parse(sink)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CloudEvents sink: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &httpPublisher{url: sink, client: &http.Client{Timeout: 10 * time.Second}}, nil
+	case "nats":
+		options, subject, err := natsclient.ParseURL(sink)
+		if err != nil {
+			return nil, err
+		}
+		options.Name = "forgejo-act-runner-controller"
+		return &natsPublisher{options: options, subject: subject}, nil
+	default:
+		return nil, fmt.Errorf("unsupported CloudEvents sink scheme %q, expected http, https or nats", u.Scheme)
+	}
+}
+
+// httpPublisher POSTs events in structured mode
+type httpPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func (p *httpPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode CloudEvent: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create CloudEvents request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send CloudEvent: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("CloudEvents sink returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// natsPublisher publishes events to a NATS subject over a single, lazily (re)established connection
+type natsPublisher struct {
+	options natsclient.Options
+	subject string
+
+	mu   sync.Mutex
+	conn *natsclient.Conn
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode CloudEvent: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		if p.conn, err = natsclient.Dial(ctx, p.options); err != nil {
+			return err
+		}
+	}
+	if err := p.publish(ctx, payload); err != nil {
+		_ = p.conn.Close()
+		p.conn = nil
+		return err
+	}
+	return nil
+}
+
+// publish sends the message followed by a PING; the PONG confirms the server processed the message
+func (p *natsPublisher) publish(ctx context.Context, payload []byte) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	if err := p.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	if err := p.conn.Publish(p.subject, payload); err != nil {
+		return err
+	}
+	if err := p.conn.Ping(); err != nil {
+		return err
+	}
+	for {
+		op, err := p.conn.ReadOp()
+		if err != nil {
+			return err
+		}
+		switch op.Kind {
+		case "PONG":
+			return nil
+		case "PING":
+			if err := p.conn.Pong(); err != nil {
+				return err
+			}
+		case "-ERR":
+			return fmt.Errorf("NATS server rejected the message: %s", op.Error)
+		}
+	}
+}
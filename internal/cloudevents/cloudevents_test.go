@@ -0,0 +1,141 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevents
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewPublisher", func() {
+	It("rejects unsupported schemes and NATS sinks without subject", func() {
+		_, err := NewPublisher("kafka://broker:9092/topic")
+		Expect(err).To(HaveOccurred())
+		_, err = NewPublisher("nats://nats:4222")
+		Expect(err).To(MatchError(ContainSubstring("subject")))
+	})
+})
+
+var _ = Describe("HTTP publisher", func() {
+	It("posts the event in structured mode", func() {
+		var contentType string
+		var received Event
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			Expect(json.NewDecoder(r.Body).Decode(&received)).To(Succeed())
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		publisher, err := NewPublisher(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		event := NewEvent("uid-created", "/namespaces/ci/actrunners/r1", TypeRunnerCreated, "r1", map[string]any{"jobId": 42})
+		Expect(publisher.Publish(context.Background(), event)).To(Succeed())
+
+		Expect(contentType).To(HavePrefix("application/cloudevents+json"))
+		Expect(received.SpecVersion).To(Equal("1.0"))
+		Expect(received.ID).To(Equal("uid-created"))
+		Expect(received.Type).To(Equal(TypeRunnerCreated))
+		Expect(received.Data).To(HaveKeyWithValue("jobId", BeNumerically("==", 42)))
+	})
+
+	It("fails on error responses", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		publisher, err := NewPublisher(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.Publish(context.Background(), NewEvent("id", "src", TypeRunnerFailed, "", nil))).To(MatchError(ContainSubstring("500")))
+	})
+})
+
+var _ = Describe("NATS publisher", func() {
+	// serveNATS accepts one client, answers the protocol and sends the CONNECT options and published payloads
+	serveNATS := func(listener net.Listener, connects chan<- string, messages chan<- string) {
+		defer GinkgoRecover()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		_, _ = fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case strings.HasPrefix(line, "CONNECT "):
+				connects <- strings.TrimPrefix(line, "CONNECT ")
+			case strings.HasPrefix(line, "PUB "):
+				var subject string
+				var size int
+				_, err := fmt.Sscanf(line, "PUB %s %d", &subject, &size)
+				Expect(err).NotTo(HaveOccurred())
+				payload := make([]byte, size+2)
+				_, err = io.ReadFull(reader, payload)
+				Expect(err).NotTo(HaveOccurred())
+				messages <- subject + " " + string(payload[:size])
+			case line == "PING":
+				_, _ = fmt.Fprint(conn, "PONG\r\n")
+			}
+		}
+	}
+
+	It("publishes events to the subject over one connection", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+		connects, messages := make(chan string, 2), make(chan string, 2)
+		go serveNATS(listener, connects, messages)
+
+		publisher, err := NewPublisher("nats://ci:secret@" + listener.Addr().String() + "/forgejo.runners")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.Publish(context.Background(), NewEvent("a", "src", TypeRunnerStarted, "", nil))).To(Succeed())
+		Expect(publisher.Publish(context.Background(), NewEvent("b", "src", TypeRunnerSucceeded, "", nil))).To(Succeed())
+
+		Expect(<-connects).To(And(ContainSubstring(`"user":"ci"`), ContainSubstring(`"pass":"secret"`)))
+		Expect(<-messages).To(And(HavePrefix("forgejo.runners "), ContainSubstring(TypeRunnerStarted)))
+		Expect(<-messages).To(ContainSubstring(TypeRunnerSucceeded))
+		Expect(connects).To(BeEmpty())
+	})
+
+	It("fails when the server is unreachable", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		address := listener.Addr().String()
+		Expect(listener.Close()).To(Succeed())
+
+		publisher, err := NewPublisher("nats://" + address + "/forgejo.runners")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publisher.Publish(context.Background(), NewEvent("a", "src", TypeRunnerCreated, "", nil))).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,85 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cloudinit renders the cloud-init user data that registers and runs a Forgejo runner in a virtual machine
+package cloudinit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultTemplate registers the runner, runs a single job and powers the VM off, which ends the runner
+// The VM image must contain forgejo-runner and a running Docker daemon
+const DefaultTemplate = `#cloud-config
+write_files:
+  - path: /etc/forgejo-runner/runner.env
+    permissions: "0600"
+    content: |
+      FORGEJO_SERVER={{ quote .Server }}
+      FORGEJO_ORG={{ quote .Organization }}
+      FORGEJO_RUNNER_NAME={{ quote .Name }}
+      FORGEJO_LABELS={{ quote .Labels }}
+      TOKEN={{ quote .Token }}
+runcmd:
+  - - sh
+    - -c
+    - >-
+      . /etc/forgejo-runner/runner.env &&
+      cd /etc/forgejo-runner &&
+      forgejo-runner register --no-interactive --instance "$FORGEJO_SERVER" --token "$TOKEN"
+      --name "$FORGEJO_RUNNER_NAME" --labels "$FORGEJO_LABELS" &&
+      forgejo-runner one-job;
+      poweroff
+`
+
+// Data is the input of the user data template
+type Data struct {
+	// Server is the Forgejo server URL
+	Server string
+	// Organization is the Forgejo organization the runner registers with
+	Organization string
+	// Name is the runner name
+	Name string
+	// Labels are the comma-separated runner labels
+	Labels string
+	// Token is the runner registration token
+	Token string
+}
+
+// Render executes the user data template, or DefaultTemplate if tmpl is empty
+// Templates can use the quote function to single-quote values for the shell
+func Render(tmpl string, data Data) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	t, err := template.New("cloud-init").Option("missingkey=error").Funcs(template.FuncMap{"quote": quote}).Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse cloud-init template: %w", err)
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render cloud-init template: %w", err)
+	}
+	return out.String(), nil
+}
+
+// quote single-quotes a value for POSIX shells
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Render", func() {
+	data := Data{
+		Server:       "https://git.example.com",
+		Organization: "acme",
+		Name:         "runner-1",
+		Labels:       "vm:host",
+		Token:        "it's-secret",
+	}
+
+	It("renders the default template with quoted values", func() {
+		userData, err := Render("", data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userData).To(HavePrefix("#cloud-config\n"))
+		Expect(userData).To(ContainSubstring("FORGEJO_SERVER='https://git.example.com'"))
+		Expect(userData).To(ContainSubstring(`TOKEN='it'\''s-secret'`))
+		Expect(userData).To(ContainSubstring("forgejo-runner one-job"))
+	})
+
+	It("renders custom templates", func() {
+		userData, err := Render("#cloud-config\nhostname: {{ .Name }}\n", data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userData).To(Equal("#cloud-config\nhostname: runner-1\n"))
+	})
+
+	It("rejects invalid templates", func() {
+		_, err := Render("{{ .Unknown }}", data)
+		Expect(err).To(HaveOccurred())
+		_, err = Render("{{ .Name", data)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -17,27 +17,64 @@ limitations under the License.
 package controller
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/airgap"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/heartbeat"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/k8sutil"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/maintenance"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/nodepool"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/operatorconfig"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerlabels"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerphase"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/secretguard"
 )
 
 // ActDeploymentReconciler reconciles an ActDeployment object
 type ActDeploymentReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	Config   *operatorconfig.Store
+
+	// APIReader reads the ActOrg token secrets uncached, since they live outside the watched namespaces
+	// nil reads them through Client
+	APIReader client.Reader
+
+	// Listeners runs embedded listener loops; nil unless the manager runs with --mode=all
+	Listeners *EmbeddedListeners
 }
 
 // +kubebuilder:rbac:groups=forgejo.actions.io,resources=actdeployments,verbs=get;list;watch;create;update;patch;delete
@@ -46,10 +83,19 @@ type ActDeploymentReconciler struct {
 // +kubebuilder:rbac:groups=forgejo.actions.io,resources=actrunners,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list
-// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=forgejo.actions.io,resources=actorgs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=scheduling.k8s.io,resources=priorityclasses,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -59,17 +105,80 @@ func (r *ActDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	actDeployment := &forgejoactionsiov1alpha1.ActDeployment{}
 	if err := r.Get(ctx, req.NamespacedName, actDeployment); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Listeners.Stop(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	original := actDeployment.DeepCopy()
 
 	log.Info("found ActDeployment", "name", actDeployment.Name, "namespace", actDeployment.Namespace)
 
 	// Handle deletion
 	if !actDeployment.DeletionTimestamp.IsZero() {
-		// Cleanup is handled by owner references on the Deployment
+		// Cleanup is handled by owner references on the Deployment; an embedded listener loop is stopped here
+		r.Listeners.Stop(req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
 
+	// Resolve the Forgejo connection from the spec or the referenced ActOrg
+	conn, err := r.resolveForgejoConnection(ctx, actDeployment)
+	if err != nil {
+		log.Error(err, "failed to resolve ActOrg")
+		// Without the ActOrg's permission the namespace loses its token and listener; other errors are retried
+		if errors.Is(err, errActOrgWithdrawn) {
+			if err := r.withdrawActOrg(ctx, actDeployment); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		meta.SetStatusCondition(&actDeployment.Status.Conditions, metav1.Condition{
+			Type:               forgejoactionsiov1alpha1.ConditionActOrgResolved,
+			Status:             metav1.ConditionFalse,
+			Reason:             "ActOrgUnavailable",
+			Message:            err.Error(),
+			ObservedGeneration: actDeployment.Generation,
+		})
+		setDeploymentReadyCondition(actDeployment)
+		actDeployment.Status.ObservedGeneration = actDeployment.Generation
+		k8sutil.ObserveGeneration(actDeployment.Status.Conditions, actDeployment.Generation)
+		if statusErr := k8sutil.PatchStatus(ctx, r.Client, actDeployment, original); statusErr != nil {
+			return ctrl.Result{}, statusErr
+		}
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
+	// Track the runner image tag in its registry
+	r.reconcileRunnerImage(ctx, actDeployment)
+
+	// Air-gapped ActDeployments must not fall back to default images or pull from Docker Hub
+	if !r.checkAirGappedImages(actDeployment, conn) {
+		log.Info("air-gapped ActDeployment uses default or public images, not deploying listener")
+		setDeploymentReadyCondition(actDeployment)
+		actDeployment.Status.ObservedGeneration = actDeployment.Generation
+		k8sutil.ObserveGeneration(actDeployment.Status.Conditions, actDeployment.Generation)
+		if err := k8sutil.PatchStatus(ctx, r.Client, actDeployment, original); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
+	// Opt-in audit: don't deploy templates that would expose secret values
+	passed, err := r.auditSecrets(ctx, actDeployment, conn)
+	if err != nil {
+		log.Error(err, "failed to audit templates for secret values")
+		return ctrl.Result{}, err
+	}
+	if !passed {
+		log.Info("templates expose secret values, not deploying listener")
+		setDeploymentReadyCondition(actDeployment)
+		actDeployment.Status.ObservedGeneration = actDeployment.Generation
+		k8sutil.ObserveGeneration(actDeployment.Status.Conditions, actDeployment.Generation)
+		if err := k8sutil.PatchStatus(ctx, r.Client, actDeployment, original); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
 	// Get or create ServiceAccount for listener
 	log.Info("reconciling ServiceAccount for listener")
 	serviceAccount, err := r.reconcileServiceAccount(ctx, actDeployment)
@@ -87,14 +196,86 @@ func (r *ActDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 	log.Info("listener RBAC ready")
 
-	// Create or update listener Deployment
-	log.Info("reconciling listener Deployment")
-	deployment, err := r.reconcileListenerDeployment(ctx, actDeployment, serviceAccount.Name)
-	if err != nil {
-		log.Error(err, "failed to reconcile listener deployment")
+	if r.listenerMode(actDeployment) == forgejoactionsiov1alpha1.ListenerModeEmbedded {
+		// Run the listener loop in the manager instead of a listener Deployment
+		if err := r.reconcileEmbeddedListener(ctx, actDeployment, conn); err != nil {
+			log.Error(err, "failed to reconcile embedded listener")
+			return ctrl.Result{}, err
+		}
+	} else {
+		r.Listeners.Stop(req.NamespacedName)
+
+		// Create or update listener Deployment
+		log.Info("reconciling listener Deployment")
+		deployment, err := r.reconcileListenerDeployment(ctx, actDeployment, serviceAccount.Name, conn)
+		if err != nil {
+			log.Error(err, "failed to reconcile listener deployment")
+			return ctrl.Result{}, err
+		}
+		log.Info("listener Deployment ready", "name", deployment.Name)
+		actDeployment.Status.ListenerMode = forgejoactionsiov1alpha1.ListenerModeDedicated
+
+		// Reflect listener pod health (crash loops, image pull errors) in conditions and events
+		if err := r.reconcileListenerHealth(ctx, actDeployment, deployment); err != nil {
+			log.Error(err, "failed to inspect listener health")
+		}
+	}
+
+	// Detect a listener whose process runs but whose poll loop is stuck
+	if err := r.reconcileListenerHeartbeat(ctx, actDeployment, time.Now()); err != nil {
+		log.Error(err, "failed to check listener heartbeat")
+	}
+
+	// Create, update or remove the PodDisruptionBudget protecting runner pods
+	if err := r.reconcileRunnerPDB(ctx, actDeployment); err != nil {
+		log.Error(err, "failed to reconcile runner PodDisruptionBudget")
+		return ctrl.Result{}, err
+	}
+
+	// Create, update or remove the Service and ServiceMonitor exposing listener metrics
+	if err := r.reconcileListenerMetrics(ctx, actDeployment); err != nil {
+		log.Error(err, "failed to reconcile listener metrics")
+		return ctrl.Result{}, err
+	}
+
+	// Create, update or remove the capacity reservation placeholder Deployment for the node pool
+	if err := r.reconcileCapacityReservation(ctx, actDeployment); err != nil {
+		log.Error(err, "failed to reconcile capacity reservation")
+		return ctrl.Result{}, err
+	}
+
+	// Create, update or remove the CronJob pruning the shared cache volume
+	if err := r.reconcileCacheJanitor(ctx, actDeployment); err != nil {
+		log.Error(err, "failed to reconcile cache janitor")
+		return ctrl.Result{}, err
+	}
+
+	// Delete the workloads of finished runners retained past their ActRunner
+	if err := r.deleteExpiredRunnerWorkloads(ctx, actDeployment); err != nil {
+		log.Error(err, "failed to delete expired runner workloads")
+		return ctrl.Result{}, err
+	}
+
+	// Create, update or remove the runner ServiceAccount of the cloud identity
+	if err := r.reconcileCloudIdentity(ctx, actDeployment); err != nil {
+		log.Error(err, "failed to reconcile runner cloud identity")
+		return ctrl.Result{}, err
+	}
+
+	// Create, update or remove the Docker config generated for the runner pods
+	if err := r.reconcileDockerConfig(ctx, actDeployment); err != nil {
+		log.Error(err, "failed to reconcile runner Docker config")
 		return ctrl.Result{}, err
 	}
-	log.Info("listener Deployment ready", "name", deployment.Name)
+
+	// Create the PriorityClass of the runner pods
+	if err := r.reconcilePriorityClass(ctx, actDeployment); err != nil {
+		log.Error(err, "failed to reconcile runner PriorityClass")
+		return ctrl.Result{}, err
+	}
+
+	// Describe the node configuration of the dedicated node group
+	actDeployment.Status.DedicatedNodes = nodepool.DedicatedStatus(actDeployment)
 
 	// Count active ActRunners
 	activeCount, err := r.countActiveActRunners(ctx, actDeployment)
@@ -104,14 +285,68 @@ func (r *ActDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		actDeployment.Status.ActiveActRunners = activeCount
 	}
 
+	// Report how far changes to the runner configuration have rolled out
+	rolloutStatus, err := r.rolloutStatus(ctx, actDeployment, conn)
+	if err != nil {
+		log.Error(err, "failed to compute rollout status")
+	} else {
+		actDeployment.Status.Rollout = rolloutStatus
+	}
+
+	// Record how the runner pods currently render, and write the rendered pod to a ConfigMap on request
+	if err := r.reconcileRenderedRunnerPod(ctx, actDeployment, conn); err != nil {
+		log.Error(err, "failed to render runner pod")
+	}
+
+	// Surface maintenance windows as a condition so users can see why no runners are created
+	now := time.Now()
+	r.setMaintenanceCondition(actDeployment, now)
+
+	// Flip Degraded when the listener reports repeated poll failures
+	setDegradedCondition(actDeployment)
+
+	// Check that the token may do what the listener needs, so a token with the wrong scope shows up in a condition
+	r.reconcileTokenPermissions(ctx, actDeployment, conn)
+
+	// Suggest runner pod requests from the peak usage of finished runners
+	if err := r.reconcileResourceRecommendations(ctx, actDeployment, time.Now()); err != nil {
+		log.Error(err, "failed to update resource recommendations")
+	}
+
+	// Report queued jobs that no ActDeployment serves, if this ActDeployment is the organization's reporter
+	if err := r.reconcileUnservedJobs(ctx, actDeployment, conn); err != nil {
+		log.Error(err, "failed to report unserved jobs")
+	}
+
 	// Update status
-	actDeployment.Status.ListenerPodName = fmt.Sprintf("%s-0", deployment.Name) // Assuming single replica
+	setDeploymentReadyCondition(actDeployment)
 	actDeployment.Status.ObservedGeneration = actDeployment.Generation
-	if err := r.Status().Update(ctx, actDeployment); err != nil {
+	k8sutil.ObserveGeneration(actDeployment.Status.Conditions, actDeployment.Generation)
+	if err := k8sutil.PatchStatus(ctx, r.Client, actDeployment, original); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	return ctrl.Result{RequeueAfter: r.resyncAfter(actDeployment, conn, now)}, nil
+}
+
+// resyncInterval is the longest time between reconciles of an ActDeployment without a watch event
+const resyncInterval = 5 * time.Minute
+
+// resyncAfter returns when the ActDeployment is reconciled again without a watch event
+// Only the checks driven by time need it: the listener heartbeat, the tracked runner image, the token check and
+// the maintenance window condition
+func (r *ActDeploymentReconciler) resyncAfter(actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection, now time.Time) time.Duration {
+	// A wedged listener shows up once its heartbeat expired
+	after := min(resyncInterval, heartbeat.Duration(r.listenerPollInterval(actDeployment, conn)))
+	if source := actDeployment.Spec.RunnerImageFrom; source != nil && source.PollInterval != nil && source.PollInterval.Duration > 0 {
+		after = min(after, source.PollInterval.Duration)
+	}
+	// Flip the MaintenanceWindowActive condition when a window starts or ends, not up to a resync later;
+	// a second late, so the reconcile already sees the new state
+	if next := maintenance.NextTransition(actDeployment.Spec.MaintenanceWindows, now); !next.IsZero() {
+		after = min(after, next.Sub(now)+time.Second)
+	}
+	return after
 }
 
 func (r *ActDeploymentReconciler) reconcileServiceAccount(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) (*corev1.ServiceAccount, error) {
@@ -156,7 +391,7 @@ func (r *ActDeploymentReconciler) reconcileListenerRBAC(ctx context.Context, act
 	roleName := fmt.Sprintf("%s-listener", actDeployment.Name)
 	namespace := actDeployment.Namespace
 
-	// Create Role with necessary permissions for the listener
+	// Create Role with the permissions the enabled listener features need
 	role := &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      roleName,
@@ -171,23 +406,7 @@ func (r *ActDeploymentReconciler) reconcileListenerRBAC(ctx context.Context, act
 				},
 			},
 		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				APIGroups: []string{""},
-				Resources: []string{"secrets"},
-				Verbs:     []string{"get", "list", "create"},
-			},
-			{
-				APIGroups: []string{"forgejo.actions.io"},
-				Resources: []string{"actdeployments"},
-				Verbs:     []string{"get", "list", "watch"},
-			},
-			{
-				APIGroups: []string{"forgejo.actions.io"},
-				Resources: []string{"actrunners"},
-				Verbs:     []string{"create", "get", "list", "watch", "update", "patch"},
-			},
-		},
+		Rules: listenerRoleRules(actDeployment),
 	}
 
 	if err := ctrl.SetControllerReference(actDeployment, role, r.Scheme); err != nil {
@@ -270,13 +489,159 @@ func (r *ActDeploymentReconciler) reconcileListenerRBAC(ctx context.Context, act
 	return nil
 }
 
-func (r *ActDeploymentReconciler) reconcileListenerDeployment(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, serviceAccountName string) (*appsv1.Deployment, error) {
-	deploymentName := fmt.Sprintf("%s-listener", actDeployment.Name)
-	pollInterval := 10 * time.Second
-	if actDeployment.Spec.PollInterval != nil {
-		pollInterval = actDeployment.Spec.PollInterval.Duration
+// forgejoConnection is the effective Forgejo connection of an ActDeployment
+// It comes from the ActDeployment spec, or from the referenced ActOrg when ActOrgRef is set
+type forgejoConnection struct {
+	server          string
+	organization    string
+	tokenSecretName string
+
+	// Defaults provided by the ActOrg, applied when the ActDeployment doesn't set them
+	pollInterval        *metav1.Duration
+	runnerImage         string
+	dockerInDockerImage string
+}
+
+// errActOrgWithdrawn marks ActOrg resolution errors that withdraw the ActOrg's token from the ActDeployment:
+// the ActOrg or its token is gone, or it no longer allows the namespace
+var errActOrgWithdrawn = errors.New("ActOrg withdrawn")
+
+// resolveForgejoConnection determines the Forgejo connection of the ActDeployment
+// For ActOrg references, the ActOrg's token is copied into a Secret owned by the ActDeployment,
+// so the listener never needs access to the platform namespace holding the original token
+// Anyone who can read Secrets in the ActDeployment's namespace can read the copy
+func (r *ActDeploymentReconciler) resolveForgejoConnection(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) (*forgejoConnection, error) {
+	if actDeployment.Spec.ActOrgRef == nil || actDeployment.Spec.ActOrgRef.Name == "" {
+		meta.RemoveStatusCondition(&actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionActOrgResolved)
+		return &forgejoConnection{
+			server:          actDeployment.Spec.ForgejoServer,
+			organization:    actDeployment.Spec.Organization,
+			tokenSecretName: actDeployment.Spec.TokenSecretRef.Name,
+		}, nil
+	}
+
+	actOrg := &forgejoactionsiov1alpha1.ActOrg{}
+	if err := r.Get(ctx, types.NamespacedName{Name: actDeployment.Spec.ActOrgRef.Name}, actOrg); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: ActOrg %s not found", errActOrgWithdrawn, actDeployment.Spec.ActOrgRef.Name)
+		}
+		return nil, fmt.Errorf("failed to get ActOrg %s: %w", actDeployment.Spec.ActOrgRef.Name, err)
+	}
+
+	// The token is copied into the namespace, so only namespaces the ActOrg lists get it
+	if !slices.Contains(actOrg.Spec.AllowedNamespaces, "*") && !slices.Contains(actOrg.Spec.AllowedNamespaces, actDeployment.Namespace) {
+		return nil, fmt.Errorf("%w: ActOrg %s does not allow references from namespace %s", errActOrgWithdrawn, actOrg.Name, actDeployment.Namespace)
+	}
+
+	sourceNamespace := actOrg.Spec.TokenSecretRef.Namespace
+	if sourceNamespace == "" {
+		return nil, fmt.Errorf("%w: ActOrg %s tokenSecretRef has no namespace", errActOrgWithdrawn, actOrg.Name)
+	}
+	// The token usually lives in a platform namespace the manager's cache doesn't cover with --watch-namespaces
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	source := &corev1.Secret{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: sourceNamespace, Name: actOrg.Spec.TokenSecretRef.Name}, source); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: token secret %s/%s of ActOrg %s not found", errActOrgWithdrawn, sourceNamespace, actOrg.Spec.TokenSecretRef.Name, actOrg.Name)
+		}
+		return nil, fmt.Errorf("failed to get token secret %s/%s of ActOrg %s: %w", sourceNamespace, actOrg.Spec.TokenSecretRef.Name, actOrg.Name, err)
+	}
+	if len(source.Data["token"]) == 0 {
+		return nil, fmt.Errorf("%w: token secret %s/%s of ActOrg %s has no \"token\" key", errActOrgWithdrawn, sourceNamespace, source.Name, actOrg.Name)
 	}
 
+	// Copy the token into the ActDeployment's namespace
+	copyName := fmt.Sprintf("%s-org-token", actDeployment.Name)
+	tokenCopy := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      copyName,
+			Namespace: actDeployment.Namespace,
+			Labels: map[string]string{
+				"forgejo.actions.io/act-deployment": actDeployment.Name,
+				"forgejo.actions.io/act-org":        actOrg.Name,
+			},
+		},
+		Data: map[string][]byte{
+			"token": source.Data["token"],
+		},
+	}
+	if err := ctrl.SetControllerReference(actDeployment, tokenCopy, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	existing := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: copyName}, existing)
+	if err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		if err := r.Create(ctx, tokenCopy); err != nil {
+			return nil, fmt.Errorf("failed to create token secret %s: %w", copyName, err)
+		}
+	} else if !bytes.Equal(existing.Data["token"], source.Data["token"]) {
+		existing.Data = tokenCopy.Data
+		if err := r.Update(ctx, existing); err != nil {
+			return nil, fmt.Errorf("failed to update token secret %s: %w", copyName, err)
+		}
+	}
+
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionActOrgResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ActOrgResolved",
+		Message:            fmt.Sprintf("using Forgejo organization %s from ActOrg %s", actOrg.Spec.Organization, actOrg.Name),
+		ObservedGeneration: actDeployment.Generation,
+	})
+
+	return &forgejoConnection{
+		server:              actOrg.Spec.ForgejoServer,
+		organization:        actOrg.Spec.Organization,
+		tokenSecretName:     copyName,
+		pollInterval:        actOrg.Spec.PollInterval,
+		runnerImage:         actOrg.Spec.RunnerImage,
+		dockerInDockerImage: actOrg.Spec.DockerInDockerImage,
+	}, nil
+}
+
+// withdrawActOrg deletes the ActOrg token copy and the listener of an ActDeployment the ActOrg no longer serves
+// Existing runners keep their registration; the listener comes back once the ActOrg allows the namespace again
+func (r *ActDeploymentReconciler) withdrawActOrg(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	r.Listeners.Stop(client.ObjectKeyFromObject(actDeployment))
+
+	objects := []client.Object{
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: actDeployment.Namespace, Name: fmt.Sprintf("%s-org-token", actDeployment.Name)}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: actDeployment.Namespace, Name: fmt.Sprintf("%s-listener", actDeployment.Name)}},
+	}
+	for _, obj := range objects {
+		if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get %s: %w", obj.GetName(), err)
+		}
+		if !metav1.IsControlledBy(obj, actDeployment) {
+			continue
+		}
+		if err := client.IgnoreNotFound(r.Delete(ctx, obj)); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", obj.GetName(), err)
+		}
+		logf.FromContext(ctx).Info("deleted object of withdrawn ActOrg", "name", obj.GetName())
+	}
+	actDeployment.Status.ListenerPodName = ""
+	actDeployment.Status.ListenerPodReady = false
+	return nil
+}
+
+// specHashAnnotation records the hash of the Deployment spec the controller last applied
+const specHashAnnotation = "forgejo.actions.io/spec-hash"
+
+func (r *ActDeploymentReconciler) reconcileListenerDeployment(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, serviceAccountName string, conn *forgejoConnection) (*appsv1.Deployment, error) {
+	deploymentName := fmt.Sprintf("%s-listener", actDeployment.Name)
+	operatorConfig := r.Config.Get()
+
 	// Build pod template from spec or use defaults
 	podTemplate := actDeployment.Spec.ListenerTemplate.DeepCopy()
 	if podTemplate.Labels == nil {
@@ -287,55 +652,52 @@ func (r *ActDeploymentReconciler) reconcileListenerDeployment(ctx context.Contex
 
 	// Set default container if not specified
 	if len(podTemplate.Spec.Containers) == 0 {
-		// TODO: Set appropriate listener image (should be the controller image with listener binary)
 		podTemplate.Spec.Containers = []corev1.Container{
 			{
 				Name:    "listener",
-				Image:   "controller-image:latest", // Should be set to controller image
+				Image:   operatorConfig.ListenerImage,
 				Command: []string{"/listener"},
 			},
 		}
 	}
 
-	// Set environment variables
+	env, err := r.listenerEnv(actDeployment, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set environment variables, replacing any of the same name in the template
 	container := &podTemplate.Spec.Containers[0]
-	container.Env = append(container.Env,
-		corev1.EnvVar{
-			Name:  "FORGEJO_SERVER",
-			Value: actDeployment.Spec.ForgejoServer,
-		},
-		corev1.EnvVar{
-			Name:  "ORGANIZATION",
-			Value: actDeployment.Spec.Organization,
-		},
-		corev1.EnvVar{
-			Name:  "LABELS",
-			Value: actDeployment.Spec.Labels,
-		},
-		corev1.EnvVar{
-			Name:  "TOKEN_SECRET_NAME",
-			Value: actDeployment.Spec.TokenSecretRef.Name,
-		},
-		corev1.EnvVar{
-			Name:  "TOKEN_SECRET_KEY",
-			Value: "token", // Default key name in the secret
-		},
-		corev1.EnvVar{
-			Name:  "NAMESPACE",
-			Value: actDeployment.Namespace,
-		},
-		corev1.EnvVar{
-			Name:  "ACT_DEPLOYMENT_NAME",
-			Value: actDeployment.Name,
-		},
-		corev1.EnvVar{
-			Name:  "POLL_INTERVAL",
-			Value: pollInterval.String(),
-		},
-	)
+	container.Env = k8sutil.MergeEnv(container.Env, env...)
+
+	// Serve listener metrics on a named port the metrics Service targets
+	if actDeployment.Spec.Metrics != nil {
+		container.Env = k8sutil.MergeEnv(container.Env, corev1.EnvVar{Name: "METRICS_BIND_ADDRESS", Value: fmt.Sprintf(":%d", listenerMetricsPort)})
+		if !slices.ContainsFunc(container.Ports, func(p corev1.ContainerPort) bool { return p.Name == listenerMetricsPortName }) {
+			container.Ports = append(container.Ports, corev1.ContainerPort{
+				Name:          listenerMetricsPortName,
+				ContainerPort: listenerMetricsPort,
+				Protocol:      corev1.ProtocolTCP,
+			})
+		}
+	}
 
 	podTemplate.Spec.ServiceAccountName = serviceAccountName
 
+	// Record a hash of the effective configuration on the pod template
+	// A changed hash changes the template, which makes the Deployment roll out new listener pods
+	configHash, err := r.listenerConfigHash(ctx, actDeployment, conn.tokenSecretName, container.Env)
+	if err != nil {
+		return nil, err
+	}
+	if configHash != "" {
+		if podTemplate.Annotations == nil {
+			podTemplate.Annotations = make(map[string]string)
+		}
+		podTemplate.Annotations["forgejo.actions.io/config-hash"] = configHash
+	}
+	actDeployment.Status.ListenerConfigHash = configHash
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      deploymentName,
@@ -366,12 +728,21 @@ func (r *ActDeploymentReconciler) reconcileListenerDeployment(ctx context.Contex
 		},
 	}
 
+	// Record a hash of the desired spec on the Deployment
+	// The spec read back always differs from the desired one in the fields the API server defaults,
+	// so comparing the hashes is what tells a changed configuration from defaulting
+	specHash, err := deploymentSpecHash(&deployment.Spec)
+	if err != nil {
+		return nil, err
+	}
+	deployment.Annotations = map[string]string{specHashAnnotation: specHash}
+
 	if err := ctrl.SetControllerReference(actDeployment, deployment, r.Scheme); err != nil {
 		return nil, err
 	}
 
 	existing := &appsv1.Deployment{}
-	err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: deploymentName}, existing)
+	err = r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: deploymentName}, existing)
 	if err != nil {
 		if client.IgnoreNotFound(err) == nil {
 			// Create
@@ -383,8 +754,17 @@ func (r *ActDeploymentReconciler) reconcileListenerDeployment(ctx context.Contex
 		return nil, err
 	}
 
-	// Update if needed
+	// Update only when the desired spec changed since it was last applied
+	if existing.Annotations[specHashAnnotation] == specHash {
+		return existing, nil
+	}
+	log := logf.FromContext(ctx)
+	log.Info("updating listener Deployment", "name", deploymentName, "specHash", specHash, "previousSpecHash", existing.Annotations[specHashAnnotation])
 	existing.Spec = deployment.Spec
+	if existing.Annotations == nil {
+		existing.Annotations = make(map[string]string)
+	}
+	existing.Annotations[specHashAnnotation] = specHash
 	if err := r.Update(ctx, existing); err != nil {
 		return nil, err
 	}
@@ -392,6 +772,614 @@ func (r *ActDeploymentReconciler) reconcileListenerDeployment(ctx context.Contex
 	return existing, nil
 }
 
+// deploymentSpecHash hashes the desired spec of a Deployment
+func deploymentSpecHash(spec *appsv1.DeploymentSpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash Deployment spec: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
+// listenerPollInterval returns the poll interval of the listener, raised to the operator's minimum
+func (r *ActDeploymentReconciler) listenerPollInterval(actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) time.Duration {
+	pollInterval := 10 * time.Second
+	if actDeployment.Spec.PollInterval != nil {
+		pollInterval = actDeployment.Spec.PollInterval.Duration
+	} else if conn.pollInterval != nil {
+		pollInterval = conn.pollInterval.Duration
+	}
+	operatorConfig := r.Config.Get()
+	if operatorConfig.MinPollInterval != nil && pollInterval < operatorConfig.MinPollInterval.Duration {
+		pollInterval = operatorConfig.MinPollInterval.Duration
+	}
+	return pollInterval
+}
+
+// listenerEnv returns the environment variables configuring the listener of the ActDeployment
+func (r *ActDeploymentReconciler) listenerEnv(actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) ([]corev1.EnvVar, error) {
+	runnerLabels, err := runnerlabels.FromSpec(&actDeployment.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse runner labels: %w", err)
+	}
+
+	env := []corev1.EnvVar{
+		{Name: "FORGEJO_SERVER", Value: conn.server},
+		{Name: "ORGANIZATION", Value: conn.organization},
+		{Name: "LABELS", Value: runnerlabels.FormatAll(runnerLabels)},
+		{Name: "TOKEN_SECRET_NAME", Value: conn.tokenSecretName},
+		{Name: "TOKEN_SECRET_KEY", Value: listenerTokenSecretKey},
+		{Name: "NAMESPACE", Value: actDeployment.Namespace},
+		{Name: "ACT_DEPLOYMENT_NAME", Value: actDeployment.Name},
+		{Name: "POLL_INTERVAL", Value: r.listenerPollInterval(actDeployment, conn).String()},
+	}
+
+	// Pass ActOrg image defaults; the listener applies them when the ActDeployment doesn't set an image
+	if conn.runnerImage != "" {
+		env = append(env, corev1.EnvVar{Name: "DEFAULT_RUNNER_IMAGE", Value: conn.runnerImage})
+	}
+	if conn.dockerInDockerImage != "" {
+		env = append(env, corev1.EnvVar{Name: "DEFAULT_DIND_IMAGE", Value: conn.dockerInDockerImage})
+	}
+
+	// The listener loads the client certificate itself, like the token
+	if actDeployment.Spec.ClientCertSecretRef != nil && actDeployment.Spec.ClientCertSecretRef.Name != "" {
+		env = append(env, corev1.EnvVar{Name: "CLIENT_CERT_SECRET_NAME", Value: actDeployment.Spec.ClientCertSecretRef.Name})
+	}
+
+	// Pass extra API headers; secret values are resolved by the kubelet, so they never show up in the Deployment
+	env = append(env, apiHeaderEnv(actDeployment.Spec.ExtraAPIHeaders)...)
+
+	// Pass the operator-wide Forgejo API rate limit
+	if operatorConfig := r.Config.Get(); operatorConfig.ForgejoAPIQPS > 0 {
+		env = append(env,
+			corev1.EnvVar{Name: "FORGEJO_API_QPS", Value: strconv.FormatFloat(operatorConfig.ForgejoAPIQPS, 'f', -1, 64)},
+			corev1.EnvVar{Name: "FORGEJO_API_BURST", Value: strconv.Itoa(operatorConfig.ForgejoAPIBurst)},
+		)
+	}
+	return env, nil
+}
+
+// listenerConfigHash hashes the listener environment together with the token Secret's resourceVersion
+// Returns an empty hash when ListenerAutoRestart is disabled
+func (r *ActDeploymentReconciler) listenerConfigHash(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, tokenSecretName string, env []corev1.EnvVar) (string, error) {
+	if actDeployment.Spec.ListenerAutoRestart != nil && !*actDeployment.Spec.ListenerAutoRestart {
+		return "", nil
+	}
+
+	// The listener reads the token and client certificate only at startup, so rotating them needs a restart to take effect
+	tokenVersion, err := r.secretVersion(ctx, actDeployment.Namespace, tokenSecretName)
+	if err != nil {
+		return "", err
+	}
+	clientCertVersion := ""
+	if actDeployment.Spec.ClientCertSecretRef != nil && actDeployment.Spec.ClientCertSecretRef.Name != "" {
+		clientCertVersion, err = r.secretVersion(ctx, actDeployment.Namespace, actDeployment.Spec.ClientCertSecretRef.Name)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Environment variables from secrets are only resolved when the pod starts
+	var headerSecretVersions map[string]string
+	for _, header := range actDeployment.Spec.ExtraAPIHeaders {
+		if header.ValueFrom == nil {
+			continue
+		}
+		version, err := r.secretVersion(ctx, actDeployment.Namespace, header.ValueFrom.Name)
+		if err != nil {
+			return "", err
+		}
+		if headerSecretVersions == nil {
+			headerSecretVersions = map[string]string{}
+		}
+		headerSecretVersions[header.ValueFrom.Name] = version
+	}
+
+	data, err := json.Marshal(struct {
+		Env                  []corev1.EnvVar   `json:"env"`
+		TokenVersion         string            `json:"tokenVersion"`
+		ClientCertVersion    string            `json:"clientCertVersion,omitempty"`
+		HeaderSecretVersions map[string]string `json:"headerSecretVersions,omitempty"`
+	}{Env: env, TokenVersion: tokenVersion, ClientCertVersion: clientCertVersion, HeaderSecretVersions: headerSecretVersions})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
+// secretVersion returns the resource version of a Secret, or an empty string if it doesn't exist
+func (r *ActDeploymentReconciler) secretVersion(ctx context.Context, namespace, name string) (string, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+	return secret.ResourceVersion, nil
+}
+
+// listenerWaitingReasons are container waiting reasons that indicate a broken listener rather than a slow start
+var listenerWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// reconcileListenerHealth inspects the listener pods and sets the ListenerReady condition, restart count
+// and the name and readiness of the current listener pod
+// A Warning event is emitted when the listener becomes unhealthy, and a Normal event when it recovers
+func (r *ActDeploymentReconciler) reconcileListenerHealth(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, deployment *appsv1.Deployment) error {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(actDeployment.Namespace), client.MatchingLabels{
+		"app":                               "forgejo-listener",
+		"forgejo.actions.io/act-deployment": actDeployment.Name,
+	}); err != nil {
+		return err
+	}
+
+	condition := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionListenerReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             "ListenerPending",
+		Message:            "waiting for the listener pod to become available",
+		ObservedGeneration: actDeployment.Generation,
+	}
+
+	restarts := int32(0)
+	unhealthy := false
+	var current *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if isBetterListenerPod(pod, current) {
+			current = pod
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			restarts += cs.RestartCount
+			if unhealthy || cs.State.Waiting == nil || !listenerWaitingReasons[cs.State.Waiting.Reason] {
+				continue
+			}
+			unhealthy = true
+			condition.Reason = cs.State.Waiting.Reason
+			condition.Message = fmt.Sprintf("listener pod %s container %s: %s", pod.Name, cs.Name, cs.State.Waiting.Message)
+			if cs.LastTerminationState.Terminated != nil {
+				condition.Message = fmt.Sprintf("%s (last exit code %d, reason %s)", condition.Message,
+					cs.LastTerminationState.Terminated.ExitCode, cs.LastTerminationState.Terminated.Reason)
+			}
+		}
+	}
+
+	if !unhealthy && deployment.Status.AvailableReplicas > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "ListenerAvailable"
+		condition.Message = "the listener is running"
+	}
+
+	previous := meta.FindStatusCondition(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionListenerReady)
+	if unhealthy && (previous == nil || previous.Reason != condition.Reason) {
+		r.recordEvent(actDeployment, corev1.EventTypeWarning, "Listener"+condition.Reason, condition.Message)
+	}
+	if condition.Status == metav1.ConditionTrue && previous != nil && previous.Status == metav1.ConditionFalse && listenerWaitingReasons[previous.Reason] {
+		r.recordEvent(actDeployment, corev1.EventTypeNormal, "ListenerRecovered", "the listener is running again")
+	}
+
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, condition)
+	actDeployment.Status.ListenerRestarts = restarts
+	actDeployment.Status.ListenerPodName = ""
+	actDeployment.Status.ListenerPodReady = false
+	if current != nil {
+		actDeployment.Status.ListenerPodName = current.Name
+		actDeployment.Status.ListenerPodReady = isPodReady(current)
+	}
+	return nil
+}
+
+// isBetterListenerPod reports whether candidate should be reported as the listener pod instead of current
+// Ready pods win over unready ones; otherwise the newest pod wins (e.g., during a rollout)
+func isBetterListenerPod(candidate, current *corev1.Pod) bool {
+	if current == nil {
+		return true
+	}
+	if isPodReady(candidate) != isPodReady(current) {
+		return isPodReady(candidate)
+	}
+	return current.CreationTimestamp.Before(&candidate.CreationTimestamp)
+}
+
+// isPodReady reports whether the pod's Ready condition is True
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// recordEvent emits an event on the ActDeployment if an event recorder is configured
+func (r *ActDeploymentReconciler) recordEvent(actDeployment *forgejoactionsiov1alpha1.ActDeployment, eventType, reason, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Event(actDeployment, eventType, reason, message)
+}
+
+// reconcileRunnerPDB manages a PodDisruptionBudget with maxUnavailable=0 that selects the running runner pods
+// of the ActDeployment, so node drains cannot evict a runner while it executes a job
+// The PDB is deleted again when DisruptionProtection.PodDisruptionBudget is disabled
+func (r *ActDeploymentReconciler) reconcileRunnerPDB(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	pdbName := fmt.Sprintf("%s-runners", actDeployment.Name)
+	enabled := actDeployment.Spec.DisruptionProtection != nil && actDeployment.Spec.DisruptionProtection.PodDisruptionBudget
+
+	existing := &policyv1.PodDisruptionBudget{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: pdbName}, existing)
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	found := err == nil
+
+	if !enabled {
+		if found && metav1.IsControlledBy(existing, actDeployment) {
+			return client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return nil
+	}
+
+	maxUnavailable := intstr.FromInt32(0)
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pdbName,
+			Namespace: actDeployment.Namespace,
+			Labels: map[string]string{
+				"forgejo.actions.io/act-deployment": actDeployment.Name,
+			},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			// Runner pods carry the actrunner label, which distinguishes them from the listener pod
+			// Pending runner pods have no job to protect and would only block the drains of their nodes
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"forgejo.actions.io/act-deployment": actDeployment.Name,
+					runnerRunningLabel:                  "true",
+				},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "forgejo.actions.io/actrunner",
+						Operator: metav1.LabelSelectorOpExists,
+					},
+				},
+			},
+		},
+	}
+
+	if err := ctrl.SetControllerReference(actDeployment, pdb, r.Scheme); err != nil {
+		return err
+	}
+
+	if !found {
+		return r.Create(ctx, pdb)
+	}
+
+	// The API server defaults no PDB spec fields, so the specs compare equal in steady state
+	if equality.Semantic.DeepEqual(existing.Spec, pdb.Spec) {
+		return nil
+	}
+	existing.Spec = pdb.Spec
+	return r.Update(ctx, existing)
+}
+
+// reconcileCapacityReservation manages a Deployment of low-priority placeholder pods on the runner node pool
+// The placeholders keep nodes warm; runner pods preempt them and the autoscaler adds nodes for the evicted placeholders
+func (r *ActDeploymentReconciler) reconcileCapacityReservation(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	deploymentName := fmt.Sprintf("%s-capacity-reservation", actDeployment.Name)
+	var reservation *forgejoactionsiov1alpha1.CapacityReservationSpec
+	if actDeployment.Spec.NodePool != nil {
+		reservation = actDeployment.Spec.NodePool.CapacityReservation
+	}
+
+	existing := &appsv1.Deployment{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: deploymentName}, existing)
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	found := err == nil
+
+	if reservation == nil {
+		if found && metav1.IsControlledBy(existing, actDeployment) {
+			return client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return nil
+	}
+
+	image := reservation.Image
+	if image == "" {
+		image = "registry.k8s.io/pause:3.10"
+	}
+
+	labels := map[string]string{
+		"app":                               "forgejo-capacity-reservation",
+		"forgejo.actions.io/act-deployment": actDeployment.Name,
+	}
+
+	podTemplate := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: labels,
+		},
+		Spec: corev1.PodSpec{
+			PriorityClassName:             reservation.PriorityClassName,
+			TerminationGracePeriodSeconds: func() *int64 { i := int64(0); return &i }(),
+			Containers: []corev1.Container{
+				{
+					Name:      "placeholder",
+					Image:     image,
+					Resources: *reservation.Resources.DeepCopy(),
+				},
+			},
+		},
+	}
+	nodepool.ApplyToPodSpec(&podTemplate.Spec, actDeployment.Spec.NodePool)
+	nodepool.ApplyDedicated(&podTemplate.Spec, actDeployment)
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploymentName,
+			Namespace: actDeployment.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &reservation.Replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: podTemplate,
+		},
+	}
+
+	// Like the listener Deployment, the spec read back differs in defaulted fields, so the hash tells a change
+	specHash, err := deploymentSpecHash(&deployment.Spec)
+	if err != nil {
+		return err
+	}
+	deployment.Annotations = map[string]string{specHashAnnotation: specHash}
+
+	if err := ctrl.SetControllerReference(actDeployment, deployment, r.Scheme); err != nil {
+		return err
+	}
+
+	if !found {
+		return r.Create(ctx, deployment)
+	}
+
+	// Update only when the desired spec changed since it was last applied
+	if existing.Annotations[specHashAnnotation] == specHash {
+		return nil
+	}
+	existing.Spec = deployment.Spec
+	if existing.Annotations == nil {
+		existing.Annotations = make(map[string]string)
+	}
+	existing.Annotations[specHashAnnotation] = specHash
+	return r.Update(ctx, existing)
+}
+
+// readinessConditions are the conditions that must not be False for an ActDeployment to be Ready, in reporting order
+var readinessConditions = []string{
+	forgejoactionsiov1alpha1.ConditionActOrgResolved,
+	forgejoactionsiov1alpha1.ConditionTokenPermissionsValid,
+	forgejoactionsiov1alpha1.ConditionAirGappedImagesValid,
+	forgejoactionsiov1alpha1.ConditionSecretAuditPassed,
+	forgejoactionsiov1alpha1.ConditionListenerReady,
+	forgejoactionsiov1alpha1.ConditionListenerResponsive,
+}
+
+// setDeploymentReadyCondition summarizes the other conditions into Ready for GitOps health checks
+// The first condition that isn't True provides the reason and message; a missing ListenerReady means the listener is still starting
+func setDeploymentReadyCondition(actDeployment *forgejoactionsiov1alpha1.ActDeployment) {
+	ready := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ListenerAvailable",
+		Message:            "the listener is running",
+		ObservedGeneration: actDeployment.Generation,
+	}
+	for _, conditionType := range readinessConditions {
+		condition := meta.FindStatusCondition(actDeployment.Status.Conditions, conditionType)
+		if condition == nil {
+			if conditionType == forgejoactionsiov1alpha1.ConditionListenerReady {
+				ready.Status = metav1.ConditionFalse
+				ready.Reason = "ListenerPending"
+				ready.Message = "the listener has not been checked yet"
+				break
+			}
+			continue
+		}
+		if condition.Status != metav1.ConditionTrue {
+			ready.Status = metav1.ConditionFalse
+			ready.Reason = condition.Reason
+			ready.Message = condition.Message
+			break
+		}
+	}
+	if degraded := meta.FindStatusCondition(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionDegraded); ready.Status == metav1.ConditionTrue &&
+		degraded != nil && degraded.Status == metav1.ConditionTrue {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = degraded.Reason
+		ready.Message = degraded.Message
+	}
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, ready)
+}
+
+// checkAirGappedImages sets the AirGappedImagesValid condition and reports whether the listener may be deployed
+// Images are resolved the way the listener and ActRunner controller resolve them, minus the operator defaults
+func (r *ActDeploymentReconciler) checkAirGappedImages(actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) bool {
+	if actDeployment.Spec.AirGapped == nil {
+		meta.RemoveStatusCondition(&actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionAirGappedImagesValid)
+		return true
+	}
+
+	images := map[string]string{
+		"listener": "",
+		"runner":   actDeployment.Spec.RunnerImage,
+		"dind":     actDeployment.Spec.DockerInDockerImage,
+	}
+	if containers := actDeployment.Spec.ListenerTemplate.Spec.Containers; len(containers) > 0 {
+		images["listener"] = containers[0].Image
+	}
+	if source := actDeployment.Spec.RunnerImageFrom; source != nil {
+		images["runner"] = source.Image
+	}
+	if images["runner"] == "" {
+		images["runner"] = conn.runnerImage
+	}
+	if containers := actDeployment.Spec.RunnerTemplate.Spec.Containers; images["runner"] == "" && len(containers) > 0 {
+		images["runner"] = containers[0].Image
+	}
+	if images["dind"] == "" {
+		images["dind"] = conn.dockerInDockerImage
+	}
+
+	condition := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionAirGappedImagesValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ExplicitImages",
+		Message:            "all images are set explicitly and not pulled from Docker Hub",
+		ObservedGeneration: actDeployment.Generation,
+	}
+	if problems := airgap.ImageProblems(images); len(problems) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "DefaultOrPublicImage"
+		condition.Message = strings.Join(problems, "; ")
+	}
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, condition)
+	return condition.Status == metav1.ConditionTrue
+}
+
+// auditSecrets sets the SecretAuditPassed condition and reports whether the listener may be deployed
+// The templates are checked for the values of the token Secret and the header Secrets; the condition
+// and event only name the places, never the values
+func (r *ActDeploymentReconciler) auditSecrets(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) (bool, error) {
+	if !actDeployment.Spec.SecretAudit {
+		meta.RemoveStatusCondition(&actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionSecretAuditPassed)
+		return true, nil
+	}
+
+	var secrets []string
+	tokenSecret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: conn.tokenSecretName}, tokenSecret)
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		return false, fmt.Errorf("failed to get token secret: %w", err)
+	}
+	for _, value := range tokenSecret.Data {
+		secrets = append(secrets, strings.TrimSpace(string(value)))
+	}
+	for _, header := range actDeployment.Spec.ExtraAPIHeaders {
+		if header.ValueFrom == nil {
+			continue
+		}
+		headerSecret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: header.ValueFrom.Name}, headerSecret)
+		if err != nil && client.IgnoreNotFound(err) != nil {
+			return false, fmt.Errorf("failed to get secret %s for header %s: %w", header.ValueFrom.Name, header.Name, err)
+		}
+		secrets = append(secrets, strings.TrimSpace(string(headerSecret.Data[header.ValueFrom.Key])))
+	}
+
+	var leaks []string
+	for _, leak := range secretguard.FindInPodTemplate(&actDeployment.Spec.ListenerTemplate, secrets...) {
+		leaks = append(leaks, "listenerTemplate."+leak)
+	}
+	for _, leak := range secretguard.FindInPodTemplate(&actDeployment.Spec.RunnerTemplate, secrets...) {
+		leaks = append(leaks, "runnerTemplate."+leak)
+	}
+	leaks = append(leaks, secretguard.FindInMap("runnerPodLabels", actDeployment.Spec.RunnerPodLabels, secrets...)...)
+	leaks = append(leaks, secretguard.FindInMap("runnerPodAnnotations", actDeployment.Spec.RunnerPodAnnotations, secrets...)...)
+
+	condition := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionSecretAuditPassed,
+		Status:             metav1.ConditionTrue,
+		Reason:             "NoSecretsExposed",
+		Message:            "no secret values found in the listener and runner templates",
+		ObservedGeneration: actDeployment.Generation,
+	}
+	if len(leaks) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "SecretExposed"
+		condition.Message = "secret values found in " + strings.Join(leaks, ", ")
+		if !meta.IsStatusConditionFalse(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionSecretAuditPassed) {
+			r.recordEvent(actDeployment, corev1.EventTypeWarning, "SecretExposed", condition.Message)
+		}
+	}
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, condition)
+	return condition.Status == metav1.ConditionTrue, nil
+}
+
+// setMaintenanceCondition sets the MaintenanceWindowActive condition from the configured maintenance windows
+func (r *ActDeploymentReconciler) setMaintenanceCondition(actDeployment *forgejoactionsiov1alpha1.ActDeployment, now time.Time) {
+	if len(actDeployment.Spec.MaintenanceWindows) == 0 {
+		meta.RemoveStatusCondition(&actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionMaintenanceWindowActive)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionMaintenanceWindowActive,
+		Status:             metav1.ConditionFalse,
+		Reason:             "NoActiveWindow",
+		Message:            "no maintenance window is active, runners are created for pending jobs",
+		ObservedGeneration: actDeployment.Generation,
+	}
+
+	active, err := maintenance.Active(actDeployment.Spec.MaintenanceWindows, now)
+	switch {
+	case active != nil:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "InMaintenanceWindow"
+		condition.Message = fmt.Sprintf("maintenance window %q is active until %s, no new runners are created",
+			active.Window.Schedule, active.End.UTC().Format(time.RFC3339))
+	case err != nil:
+		condition.Reason = "InvalidMaintenanceWindow"
+		condition.Message = err.Error()
+	}
+
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, condition)
+}
+
+// setDegradedCondition sets the Degraded condition from the poll failures the listener reports
+// The condition is left out until the listener reported a poll
+func setDegradedCondition(actDeployment *forgejoactionsiov1alpha1.ActDeployment) {
+	if actDeployment.Status.LastPollTime == nil && actDeployment.Status.ConsecutivePollFailures == 0 {
+		meta.RemoveStatusCondition(&actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionDegraded)
+		return
+	}
+
+	threshold := int32(3)
+	if actDeployment.Spec.PollFailureThreshold != nil {
+		threshold = *actDeployment.Spec.PollFailureThreshold
+	}
+	condition := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             "PollsSucceeding",
+		Message:            "the listener polls Forgejo successfully",
+		ObservedGeneration: actDeployment.Generation,
+	}
+	if failures := actDeployment.Status.ConsecutivePollFailures; failures >= threshold {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "PollsFailing"
+		condition.Message = fmt.Sprintf("%d listener polls failed in a row: %s", failures, actDeployment.Status.LastPollError)
+	} else if failures > 0 {
+		condition.Reason = "PollsFailingIntermittently"
+		condition.Message = fmt.Sprintf("%d listener polls failed in a row, below the threshold of %d", failures, threshold)
+	}
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, condition)
+}
+
 func (r *ActDeploymentReconciler) countActiveActRunners(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) (int32, error) {
 	actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
 	if err := r.List(ctx, actRunners, client.InNamespace(actDeployment.Namespace)); err != nil {
@@ -403,9 +1391,8 @@ func (r *ActDeploymentReconciler) countActiveActRunners(ctx context.Context, act
 		// Check if owned by this ActDeployment
 		for _, ref := range ar.OwnerReferences {
 			if ref.UID == actDeployment.UID {
-				// Count as active if not succeeded or failed
-				if ar.Status.Phase != forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded &&
-					ar.Status.Phase != forgejoactionsiov1alpha1.ActRunnerPhaseFailed {
+				// Count as active if not finished
+				if !runnerphase.Finished(ar.Status.Phase) {
 					count++
 				}
 				break
@@ -419,7 +1406,57 @@ func (r *ActDeploymentReconciler) countActiveActRunners(ctx context.Context, act
 // SetupWithManager sets up the controller with the Manager.
 func (r *ActDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&forgejoactionsiov1alpha1.ActDeployment{}).
+		// Status writes don't trigger a reconcile, except the poll failures the listener reports;
+		// annotation changes do, since they request token and runner image checks
+		For(&forgejoactionsiov1alpha1.ActDeployment{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+			pollHealthChangedPredicate(),
+		))).
+		// Changes to the listener Deployment and its RBAC are reconciled right away
+		Owns(&appsv1.Deployment{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Watches(&forgejoactionsiov1alpha1.ActOrg{}, handler.EnqueueRequestsFromMapFunc(r.actDeploymentsForActOrg)).
 		Named("actdeployment").
 		Complete(r)
 }
+
+// pollHealthChangedPredicate passes updates that change the poll failures the listener reports,
+// so the Degraded condition follows them without waiting for a resync
+func pollHealthChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldActDeployment, ok := e.ObjectOld.(*forgejoactionsiov1alpha1.ActDeployment)
+			if !ok {
+				return false
+			}
+			newActDeployment, ok := e.ObjectNew.(*forgejoactionsiov1alpha1.ActDeployment)
+			if !ok {
+				return false
+			}
+			return oldActDeployment.Status.ConsecutivePollFailures != newActDeployment.Status.ConsecutivePollFailures ||
+				oldActDeployment.Status.LastPollError != newActDeployment.Status.LastPollError
+		},
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
+
+// actDeploymentsForActOrg maps an ActOrg to the ActDeployments referencing it
+func (r *ActDeploymentReconciler) actDeploymentsForActOrg(ctx context.Context, obj client.Object) []reconcile.Request {
+	actDeployments := &forgejoactionsiov1alpha1.ActDeploymentList{}
+	if err := r.List(ctx, actDeployments); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list ActDeployments for ActOrg", "actOrg", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, actDeployment := range actDeployments.Items {
+		if actDeployment.Spec.ActOrgRef != nil && actDeployment.Spec.ActOrgRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&actDeployment)})
+		}
+	}
+	return requests
+}
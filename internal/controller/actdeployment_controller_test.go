@@ -18,15 +18,26 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
 )
 
 var _ = Describe("ActDeployment Controller", func() {
@@ -81,3 +92,419 @@ var _ = Describe("ActDeployment Controller", func() {
 		})
 	})
 })
+
+var _ = Describe("Listener Deployment", func() {
+	const (
+		namespace      = "default"
+		deploymentName = "listener-drift"
+	)
+
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: namespace, Name: deploymentName}
+	listenerKey := types.NamespacedName{Namespace: namespace, Name: deploymentName + "-listener"}
+
+	var server *fake.Server
+
+	BeforeEach(func() {
+		server = fake.NewServer()
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "listener-drift-token", Namespace: namespace},
+			Data:       map[string][]byte{"token": []byte("token")},
+		})).To(Succeed())
+		Expect(k8sClient.Create(ctx, &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: deploymentName, Namespace: namespace},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:       server.URL(),
+				Organization:        "org",
+				Labels:              "docker",
+				TokenSecretRef:      corev1.SecretReference{Name: "listener-drift-token"},
+				RunnerImage:         "runner:test",
+				DockerInDockerImage: "dind:test",
+			},
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: deploymentName, Namespace: namespace},
+		}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "listener-drift-token", Namespace: namespace},
+		}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: listenerKey.Name, Namespace: namespace},
+		}))).To(Succeed())
+	})
+
+	It("is only updated when its configuration changes", func() {
+		reconciler := &ActDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		deployment := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, listenerKey, deployment)).To(Succeed())
+		Expect(deployment.Annotations).To(HaveKey(specHashAnnotation))
+		resourceVersion := deployment.ResourceVersion
+
+		By("reconciling an unchanged ActDeployment")
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, listenerKey, deployment)).To(Succeed())
+		Expect(deployment.ResourceVersion).To(Equal(resourceVersion))
+
+		By("changing the poll interval")
+		actDeployment := &forgejoactionsiov1alpha1.ActDeployment{}
+		Expect(k8sClient.Get(ctx, key, actDeployment)).To(Succeed())
+		actDeployment.Spec.PollInterval = &metav1.Duration{Duration: 30 * time.Second}
+		Expect(k8sClient.Update(ctx, actDeployment)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, listenerKey, deployment)).To(Succeed())
+		Expect(deployment.ResourceVersion).NotTo(Equal(resourceVersion))
+		Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "POLL_INTERVAL", Value: "30s"}))
+	})
+})
+
+var _ = Describe("Runner network validation", func() {
+	const namespace = "default"
+
+	ctx := context.Background()
+
+	DescribeTable("validates spec.network against the rest of the ActDeployment",
+		func(mutate func(*forgejoactionsiov1alpha1.ActDeploymentSpec), message string) {
+			actDeployment := &forgejoactionsiov1alpha1.ActDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "network-validation", Namespace: namespace},
+				Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+					ForgejoServer:  "https://forgejo.example.com",
+					Organization:   "org",
+					Labels:         "docker",
+					TokenSecretRef: corev1.SecretReference{Name: "token"},
+					Network:        &forgejoactionsiov1alpha1.RunnerNetworkSpec{},
+				},
+			}
+			mutate(&actDeployment.Spec)
+
+			err := k8sClient.Create(ctx, actDeployment)
+			if message == "" {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Delete(ctx, actDeployment)).To(Succeed())
+				return
+			}
+			Expect(errors.IsInvalid(err)).To(BeTrue(), "expected an invalid error, got %v", err)
+			Expect(err.Error()).To(ContainSubstring(message))
+		},
+		Entry("accepts the host network", func(spec *forgejoactionsiov1alpha1.ActDeploymentSpec) {
+			spec.Network.HostNetwork = true
+			spec.Network.MTU = 1450
+		}, ""),
+		Entry("accepts a fixed IPv6 subnet with IPv6", func(spec *forgejoactionsiov1alpha1.ActDeploymentSpec) {
+			spec.Network.IPv6 = true
+			spec.Network.FixedCIDRv6 = "fd00:dead:beef::/64"
+		}, ""),
+		Entry("rejects a fixed IPv6 subnet without IPv6", func(spec *forgejoactionsiov1alpha1.ActDeploymentSpec) {
+			spec.Network.FixedCIDRv6 = "fd00:dead:beef::/64"
+		}, "fixedCIDRv6 requires ipv6"),
+		Entry("accepts a DinD TCP port off the host network", func(spec *forgejoactionsiov1alpha1.ActDeploymentSpec) {
+			spec.Network.DinDTCPPort = 2375
+		}, ""),
+		Entry("rejects a DinD TCP port on the host network", func(spec *forgejoactionsiov1alpha1.ActDeploymentSpec) {
+			spec.Network.HostNetwork = true
+			spec.Network.DinDTCPPort = 2375
+		}, "dindTCPPort would expose the DinD daemon on the node with hostNetwork"),
+		Entry("accepts Istio off the host network", func(spec *forgejoactionsiov1alpha1.ActDeploymentSpec) {
+			spec.Mesh = &forgejoactionsiov1alpha1.MeshSpec{Istio: true}
+		}, ""),
+		Entry("rejects Istio on the host network", func(spec *forgejoactionsiov1alpha1.ActDeploymentSpec) {
+			spec.Network.HostNetwork = true
+			spec.Mesh = &forgejoactionsiov1alpha1.MeshSpec{Istio: true}
+		}, "network.hostNetwork is not supported with mesh.istio"),
+		Entry("rejects sysbox on the host network", func(spec *forgejoactionsiov1alpha1.ActDeploymentSpec) {
+			spec.Network.HostNetwork = true
+			spec.SecurityProfile = forgejoactionsiov1alpha1.SecurityProfileSysbox
+		}, "network.hostNetwork is not supported with the sysbox securityProfile"),
+	)
+})
+
+var _ = Describe("Listener mode", func() {
+	withListenerEnv := func(env ...corev1.EnvVar) *forgejoactionsiov1alpha1.ActDeployment {
+		actDeployment := &forgejoactionsiov1alpha1.ActDeployment{}
+		actDeployment.Spec.ListenerTemplate.Spec.Containers = []corev1.Container{{Name: "listener", Env: env}}
+		return actDeployment
+	}
+
+	It("embeds listeners whose template only sets settings the embedded loop applies", func() {
+		reconciler := &ActDeploymentReconciler{Listeners: &EmbeddedListeners{}}
+		actDeployment := withListenerEnv(
+			corev1.EnvVar{Name: "SKIP_TLS_VERIFY", Value: "true"},
+			corev1.EnvVar{Name: "JOB_BUS_URL", Value: "nats://nats:4222/jobs"},
+		)
+		Expect(reconciler.listenerMode(actDeployment)).To(Equal(forgejoactionsiov1alpha1.ListenerModeEmbedded))
+	})
+
+	It("deploys a dedicated listener for templates with job claims or values from other sources", func() {
+		reconciler := &ActDeploymentReconciler{Listeners: &EmbeddedListeners{}}
+		Expect(reconciler.listenerMode(withListenerEnv(corev1.EnvVar{Name: "CLAIM_NAMESPACE", Value: "claims"}))).
+			To(Equal(forgejoactionsiov1alpha1.ListenerModeDedicated))
+
+		fromSecret := &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "nats"}, Key: "url",
+		}}
+		actDeployment := withListenerEnv(corev1.EnvVar{Name: "JOB_BUS_URL", ValueFrom: fromSecret})
+		actDeployment.Spec.ListenerMode = forgejoactionsiov1alpha1.ListenerModeEmbedded
+		Expect(reconciler.listenerMode(actDeployment)).To(Equal(forgejoactionsiov1alpha1.ListenerModeDedicated))
+	})
+})
+
+var _ = Describe("ActOrg withdrawal", func() {
+	const namespace = "runners"
+
+	var (
+		ctx           context.Context
+		c             client.Client
+		reconciler    *ActDeploymentReconciler
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithStatusSubresource(&forgejoactionsiov1alpha1.ActDeployment{}).Build()
+		reconciler = &ActDeploymentReconciler{Client: c, Scheme: scheme.Scheme}
+
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "org-runners", Namespace: namespace},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ActOrgRef: &corev1.LocalObjectReference{Name: "platform"},
+				Labels:    "docker",
+			},
+		}
+		Expect(c.Create(ctx, actDeployment)).To(Succeed())
+
+		// The token copy and listener a reconcile left behind while the ActOrg still allowed the namespace
+		owned := func(obj client.Object) client.Object {
+			Expect(controllerutil.SetControllerReference(actDeployment, obj, scheme.Scheme)).To(Succeed())
+			return obj
+		}
+		Expect(c.Create(ctx, owned(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "org-runners-org-token", Namespace: namespace},
+			Data:       map[string][]byte{"token": []byte("org-token")},
+		}))).To(Succeed())
+		Expect(c.Create(ctx, owned(&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "org-runners-listener", Namespace: namespace},
+		}))).To(Succeed())
+	})
+
+	createActOrg := func(allowedNamespaces ...string) {
+		Expect(c.Create(ctx, &forgejoactionsiov1alpha1.ActOrg{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+			Spec: forgejoactionsiov1alpha1.ActOrgSpec{
+				ForgejoServer:     "https://forgejo.example.com",
+				Organization:      "platform",
+				TokenSecretRef:    corev1.SecretReference{Name: "forgejo-token", Namespace: "platform-system"},
+				AllowedNamespaces: allowedNamespaces,
+			},
+		})).To(Succeed())
+	}
+
+	// expectWithdrawn reconciles and expects the token copy and listener to be gone and the ActOrg to be unresolved
+	expectWithdrawn := func() {
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(actDeployment)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+		err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "org-runners-org-token"}, &corev1.Secret{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+		err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "org-runners-listener"}, &appsv1.Deployment{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(actDeployment), actDeployment)).To(Succeed())
+		Expect(meta.IsStatusConditionFalse(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionActOrgResolved)).To(BeTrue())
+	}
+
+	It("removes the token copy and listener when the ActOrg is deleted", func() {
+		expectWithdrawn()
+	})
+
+	It("removes the token copy and listener when the ActOrg no longer allows the namespace", func() {
+		createActOrg("other-team")
+		expectWithdrawn()
+	})
+
+	It("reads the ActOrg token through the API reader, outside the watched namespaces", func() {
+		createActOrg(namespace)
+		reconciler.APIReader = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "forgejo-token", Namespace: "platform-system"},
+			Data:       map[string][]byte{"token": []byte("rotated-token")},
+		}).Build()
+
+		conn, err := reconciler.resolveForgejoConnection(ctx, actDeployment)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conn.tokenSecretName).To(Equal("org-runners-org-token"))
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: conn.tokenSecretName}, secret)).To(Succeed())
+		Expect(string(secret.Data["token"])).To(Equal("rotated-token"))
+	})
+})
+
+var _ = Describe("Capacity reservation", func() {
+	const namespace = "runners"
+
+	var (
+		ctx           context.Context
+		c             client.Client
+		reconciler    *ActDeploymentReconciler
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+	)
+	key := types.NamespacedName{Namespace: namespace, Name: "gpu-capacity-reservation"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		reconciler = &ActDeploymentReconciler{Client: c, Scheme: scheme.Scheme}
+
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "gpu", Namespace: namespace, UID: "gpu-uid"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				Labels: "gpu",
+				NodePool: &forgejoactionsiov1alpha1.NodePoolSpec{
+					Provider:            forgejoactionsiov1alpha1.NodePoolProviderKarpenter,
+					Name:                "gpu",
+					CapacityReservation: &forgejoactionsiov1alpha1.CapacityReservationSpec{Replicas: 1},
+				},
+			},
+		}
+	})
+
+	It("is only updated when the reservation changes", func() {
+		Expect(reconciler.reconcileCapacityReservation(ctx, actDeployment)).To(Succeed())
+		deployment := &appsv1.Deployment{}
+		Expect(c.Get(ctx, key, deployment)).To(Succeed())
+		Expect(deployment.Annotations).To(HaveKey(specHashAnnotation))
+		Expect(deployment.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue("karpenter.sh/nodepool", "gpu"))
+		resourceVersion := deployment.ResourceVersion
+
+		By("reconciling an unchanged reservation")
+		Expect(reconciler.reconcileCapacityReservation(ctx, actDeployment)).To(Succeed())
+		Expect(c.Get(ctx, key, deployment)).To(Succeed())
+		Expect(deployment.ResourceVersion).To(Equal(resourceVersion))
+
+		By("scaling the reservation")
+		actDeployment.Spec.NodePool.CapacityReservation.Replicas = 3
+		Expect(reconciler.reconcileCapacityReservation(ctx, actDeployment)).To(Succeed())
+		Expect(c.Get(ctx, key, deployment)).To(Succeed())
+		Expect(deployment.ResourceVersion).NotTo(Equal(resourceVersion))
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(3)))
+	})
+
+	It("is deleted when the reservation is removed", func() {
+		Expect(reconciler.reconcileCapacityReservation(ctx, actDeployment)).To(Succeed())
+		actDeployment.Spec.NodePool.CapacityReservation = nil
+		Expect(reconciler.reconcileCapacityReservation(ctx, actDeployment)).To(Succeed())
+		Expect(errors.IsNotFound(c.Get(ctx, key, &appsv1.Deployment{}))).To(BeTrue())
+	})
+})
+
+var _ = Describe("Runner PodDisruptionBudget", func() {
+	const namespace = "runners"
+
+	var (
+		ctx           context.Context
+		c             client.Client
+		reconciler    *ActDeploymentReconciler
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+	)
+	key := types.NamespacedName{Namespace: namespace, Name: "protected-runners"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		reconciler = &ActDeploymentReconciler{Client: c, Scheme: scheme.Scheme}
+
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "protected", Namespace: namespace, UID: "protected-uid"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				Labels:               "docker",
+				DisruptionProtection: &forgejoactionsiov1alpha1.DisruptionProtectionSpec{PodDisruptionBudget: true},
+			},
+		}
+	})
+
+	It("is not written again while it is up to date", func() {
+		Expect(reconciler.reconcileRunnerPDB(ctx, actDeployment)).To(Succeed())
+		pdb := &policyv1.PodDisruptionBudget{}
+		Expect(c.Get(ctx, key, pdb)).To(Succeed())
+		Expect(pdb.Spec.MaxUnavailable.IntValue()).To(Equal(0))
+		resourceVersion := pdb.ResourceVersion
+
+		Expect(reconciler.reconcileRunnerPDB(ctx, actDeployment)).To(Succeed())
+		Expect(c.Get(ctx, key, pdb)).To(Succeed())
+		Expect(pdb.ResourceVersion).To(Equal(resourceVersion))
+
+		By("restoring a spec changed by hand")
+		pdb.Spec.MaxUnavailable = nil
+		Expect(c.Update(ctx, pdb)).To(Succeed())
+		Expect(reconciler.reconcileRunnerPDB(ctx, actDeployment)).To(Succeed())
+		Expect(c.Get(ctx, key, pdb)).To(Succeed())
+		Expect(pdb.Spec.MaxUnavailable).NotTo(BeNil())
+	})
+
+	It("only selects the running runner pods of the ActDeployment", func() {
+		Expect(reconciler.reconcileRunnerPDB(ctx, actDeployment)).To(Succeed())
+		pdb := &policyv1.PodDisruptionBudget{}
+		Expect(c.Get(ctx, key, pdb)).To(Succeed())
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		Expect(err).NotTo(HaveOccurred())
+
+		runnerPod := labels.Set{
+			"forgejo.actions.io/act-deployment": "protected",
+			"forgejo.actions.io/actrunner":      "runner-1",
+			runnerRunningLabel:                  "true",
+		}
+		Expect(selector.Matches(runnerPod)).To(BeTrue())
+
+		pendingPod := labels.Set{"forgejo.actions.io/act-deployment": "protected", "forgejo.actions.io/actrunner": "runner-2"}
+		Expect(selector.Matches(pendingPod)).To(BeFalse())
+
+		listenerPod := labels.Set{"forgejo.actions.io/act-deployment": "protected", runnerRunningLabel: "true"}
+		Expect(selector.Matches(listenerPod)).To(BeFalse())
+
+		otherRunnerPod := labels.Set{
+			"forgejo.actions.io/act-deployment": "other",
+			"forgejo.actions.io/actrunner":      "runner-3",
+			runnerRunningLabel:                  "true",
+		}
+		Expect(selector.Matches(otherRunnerPod)).To(BeFalse())
+	})
+
+	It("is deleted when disruption protection is turned off", func() {
+		Expect(reconciler.reconcileRunnerPDB(ctx, actDeployment)).To(Succeed())
+		actDeployment.Spec.DisruptionProtection = nil
+		Expect(reconciler.reconcileRunnerPDB(ctx, actDeployment)).To(Succeed())
+		Expect(errors.IsNotFound(c.Get(ctx, key, &policyv1.PodDisruptionBudget{}))).To(BeTrue())
+	})
+})
+
+var _ = Describe("Resync", func() {
+	// Sunday 02:00 UTC for two hours
+	sundayNight := forgejoactionsiov1alpha1.MaintenanceWindow{
+		Schedule: "0 2 * * SUN",
+		Duration: metav1.Duration{Duration: 2 * time.Hour},
+	}
+
+	DescribeTable("is due by the next maintenance window transition",
+		func(now time.Time, after time.Duration) {
+			reconciler := &ActDeploymentReconciler{}
+			actDeployment := &forgejoactionsiov1alpha1.ActDeployment{
+				Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+					MaintenanceWindows: []forgejoactionsiov1alpha1.MaintenanceWindow{sundayNight},
+				},
+			}
+			Expect(reconciler.resyncAfter(actDeployment, &forgejoConnection{}, now)).To(Equal(after))
+		},
+		Entry("long before a window, at the listener heartbeat", time.Date(2025, 5, 31, 12, 0, 0, 0, time.UTC), time.Minute),
+		Entry("shortly before a window starts", time.Date(2025, 6, 1, 1, 59, 50, 0, time.UTC), 11*time.Second),
+		Entry("shortly before a window ends", time.Date(2025, 6, 1, 3, 59, 30, 0, time.UTC), 31*time.Second),
+	)
+})
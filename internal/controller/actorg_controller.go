@@ -0,0 +1,107 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/k8sutil"
+)
+
+// ActOrgReconciler reconciles an ActOrg object
+// ActDeployments resolve their ActOrg themselves; this controller only reports the ActOrg's health
+type ActOrgReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=forgejo.actions.io,resources=actorgs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=forgejo.actions.io,resources=actorgs/status,verbs=get;update;patch
+
+// Reconcile checks the ActOrg's token Secret and counts the ActDeployments referencing it
+func (r *ActOrgReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	actOrg := &forgejoactionsiov1alpha1.ActOrg{}
+	if err := r.Get(ctx, req.NamespacedName, actOrg); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	original := actOrg.DeepCopy()
+
+	actDeployments := &forgejoactionsiov1alpha1.ActDeploymentList{}
+	if err := r.List(ctx, actDeployments); err != nil {
+		return ctrl.Result{}, err
+	}
+	referencing := int32(0)
+	for _, ad := range actDeployments.Items {
+		if ad.Spec.ActOrgRef != nil && ad.Spec.ActOrgRef.Name == actOrg.Name {
+			referencing++
+		}
+	}
+	actOrg.Status.ReferencingDeployments = referencing
+
+	condition := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "TokenAvailable",
+		Message:            "the token secret is available",
+		ObservedGeneration: actOrg.Generation,
+	}
+	secret := &corev1.Secret{}
+	secretKey := types.NamespacedName{Namespace: actOrg.Spec.TokenSecretRef.Namespace, Name: actOrg.Spec.TokenSecretRef.Name}
+	if err := r.Get(ctx, secretKey, secret); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "TokenSecretNotFound"
+		condition.Message = fmt.Sprintf("token secret %s not found", secretKey)
+	} else if len(secret.Data["token"]) == 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "TokenMissing"
+		condition.Message = fmt.Sprintf("token secret %s has no \"token\" key", secretKey)
+	}
+	meta.SetStatusCondition(&actOrg.Status.Conditions, condition)
+	actOrg.Status.ObservedGeneration = actOrg.Generation
+
+	if err := k8sutil.PatchStatus(ctx, r.Client, actOrg, original); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("reconciled ActOrg", "name", actOrg.Name, "referencingDeployments", referencing, "ready", condition.Status)
+	return ctrl.Result{RequeueAfter: time.Minute}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ActOrgReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&forgejoactionsiov1alpha1.ActOrg{}).
+		Named("actorg").
+		Complete(r)
+}
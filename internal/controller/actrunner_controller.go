@@ -18,33 +18,57 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/cloudevents"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/k8sutil"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/operatorconfig"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/podbuilder"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/podcompliance"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/rollout"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerphase"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/secretguard"
 )
 
 // ActRunnerReconciler reconciles an ActRunner object
 type ActRunnerReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	Config   *operatorconfig.Store
+
+	// Events publishes the runner lifecycle as CloudEvents; nil disables publishing
+	Events cloudevents.Publisher
+
+	// Clientset reads runner pod logs for the log archive; nil disables archiving
+	Clientset kubernetes.Interface
 }
 
 // +kubebuilder:rbac:groups=forgejo.actions.io,resources=actrunners,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=forgejo.actions.io,resources=actrunners/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=forgejo.actions.io,resources=actrunners/finalizers,verbs=update
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachineinstances,verbs=get;list;watch;create;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop
 func (r *ActRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -54,6 +78,7 @@ func (r *ActRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	if err := r.Get(ctx, req.NamespacedName, actRunner); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	original := actRunner.DeepCopy()
 
 	// Handle deletion - clean up registration token secret
 	if !actRunner.DeletionTimestamp.IsZero() {
@@ -64,45 +89,221 @@ func (r *ActRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
-	// Determine current phase based on Kubernetes Pod status
+	// Determine current phase based on the status of the pod run by the backend
+	backend := r.runnerBackend(actRunner)
 	var k8sPod *corev1.Pod
 	if actRunner.Status.KubernetesJobName != "" {
-		k8sPod = &corev1.Pod{}
-		if err := r.Get(ctx, client.ObjectKey{Namespace: actRunner.Namespace, Name: actRunner.Status.KubernetesJobName}, k8sPod); err != nil {
-			if client.IgnoreNotFound(err) == nil {
-				// Pod was deleted, reset status
+		pod, err := backend.Pod(ctx, actRunner, actRunner.Status.KubernetesJobName)
+		if err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return ctrl.Result{}, err
+			}
+			switch {
+			case actRunner.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhaseTerminating:
+				// The workload was deleted before the runner finished
+				log.Info("runner workload was deleted before the runner finished, cancelling runner", "actRunner", actRunner.Name, "workload", actRunner.Status.KubernetesJobName)
+				actRunner.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhaseCancelled
+				if actRunner.Status.CompletedAt == nil {
+					now := metav1.Now()
+					actRunner.Status.CompletedAt = &now
+				}
+				meta.SetStatusCondition(&actRunner.Status.Conditions, runnerReadyCondition(actRunner))
+				if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
+					return ctrl.Result{}, err
+				}
+				r.publishRunnerEvent(ctx, actRunner, cloudevents.TypeRunnerCancelled)
+				return ctrl.Result{}, nil
+			case !runnerphase.Finished(actRunner.Status.Phase):
+				// Workload was deleted, reset status
 				actRunner.Status.KubernetesJobName = ""
 				actRunner.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhasePending
-				if err := r.Status().Update(ctx, actRunner); err != nil {
+				if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
 					return ctrl.Result{}, err
 				}
 				return ctrl.Result{}, nil
 			}
+			// A finished runner keeps its phase once its workload is gone
+		} else {
+			k8sPod = pod
+		}
+	}
+
+	// Record how the runner and DinD containers terminated, so infrastructure failures (OOM kills, signals)
+	// can be told apart from failing workflows
+	if k8sPod != nil && r.updateContainerTerminations(actRunner, k8sPod) {
+		if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
+	// Fail the runner right away if it could not register; the DinD sidecar would otherwise keep the pod running
+	if message, failed := registrationFailure(k8sPod); failed {
+		if !meta.IsStatusConditionTrue(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionRegistrationFailed) {
+			log.Info("runner failed to register", "actRunner", actRunner.Name, "pod", k8sPod.Name, "message", message)
+			meta.SetStatusCondition(&actRunner.Status.Conditions, metav1.Condition{
+				Type:               forgejoactionsiov1alpha1.ConditionRegistrationFailed,
+				Status:             metav1.ConditionTrue,
+				Reason:             "RegistrationFailed",
+				Message:            message,
+				ObservedGeneration: actRunner.Generation,
+			})
+			if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	// A runner that received no task within its idle timeout has nothing left to do
+	if idleTimedOut(k8sPod) && !meta.IsStatusConditionTrue(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionIdleTimedOut) {
+		log.Info("runner received no task within its idle timeout", "actRunner", actRunner.Name, "pod", k8sPod.Name)
+		message := "no task received within the idle timeout"
+		if actRunner.Spec.IdleTimeout != nil {
+			message = fmt.Sprintf("no task received within %s", actRunner.Spec.IdleTimeout.Duration)
+		}
+		meta.SetStatusCondition(&actRunner.Status.Conditions, metav1.Condition{
+			Type:               forgejoactionsiov1alpha1.ConditionIdleTimedOut,
+			Status:             metav1.ConditionTrue,
+			Reason:             "IdleTimeout",
+			Message:            message,
+			ObservedGeneration: actRunner.Generation,
+		})
+		if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Tell infrastructure failures (DinD crashes) apart from failing workflows
+	// A DinD crash is retried once with a new pod, as the runner can't work without Docker
+	if reason, message := failureReason(k8sPod); reason != "" {
+		if reason == forgejoactionsiov1alpha1.FailureReasonDinDFailed && actRunner.Status.DinDRetries < forgejoactionsiov1alpha1.MaxDinDRetries &&
+			!runnerphase.Finished(actRunner.Status.Phase) && k8sPod.DeletionTimestamp == nil {
+			log.Info("DinD sidecar crashed, recreating runner pod", "actRunner", actRunner.Name, "pod", k8sPod.Name, "message", message)
+			if err := backend.Delete(ctx, actRunner, actRunner.Status.KubernetesJobName); err != nil {
+				return ctrl.Result{}, err
+			}
+			// The terminations may have been written above; patching against them is what clears them
+			recorded := actRunner.DeepCopy()
+			actRunner.Status.DinDRetries++
+			actRunner.Status.KubernetesJobName = ""
+			actRunner.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhasePending
+			actRunner.Status.RunnerContainer = nil
+			actRunner.Status.DinDContainer = nil
+			if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, recorded); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+		}
+
+		if !meta.IsStatusConditionTrue(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionFailed) {
+			meta.SetStatusCondition(&actRunner.Status.Conditions, metav1.Condition{
+				Type:               forgejoactionsiov1alpha1.ConditionFailed,
+				Status:             metav1.ConditionTrue,
+				Reason:             reason,
+				Message:            message,
+				ObservedGeneration: actRunner.Generation,
+			})
+			if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
 	// Update phase based on Pod status
-	newPhase := r.determinePhase(k8sPod)
-	if actRunner.Status.Phase != newPhase {
+	// Record the reconciled generation and Ready condition along with the phase, so health checks know the status is current
+	// Finished phases are final, e.g. when the backend rejected the runner before any workload existed
+	newPhase := runnerphase.Next(actRunner.Status.Phase, r.determinePhase(k8sPod))
+	phaseChanged := actRunner.Status.Phase != newPhase
+	if phaseChanged {
 		actRunner.Status.Phase = newPhase
 
 		now := metav1.Now()
 		if newPhase == forgejoactionsiov1alpha1.ActRunnerPhaseRunning && actRunner.Status.StartedAt == nil {
 			actRunner.Status.StartedAt = &now
 		}
-		if (newPhase == forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded || newPhase == forgejoactionsiov1alpha1.ActRunnerPhaseFailed) && actRunner.Status.CompletedAt == nil {
+		if runnerphase.Finished(newPhase) && actRunner.Status.CompletedAt == nil {
 			actRunner.Status.CompletedAt = &now
 		}
+	}
+	readyChanged := meta.SetStatusCondition(&actRunner.Status.Conditions, runnerReadyCondition(actRunner))
+	observedChanged := k8sutil.ObserveGeneration(actRunner.Status.Conditions, actRunner.Generation) ||
+		actRunner.Status.ObservedGeneration != actRunner.Generation
+	actRunner.Status.ObservedGeneration = actRunner.Generation
+	if phaseChanged || readyChanged || observedChanged {
+		if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	// The runner counts as started once its pod runs; the phase is already Running right after creation
+	switch {
+	case newPhase == forgejoactionsiov1alpha1.ActRunnerPhaseRunning && !meta.IsStatusConditionTrue(original.Status.Conditions, forgejoactionsiov1alpha1.ConditionReady):
+		r.publishRunnerEvent(ctx, actRunner, cloudevents.TypeRunnerStarted)
+	case phaseChanged && newPhase == forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded:
+		r.publishRunnerEvent(ctx, actRunner, cloudevents.TypeRunnerSucceeded)
+	case phaseChanged && newPhase == forgejoactionsiov1alpha1.ActRunnerPhaseFailed:
+		r.publishRunnerEvent(ctx, actRunner, cloudevents.TypeRunnerFailed)
+	case phaseChanged && newPhase == forgejoactionsiov1alpha1.ActRunnerPhaseCancelled:
+		r.publishRunnerEvent(ctx, actRunner, cloudevents.TypeRunnerCancelled)
+	}
+
+	// Give up on runners stuck in Pending (unschedulable pods, missing secrets), so the listener can replace them
+	if message, stuck := pendingTimedOut(actRunner, k8sPod, time.Now()); stuck {
+		log.Info("runner stuck in Pending, failing it", "actRunner", actRunner.Name, "message", message)
+		if actRunner.Status.KubernetesJobName != "" {
+			if err := backend.Delete(ctx, actRunner, actRunner.Status.KubernetesJobName); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, r.failRunner(ctx, actRunner, forgejoactionsiov1alpha1.FailureReasonPendingTimeout, message)
+	}
 
-		if err := r.Status().Update(ctx, actRunner); err != nil {
+	// With the Immediate rollout strategy, a workload that has not started yet is replaced when the
+	// listener updated the runner to a newer configuration
+	if k8sPod != nil && outdatedWorkload(actRunner) {
+		log.Info("runner configuration changed, replacing runner workload that has not started yet", "actRunner", actRunner.Name,
+			"workload", actRunner.Status.KubernetesJobName, "templateHash", actRunner.Annotations[rollout.TemplateHashAnnotation])
+		if err := backend.Delete(ctx, actRunner, actRunner.Status.KubernetesJobName); err != nil {
+			return ctrl.Result{}, err
+		}
+		actRunner.Status.KubernetesJobName = ""
+		actRunner.Status.TemplateHash = ""
+		if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
 			return ctrl.Result{}, err
 		}
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	}
 
-	// If pending, create Kubernetes Pod
+	// If pending, create the runner pod through the backend
 	if actRunner.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhasePending {
-		if err := r.createKubernetesPod(ctx, actRunner); err != nil {
+		// Admission control would reject the same workload again; the listener updating the spec retries it
+		if actRunner.Status.KubernetesJobName == "" && admissionRejected(actRunner) {
+			log.V(1).Info("runner workload was rejected by admission control, waiting for a spec change", "actRunner", actRunner.Name)
+			return ctrl.Result{}, nil
+		}
+		if actRunner.Status.KubernetesJobName == "" {
+			waiting, err := r.higherPriorityRunnerWaiting(ctx, actRunner)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if waiting != "" {
+				log.V(1).Info("deferring runner workload until a higher priority runner has one", "actRunner", actRunner.Name, "waitingFor", waiting)
+				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+			}
+		}
+		if err := r.createKubernetesPod(ctx, actRunner, backend); err != nil {
+			if errors.Is(err, errBackendUnavailable) {
+				return ctrl.Result{}, r.failRunner(ctx, actRunner, forgejoactionsiov1alpha1.FailureReasonBackendUnavailable, err.Error())
+			}
+			if errors.Is(err, errPodNonCompliant) {
+				log.Info("runner pod violates the pod compliance settings, failing runner", "actRunner", actRunner.Name, "message", err.Error())
+				r.recordDeploymentEvent(actRunner, corev1.EventTypeWarning, "RunnerPodNonCompliant", fmt.Sprintf("ActRunner %s: %s", actRunner.Name, err))
+				return ctrl.Result{}, r.failRunner(ctx, actRunner, forgejoactionsiov1alpha1.FailureReasonPodNonCompliant, err.Error())
+			}
+			if reason, rejected := quotaRejection(err); rejected {
+				return r.backOffQuotaRejection(ctx, actRunner, reason, err)
+			}
+			if admissionRejection(err) {
+				return ctrl.Result{}, r.recordAdmissionRejection(ctx, actRunner, err)
+			}
 			log.Error(err, "failed to create Kubernetes Pod")
 			return ctrl.Result{}, err
 		}
@@ -112,12 +313,26 @@ func (r *ActRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	// If running, periodically check status
 	if actRunner.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhaseRunning {
+		if err := r.labelRunningPod(ctx, actRunner, k8sPod); err != nil {
+			return ctrl.Result{}, err
+		}
+		// Sample the pod's usage for resource recommendations; clusters without metrics-server just have no samples
+		if actRunner.Spec.RecordUsage && k8sPod != nil {
+			if err := r.recordPeakUsage(ctx, actRunner, k8sPod); err != nil {
+				log.V(1).Info("failed to record runner pod usage", "actRunner", actRunner.Name, "error", err.Error())
+			}
+		}
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	// Terminating pods and pods of unreachable nodes are watched until they finish or go away
+	if actRunner.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhaseTerminating ||
+		actRunner.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhaseUnknown {
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
 
-	// If succeeded or failed, clean up registration token secret and schedule deletion after 3 minutes
-	if actRunner.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded ||
-		actRunner.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhaseFailed {
+	// If finished, clean up registration token secret and schedule deletion after the retention
+	if runnerphase.Finished(actRunner.Status.Phase) {
 		// Clean up the registration token secret when runner is finished
 		// We do this on each reconcile while finished to handle cases where cleanup failed previously
 		if err := r.cleanupRegistrationSecret(ctx, log, actRunner); err != nil {
@@ -126,14 +341,41 @@ func (r *ActRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 		}
 
-		// Check if we should delete the ActRunner (3 minutes after completion)
+		// Archive the logs while the pod still exists; failed uploads are retried until the retention ends
+		archivePending := false
+		if r.needsLogArchive(actRunner, k8sPod) {
+			if err := r.archiveLogs(ctx, actRunner, k8sPod); err != nil {
+				log.Error(err, "failed to archive runner logs", "actRunner", actRunner.Name)
+				archivePending = true
+			}
+		}
+
+		// Check if we should delete the ActRunner (completedRunnerRetention after completion)
 		if actRunner.Status.CompletedAt != nil {
-			cleanupTime := actRunner.Status.CompletedAt.Time.Add(3 * time.Minute)
+			retention := 3 * time.Minute
+			if configured := r.Config.Get().CompletedRunnerRetention; configured != nil {
+				retention = configured.Duration
+			}
+			cleanupTime := actRunner.Status.CompletedAt.Time.Add(retention)
 			now := time.Now()
 
+			// spec.podRetention deletes the workload on its own schedule; the logs are archived first
+			deleteAt, scheduled := workloadDeleteAt(actRunner)
+			if scheduled && k8sPod != nil && !archivePending && !now.Before(deleteAt) {
+				log.Info("deleting workload of finished runner", "actRunner", actRunner.Name, "workload", actRunner.Status.KubernetesJobName, "phase", actRunner.Status.Phase)
+				if err := backend.Delete(ctx, actRunner, actRunner.Status.KubernetesJobName); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+
 			if now.After(cleanupTime) || now.Equal(cleanupTime) {
-				// 3 minutes have passed, delete the ActRunner
-				// The pod will be automatically cleaned up via owner references
+				// The retention has passed, delete the ActRunner
+				// The pod will be automatically cleaned up via owner references, unless it is retained for longer
+				if scheduled && k8sPod != nil && now.Before(deleteAt) {
+					if err := backend.Retain(ctx, actRunner, actRunner.Status.KubernetesJobName, deleteAt); err != nil {
+						return ctrl.Result{}, err
+					}
+				}
 				log.Info("deleting completed ActRunner", "actRunner", actRunner.Name, "phase", actRunner.Status.Phase, "completedAt", actRunner.Status.CompletedAt)
 				if err := r.Delete(ctx, actRunner); err != nil {
 					log.Error(err, "failed to delete completed ActRunner")
@@ -145,6 +387,12 @@ func (r *ActRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 			// Not yet time to delete, requeue for the remaining time
 			remainingTime := cleanupTime.Sub(now)
+			if scheduled && k8sPod != nil && now.Before(deleteAt) {
+				remainingTime = min(remainingTime, deleteAt.Sub(now))
+			}
+			if archivePending {
+				remainingTime = min(remainingTime, 30*time.Second)
+			}
 			log.V(1).Info("ActRunner completed, will delete after cleanup delay", "actRunner", actRunner.Name, "remainingTime", remainingTime)
 			return ctrl.Result{RequeueAfter: remainingTime}, nil
 		}
@@ -157,11 +405,93 @@ func (r *ActRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	return ctrl.Result{}, nil
 }
 
+// outdatedWorkload reports whether the runner's pending workload should be replaced with one built from
+// the configuration the listener last updated the runner to
+// Workloads created before template hashes were recorded are left alone
+func outdatedWorkload(actRunner *forgejoactionsiov1alpha1.ActRunner) bool {
+	if actRunner.Spec.RolloutStrategy != forgejoactionsiov1alpha1.RolloutStrategyImmediate ||
+		actRunner.Status.Phase != forgejoactionsiov1alpha1.ActRunnerPhasePending || actRunner.Status.KubernetesJobName == "" {
+		return false
+	}
+	hash := actRunner.Annotations[rollout.TemplateHashAnnotation]
+	return hash != "" && actRunner.Status.TemplateHash != "" && hash != actRunner.Status.TemplateHash
+}
+
+// runnerRunningLabel marks runner pods that started running
+// The runner PodDisruptionBudget only selects these, so pods stuck in Pending don't hold up node drains
+const runnerRunningLabel = "forgejo.actions.io/running"
+
+// labelRunningPod sets runnerRunningLabel on the runner pod once it runs
+// The KubeVirt and ExternalVM backends don't run the runner in a pod of their own
+func (r *ActRunnerReconciler) labelRunningPod(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) error {
+	switch actRunner.Spec.Backend {
+	case "", forgejoactionsiov1alpha1.RunnerBackendPod, forgejoactionsiov1alpha1.RunnerBackendJob:
+	default:
+		return nil
+	}
+	if pod == nil || pod.Status.Phase != corev1.PodRunning || pod.Labels[runnerRunningLabel] == "true" {
+		return nil
+	}
+	patch := client.MergeFrom(pod.DeepCopy())
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	pod.Labels[runnerRunningLabel] = "true"
+	if err := r.Patch(ctx, pod, patch); err != nil {
+		return fmt.Errorf("failed to label running runner pod: %w", err)
+	}
+	return nil
+}
+
+// runnerReadyCondition derives the Ready condition from the phase
+// Running and succeeded runners are Ready; pending runners are still progressing and failed runners are not Ready
+func runnerReadyCondition(actRunner *forgejoactionsiov1alpha1.ActRunner) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             string(actRunner.Status.Phase),
+		Message:            fmt.Sprintf("runner is %s", strings.ToLower(string(actRunner.Status.Phase))),
+		ObservedGeneration: actRunner.Generation,
+	}
+	switch actRunner.Status.Phase {
+	case forgejoactionsiov1alpha1.ActRunnerPhaseRunning, forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded:
+		condition.Status = metav1.ConditionTrue
+	case forgejoactionsiov1alpha1.ActRunnerPhaseFailed:
+		if failed := meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionFailed); failed != nil && failed.Status == metav1.ConditionTrue {
+			condition.Reason = failed.Reason
+			condition.Message = failed.Message
+		}
+	}
+	return condition
+}
+
 func (r *ActRunnerReconciler) determinePhase(pod *corev1.Pod) forgejoactionsiov1alpha1.ActRunnerPhase {
 	if pod == nil {
 		return forgejoactionsiov1alpha1.ActRunnerPhasePending
 	}
 
+	// The runner container finishing decides the outcome, even while the DinD sidecar keeps the pod running
+	// A runner stopped by the deletion of its pod was cancelled rather than failed
+	if state := runnerContainerTerminated(pod); state != nil {
+		if state.ExitCode == 0 || state.ExitCode == forgejoactionsiov1alpha1.IdleTimeoutExitCode {
+			return forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded
+		}
+		if pod.DeletionTimestamp != nil {
+			return forgejoactionsiov1alpha1.ActRunnerPhaseCancelled
+		}
+		return forgejoactionsiov1alpha1.ActRunnerPhaseFailed
+	}
+
+	// A pod being deleted can't take new work; the runner drains its job in the preStop hook
+	if pod.DeletionTimestamp != nil {
+		return forgejoactionsiov1alpha1.ActRunnerPhaseTerminating
+	}
+
+	// Without Docker the runner can't run jobs, so a crashed DinD sidecar fails the runner
+	if reason, _ := failureReason(pod); reason == forgejoactionsiov1alpha1.FailureReasonDinDFailed {
+		return forgejoactionsiov1alpha1.ActRunnerPhaseFailed
+	}
+
 	switch pod.Status.Phase {
 	case corev1.PodSucceeded:
 		return forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded
@@ -169,302 +499,165 @@ func (r *ActRunnerReconciler) determinePhase(pod *corev1.Pod) forgejoactionsiov1
 		return forgejoactionsiov1alpha1.ActRunnerPhaseFailed
 	case corev1.PodRunning:
 		return forgejoactionsiov1alpha1.ActRunnerPhaseRunning
+	case corev1.PodUnknown:
+		return forgejoactionsiov1alpha1.ActRunnerPhaseUnknown
 	default:
 		return forgejoactionsiov1alpha1.ActRunnerPhasePending
 	}
 }
 
-func (r *ActRunnerReconciler) createKubernetesPod(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner) error {
-	podName := fmt.Sprintf("runner-%d-%s", actRunner.Spec.ForgejoJobID, actRunner.Name)
-	if len(podName) > 63 {
-		podName = podName[:63]
-	}
-
-	// Use JobTemplate from spec as base
-	// This allows runnerTemplate to specify pod-level fields (like dnsPolicy, hostAliases, etc.)
-	// without requiring a containers section - we'll add a default container if needed
-	podTemplate := actRunner.Spec.JobTemplate.DeepCopy()
-	if podTemplate.ObjectMeta.Labels == nil {
-		podTemplate.ObjectMeta.Labels = make(map[string]string)
-	}
-	podTemplate.ObjectMeta.Labels["forgejo.actions.io/job-id"] = fmt.Sprintf("%d", actRunner.Spec.ForgejoJobID)
-	podTemplate.ObjectMeta.Labels["forgejo.actions.io/actrunner"] = actRunner.Name
-
-	// Set default runner container if not specified in runnerTemplate
-	// This allows users to specify pod-level overrides (dnsPolicy, hostAliases, etc.)
-	// without having to define a containers section
-	if len(podTemplate.Spec.Containers) == 0 {
-		runnerImage := actRunner.Spec.RunnerImage
-		if runnerImage == "" {
-			runnerImage = "runner-image:latest" // Fallback default
-		}
-		podTemplate.Spec.Containers = []corev1.Container{
-			{
-				Name:  "runner",
-				Image: runnerImage,
-			},
+// runnerContainerTerminated returns the terminated state of the pod's runner container, or nil if it hasn't terminated
+func runnerContainerTerminated(pod *corev1.Pod) *corev1.ContainerStateTerminated {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == "runner" {
+			return status.State.Terminated
 		}
 	}
+	return nil
+}
+
+// updateContainerTerminations copies the terminated container states of the pod into the ActRunner status
+// and sets the OOMKilled condition; it reports whether the status changed
+func (r *ActRunnerReconciler) updateContainerTerminations(actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) bool {
+	before := actRunner.Status.DeepCopy()
 
-	// Configure runner container
-	// We'll modify the first container directly (don't use a pointer since we'll be appending to Containers slice)
-	runnerContainer := &podTemplate.Spec.Containers[0]
-	runnerContainer.Name = "runner"
+	var oomKilled []string
+	for _, status := range pod.Status.ContainerStatuses {
+		terminated := status.State.Terminated
+		if terminated == nil {
+			continue
+		}
+		termination := &forgejoactionsiov1alpha1.ContainerTermination{
+			ExitCode: terminated.ExitCode,
+			Signal:   terminated.Signal,
+			Reason:   terminated.Reason,
+			Message:  terminated.Message,
+		}
+		if !terminated.FinishedAt.IsZero() {
+			finishedAt := terminated.FinishedAt
+			termination.FinishedAt = &finishedAt
+		}
 
-	// Override image if RunnerImage is specified in spec
-	if actRunner.Spec.RunnerImage != "" {
-		runnerContainer.Image = actRunner.Spec.RunnerImage
+		switch status.Name {
+		case "runner":
+			actRunner.Status.RunnerContainer = termination
+		case "dind":
+			actRunner.Status.DinDContainer = termination
+		default:
+			continue
+		}
+		if terminated.Reason == "OOMKilled" {
+			oomKilled = append(oomKilled, status.Name)
+		}
 	}
 
-	// Initialize volume mounts early to ensure they're available
-	if runnerContainer.VolumeMounts == nil {
-		runnerContainer.VolumeMounts = []corev1.VolumeMount{}
+	if len(oomKilled) > 0 {
+		meta.SetStatusCondition(&actRunner.Status.Conditions, metav1.Condition{
+			Type:               forgejoactionsiov1alpha1.ConditionOOMKilled,
+			Status:             metav1.ConditionTrue,
+			Reason:             "OOMKilled",
+			Message:            fmt.Sprintf("container %s exceeded its memory limit", strings.Join(oomKilled, ", ")),
+			ObservedGeneration: actRunner.Generation,
+		})
 	}
 
-	// Add registration token from secret as TOKEN environment variable
-	// We use an explicit EnvVar instead of envFrom to ensure the variable name is TOKEN (uppercase)
-	runnerContainer.Env = append(runnerContainer.Env,
-		corev1.EnvVar{
-			Name: "TOKEN",
-			ValueFrom: &corev1.EnvVarSource{
-				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: actRunner.Spec.RegistrationTokenSecretRef.Name,
-					},
-					Key: "token",
-				},
-			},
-		},
-	)
+	return !equality.Semantic.DeepEqual(before, &actRunner.Status)
+}
 
-	// Add additional environment variables
-	if runnerContainer.Env == nil {
-		runnerContainer.Env = []corev1.EnvVar{}
-	}
-	// Build labels string from job data (comma-separated)
-	labels := ""
-	if len(actRunner.Spec.JobData.RunsOn) > 0 {
-		labels = strings.Join(actRunner.Spec.JobData.RunsOn, ",")
+// failureReason returns which container failed the pod and why, or an empty reason if none failed
+// If both failed, the container that terminated first is blamed
+func failureReason(pod *corev1.Pod) (string, string) {
+	if pod == nil {
+		return "", ""
 	}
 
-	runnerContainer.Env = append(runnerContainer.Env,
-		corev1.EnvVar{
-			Name:  "FORGEJO_SERVER",
-			Value: actRunner.Spec.ForgejoServer,
-		},
-		corev1.EnvVar{
-			Name:  "FORGEJO_ORG",
-			Value: actRunner.Spec.Organization,
-		},
-		corev1.EnvVar{
-			Name:  "FORGEJO_LABELS",
-			Value: labels,
-		},
-	)
-
-	// Add repository and run information if available in status
-	if actRunner.Status.RepositoryFullName != "" {
-		runnerContainer.Env = append(runnerContainer.Env,
-			corev1.EnvVar{
-				Name:  "FORGEJO_REPOSITORY",
-				Value: actRunner.Status.RepositoryFullName,
-			},
-		)
-	}
-	if actRunner.Status.TriggerUser != "" {
-		runnerContainer.Env = append(runnerContainer.Env,
-			corev1.EnvVar{
-				Name:  "FORGEJO_TRIGGER_USER",
-				Value: actRunner.Status.TriggerUser,
-			},
-		)
-	}
-	if actRunner.Status.PrettyRef != "" {
-		runnerContainer.Env = append(runnerContainer.Env,
-			corev1.EnvVar{
-				Name:  "FORGEJO_REF",
-				Value: actRunner.Status.PrettyRef,
-			},
-		)
-	}
-	if actRunner.Status.TriggerEvent != "" {
-		runnerContainer.Env = append(runnerContainer.Env,
-			corev1.EnvVar{
-				Name:  "FORGEJO_TRIGGER_EVENT",
-				Value: actRunner.Status.TriggerEvent,
-			},
-		)
+	var runner, dind *corev1.ContainerStateTerminated
+	for _, status := range pod.Status.ContainerStatuses {
+		switch status.Name {
+		case "runner":
+			runner = status.State.Terminated
+		case "dind":
+			dind = status.State.Terminated
+		}
 	}
+	runnerFailed := runner != nil && runner.ExitCode != 0 && runner.ExitCode != forgejoactionsiov1alpha1.IdleTimeoutExitCode
+	dindFailed := dind != nil && dind.ExitCode != 0
 
-	// Set DOCKER_HOST to use Unix socket (override if already set in JobTemplate)
-	// Remove any existing DOCKER_HOST env var first to avoid duplicates
-	envWithoutDockerHost := []corev1.EnvVar{}
-	for _, env := range runnerContainer.Env {
-		if env.Name != "DOCKER_HOST" {
-			envWithoutDockerHost = append(envWithoutDockerHost, env)
-		}
+	if dindFailed && (!runnerFailed || !dind.FinishedAt.After(runner.FinishedAt.Time)) {
+		return forgejoactionsiov1alpha1.FailureReasonDinDFailed, fmt.Sprintf("dind container exited with code %d (%s)", dind.ExitCode, dind.Reason)
 	}
-	runnerContainer.Env = envWithoutDockerHost
-	// Now add our DOCKER_HOST
-	runnerContainer.Env = append(runnerContainer.Env,
-		corev1.EnvVar{
-			Name:  "DOCKER_HOST",
-			Value: "unix:///var/docker/docker.sock",
-		},
-	)
-
-	// Determine DinD image (default if not specified)
-	dindImage := actRunner.Spec.DockerInDockerImage
-	if dindImage == "" {
-		dindImage = "docker.io/library/docker:29.1.3-dind-alpine3.23"
-	}
-
-	// Add DinD sidecar container
-	// We mount the docker-socket volume at /var/docker, and configure dockerd to create the socket there
-	// We use a wrapper script to start dockerd and fix socket permissions so the runner user can access it
-	// This is needed because the docker group GID may differ between containers
-	dindContainer := corev1.Container{
-		Name:  "dind",
-		Image: dindImage,
-		SecurityContext: &corev1.SecurityContext{
-			Privileged: func() *bool { b := true; return &b }(),
-		},
-		Env: []corev1.EnvVar{
-			{
-				Name:  "DOCKER_TLS_CERTDIR",
-				Value: "",
-			},
-		},
-		Command: []string{"/bin/sh"},
-		Args: []string{
-			"-c",
-			// Start dockerd in background and wait for socket to be created, then fix permissions
-			"dockerd --host=unix:///var/docker/docker.sock --storage-driver=vfs & " +
-				"DOCKER_PID=$! && " +
-				"until [ -S /var/docker/docker.sock ]; do sleep 0.1; done && " +
-				"chmod 666 /var/docker/docker.sock && " +
-				"wait $DOCKER_PID",
-		},
-		VolumeMounts: []corev1.VolumeMount{
-			{
-				Name:      "docker-socket",
-				MountPath: "/var/docker",
-			},
-		},
+	if runnerFailed {
+		return forgejoactionsiov1alpha1.FailureReasonRunnerFailed, fmt.Sprintf("runner container exited with code %d (%s)", runner.ExitCode, runner.Reason)
 	}
+	return "", ""
+}
 
-	// Add shared emptyDir volume for Docker socket
-	dockerSocketVolume := corev1.Volume{
-		Name: "docker-socket",
-		VolumeSource: corev1.VolumeSource{
-			EmptyDir: &corev1.EmptyDirVolumeSource{},
-		},
+// idleTimedOut reports whether the runner container exited because it received no task within its idle timeout
+func idleTimedOut(pod *corev1.Pod) bool {
+	if pod == nil {
+		return false
 	}
+	state := runnerContainerTerminated(pod)
+	return state != nil && state.ExitCode == forgejoactionsiov1alpha1.IdleTimeoutExitCode
+}
 
-	if podTemplate.Spec.Volumes == nil {
-		podTemplate.Spec.Volumes = []corev1.Volume{}
+// registrationFailure reports whether the runner container exited because registration with Forgejo failed
+// Only the exit code decides; the container's termination message, which holds the registration error, is
+// returned for the condition as is
+func registrationFailure(pod *corev1.Pod) (string, bool) {
+	if pod == nil {
+		return "", false
 	}
-	podTemplate.Spec.Volumes = append(podTemplate.Spec.Volumes, dockerSocketVolume)
-
-	// Mount Docker socket volume in runner container (shared emptyDir with DinD)
-	// Check if docker-socket volume mount already exists (from JobTemplate) and remove it if present
-	// Then add our mount to ensure it's always present with the correct path
-	// Note: We must do this BEFORE appending the DinD container, since appending might reallocate the slice
-	filteredVolumeMounts := []corev1.VolumeMount{}
-	for _, vm := range podTemplate.Spec.Containers[0].VolumeMounts {
-		if vm.Name != "docker-socket" {
-			filteredVolumeMounts = append(filteredVolumeMounts, vm)
-		}
+	state := runnerContainerTerminated(pod)
+	if state == nil || state.ExitCode != forgejoactionsiov1alpha1.RegistrationFailedExitCode {
+		return "", false
 	}
-	podTemplate.Spec.Containers[0].VolumeMounts = filteredVolumeMounts
-	// Always add the docker-socket mount (this ensures it's always present)
-	podTemplate.Spec.Containers[0].VolumeMounts = append(podTemplate.Spec.Containers[0].VolumeMounts,
-		corev1.VolumeMount{
-			Name:      "docker-socket",
-			MountPath: "/var/docker",
-		},
-	)
-
-	// Add DinD sidecar container AFTER we've finished modifying the runner container
-	// This avoids potential pointer invalidation issues if the slice needs to reallocate
-	podTemplate.Spec.Containers = append(podTemplate.Spec.Containers, dindContainer)
-
-	// Mount Docker config.json from ConfigMap if specified
-	if actRunner.Spec.DockerConfigMapRef != nil && actRunner.Spec.DockerConfigMapRef.Name != "" {
-		// Add volume for Docker config
-		dockerConfigVolume := corev1.Volume{
-			Name: "docker-config",
-			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					LocalObjectReference: *actRunner.Spec.DockerConfigMapRef,
-					Items: []corev1.KeyToPath{
-						{
-							Key:  "config.json",
-							Path: "config.json",
-						},
-					},
-				},
-			},
-		}
-		podTemplate.Spec.Volumes = append(podTemplate.Spec.Volumes, dockerConfigVolume)
-
-		// Mount at ~/.docker/config.json (using /root/.docker for root user, or /home/runner/.docker for runner user)
-		// Default to /root/.docker/config.json - can be overridden in RunnerTemplate if needed
-		runnerContainer.VolumeMounts = append(runnerContainer.VolumeMounts,
-			corev1.VolumeMount{
-				Name:      "docker-config",
-				MountPath: "/root/.docker",
-				ReadOnly:  true,
-			},
-		)
+	message := strings.TrimSpace(state.Message)
+	if message == "" {
+		message = fmt.Sprintf("runner container exited with code %d", state.ExitCode)
 	}
+	return message, true
+}
 
-	// Set restart policy to Never if not set
-	if podTemplate.Spec.RestartPolicy == "" {
-		podTemplate.Spec.RestartPolicy = corev1.RestartPolicyNever
-	}
+func (r *ActRunnerReconciler) createKubernetesPod(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, backend RunnerBackend) error {
+	original := actRunner.DeepCopy()
+	pod, runnerName := podbuilder.Build(actRunner, r.Config.Get())
+	podName := pod.Name
 
-	pod := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      podName,
-			Namespace: actRunner.Namespace,
-			Labels: map[string]string{
-				"forgejo.actions.io/job-id":    fmt.Sprintf("%d", actRunner.Spec.ForgejoJobID),
-				"forgejo.actions.io/actrunner": actRunner.Name,
-			},
-			OwnerReferences: []metav1.OwnerReference{
-				{
-					APIVersion: actRunner.APIVersion,
-					Kind:       actRunner.Kind,
-					Name:       actRunner.Name,
-					UID:        actRunner.UID,
-					Controller: func() *bool { b := true; return &b }(),
-				},
-			},
-		},
-		Spec: podTemplate.Spec,
+	// Refuse pods that cluster admission policies would reject
+	if violations := podcompliance.Violations(pod, actRunner.Spec.PodCompliance); len(violations) > 0 {
+		return fmt.Errorf("%w: %s", errPodNonCompliant, strings.Join(violations, "; "))
 	}
 
-	if err := ctrl.SetControllerReference(actRunner, pod, r.Scheme); err != nil {
+	// The registration token must only reach the runner through the secretKeyRef
+	if err := r.checkTokenExposure(ctx, actRunner, pod); err != nil {
 		return err
 	}
 
-	if err := r.Create(ctx, pod); err != nil {
+	if err := backend.Create(ctx, actRunner, pod); err != nil {
 		if apierrors.IsAlreadyExists(err) {
-			// Pod already exists, get it and update status accordingly
-			existingPod := &corev1.Pod{}
-			if getErr := r.Get(ctx, client.ObjectKey{Namespace: actRunner.Namespace, Name: podName}, existingPod); getErr != nil {
-				return fmt.Errorf("pod already exists but failed to get it: %w", getErr)
+			// Workload already exists, get its pod and update status accordingly
+			existingPod, getErr := backend.Pod(ctx, actRunner, podName)
+			if getErr != nil {
+				return fmt.Errorf("runner workload already exists but failed to get its pod: %w", getErr)
+			}
+			// A pod from a previous attempt may still be terminating; wait for it to go away
+			if existingPod != nil && !existingPod.DeletionTimestamp.IsZero() {
+				return fmt.Errorf("pod %s from a previous attempt is still terminating", podName)
 			}
 			// Update status to reflect the existing pod
 			actRunner.Status.KubernetesJobName = podName
+			if existingPod != nil {
+				actRunner.Status.RunnerImage = existingPod.Annotations[podbuilder.RunnerImageAnnotation]
+				actRunner.Status.DinDImage = existingPod.Annotations[podbuilder.DinDImageAnnotation]
+			}
 			phase := r.determinePhase(existingPod)
 			actRunner.Status.Phase = phase
 			if phase == forgejoactionsiov1alpha1.ActRunnerPhaseRunning && actRunner.Status.StartedAt == nil {
 				now := metav1.Now()
 				actRunner.Status.StartedAt = &now
 			}
-			if err := r.Status().Update(ctx, actRunner); err != nil {
+			if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
 				return err
 			}
 			return nil
@@ -473,19 +666,83 @@ func (r *ActRunnerReconciler) createKubernetesPod(ctx context.Context, actRunner
 	}
 
 	// Update status
-	actRunner.Status.KubernetesJobName = podName // Reusing this field name for Pod name
+	actRunner.Status.KubernetesJobName = podName // Name of the Pod or Job, depending on the backend
+	actRunner.Status.TemplateHash = actRunner.Annotations[rollout.TemplateHashAnnotation]
+	actRunner.Status.RunnerImage = pod.Annotations[podbuilder.RunnerImageAnnotation]
+	actRunner.Status.DinDImage = pod.Annotations[podbuilder.DinDImageAnnotation]
+	// The listener looks up the ID once the new workload registered
+	actRunner.Status.RunnerName = runnerName
+	actRunner.Status.RunnerID = 0
 	actRunner.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhaseRunning
 	now := metav1.Now()
 	actRunner.Status.StartedAt = &now
-	if err := r.Status().Update(ctx, actRunner); err != nil {
+	meta.RemoveStatusCondition(&actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionQuotaExceeded)
+	meta.RemoveStatusCondition(&actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionAdmissionRejected)
+	actRunner.Status.AdmissionRejection = nil
+	if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
+		return err
+	}
+	r.publishRunnerEvent(ctx, actRunner, cloudevents.TypeRunnerCreated)
+
+	return nil
+}
+
+// failRunner fails a runner that can't run (e.g., its backend is unavailable or it is stuck in Pending),
+// so it is cleaned up like any failed runner
+// Its workload must already be deleted
+func (r *ActRunnerReconciler) failRunner(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, reason, message string) error {
+	original := actRunner.DeepCopy()
+	now := metav1.Now()
+	actRunner.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhaseFailed
+	actRunner.Status.KubernetesJobName = ""
+	actRunner.Status.CompletedAt = &now
+	meta.SetStatusCondition(&actRunner.Status.Conditions, metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionFailed,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: actRunner.Generation,
+	})
+	meta.SetStatusCondition(&actRunner.Status.Conditions, runnerReadyCondition(actRunner))
+	if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
 		return err
 	}
+	r.publishRunnerEvent(ctx, actRunner, cloudevents.TypeRunnerFailed)
+	return nil
+}
+
+// checkTokenExposure returns an error if the registration token shows up in the pod's labels,
+// annotations, container args or literal env vars, e.g. through a runnerTemplate
+func (r *ActRunnerReconciler) checkTokenExposure(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) error {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: actRunner.Namespace, Name: actRunner.Spec.RegistrationTokenSecretRef.Name}, secret)
+	if err != nil {
+		// A missing secret makes the pod fail to start anyway
+		return client.IgnoreNotFound(err)
+	}
 
+	template := &corev1.PodTemplateSpec{ObjectMeta: pod.ObjectMeta, Spec: pod.Spec}
+	if leaks := secretguard.FindInPodTemplate(template, strings.TrimSpace(string(secret.Data["token"]))); len(leaks) > 0 {
+		return fmt.Errorf("refusing to create pod %s: registration token found in %s", pod.Name, strings.Join(leaks, ", "))
+	}
 	return nil
 }
 
 // cleanupRegistrationSecret deletes the registration token secret associated with the ActRunner
 func (r *ActRunnerReconciler) cleanupRegistrationSecret(ctx context.Context, log logr.Logger, actRunner *forgejoactionsiov1alpha1.ActRunner) error {
+	// The cloud-init user data of runner VMs contains the token as well
+	if actRunner.Spec.Backend == forgejoactionsiov1alpha1.RunnerBackendKubeVirt && actRunner.Status.KubernetesJobName != "" {
+		userData := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cloudInitSecretName(actRunner.Status.KubernetesJobName),
+				Namespace: actRunner.Namespace,
+			},
+		}
+		if err := r.Delete(ctx, userData); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete cloud-init secret %s/%s: %w", userData.Namespace, userData.Name, err)
+		}
+	}
+
 	if actRunner.Spec.RegistrationTokenSecretRef.Name == "" {
 		// No secret to clean up
 		return nil
@@ -508,6 +765,7 @@ func (r *ActRunnerReconciler) cleanupRegistrationSecret(ctx context.Context, log
 	}
 
 	log.Info("deleted registration token secret", "secret", secret.Name, "actRunner", actRunner.Name)
+
 	return nil
 }
 
@@ -0,0 +1,257 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Runner pod terminations", func() {
+	const (
+		namespace = "runners"
+		podName   = "runner-1-terminations"
+	)
+
+	var (
+		ctx context.Context
+		c   client.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithStatusSubresource(&forgejoactionsiov1alpha1.ActRunner{}).Build()
+	})
+
+	// reconcilePod reconciles a running ActRunner whose pod has the container states, after the given DinD retries,
+	// and returns the updated ActRunner
+	reconcilePod := func(dindRetries int32, runner, dind corev1.ContainerState) *forgejoactionsiov1alpha1.ActRunner {
+		actRunner := &forgejoactionsiov1alpha1.ActRunner{
+			ObjectMeta: metav1.ObjectMeta{Name: "terminations", Namespace: namespace},
+			Spec:       forgejoactionsiov1alpha1.ActRunnerSpec{ForgejoJobID: 1},
+		}
+		Expect(c.Create(ctx, actRunner)).To(Succeed())
+		actRunner.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhaseRunning
+		actRunner.Status.KubernetesJobName = podName
+		actRunner.Status.DinDRetries = dindRetries
+		Expect(c.Status().Update(ctx, actRunner)).To(Succeed())
+
+		Expect(c.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: namespace},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "runner", State: runner},
+					{Name: "dind", State: dind},
+				},
+			},
+		})).To(Succeed())
+
+		reconciler := &ActRunnerReconciler{Client: c, Scheme: scheme.Scheme}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(actRunner)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(actRunner), actRunner)).To(Succeed())
+		return actRunner
+	}
+
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	exited := func(exitCode int32, reason, message string) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Reason: reason, Message: message}}
+	}
+
+	Describe("registration failures", func() {
+		DescribeTable("are detected by the exit code alone",
+			func(runner corev1.ContainerState, failed bool, message string) {
+				actRunner := reconcilePod(0, runner, running)
+				condition := meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionRegistrationFailed)
+				if !failed {
+					Expect(condition).To(BeNil())
+					return
+				}
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+				Expect(condition.Message).To(Equal(message))
+				Expect(actRunner.Status.Phase).To(Equal(forgejoactionsiov1alpha1.ActRunnerPhaseFailed))
+			},
+			Entry("exit 78 with the registration error as termination message",
+				exited(forgejoactionsiov1alpha1.RegistrationFailedExitCode, "Error", "registration failed (exit code 1): unauthorized"),
+				true, "registration failed (exit code 1): unauthorized"),
+			Entry("exit 78 without a termination message",
+				exited(forgejoactionsiov1alpha1.RegistrationFailedExitCode, "Error", ""),
+				true, "runner container exited with code 78"),
+			Entry("another exit code whose log tail mentions registration",
+				exited(1, "Error", "Error: failed to register runner"), false, ""),
+		)
+	})
+
+	Describe("idle timeouts", func() {
+		DescribeTable("finish the runner without failing it",
+			func(runner corev1.ContainerState, idle bool, phase forgejoactionsiov1alpha1.ActRunnerPhase) {
+				actRunner := reconcilePod(0, runner, running)
+				Expect(meta.IsStatusConditionTrue(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionIdleTimedOut)).To(Equal(idle))
+				Expect(meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionFailed)).To(BeNil())
+				Expect(actRunner.Status.Phase).To(Equal(phase))
+			},
+			Entry("exit 75 after no task arrived",
+				exited(forgejoactionsiov1alpha1.IdleTimeoutExitCode, "Error", "idle timeout: no task received within 300s"),
+				true, forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded),
+			Entry("exit 0 after the job", exited(0, "Completed", ""), false, forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded),
+		)
+	})
+
+	Describe("container terminations", func() {
+		DescribeTable("are recorded with OOM kills",
+			func(runner, dind corev1.ContainerState, oomKilled string) {
+				// With the DinD retries used up, an OOM-killed sidecar fails the runner instead of being recreated
+				actRunner := reconcilePod(forgejoactionsiov1alpha1.MaxDinDRetries, runner, dind)
+				if runner.Terminated != nil {
+					Expect(actRunner.Status.RunnerContainer).NotTo(BeNil())
+					Expect(actRunner.Status.RunnerContainer.ExitCode).To(Equal(runner.Terminated.ExitCode))
+					Expect(actRunner.Status.RunnerContainer.Reason).To(Equal(runner.Terminated.Reason))
+				}
+				if dind.Terminated != nil {
+					Expect(actRunner.Status.DinDContainer).NotTo(BeNil())
+					Expect(actRunner.Status.DinDContainer.ExitCode).To(Equal(dind.Terminated.ExitCode))
+				}
+				condition := meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionOOMKilled)
+				if oomKilled == "" {
+					Expect(condition).To(BeNil())
+					return
+				}
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Message).To(ContainSubstring(oomKilled))
+				Expect(actRunner.Status.Phase).To(Equal(forgejoactionsiov1alpha1.ActRunnerPhaseFailed))
+			},
+			Entry("an OOM-killed runner", exited(137, "OOMKilled", ""), running, "runner"),
+			Entry("an OOM-killed DinD sidecar", running, exited(137, "OOMKilled", ""), "dind"),
+			Entry("a failing workflow", exited(1, "Error", ""), running, ""),
+		)
+	})
+
+	Describe("DinD crashes", func() {
+		podExists := func() bool {
+			err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: podName}, &corev1.Pod{})
+			if errors.IsNotFound(err) {
+				return false
+			}
+			Expect(err).NotTo(HaveOccurred())
+			return true
+		}
+
+		It("recreates the runner pod once", func() {
+			actRunner := reconcilePod(0, running, exited(1, "Error", ""))
+			Expect(podExists()).To(BeFalse())
+			Expect(actRunner.Status.DinDRetries).To(BeEquivalentTo(1))
+			Expect(actRunner.Status.Phase).To(Equal(forgejoactionsiov1alpha1.ActRunnerPhasePending))
+			Expect(actRunner.Status.KubernetesJobName).To(BeEmpty())
+			Expect(actRunner.Status.DinDContainer).To(BeNil())
+			Expect(meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionFailed)).To(BeNil())
+		})
+
+		It("fails the runner once the retries are used up", func() {
+			actRunner := reconcilePod(forgejoactionsiov1alpha1.MaxDinDRetries, running, exited(1, "Error", ""))
+			Expect(podExists()).To(BeTrue())
+			Expect(actRunner.Status.DinDRetries).To(BeEquivalentTo(forgejoactionsiov1alpha1.MaxDinDRetries))
+			Expect(actRunner.Status.Phase).To(Equal(forgejoactionsiov1alpha1.ActRunnerPhaseFailed))
+			condition := meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionFailed)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(forgejoactionsiov1alpha1.FailureReasonDinDFailed))
+		})
+
+		It("blames the runner when it failed before the sidecar", func() {
+			runner, dind := exited(1, "Error", ""), exited(1, "Error", "")
+			runner.Terminated.FinishedAt = metav1.Unix(100, 0)
+			dind.Terminated.FinishedAt = metav1.Unix(200, 0)
+			actRunner := reconcilePod(0, runner, dind)
+			Expect(podExists()).To(BeTrue())
+			Expect(actRunner.Status.DinDRetries).To(BeZero())
+			condition := meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionFailed)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(forgejoactionsiov1alpha1.FailureReasonRunnerFailed))
+		})
+	})
+})
+
+var _ = Describe("Running runner pods", func() {
+	const namespace = "runners"
+
+	var (
+		ctx context.Context
+		c   client.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithStatusSubresource(&forgejoactionsiov1alpha1.ActRunner{}).Build()
+	})
+
+	// reconcilePod reconciles a Running ActRunner whose pod is in the phase and returns the pod's labels
+	reconcilePod := func(backend forgejoactionsiov1alpha1.RunnerBackend, phase corev1.PodPhase) map[string]string {
+		actRunner := &forgejoactionsiov1alpha1.ActRunner{
+			ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: namespace},
+			Spec:       forgejoactionsiov1alpha1.ActRunnerSpec{ForgejoJobID: 1, Backend: backend},
+		}
+		Expect(c.Create(ctx, actRunner)).To(Succeed())
+		actRunner.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhaseRunning
+		actRunner.Status.KubernetesJobName = "runner-1-running"
+		Expect(c.Status().Update(ctx, actRunner)).To(Succeed())
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "runner-1-running",
+				Namespace: namespace,
+				Labels:    map[string]string{"forgejo.actions.io/actrunner": actRunner.Name},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+		Expect(c.Create(ctx, pod)).To(Succeed())
+
+		reconciler := &ActRunnerReconciler{Client: c, Scheme: scheme.Scheme}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(actRunner)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+		return pod.Labels
+	}
+
+	It("are labelled for the runner PodDisruptionBudget", func() {
+		Expect(reconcilePod("", corev1.PodRunning)).To(HaveKeyWithValue(runnerRunningLabel, "true"))
+	})
+
+	It("are labelled with the Pod backend", func() {
+		Expect(reconcilePod(forgejoactionsiov1alpha1.RunnerBackendPod, corev1.PodRunning)).To(HaveKeyWithValue(runnerRunningLabel, "true"))
+	})
+
+	It("are not labelled while they are Pending", func() {
+		labels := reconcilePod("", corev1.PodPending)
+		Expect(labels).NotTo(HaveKey(runnerRunningLabel))
+		Expect(labels).To(HaveKey("forgejo.actions.io/actrunner"))
+	})
+})
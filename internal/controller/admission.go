@@ -0,0 +1,86 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/k8sutil"
+)
+
+// maxAdmissionMessageLength bounds the rejection message kept in status; condition messages allow 32768 bytes
+const maxAdmissionMessageLength = 32768
+
+// admissionRejection reports whether the API server rejected a workload in admission control: a validating or
+// mutating webhook, a ValidatingAdmissionPolicy or Pod Security admission
+// Quota rejections are handled separately, since they go away without a spec change
+func admissionRejection(err error) bool {
+	if !apierrors.IsForbidden(err) && !apierrors.IsInvalid(err) && !apierrors.IsBadRequest(err) {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, "admission webhook") || strings.Contains(message, "denied request") ||
+		strings.Contains(message, "ValidatingAdmissionPolicy") || strings.Contains(message, "violates PodSecurity")
+}
+
+// admissionRejected reports whether the runner's current spec was already rejected, so creation isn't retried
+func admissionRejected(actRunner *forgejoactionsiov1alpha1.ActRunner) bool {
+	rejection := actRunner.Status.AdmissionRejection
+	return rejection != nil && rejection.Generation == actRunner.Generation
+}
+
+// recordAdmissionRejection records the rejection in the ActRunner's status and conditions and, once per distinct
+// message, on its ActDeployment
+// The event message doesn't name the runner, so rejections of several runners for the same reason are aggregated
+func (r *ActRunnerReconciler) recordAdmissionRejection(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, cause error) error {
+	log := logf.FromContext(ctx)
+	original := actRunner.DeepCopy()
+
+	message := cause.Error()
+	if len(message) > maxAdmissionMessageLength {
+		message = message[:maxAdmissionMessageLength]
+	}
+	changed := actRunner.Status.AdmissionRejection == nil || actRunner.Status.AdmissionRejection.Message != message
+	actRunner.Status.AdmissionRejection = &forgejoactionsiov1alpha1.AdmissionRejection{
+		Message:    message,
+		Generation: actRunner.Generation,
+		RejectedAt: metav1.Now(),
+	}
+	meta.SetStatusCondition(&actRunner.Status.Conditions, metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionAdmissionRejected,
+		Status:             metav1.ConditionTrue,
+		Reason:             "AdmissionDenied",
+		Message:            message,
+		ObservedGeneration: actRunner.Generation,
+	})
+	if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
+		return err
+	}
+	log.Info("runner workload rejected by admission control, waiting for a spec change", "actRunner", actRunner.Name, "message", message)
+	if changed {
+		r.recordDeploymentEvent(actRunner, corev1.EventTypeWarning, "RunnerAdmissionRejected", "runner pod rejected by admission control: "+message)
+	}
+	return nil
+}
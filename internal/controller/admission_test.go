@@ -0,0 +1,149 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Admission rejections", func() {
+	pods := schema.GroupResource{Resource: "pods"}
+
+	// webhookDenial is the error the API server returns when a validating webhook denies a request
+	webhookDenial := &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Message: `admission webhook "validate.kyverno.svc-fail" denied the request: privileged containers are not allowed`,
+	}}
+	podSecurity := apierrors.NewForbidden(pods, "runner-1",
+		fmt.Errorf(`violates PodSecurity "restricted:latest": privileged (container "dind" must not set securityContext.privileged=true)`))
+	admissionPolicy := apierrors.NewInvalid(schema.GroupKind{Kind: "Pod"}, "runner-1", nil)
+	admissionPolicy.ErrStatus.Message = `pods "runner-1" is forbidden: ValidatingAdmissionPolicy 'no-privileged' with binding 'no-privileged' denied request: failed expression`
+	quota := apierrors.NewForbidden(pods, "runner-1",
+		fmt.Errorf("exceeded quota: compute, requested: limits.cpu=2, used: limits.cpu=8, limited: limits.cpu=8"))
+	rbac := apierrors.NewForbidden(pods, "",
+		fmt.Errorf(`User "system:serviceaccount:system:controller" cannot create resource "pods" in API group "" in the namespace "runners"`))
+
+	DescribeTable("are told apart from other API errors",
+		func(err error, rejected bool) {
+			Expect(admissionRejection(err)).To(Equal(rejected))
+		},
+		Entry("a validating webhook denial", error(webhookDenial), true),
+		Entry("a Pod Security admission violation", error(podSecurity), true),
+		Entry("a ValidatingAdmissionPolicy denial", error(admissionPolicy), true),
+		Entry("an exceeded ResourceQuota", error(quota), false),
+		Entry("missing RBAC permissions", error(rbac), false),
+		Entry("a conflict", error(apierrors.NewConflict(pods, "runner-1", fmt.Errorf("object was modified"))), false),
+		Entry("a plain error mentioning a webhook", fmt.Errorf("admission webhook unreachable"), false),
+	)
+
+	Describe("runner creation", func() {
+		const namespace = "runners"
+
+		var (
+			ctx       context.Context
+			c         client.Client
+			createErr error
+			creates   int
+			actRunner *forgejoactionsiov1alpha1.ActRunner
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			createErr = webhookDenial
+			creates = 0
+			c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithStatusSubresource(&forgejoactionsiov1alpha1.ActRunner{}).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+						if _, ok := obj.(*corev1.Pod); ok {
+							creates++
+							if createErr != nil {
+								return createErr
+							}
+						}
+						return c.Create(ctx, obj, opts...)
+					},
+				}).Build()
+
+			actRunner = &forgejoactionsiov1alpha1.ActRunner{
+				ObjectMeta: metav1.ObjectMeta{Name: "rejected", Namespace: namespace, UID: "rejected-uid", Generation: 1},
+				Spec:       forgejoactionsiov1alpha1.ActRunnerSpec{ForgejoJobID: 1},
+			}
+			Expect(c.Create(ctx, actRunner)).To(Succeed())
+			actRunner.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhasePending
+			Expect(c.Status().Update(ctx, actRunner)).To(Succeed())
+		})
+
+		reconcileRunner := func() {
+			reconciler := &ActRunnerReconciler{Client: c, Scheme: scheme.Scheme}
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(actRunner)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(actRunner), actRunner)).To(Succeed())
+		}
+
+		It("stops retrying a rejected spec until the spec changes", func() {
+			reconcileRunner()
+			Expect(creates).To(Equal(1))
+			Expect(actRunner.Status.AdmissionRejection).NotTo(BeNil())
+			Expect(actRunner.Status.AdmissionRejection.Generation).To(Equal(int64(1)))
+			Expect(actRunner.Status.AdmissionRejection.Message).To(ContainSubstring("privileged containers are not allowed"))
+			Expect(meta.IsStatusConditionTrue(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionAdmissionRejected)).To(BeTrue())
+			Expect(actRunner.Status.Phase).To(Equal(forgejoactionsiov1alpha1.ActRunnerPhasePending))
+
+			By("reconciling the unchanged runner")
+			reconcileRunner()
+			Expect(creates).To(Equal(1))
+
+			By("updating the runner's spec so admission accepts it")
+			createErr = nil
+			actRunner.Generation = 2
+			Expect(c.Update(ctx, actRunner)).To(Succeed())
+			reconcileRunner()
+			Expect(creates).To(Equal(2))
+			Expect(actRunner.Status.AdmissionRejection).To(BeNil())
+			Expect(meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionAdmissionRejected)).To(BeNil())
+			Expect(actRunner.Status.Phase).To(Equal(forgejoactionsiov1alpha1.ActRunnerPhaseRunning))
+		})
+
+		It("keeps retrying a quota rejection without recording an admission rejection", func() {
+			createErr = quota
+			reconcileRunner()
+			reconcileRunner()
+			Expect(creates).To(Equal(2))
+			Expect(actRunner.Status.AdmissionRejection).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionQuotaExceeded)).To(BeTrue())
+		})
+	})
+})
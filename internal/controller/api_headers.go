@@ -0,0 +1,75 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// apiHeaderEnv returns the listener environment for extra API headers
+// EXTRA_API_HEADER_NAMES lists the header names, and EXTRA_API_HEADER_<i> holds the value of the i-th header
+func apiHeaderEnv(headers []forgejoactionsiov1alpha1.APIHeader) []corev1.EnvVar {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(headers))
+	env := make([]corev1.EnvVar, 0, len(headers)+1)
+	for i, header := range headers {
+		names = append(names, header.Name)
+		envVar := corev1.EnvVar{Name: "EXTRA_API_HEADER_" + strconv.Itoa(i), Value: header.Value}
+		if header.ValueFrom != nil {
+			envVar.Value = ""
+			envVar.ValueFrom = &corev1.EnvVarSource{SecretKeyRef: header.ValueFrom.DeepCopy()}
+		}
+		env = append(env, envVar)
+	}
+	return append([]corev1.EnvVar{{Name: "EXTRA_API_HEADER_NAMES", Value: strings.Join(names, ",")}}, env...)
+}
+
+// resolveAPIHeaders returns the extra API headers of the ActDeployment with secret values looked up
+func (r *ActDeploymentReconciler) resolveAPIHeaders(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) (map[string]string, error) {
+	if len(actDeployment.Spec.ExtraAPIHeaders) == 0 {
+		return nil, nil
+	}
+
+	headers := make(map[string]string, len(actDeployment.Spec.ExtraAPIHeaders))
+	for _, header := range actDeployment.Spec.ExtraAPIHeaders {
+		if header.ValueFrom == nil {
+			headers[header.Name] = header.Value
+			continue
+		}
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: header.ValueFrom.Name}, secret); err != nil {
+			return nil, fmt.Errorf("failed to get secret %s for header %s: %w", header.ValueFrom.Name, header.Name, err)
+		}
+		value, ok := secret.Data[header.ValueFrom.Key]
+		if !ok {
+			return nil, fmt.Errorf("key %s not found in secret %s for header %s", header.ValueFrom.Key, header.ValueFrom.Name, header.Name)
+		}
+		headers[header.Name] = strings.TrimSpace(string(value))
+	}
+	return headers, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// cacheMountPath is where the janitor mounts the cache volume
+const cacheMountPath = "/cache"
+
+// reconcileCacheJanitor manages the CronJob pruning stale entries from the shared cache volume
+func (r *ActDeploymentReconciler) reconcileCacheJanitor(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	cronJobName := fmt.Sprintf("%s-cache-janitor", actDeployment.Name)
+	cleanup := actDeployment.Spec.CacheCleanup
+
+	existing := &batchv1.CronJob{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: cronJobName}, existing)
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	found := err == nil
+
+	if cleanup == nil {
+		if found && metav1.IsControlledBy(existing, actDeployment) {
+			return client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return nil
+	}
+
+	image := cleanup.Image
+	if image == "" {
+		image = "busybox:1.37"
+	}
+	schedule := cleanup.Schedule
+	if schedule == "" {
+		schedule = "0 * * * *"
+	}
+
+	labels := map[string]string{
+		"app":                               "forgejo-cache-janitor",
+		"forgejo.actions.io/act-deployment": actDeployment.Name,
+	}
+
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cronJobName,
+			Namespace: actDeployment.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          schedule,
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					BackoffLimit: func() *int32 { i := int32(0); return &i }(),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							RestartPolicy: corev1.RestartPolicyNever,
+							Containers: []corev1.Container{
+								{
+									Name:         "janitor",
+									Image:        image,
+									Command:      []string{"sh", "-c", cacheJanitorScript(cleanup)},
+									VolumeMounts: []corev1.VolumeMount{{Name: "cache", MountPath: cacheMountPath}},
+								},
+							},
+							Volumes: []corev1.Volume{
+								{
+									Name: "cache",
+									VolumeSource: corev1.VolumeSource{
+										PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: cleanup.ClaimName},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if err := ctrl.SetControllerReference(actDeployment, cronJob, r.Scheme); err != nil {
+		return err
+	}
+
+	if !found {
+		return r.Create(ctx, cronJob)
+	}
+
+	// Update if needed
+	existing.Spec = cronJob.Spec
+	return r.Update(ctx, existing)
+}
+
+// cacheJanitorScript returns the shell script deleting the entries of the cache paths not modified within the max age
+// Paths are confined to the mounted volume and quoted for the shell
+func cacheJanitorScript(cleanup *forgejoactionsiov1alpha1.CacheCleanupSpec) string {
+	maxAge := 24 * time.Hour
+	if cleanup.MaxAge != nil && cleanup.MaxAge.Duration > 0 {
+		maxAge = cleanup.MaxAge.Duration
+	}
+	paths := cleanup.Paths
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var quoted []string
+	for _, p := range paths {
+		// Joining with the root and cleaning drops any ../ escaping the volume
+		dir := path.Join(cacheMountPath, path.Clean("/"+p))
+		quoted = append(quoted, "'"+strings.ReplaceAll(dir, "'", `'\''`)+"'")
+	}
+	minutes := max(int(maxAge.Minutes()), 1)
+	return fmt.Sprintf(`for dir in %s; do [ -d "$dir" ] && find "$dir" -mindepth 1 -maxdepth 1 -mmin +%d -exec rm -rf {} + ; done; exit 0`,
+		strings.Join(quoted, " "), minutes)
+}
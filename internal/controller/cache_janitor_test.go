@@ -0,0 +1,120 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Cache janitor", func() {
+	DescribeTable("prunes the cache paths within the volume",
+		func(cleanup *forgejoactionsiov1alpha1.CacheCleanupSpec, script string) {
+			Expect(cacheJanitorScript(cleanup)).To(Equal(script))
+		},
+		Entry("the whole volume after a day by default",
+			&forgejoactionsiov1alpha1.CacheCleanupSpec{ClaimName: "cache"},
+			`for dir in '/cache'; do [ -d "$dir" ] && find "$dir" -mindepth 1 -maxdepth 1 -mmin +1440 -exec rm -rf {} + ; done; exit 0`),
+		Entry("the listed paths after the max age",
+			&forgejoactionsiov1alpha1.CacheCleanupSpec{ClaimName: "cache", Paths: []string{"npm", "go/pkg"}, MaxAge: &metav1.Duration{Duration: 2 * time.Hour}},
+			`for dir in '/cache/npm' '/cache/go/pkg'; do [ -d "$dir" ] && find "$dir" -mindepth 1 -maxdepth 1 -mmin +120 -exec rm -rf {} + ; done; exit 0`),
+		Entry("paths escaping the volume confined to it",
+			&forgejoactionsiov1alpha1.CacheCleanupSpec{ClaimName: "cache", Paths: []string{"../../etc", "/var/lib"}},
+			`for dir in '/cache/etc' '/cache/var/lib'; do [ -d "$dir" ] && find "$dir" -mindepth 1 -maxdepth 1 -mmin +1440 -exec rm -rf {} + ; done; exit 0`),
+		Entry("paths with quotes quoted for the shell",
+			&forgejoactionsiov1alpha1.CacheCleanupSpec{ClaimName: "cache", Paths: []string{"it's; rm -rf /"}},
+			`for dir in '/cache/it'\''s; rm -rf '; do [ -d "$dir" ] && find "$dir" -mindepth 1 -maxdepth 1 -mmin +1440 -exec rm -rf {} + ; done; exit 0`),
+		Entry("max ages below a minute rounded up",
+			&forgejoactionsiov1alpha1.CacheCleanupSpec{ClaimName: "cache", MaxAge: &metav1.Duration{Duration: time.Second}},
+			`for dir in '/cache'; do [ -d "$dir" ] && find "$dir" -mindepth 1 -maxdepth 1 -mmin +1 -exec rm -rf {} + ; done; exit 0`),
+	)
+
+	Describe("CronJob", func() {
+		const namespace = "runners"
+
+		var (
+			ctx           context.Context
+			c             client.Client
+			reconciler    *ActDeploymentReconciler
+			actDeployment *forgejoactionsiov1alpha1.ActDeployment
+		)
+		key := types.NamespacedName{Namespace: namespace, Name: "cached-cache-janitor"}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+			reconciler = &ActDeploymentReconciler{Client: c, Scheme: scheme.Scheme}
+			actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "cached", Namespace: namespace, UID: "cached-uid"},
+				Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+					CacheCleanup: &forgejoactionsiov1alpha1.CacheCleanupSpec{ClaimName: "ci-cache"},
+				},
+			}
+		})
+
+		It("mounts the cache volume with the defaults", func() {
+			Expect(reconciler.reconcileCacheJanitor(ctx, actDeployment)).To(Succeed())
+			cronJob := &batchv1.CronJob{}
+			Expect(c.Get(ctx, key, cronJob)).To(Succeed())
+			Expect(metav1.IsControlledBy(cronJob, actDeployment)).To(BeTrue())
+			Expect(cronJob.Spec.Schedule).To(Equal("0 * * * *"))
+			Expect(cronJob.Spec.ConcurrencyPolicy).To(Equal(batchv1.ForbidConcurrent))
+
+			podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+			Expect(podSpec.Containers).To(HaveLen(1))
+			Expect(podSpec.Containers[0].Image).To(Equal("busybox:1.37"))
+			Expect(podSpec.Containers[0].Command[2]).To(Equal(cacheJanitorScript(actDeployment.Spec.CacheCleanup)))
+			Expect(podSpec.Volumes).To(HaveLen(1))
+			Expect(podSpec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("ci-cache"))
+		})
+
+		It("follows changes of the cleanup settings", func() {
+			Expect(reconciler.reconcileCacheJanitor(ctx, actDeployment)).To(Succeed())
+			actDeployment.Spec.CacheCleanup.Schedule = "*/15 * * * *"
+			actDeployment.Spec.CacheCleanup.Image = "registry.example.com/busybox:1.37"
+			Expect(reconciler.reconcileCacheJanitor(ctx, actDeployment)).To(Succeed())
+
+			cronJob := &batchv1.CronJob{}
+			Expect(c.Get(ctx, key, cronJob)).To(Succeed())
+			Expect(cronJob.Spec.Schedule).To(Equal("*/15 * * * *"))
+			Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com/busybox:1.37"))
+		})
+
+		It("is deleted when the cleanup is turned off, unless it isn't the ActDeployment's", func() {
+			Expect(reconciler.reconcileCacheJanitor(ctx, actDeployment)).To(Succeed())
+			actDeployment.Spec.CacheCleanup = nil
+			Expect(reconciler.reconcileCacheJanitor(ctx, actDeployment)).To(Succeed())
+			Expect(errors.IsNotFound(c.Get(ctx, key, &batchv1.CronJob{}))).To(BeTrue())
+
+			Expect(c.Create(ctx, &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: namespace}})).To(Succeed())
+			Expect(reconciler.reconcileCacheJanitor(ctx, actDeployment)).To(Succeed())
+			Expect(c.Get(ctx, key, &batchv1.CronJob{})).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,60 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerphase"
+)
+
+// CacheOptions returns the informer settings that keep the manager's memory flat with many ActRunners
+// ManagedFields are never read, and finished ActRunners only need their status until they are deleted
+func CacheOptions(options cache.Options) cache.Options {
+	stripManagedFields := cache.TransformStripManagedFields()
+	options.DefaultTransform = stripManagedFields
+	if options.ByObject == nil {
+		options.ByObject = map[client.Object]cache.ByObject{}
+	}
+	options.ByObject[&forgejoactionsiov1alpha1.ActRunner{}] = cache.ByObject{
+		Transform: func(obj any) (any, error) {
+			obj, err := stripManagedFields(obj)
+			if err != nil {
+				return nil, err
+			}
+			return stripFinishedActRunner(obj), nil
+		},
+	}
+	return options
+}
+
+// stripFinishedActRunner drops the pod template of a finished ActRunner, the largest part of its spec
+// The template is only read to create the workload, which never happens again once the runner finished
+// The manager never updates ActRunner specs, so the stripped copy is never written back
+func stripFinishedActRunner(obj any) any {
+	actRunner, ok := obj.(*forgejoactionsiov1alpha1.ActRunner)
+	if !ok {
+		return obj
+	}
+	if runnerphase.Finished(actRunner.Status.Phase) {
+		actRunner.Spec.JobTemplate = corev1.PodTemplateSpec{}
+	}
+	return actRunner
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerspec"
+)
+
+const (
+	// awsRoleARNAnnotation binds a ServiceAccount to an IAM role through IRSA
+	awsRoleARNAnnotation = "eks.amazonaws.com/role-arn"
+	// gcpServiceAccountAnnotation binds a ServiceAccount to a Google service account through Workload Identity
+	gcpServiceAccountAnnotation = "iam.gke.io/gcp-service-account"
+)
+
+// reconcileCloudIdentity creates, updates or removes the <name>-runner ServiceAccount carrying the cloud identity
+func (r *ActDeploymentReconciler) reconcileCloudIdentity(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	name := runnerspec.ServiceAccountName(actDeployment.Name)
+	identity := actDeployment.Spec.CloudIdentity
+
+	existing := &corev1.ServiceAccount{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: name}, existing)
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	found := err == nil
+
+	if identity == nil {
+		if found && metav1.IsControlledBy(existing, actDeployment) {
+			return client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return nil
+	}
+
+	annotations := map[string]string{}
+	if identity.AWS != nil {
+		annotations[awsRoleARNAnnotation] = identity.AWS.RoleARN
+	}
+	if identity.GCP != nil {
+		annotations[gcpServiceAccountAnnotation] = identity.GCP.ServiceAccount
+	}
+
+	if !found {
+		serviceAccount := &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   actDeployment.Namespace,
+				Labels:      map[string]string{"forgejo.actions.io/act-deployment": actDeployment.Name},
+				Annotations: annotations,
+			},
+			// Workflows need the cloud identity, not access to the Kubernetes API
+			AutomountServiceAccountToken: ptr.To(false),
+		}
+		if err := ctrl.SetControllerReference(actDeployment, serviceAccount, r.Scheme); err != nil {
+			return err
+		}
+		return r.Create(ctx, serviceAccount)
+	}
+	if !metav1.IsControlledBy(existing, actDeployment) {
+		return fmt.Errorf("ServiceAccount %s exists and is not managed by this ActDeployment", name)
+	}
+
+	changed := false
+	for _, key := range []string{awsRoleARNAnnotation, gcpServiceAccountAnnotation} {
+		value, wanted := annotations[key]
+		current, present := existing.Annotations[key]
+		switch {
+		case wanted && current != value:
+			if existing.Annotations == nil {
+				existing.Annotations = map[string]string{}
+			}
+			existing.Annotations[key] = value
+			changed = true
+		case !wanted && present:
+			delete(existing.Annotations, key)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return r.Update(ctx, existing)
+}
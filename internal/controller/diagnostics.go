@@ -0,0 +1,115 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/diagnostics"
+)
+
+// ManagerState is the manager's part of the diagnostics state dump
+type ManagerState struct {
+	// Queues are the controller work queue metrics, e.g. workqueue_depth{controller="actrunner",name="actrunner"}
+	Queues map[string]float64 `json:"queues"`
+
+	// CachedObjects counts the custom resources in the manager's cache by kind
+	CachedObjects map[string]int `json:"cachedObjects"`
+
+	ActDeployments []ActDeploymentState `json:"actDeployments"`
+
+	// EmbeddedListeners are the ActDeployments whose listener loop runs in the manager
+	EmbeddedListeners []string `json:"embeddedListeners,omitempty"`
+}
+
+// ActDeploymentState is the state of one ActDeployment in the diagnostics state dump
+type ActDeploymentState struct {
+	Namespace               string                                          `json:"namespace"`
+	Name                    string                                          `json:"name"`
+	ListenerMode            forgejoactionsiov1alpha1.ListenerMode           `json:"listenerMode,omitempty"`
+	ActRunners              map[forgejoactionsiov1alpha1.ActRunnerPhase]int `json:"actRunners,omitempty"`
+	ConsecutivePollFailures int32                                           `json:"consecutivePollFailures,omitempty"`
+	LastPollTime            string                                          `json:"lastPollTime,omitempty"`
+}
+
+// DiagnosticState returns the state dump of the manager, read from c, normally the manager's cached client
+// listeners may be nil when the manager doesn't run embedded listener loops
+func DiagnosticState(c client.Client, listeners *EmbeddedListeners) diagnostics.StateFunc {
+	return func(ctx context.Context) (any, error) {
+		queues, err := diagnostics.Metrics(metrics.Registry, "workqueue_")
+		if err != nil {
+			return nil, err
+		}
+
+		actDeployments := &forgejoactionsiov1alpha1.ActDeploymentList{}
+		if err := c.List(ctx, actDeployments); err != nil {
+			return nil, err
+		}
+		actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+		if err := c.List(ctx, actRunners); err != nil {
+			return nil, err
+		}
+
+		// ActRunners are counted by the ActDeployment owning them
+		phases := map[types.UID]map[forgejoactionsiov1alpha1.ActRunnerPhase]int{}
+		for _, actRunner := range actRunners.Items {
+			for _, ref := range actRunner.OwnerReferences {
+				if ref.Kind != "ActDeployment" {
+					continue
+				}
+				if phases[ref.UID] == nil {
+					phases[ref.UID] = map[forgejoactionsiov1alpha1.ActRunnerPhase]int{}
+				}
+				phases[ref.UID][actRunner.Status.Phase]++
+			}
+		}
+
+		state := ManagerState{
+			Queues: queues,
+			CachedObjects: map[string]int{
+				"ActDeployment": len(actDeployments.Items),
+				"ActRunner":     len(actRunners.Items),
+			},
+			ActDeployments: make([]ActDeploymentState, 0, len(actDeployments.Items)),
+		}
+		for _, actDeployment := range actDeployments.Items {
+			deploymentState := ActDeploymentState{
+				Namespace:               actDeployment.Namespace,
+				Name:                    actDeployment.Name,
+				ListenerMode:            actDeployment.Status.ListenerMode,
+				ActRunners:              phases[actDeployment.UID],
+				ConsecutivePollFailures: actDeployment.Status.ConsecutivePollFailures,
+			}
+			if actDeployment.Status.LastPollTime != nil {
+				deploymentState.LastPollTime = actDeployment.Status.LastPollTime.UTC().Format(time.RFC3339)
+			}
+			state.ActDeployments = append(state.ActDeployments, deploymentState)
+		}
+		for _, key := range listeners.Running() {
+			state.EmbeddedListeners = append(state.EmbeddedListeners, key.String())
+		}
+		sort.Strings(state.EmbeddedListeners)
+		return state, nil
+	}
+}
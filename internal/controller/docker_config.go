@@ -0,0 +1,169 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerspec"
+)
+
+// reconcileDockerConfig creates, updates or removes the <name>-docker-config Secret mounted in the runner pods
+// It merges spec.dockerConfigMapRef's config.json, the cloud registries' credential helpers and the registryAuth
+// Secrets' credentials
+func (r *ActDeploymentReconciler) reconcileDockerConfig(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	name := runnerspec.DockerConfigName(actDeployment.Name)
+
+	existing := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: name}, existing)
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	found := err == nil
+
+	if !runnerspec.GeneratesDockerConfig(actDeployment) {
+		if found && metav1.IsControlledBy(existing, actDeployment) {
+			return client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return nil
+	}
+
+	data, err := r.buildDockerConfig(ctx, actDeployment)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: actDeployment.Namespace,
+				Labels:    map[string]string{"forgejo.actions.io/act-deployment": actDeployment.Name},
+			},
+			Type: corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{corev1.DockerConfigJsonKey: data},
+		}
+		if err := ctrl.SetControllerReference(actDeployment, secret, r.Scheme); err != nil {
+			return err
+		}
+		return r.Create(ctx, secret)
+	}
+	if !metav1.IsControlledBy(existing, actDeployment) {
+		return fmt.Errorf("secret %s exists and is not managed by this ActDeployment", name)
+	}
+	if string(existing.Data[corev1.DockerConfigJsonKey]) == string(data) {
+		return nil
+	}
+	existing.Data = map[string][]byte{corev1.DockerConfigJsonKey: data}
+	return r.Update(ctx, existing)
+}
+
+// buildDockerConfig returns the Docker config.json document for the runners of the ActDeployment
+func (r *ActDeploymentReconciler) buildDockerConfig(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) ([]byte, error) {
+	config := map[string]any{}
+	if ref := actDeployment.Spec.DockerConfigMapRef; ref != nil && ref.Name != "" {
+		base := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: ref.Name}, base); err != nil {
+			return nil, fmt.Errorf("failed to get Docker config %s: %w", ref.Name, err)
+		}
+		if data := base.Data["config.json"]; data != "" {
+			if err := json.Unmarshal([]byte(data), &config); err != nil {
+				return nil, fmt.Errorf("failed to parse config.json of %s: %w", ref.Name, err)
+			}
+		}
+	}
+
+	if helpers := runnerspec.CredentialHelpers(actDeployment); len(helpers) > 0 {
+		credHelpers, _ := config["credHelpers"].(map[string]any)
+		if credHelpers == nil {
+			credHelpers = map[string]any{}
+		}
+		for registry, helper := range helpers {
+			credHelpers[registry] = helper
+		}
+		config["credHelpers"] = credHelpers
+	}
+
+	if len(actDeployment.Spec.RegistryAuth) > 0 {
+		auths, _ := config["auths"].(map[string]any)
+		if auths == nil {
+			auths = map[string]any{}
+		}
+		for _, registryAuth := range actDeployment.Spec.RegistryAuth {
+			secretAuths, err := r.registryAuths(ctx, actDeployment.Namespace, registryAuth)
+			if err != nil {
+				return nil, err
+			}
+			for registry, auth := range secretAuths {
+				auths[registry] = auth
+			}
+		}
+		config["auths"] = auths
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Docker config: %w", err)
+	}
+	return data, nil
+}
+
+// registryAuths returns the auths entries of a registryAuth Secret, limited to its registries if any are listed
+func (r *ActDeploymentReconciler) registryAuths(ctx context.Context, namespace string, registryAuth forgejoactionsiov1alpha1.RegistryAuthSpec) (map[string]json.RawMessage, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: registryAuth.SecretRef.Name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get registry auth secret %s: %w", registryAuth.SecretRef.Name, err)
+	}
+	if secret.Type != corev1.SecretTypeDockerConfigJson {
+		return nil, fmt.Errorf("registry auth secret %s is of type %s, not %s", secret.Name, secret.Type, corev1.SecretTypeDockerConfigJson)
+	}
+
+	var dockerConfig struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+	if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &dockerConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse registry auth secret %s: %w", secret.Name, err)
+	}
+	if len(registryAuth.Registries) == 0 {
+		return dockerConfig.Auths, nil
+	}
+
+	auths := map[string]json.RawMessage{}
+	for key, auth := range dockerConfig.Auths {
+		for _, registry := range registryAuth.Registries {
+			if registryHost(key) == registryHost(registry) {
+				auths[key] = auth
+			}
+		}
+	}
+	return auths, nil
+}
+
+// registryHost strips the scheme and trailing slash of a Docker config auths key
+func registryHost(key string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://"), "/")
+}
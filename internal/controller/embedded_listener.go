@@ -0,0 +1,285 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+const (
+	// listenerTokenSecretKey is the key of the Forgejo token in the token Secret
+	listenerTokenSecretKey = "token"
+
+	// embeddedListenerRetryDelay is the wait before an embedded listener loop that failed is started again
+	embeddedListenerRetryDelay = 10 * time.Second
+)
+
+// EmbeddedListeners runs the listener loops of ActDeployments inside the manager started with --mode=all
+// The ActDeployment controller starts, restarts and stops the loops; all of them stop with the manager
+type EmbeddedListeners struct {
+	// Client reads the API server directly, like the client of a dedicated listener,
+	// so the loops don't make the manager cache every Secret they read
+	Client   client.Client
+	Recorder record.EventRecorder
+
+	mu sync.Mutex
+	// ctx is the context of the manager, nil until Start is called
+	ctx   context.Context
+	loops map[types.NamespacedName]*embeddedListener
+	wg    sync.WaitGroup
+}
+
+// embeddedListener is one listener loop and the configuration it was started with
+type embeddedListener struct {
+	config listener.Config
+	hash   string
+	cancel context.CancelFunc
+}
+
+// Start runs the loops until ctx is cancelled; loops ensured before Start are started now
+func (l *EmbeddedListeners) Start(ctx context.Context) error {
+	l.mu.Lock()
+	l.ctx = ctx
+	for key, loop := range l.loops {
+		l.start(key, loop)
+	}
+	l.mu.Unlock()
+
+	<-ctx.Done()
+	l.wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection keeps the loops on the leader, so a standby manager doesn't create ActRunners too
+func (l *EmbeddedListeners) NeedLeaderElection() bool {
+	return true
+}
+
+// Ensure runs the listener loop of the ActDeployment with config, restarting it when config or hash changed
+// hash covers what config doesn't show, such as the contents of the token Secret
+func (l *EmbeddedListeners) Ensure(key types.NamespacedName, config listener.Config, hash string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if loop, ok := l.loops[key]; ok {
+		if loop.hash == hash && reflect.DeepEqual(loop.config, config) {
+			return
+		}
+		if loop.cancel != nil {
+			loop.cancel()
+		}
+	}
+	if l.loops == nil {
+		l.loops = map[types.NamespacedName]*embeddedListener{}
+	}
+	loop := &embeddedListener{config: config, hash: hash}
+	l.loops[key] = loop
+	if l.ctx != nil {
+		l.start(key, loop)
+	}
+}
+
+// Stop stops the listener loop of the ActDeployment, if one runs; it is safe to call on a nil EmbeddedListeners
+func (l *EmbeddedListeners) Stop(key types.NamespacedName) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if loop, ok := l.loops[key]; ok {
+		if loop.cancel != nil {
+			loop.cancel()
+		}
+		delete(l.loops, key)
+	}
+}
+
+// Running returns the ActDeployments with an embedded listener loop; it is safe to call on a nil EmbeddedListeners
+func (l *EmbeddedListeners) Running() []types.NamespacedName {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keys := make([]types.NamespacedName, 0, len(l.loops))
+	for key := range l.loops {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// start runs loop in the background, starting it again after a failure like the kubelet restarts a listener pod
+// The caller holds l.mu
+func (l *EmbeddedListeners) start(key types.NamespacedName, loop *embeddedListener) {
+	ctx, cancel := context.WithCancel(l.ctx)
+	loop.cancel = cancel
+	logger := logf.Log.WithName("listener").WithValues("actDeployment", key.String())
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		for {
+			err := listener.Run(ctx, logger, l.Client, l.Recorder, loop.config)
+			if ctx.Err() != nil {
+				logger.Info("embedded listener stopped")
+				return
+			}
+			logger.Error(err, "embedded listener failed, restarting", "after", embeddedListenerRetryDelay)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(embeddedListenerRetryDelay):
+			}
+		}
+	}()
+}
+
+// listenerMode returns where the listener loop of the ActDeployment runs
+// Without --mode=all there are no embedded loops, so every ActDeployment gets a listener Deployment
+func (r *ActDeploymentReconciler) listenerMode(actDeployment *forgejoactionsiov1alpha1.ActDeployment) forgejoactionsiov1alpha1.ListenerMode {
+	if r.Listeners == nil {
+		// Warn once per spec change rather than on every requeue
+		if actDeployment.Spec.ListenerMode == forgejoactionsiov1alpha1.ListenerModeEmbedded &&
+			actDeployment.Status.ObservedGeneration != actDeployment.Generation {
+			r.recordEvent(actDeployment, corev1.EventTypeWarning, "EmbeddedListenerUnavailable",
+				"listenerMode is embedded, but the manager doesn't run with --mode=all; deploying a dedicated listener")
+		}
+		return forgejoactionsiov1alpha1.ListenerModeDedicated
+	}
+	if actDeployment.Spec.ListenerMode == forgejoactionsiov1alpha1.ListenerModeDedicated {
+		return forgejoactionsiov1alpha1.ListenerModeDedicated
+	}
+	// A listenerTemplate written for a listener Deployment keeps working when the manager switches to --mode=all
+	if unsupported := embeddedUnsupportedEnv(actDeployment); len(unsupported) > 0 {
+		if actDeployment.Status.ObservedGeneration != actDeployment.Generation {
+			r.recordEvent(actDeployment, corev1.EventTypeWarning, "EmbeddedListenerUnavailable",
+				fmt.Sprintf("listenerTemplate sets %s, which embedded listeners don't support; deploying a dedicated listener", strings.Join(unsupported, ", ")))
+		}
+		return forgejoactionsiov1alpha1.ListenerModeDedicated
+	}
+	return forgejoactionsiov1alpha1.ListenerModeEmbedded
+}
+
+// embeddedUnsupportedEnv returns the listenerTemplate environment variables an embedded loop can't apply
+// Embedded loops keep no job claims, and values from a valueFrom source are only resolved in a listener pod
+func embeddedUnsupportedEnv(actDeployment *forgejoactionsiov1alpha1.ActDeployment) []string {
+	containers := actDeployment.Spec.ListenerTemplate.Spec.Containers
+	if len(containers) == 0 {
+		return nil
+	}
+	var unsupported []string
+	for _, e := range containers[0].Env {
+		switch {
+		case strings.HasPrefix(e.Name, "CLAIM_") && (e.Value != "" || e.ValueFrom != nil):
+			unsupported = append(unsupported, e.Name)
+		case (e.Name == "SKIP_TLS_VERIFY" || e.Name == "JOB_BUS_URL") && e.ValueFrom != nil:
+			unsupported = append(unsupported, e.Name)
+		}
+	}
+	return unsupported
+}
+
+// reconcileEmbeddedListener runs the listener loop of the ActDeployment in the manager
+// and deletes the listener Deployment left from a dedicated listener
+func (r *ActDeploymentReconciler) reconcileEmbeddedListener(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) error {
+	headers, err := r.resolveAPIHeaders(ctx, actDeployment)
+	if err != nil {
+		return err
+	}
+	env, err := r.listenerEnv(actDeployment, conn)
+	if err != nil {
+		return err
+	}
+	configHash, err := r.listenerConfigHash(ctx, actDeployment, conn.tokenSecretName, env)
+	if err != nil {
+		return err
+	}
+
+	labels, _ := envValue(env, "LABELS")
+	config := listener.Config{
+		ForgejoServer:      conn.server,
+		Organization:       conn.organization,
+		Labels:             labels,
+		TokenSecretName:    conn.tokenSecretName,
+		TokenSecretKey:     listenerTokenSecretKey,
+		Namespace:          actDeployment.Namespace,
+		ActDeploymentName:  actDeployment.Name,
+		PollInterval:       r.listenerPollInterval(actDeployment, conn),
+		APIHeaders:         headers,
+		DefaultRunnerImage: conn.runnerImage,
+		DefaultDinDImage:   conn.dockerInDockerImage,
+	}
+	if containers := actDeployment.Spec.ListenerTemplate.Spec.Containers; len(containers) > 0 {
+		skipTLSVerify, _ := envValue(containers[0].Env, "SKIP_TLS_VERIFY")
+		config.SkipTLSVerify = skipTLSVerify == "true" || skipTLSVerify == "1" || skipTLSVerify == "yes"
+		config.JobBusURL, _ = envValue(containers[0].Env, "JOB_BUS_URL")
+	}
+	if actDeployment.Spec.ClientCertSecretRef != nil {
+		config.ClientCertSecretName = actDeployment.Spec.ClientCertSecretRef.Name
+	}
+	if operatorConfig := r.Config.Get(); operatorConfig.ForgejoAPIQPS > 0 {
+		config.APIQPS = operatorConfig.ForgejoAPIQPS
+		config.APIBurst = operatorConfig.ForgejoAPIBurst
+	}
+	r.Listeners.Ensure(types.NamespacedName{Namespace: actDeployment.Namespace, Name: actDeployment.Name}, config, configHash)
+
+	// The loop replaces a listener Deployment created before the ActDeployment switched to embedded
+	deployment := &appsv1.Deployment{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: fmt.Sprintf("%s-listener", actDeployment.Name)}, deployment)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && metav1.IsControlledBy(deployment, actDeployment) {
+		if err := r.Delete(ctx, deployment); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete listener Deployment: %w", err)
+		}
+	}
+
+	actDeployment.Status.ListenerConfigHash = configHash
+	actDeployment.Status.ListenerMode = forgejoactionsiov1alpha1.ListenerModeEmbedded
+	actDeployment.Status.ListenerRestarts = 0
+	actDeployment.Status.ListenerPodName = ""
+	actDeployment.Status.ListenerPodReady = false
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionListenerReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ListenerEmbedded",
+		Message:            "the listener loop runs in the controller manager",
+		ObservedGeneration: actDeployment.Generation,
+	})
+	return nil
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/cloudinit"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerlabels"
+)
+
+// virtualMachineInstanceGVK is the KubeVirt VirtualMachineInstance kind
+// It is handled as unstructured so the operator does not depend on the KubeVirt API module
+var virtualMachineInstanceGVK = schema.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: "VirtualMachineInstance"}
+
+// kubeVirtBackend runs the runner in a disposable VirtualMachineInstance
+// The cloud-init user data registers the runner, runs one job and powers the VM off
+// The runner pod built by the controller only contributes its metadata and scheduling constraints
+type kubeVirtBackend struct {
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// cloudInitSecretName returns the name of the Secret holding the user data of a runner VM
+func cloudInitSecretName(name string) string {
+	return name + "-cloud-init"
+}
+
+func (b *kubeVirtBackend) Create(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) error {
+	vm := actRunner.Spec.VirtualMachine
+	if vm == nil {
+		return fmt.Errorf("KubeVirt requires spec.virtualMachine: %w", errBackendUnavailable)
+	}
+
+	tokenSecret := &corev1.Secret{}
+	if err := b.client.Get(ctx, client.ObjectKey{Namespace: actRunner.Namespace, Name: actRunner.Spec.RegistrationTokenSecretRef.Name}, tokenSecret); err != nil {
+		return fmt.Errorf("failed to get registration token secret: %w", err)
+	}
+	userData, err := cloudinit.Render(vm.CloudInitTemplate, cloudinit.Data{
+		Server:       actRunner.Spec.ForgejoServer,
+		Organization: actRunner.Spec.Organization,
+		Name:         pod.Name,
+		Labels:       runnerlabels.ForRunner(actRunner.Spec.RunnerLabels, actRunner.Spec.RegistrationLabels, actRunner.Spec.JobData.RunsOn),
+		Token:        strings.TrimSpace(string(tokenSecret.Data["token"])),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The user data Secret is created before the VMI, so a failed attempt is retried until the VMI exists
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cloudInitSecretName(pod.Name),
+			Namespace: actRunner.Namespace,
+			Labels:    map[string]string{"forgejo.actions.io/actrunner": actRunner.Name},
+		},
+		Data: map[string][]byte{"userdata": []byte(userData)},
+	}
+	if err := ctrl.SetControllerReference(actRunner, secret, b.scheme); err != nil {
+		return err
+	}
+	if err := b.client.Create(ctx, secret); client.IgnoreAlreadyExists(err) != nil {
+		return fmt.Errorf("failed to create cloud-init secret: %w", err)
+	}
+
+	vmi := &unstructured.Unstructured{}
+	vmi.SetGroupVersionKind(virtualMachineInstanceGVK)
+	vmi.SetName(pod.Name)
+	vmi.SetNamespace(actRunner.Namespace)
+	vmi.SetLabels(pod.Labels)
+	vmi.SetAnnotations(pod.Annotations)
+	spec, err := virtualMachineInstanceSpec(vm, pod)
+	if err != nil {
+		return err
+	}
+	vmi.Object["spec"] = spec
+	if err := ctrl.SetControllerReference(actRunner, vmi, b.scheme); err != nil {
+		return err
+	}
+	err = b.client.Create(ctx, vmi)
+	if meta.IsNoMatchError(err) {
+		return fmt.Errorf("KubeVirt is not installed: %w", errBackendUnavailable)
+	}
+	return err
+}
+
+// virtualMachineInstanceSpec builds the VMI spec from the VM settings and the runner pod's scheduling constraints
+func virtualMachineInstanceSpec(vm *forgejoactionsiov1alpha1.VirtualMachineSpec, pod *corev1.Pod) (map[string]any, error) {
+	cpu := vm.CPU
+	if cpu == 0 {
+		cpu = 2
+	}
+	memory := resource.MustParse("4Gi")
+	if vm.Memory != nil {
+		memory = *vm.Memory
+	}
+
+	spec := map[string]any{
+		"terminationGracePeriodSeconds": int64(30),
+		"domain": map[string]any{
+			"cpu": map[string]any{"cores": int64(cpu)},
+			"resources": map[string]any{
+				"requests": map[string]any{"memory": memory.String()},
+			},
+			"devices": map[string]any{
+				"disks": []any{
+					map[string]any{"name": "rootdisk", "disk": map[string]any{"bus": "virtio"}},
+					map[string]any{"name": "cloudinit", "disk": map[string]any{"bus": "virtio"}},
+				},
+			},
+		},
+		"volumes": []any{
+			map[string]any{"name": "rootdisk", "containerDisk": map[string]any{"image": vm.Image}},
+			map[string]any{"name": "cloudinit", "cloudInitNoCloud": map[string]any{
+				"secretRef": map[string]any{"name": cloudInitSecretName(pod.Name)},
+			}},
+		},
+	}
+
+	if len(pod.Spec.NodeSelector) > 0 {
+		nodeSelector := make(map[string]any, len(pod.Spec.NodeSelector))
+		for k, v := range pod.Spec.NodeSelector {
+			nodeSelector[k] = v
+		}
+		spec["nodeSelector"] = nodeSelector
+	}
+	if len(pod.Spec.Tolerations) > 0 {
+		tolerations := make([]any, 0, len(pod.Spec.Tolerations))
+		for i := range pod.Spec.Tolerations {
+			toleration, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&pod.Spec.Tolerations[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert toleration: %w", err)
+			}
+			tolerations = append(tolerations, toleration)
+		}
+		spec["tolerations"] = tolerations
+	}
+	return spec, nil
+}
+
+// Pod reports the VMI as a pod, so the controller derives the runner phase the same way as for pods
+// A VM that powered off counts as a runner container that exited with 0, a failed VM as exit code 1
+func (b *kubeVirtBackend) Pod(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string) (*corev1.Pod, error) {
+	vmi := &unstructured.Unstructured{}
+	vmi.SetGroupVersionKind(virtualMachineInstanceGVK)
+	if err := b.client.Get(ctx, client.ObjectKey{Namespace: actRunner.Namespace, Name: name}, vmi); err != nil {
+		return nil, err
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              vmi.GetName(),
+			Namespace:         vmi.GetNamespace(),
+			Labels:            vmi.GetLabels(),
+			CreationTimestamp: vmi.GetCreationTimestamp(),
+			DeletionTimestamp: vmi.GetDeletionTimestamp(),
+		},
+	}
+	phase, _, _ := unstructured.NestedString(vmi.Object, "status", "phase")
+	switch phase {
+	case "Running":
+		pod.Status.Phase = corev1.PodRunning
+	case "Succeeded":
+		pod.Status.Phase = corev1.PodSucceeded
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{vmRunnerStatus(vmi, phase, 0, "Completed", "virtual machine powered off")}
+	case "Failed":
+		pod.Status.Phase = corev1.PodFailed
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{vmRunnerStatus(vmi, phase, 1, "VMFailed", "virtual machine failed")}
+	default:
+		pod.Status.Phase = corev1.PodPending
+	}
+	return pod, nil
+}
+
+// vmRunnerStatus returns a terminated runner container status for a finished VMI
+// The finish time is the VMI's transition into the phase, if KubeVirt recorded it
+func vmRunnerStatus(vmi *unstructured.Unstructured, phase string, exitCode int32, reason, message string) corev1.ContainerStatus {
+	var finishedAt metav1.Time
+	transitions, _, _ := unstructured.NestedSlice(vmi.Object, "status", "phaseTransitionTimestamps")
+	for _, transition := range transitions {
+		entry, ok := transition.(map[string]any)
+		if !ok || entry["phase"] != phase {
+			continue
+		}
+		if timestamp, ok := entry["phaseTransitionTimestamp"].(string); ok {
+			_ = finishedAt.UnmarshalQueryParameter(timestamp)
+		}
+	}
+	return corev1.ContainerStatus{
+		Name: "runner",
+		State: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{
+				ExitCode:   exitCode,
+				Reason:     reason,
+				Message:    message,
+				FinishedAt: finishedAt,
+			},
+		},
+	}
+}
+
+func (b *kubeVirtBackend) Delete(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string) error {
+	vmi := &unstructured.Unstructured{}
+	vmi.SetGroupVersionKind(virtualMachineInstanceGVK)
+	vmi.SetName(name)
+	vmi.SetNamespace(actRunner.Namespace)
+	if err := b.client.Delete(ctx, vmi); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	secret := &corev1.Secret{}
+	secret.Name = cloudInitSecretName(name)
+	secret.Namespace = actRunner.Namespace
+	return client.IgnoreNotFound(b.client.Delete(ctx, secret))
+}
+
+// Retain leaves the VMI to be deleted with the ActRunner, as a stopped VM only holds its disk
+func (b *kubeVirtBackend) Retain(context.Context, *forgejoactionsiov1alpha1.ActRunner, string, time.Time) error {
+	return nil
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Listener health", func() {
+	const namespace = "runners"
+
+	var (
+		ctx           context.Context
+		c             client.Client
+		recorder      *record.FakeRecorder
+		reconciler    *ActDeploymentReconciler
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+		deployment    *appsv1.Deployment
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &ActDeploymentReconciler{Client: c, Scheme: scheme.Scheme, Recorder: recorder}
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{ObjectMeta: metav1.ObjectMeta{Name: "health", Namespace: namespace}}
+		deployment = &appsv1.Deployment{}
+	})
+
+	// listenerPod returns a pod of the listener whose container has the restarts and waiting reason, if any
+	listenerPod := func(name string, restarts int32, waitingReason string) *corev1.Pod {
+		status := corev1.ContainerStatus{Name: "listener", RestartCount: restarts}
+		if waitingReason != "" {
+			status.State.Waiting = &corev1.ContainerStateWaiting{Reason: waitingReason, Message: "back-off restarting"}
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{"app": "forgejo-listener", "forgejo.actions.io/act-deployment": "health"},
+			},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{status}},
+		}
+	}
+
+	listenerReady := func() *metav1.Condition {
+		return meta.FindStatusCondition(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionListenerReady)
+	}
+
+	It("waits for the listener to become available", func() {
+		Expect(reconciler.reconcileListenerHealth(ctx, actDeployment, deployment)).To(Succeed())
+		Expect(listenerReady().Status).To(Equal(metav1.ConditionFalse))
+		Expect(listenerReady().Reason).To(Equal("ListenerPending"))
+
+		deployment.Status.AvailableReplicas = 1
+		Expect(reconciler.reconcileListenerHealth(ctx, actDeployment, deployment)).To(Succeed())
+		Expect(listenerReady().Status).To(Equal(metav1.ConditionTrue))
+		Expect(listenerReady().Reason).To(Equal("ListenerAvailable"))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("reports a crash looping listener with its last exit once, and its recovery", func() {
+		deployment.Status.AvailableReplicas = 1
+		pod := listenerPod("listener-1", 3, "CrashLoopBackOff")
+		pod.Status.ContainerStatuses[0].LastTerminationState.Terminated = &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}
+		Expect(c.Create(ctx, pod)).To(Succeed())
+		Expect(reconciler.reconcileListenerHealth(ctx, actDeployment, deployment)).To(Succeed())
+
+		Expect(listenerReady().Status).To(Equal(metav1.ConditionFalse))
+		Expect(listenerReady().Reason).To(Equal("CrashLoopBackOff"))
+		Expect(listenerReady().Message).To(Equal("listener pod listener-1 container listener: back-off restarting (last exit code 1, reason Error)"))
+		Expect(actDeployment.Status.ListenerRestarts).To(Equal(int32(3)))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning ListenerCrashLoopBackOff")))
+
+		Expect(reconciler.reconcileListenerHealth(ctx, actDeployment, deployment)).To(Succeed())
+		Expect(recorder.Events).To(BeEmpty())
+
+		By("recovering")
+		pod.Status.ContainerStatuses[0].State.Waiting = nil
+		Expect(c.Status().Update(ctx, pod)).To(Succeed())
+		Expect(reconciler.reconcileListenerHealth(ctx, actDeployment, deployment)).To(Succeed())
+		Expect(listenerReady().Status).To(Equal(metav1.ConditionTrue))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal ListenerRecovered")))
+	})
+
+	It("ignores slow starts and terminating pods", func() {
+		Expect(c.Create(ctx, listenerPod("starting", 0, "ContainerCreating"))).To(Succeed())
+		terminating := listenerPod("terminating", 5, "ImagePullBackOff")
+		terminating.Finalizers = []string{"test/keep"}
+		Expect(c.Create(ctx, terminating)).To(Succeed())
+		Expect(c.Delete(ctx, terminating)).To(Succeed())
+		Expect(reconciler.reconcileListenerHealth(ctx, actDeployment, deployment)).To(Succeed())
+
+		Expect(listenerReady().Reason).To(Equal("ListenerPending"))
+		Expect(actDeployment.Status.ListenerRestarts).To(BeZero())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("sums the restarts of the listener pods", func() {
+		Expect(c.Create(ctx, listenerPod("listener-1", 2, ""))).To(Succeed())
+		Expect(c.Create(ctx, listenerPod("listener-2", 1, ""))).To(Succeed())
+		Expect(reconciler.reconcileListenerHealth(ctx, actDeployment, deployment)).To(Succeed())
+
+		Expect(actDeployment.Status.ListenerRestarts).To(Equal(int32(3)))
+	})
+})
+
+var _ = Describe("Listener pod", func() {
+	// pod returns a pod created at the minute that is ready or not
+	pod := func(name string, minute int, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "runners",
+				Labels:            map[string]string{"app": "forgejo-listener", "forgejo.actions.io/act-deployment": "pods"},
+				CreationTimestamp: metav1.NewTime(time.Date(2025, 1, 1, 0, minute, 0, 0, time.UTC)),
+			},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+		}
+	}
+
+	DescribeTable("is the ready pod, or the newest during a rollout",
+		func(pods []*corev1.Pod, name string, ready bool) {
+			builder := clientfake.NewClientBuilder().WithScheme(scheme.Scheme)
+			for _, p := range pods {
+				builder = builder.WithObjects(p)
+			}
+			reconciler := &ActDeploymentReconciler{Client: builder.Build(), Scheme: scheme.Scheme}
+			actDeployment := &forgejoactionsiov1alpha1.ActDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "pods", Namespace: "runners"},
+				Status:     forgejoactionsiov1alpha1.ActDeploymentStatus{ListenerPodName: "stale", ListenerPodReady: true},
+			}
+			Expect(reconciler.reconcileListenerHealth(context.Background(), actDeployment, &appsv1.Deployment{})).To(Succeed())
+
+			Expect(actDeployment.Status.ListenerPodName).To(Equal(name))
+			Expect(actDeployment.Status.ListenerPodReady).To(Equal(ready))
+		},
+		Entry("no pods", nil, "", false),
+		Entry("a single unready pod", []*corev1.Pod{pod("old", 0, false)}, "old", false),
+		Entry("a ready pod and a newer unready one", []*corev1.Pod{pod("old", 0, true), pod("new", 1, false)}, "old", true),
+		Entry("two ready pods", []*corev1.Pod{pod("new", 1, true), pod("old", 0, true)}, "new", true),
+		Entry("two unready pods", []*corev1.Pod{pod("old", 0, false), pod("new", 1, false)}, "new", false),
+	)
+
+	It("is read from the Ready condition", func() {
+		Expect(isPodReady(pod("ready", 0, true))).To(BeTrue())
+		Expect(isPodReady(pod("unready", 0, false))).To(BeFalse())
+		Expect(isPodReady(&corev1.Pod{})).To(BeFalse())
+	})
+})
+
+var _ = Describe("Listener config hash", func() {
+	const namespace = "runners"
+
+	var (
+		ctx           context.Context
+		c             client.Client
+		reconciler    *ActDeploymentReconciler
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+		env           []corev1.EnvVar
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "forgejo-token", Namespace: namespace},
+			Data:       map[string][]byte{"token": []byte("token")},
+		}).Build()
+		reconciler = &ActDeploymentReconciler{Client: c, Scheme: scheme.Scheme}
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{ObjectMeta: metav1.ObjectMeta{Name: "hash", Namespace: namespace}}
+		env = []corev1.EnvVar{{Name: "POLL_INTERVAL", Value: "10s"}}
+	})
+
+	hash := func() string {
+		configHash, err := reconciler.listenerConfigHash(ctx, actDeployment, "forgejo-token", env)
+		Expect(err).NotTo(HaveOccurred())
+		return configHash
+	}
+
+	It("is stable for the same configuration", func() {
+		Expect(hash()).To(HaveLen(16))
+		Expect(hash()).To(Equal(hash()))
+	})
+
+	It("changes with the listener environment", func() {
+		base := hash()
+		env[0].Value = "30s"
+		Expect(hash()).NotTo(Equal(base))
+	})
+
+	It("changes when the token is rotated", func() {
+		base := hash()
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "forgejo-token"}, secret)).To(Succeed())
+		secret.Data["token"] = []byte("rotated")
+		Expect(c.Update(ctx, secret)).To(Succeed())
+		Expect(hash()).NotTo(Equal(base))
+	})
+
+	It("is computed while the token Secret is missing", func() {
+		configHash, err := reconciler.listenerConfigHash(ctx, actDeployment, "missing-token", env)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configHash).NotTo(BeEmpty())
+	})
+
+	It("is empty when the listener is not restarted automatically", func() {
+		actDeployment.Spec.ListenerAutoRestart = ptr.To(false)
+		Expect(hash()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,92 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/heartbeat"
+)
+
+// reconcileListenerHeartbeat sets the ListenerResponsive condition from the listener's heartbeat Lease
+// An expired Lease means the listener process runs but its poll loop is stuck, which the pod's health doesn't show
+// Unless RestartWedgedListener is false, the pod holding the Lease is deleted so the Deployment replaces it
+func (r *ActDeploymentReconciler) reconcileListenerHeartbeat(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, now time.Time) error {
+	log := logf.FromContext(ctx)
+
+	lease := &coordinationv1.Lease{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: heartbeat.LeaseName(actDeployment.Name)}, lease); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The listener hasn't started polling yet
+			meta.RemoveStatusCondition(&actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionListenerResponsive)
+			return nil
+		}
+		return err
+	}
+
+	holder := ptr.Deref(lease.Spec.HolderIdentity, "")
+	condition := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionListenerResponsive,
+		Status:             metav1.ConditionTrue,
+		Reason:             "HeartbeatRenewed",
+		Message:            fmt.Sprintf("listener pod %s renews its heartbeat", holder),
+		ObservedGeneration: actDeployment.Generation,
+	}
+	if heartbeat.Expired(lease, now) {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ListenerWedged"
+		condition.Message = fmt.Sprintf("listener pod %s hasn't renewed its heartbeat since %s", holder, lease.Spec.RenewTime.UTC().Format(time.RFC3339))
+
+		previous := meta.FindStatusCondition(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionListenerResponsive)
+		if previous == nil || previous.Status != metav1.ConditionFalse {
+			r.recordEvent(actDeployment, corev1.EventTypeWarning, "ListenerWedged", condition.Message)
+		}
+
+		if ptr.Deref(actDeployment.Spec.RestartWedgedListener, true) {
+			pod := &corev1.Pod{}
+			err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: holder}, pod)
+			switch {
+			case apierrors.IsNotFound(err):
+				// Already replaced; the new pod takes over the Lease with its first poll
+			case err != nil:
+				return err
+			case pod.Labels["forgejo.actions.io/act-deployment"] == actDeployment.Name && pod.DeletionTimestamp.IsZero():
+				log.Info("deleting wedged listener pod", "pod", pod.Name, "renewTime", lease.Spec.RenewTime)
+				if err := client.IgnoreNotFound(r.Delete(ctx, pod)); err != nil {
+					return err
+				}
+				r.recordEvent(actDeployment, corev1.EventTypeWarning, "ListenerRestarted", fmt.Sprintf("deleted wedged listener pod %s", pod.Name))
+			}
+		}
+	}
+
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, condition)
+	return nil
+}
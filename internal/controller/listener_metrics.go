@@ -0,0 +1,196 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+const (
+	// listenerMetricsPort is the port the listener serves metrics on when spec.metrics is set
+	listenerMetricsPort = 8080
+	// listenerMetricsPortName names the listener container port and the Service port
+	listenerMetricsPortName = "metrics"
+)
+
+// serviceMonitorGVK is the Prometheus Operator ServiceMonitor kind
+// It is handled as unstructured so the operator does not depend on the Prometheus Operator API module
+var serviceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+
+// reconcileListenerMetrics manages the Service and optional ServiceMonitor exposing the listener metrics
+// Both are deleted again when spec.metrics is removed, and for an embedded listener, whose metrics the manager serves
+func (r *ActDeploymentReconciler) reconcileListenerMetrics(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	name := fmt.Sprintf("%s-listener-metrics", actDeployment.Name)
+	metrics := actDeployment.Spec.Metrics
+	if actDeployment.Status.ListenerMode == forgejoactionsiov1alpha1.ListenerModeEmbedded {
+		metrics = nil
+	}
+
+	if err := r.reconcileListenerMetricsService(ctx, actDeployment, name, metrics != nil); err != nil {
+		return fmt.Errorf("failed to reconcile listener metrics service: %w", err)
+	}
+	if err := r.reconcileListenerServiceMonitor(ctx, actDeployment, name, metrics != nil && metrics.ServiceMonitor); err != nil {
+		return fmt.Errorf("failed to reconcile listener service monitor: %w", err)
+	}
+	return nil
+}
+
+func (r *ActDeploymentReconciler) reconcileListenerMetricsService(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, name string, enabled bool) error {
+	existing := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: name}, existing)
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	found := err == nil
+
+	if !enabled {
+		if found && metav1.IsControlledBy(existing, actDeployment) {
+			return client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return nil
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: actDeployment.Namespace,
+			Labels:    listenerMetricsLabels(actDeployment),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"app":                               "forgejo-listener",
+				"forgejo.actions.io/act-deployment": actDeployment.Name,
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       listenerMetricsPortName,
+					Port:       listenerMetricsPort,
+					TargetPort: intstr.FromString(listenerMetricsPortName),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+
+	if err := ctrl.SetControllerReference(actDeployment, service, r.Scheme); err != nil {
+		return err
+	}
+
+	if !found {
+		return r.Create(ctx, service)
+	}
+
+	// Update if needed; ClusterIP and other defaulted fields are kept
+	existing.Labels = service.Labels
+	existing.Spec.Selector = service.Spec.Selector
+	existing.Spec.Ports = service.Spec.Ports
+	return r.Update(ctx, existing)
+}
+
+func (r *ActDeploymentReconciler) reconcileListenerServiceMonitor(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, name string, enabled bool) error {
+	log := logf.FromContext(ctx)
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(serviceMonitorGVK)
+	err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: name}, existing)
+	if meta.IsNoMatchError(err) {
+		// Prometheus Operator is not installed
+		if enabled {
+			log.Info("ServiceMonitor CRD not installed, skipping listener ServiceMonitor")
+		}
+		return nil
+	}
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	found := err == nil
+
+	if !enabled {
+		if found && metav1.IsControlledBy(existing, actDeployment) {
+			return client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return nil
+	}
+
+	labels := listenerMetricsLabels(actDeployment)
+	for k, v := range actDeployment.Spec.Metrics.ServiceMonitorLabels {
+		labels[k] = v
+	}
+	endpoint := map[string]any{
+		"port": listenerMetricsPortName,
+		"path": "/metrics",
+	}
+	if interval := actDeployment.Spec.Metrics.Interval; interval != nil {
+		endpoint["interval"] = interval.Duration.String()
+	}
+
+	monitor := &unstructured.Unstructured{}
+	monitor.SetGroupVersionKind(serviceMonitorGVK)
+	monitor.SetName(name)
+	monitor.SetNamespace(actDeployment.Namespace)
+	monitor.SetLabels(labels)
+	monitor.Object["spec"] = map[string]any{
+		"selector": map[string]any{
+			"matchLabels": toAnyMap(listenerMetricsLabels(actDeployment)),
+		},
+		"endpoints": []any{endpoint},
+	}
+
+	if err := ctrl.SetControllerReference(actDeployment, monitor, r.Scheme); err != nil {
+		return err
+	}
+
+	if !found {
+		return r.Create(ctx, monitor)
+	}
+
+	// Update if needed
+	existing.SetLabels(labels)
+	existing.Object["spec"] = monitor.Object["spec"]
+	return r.Update(ctx, existing)
+}
+
+// listenerMetricsLabels returns the labels of the listener metrics Service, which the ServiceMonitor selects on
+func listenerMetricsLabels(actDeployment *forgejoactionsiov1alpha1.ActDeployment) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":            "forgejo-listener",
+		"app.kubernetes.io/component":       "metrics",
+		"forgejo.actions.io/act-deployment": actDeployment.Name,
+	}
+}
+
+func toAnyMap(m map[string]string) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Listener metrics", func() {
+	const namespace = "runners"
+
+	var (
+		ctx           context.Context
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: forgejoactionsiov1alpha1.GroupVersion.String(), Kind: "ActDeployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "metrics", Namespace: namespace, UID: "metrics-uid"},
+			Spec:       forgejoactionsiov1alpha1.ActDeploymentSpec{Metrics: &forgejoactionsiov1alpha1.MetricsSpec{}},
+		}
+	})
+
+	// newReconciler returns a reconciler whose cluster has the Prometheus Operator CRDs if withServiceMonitors is set
+	newReconciler := func(withServiceMonitors bool) *ActDeploymentReconciler {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		for gvk := range scheme.Scheme.AllKnownTypes() {
+			mapper.Add(gvk, meta.RESTScopeNamespace)
+		}
+		if withServiceMonitors {
+			mapper.Add(serviceMonitorGVK, meta.RESTScopeNamespace)
+		}
+		c := clientfake.NewClientBuilder().WithScheme(scheme.Scheme).WithRESTMapper(mapper).Build()
+		return &ActDeploymentReconciler{Client: c, Scheme: scheme.Scheme}
+	}
+
+	getService := func(r *ActDeploymentReconciler) (*corev1.Service, error) {
+		service := &corev1.Service{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "metrics-listener-metrics"}, service)
+		return service, err
+	}
+
+	getServiceMonitor := func(r *ActDeploymentReconciler) (*unstructured.Unstructured, error) {
+		monitor := &unstructured.Unstructured{}
+		monitor.SetGroupVersionKind(serviceMonitorGVK)
+		err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "metrics-listener-metrics"}, monitor)
+		return monitor, err
+	}
+
+	It("exposes the listener metrics port in a Service owned by the ActDeployment", func() {
+		r := newReconciler(false)
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+
+		service, err := getService(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metav1.IsControlledBy(service, actDeployment)).To(BeTrue())
+		Expect(service.Labels).To(Equal(listenerMetricsLabels(actDeployment)))
+		Expect(service.Spec.Selector).To(Equal(map[string]string{
+			"app":                               "forgejo-listener",
+			"forgejo.actions.io/act-deployment": "metrics",
+		}))
+		Expect(service.Spec.Ports).To(ConsistOf(corev1.ServicePort{
+			Name:       "metrics",
+			Port:       8080,
+			TargetPort: intstr.FromString("metrics"),
+			Protocol:   corev1.ProtocolTCP,
+		}))
+	})
+
+	It("keeps the defaulted fields of the Service when updating it", func() {
+		r := newReconciler(false)
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+		service, err := getService(r)
+		Expect(err).NotTo(HaveOccurred())
+		service.Spec.ClusterIP = "10.0.0.10"
+		service.Spec.Ports = nil
+		Expect(r.Update(ctx, service)).To(Succeed())
+
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+		service, err = getService(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(service.Spec.ClusterIP).To(Equal("10.0.0.10"))
+		Expect(service.Spec.Ports).To(HaveLen(1))
+	})
+
+	It("deletes the Service once metrics are disabled or the listener is embedded", func() {
+		r := newReconciler(false)
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+		actDeployment.Status.ListenerMode = forgejoactionsiov1alpha1.ListenerModeEmbedded
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+		_, err := getService(r)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		actDeployment.Status.ListenerMode = ""
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+		actDeployment.Spec.Metrics = nil
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+		_, err = getService(r)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("leaves a Service of the same name it doesn't own alone", func() {
+		r := newReconciler(false)
+		Expect(r.Create(ctx, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "metrics-listener-metrics", Namespace: namespace}})).To(Succeed())
+		actDeployment.Spec.Metrics = nil
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+
+		_, err := getService(r)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("creates a ServiceMonitor selecting the Service with the extra labels and interval", func() {
+		r := newReconciler(true)
+		actDeployment.Spec.Metrics = &forgejoactionsiov1alpha1.MetricsSpec{
+			ServiceMonitor:       true,
+			ServiceMonitorLabels: map[string]string{"release": "prometheus"},
+			Interval:             &metav1.Duration{Duration: 30 * time.Second},
+		}
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+
+		monitor, err := getServiceMonitor(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metav1.IsControlledBy(monitor, actDeployment)).To(BeTrue())
+		Expect(monitor.GetLabels()).To(HaveKeyWithValue("release", "prometheus"))
+		Expect(monitor.GetLabels()).To(HaveKeyWithValue("forgejo.actions.io/act-deployment", "metrics"))
+		matchLabels, _, err := unstructured.NestedStringMap(monitor.Object, "spec", "selector", "matchLabels")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matchLabels).To(Equal(listenerMetricsLabels(actDeployment)))
+		endpoints, _, err := unstructured.NestedSlice(monitor.Object, "spec", "endpoints")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoints).To(ConsistOf(map[string]any{"port": "metrics", "path": "/metrics", "interval": "30s"}))
+
+		By("disabling the ServiceMonitor")
+		actDeployment.Spec.Metrics.ServiceMonitor = false
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+		_, err = getServiceMonitor(r)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		_, err = getService(r)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("skips the ServiceMonitor when the Prometheus Operator is not installed", func() {
+		r := newReconciler(false)
+		actDeployment.Spec.Metrics.ServiceMonitor = true
+		Expect(r.reconcileListenerMetrics(ctx, actDeployment)).To(Succeed())
+
+		_, err := getService(r)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("serves the metrics on the named port of the listener container", func() {
+		r := newReconciler(false)
+		Expect(r.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "forgejo-token", Namespace: namespace},
+			Data:       map[string][]byte{"token": []byte("token")},
+		})).To(Succeed())
+		actDeployment.Spec.ListenerTemplate.Spec.Containers = []corev1.Container{{
+			Name:  "listener",
+			Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 8080}},
+		}}
+		conn := &forgejoConnection{server: "https://forgejo.example.com", organization: "org", tokenSecretName: "forgejo-token"}
+		deployment, err := r.reconcileListenerDeployment(ctx, actDeployment, "listener", conn)
+		Expect(err).NotTo(HaveOccurred())
+
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "METRICS_BIND_ADDRESS", Value: ":8080"}))
+		Expect(container.Ports).To(HaveLen(1), "the metrics port of the template is not added twice")
+
+		By("not setting a metrics port of its own")
+		actDeployment.Spec.ListenerTemplate.Spec.Containers[0].Ports = nil
+		deployment, err = r.reconcileListenerDeployment(ctx, actDeployment, "listener", conn)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deployment.Spec.Template.Spec.Containers[0].Ports).To(ConsistOf(corev1.ContainerPort{
+			Name:          "metrics",
+			ContainerPort: 8080,
+			Protocol:      corev1.ProtocolTCP,
+		}))
+	})
+})
@@ -0,0 +1,135 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/heartbeat"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/jobledger"
+)
+
+// listenerRoleRules returns the rules of the listener's Role, limited to what the enabled features use
+// The listener never deletes ActRunners; the ActRunner controller cleans them up
+func listenerRoleRules(actDeployment *forgejoactionsiov1alpha1.ActDeployment) []rbacv1.PolicyRule {
+	rules := []rbacv1.PolicyRule{
+		{
+			// Token and client certificate secrets, and the registration token secrets it creates,
+			// replaces after a failed creation, hands over to their ActRunners and deletes once orphaned
+			APIGroups: []string{""},
+			Resources: []string{"secrets"},
+			Verbs:     []string{"get", "list", "create", "update", "patch", "delete"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"events"},
+			Verbs:     []string{"create", "patch"},
+		},
+		{
+			APIGroups:     []string{"forgejo.actions.io"},
+			Resources:     []string{"actdeployments"},
+			ResourceNames: []string{actDeployment.Name},
+			Verbs:         []string{"get"},
+		},
+		{
+			APIGroups:     []string{"forgejo.actions.io"},
+			Resources:     []string{"actdeployments/status"},
+			ResourceNames: []string{actDeployment.Name},
+			Verbs:         []string{"patch"},
+		},
+		{
+			APIGroups: []string{"forgejo.actions.io"},
+			Resources: []string{"actrunners"},
+			Verbs:     []string{"create", "list", "update"},
+		},
+		{
+			APIGroups: []string{"forgejo.actions.io"},
+			Resources: []string{"actrunners/status"},
+			Verbs:     []string{"patch"},
+		},
+		{
+			// The job ledger; create can't be restricted by name
+			APIGroups:     []string{""},
+			Resources:     []string{"configmaps"},
+			ResourceNames: []string{jobledger.ConfigMapName(actDeployment.Name)},
+			Verbs:         []string{"get", "update"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps"},
+			Verbs:     []string{"create"},
+		},
+	}
+
+	if listenerClaimsLocally(actDeployment) {
+		// Job claims in the listener's namespace, which also covers the heartbeat Lease
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{"coordination.k8s.io"},
+			Resources: []string{"leases"},
+			Verbs:     []string{"get", "list", "create", "update", "delete"},
+		})
+	} else {
+		// Create can't be restricted by name, so only reads and renewals are
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups:     []string{"coordination.k8s.io"},
+			Resources:     []string{"leases"},
+			ResourceNames: []string{heartbeat.LeaseName(actDeployment.Name)},
+			Verbs:         []string{"get", "update"},
+		}, rbacv1.PolicyRule{
+			APIGroups: []string{"coordination.k8s.io"},
+			Resources: []string{"leases"},
+			Verbs:     []string{"create"},
+		})
+	}
+
+	if actDeployment.Spec.ListenerRBAC != nil {
+		rules = append(rules, actDeployment.Spec.ListenerRBAC.AdditionalRules...)
+	}
+	return rules
+}
+
+// listenerClaimsLocally reports whether the listener keeps job claims in its own namespace
+// A CLAIM_NAMESPACE from a valueFrom source can't be resolved here and counts as local
+func listenerClaimsLocally(actDeployment *forgejoactionsiov1alpha1.ActDeployment) bool {
+	containers := actDeployment.Spec.ListenerTemplate.Spec.Containers
+	if len(containers) == 0 {
+		return false
+	}
+	env := containers[0].Env
+	if kubeconfig, ok := envValue(env, "CLAIM_KUBECONFIG"); ok && kubeconfig != "" {
+		return false
+	}
+	for _, e := range env {
+		if e.Name == "CLAIM_NAMESPACE" {
+			return e.Value == actDeployment.Namespace || (e.Value == "" && e.ValueFrom != nil)
+		}
+	}
+	return false
+}
+
+// envValue returns the literal value of an environment variable and whether it is set
+// Values from a valueFrom source are returned empty
+func envValue(env []corev1.EnvVar, name string) (string, bool) {
+	for _, e := range env {
+		if e.Name == name {
+			return e.Value, true
+		}
+	}
+	return "", false
+}
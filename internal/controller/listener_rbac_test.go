@@ -0,0 +1,38 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Listener Role", func() {
+	It("lets the listener record its shutdown in the status of its own ActDeployment only", func() {
+		actDeployment := &forgejoactionsiov1alpha1.ActDeployment{ObjectMeta: metav1.ObjectMeta{Name: "runners", Namespace: "ci"}}
+		Expect(listenerRoleRules(actDeployment)).To(ContainElement(rbacv1.PolicyRule{
+			APIGroups:     []string{"forgejo.actions.io"},
+			Resources:     []string{"actdeployments/status"},
+			ResourceNames: []string{"runners"},
+			Verbs:         []string{"patch"},
+		}))
+	})
+})
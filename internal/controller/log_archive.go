@@ -0,0 +1,123 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/k8sutil"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/logarchive"
+)
+
+// +kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
+
+// maxArchivedLogBytes bounds the log read per container, so a chatty job can't exhaust the operator's memory
+const maxArchivedLogBytes = 64 << 20
+
+// archivedContainers are the runner pod containers whose logs are archived; the runner log comes first
+var archivedContainers = []string{"runner", "dind"}
+
+// needsLogArchive reports whether the finished runner's logs are still to be archived
+// Only pod-based backends have container logs; VM backends log to their console
+func (r *ActRunnerReconciler) needsLogArchive(actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) bool {
+	if r.Clientset == nil || pod == nil || actRunner.Spec.LogArchive == nil || actRunner.Status.LogArchiveURL != "" {
+		return false
+	}
+	return actRunner.Spec.Backend != forgejoactionsiov1alpha1.RunnerBackendKubeVirt &&
+		actRunner.Spec.Backend != forgejoactionsiov1alpha1.RunnerBackendExternalVM
+}
+
+// archiveLogs uploads the container logs of the finished runner's pod and records the URL of the runner log
+// A failure is recorded in the LogsArchived condition, so the caller can retry it
+func (r *ActRunnerReconciler) archiveLogs(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) error {
+	original := actRunner.DeepCopy()
+	url, err := r.uploadLogs(ctx, actRunner, pod)
+	condition := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionLogsArchived,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Uploaded",
+		Message:            fmt.Sprintf("logs uploaded to %s", url),
+		ObservedGeneration: actRunner.Generation,
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "UploadFailed"
+		condition.Message = err.Error()
+	} else {
+		actRunner.Status.LogArchiveURL = url
+	}
+	if meta.SetStatusCondition(&actRunner.Status.Conditions, condition) && err != nil {
+		r.recordDeploymentEvent(actRunner, corev1.EventTypeWarning, "LogArchiveFailed", fmt.Sprintf("ActRunner %s: %s", actRunner.Name, err))
+	}
+	if patchErr := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); patchErr != nil {
+		return patchErr
+	}
+	return err
+}
+
+// uploadLogs reads the logs of the pod's runner and DinD containers and uploads them to the log archive
+func (r *ActRunnerReconciler) uploadLogs(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) (string, error) {
+	spec := actRunner.Spec.LogArchive
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: actRunner.Namespace, Name: spec.CredentialsSecretName}, secret); err != nil {
+		return "", fmt.Errorf("failed to get log archive credentials secret %s: %w", spec.CredentialsSecretName, err)
+	}
+	uploader, err := logarchive.New(spec.Endpoint, spec.Bucket, spec.Region, logarchive.Credentials{
+		AccessKeyID:     string(secret.Data["accessKeyID"]),
+		SecretAccessKey: string(secret.Data["secretAccessKey"]),
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid log archive configuration: %w", err)
+	}
+
+	var runnerLogURL string
+	for _, container := range archivedContainers {
+		if !slices.ContainsFunc(pod.Spec.Containers, func(c corev1.Container) bool { return c.Name == container }) {
+			continue
+		}
+		limit := int64(maxArchivedLogBytes)
+		logs, err := r.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container:  container,
+			Timestamps: true,
+			LimitBytes: &limit,
+		}).DoRaw(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s container logs: %w", container, err)
+		}
+		key := path.Join(spec.Prefix, actRunner.Namespace, actRunner.Name, container+".log")
+		url, err := uploader.Put(ctx, key, "text/plain; charset=utf-8", logs)
+		if err != nil {
+			return "", err
+		}
+		if runnerLogURL == "" {
+			runnerLogURL = url
+		}
+	}
+	if runnerLogURL == "" {
+		return "", fmt.Errorf("pod %s has no runner container", pod.Name)
+	}
+	return runnerLogURL, nil
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// unservedJobs is the number of queued jobs whose runs-on labels no ActDeployment serves
+	unservedJobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "forgejo_actions_unserved_jobs",
+		Help: "Number of queued Forgejo jobs whose runs-on labels no ActDeployment serves",
+	}, []string{"server", "organization"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(unservedJobs)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// stuckWaitingReasons are the waiting reasons of containers that won't start without outside help
+var stuckWaitingReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"CrashLoopBackOff":           true,
+}
+
+// pendingTimedOut reports whether the runner has been Pending for longer than its pending timeout
+// The time is measured from the creation of the current pod, or of the ActRunner while no workload could be created
+// A scheduled pod is only stuck while a container waits for a reason in stuckWaitingReasons, so long init
+// containers (e.g., a workspace clone) and image pulls don't time out
+// It returns a message describing why the runner is stuck
+func pendingTimedOut(actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod, now time.Time) (string, bool) {
+	if actRunner.Spec.PendingTimeout == nil || actRunner.Status.Phase != forgejoactionsiov1alpha1.ActRunnerPhasePending {
+		return "", false
+	}
+	if pod != nil && podScheduled(pod) && stuckContainer(pod) == nil {
+		return "", false
+	}
+
+	var since time.Time
+	switch {
+	case pod != nil:
+		since = pod.CreationTimestamp.Time
+	case actRunner.Status.StartedAt != nil && actRunner.Status.KubernetesJobName != "":
+		// The workload exists but has no pod yet (e.g., a Job whose pods are rejected)
+		since = actRunner.Status.StartedAt.Time
+	case actRunner.Status.StartedAt == nil:
+		since = actRunner.CreationTimestamp.Time
+	default:
+		// The previous workload was replaced and the new one is about to be created
+		return "", false
+	}
+
+	timeout := actRunner.Spec.PendingTimeout.Duration
+	if since.IsZero() || now.Sub(since) < timeout {
+		return "", false
+	}
+	return fmt.Sprintf("runner was still pending after %s: %s", timeout, pendingReason(pod)), true
+}
+
+// pendingReason describes why a runner pod hasn't started, from its scheduling condition and waiting containers
+func pendingReason(pod *corev1.Pod) string {
+	if pod == nil {
+		return "no runner pod was created"
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			return fmt.Sprintf("pod %s is unschedulable: %s", pod.Name, condition.Message)
+		}
+	}
+	if status := stuckContainer(pod); status != nil {
+		waiting := status.State.Waiting
+		if waiting.Message == "" {
+			return fmt.Sprintf("container %s is waiting: %s", status.Name, waiting.Reason)
+		}
+		return fmt.Sprintf("container %s is waiting: %s: %s", status.Name, waiting.Reason, waiting.Message)
+	}
+	return fmt.Sprintf("pod %s is %s", pod.Name, pod.Status.Phase)
+}
+
+// podScheduled reports whether the pod was bound to a node
+func podScheduled(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// stuckContainer returns the status of the first (init) container waiting for a reason in stuckWaitingReasons
+func stuckContainer(pod *corev1.Pod) *corev1.ContainerStatus {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for i := range statuses {
+		if waiting := statuses[i].State.Waiting; waiting != nil && stuckWaitingReasons[waiting.Reason] {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Pending timeout", func() {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	longAgo := metav1.NewTime(now.Add(-time.Hour))
+
+	pendingRunner := func() *forgejoactionsiov1alpha1.ActRunner {
+		return &forgejoactionsiov1alpha1.ActRunner{
+			ObjectMeta: metav1.ObjectMeta{Name: "stuck", CreationTimestamp: longAgo},
+			Spec:       forgejoactionsiov1alpha1.ActRunnerSpec{PendingTimeout: &metav1.Duration{Duration: 10 * time.Minute}},
+			Status: forgejoactionsiov1alpha1.ActRunnerStatus{
+				Phase:             forgejoactionsiov1alpha1.ActRunnerPhasePending,
+				KubernetesJobName: "stuck-pod",
+				StartedAt:         &longAgo,
+			},
+		}
+	}
+	scheduled := corev1.PodCondition{Type: corev1.PodScheduled, Status: corev1.ConditionTrue}
+	unschedulable := corev1.PodCondition{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable",
+		Message: "0/3 nodes are available: 3 Insufficient cpu."}
+	waiting := func(name, reason, message string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: message}}}
+	}
+	pod := func(created metav1.Time, conditions []corev1.PodCondition, initContainers, containers []corev1.ContainerStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "stuck-pod", CreationTimestamp: created},
+			Status: corev1.PodStatus{
+				Phase:                 corev1.PodPending,
+				Conditions:            conditions,
+				InitContainerStatuses: initContainers,
+				ContainerStatuses:     containers,
+			},
+		}
+	}
+
+	DescribeTable("fails runners whose pod can't start",
+		func(pod *corev1.Pod, timedOut bool, message string) {
+			reason, stuck := pendingTimedOut(pendingRunner(), pod, now)
+			Expect(stuck).To(Equal(timedOut))
+			if timedOut {
+				Expect(reason).To(Equal("runner was still pending after 10m0s: " + message))
+			}
+		},
+		Entry("an unschedulable pod",
+			pod(longAgo, []corev1.PodCondition{unschedulable}, nil, nil),
+			true, "pod stuck-pod is unschedulable: 0/3 nodes are available: 3 Insufficient cpu."),
+		Entry("a pod the scheduler hasn't looked at yet",
+			pod(longAgo, nil, nil, nil),
+			true, "pod stuck-pod is Pending"),
+		Entry("a recently created unschedulable pod",
+			pod(metav1.NewTime(now.Add(-time.Minute)), []corev1.PodCondition{unschedulable}, nil, nil),
+			false, ""),
+		Entry("a scheduled pod with a long running init container",
+			pod(longAgo, []corev1.PodCondition{scheduled},
+				[]corev1.ContainerStatus{{Name: "workspace-init", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+				[]corev1.ContainerStatus{waiting("runner", "PodInitializing", "")}),
+			false, ""),
+		Entry("a scheduled pod pulling a large image",
+			pod(longAgo, []corev1.PodCondition{scheduled}, nil, []corev1.ContainerStatus{waiting("dind", "ContainerCreating", "")}),
+			false, ""),
+		Entry("a scheduled pod failing to pull its image",
+			pod(longAgo, []corev1.PodCondition{scheduled}, nil,
+				[]corev1.ContainerStatus{waiting("runner", "ContainerCreating", ""), waiting("dind", "ImagePullBackOff", "Back-off pulling image \"docker:dind\"")}),
+			true, "container dind is waiting: ImagePullBackOff: Back-off pulling image \"docker:dind\""),
+		Entry("a scheduled pod referencing a missing secret",
+			pod(longAgo, []corev1.PodCondition{scheduled}, nil, []corev1.ContainerStatus{waiting("runner", "CreateContainerConfigError", "")}),
+			true, "container runner is waiting: CreateContainerConfigError"),
+		Entry("a crashing init container",
+			pod(longAgo, []corev1.PodCondition{scheduled}, []corev1.ContainerStatus{waiting("workspace-init", "CrashLoopBackOff", "")}, nil),
+			true, "container workspace-init is waiting: CrashLoopBackOff"),
+	)
+
+	It("measures from the runner's creation while no workload could be created", func() {
+		actRunner := pendingRunner()
+		actRunner.Status.KubernetesJobName = ""
+		actRunner.Status.StartedAt = nil
+		reason, stuck := pendingTimedOut(actRunner, nil, now)
+		Expect(stuck).To(BeTrue())
+		Expect(reason).To(HaveSuffix("no runner pod was created"))
+
+		actRunner.CreationTimestamp = metav1.NewTime(now.Add(-time.Minute))
+		_, stuck = pendingTimedOut(actRunner, nil, now)
+		Expect(stuck).To(BeFalse())
+	})
+
+	It("ignores runners without a timeout or past Pending", func() {
+		actRunner := pendingRunner()
+		actRunner.Spec.PendingTimeout = nil
+		_, stuck := pendingTimedOut(actRunner, nil, now)
+		Expect(stuck).To(BeFalse())
+
+		actRunner = pendingRunner()
+		actRunner.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhaseRunning
+		_, stuck = pendingTimedOut(actRunner, pod(longAgo, nil, nil, nil), now)
+		Expect(stuck).To(BeFalse())
+	})
+
+	It("waits for the replacement workload of a runner whose workload was replaced", func() {
+		actRunner := pendingRunner()
+		actRunner.Status.KubernetesJobName = ""
+		_, stuck := pendingTimedOut(actRunner, nil, now)
+		Expect(stuck).To(BeFalse())
+	})
+})
@@ -0,0 +1,130 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;update;patch;delete
+
+// deleteAfterAnnotation holds the RFC 3339 time at which a retained runner workload is deleted
+const deleteAfterAnnotation = "forgejo.actions.io/delete-after"
+
+// workloadDeleteAt returns when the workload of the finished runner is to be deleted
+// It returns false if the workload is kept for as long as the ActRunner
+func workloadDeleteAt(actRunner *forgejoactionsiov1alpha1.ActRunner) (time.Time, bool) {
+	retention := actRunner.Spec.PodRetention
+	if retention == nil || actRunner.Status.CompletedAt == nil {
+		return time.Time{}, false
+	}
+	completedAt := actRunner.Status.CompletedAt.Time
+
+	keep := true
+	switch retention.Policy {
+	case forgejoactionsiov1alpha1.PodRetentionNever:
+		keep = false
+	case forgejoactionsiov1alpha1.PodRetentionOnFailure:
+		keep = actRunner.Status.Phase != forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded
+	}
+	if !keep {
+		return completedAt, true
+	}
+	if retention.TTL == nil {
+		return time.Time{}, false
+	}
+	return completedAt.Add(retention.TTL.Duration), true
+}
+
+// retainWorkload moves the controller reference of the runner's workload from the ActRunner to the runner's
+// ActDeployment and records when the workload is deleted
+// Workloads of ActRunners without an ActDeployment are left to be deleted with the ActRunner
+func retainWorkload(ctx context.Context, c client.Client, actRunner *forgejoactionsiov1alpha1.ActRunner, workload client.Object, deleteAt time.Time) error {
+	owner := metav1.GetControllerOf(actRunner)
+	if owner == nil {
+		return nil
+	}
+
+	patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+	annotations := workload.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[deleteAfterAnnotation] = deleteAt.UTC().Format(time.RFC3339)
+	workload.SetAnnotations(annotations)
+	workload.SetOwnerReferences([]metav1.OwnerReference{*owner})
+	if err := c.Patch(ctx, workload, patch); err != nil {
+		return fmt.Errorf("failed to retain runner workload %s: %w", workload.GetName(), err)
+	}
+	return nil
+}
+
+// deleteExpiredRunnerWorkloads deletes the retained workloads of the ActDeployment's finished runners
+// once their retention has passed
+func (r *ActDeploymentReconciler) deleteExpiredRunnerWorkloads(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	log := logf.FromContext(ctx)
+	selector := client.MatchingLabels{"forgejo.actions.io/act-deployment": actDeployment.Name}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(actDeployment.Namespace), selector); err != nil {
+		return fmt.Errorf("failed to list runner pods: %w", err)
+	}
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(actDeployment.Namespace), selector); err != nil {
+		return fmt.Errorf("failed to list runner jobs: %w", err)
+	}
+
+	var workloads []client.Object
+	for i := range pods.Items {
+		workloads = append(workloads, &pods.Items[i])
+	}
+	for i := range jobs.Items {
+		workloads = append(workloads, &jobs.Items[i])
+	}
+
+	now := time.Now()
+	for _, workload := range workloads {
+		value, ok := workload.GetAnnotations()[deleteAfterAnnotation]
+		if !ok || !metav1.IsControlledBy(workload, actDeployment) {
+			continue
+		}
+		deleteAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			log.Info("ignoring retained runner workload with an invalid deletion time", "workload", workload.GetName(), "deleteAfter", value)
+			continue
+		}
+		if now.Before(deleteAt) {
+			continue
+		}
+		log.Info("deleting retained runner workload", "workload", workload.GetName(), "deleteAfter", value)
+		if err := r.Delete(ctx, workload, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete retained runner workload %s: %w", workload.GetName(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Pod retention", func() {
+	const namespace = "runners"
+
+	completedAt := metav1.NewTime(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	hour := &metav1.Duration{Duration: time.Hour}
+
+	DescribeTable("schedules the deletion of finished runner workloads",
+		func(retention *forgejoactionsiov1alpha1.PodRetentionSpec, phase forgejoactionsiov1alpha1.ActRunnerPhase, after time.Duration, scheduled bool) {
+			actRunner := &forgejoactionsiov1alpha1.ActRunner{
+				Spec:   forgejoactionsiov1alpha1.ActRunnerSpec{PodRetention: retention},
+				Status: forgejoactionsiov1alpha1.ActRunnerStatus{Phase: phase, CompletedAt: &completedAt},
+			}
+			deleteAt, ok := workloadDeleteAt(actRunner)
+			Expect(ok).To(Equal(scheduled))
+			if scheduled {
+				Expect(deleteAt).To(Equal(completedAt.Add(after)))
+			}
+		},
+		Entry("without a retention", nil, forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded, time.Duration(0), false),
+		Entry("kept for as long as the ActRunner",
+			&forgejoactionsiov1alpha1.PodRetentionSpec{}, forgejoactionsiov1alpha1.ActRunnerPhaseFailed, time.Duration(0), false),
+		Entry("kept for the TTL",
+			&forgejoactionsiov1alpha1.PodRetentionSpec{TTL: hour}, forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded, time.Hour, true),
+		Entry("deleted right away with Never",
+			&forgejoactionsiov1alpha1.PodRetentionSpec{Policy: forgejoactionsiov1alpha1.PodRetentionNever, TTL: hour},
+			forgejoactionsiov1alpha1.ActRunnerPhaseFailed, time.Duration(0), true),
+		Entry("deleted right away with OnFailure after the runner succeeded",
+			&forgejoactionsiov1alpha1.PodRetentionSpec{Policy: forgejoactionsiov1alpha1.PodRetentionOnFailure, TTL: hour},
+			forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded, time.Duration(0), true),
+		Entry("kept for the TTL with OnFailure after the runner was cancelled",
+			&forgejoactionsiov1alpha1.PodRetentionSpec{Policy: forgejoactionsiov1alpha1.PodRetentionOnFailure, TTL: hour},
+			forgejoactionsiov1alpha1.ActRunnerPhaseCancelled, time.Hour, true),
+	)
+
+	It("doesn't schedule the deletion of unfinished runner workloads", func() {
+		actRunner := &forgejoactionsiov1alpha1.ActRunner{
+			Spec: forgejoactionsiov1alpha1.ActRunnerSpec{
+				PodRetention: &forgejoactionsiov1alpha1.PodRetentionSpec{Policy: forgejoactionsiov1alpha1.PodRetentionNever},
+			},
+		}
+		_, scheduled := workloadDeleteAt(actRunner)
+		Expect(scheduled).To(BeFalse())
+	})
+
+	Describe("retained workloads", func() {
+		var (
+			ctx           context.Context
+			c             client.Client
+			actDeployment *forgejoactionsiov1alpha1.ActDeployment
+			actRunner     *forgejoactionsiov1alpha1.ActRunner
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+			actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "retaining", Namespace: namespace, UID: "deployment-uid"},
+			}
+			actRunner = &forgejoactionsiov1alpha1.ActRunner{
+				ObjectMeta: metav1.ObjectMeta{Name: "runner-1", Namespace: namespace, UID: "runner-uid"},
+			}
+			Expect(controllerutil.SetControllerReference(actDeployment, actRunner, scheme.Scheme)).To(Succeed())
+		})
+
+		runnerPod := func(name string) *corev1.Pod {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{"forgejo.actions.io/act-deployment": actDeployment.Name},
+			}}
+			Expect(controllerutil.SetControllerReference(actRunner, pod, scheme.Scheme)).To(Succeed())
+			Expect(c.Create(ctx, pod)).To(Succeed())
+			return pod
+		}
+
+		It("hands the workload over to the ActDeployment until its deletion time", func() {
+			pod := runnerPod("runner-1-pod")
+			deleteAt := completedAt.Add(time.Hour)
+			Expect(retainWorkload(ctx, c, actRunner, pod, deleteAt)).To(Succeed())
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+			Expect(metav1.IsControlledBy(pod, actDeployment)).To(BeTrue())
+			Expect(pod.OwnerReferences).To(HaveLen(1))
+			Expect(pod.Annotations).To(HaveKeyWithValue(deleteAfterAnnotation, "2026-01-01T13:00:00Z"))
+		})
+
+		It("leaves the workloads of ActRunners without an ActDeployment alone", func() {
+			actRunner.OwnerReferences = nil
+			pod := runnerPod("runner-1-pod")
+			Expect(retainWorkload(ctx, c, actRunner, pod, completedAt.Time)).To(Succeed())
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+			Expect(metav1.IsControlledBy(pod, actRunner)).To(BeTrue())
+			Expect(pod.Annotations).NotTo(HaveKey(deleteAfterAnnotation))
+		})
+
+		It("deletes the retained pods and Jobs of the ActDeployment once their retention passed", func() {
+			reconciler := &ActDeploymentReconciler{Client: c, Scheme: scheme.Scheme}
+			retain := func(workload client.Object, deleteAt time.Time) {
+				Expect(retainWorkload(ctx, c, actRunner, workload, deleteAt)).To(Succeed())
+			}
+
+			expiredPod := runnerPod("expired")
+			retain(expiredPod, time.Now().Add(-time.Minute))
+			retainedPod := runnerPod("retained")
+			retain(retainedPod, time.Now().Add(time.Hour))
+			runningPod := runnerPod("running")
+
+			expiredJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+				Name:      "expired-job",
+				Namespace: namespace,
+				Labels:    map[string]string{"forgejo.actions.io/act-deployment": actDeployment.Name},
+			}}
+			Expect(c.Create(ctx, expiredJob)).To(Succeed())
+			retain(expiredJob, time.Now().Add(-time.Minute))
+
+			invalidPod := runnerPod("invalid")
+			retain(invalidPod, time.Now().Add(-time.Minute))
+			invalidPod.Annotations[deleteAfterAnnotation] = "yesterday"
+			Expect(c.Update(ctx, invalidPod)).To(Succeed())
+
+			Expect(reconciler.deleteExpiredRunnerWorkloads(ctx, actDeployment)).To(Succeed())
+
+			exists := func(workload client.Object) bool {
+				err := c.Get(ctx, client.ObjectKeyFromObject(workload), workload)
+				Expect(client.IgnoreNotFound(err)).To(Succeed())
+				return !errors.IsNotFound(err)
+			}
+			Expect(exists(expiredPod)).To(BeFalse())
+			Expect(exists(expiredJob)).To(BeFalse())
+			Expect(exists(retainedPod)).To(BeTrue())
+			Expect(exists(runningPod)).To(BeTrue())
+			Expect(exists(invalidPod)).To(BeTrue())
+		})
+	})
+})
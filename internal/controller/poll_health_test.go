@@ -0,0 +1,98 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Listener poll health", func() {
+	newActDeployment := func(failures int32, threshold *int32) *forgejoactionsiov1alpha1.ActDeployment {
+		pollTime := metav1.Now()
+		return &forgejoactionsiov1alpha1.ActDeployment{
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{PollFailureThreshold: threshold},
+			Status: forgejoactionsiov1alpha1.ActDeploymentStatus{
+				LastPollTime:            &pollTime,
+				ConsecutivePollFailures: failures,
+				LastPollError:           "connection refused",
+			},
+		}
+	}
+
+	DescribeTable("sets the Degraded condition from the consecutive poll failures",
+		func(failures int32, threshold *int32, status metav1.ConditionStatus, reason string) {
+			actDeployment := newActDeployment(failures, threshold)
+			setDegradedCondition(actDeployment)
+
+			degraded := meta.FindStatusCondition(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionDegraded)
+			Expect(degraded).NotTo(BeNil())
+			Expect(degraded.Status).To(Equal(status))
+			Expect(degraded.Reason).To(Equal(reason))
+		},
+		Entry("polls succeed", int32(0), nil, metav1.ConditionFalse, "PollsSucceeding"),
+		Entry("failures below the default threshold", int32(2), nil, metav1.ConditionFalse, "PollsFailingIntermittently"),
+		Entry("failures reach the default threshold", int32(3), nil, metav1.ConditionTrue, "PollsFailing"),
+		Entry("failures below a custom threshold", int32(3), ptr.To(int32(5)), metav1.ConditionFalse, "PollsFailingIntermittently"),
+		Entry("failures reach a custom threshold", int32(1), ptr.To(int32(1)), metav1.ConditionTrue, "PollsFailing"),
+	)
+
+	It("quotes the last poll error once degraded", func() {
+		actDeployment := newActDeployment(4, nil)
+		setDegradedCondition(actDeployment)
+
+		degraded := meta.FindStatusCondition(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionDegraded)
+		Expect(degraded.Message).To(Equal("4 listener polls failed in a row: connection refused"))
+	})
+
+	It("leaves the condition out until the listener reported a poll", func() {
+		actDeployment := newActDeployment(0, nil)
+		setDegradedCondition(actDeployment)
+		actDeployment.Status.LastPollTime = nil
+		setDegradedCondition(actDeployment)
+
+		Expect(meta.FindStatusCondition(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionDegraded)).To(BeNil())
+	})
+
+	It("makes a ready ActDeployment not ready while degraded", func() {
+		actDeployment := newActDeployment(3, nil)
+		meta.SetStatusCondition(&actDeployment.Status.Conditions, metav1.Condition{
+			Type:   forgejoactionsiov1alpha1.ConditionListenerReady,
+			Status: metav1.ConditionTrue,
+			Reason: "ListenerAvailable",
+		})
+		setDeploymentReadyCondition(actDeployment)
+		Expect(meta.IsStatusConditionTrue(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionReady)).To(BeTrue())
+
+		setDegradedCondition(actDeployment)
+		setDeploymentReadyCondition(actDeployment)
+		ready := meta.FindStatusCondition(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionReady)
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal("PollsFailing"))
+
+		By("recovering")
+		actDeployment.Status.ConsecutivePollFailures = 0
+		setDegradedCondition(actDeployment)
+		setDeploymentReadyCondition(actDeployment)
+		Expect(meta.IsStatusConditionTrue(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionReady)).To(BeTrue())
+	})
+})
@@ -0,0 +1,91 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/scheduling"
+)
+
+// reconcilePriorityClass creates the PriorityClass for the ActDeployment's runner priority
+// PriorityClasses are shared by all ActDeployments with the same priority and are never deleted
+// They don't preempt running pods, so a higher-priority runner never kills another runner's job
+func (r *ActDeploymentReconciler) reconcilePriorityClass(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	if actDeployment.Spec.Priority == nil {
+		return nil
+	}
+
+	name := scheduling.PriorityClassName(*actDeployment.Spec.Priority)
+	existing := &schedulingv1.PriorityClass{}
+	err := r.Get(ctx, client.ObjectKey{Name: name}, existing)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	preemptNever := corev1.PreemptNever
+	priorityClass := &schedulingv1.PriorityClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"app.kubernetes.io/managed-by": "forgejo-act-runner-controller"},
+		},
+		Value:            *actDeployment.Spec.Priority,
+		PreemptionPolicy: &preemptNever,
+		Description:      fmt.Sprintf("Forgejo runners of ActDeployments with priority %d", *actDeployment.Spec.Priority),
+	}
+	if err := r.Create(ctx, priorityClass); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	logf.FromContext(ctx).Info("created runner PriorityClass", "priorityClass", name)
+	return nil
+}
+
+// runnerPriority returns the priority of an ActRunner, 0 if it has none
+func runnerPriority(actRunner *forgejoactionsiov1alpha1.ActRunner) int32 {
+	if actRunner.Spec.Priority == nil {
+		return 0
+	}
+	return *actRunner.Spec.Priority
+}
+
+// higherPriorityRunnerWaiting returns the name of a Pending runner in the namespace with a higher priority
+// that has no workload yet, e.g. because it is backing off from a quota rejection
+// Lower-priority runners defer their workloads until then, so higher-priority runners are created first
+func (r *ActRunnerReconciler) higherPriorityRunnerWaiting(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner) (string, error) {
+	actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+	if err := r.List(ctx, actRunners, client.InNamespace(actRunner.Namespace)); err != nil {
+		return "", err
+	}
+	priority := runnerPriority(actRunner)
+	for i := range actRunners.Items {
+		other := &actRunners.Items[i]
+		if runnerPriority(other) > priority && other.DeletionTimestamp == nil &&
+			other.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhasePending && other.Status.KubernetesJobName == "" {
+			return other.Name, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/k8sutil"
+)
+
+const (
+	// minQuotaBackoff and maxQuotaBackoff bound how long creation waits after a quota rejection
+	minQuotaBackoff = 30 * time.Second
+	maxQuotaBackoff = 5 * time.Minute
+)
+
+// quotaRejection reports whether the API server rejected a workload because of a ResourceQuota or LimitRange
+// It returns the condition reason naming the policy
+func quotaRejection(err error) (string, bool) {
+	if !apierrors.IsForbidden(err) {
+		return "", false
+	}
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "exceeded quota") || strings.Contains(message, "failed quota"):
+		return "ResourceQuota", true
+	case strings.Contains(message, "usage per Container") || strings.Contains(message, "usage per Pod") ||
+		strings.Contains(message, "limit to request ratio"):
+		return "LimitRange", true
+	}
+	return "", false
+}
+
+// quotaBackoff waits as long as the runner has been rejected so far, within minQuotaBackoff and maxQuotaBackoff
+func quotaBackoff(condition *metav1.Condition, now time.Time) time.Duration {
+	if condition == nil {
+		return minQuotaBackoff
+	}
+	return min(max(now.Sub(condition.LastTransitionTime.Time), minQuotaBackoff), maxQuotaBackoff)
+}
+
+// backOffQuotaRejection records a quota rejection in the ActRunner's conditions and on its ActDeployment,
+// and schedules the next creation attempt with a backoff
+func (r *ActRunnerReconciler) backOffQuotaRejection(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, reason string, cause error) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	original := actRunner.DeepCopy()
+
+	changed := meta.SetStatusCondition(&actRunner.Status.Conditions, metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionQuotaExceeded,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            cause.Error(),
+		ObservedGeneration: actRunner.Generation,
+	})
+	if changed {
+		log.Info("runner workload rejected by quota, backing off", "actRunner", actRunner.Name, "reason", reason, "message", cause.Error())
+		if err := k8sutil.PatchStatus(ctx, r.Client, actRunner, original); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.recordDeploymentEvent(actRunner, corev1.EventTypeWarning, "RunnerQuotaExceeded", fmt.Sprintf("ActRunner %s: %s", actRunner.Name, cause))
+	}
+
+	condition := meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionQuotaExceeded)
+	return ctrl.Result{RequeueAfter: quotaBackoff(condition, time.Now())}, nil
+}
+
+// recordDeploymentEvent emits an event on the ActDeployment owning the ActRunner if an event recorder is configured
+func (r *ActRunnerReconciler) recordDeploymentEvent(actRunner *forgejoactionsiov1alpha1.ActRunner, eventType, reason, message string) {
+	owner := metav1.GetControllerOf(actRunner)
+	if r.Recorder == nil || owner == nil || owner.Kind != "ActDeployment" {
+		return
+	}
+	actDeployment := &forgejoactionsiov1alpha1.ActDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: owner.Name, Namespace: actRunner.Namespace, UID: owner.UID},
+	}
+	r.Recorder.Event(actDeployment, eventType, reason, message)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Quota rejections", func() {
+	forbidden := func(message string) error {
+		return apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "runner-1", errors.New(message))
+	}
+
+	DescribeTable("are told apart from other errors",
+		func(err error, reason string, rejected bool) {
+			gotReason, gotRejected := quotaRejection(err)
+			Expect(gotRejected).To(Equal(rejected))
+			Expect(gotReason).To(Equal(reason))
+		},
+		Entry("an exceeded ResourceQuota",
+			forbidden("exceeded quota: compute, requested: limits.cpu=2, used: limits.cpu=8, limited: limits.cpu=8"), "ResourceQuota", true),
+		Entry("a ResourceQuota requiring limits",
+			forbidden("failed quota: compute: must specify limits.cpu for: runner"), "ResourceQuota", true),
+		Entry("a LimitRange maximum",
+			forbidden("maximum cpu usage per Container is 1, but limit is 2"), "LimitRange", true),
+		Entry("a LimitRange ratio",
+			forbidden("cpu max limit to request ratio per Container is 2, but provided ratio is 4.000000"), "LimitRange", true),
+		Entry("another forbidden error", forbidden("pods is forbidden: User cannot create resource"), "", false),
+		Entry("a non-forbidden error mentioning a quota", errors.New("exceeded quota: compute"), "", false),
+	)
+
+	DescribeTable("back off for as long as the runner has been rejected",
+		func(rejectedFor time.Duration, backoff time.Duration) {
+			now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+			condition := &metav1.Condition{LastTransitionTime: metav1.NewTime(now.Add(-rejectedFor))}
+			Expect(quotaBackoff(condition, now)).To(Equal(backoff))
+		},
+		Entry("at least minQuotaBackoff", 5*time.Second, minQuotaBackoff),
+		Entry("in between", 2*time.Minute, 2*time.Minute),
+		Entry("at most maxQuotaBackoff", time.Hour, maxQuotaBackoff),
+	)
+
+	It("back off for minQuotaBackoff after the first rejection", func() {
+		Expect(quotaBackoff(nil, time.Now())).To(Equal(minQuotaBackoff))
+	})
+
+	It("are recorded on the ActRunner and its ActDeployment once", func() {
+		ctx := context.Background()
+		c := clientfake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithStatusSubresource(&forgejoactionsiov1alpha1.ActRunner{}).Build()
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &ActRunnerReconciler{Client: c, Scheme: scheme.Scheme, Recorder: recorder}
+
+		actRunner := &forgejoactionsiov1alpha1.ActRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "runner-1",
+				Namespace: "runners",
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: forgejoactionsiov1alpha1.GroupVersion.String(),
+					Kind:       "ActDeployment",
+					Name:       "deploy",
+					UID:        "deploy-uid",
+					Controller: func() *bool { b := true; return &b }(),
+				}},
+			},
+		}
+		Expect(c.Create(ctx, actRunner)).To(Succeed())
+		cause := forbidden("exceeded quota: compute")
+
+		result, err := reconciler.backOffQuotaRejection(ctx, actRunner, "ResourceQuota", cause)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(minQuotaBackoff))
+		Expect(recorder.Events).To(Receive(And(ContainSubstring(corev1.EventTypeWarning), ContainSubstring("RunnerQuotaExceeded"))))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(actRunner), actRunner)).To(Succeed())
+		condition := meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionQuotaExceeded)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("ResourceQuota"))
+
+		By("being rejected again")
+		_, err = reconciler.backOffQuotaRejection(ctx, actRunner, "ResourceQuota", cause)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
@@ -0,0 +1,134 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/k8sutil"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/recommend"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerphase"
+)
+
+// +kubebuilder:rbac:groups=metrics.k8s.io,resources=pods,verbs=get
+
+// usageRecordedAnnotation marks finished ActRunners whose peak usage was folded into the recommendations
+const usageRecordedAnnotation = "forgejo.actions.io/usage-recorded"
+
+var podMetricsGVK = schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetrics"}
+
+// recordPeakUsage samples the runner pod's usage from metrics-server and keeps the highest CPU and memory seen
+func (r *ActRunnerReconciler) recordPeakUsage(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) error {
+	metrics := &unstructured.Unstructured{}
+	metrics.SetGroupVersionKind(podMetricsGVK)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, metrics); err != nil {
+		return fmt.Errorf("failed to get pod metrics: %w", err)
+	}
+	usage, err := podUsage(metrics)
+	if err != nil {
+		return err
+	}
+
+	original := actRunner.DeepCopy()
+	peak := actRunner.Status.PeakUsage
+	if peak == nil {
+		peak = &forgejoactionsiov1alpha1.ResourceUsage{}
+	}
+	if usage.CPU.Cmp(peak.CPU) <= 0 && usage.Memory.Cmp(peak.Memory) <= 0 {
+		return nil
+	}
+	if usage.CPU.Cmp(peak.CPU) > 0 {
+		peak.CPU = usage.CPU
+	}
+	if usage.Memory.Cmp(peak.Memory) > 0 {
+		peak.Memory = usage.Memory
+	}
+	actRunner.Status.PeakUsage = peak
+	return k8sutil.PatchStatus(ctx, r.Client, actRunner, original)
+}
+
+// podUsage sums the usage of all containers of a PodMetrics object
+func podUsage(metrics *unstructured.Unstructured) (forgejoactionsiov1alpha1.ResourceUsage, error) {
+	var usage forgejoactionsiov1alpha1.ResourceUsage
+	containers, _, err := unstructured.NestedSlice(metrics.Object, "containers")
+	if err != nil {
+		return usage, fmt.Errorf("failed to read pod metrics: %w", err)
+	}
+	for _, c := range containers {
+		container, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		values, _, _ := unstructured.NestedStringMap(container, "usage")
+		for name, sum := range map[string]*resource.Quantity{"cpu": &usage.CPU, "memory": &usage.Memory} {
+			if values[name] == "" {
+				continue
+			}
+			quantity, err := resource.ParseQuantity(values[name])
+			if err != nil {
+				return usage, fmt.Errorf("failed to parse %s usage: %w", name, err)
+			}
+			sum.Add(quantity)
+		}
+	}
+	return usage, nil
+}
+
+// reconcileResourceRecommendations folds the peak usage of the ActDeployment's finished runners into the
+// recommendations in status; each runner is counted once
+func (r *ActDeploymentReconciler) reconcileResourceRecommendations(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, now time.Time) error {
+	if actDeployment.Spec.ResourceRecommendations == nil {
+		actDeployment.Status.ResourceRecommendations = nil
+		return nil
+	}
+
+	actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+	if err := r.List(ctx, actRunners, client.InNamespace(actDeployment.Namespace)); err != nil {
+		return err
+	}
+	for i := range actRunners.Items {
+		ar := &actRunners.Items[i]
+		if owner := metav1.GetControllerOf(ar); owner == nil || owner.UID != actDeployment.UID {
+			continue
+		}
+		if ar.Status.PeakUsage == nil || ar.Annotations[usageRecordedAnnotation] != "" || !runnerphase.Finished(ar.Status.Phase) {
+			continue
+		}
+		patch := client.MergeFrom(ar.DeepCopy())
+		if ar.Annotations == nil {
+			ar.Annotations = map[string]string{}
+		}
+		ar.Annotations[usageRecordedAnnotation] = "true"
+		if err := r.Patch(ctx, ar, patch); err != nil {
+			return fmt.Errorf("failed to mark ActRunner %s as recorded: %w", ar.Name, err)
+		}
+		actDeployment.Status.ResourceRecommendations = recommend.Fold(actDeployment.Status.ResourceRecommendations,
+			recommend.Key(ar.Spec.JobData.RunsOn), *ar.Status.PeakUsage, metav1.NewTime(now))
+	}
+	return nil
+}
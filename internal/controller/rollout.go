@@ -0,0 +1,81 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/rollout"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerphase"
+)
+
+// runnerTemplateHash computes the template hash of the ActDeployment's current runner configuration
+func runnerTemplateHash(actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) string {
+	return rollout.TemplateHash(effectiveRunnerSpec(actDeployment, conn), actDeployment.Status.ResourceRecommendations)
+}
+
+// effectiveRunnerSpec returns the ActDeployment's spec with the image defaults applied the same way the listener
+// applies them before stamping ActRunners
+func effectiveRunnerSpec(actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) *forgejoactionsiov1alpha1.ActDeploymentSpec {
+	spec := actDeployment.Spec.DeepCopy()
+	if source := spec.RunnerImageFrom; source != nil {
+		spec.RunnerImage = source.Image
+		if actDeployment.Status.ResolvedRunnerImage != "" {
+			spec.RunnerImage = actDeployment.Status.ResolvedRunnerImage
+		}
+	}
+	if spec.RunnerImage == "" {
+		spec.RunnerImage = conn.runnerImage
+	}
+	if spec.DockerInDockerImage == "" {
+		spec.DockerInDockerImage = conn.dockerInDockerImage
+	}
+	return spec
+}
+
+// rolloutStatus counts the active runners of the ActDeployment by whether they use the current runner configuration
+// A runner whose workload exists counts by the configuration the workload was created from,
+// otherwise by the configuration its spec was last updated to
+func (r *ActDeploymentReconciler) rolloutStatus(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) (*forgejoactionsiov1alpha1.RolloutStatus, error) {
+	actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+	if err := r.List(ctx, actRunners, client.InNamespace(actDeployment.Namespace)); err != nil {
+		return nil, err
+	}
+
+	status := &forgejoactionsiov1alpha1.RolloutStatus{TemplateHash: runnerTemplateHash(actDeployment, conn)}
+	for i := range actRunners.Items {
+		ar := &actRunners.Items[i]
+		if !metav1.IsControlledBy(ar, actDeployment) || runnerphase.Finished(ar.Status.Phase) {
+			continue
+		}
+
+		hash := ar.Annotations[rollout.TemplateHashAnnotation]
+		if ar.Status.KubernetesJobName != "" && ar.Status.TemplateHash != "" {
+			hash = ar.Status.TemplateHash
+		}
+		if hash == status.TemplateHash {
+			status.UpdatedRunners++
+		} else {
+			status.OutdatedRunners++
+		}
+	}
+	return status, nil
+}
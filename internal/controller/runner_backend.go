@@ -0,0 +1,194 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// errBackendUnavailable is returned by backends that cannot run runners, because they are not implemented or installed
+var errBackendUnavailable = errors.New("runner backend is not available")
+
+// errPodNonCompliant is returned when the generated runner pod violates the runner's pod compliance settings
+var errPodNonCompliant = errors.New("runner pod violates spec.podCompliance")
+
+// RunnerBackend creates and observes the workload that executes an ActRunner
+// The ActRunner controller builds the runner pod and derives the runner phase from its container statuses;
+// backends only decide how the pod is run
+type RunnerBackend interface {
+	// Create starts a workload named after the pod that runs the pod
+	// It returns an AlreadyExists error if the workload exists
+	Create(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) error
+
+	// Pod returns the pod of the named workload
+	// It returns a NotFound error if the workload is gone, and a nil pod if the workload has no pod yet
+	Pod(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string) (*corev1.Pod, error)
+
+	// Delete removes the named workload and its pod
+	Delete(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string) error
+
+	// Retain hands the named workload over to the runner's ActDeployment, so it outlives the ActRunner
+	// until deleteAt; backends that can't retain workloads leave them to be deleted with the ActRunner
+	Retain(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string, deleteAt time.Time) error
+}
+
+// runnerBackend returns the backend selected by the ActRunner
+func (r *ActRunnerReconciler) runnerBackend(actRunner *forgejoactionsiov1alpha1.ActRunner) RunnerBackend {
+	switch actRunner.Spec.Backend {
+	case forgejoactionsiov1alpha1.RunnerBackendJob:
+		return &jobBackend{client: r.Client, scheme: r.Scheme}
+	case forgejoactionsiov1alpha1.RunnerBackendKubeVirt:
+		return &kubeVirtBackend{client: r.Client, scheme: r.Scheme}
+	case forgejoactionsiov1alpha1.RunnerBackendExternalVM:
+		return externalVMBackend{}
+	default:
+		return &podBackend{client: r.Client, scheme: r.Scheme}
+	}
+}
+
+// podBackend runs the runner in a bare Pod owned by the ActRunner
+type podBackend struct {
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+func (b *podBackend) Create(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) error {
+	if err := ctrl.SetControllerReference(actRunner, pod, b.scheme); err != nil {
+		return err
+	}
+	return b.client.Create(ctx, pod)
+}
+
+func (b *podBackend) Pod(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string) (*corev1.Pod, error) {
+	pod := &corev1.Pod{}
+	if err := b.client.Get(ctx, client.ObjectKey{Namespace: actRunner.Namespace, Name: name}, pod); err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+func (b *podBackend) Delete(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string) error {
+	pod := &corev1.Pod{}
+	pod.Name = name
+	pod.Namespace = actRunner.Namespace
+	return client.IgnoreNotFound(b.client.Delete(ctx, pod))
+}
+
+func (b *podBackend) Retain(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string, deleteAt time.Time) error {
+	pod := &corev1.Pod{}
+	if err := b.client.Get(ctx, client.ObjectKey{Namespace: actRunner.Namespace, Name: name}, pod); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return retainWorkload(ctx, b.client, actRunner, pod, deleteAt)
+}
+
+// jobBackend runs the runner in a batch/v1 Job owned by the ActRunner
+// The Job never retries the pod; retries are decided by the ActRunner controller and the listener
+type jobBackend struct {
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+func (b *jobBackend) Create(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, pod *corev1.Pod) error {
+	backoffLimit := int32(0)
+	job := &batchv1.Job{
+		ObjectMeta: pod.ObjectMeta,
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: pod.ObjectMeta,
+				Spec:       pod.Spec,
+			},
+		},
+	}
+	// The Job controller names the pod and owns it
+	job.Spec.Template.Name = ""
+	job.Spec.Template.Namespace = ""
+	job.Spec.Template.OwnerReferences = nil
+
+	if err := ctrl.SetControllerReference(actRunner, job, b.scheme); err != nil {
+		return err
+	}
+	return b.client.Create(ctx, job)
+}
+
+func (b *jobBackend) Pod(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string) (*corev1.Pod, error) {
+	job := &batchv1.Job{}
+	if err := b.client.Get(ctx, client.ObjectKey{Namespace: actRunner.Namespace, Name: name}, job); err != nil {
+		return nil, err
+	}
+
+	pods := &corev1.PodList{}
+	if err := b.client.List(ctx, pods, client.InNamespace(actRunner.Namespace), client.MatchingLabels{batchv1.JobNameLabel: name}); err != nil {
+		return nil, fmt.Errorf("failed to list pods of job %s: %w", name, err)
+	}
+	// BackoffLimit is 0, so there is at most one pod that isn't being replaced
+	var newest *corev1.Pod
+	for i := range pods.Items {
+		if newest == nil || pods.Items[i].CreationTimestamp.After(newest.CreationTimestamp.Time) {
+			newest = &pods.Items[i]
+		}
+	}
+	return newest, nil
+}
+
+func (b *jobBackend) Delete(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string) error {
+	job := &batchv1.Job{}
+	job.Name = name
+	job.Namespace = actRunner.Namespace
+	return client.IgnoreNotFound(b.client.Delete(ctx, job, client.PropagationPolicy("Background")))
+}
+
+// Retain hands over the Job; its pod stays owned by the Job
+func (b *jobBackend) Retain(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, name string, deleteAt time.Time) error {
+	job := &batchv1.Job{}
+	if err := b.client.Get(ctx, client.ObjectKey{Namespace: actRunner.Namespace, Name: name}, job); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return retainWorkload(ctx, b.client, actRunner, job, deleteAt)
+}
+
+// externalVMBackend is the extension point for runners in virtual machines outside the cluster
+// It rejects every runner until a VM provider is implemented
+type externalVMBackend struct{}
+
+func (externalVMBackend) Create(context.Context, *forgejoactionsiov1alpha1.ActRunner, *corev1.Pod) error {
+	return fmt.Errorf("ExternalVM is not implemented: %w", errBackendUnavailable)
+}
+
+func (externalVMBackend) Pod(context.Context, *forgejoactionsiov1alpha1.ActRunner, string) (*corev1.Pod, error) {
+	return nil, fmt.Errorf("ExternalVM is not implemented: %w", errBackendUnavailable)
+}
+
+func (externalVMBackend) Delete(context.Context, *forgejoactionsiov1alpha1.ActRunner, string) error {
+	return nil
+}
+
+func (externalVMBackend) Retain(context.Context, *forgejoactionsiov1alpha1.ActRunner, string, time.Time) error {
+	return nil
+}
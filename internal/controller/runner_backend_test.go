@@ -0,0 +1,165 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Runner backends", func() {
+	const namespace = "runners"
+
+	var (
+		ctx       context.Context
+		c         client.Client
+		actRunner *forgejoactionsiov1alpha1.ActRunner
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		actRunner = &forgejoactionsiov1alpha1.ActRunner{
+			ObjectMeta: metav1.ObjectMeta{Name: "runner-1", Namespace: namespace, UID: "runner-1-uid"},
+		}
+	})
+
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "runner-1-pod", Namespace: namespace, Labels: map[string]string{"app": "runner"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "runner", Image: "runner:test"}}},
+		}
+	}
+
+	DescribeTable("are selected by the ActRunner",
+		func(backend forgejoactionsiov1alpha1.RunnerBackend, expected RunnerBackend) {
+			reconciler := &ActRunnerReconciler{Client: c, Scheme: scheme.Scheme}
+			actRunner.Spec.Backend = backend
+			Expect(reconciler.runnerBackend(actRunner)).To(BeAssignableToTypeOf(expected))
+		},
+		Entry("default", forgejoactionsiov1alpha1.RunnerBackend(""), &podBackend{}),
+		Entry("Pod", forgejoactionsiov1alpha1.RunnerBackendPod, &podBackend{}),
+		Entry("Job", forgejoactionsiov1alpha1.RunnerBackendJob, &jobBackend{}),
+		Entry("KubeVirt", forgejoactionsiov1alpha1.RunnerBackendKubeVirt, &kubeVirtBackend{}),
+		Entry("ExternalVM", forgejoactionsiov1alpha1.RunnerBackendExternalVM, externalVMBackend{}),
+	)
+
+	Describe("Pod", func() {
+		var backend *podBackend
+
+		BeforeEach(func() {
+			backend = &podBackend{client: c, scheme: scheme.Scheme}
+		})
+
+		It("creates the pod owned by the ActRunner and returns it", func() {
+			Expect(backend.Create(ctx, actRunner, newPod())).To(Succeed())
+
+			pod, err := backend.Pod(ctx, actRunner, "runner-1-pod")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metav1.IsControlledBy(pod, actRunner)).To(BeTrue())
+			Expect(apierrors.IsAlreadyExists(backend.Create(ctx, actRunner, newPod()))).To(BeTrue())
+		})
+
+		It("reports a deleted pod as gone and deletes missing pods without error", func() {
+			Expect(backend.Create(ctx, actRunner, newPod())).To(Succeed())
+			Expect(backend.Delete(ctx, actRunner, "runner-1-pod")).To(Succeed())
+			Expect(backend.Delete(ctx, actRunner, "runner-1-pod")).To(Succeed())
+
+			_, err := backend.Pod(ctx, actRunner, "runner-1-pod")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			Expect(backend.Retain(ctx, actRunner, "runner-1-pod", time.Now())).To(Succeed())
+		})
+	})
+
+	Describe("Job", func() {
+		var backend *jobBackend
+
+		BeforeEach(func() {
+			backend = &jobBackend{client: c, scheme: scheme.Scheme}
+		})
+
+		// createJobPod creates a pod of the Job like the Job controller does
+		createJobPod := func(name string, created time.Time) {
+			Expect(c.Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				Labels:            map[string]string{batchv1.JobNameLabel: "runner-1-pod"},
+				CreationTimestamp: metav1.NewTime(created),
+			}})).To(Succeed())
+		}
+
+		It("creates a Job owned by the ActRunner that runs the pod once", func() {
+			pod := newPod()
+			Expect(backend.Create(ctx, actRunner, pod)).To(Succeed())
+
+			job := &batchv1.Job{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "runner-1-pod"}, job)).To(Succeed())
+			Expect(metav1.IsControlledBy(job, actRunner)).To(BeTrue())
+			Expect(*job.Spec.BackoffLimit).To(BeZero())
+			Expect(job.Spec.Template.Name).To(BeEmpty())
+			Expect(job.Spec.Template.Namespace).To(BeEmpty())
+			Expect(job.Spec.Template.OwnerReferences).To(BeEmpty())
+			Expect(job.Spec.Template.Labels).To(HaveKeyWithValue("app", "runner"))
+			Expect(job.Spec.Template.Spec.Containers).To(Equal(pod.Spec.Containers))
+		})
+
+		It("returns no pod until the Job controller created one, then the newest", func() {
+			Expect(backend.Create(ctx, actRunner, newPod())).To(Succeed())
+			pod, err := backend.Pod(ctx, actRunner, "runner-1-pod")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod).To(BeNil())
+
+			createJobPod("runner-1-pod-old", time.Now().Add(-time.Minute))
+			createJobPod("runner-1-pod-new", time.Now())
+			pod, err = backend.Pod(ctx, actRunner, "runner-1-pod")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Name).To(Equal("runner-1-pod-new"))
+		})
+
+		It("reports a deleted Job as gone and deletes missing Jobs without error", func() {
+			Expect(backend.Create(ctx, actRunner, newPod())).To(Succeed())
+			Expect(backend.Delete(ctx, actRunner, "runner-1-pod")).To(Succeed())
+			Expect(backend.Delete(ctx, actRunner, "runner-1-pod")).To(Succeed())
+
+			_, err := backend.Pod(ctx, actRunner, "runner-1-pod")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			Expect(backend.Retain(ctx, actRunner, "runner-1-pod", time.Now())).To(Succeed())
+		})
+	})
+
+	Describe("ExternalVM", func() {
+		It("refuses to run runners", func() {
+			backend := externalVMBackend{}
+			Expect(backend.Create(ctx, actRunner, newPod())).To(MatchError(errBackendUnavailable))
+			_, err := backend.Pod(ctx, actRunner, "runner-1-pod")
+			Expect(err).To(MatchError(errBackendUnavailable))
+			Expect(backend.Delete(ctx, actRunner, "runner-1-pod")).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,93 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/cloudevents"
+)
+
+// runnerEventData is the data of the runner lifecycle CloudEvents
+type runnerEventData struct {
+	ActRunner      string       `json:"actRunner"`
+	Namespace      string       `json:"namespace"`
+	ActDeployment  string       `json:"actDeployment,omitempty"`
+	Phase          string       `json:"phase"`
+	Workload       string       `json:"workload,omitempty"`
+	JobID          int64        `json:"jobId"`
+	JobName        string       `json:"jobName,omitempty"`
+	RunsOn         []string     `json:"runsOn,omitempty"`
+	Repository     string       `json:"repository,omitempty"`
+	Ref            string       `json:"ref,omitempty"`
+	Event          string       `json:"event,omitempty"`
+	TriggerUser    string       `json:"triggerUser,omitempty"`
+	FailureReason  string       `json:"failureReason,omitempty"`
+	FailureMessage string       `json:"failureMessage,omitempty"`
+	StartedAt      *metav1.Time `json:"startedAt,omitempty"`
+	CompletedAt    *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// publishRunnerEvent publishes a lifecycle CloudEvent of the runner if a sink is configured
+// The event ID is stable per runner and event type, so consumers can drop the duplicates that
+// retried reconciles may publish; failures are logged and never block reconciliation
+func (r *ActRunnerReconciler) publishRunnerEvent(ctx context.Context, actRunner *forgejoactionsiov1alpha1.ActRunner, eventType string) {
+	if r.Events == nil {
+		return
+	}
+
+	data := runnerEventData{
+		ActRunner:   actRunner.Name,
+		Namespace:   actRunner.Namespace,
+		Phase:       string(actRunner.Status.Phase),
+		Workload:    actRunner.Status.KubernetesJobName,
+		JobID:       actRunner.Spec.ForgejoJobID,
+		JobName:     actRunner.Spec.JobData.Name,
+		RunsOn:      actRunner.Spec.JobData.RunsOn,
+		Repository:  actRunner.Status.RepositoryFullName,
+		Ref:         actRunner.Status.PrettyRef,
+		Event:       actRunner.Status.TriggerEvent,
+		TriggerUser: actRunner.Status.TriggerUser,
+		StartedAt:   actRunner.Status.StartedAt,
+		CompletedAt: actRunner.Status.CompletedAt,
+	}
+	if owner := metav1.GetControllerOf(actRunner); owner != nil && owner.Kind == "ActDeployment" {
+		data.ActDeployment = owner.Name
+	}
+	if failed := meta.FindStatusCondition(actRunner.Status.Conditions, forgejoactionsiov1alpha1.ConditionFailed); failed != nil && failed.Status == metav1.ConditionTrue {
+		data.FailureReason = failed.Reason
+		data.FailureMessage = failed.Message
+	}
+
+	id := fmt.Sprintf("%s.%s", actRunner.UID, strings.TrimPrefix(eventType, "io.forgejo.actions.runner."))
+	source := fmt.Sprintf("/apis/%s/namespaces/%s/actrunners/%s", forgejoactionsiov1alpha1.GroupVersion, actRunner.Namespace, actRunner.Name)
+	event := cloudevents.NewEvent(id, source, eventType, fmt.Sprintf("%d", actRunner.Spec.ForgejoJobID), data)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := r.Events.Publish(ctx, event); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to publish CloudEvent", "actRunner", actRunner.Name, "type", eventType)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/registry"
+)
+
+const (
+	// checkRunnerImageAnnotation triggers an immediate registry check when its value changes
+	checkRunnerImageAnnotation = "forgejo.actions.io/check-runner-image"
+	// defaultRunnerImagePollInterval is how often RunnerImageFrom is checked if no poll interval is set
+	defaultRunnerImagePollInterval = 5 * time.Minute
+)
+
+// reconcileRunnerImage resolves spec.runnerImageFrom to a digest and records it in status.resolvedRunnerImage
+// The registry is only queried when the poll interval has passed, the spec changed or a check was requested
+// The listener runs new and pending runners with the resolved digest
+func (r *ActDeploymentReconciler) reconcileRunnerImage(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) {
+	source := actDeployment.Spec.RunnerImageFrom
+	if source == nil {
+		actDeployment.Status.ResolvedRunnerImage = ""
+		actDeployment.Status.RunnerImageCheckedAt = nil
+		actDeployment.Status.RunnerImageCheck = ""
+		meta.RemoveStatusCondition(&actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionRunnerImageResolved)
+		return
+	}
+	log := logf.FromContext(ctx)
+
+	interval := defaultRunnerImagePollInterval
+	if source.PollInterval != nil && source.PollInterval.Duration > 0 {
+		interval = source.PollInterval.Duration
+	}
+	requested := actDeployment.Annotations[checkRunnerImageAnnotation]
+	checkedAt := actDeployment.Status.RunnerImageCheckedAt
+	if checkedAt != nil && time.Since(checkedAt.Time) < interval &&
+		actDeployment.Status.ObservedGeneration == actDeployment.Generation &&
+		actDeployment.Status.RunnerImageCheck == requested {
+		return
+	}
+
+	now := metav1.Now()
+	actDeployment.Status.RunnerImageCheckedAt = &now
+	actDeployment.Status.RunnerImageCheck = requested
+
+	resolved, err := r.resolveRunnerImage(ctx, actDeployment.Namespace, source)
+	if err != nil {
+		log.Error(err, "failed to resolve runner image", "image", source.Image)
+		meta.SetStatusCondition(&actDeployment.Status.Conditions, metav1.Condition{
+			Type:               forgejoactionsiov1alpha1.ConditionRunnerImageResolved,
+			Status:             metav1.ConditionFalse,
+			Reason:             "ResolveFailed",
+			Message:            err.Error(),
+			ObservedGeneration: actDeployment.Generation,
+		})
+		return
+	}
+
+	if previous := actDeployment.Status.ResolvedRunnerImage; previous != resolved {
+		log.Info("runner image changed", "image", source.Image, "previous", previous, "resolved", resolved)
+		if previous != "" {
+			r.recordEvent(actDeployment, corev1.EventTypeNormal, "RunnerImageUpdated",
+				fmt.Sprintf("%s now resolves to %s, pending runners are updated", source.Image, resolved))
+		}
+	}
+	actDeployment.Status.ResolvedRunnerImage = resolved
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionRunnerImageResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Resolved",
+		Message:            fmt.Sprintf("%s resolved to %s", source.Image, resolved),
+		ObservedGeneration: actDeployment.Generation,
+	})
+}
+
+// resolveRunnerImage returns the digest reference the tracked tag currently points to
+func (r *ActDeploymentReconciler) resolveRunnerImage(ctx context.Context, namespace string, source *forgejoactionsiov1alpha1.RunnerImageSource) (string, error) {
+	ref, err := registry.ParseReference(source.Image)
+	if err != nil {
+		return "", err
+	}
+
+	client := registry.NewClient(nil)
+	if source.PullSecretRef != nil && source.PullSecretRef.Name != "" {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: source.PullSecretRef.Name}, secret); err != nil {
+			return "", fmt.Errorf("failed to get pull secret %s: %w", source.PullSecretRef.Name, err)
+		}
+		if username, password, ok := registry.CredentialsFromDockerConfig(secret.Data[corev1.DockerConfigJsonKey], ref.Registry); ok {
+			client.SetCredentials(username, password)
+		}
+	}
+
+	digest, err := client.Digest(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return ref.Name + "@" + digest, nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/podbuilder"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerlabels"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerspec"
+)
+
+// renderRunnerPodAnnotation requests the <name>-runner-pod ConfigMap with the rendered runner pod
+// Its value lists the runs-on labels of the sample job, comma-separated; empty uses the first runner label
+const renderRunnerPodAnnotation = "forgejo.actions.io/render-runner-pod"
+
+// renderRunnerPod renders the pod a runner of the ActDeployment gets for a sample job with the given runs-on labels
+// The job ID and registration secret are placeholders, everything else is what the controller would create
+func (r *ActDeploymentReconciler) renderRunnerPod(actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection, runsOn []string) (*corev1.Pod, error) {
+	deployment := actDeployment.DeepCopy()
+	deployment.Spec = *effectiveRunnerSpec(actDeployment, conn)
+	deployment.Spec.ForgejoServer = conn.server
+	deployment.Spec.Organization = conn.organization
+
+	runnerLabels, err := runnerlabels.FromSpec(&deployment.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse runner labels: %w", err)
+	}
+	if len(runnerLabels) == 0 {
+		return nil, errors.New("no runner labels")
+	}
+	if len(runsOn) == 0 {
+		runsOn = []string{runnerLabels[0].Name}
+	}
+
+	job := forgejo.Job{ID: 0, Name: "preview", RunsOn: runsOn}
+	actRunner := runnerspec.ForJob(deployment, job, runnerspec.Name(conn.server, conn.organization, job.ID, job.Attempt),
+		deployment.Namespace, "registration-token", runnerLabels)
+	actRunner.APIVersion = forgejoactionsiov1alpha1.GroupVersion.String()
+	actRunner.Kind = "ActRunner"
+	pod, _ := podbuilder.Build(actRunner, r.Config.Get())
+	pod.APIVersion = "v1"
+	pod.Kind = "Pod"
+	return pod, nil
+}
+
+// renderedPodHash hashes the metadata and spec of a rendered runner pod
+func renderedPodHash(pod *corev1.Pod) string {
+	data, err := json.Marshal(struct {
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+		Spec        corev1.PodSpec    `json:"spec"`
+	}{pod.Labels, pod.Annotations, pod.Spec})
+	if err != nil {
+		// Pods only contain JSON-serializable API types
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// reconcileRenderedRunnerPod records the hash of the rendered runner pod, and while the ActDeployment carries the
+// render annotation, keeps the rendered pod in the <name>-runner-pod ConfigMap
+func (r *ActDeploymentReconciler) reconcileRenderedRunnerPod(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) error {
+	pod, err := r.renderRunnerPod(actDeployment, conn, nil)
+	if err != nil {
+		return err
+	}
+	actDeployment.Status.RenderedTemplateHash = renderedPodHash(pod)
+
+	configMapName := fmt.Sprintf("%s-runner-pod", actDeployment.Name)
+	existing := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: configMapName}, existing)
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	found := err == nil
+
+	value, requested := actDeployment.Annotations[renderRunnerPodAnnotation]
+	if !requested {
+		if found && metav1.IsControlledBy(existing, actDeployment) {
+			return client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return nil
+	}
+
+	if value = strings.TrimSpace(value); value != "" {
+		if pod, err = r.renderRunnerPod(actDeployment, conn, strings.Split(value, ",")); err != nil {
+			return err
+		}
+	}
+	rendered, err := yaml.Marshal(pod)
+	if err != nil {
+		return fmt.Errorf("failed to render runner pod: %w", err)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: actDeployment.Namespace,
+			Labels:    map[string]string{"forgejo.actions.io/act-deployment": actDeployment.Name},
+		},
+		Data: map[string]string{"pod.yaml": string(rendered)},
+	}
+	if err := ctrl.SetControllerReference(actDeployment, configMap, r.Scheme); err != nil {
+		return err
+	}
+
+	if !found {
+		return r.Create(ctx, configMap)
+	}
+	if existing.Data["pod.yaml"] == configMap.Data["pod.yaml"] {
+		return nil
+	}
+	existing.Data = configMap.Data
+	return r.Update(ctx, existing)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+)
+
+const (
+	// checkTokenAnnotation triggers an immediate token check when its value changes
+	checkTokenAnnotation = "forgejo.actions.io/check-token"
+	// tokenCheckInterval is how often a token that passed is checked again
+	tokenCheckInterval = time.Hour
+	// tokenRecheckInterval is how often a token that failed is checked again
+	tokenRecheckInterval = 5 * time.Minute
+)
+
+// reconcileTokenPermissions checks that the token may list the organization's jobs and create runner registration
+// tokens, and sets the TokenPermissionsValid condition
+// The check runs when the spec, the token Secret or the check-token annotation changed, and periodically
+// Errors that don't tell anything about the token (e.g. Forgejo being down) keep the previous result
+func (r *ActDeploymentReconciler) reconcileTokenPermissions(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) {
+	log := logf.FromContext(ctx)
+
+	secretVersion, err := r.secretVersion(ctx, actDeployment.Namespace, conn.tokenSecretName)
+	if err != nil {
+		log.Error(err, "failed to get token secret for the permission check")
+		return
+	}
+	requested := actDeployment.Annotations[checkTokenAnnotation]
+	previous := meta.FindStatusCondition(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionTokenPermissionsValid)
+	interval := tokenCheckInterval
+	if previous == nil || previous.Status != metav1.ConditionTrue {
+		interval = tokenRecheckInterval
+	}
+	if check := actDeployment.Status.TokenCheck; check != nil && time.Since(check.CheckedAt.Time) < interval &&
+		actDeployment.Status.ObservedGeneration == actDeployment.Generation &&
+		check.SecretVersion == secretVersion && check.Request == requested {
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               forgejoactionsiov1alpha1.ConditionTokenPermissionsValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             "TokenPermitted",
+		Message:            fmt.Sprintf("the token may list jobs and create registration tokens for %s", conn.organization),
+		ObservedGeneration: actDeployment.Generation,
+	}
+	check, err := r.checkTokenPermissions(ctx, actDeployment, conn)
+	if err != nil {
+		reason, ok := tokenErrorReason(err)
+		if !ok {
+			log.Error(err, "failed to check token permissions")
+			return
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reason
+		condition.Message = fmt.Sprintf("the token may not %s for %s: %v", check, conn.organization, err)
+		if previous == nil || previous.Status != metav1.ConditionFalse || previous.Reason != reason {
+			r.recordEvent(actDeployment, corev1.EventTypeWarning, "TokenPermissionsInvalid", condition.Message)
+		}
+	}
+
+	actDeployment.Status.TokenCheck = &forgejoactionsiov1alpha1.TokenCheckStatus{
+		CheckedAt:     metav1.Now(),
+		SecretVersion: secretVersion,
+		Request:       requested,
+	}
+	meta.SetStatusCondition(&actDeployment.Status.Conditions, condition)
+}
+
+// checkTokenPermissions calls the endpoints the listener depends on and returns the failing one with its error
+func (r *ActDeploymentReconciler) checkTokenPermissions(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) (string, error) {
+	forgejoClient, err := r.forgejoClient(ctx, actDeployment, conn)
+	if err != nil {
+		return "connect", err
+	}
+	if _, err := forgejoClient.GetPendingJobs(ctx, conn.organization, ""); err != nil {
+		return "list jobs", err
+	}
+	// The registration token itself is a secret and never stored or logged
+	if _, err := forgejoClient.GetRegistrationToken(ctx, conn.organization); err != nil {
+		return "create registration tokens", err
+	}
+	return "", nil
+}
+
+// tokenErrorReason maps the errors that mean the token lacks permissions to a condition reason
+func tokenErrorReason(err error) (string, bool) {
+	var apiErr *forgejo.APIError
+	if !errors.As(err, &apiErr) {
+		return "", false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized:
+		return "Unauthorized", true
+	case http.StatusForbidden:
+		return "Forbidden", true
+	case http.StatusNotFound:
+		// Forgejo hides organizations the token may not see
+		return "OrganizationNotFound", true
+	}
+	return "", false
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
+)
+
+var _ = Describe("Token permissions", func() {
+	const (
+		namespace    = "runners"
+		organization = "token-org"
+	)
+
+	var (
+		ctx           context.Context
+		server        *fake.Server
+		c             client.Client
+		recorder      *record.FakeRecorder
+		reconciler    *ActDeploymentReconciler
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+		conn          *forgejoConnection
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = fake.NewServer()
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: namespace, Generation: 1},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:  server.URL(),
+				Organization:   organization,
+				TokenSecretRef: corev1.SecretReference{Name: "forgejo-token"},
+			},
+		}
+		actDeployment.Status.ObservedGeneration = 1
+		c = clientfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "forgejo-token", Namespace: namespace},
+			Data:       map[string][]byte{"token": []byte("token")},
+		}).Build()
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &ActDeploymentReconciler{Client: c, Scheme: scheme.Scheme, Recorder: recorder}
+		conn = &forgejoConnection{server: server.URL(), organization: organization, tokenSecretName: "forgejo-token"}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	condition := func() *metav1.Condition {
+		return meta.FindStatusCondition(actDeployment.Status.Conditions, forgejoactionsiov1alpha1.ConditionTokenPermissionsValid)
+	}
+
+	It("passes a token that may list jobs and create registration tokens", func() {
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+
+		Expect(condition()).NotTo(BeNil())
+		Expect(condition().Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition().Reason).To(Equal("TokenPermitted"))
+		Expect(actDeployment.Status.TokenCheck).NotTo(BeNil())
+		Expect(server.Requests()).To(ConsistOf(
+			"/api/v1/orgs/"+organization+"/actions/runners/jobs",
+			"/api/v1/orgs/"+organization+"/actions/runners/registration-token",
+		))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	DescribeTable("fails a token Forgejo refuses and tells which call failed",
+		func(statusCodes []int, reason, call string) {
+			server.FailNext(statusCodes...)
+			reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+
+			Expect(condition()).NotTo(BeNil())
+			Expect(condition().Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition().Reason).To(Equal(reason))
+			Expect(condition().Message).To(HavePrefix("the token may not " + call + " for " + organization))
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning TokenPermissionsInvalid")))
+		},
+		Entry("unauthorized", []int{http.StatusUnauthorized}, "Unauthorized", "list jobs"),
+		Entry("no access to the organization", []int{http.StatusNotFound}, "OrganizationNotFound", "list jobs"),
+		Entry("no permission to register runners", []int{0, http.StatusForbidden}, "Forbidden", "create registration tokens"),
+	)
+
+	It("keeps the previous result when Forgejo fails for reasons unrelated to the token", func() {
+		server.FailNext(http.StatusInternalServerError)
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+
+		Expect(condition()).To(BeNil())
+		Expect(actDeployment.Status.TokenCheck).To(BeNil())
+	})
+
+	It("warns only once while the token keeps failing for the same reason", func() {
+		server.FailNext(http.StatusUnauthorized)
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		Expect(recorder.Events).To(HaveLen(1))
+
+		actDeployment.Status.TokenCheck.CheckedAt = metav1.NewTime(time.Now().Add(-tokenRecheckInterval))
+		server.FailNext(http.StatusUnauthorized)
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		Expect(recorder.Events).To(HaveLen(1))
+	})
+
+	It("checks again once the interval passed, sooner for a failing token", func() {
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		checks := len(server.Requests())
+
+		By("passing")
+		actDeployment.Status.TokenCheck.CheckedAt = metav1.NewTime(time.Now().Add(-tokenRecheckInterval))
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		Expect(server.Requests()).To(HaveLen(checks))
+		actDeployment.Status.TokenCheck.CheckedAt = metav1.NewTime(time.Now().Add(-tokenCheckInterval))
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		Expect(server.Requests()).To(HaveLen(2 * checks))
+
+		By("failing")
+		meta.SetStatusCondition(&actDeployment.Status.Conditions, metav1.Condition{
+			Type:   forgejoactionsiov1alpha1.ConditionTokenPermissionsValid,
+			Status: metav1.ConditionFalse,
+			Reason: "Unauthorized",
+		})
+		actDeployment.Status.TokenCheck.CheckedAt = metav1.NewTime(time.Now().Add(-tokenRecheckInterval))
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		Expect(server.Requests()).To(HaveLen(3 * checks))
+		Expect(condition().Status).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("checks right away after a spec, token secret or check-token annotation change", func() {
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		checks := len(server.Requests())
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		Expect(server.Requests()).To(HaveLen(checks))
+
+		By("changing the spec")
+		actDeployment.Generation = 2
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		Expect(server.Requests()).To(HaveLen(2 * checks))
+		actDeployment.Status.ObservedGeneration = 2
+
+		By("rotating the token")
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "forgejo-token"}, secret)).To(Succeed())
+		secret.Data["token"] = []byte("rotated")
+		Expect(c.Update(ctx, secret)).To(Succeed())
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		Expect(server.Requests()).To(HaveLen(3 * checks))
+
+		By("requesting a check")
+		actDeployment.Annotations = map[string]string{checkTokenAnnotation: "1"}
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		Expect(server.Requests()).To(HaveLen(4 * checks))
+		reconciler.reconcileTokenPermissions(ctx, actDeployment, conn)
+		Expect(server.Requests()).To(HaveLen(4 * checks))
+	})
+})
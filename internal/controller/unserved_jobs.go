@@ -0,0 +1,183 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/resourceprofile"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerlabels"
+)
+
+// unservedJobsCheckPolls is how many listener poll intervals pass between checks for unserved jobs
+// The ActDeployment is reconciled on every listener status update, which would otherwise list the jobs each time;
+// a spec change checks right away
+const unservedJobsCheckPolls = 6
+
+// reconcileUnservedJobs reports queued jobs of the organization that no ActDeployment in the cluster serves
+// Typos in runs-on otherwise leave jobs waiting forever without any hint
+func (r *ActDeploymentReconciler) reconcileUnservedJobs(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) error {
+	if !actDeployment.Spec.ReportUnservedJobs {
+		if len(actDeployment.Status.UnservedJobs) > 0 || actDeployment.Status.UnservedJobsCheckedAt != nil {
+			actDeployment.Status.UnservedJobs = nil
+			actDeployment.Status.UnservedJobsCheckedAt = nil
+			unservedJobs.DeleteLabelValues(conn.server, conn.organization)
+		}
+		return nil
+	}
+	interval := unservedJobsCheckPolls * r.listenerPollInterval(actDeployment, conn)
+	if checked := actDeployment.Status.UnservedJobsCheckedAt; checked != nil && time.Since(checked.Time) < interval &&
+		actDeployment.Status.ObservedGeneration == actDeployment.Generation {
+		return nil
+	}
+	log := logf.FromContext(ctx)
+
+	forgejoClient, err := r.forgejoClient(ctx, actDeployment, conn)
+	if err != nil {
+		return err
+	}
+
+	// Without a label filter the API returns every waiting job of the organization
+	jobs, err := forgejoClient.GetPendingJobs(ctx, conn.organization, "")
+	if err != nil {
+		return fmt.Errorf("failed to get pending jobs: %w", err)
+	}
+
+	served, err := r.servedLabelSets(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	previous := map[int64]forgejoactionsiov1alpha1.UnservedJob{}
+	for _, job := range actDeployment.Status.UnservedJobs {
+		previous[job.ID] = job
+	}
+
+	var unserved []forgejoactionsiov1alpha1.UnservedJob
+	for _, job := range jobs {
+		if isJobServed(served, job.RunsOn) {
+			continue
+		}
+		if known, ok := previous[job.ID]; ok {
+			unserved = append(unserved, known)
+			continue
+		}
+		unserved = append(unserved, forgejoactionsiov1alpha1.UnservedJob{
+			ID:        job.ID,
+			Name:      job.Name,
+			RepoID:    job.RepoID,
+			RunsOn:    job.RunsOn,
+			FirstSeen: metav1.NewTime(time.Now()),
+		})
+		log.Info("found job no ActDeployment serves", "jobID", job.ID, "jobName", job.Name, "runsOn", job.RunsOn)
+		r.recordEvent(actDeployment, corev1.EventTypeWarning, "UnservedJob",
+			fmt.Sprintf("job %d (%s) in repository %d requests labels %v that no ActDeployment serves", job.ID, job.Name, job.RepoID, job.RunsOn))
+	}
+
+	actDeployment.Status.UnservedJobs = unserved
+	checkedAt := metav1.Now()
+	actDeployment.Status.UnservedJobsCheckedAt = &checkedAt
+	unservedJobs.WithLabelValues(conn.server, conn.organization).Set(float64(len(unserved)))
+	return nil
+}
+
+// forgejoClient returns a Forgejo client authenticated like the ActDeployment's listener
+func (r *ActDeploymentReconciler) forgejoClient(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, conn *forgejoConnection) (*forgejo.Client, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: conn.tokenSecretName}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get token secret %s: %w", conn.tokenSecretName, err)
+	}
+	forgejoClient := forgejo.NewClient(conn.server, strings.TrimSpace(string(secret.Data["token"])))
+	headers, err := r.resolveAPIHeaders(ctx, actDeployment)
+	if err != nil {
+		return nil, err
+	}
+	forgejoClient.SetHeaders(headers)
+	if ref := actDeployment.Spec.ClientCertSecretRef; ref != nil && ref.Name != "" {
+		certSecret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: actDeployment.Namespace, Name: ref.Name}, certSecret); err != nil {
+			return nil, fmt.Errorf("failed to get client certificate secret %s: %w", ref.Name, err)
+		}
+		if err := forgejoClient.SetClientCertificate(certSecret.Data[corev1.TLSCertKey], certSecret.Data[corev1.TLSPrivateKeyKey]); err != nil {
+			return nil, fmt.Errorf("failed to load client certificate from secret %s: %w", ref.Name, err)
+		}
+	}
+	operatorConfig := r.Config.Get()
+	forgejoClient.SetRateLimit(operatorConfig.ForgejoAPIQPS, operatorConfig.ForgejoAPIBurst)
+	return forgejoClient, nil
+}
+
+// servedLabelSets returns the runner labels of every ActDeployment connected to the same organization
+func (r *ActDeploymentReconciler) servedLabelSets(ctx context.Context, conn *forgejoConnection) ([][]forgejoactionsiov1alpha1.RunnerLabel, error) {
+	actDeployments := &forgejoactionsiov1alpha1.ActDeploymentList{}
+	if err := r.List(ctx, actDeployments); err != nil {
+		return nil, fmt.Errorf("failed to list ActDeployments: %w", err)
+	}
+
+	var served [][]forgejoactionsiov1alpha1.RunnerLabel
+	for i := range actDeployments.Items {
+		ad := &actDeployments.Items[i]
+		server, organization := ad.Spec.ForgejoServer, ad.Spec.Organization
+		if ad.Spec.ActOrgRef != nil {
+			actOrg := &forgejoactionsiov1alpha1.ActOrg{}
+			if err := r.Get(ctx, types.NamespacedName{Name: ad.Spec.ActOrgRef.Name}, actOrg); err != nil {
+				continue
+			}
+			server, organization = actOrg.Spec.ForgejoServer, actOrg.Spec.Organization
+		}
+		if strings.TrimSuffix(server, "/") != strings.TrimSuffix(conn.server, "/") || organization != conn.organization {
+			continue
+		}
+
+		labels, err := runnerlabels.FromSpec(&ad.Spec)
+		if err != nil {
+			continue
+		}
+		labels = append(labels, resourceprofile.SelectorLabels(ad.Spec.ResourceProfiles)...)
+		// With registration labels, the listener only takes jobs both label sets cover
+		if registration := ad.Spec.RegistrationLabels; len(registration) > 0 {
+			var covered []forgejoactionsiov1alpha1.RunnerLabel
+			for _, label := range labels {
+				if runnerlabels.Matches(registration, []string{label.Name}) {
+					covered = append(covered, label)
+				}
+			}
+			labels = covered
+		}
+		served = append(served, labels)
+	}
+	return served, nil
+}
+
+func isJobServed(served [][]forgejoactionsiov1alpha1.RunnerLabel, runsOn []string) bool {
+	for _, labels := range served {
+		if runnerlabels.Matches(labels, runsOn) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
+)
+
+var _ = Describe("Unserved jobs", func() {
+	const (
+		namespace    = "runners"
+		organization = "unserved-org"
+	)
+
+	var (
+		ctx           context.Context
+		server        *fake.Server
+		reconciler    *ActDeploymentReconciler
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+		conn          *forgejoConnection
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = fake.NewServer()
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "reporter", Namespace: namespace, Generation: 1},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:      server.URL(),
+				Organization:       organization,
+				Labels:             "docker",
+				TokenSecretRef:     corev1.SecretReference{Name: "forgejo-token"},
+				ReportUnservedJobs: true,
+			},
+		}
+		actDeployment.Status.ObservedGeneration = 1
+		c := clientfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			actDeployment.DeepCopy(),
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "forgejo-token", Namespace: namespace},
+				Data:       map[string][]byte{"token": []byte("token")},
+			},
+		).Build()
+		reconciler = &ActDeploymentReconciler{Client: c, Scheme: scheme.Scheme}
+		conn = &forgejoConnection{server: server.URL(), organization: organization, tokenSecretName: "forgejo-token"}
+
+		server.AddJob(organization, forgejo.Job{ID: 1, Name: "build", RunsOn: []string{"docker"}})
+		server.AddJob(organization, forgejo.Job{ID: 2, Name: "train", RunsOn: []string{"gpu"}})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// jobLists counts the requests for the organization's waiting jobs
+	jobLists := func() int {
+		count := 0
+		for _, path := range server.Requests() {
+			if path == "/api/v1/orgs/"+organization+"/actions/runners/jobs" {
+				count++
+			}
+		}
+		return count
+	}
+
+	unservedIDs := func() []int64 {
+		var ids []int64
+		for _, job := range actDeployment.Status.UnservedJobs {
+			ids = append(ids, job.ID)
+		}
+		return ids
+	}
+
+	It("checks the queued jobs once every few poll intervals", func() {
+		Expect(reconciler.reconcileUnservedJobs(ctx, actDeployment, conn)).To(Succeed())
+		Expect(unservedIDs()).To(ConsistOf(int64(2)))
+		Expect(actDeployment.Status.UnservedJobsCheckedAt).NotTo(BeNil())
+		Expect(jobLists()).To(Equal(1))
+
+		// Reconciles in between keep the last report
+		server.AddJob(organization, forgejo.Job{ID: 3, Name: "render", RunsOn: []string{"gpu"}})
+		Expect(reconciler.reconcileUnservedJobs(ctx, actDeployment, conn)).To(Succeed())
+		Expect(unservedIDs()).To(ConsistOf(int64(2)))
+		Expect(jobLists()).To(Equal(1))
+
+		// The default poll interval is 10s
+		actDeployment.Status.UnservedJobsCheckedAt = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+		Expect(reconciler.reconcileUnservedJobs(ctx, actDeployment, conn)).To(Succeed())
+		Expect(unservedIDs()).To(ConsistOf(int64(2), int64(3)))
+		Expect(jobLists()).To(Equal(2))
+	})
+
+	It("checks right away after a spec change", func() {
+		Expect(reconciler.reconcileUnservedJobs(ctx, actDeployment, conn)).To(Succeed())
+		Expect(jobLists()).To(Equal(1))
+
+		actDeployment.Generation = 2
+		Expect(reconciler.reconcileUnservedJobs(ctx, actDeployment, conn)).To(Succeed())
+		Expect(jobLists()).To(Equal(2))
+	})
+
+	It("clears the report when reporting is disabled", func() {
+		Expect(reconciler.reconcileUnservedJobs(ctx, actDeployment, conn)).To(Succeed())
+		Expect(unservedIDs()).NotTo(BeEmpty())
+
+		actDeployment.Spec.ReportUnservedJobs = false
+		Expect(reconciler.reconcileUnservedJobs(ctx, actDeployment, conn)).To(Succeed())
+		Expect(actDeployment.Status.UnservedJobs).To(BeEmpty())
+		Expect(actDeployment.Status.UnservedJobsCheckedAt).To(BeNil())
+	})
+})
@@ -0,0 +1,183 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diagnostics serves live profiles and a JSON dump of a process' state, for diagnosing performance problems
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// StateFunc returns the process specific part of the state dump; it must be safe to marshal as JSON
+type StateFunc func(ctx context.Context) (any, error)
+
+// RuntimeStats are the Go runtime statistics included in every state dump
+type RuntimeStats struct {
+	Goroutines     int    `json:"goroutines"`
+	HeapAllocBytes uint64 `json:"heapAllocBytes"`
+	HeapObjects    uint64 `json:"heapObjects"`
+	SysBytes       uint64 `json:"sysBytes"`
+	GCCycles       uint32 `json:"gcCycles"`
+	// LastGC is empty before the first garbage collection
+	LastGC string `json:"lastGC,omitempty"`
+}
+
+// ReadRuntimeStats returns the current Go runtime statistics
+// It stops the world briefly, like every read of the memory statistics
+func ReadRuntimeStats() RuntimeStats {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	stats := RuntimeStats{
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: memStats.HeapAlloc,
+		HeapObjects:    memStats.HeapObjects,
+		SysBytes:       memStats.Sys,
+		GCCycles:       memStats.NumGC,
+	}
+	if memStats.LastGC != 0 {
+		stats.LastGC = time.Unix(0, int64(memStats.LastGC)).UTC().Format(time.RFC3339)
+	}
+	return stats
+}
+
+// Handler serves the net/http/pprof profiles under /debug/pprof/ and the state dump under /debug/state
+func Handler(state StateFunc) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/state", func(w http.ResponseWriter, r *http.Request) {
+		dump := struct {
+			Runtime RuntimeStats `json:"runtime"`
+			State   any          `json:"state,omitempty"`
+			Error   string       `json:"error,omitempty"`
+		}{Runtime: ReadRuntimeStats()}
+		if state != nil {
+			var err error
+			if dump.State, err = state(r.Context()); err != nil {
+				dump.Error = err.Error()
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(dump)
+	})
+	return mux
+}
+
+// Server serves Handler on Addr until its context is cancelled
+// It is a manager.Runnable that runs on every replica, not only the leader
+type Server struct {
+	Addr   string
+	State  StateFunc
+	Logger logr.Logger
+}
+
+// Start serves until ctx is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.Addr, err)
+	}
+	server := &http.Server{
+		Handler:           Handler(s.State),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	s.Logger.Info("serving diagnostics", "address", listener.Addr().String())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection lets standby replicas be profiled too
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Metrics returns the current values of the counters and gauges in gatherer whose name starts with prefix,
+// keyed by the metric name and its labels in the Prometheus text format, e.g. workqueue_depth{name="actrunner"}
+// Histograms and summaries are reported by their sample count
+func Metrics(gatherer prometheus.Gatherer, prefix string) (map[string]float64, error) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	values := map[string]float64{}
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), prefix) {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			values[family.GetName()+labelString(metric.GetLabel())] = metricValue(metric)
+		}
+	}
+	return values, nil
+}
+
+// labelString formats labels like the Prometheus text format, sorted by name
+func labelString(labels []*dto.LabelPair) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", label.GetName(), label.GetValue()))
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func metricValue(metric *dto.Metric) float64 {
+	switch {
+	case metric.Counter != nil:
+		return metric.Counter.GetValue()
+	case metric.Gauge != nil:
+		return metric.Gauge.GetValue()
+	case metric.Histogram != nil:
+		return float64(metric.Histogram.GetSampleCount())
+	case metric.Summary != nil:
+		return float64(metric.Summary.GetSampleCount())
+	default:
+		return metric.GetUntyped().GetValue()
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ = Describe("Handler", func() {
+	get := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	It("dumps the runtime statistics and the state as JSON", func() {
+		handler := Handler(func(context.Context) (any, error) {
+			return map[string]int{"actDeployments": 2}, nil
+		})
+		response := get(handler, "/debug/state")
+		Expect(response.Code).To(Equal(http.StatusOK))
+		Expect(response.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var dump struct {
+			Runtime RuntimeStats   `json:"runtime"`
+			State   map[string]int `json:"state"`
+			Error   string         `json:"error"`
+		}
+		Expect(json.Unmarshal(response.Body.Bytes(), &dump)).To(Succeed())
+		Expect(dump.Runtime.Goroutines).To(BeNumerically(">", 0))
+		Expect(dump.State).To(HaveKeyWithValue("actDeployments", 2))
+		Expect(dump.Error).To(BeEmpty())
+	})
+
+	It("reports a failure to collect the state next to the runtime statistics", func() {
+		handler := Handler(func(context.Context) (any, error) { return nil, errors.New("cache not synced") })
+		response := get(handler, "/debug/state")
+		Expect(response.Code).To(Equal(http.StatusOK))
+		Expect(response.Body.String()).To(ContainSubstring(`"error": "cache not synced"`))
+		Expect(response.Body.String()).To(ContainSubstring(`"goroutines"`))
+	})
+
+	It("serves the pprof index", func() {
+		response := get(Handler(nil), "/debug/pprof/")
+		Expect(response.Code).To(Equal(http.StatusOK))
+		Expect(response.Body.String()).To(ContainSubstring("goroutine"))
+	})
+})
+
+var _ = Describe("Metrics", func() {
+	It("returns the metrics with the prefix keyed by name and labels", func() {
+		registry := prometheus.NewRegistry()
+		depth := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "workqueue_depth", Help: "depth"}, []string{"name", "controller"})
+		depth.WithLabelValues("actrunner", "actrunner").Set(3)
+		polls := prometheus.NewCounter(prometheus.CounterOpts{Name: "forgejo_listener_polls_total", Help: "polls"})
+		polls.Add(5)
+		duration := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "workqueue_work_duration_seconds", Help: "duration"})
+		duration.Observe(1)
+		duration.Observe(2)
+		registry.MustRegister(depth, polls, duration)
+
+		values, err := Metrics(registry, "workqueue_")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal(map[string]float64{
+			`workqueue_depth{controller="actrunner",name="actrunner"}`: 3,
+			"workqueue_work_duration_seconds":                          2,
+		}))
+	})
+})
@@ -0,0 +1,110 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventagg keeps recurring warnings from flooding the API server with Events
+package eventagg
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// DefaultInterval is how long a warning is held back after it was emitted for the same object
+const DefaultInterval = 5 * time.Minute
+
+// Recorder is an EventRecorder that emits each warning at most once per interval for an object
+// A warning that keeps recurring, e.g. a rejected token on every poll, bumps the count of a single Event
+// every interval instead of being sent to the API server on every occurrence; Normal events are passed through
+type Recorder struct {
+	recorder record.EventRecorder
+	interval time.Duration
+	now      func() time.Time
+
+	mu sync.Mutex
+	// emitted is when each warning was last passed on
+	emitted map[warningKey]time.Time
+}
+
+// warningKey identifies a warning by its object, reason and message
+type warningKey struct {
+	uid       types.UID
+	namespace string
+	name      string
+	reason    string
+	message   string
+}
+
+// New returns a Recorder passing warnings on to recorder at most once per interval
+func New(recorder record.EventRecorder, interval time.Duration) *Recorder {
+	return &Recorder{recorder: recorder, interval: interval, now: time.Now, emitted: map[warningKey]time.Time{}}
+}
+
+// Event implements record.EventRecorder
+func (r *Recorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.allow(object, eventtype, reason, message) {
+		r.recorder.Event(object, eventtype, reason, message)
+	}
+}
+
+// Eventf implements record.EventRecorder
+func (r *Recorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...any) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf implements record.EventRecorder
+func (r *Recorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...any) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if r.allow(object, eventtype, reason, message) {
+		r.recorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+	}
+}
+
+// allow reports whether the event is passed on, and records the time for a warning that is
+func (r *Recorder) allow(object runtime.Object, eventtype, reason, message string) bool {
+	if eventtype != corev1.EventTypeWarning {
+		return true
+	}
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		// Object references and other objects without metadata aren't held back
+		return true
+	}
+	key := warningKey{uid: accessor.GetUID(), namespace: accessor.GetNamespace(), name: accessor.GetName(), reason: reason, message: message}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if last, ok := r.emitted[key]; ok && now.Sub(last) < r.interval {
+		return false
+	}
+
+	// Forget warnings that stopped recurring, so the map doesn't grow with every message ever emitted
+	for k, last := range r.emitted {
+		if now.Sub(last) >= r.interval {
+			delete(r.emitted, k)
+		}
+	}
+	r.emitted[key] = now
+	return true
+}
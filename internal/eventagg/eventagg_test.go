@@ -0,0 +1,81 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventagg
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Recorder", func() {
+	var (
+		fake     *record.FakeRecorder
+		recorder *Recorder
+		now      time.Time
+		object   *forgejoactionsiov1alpha1.ActDeployment
+	)
+
+	BeforeEach(func() {
+		fake = record.NewFakeRecorder(10)
+		recorder = New(fake, time.Minute)
+		now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		recorder.now = func() time.Time { return now }
+		object = &forgejoactionsiov1alpha1.ActDeployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "runners", UID: "1"}}
+	})
+
+	It("holds back a recurring warning until the interval passed", func() {
+		recorder.Event(object, corev1.EventTypeWarning, "PollFailed", "403 Forbidden")
+		now = now.Add(10 * time.Second)
+		recorder.Eventf(object, corev1.EventTypeWarning, "PollFailed", "%d Forbidden", 403)
+		Expect(fake.Events).To(HaveLen(1))
+
+		now = now.Add(time.Minute)
+		recorder.Event(object, corev1.EventTypeWarning, "PollFailed", "403 Forbidden")
+		Expect(fake.Events).To(HaveLen(2))
+		Expect(<-fake.Events).To(Equal("Warning PollFailed 403 Forbidden"))
+	})
+
+	It("passes on warnings with another message, reason or object", func() {
+		other := object.DeepCopy()
+		other.UID = "2"
+		recorder.Event(object, corev1.EventTypeWarning, "PollFailed", "403 Forbidden")
+		recorder.Event(object, corev1.EventTypeWarning, "PollFailed", "502 Bad Gateway")
+		recorder.Event(object, corev1.EventTypeWarning, "ListenerWedged", "403 Forbidden")
+		recorder.Event(other, corev1.EventTypeWarning, "PollFailed", "403 Forbidden")
+		Expect(fake.Events).To(HaveLen(4))
+	})
+
+	It("passes on every Normal event", func() {
+		recorder.Event(object, corev1.EventTypeNormal, "ListenerRecovered", "the listener is running again")
+		recorder.Event(object, corev1.EventTypeNormal, "ListenerRecovered", "the listener is running again")
+		Expect(fake.Events).To(HaveLen(2))
+	})
+
+	It("forgets warnings that stopped recurring", func() {
+		recorder.Event(object, corev1.EventTypeWarning, "PollFailed", "403 Forbidden")
+		now = now.Add(2 * time.Minute)
+		recorder.Event(object, corev1.EventTypeWarning, "PollFailed", "502 Bad Gateway")
+		Expect(recorder.emitted).To(HaveLen(1))
+	})
+})
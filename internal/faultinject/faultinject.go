@@ -0,0 +1,155 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package faultinject injects Forgejo API and pod creation failures for soak tests of the retry, backoff and
+// garbage collection paths
+// Its flags are left out of the usage text, as they only belong in test environments
+package faultinject
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// flagPrefix marks the hidden flags
+const flagPrefix = "fault-"
+
+// APIFaults configures failures of Forgejo API requests; the zero value injects none
+type APIFaults struct {
+	// ErrorRate is the fraction of requests, between 0 and 1, answered with a 503 without reaching the server
+	ErrorRate float64
+	// Latency delays every request
+	Latency time.Duration
+}
+
+// BindFlags registers the hidden --fault-api-* flags
+func (f *APIFaults) BindFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&f.ErrorRate, flagPrefix+"api-error-rate", 0, "Fraction of Forgejo API requests failing with 503 (testing only)")
+	fs.DurationVar(&f.Latency, flagPrefix+"api-latency", 0, "Delay added to every Forgejo API request (testing only)")
+}
+
+// Enabled reports whether any API fault is configured
+func (f APIFaults) Enabled() bool {
+	return f.ErrorRate > 0 || f.Latency > 0
+}
+
+// Transport wraps next with the configured faults, or returns it unchanged if none are
+func (f APIFaults) Transport(next http.RoundTripper) http.RoundTripper {
+	if !f.Enabled() {
+		return next
+	}
+	return &faultyTransport{faults: f, next: next}
+}
+
+// faultyTransport delays requests and fails some of them
+type faultyTransport struct {
+	faults APIFaults
+	next   http.RoundTripper
+}
+
+func (t *faultyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.faults.Latency > 0 {
+		timer := time.NewTimer(t.faults.Latency)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+	if hit(t.faults.ErrorRate) {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		body := "injected fault"
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)),
+			StatusCode:    http.StatusServiceUnavailable,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"text/plain"}},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+// PodFaults configures failures of runner pod creation; the zero value injects none
+type PodFaults struct {
+	// CreateErrorRate is the fraction of pod creations, between 0 and 1, failing with ServiceUnavailable
+	CreateErrorRate float64
+}
+
+// BindFlags registers the hidden --fault-pod-* flags
+func (f *PodFaults) BindFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&f.CreateErrorRate, flagPrefix+"pod-create-error-rate", 0, "Fraction of pod creations failing with ServiceUnavailable (testing only)")
+}
+
+// Enabled reports whether any pod fault is configured
+func (f PodFaults) Enabled() bool {
+	return f.CreateErrorRate > 0
+}
+
+// Client wraps c so that pod creations fail at the configured rate, or returns it unchanged if no fault is configured
+func (f PodFaults) Client(c client.Client) client.Client {
+	if !f.Enabled() {
+		return c
+	}
+	return &faultyClient{Client: c, faults: f}
+}
+
+// faultyClient fails some pod creations
+type faultyClient struct {
+	client.Client
+	faults PodFaults
+}
+
+func (c *faultyClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if _, ok := obj.(*corev1.Pod); ok && hit(c.faults.CreateErrorRate) {
+		return apierrors.NewServiceUnavailable("injected fault")
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// PrintDefaults prints the usage of the flag set like flag.PrintDefaults, without the fault injection flags
+func PrintDefaults(fs *flag.FlagSet) {
+	visible := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	visible.SetOutput(fs.Output())
+	fs.VisitAll(func(f *flag.Flag) {
+		if !strings.HasPrefix(f.Name, flagPrefix) {
+			visible.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	visible.PrintDefaults()
+}
+
+// hit reports true with the given probability
+func hit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faultinject
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("APIFaults", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	get := func(ctx context.Context, transport http.RoundTripper) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		return transport.RoundTrip(req)
+	}
+
+	It("keeps the transport without faults", func() {
+		Expect(APIFaults{}.Transport(http.DefaultTransport)).To(BeIdenticalTo(http.DefaultTransport))
+	})
+
+	It("fails every request at rate 1", func() {
+		resp, err := get(context.Background(), APIFaults{ErrorRate: 1}.Transport(http.DefaultTransport))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("delays requests and gives up when the context ends", func() {
+		transport := APIFaults{Latency: 20 * time.Millisecond}.Transport(http.DefaultTransport)
+		start := time.Now()
+		resp, err := get(context.Background(), transport)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(time.Since(start)).To(BeNumerically(">=", 20*time.Millisecond))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = get(ctx, APIFaults{Latency: time.Hour}.Transport(http.DefaultTransport))
+		Expect(err).To(MatchError(context.Canceled))
+	})
+})
+
+var _ = Describe("PodFaults", func() {
+	It("fails pod creations only", func() {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		c := PodFaults{CreateErrorRate: 1}.Client(fake.NewClientBuilder().WithScheme(scheme).Build())
+
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "runner"}}
+		Expect(apierrors.IsServiceUnavailable(c.Create(context.Background(), pod))).To(BeTrue())
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "token"}}
+		Expect(c.Create(context.Background(), secret)).To(Succeed())
+	})
+})
+
+var _ = Describe("PrintDefaults", func() {
+	It("leaves out the fault flags", func() {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		out := &bytes.Buffer{}
+		fs.SetOutput(out)
+		fs.Bool("verbose", false, "Log more")
+		(&APIFaults{}).BindFlags(fs)
+		(&PodFaults{}).BindFlags(fs)
+
+		PrintDefaults(fs)
+		Expect(out.String()).To(ContainSubstring("-verbose"))
+		Expect(out.String()).NotTo(ContainSubstring("fault"))
+		Expect(fs.Parse([]string{"-fault-api-error-rate=0.5"})).To(Succeed())
+	})
+})
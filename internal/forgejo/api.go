@@ -0,0 +1,44 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forgejo
+
+import (
+	"context"
+)
+
+// API is the part of the Forgejo API the listener polls and creates runners with
+// Client implements it over HTTP; tests use the mock package and other transports can plug in
+type API interface {
+	// GetPendingJobs returns the waiting jobs of the organization matching the comma-separated labels
+	GetPendingJobs(ctx context.Context, org, labels string) ([]Job, error)
+	// EachPendingJob calls fn for each waiting job of the organization matching the comma-separated labels
+	EachPendingJob(ctx context.Context, org, labels string, fn func(Job) error) error
+	// GetRegistrationToken returns a new runner registration token for the organization
+	GetRegistrationToken(ctx context.Context, org string) (string, error)
+	// GetRepository returns the organization's repository with the given ID
+	GetRepository(ctx context.Context, org string, repoID int64) (*Repository, error)
+	// GetRun returns a workflow run of a repository
+	GetRun(ctx context.Context, owner, repo string, runID int64) (*Run, error)
+	// GetRunJobs returns the jobs of a workflow run
+	GetRunJobs(ctx context.Context, owner, repo string, runID int64) ([]Job, error)
+	// ListRunners returns the runners registered with the organization
+	ListRunners(ctx context.Context, org string) ([]Runner, error)
+	// DeleteRunner removes a runner from the organization
+	DeleteRunner(ctx context.Context, org string, runnerID int64) error
+}
+
+var _ API = &Client{}
@@ -22,26 +22,41 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
 )
 
 // Job represents a Forgejo Actions job from the API
 type Job struct {
-	ID      int64    `json:"id"`
-	RepoID  int64    `json:"repo_id"`
-	OwnerID int64    `json:"owner_id"`
-	Name    string   `json:"name"`
-	Needs   []string `json:"needs,omitempty"`
-	RunsOn  []string `json:"runs_on"`
-	TaskID  int64    `json:"task_id"`
-	Status  string   `json:"status"`
+	ID      int64  `json:"id"`
+	RepoID  int64  `json:"repo_id"`
+	OwnerID int64  `json:"owner_id"`
+	Name    string `json:"name"`
+	// JobID is the key of the job under jobs: in the workflow, which the needs of other jobs refer to
+	JobID  string   `json:"job_id,omitempty"`
+	Needs  []string `json:"needs,omitempty"`
+	RunsOn []string `json:"runs_on"`
+	TaskID int64    `json:"task_id"`
+	Status string   `json:"status"`
+	// Handle identifies the job for runners that fetch one specific job; empty on servers without support
+	Handle string `json:"handle,omitempty"`
+	// Attempt is the run attempt of the job, increased by each re-run; zero on servers that don't report it
+	Attempt int64 `json:"attempt,omitempty"`
 }
 
 // Client is a client for interacting with the Forgejo API
 type Client struct {
 	serverURL  string
 	token      string
+	tlsConfig  *tls.Config
+	transport  *http.Transport
+	headers    map[string]string
+	retries    RetryOptions
+	logger     logr.Logger
 	httpClient *http.Client
 }
 
@@ -52,15 +67,28 @@ func NewClient(serverURL, token string) *Client {
 
 // NewClientWithTLS creates a new Forgejo API client with TLS configuration
 func NewClientWithTLS(serverURL, token string, skipTLSVerify bool) *Client {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: skipTLSVerify,
+	}
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: skipTLSVerify,
-		},
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
 	}
+	DefaultConnectionOptions().applyTo(transport)
 
 	return &Client{
 		serverURL: serverURL,
 		token:     token,
+		tlsConfig: tlsConfig,
+		transport: transport,
+		retries:   DefaultRetryOptions(),
 		httpClient: &http.Client{
 			Timeout:   30 * time.Second,
 			Transport: transport,
@@ -68,53 +96,155 @@ func NewClientWithTLS(serverURL, token string, skipTLSVerify bool) *Client {
 	}
 }
 
+// SetClientCertificate makes the client present a certificate for mutual TLS, in addition to the token
+// It must be called before the first request
+func (c *Client) SetClientCertificate(certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+	c.tlsConfig.Certificates = []tls.Certificate{cert}
+	return nil
+}
+
+// SetToken replaces the API token, e.g., after it was rotated
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// SetHeaders sets extra headers sent with every request (e.g., for authenticating proxies)
+// They cannot override the Accept and Authorization headers
+func (c *Client) SetHeaders(headers map[string]string) {
+	c.headers = headers
+}
+
+// setHeaders sets the extra, Accept and Authorization headers on a request
+func (c *Client) setHeaders(req *http.Request) {
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", c.token))
+}
+
+// secrets returns the credentials the client sends, which must never show up in logs or errors
+func (c *Client) secrets() []string {
+	secrets := []string{c.token}
+	for _, value := range c.headers {
+		secrets = append(secrets, value)
+	}
+	return secrets
+}
+
+// SetLogger sets the logger for request debug logging
+// Requests are logged at V(2) with sanitized URLs, status codes, latencies and truncated response bodies
+func (c *Client) SetLogger(logger logr.Logger) {
+	c.logger = logger
+}
+
+// SetRateLimit limits the client to qps requests per second with the given burst
+// A qps of 0 or less removes the limit
+func (c *Client) SetRateLimit(qps float64, burst int) {
+	transport := c.httpClient.Transport
+	if limited, ok := transport.(*rateLimitedTransport); ok {
+		transport = limited.next
+	}
+	if qps <= 0 {
+		c.httpClient.Transport = transport
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	c.httpClient.Transport = &rateLimitedTransport{
+		next:    transport,
+		limiter: rate.NewLimiter(rate.Limit(qps), burst),
+	}
+}
+
+// rateLimitedTransport waits for the limiter before each request
+type rateLimitedTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	return t.next.RoundTrip(req)
+}
+
 // GetPendingJobs fetches pending jobs from the Forgejo API for the specified organization and labels
+// An empty labels string omits the label filter
 func (c *Client) GetPendingJobs(ctx context.Context, org, labels string) ([]Job, error) {
-	url := fmt.Sprintf("%s/api/v1/orgs/%s/actions/runners/jobs?labels=%s", c.serverURL, org, labels)
+	waitingJobs := []Job{}
+	err := c.EachPendingJob(ctx, org, labels, func(job Job) error {
+		waitingJobs = append(waitingJobs, job)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return waitingJobs, nil
+}
+
+// EachPendingJob calls fn for each waiting job while decoding the response, so a large queue is never held in memory
+// The API returns the whole queue in one response; an error from fn stops the iteration and is returned
+func (c *Client) EachPendingJob(ctx context.Context, org, labels string, fn func(Job) error) error {
+	url := fmt.Sprintf("%s/api/v1/orgs/%s/actions/runners/jobs", c.serverURL, org)
+	if labels != "" {
+		url += "?labels=" + labels
+	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", c.token))
+	c.setHeaders(req)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return newAPIError(resp, body, c.secrets()...)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	decoder := json.NewDecoder(resp.Body)
+	token, err := decoder.Token()
+	// Handle empty and null responses
+	if err == io.EOF || (err == nil && token == nil) {
+		return nil
 	}
-
-	// Handle null response
-	if len(body) == 0 || string(body) == "null" {
-		return []Job{}, nil
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-
-	var jobs []Job
-	if err := json.Unmarshal(body, &jobs); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if token != json.Delim('[') {
+		return fmt.Errorf("failed to unmarshal response: expected a list of jobs, got %v", token)
 	}
 
-	// Filter for jobs with status "waiting"
-	var waitingJobs []Job
-	for _, job := range jobs {
-		if job.Status == "waiting" {
-			waitingJobs = append(waitingJobs, job)
+	for decoder.More() {
+		var job Job
+		if err := decoder.Decode(&job); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		// Filter for jobs with status "waiting"
+		if job.Status != "waiting" {
+			continue
+		}
+		if err := fn(job); err != nil {
+			return err
 		}
 	}
-
-	return waitingJobs, nil
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
 }
 
 // RegistrationTokenResponse represents the response from the registration token API
@@ -131,10 +261,9 @@ func (c *Client) GetRegistrationToken(ctx context.Context, org string) (string,
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", c.token))
+	c.setHeaders(req)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -142,7 +271,7 @@ func (c *Client) GetRegistrationToken(ctx context.Context, org string) (string,
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return "", newAPIError(resp, body, c.secrets()...)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -181,10 +310,9 @@ func (c *Client) GetRepository(ctx context.Context, org string, repoID int64) (*
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", c.token))
+	c.setHeaders(req)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -192,7 +320,7 @@ func (c *Client) GetRepository(ctx context.Context, org string, repoID int64) (*
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp, body, c.secrets()...)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -228,6 +356,12 @@ type Run struct {
 	TriggerEvent string `json:"trigger_event"`
 	Status       string `json:"status"`
 	HTMLURL      string `json:"html_url"`
+
+	// ConcurrencyGroup is the evaluated workflow concurrency group of the run (empty if none)
+	ConcurrencyGroup string `json:"concurrency_group,omitempty"`
+
+	// ConcurrencyCancel reports whether the run cancels in-progress runs of the same group
+	ConcurrencyCancel bool `json:"concurrency_cancel,omitempty"`
 }
 
 // GetRun fetches run information by ID from a repository
@@ -239,10 +373,9 @@ func (c *Client) GetRun(ctx context.Context, owner, repo string, runID int64) (*
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", c.token))
+	c.setHeaders(req)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -250,7 +383,7 @@ func (c *Client) GetRun(ctx context.Context, owner, repo string, runID int64) (*
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp, body, c.secrets()...)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -265,3 +398,133 @@ func (c *Client) GetRun(ctx context.Context, owner, repo string, runID int64) (*
 
 	return &run, nil
 }
+
+// GetRunJobs fetches all jobs belonging to a run
+// This is used to resolve the state of a job's dependencies (needs)
+func (c *Client) GetRunJobs(ctx context.Context, owner, repo string, runID int64) ([]Job, error) {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/actions/runs/%d/jobs", c.serverURL, owner, repo, runID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setHeaders(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp, body, c.secrets()...)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// Handle null response
+	if len(body) == 0 || string(body) == "null" {
+		return []Job{}, nil
+	}
+
+	var jobs []Job
+	if err := json.Unmarshal(body, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// Runner represents a runner registered with Forgejo
+type Runner struct {
+	ID     int64  `json:"id"`
+	UUID   string `json:"uuid"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// ListRunners fetches the runners registered with the organization
+// Both a plain list and a {"runners": [...]} envelope are accepted, as the response shape differs between versions
+func (c *Client) ListRunners(ctx context.Context, org string) ([]Runner, error) {
+	url := fmt.Sprintf("%s/api/v1/orgs/%s/actions/runners", c.serverURL, org)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setHeaders(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp, body, c.secrets()...)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// Handle null response
+	if len(body) == 0 || string(body) == "null" {
+		return []Runner{}, nil
+	}
+
+	var runners []Runner
+	if err := json.Unmarshal(body, &runners); err == nil {
+		return runners, nil
+	}
+	var envelope struct {
+		Runners []Runner `json:"runners"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return envelope.Runners, nil
+}
+
+// DeleteRunner removes a runner from the organization
+// Deleting a runner that is already gone returns ErrNotFound
+func (c *Client) DeleteRunner(ctx context.Context, org string, runnerID int64) error {
+	url := fmt.Sprintf("%s/api/v1/orgs/%s/actions/runners/%d", c.serverURL, org, runnerID)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setHeaders(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return newAPIError(resp, body, c.secrets()...)
+	}
+	return nil
+}
+
+// IsJobFinished reports whether a job status is terminal (the job will not run again)
+func IsJobFinished(status string) bool {
+	switch status {
+	case "success", "failure", "cancelled", "skipped":
+		return true
+	default:
+		return false
+	}
+}
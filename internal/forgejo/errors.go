@@ -0,0 +1,96 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forgejo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/secretguard"
+)
+
+var (
+	// ErrUnauthorized is returned for 401 and 403 responses, e.g., after the token was revoked
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotFound is returned for 404 responses
+	ErrNotFound = errors.New("not found")
+	// ErrServerError is returned for 5xx responses
+	ErrServerError = errors.New("server error")
+)
+
+// ErrRateLimited is returned for 429 responses
+type ErrRateLimited struct {
+	// RetryAfter is the wait requested by the server, 0 if it sent none
+	RetryAfter time.Duration
+}
+
+func (e *ErrRateLimited) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+	}
+	return "rate limited"
+}
+
+// APIError is returned for unexpected response status codes
+// It wraps ErrUnauthorized, ErrNotFound, ErrServerError or *ErrRateLimited where the status code matches one of them
+type APIError struct {
+	StatusCode int
+	Body       string
+	err        error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// newAPIError classifies a non-OK response
+// The body is sanitized, since errors end up in logs and events
+func newAPIError(resp *http.Response, body []byte, secrets ...string) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Body: secretguard.SanitizeBody(string(body), secrets...)}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		apiErr.err = ErrUnauthorized
+	case resp.StatusCode == http.StatusNotFound:
+		apiErr.err = ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests:
+		apiErr.err = &ErrRateLimited{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode >= 500:
+		apiErr.err = ErrServerError
+	}
+	return apiErr
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forgejo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("API errors", func() {
+	var (
+		status  int
+		headers map[string]string
+		server  *httptest.Server
+		client  *Client
+	)
+
+	BeforeEach(func() {
+		headers = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for k, v := range headers {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte("details"))
+		}))
+		client = NewClient(server.URL, "token")
+		client.SetRetryOptions(RetryOptions{})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	DescribeTable("classify status codes",
+		func(code int, expected error) {
+			status = code
+			_, err := client.GetPendingJobs(context.Background(), "org", "")
+			Expect(err).To(MatchError(expected))
+
+			var apiErr *APIError
+			Expect(errors.As(err, &apiErr)).To(BeTrue())
+			Expect(apiErr.StatusCode).To(Equal(code))
+			Expect(apiErr.Body).To(Equal("details"))
+		},
+		Entry("401", http.StatusUnauthorized, ErrUnauthorized),
+		Entry("403", http.StatusForbidden, ErrUnauthorized),
+		Entry("404", http.StatusNotFound, ErrNotFound),
+		Entry("500", http.StatusInternalServerError, ErrServerError),
+	)
+
+	It("reports the Retry-After of rate limited requests", func() {
+		status = http.StatusTooManyRequests
+		headers = map[string]string{"Retry-After": "30"}
+		_, err := client.GetRegistrationToken(context.Background(), "org")
+
+		var rateLimited *ErrRateLimited
+		Expect(errors.As(err, &rateLimited)).To(BeTrue())
+		Expect(rateLimited.RetryAfter).To(Equal(30 * time.Second))
+	})
+
+	It("redacts credentials from error bodies", func() {
+		status = http.StatusBadRequest
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"message":"bad header X-Proxy-Auth: proxy-secret","token":"registration-token"}`))
+		})
+		client.SetHeaders(map[string]string{"X-Proxy-Auth": "proxy-secret"})
+
+		_, err := client.GetPendingJobs(context.Background(), "org", "")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).NotTo(ContainSubstring("proxy-secret"))
+		Expect(err.Error()).NotTo(ContainSubstring("registration-token"))
+	})
+
+	It("retries server errors before giving up", func() {
+		status = http.StatusServiceUnavailable
+		requests := 0
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(status)
+		})
+		client.SetRetryOptions(RetryOptions{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+		_, err := client.GetPendingJobs(context.Background(), "org", "")
+		Expect(err).To(MatchError(ErrServerError))
+		Expect(requests).To(Equal(3))
+	})
+})
@@ -0,0 +1,320 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides an in-memory Forgejo API server for tests and local development
+// It implements the endpoints used by forgejo.Client with scriptable job queues and failures
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+)
+
+// RegistrationToken is the registration token returned by the fake server
+const RegistrationToken = "fake-registration-token"
+
+// Server is a fake Forgejo API server
+type Server struct {
+	mu       sync.Mutex
+	server   *httptest.Server
+	token    string
+	jobs     map[string][]forgejo.Job
+	repos    map[string][]forgejo.Repository
+	runs     map[string]forgejo.Run
+	runJobs  map[string][]forgejo.Job
+	runners  map[string][]forgejo.Runner
+	failures []int
+	requests []string
+}
+
+// NewServer starts a fake server that accepts any API token
+func NewServer() *Server {
+	s := &Server{
+		jobs:    map[string][]forgejo.Job{},
+		repos:   map[string][]forgejo.Repository{},
+		runs:    map[string]forgejo.Run{},
+		runJobs: map[string][]forgejo.Job{},
+		runners: map[string][]forgejo.Runner{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/orgs/{org}/actions/runners/jobs", s.handleJobs)
+	mux.HandleFunc("GET /api/v1/orgs/{org}/actions/runners/registration-token", s.handleRegistrationToken)
+	mux.HandleFunc("GET /api/v1/orgs/{org}/actions/runners", s.handleRunners)
+	mux.HandleFunc("DELETE /api/v1/orgs/{org}/actions/runners/{id}", s.handleDeleteRunner)
+	mux.HandleFunc("GET /api/v1/orgs/{org}/repos", s.handleRepos)
+	mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}/actions/runs/{id}", s.handleRun)
+	mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}/actions/runs/{id}/jobs", s.handleRunJobs)
+	s.server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// URL returns the base URL of the server, to be used as Forgejo server URL
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// RequireToken makes the server answer 401 to requests without the given token
+func (s *Server) RequireToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// AddJob queues a job for the organization
+func (s *Server) AddJob(org string, job forgejo.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.Status == "" {
+		job.Status = "waiting"
+	}
+	s.jobs[org] = append(s.jobs[org], job)
+}
+
+// SetJobStatus changes the status of a queued job, e.g., to "running" or "success"
+func (s *Server) SetJobStatus(org string, jobID int64, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.jobs[org] {
+		if s.jobs[org][i].ID == jobID {
+			s.jobs[org][i].Status = status
+		}
+	}
+	for key, jobs := range s.runJobs {
+		for i := range jobs {
+			if jobs[i].ID == jobID {
+				s.runJobs[key][i].Status = status
+			}
+		}
+	}
+}
+
+// RerunJob queues the job again as its next run attempt, like re-running it in the Forgejo UI
+func (s *Server) RerunJob(org string, jobID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.jobs[org] {
+		job := &s.jobs[org][i]
+		if job.ID == jobID {
+			job.Attempt = max(job.Attempt, 1) + 1
+			job.Status = "waiting"
+			job.TaskID = 0
+		}
+	}
+}
+
+// AddRepository adds a repository to the organization
+func (s *Server) AddRepository(org string, repo forgejo.Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos[org] = append(s.repos[org], repo)
+}
+
+// AddRun adds a run with its jobs to a repository
+func (s *Server) AddRun(owner, repo string, run forgejo.Run, jobs []forgejo.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := runKey(owner, repo, run.ID)
+	s.runs[key] = run
+	s.runJobs[key] = slices.Clone(jobs)
+}
+
+// AddJobRun makes the run the answer to run lookups by the job's ID, as the listener looks up a job's run
+func (s *Server) AddJobRun(owner, repo string, jobID int64, run forgejo.Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[runKey(owner, repo, jobID)] = run
+}
+
+// AddRunner registers a runner with the organization
+func (s *Server) AddRunner(org string, runner forgejo.Runner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runners[org] = append(s.runners[org], runner)
+}
+
+// Runners returns the runners registered with the organization
+func (s *Server) Runners(org string) []forgejo.Runner {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.runners[org])
+}
+
+// FailNext makes the next requests fail with the given status codes, one per request
+func (s *Server) FailNext(statusCodes ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statusCodes...)
+}
+
+// Requests returns the paths of all requests received so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// RunDemo queues a job for the organization every interval until the context is done
+// The previous demo job is marked successful when the next one is queued, so the queue doesn't grow forever
+func (s *Server) RunDemo(ctx context.Context, org string, labels []string, interval time.Duration) {
+	s.AddRepository(org, forgejo.Repository{ID: 1, Name: "demo", FullName: org + "/demo", DefaultBranch: "main"})
+
+	var previous int64
+	for id := int64(1); ; id++ {
+		if previous != 0 {
+			s.SetJobStatus(org, previous, "success")
+		}
+		job := forgejo.Job{ID: id, RepoID: 1, Name: fmt.Sprintf("demo-%d", id), RunsOn: labels, Status: "waiting"}
+		s.AddJob(org, job)
+		s.AddRun(org, "demo", forgejo.Run{ID: id, Title: job.Name, PrettyRef: "main", TriggerEvent: "push", Status: "waiting"}, []forgejo.Job{job})
+		previous = id
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.Path)
+		var failure int
+		if len(s.failures) > 0 {
+			failure, s.failures = s.failures[0], s.failures[1:]
+		}
+		token := s.token
+		s.mu.Unlock()
+
+		if failure != 0 {
+			http.Error(w, http.StatusText(failure), failure)
+			return
+		}
+		if token != "" && r.Header.Get("Authorization") != "token "+token {
+			http.Error(w, "token is required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	var labels []string
+	if query := r.URL.Query().Get("labels"); query != "" {
+		labels = strings.Split(query, ",")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := []forgejo.Job{}
+	for _, job := range s.jobs[r.PathValue("org")] {
+		// A runner with the given labels can take jobs whose runs-on labels it all has
+		if labels == nil || !slices.ContainsFunc(job.RunsOn, func(label string) bool { return !slices.Contains(labels, label) }) {
+			jobs = append(jobs, job)
+		}
+	}
+	writeJSON(w, jobs)
+}
+
+func (s *Server) handleRegistrationToken(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, forgejo.RegistrationTokenResponse{Token: RegistrationToken})
+}
+
+func (s *Server) handleRunners(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runners := s.runners[r.PathValue("org")]
+	if runners == nil {
+		runners = []forgejo.Runner{}
+	}
+	writeJSON(w, runners)
+}
+
+func (s *Server) handleDeleteRunner(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	org, id := r.PathValue("org"), parseID(r.PathValue("id"))
+	i := slices.IndexFunc(s.runners[org], func(runner forgejo.Runner) bool { return runner.ID == id })
+	if i < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	s.runners[org] = slices.Delete(s.runners[org], i, i+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRepos(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repos := s.repos[r.PathValue("org")]
+	if repos == nil {
+		repos = []forgejo.Repository{}
+	}
+	writeJSON(w, repos)
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[runKey(r.PathValue("owner"), r.PathValue("repo"), parseID(r.PathValue("id")))]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, run)
+}
+
+func (s *Server) handleRunJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs, ok := s.runJobs[runKey(r.PathValue("owner"), r.PathValue("repo"), parseID(r.PathValue("id")))]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, jobs)
+}
+
+func runKey(owner, repo string, runID int64) string {
+	return fmt.Sprintf("%s/%s/%d", owner, repo, runID)
+}
+
+func parseID(value string) int64 {
+	id, _ := strconv.ParseInt(value, 10, 64)
+	return id
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		ctx    context.Context
+		server *Server
+		client *forgejo.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = NewServer()
+		client = forgejo.NewClient(server.URL(), "token")
+		client.SetRetryOptions(forgejo.RetryOptions{})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("returns waiting jobs matching the labels", func() {
+		server.AddJob("org", forgejo.Job{ID: 1, RunsOn: []string{"docker"}})
+		server.AddJob("org", forgejo.Job{ID: 2, RunsOn: []string{"gpu"}})
+		server.AddJob("org", forgejo.Job{ID: 3, RunsOn: []string{"docker"}})
+		server.SetJobStatus("org", 3, "running")
+
+		jobs, err := client.GetPendingJobs(ctx, "org", "docker,ubuntu-latest")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].ID).To(BeEquivalentTo(1))
+	})
+
+	It("queues a re-run job as its next attempt", func() {
+		server.AddJob("org", forgejo.Job{ID: 1, RunsOn: []string{"docker"}})
+		server.SetJobStatus("org", 1, "success")
+		server.RerunJob("org", 1)
+
+		jobs, err := client.GetPendingJobs(ctx, "org", "docker")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].Attempt).To(BeEquivalentTo(2))
+	})
+
+	It("streams waiting jobs until the callback stops", func() {
+		for id := int64(1); id <= 5; id++ {
+			server.AddJob("org", forgejo.Job{ID: id, RunsOn: []string{"docker"}})
+		}
+		server.SetJobStatus("org", 2, "running")
+
+		stop := errors.New("stop")
+		var seen []int64
+		err := client.EachPendingJob(ctx, "org", "docker", func(job forgejo.Job) error {
+			seen = append(seen, job.ID)
+			if len(seen) == 3 {
+				return stop
+			}
+			return nil
+		})
+		Expect(err).To(MatchError(stop))
+		Expect(seen).To(Equal([]int64{1, 3, 4}))
+	})
+
+	It("serves registration tokens, runners, repositories and runs", func() {
+		server.AddRunner("org", forgejo.Runner{ID: 3, Name: "runner-1-actrunner-1"})
+		server.AddRepository("org", forgejo.Repository{ID: 7, Name: "app", FullName: "org/app"})
+		server.AddRun("org", "app", forgejo.Run{ID: 42, Title: "build"}, []forgejo.Job{{ID: 1, Name: "test", Status: "success"}})
+
+		token, err := client.GetRegistrationToken(ctx, "org")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(Equal(RegistrationToken))
+
+		runners, err := client.ListRunners(ctx, "org")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runners).To(ConsistOf(forgejo.Runner{ID: 3, Name: "runner-1-actrunner-1"}))
+
+		repo, err := client.GetRepository(ctx, "org", 7)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.FullName).To(Equal("org/app"))
+
+		run, err := client.GetRun(ctx, "org", "app", 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(run.Title).To(Equal("build"))
+
+		runJobs, err := client.GetRunJobs(ctx, "org", "app", 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runJobs).To(HaveLen(1))
+
+		_, err = client.GetRun(ctx, "org", "app", 43)
+		Expect(err).To(MatchError(forgejo.ErrNotFound))
+	})
+
+	It("deletes runners", func() {
+		server.AddRunner("org", forgejo.Runner{ID: 3, Name: "runner-1-actrunner-1"})
+		server.AddRunner("org", forgejo.Runner{ID: 4, Name: "runner-2-actrunner-2"})
+
+		Expect(client.DeleteRunner(ctx, "org", 3)).To(Succeed())
+		Expect(server.Runners("org")).To(ConsistOf(forgejo.Runner{ID: 4, Name: "runner-2-actrunner-2"}))
+
+		Expect(client.DeleteRunner(ctx, "org", 3)).To(MatchError(forgejo.ErrNotFound))
+	})
+
+	It("fails scripted requests and enforces the token", func() {
+		server.FailNext(http.StatusInternalServerError)
+		_, err := client.GetPendingJobs(ctx, "org", "")
+		Expect(err).To(MatchError(forgejo.ErrServerError))
+
+		server.RequireToken("other")
+		_, err = client.GetPendingJobs(ctx, "org", "")
+		Expect(err).To(MatchError(forgejo.ErrUnauthorized))
+
+		Expect(server.Requests()).To(HaveLen(2))
+	})
+})
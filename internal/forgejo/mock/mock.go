@@ -0,0 +1,155 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mock provides a forgejo.API returning scripted results, for unit tests of code that talks to Forgejo
+// Use the fake package instead to test the HTTP client itself
+package mock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+)
+
+// Call is one recorded API call
+type Call struct {
+	Method string
+	Args   []any
+}
+
+// API implements forgejo.API with the configured functions and records every call
+// Methods without a function return empty results
+type API struct {
+	GetPendingJobsFunc       func(ctx context.Context, org, labels string) ([]forgejo.Job, error)
+	GetRegistrationTokenFunc func(ctx context.Context, org string) (string, error)
+	GetRepositoryFunc        func(ctx context.Context, org string, repoID int64) (*forgejo.Repository, error)
+	GetRunFunc               func(ctx context.Context, owner, repo string, runID int64) (*forgejo.Run, error)
+	GetRunJobsFunc           func(ctx context.Context, owner, repo string, runID int64) ([]forgejo.Job, error)
+	ListRunnersFunc          func(ctx context.Context, org string) ([]forgejo.Runner, error)
+	DeleteRunnerFunc         func(ctx context.Context, org string, runnerID int64) error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ forgejo.API = &API{}
+
+func (m *API) record(method string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns the recorded calls in order
+func (m *API) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// CallCount returns how often a method was called
+func (m *API) CallCount(method string) int {
+	count := 0
+	for _, call := range m.Calls() {
+		if call.Method == method {
+			count++
+		}
+	}
+	return count
+}
+
+// GetPendingJobs returns the result of GetPendingJobsFunc
+func (m *API) GetPendingJobs(ctx context.Context, org, labels string) ([]forgejo.Job, error) {
+	m.record("GetPendingJobs", org, labels)
+	return m.pendingJobs(ctx, org, labels)
+}
+
+// EachPendingJob calls fn for each job GetPendingJobsFunc returns
+func (m *API) EachPendingJob(ctx context.Context, org, labels string, fn func(forgejo.Job) error) error {
+	m.record("EachPendingJob", org, labels)
+	jobs, err := m.pendingJobs(ctx, org, labels)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if err := fn(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *API) pendingJobs(ctx context.Context, org, labels string) ([]forgejo.Job, error) {
+	if m.GetPendingJobsFunc == nil {
+		return []forgejo.Job{}, nil
+	}
+	return m.GetPendingJobsFunc(ctx, org, labels)
+}
+
+// GetRegistrationToken returns the result of GetRegistrationTokenFunc
+func (m *API) GetRegistrationToken(ctx context.Context, org string) (string, error) {
+	m.record("GetRegistrationToken", org)
+	if m.GetRegistrationTokenFunc == nil {
+		return "", nil
+	}
+	return m.GetRegistrationTokenFunc(ctx, org)
+}
+
+// GetRepository returns the result of GetRepositoryFunc
+func (m *API) GetRepository(ctx context.Context, org string, repoID int64) (*forgejo.Repository, error) {
+	m.record("GetRepository", org, repoID)
+	if m.GetRepositoryFunc == nil {
+		return &forgejo.Repository{ID: repoID}, nil
+	}
+	return m.GetRepositoryFunc(ctx, org, repoID)
+}
+
+// GetRun returns the result of GetRunFunc
+func (m *API) GetRun(ctx context.Context, owner, repo string, runID int64) (*forgejo.Run, error) {
+	m.record("GetRun", owner, repo, runID)
+	if m.GetRunFunc == nil {
+		return &forgejo.Run{ID: runID}, nil
+	}
+	return m.GetRunFunc(ctx, owner, repo, runID)
+}
+
+// GetRunJobs returns the result of GetRunJobsFunc
+func (m *API) GetRunJobs(ctx context.Context, owner, repo string, runID int64) ([]forgejo.Job, error) {
+	m.record("GetRunJobs", owner, repo, runID)
+	if m.GetRunJobsFunc == nil {
+		return []forgejo.Job{}, nil
+	}
+	return m.GetRunJobsFunc(ctx, owner, repo, runID)
+}
+
+// ListRunners returns the result of ListRunnersFunc
+func (m *API) ListRunners(ctx context.Context, org string) ([]forgejo.Runner, error) {
+	m.record("ListRunners", org)
+	if m.ListRunnersFunc == nil {
+		return []forgejo.Runner{}, nil
+	}
+	return m.ListRunnersFunc(ctx, org)
+}
+
+// DeleteRunner returns the result of DeleteRunnerFunc
+func (m *API) DeleteRunner(ctx context.Context, org string, runnerID int64) error {
+	m.record("DeleteRunner", org, runnerID)
+	if m.DeleteRunnerFunc == nil {
+		return nil
+	}
+	return m.DeleteRunnerFunc(ctx, org, runnerID)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mock
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+)
+
+var _ = Describe("API", func() {
+	ctx := context.Background()
+
+	It("returns empty results without configured functions", func() {
+		api := &API{}
+		jobs, err := api.GetPendingJobs(ctx, "org", "docker")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobs).To(BeEmpty())
+		runners, err := api.ListRunners(ctx, "org")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runners).To(BeEmpty())
+		repo, err := api.GetRepository(ctx, "org", 7)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.ID).To(Equal(int64(7)))
+	})
+
+	It("feeds EachPendingJob from GetPendingJobsFunc and stops on errors", func() {
+		stop := errors.New("stop")
+		api := &API{GetPendingJobsFunc: func(_ context.Context, org, labels string) ([]forgejo.Job, error) {
+			return []forgejo.Job{{ID: 1}, {ID: 2}, {ID: 3}}, nil
+		}}
+		seen := []int64{}
+		err := api.EachPendingJob(ctx, "org", "docker", func(job forgejo.Job) error {
+			seen = append(seen, job.ID)
+			if job.ID == 2 {
+				return stop
+			}
+			return nil
+		})
+		Expect(err).To(MatchError(stop))
+		Expect(seen).To(Equal([]int64{1, 2}))
+	})
+
+	It("returns the configured errors", func() {
+		api := &API{GetRegistrationTokenFunc: func(context.Context, string) (string, error) {
+			return "", &forgejo.APIError{StatusCode: 403}
+		}}
+		_, err := api.GetRegistrationToken(ctx, "org")
+		var apiErr *forgejo.APIError
+		Expect(errors.As(err, &apiErr)).To(BeTrue())
+	})
+
+	It("records the calls with their arguments", func() {
+		api := &API{}
+		_, _ = api.GetRun(ctx, "org", "app", 5)
+		_, _ = api.GetRun(ctx, "org", "app", 6)
+		_, _ = api.GetRunJobs(ctx, "org", "app", 5)
+
+		Expect(api.CallCount("GetRun")).To(Equal(2))
+		Expect(api.Calls()[2]).To(Equal(Call{Method: "GetRunJobs", Args: []any{"org", "app", int64(5)}}))
+	})
+})
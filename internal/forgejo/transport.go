@@ -0,0 +1,195 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forgejo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/secretguard"
+)
+
+// ConnectionOptions tune the connection pool of the client
+// Polling every few seconds without keep-alive opens a new connection per request, which can exhaust ephemeral ports
+type ConnectionOptions struct {
+	// MaxIdleConns limits idle connections across all hosts
+	MaxIdleConns int
+	// MaxIdleConnsPerHost limits idle connections to the Forgejo server
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits all connections to the Forgejo server, 0 for no limit
+	MaxConnsPerHost int
+	// IdleConnTimeout closes idle connections after this duration
+	IdleConnTimeout time.Duration
+	// WrapTransport wraps the HTTP transport, e.g. to inject faults in soak tests; nil keeps it as is
+	WrapTransport func(http.RoundTripper) http.RoundTripper
+}
+
+// DefaultConnectionOptions returns the connection pool settings used by new clients
+func DefaultConnectionOptions() ConnectionOptions {
+	return ConnectionOptions{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 4,
+		MaxConnsPerHost:     8,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+func (o ConnectionOptions) applyTo(transport *http.Transport) {
+	transport.MaxIdleConns = o.MaxIdleConns
+	transport.MaxIdleConnsPerHost = o.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = o.MaxConnsPerHost
+	transport.IdleConnTimeout = o.IdleConnTimeout
+}
+
+// SetConnectionOptions replaces the connection pool settings
+// It must be called before the first request
+func (c *Client) SetConnectionOptions(options ConnectionOptions) {
+	options.applyTo(c.transport)
+	if options.WrapTransport != nil {
+		c.httpClient.Transport = options.WrapTransport(c.httpClient.Transport)
+	}
+}
+
+// RetryOptions configure retries of requests that failed with a network error or a 502, 503 or 504 response
+type RetryOptions struct {
+	// MaxRetries is the number of retries after the first attempt, 0 disables retries
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry; it doubles with every further retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryOptions returns the retry settings used by new clients
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxRetries:     2,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}
+}
+
+// SetRetryOptions replaces the retry settings
+func (c *Client) SetRetryOptions(options RetryOptions) {
+	c.retries = options
+}
+
+// do sends a request and retries transient failures with jittered exponential backoff
+// Only requests without a body are retried, which covers all requests of this client
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	backoff := c.retries.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.doLogged(req, attempt)
+		if attempt >= c.retries.MaxRetries || req.Body != nil || !isRetryable(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		if err := sleepWithJitter(req.Context(), backoff); err != nil {
+			return nil, err
+		}
+		backoff = min(backoff*2, c.retries.MaxBackoff)
+	}
+}
+
+// debugBodyLimit is the number of response body bytes included in debug logs
+const debugBodyLimit = 1024
+
+// doLogged sends a request and logs it at V(2) if enabled
+// The response body is read for the log and replaced, so callers can still read it
+func (c *Client) doLogged(req *http.Request, attempt int) (*http.Response, error) {
+	logger := c.logger.V(2)
+	if !logger.Enabled() {
+		return c.httpClient.Do(req)
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	keysAndValues := []any{"method", req.Method, "url", sanitizeURL(req.URL), "attempt", attempt, "latency", time.Since(start)}
+	if err != nil {
+		logger.Info("Forgejo API request failed", append(keysAndValues, "error", err.Error())...)
+		return resp, err
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if readErr != nil {
+		logger.Info("Forgejo API request failed", append(keysAndValues, "status", resp.StatusCode, "error", readErr.Error())...)
+		return nil, readErr
+	}
+
+	// Sanitize before truncating, so a cut-off credential can't slip through
+	sanitized := secretguard.SanitizeBody(string(body), c.secrets()...)
+	truncated := len(sanitized) > debugBodyLimit
+	if truncated {
+		sanitized = sanitized[:debugBodyLimit]
+	}
+	logger.Info("Forgejo API request", append(keysAndValues, "status", resp.StatusCode, "body", sanitized, "truncated", truncated)...)
+	return resp, nil
+}
+
+// sanitizeURL returns the URL without user info and with credential-like query values redacted
+func sanitizeURL(u *url.URL) string {
+	sanitized := *u
+	sanitized.User = nil
+	query := sanitized.Query()
+	for key := range query {
+		switch strings.ToLower(key) {
+		case "token", "access_token", "sig", "signature":
+			query.Set(key, "redacted")
+		}
+	}
+	sanitized.RawQuery = query.Encode()
+	return sanitized.String()
+}
+
+// isRetryable reports whether a request failed in a way that may succeed when retried
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// sleepWithJitter waits between half and the full duration, or until the context is done
+func sleepWithJitter(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	d = d/2 + rand.N(d/2+1)
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forgejo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// roundTripperFunc answers requests without a server
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+var _ = Describe("Retries", func() {
+	var (
+		client   *Client
+		attempts int
+		// respond answers the attempt, counting from 0
+		respond func(attempt int, req *http.Request) (*http.Response, error)
+	)
+
+	BeforeEach(func() {
+		attempts = 0
+		respond = nil
+		client = NewClient("https://forgejo.example.com", "token")
+		client.SetRetryOptions(RetryOptions{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+		client.SetConnectionOptions(ConnectionOptions{WrapTransport: func(http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				attempt := attempts
+				attempts++
+				return respond(attempt, req)
+			})
+		}})
+	})
+
+	status := func(code int) (*http.Response, error) {
+		return &http.Response{StatusCode: code, Body: io.NopCloser(strings.NewReader(http.StatusText(code))), Header: http.Header{}}, nil
+	}
+
+	// failFirst fails the first attempts with the status code and then succeeds
+	failFirst := func(failures, code int) func(int, *http.Request) (*http.Response, error) {
+		return func(attempt int, _ *http.Request) (*http.Response, error) {
+			if attempt < failures {
+				return status(code)
+			}
+			return status(http.StatusOK)
+		}
+	}
+
+	newRequest := func(ctx context.Context, body io.Reader) *http.Request {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://forgejo.example.com/api/v1/version", body)
+		Expect(err).NotTo(HaveOccurred())
+		return req
+	}
+
+	DescribeTable("retry gateway errors until a request succeeds",
+		func(code int) {
+			respond = failFirst(2, code)
+			resp, err := client.do(newRequest(context.Background(), nil))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(attempts).To(Equal(3))
+		},
+		Entry("502", http.StatusBadGateway),
+		Entry("503", http.StatusServiceUnavailable),
+		Entry("504", http.StatusGatewayTimeout),
+	)
+
+	DescribeTable("don't retry other statuses",
+		func(code int) {
+			respond = failFirst(1, code)
+			resp, err := client.do(newRequest(context.Background(), nil))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(code))
+			Expect(attempts).To(Equal(1))
+		},
+		Entry("400", http.StatusBadRequest),
+		Entry("401", http.StatusUnauthorized),
+		Entry("404", http.StatusNotFound),
+		Entry("429", http.StatusTooManyRequests),
+		Entry("500", http.StatusInternalServerError),
+	)
+
+	It("retries network errors", func() {
+		respond = func(attempt int, _ *http.Request) (*http.Response, error) {
+			if attempt == 0 {
+				return nil, errors.New("connection reset by peer")
+			}
+			return status(http.StatusOK)
+		}
+		resp, err := client.do(newRequest(context.Background(), nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(attempts).To(Equal(2))
+	})
+
+	It("returns the last failure once the retries are used up", func() {
+		respond = func(int, *http.Request) (*http.Response, error) {
+			return status(http.StatusServiceUnavailable)
+		}
+		resp, err := client.do(newRequest(context.Background(), nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(attempts).To(Equal(3))
+
+		By("returning network errors the same way")
+		attempts = 0
+		respond = func(int, *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}
+		_, err = client.do(newRequest(context.Background(), nil))
+		Expect(err).To(MatchError(ContainSubstring("connection refused")))
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("sends a request once when retries are disabled", func() {
+		client.SetRetryOptions(RetryOptions{})
+		respond = failFirst(1, http.StatusBadGateway)
+		resp, err := client.do(newRequest(context.Background(), nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("doesn't retry requests with a body, which can't be sent again", func() {
+		respond = failFirst(1, http.StatusBadGateway)
+		resp, err := client.do(newRequest(context.Background(), strings.NewReader(`{"name":"runner"}`)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("stops waiting for the next attempt when the context is cancelled", func() {
+		client.SetRetryOptions(RetryOptions{MaxRetries: 2, InitialBackoff: time.Hour, MaxBackoff: time.Hour})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		respond = func(int, *http.Request) (*http.Response, error) {
+			time.AfterFunc(10*time.Millisecond, cancel)
+			return status(http.StatusServiceUnavailable)
+		}
+
+		start := time.Now()
+		_, err := client.do(newRequest(ctx, nil))
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Minute))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("waits between half and the full backoff", func() {
+		start := time.Now()
+		Expect(sleepWithJitter(context.Background(), 40*time.Millisecond)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 20*time.Millisecond))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(sleepWithJitter(ctx, 0)).To(MatchError(context.Canceled))
+	})
+})
@@ -0,0 +1,116 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package heartbeat lets a listener prove that its poll loop is alive through a coordination.k8s.io Lease
+// The ActDeployment controller treats a Lease that wasn't renewed within its duration as a wedged listener
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LeaseName returns the name of the heartbeat Lease of an ActDeployment's listener
+func LeaseName(actDeploymentName string) string {
+	return actDeploymentName + "-listener-heartbeat"
+}
+
+// Duration returns how long a heartbeat stays valid for a listener polling every pollInterval
+// It spans several polls, so a single slow poll doesn't count as wedged
+func Duration(pollInterval time.Duration) time.Duration {
+	return max(3*pollInterval, time.Minute)
+}
+
+// Beater renews the heartbeat Lease of one listener
+type Beater struct {
+	client    client.Client
+	namespace string
+	name      string
+	identity  string
+	duration  time.Duration
+	now       func() time.Time
+
+	// renewed is the time of the last renewal
+	renewed time.Time
+}
+
+// New returns a Beater renewing the heartbeat Lease of the ActDeployment, held by identity (the listener pod)
+func New(c client.Client, namespace, actDeploymentName, identity string, duration time.Duration) *Beater {
+	return &Beater{client: c, namespace: namespace, name: LeaseName(actDeploymentName), identity: identity, duration: duration, now: time.Now}
+}
+
+// Beat renews the Lease, at most every third of its duration to keep API writes low
+func (b *Beater) Beat(ctx context.Context) error {
+	now := b.now()
+	if !b.renewed.IsZero() && now.Sub(b.renewed) < b.duration/3 {
+		return nil
+	}
+
+	renewTime := metav1.NewMicroTime(now)
+	lease := &coordinationv1.Lease{}
+	err := b.client.Get(ctx, client.ObjectKey{Namespace: b.namespace, Name: b.name}, lease)
+	switch {
+	case apierrors.IsNotFound(err):
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: b.name, Namespace: b.namespace},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(b.identity),
+				LeaseDurationSeconds: ptr.To(durationSeconds(b.duration)),
+				AcquireTime:          &renewTime,
+				RenewTime:            &renewTime,
+			},
+		}
+		if err := b.client.Create(ctx, lease); err != nil {
+			return fmt.Errorf("failed to create heartbeat Lease: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get heartbeat Lease: %w", err)
+	default:
+		if ptr.Deref(lease.Spec.HolderIdentity, "") != b.identity {
+			lease.Spec.HolderIdentity = ptr.To(b.identity)
+			lease.Spec.AcquireTime = &renewTime
+		}
+		lease.Spec.LeaseDurationSeconds = ptr.To(durationSeconds(b.duration))
+		lease.Spec.RenewTime = &renewTime
+		if err := b.client.Update(ctx, lease); err != nil {
+			return fmt.Errorf("failed to renew heartbeat Lease: %w", err)
+		}
+	}
+	b.renewed = now
+	return nil
+}
+
+// Expired reports whether the Lease wasn't renewed within its duration
+// Leases without a renew time or duration never expire
+func Expired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return false
+	}
+	return now.After(lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+// durationSeconds rounds the duration up to whole seconds
+func durationSeconds(d time.Duration) int32 {
+	return int32(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package heartbeat
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Beater", func() {
+	var (
+		ctx       context.Context
+		k8sClient client.Client
+		now       time.Time
+		beater    *Beater
+	)
+
+	getLease := func() *coordinationv1.Lease {
+		lease := &coordinationv1.Lease{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "runners", Name: "linux-listener-heartbeat"}, lease)).To(Succeed())
+		return lease
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(coordinationv1.AddToScheme(scheme)).To(Succeed())
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+		now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		beater = New(k8sClient, "runners", "linux", "listener-abc", Duration(10*time.Second))
+		beater.now = func() time.Time { return now }
+	})
+
+	It("creates the Lease and renews it at most every third of its duration", func() {
+		Expect(beater.Beat(ctx)).To(Succeed())
+		lease := getLease()
+		Expect(*lease.Spec.HolderIdentity).To(Equal("listener-abc"))
+		Expect(*lease.Spec.LeaseDurationSeconds).To(BeEquivalentTo(60))
+		Expect(lease.Spec.RenewTime.Time).To(BeTemporally("==", now))
+
+		start := now
+		now = now.Add(10 * time.Second)
+		Expect(beater.Beat(ctx)).To(Succeed())
+		Expect(getLease().Spec.RenewTime.Time).To(BeTemporally("==", start))
+
+		now = now.Add(15 * time.Second)
+		Expect(beater.Beat(ctx)).To(Succeed())
+		Expect(getLease().Spec.RenewTime.Time).To(BeTemporally("==", now))
+	})
+
+	It("takes over the Lease of a previous listener pod", func() {
+		Expect(beater.Beat(ctx)).To(Succeed())
+
+		next := New(k8sClient, "runners", "linux", "listener-def", Duration(10*time.Second))
+		next.now = func() time.Time { return now.Add(time.Second) }
+		Expect(next.Beat(ctx)).To(Succeed())
+		lease := getLease()
+		Expect(*lease.Spec.HolderIdentity).To(Equal("listener-def"))
+		Expect(lease.Spec.AcquireTime.Time).To(BeTemporally("==", now.Add(time.Second)))
+	})
+
+	It("expires Leases that weren't renewed within their duration", func() {
+		Expect(beater.Beat(ctx)).To(Succeed())
+		lease := getLease()
+		Expect(Expired(lease, now.Add(time.Minute))).To(BeFalse())
+		Expect(Expired(lease, now.Add(61*time.Second))).To(BeTrue())
+		Expect(Expired(&coordinationv1.Lease{}, now)).To(BeFalse())
+	})
+})
@@ -0,0 +1,151 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jobbus receives job notifications from a NATS subject, so the listener can poll Forgejo as soon as
+// jobs are queued instead of waiting for its poll interval
+// With a JetStream push consumer delivering to the subject, messages are acknowledged after they were handled,
+// giving at-least-once delivery; plain subjects deliver at most once
+package jobbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/natsclient"
+)
+
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// Message is a job notification
+// Its payload is informational: the listener polls Forgejo for all waiting jobs when notified
+type Message struct {
+	Data []byte
+
+	reply string
+	conn  *natsclient.Conn
+}
+
+// Ack acknowledges a JetStream message, so it is not redelivered
+func (m *Message) Ack() error {
+	if m.reply == "" {
+		return nil
+	}
+	return m.conn.Publish(m.reply, []byte("+ACK"))
+}
+
+// Nak asks JetStream to redeliver the message after the delay
+func (m *Message) Nak(delay time.Duration) error {
+	if m.reply == "" {
+		return nil
+	}
+	return m.conn.Publish(m.reply, fmt.Appendf(nil, `-NAK {"delay":%d}`, delay.Nanoseconds()))
+}
+
+// Subscribe receives messages from the nats://[user[:password]@]host[:port]/subject[?queue=group] URL
+// It reconnects with a backoff until the context is cancelled, then closes the returned channel
+func Subscribe(ctx context.Context, logger logr.Logger, rawURL string) (<-chan *Message, error) {
+	options, subject, err := natsclient.ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	options.Name = "forgejo-listener"
+	queue := u.Query().Get("queue")
+
+	messages := make(chan *Message)
+	go func() {
+		defer close(messages)
+		backoff := minReconnectBackoff
+		for {
+			connected, err := receive(ctx, options, subject, queue, messages)
+			if ctx.Err() != nil {
+				return
+			}
+			if connected {
+				backoff = minReconnectBackoff
+			}
+			logger.Error(err, "job bus connection lost, reconnecting", "subject", subject, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxReconnectBackoff)
+		}
+	}()
+	return messages, nil
+}
+
+// receive subscribes over a new connection and delivers its messages until the connection fails
+// It reports whether the subscription was established
+func receive(ctx context.Context, options natsclient.Options, subject, queue string, messages chan<- *Message) (bool, error) {
+	conn, err := natsclient.Dial(ctx, options)
+	if err != nil {
+		return false, err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = conn.Close()
+	}()
+
+	if err := conn.Subscribe(subject, queue, "1"); err != nil {
+		return false, err
+	}
+	// The PONG confirms the server accepted the credentials and the subscription
+	if err := conn.Ping(); err != nil {
+		return false, err
+	}
+
+	connected := false
+	for {
+		op, err := conn.ReadOp()
+		if err != nil {
+			return connected, err
+		}
+		switch op.Kind {
+		case "PONG":
+			connected = true
+		case "PING":
+			if err := conn.Pong(); err != nil {
+				return connected, err
+			}
+		case "-ERR":
+			return connected, errors.New("NATS server error: " + op.Error)
+		case "MSG":
+			select {
+			case messages <- &Message{Data: op.Data, reply: op.Reply, conn: conn}:
+			case <-ctx.Done():
+				return connected, ctx.Err()
+			}
+		}
+	}
+}
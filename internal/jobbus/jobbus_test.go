@@ -0,0 +1,109 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobbus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Subscribe", func() {
+	// serveNATS accepts one client, delivers a message for its subscription and reports the subscription and
+	// published acknowledgements
+	serveNATS := func(listener net.Listener, subscriptions chan<- string, published chan<- string) {
+		defer GinkgoRecover()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		_, _ = fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case strings.HasPrefix(line, "SUB "):
+				subscriptions <- line
+				fields := strings.Fields(line)
+				sid := fields[len(fields)-1]
+				payload := `{"jobId":42}`
+				_, _ = fmt.Fprintf(conn, "MSG %s %s $JS.ACK.jobs.1 %d\r\n%s\r\n", fields[1], sid, len(payload), payload)
+			case strings.HasPrefix(line, "PUB "):
+				var subject string
+				var size int
+				_, err := fmt.Sscanf(line, "PUB %s %d", &subject, &size)
+				Expect(err).NotTo(HaveOccurred())
+				payload := make([]byte, size+2)
+				_, err = io.ReadFull(reader, payload)
+				Expect(err).NotTo(HaveOccurred())
+				published <- subject + " " + string(payload[:size])
+			case line == "PING":
+				_, _ = fmt.Fprint(conn, "PONG\r\n")
+			}
+		}
+	}
+
+	It("delivers messages and acknowledges them to the reply subject", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+		subscriptions, published := make(chan string, 1), make(chan string, 2)
+		go serveNATS(listener, subscriptions, published)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		messages, err := Subscribe(ctx, logr.Discard(), "nats://"+listener.Addr().String()+"/forgejo.jobs?queue=listeners")
+		Expect(err).NotTo(HaveOccurred())
+
+		var message *Message
+		Eventually(messages, 5*time.Second).Should(Receive(&message))
+		Expect(string(message.Data)).To(Equal(`{"jobId":42}`))
+		Expect(<-subscriptions).To(Equal("SUB forgejo.jobs listeners 1"))
+
+		Expect(message.Ack()).To(Succeed())
+		Expect(<-published).To(Equal("$JS.ACK.jobs.1 +ACK"))
+		Expect(message.Nak(5 * time.Second)).To(Succeed())
+		Expect(<-published).To(Equal(`$JS.ACK.jobs.1 -NAK {"delay":5000000000}`))
+
+		cancel()
+		Eventually(messages, 5*time.Second).Should(BeClosed())
+	})
+
+	It("ignores acknowledgements of messages without a reply subject", func() {
+		message := &Message{Data: []byte("job")}
+		Expect(message.Ack()).To(Succeed())
+		Expect(message.Nak(time.Second)).To(Succeed())
+	})
+
+	It("rejects URLs without a subject", func() {
+		_, err := Subscribe(context.Background(), logr.Discard(), "nats://nats:4222")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,153 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jobclaim coordinates listeners of several clusters serving the same Forgejo organization, so only one
+// cluster provisions a runner per job
+// A claim is a coordination.k8s.io Lease per job in a namespace all clusters can reach; the cluster creating it
+// first holds it until it expires
+package jobclaim
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ClaimLabel marks the Leases holding job claims
+	ClaimLabel = "forgejo.actions.io/job-claim"
+
+	// JobIDAnnotation records the Forgejo job ID of a claim
+	JobIDAnnotation = "forgejo.actions.io/job-id"
+)
+
+// Claimer claims jobs for one cluster
+type Claimer struct {
+	client    client.Client
+	namespace string
+	identity  string
+	ttl       time.Duration
+	now       func() time.Time
+
+	// cleanedUp is the time of the last cleanup
+	cleanedUp time.Time
+}
+
+// New returns a Claimer creating claims in the namespace, held by identity for ttl
+func New(c client.Client, namespace, identity string, ttl time.Duration) *Claimer {
+	return &Claimer{client: c, namespace: namespace, identity: identity, ttl: ttl, now: time.Now}
+}
+
+// LeaseName returns the name of the claim Lease of a job
+func LeaseName(forgejoServer, organization string, jobID int64) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s/%s/%d", strings.TrimSuffix(forgejoServer, "/"), organization, jobID))
+	return fmt.Sprintf("forgejo-job-%d-%s", jobID, hex.EncodeToString(sum[:4]))
+}
+
+// Claim claims the job, or renews the claim if this cluster already holds it
+// Expired claims of other clusters are taken over; otherwise it returns false and the current holder
+func (c *Claimer) Claim(ctx context.Context, forgejoServer, organization string, jobID int64) (bool, string, error) {
+	now := metav1.NewMicroTime(c.now())
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        LeaseName(forgejoServer, organization, jobID),
+			Namespace:   c.namespace,
+			Labels:      map[string]string{ClaimLabel: "true"},
+			Annotations: map[string]string{JobIDAnnotation: strconv.FormatInt(jobID, 10)},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To(c.identity),
+			LeaseDurationSeconds: ptr.To(int32(c.ttl.Seconds())),
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}
+	err := c.client.Create(ctx, lease)
+	if err == nil {
+		return true, c.identity, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return false, "", fmt.Errorf("failed to create job claim: %w", err)
+	}
+
+	if err := c.client.Get(ctx, client.ObjectKeyFromObject(lease), lease); err != nil {
+		return false, "", fmt.Errorf("failed to get job claim: %w", err)
+	}
+	holder := ptr.Deref(lease.Spec.HolderIdentity, "")
+	if holder != c.identity && !c.expired(lease, 0) {
+		return false, holder, nil
+	}
+
+	if holder != c.identity {
+		lease.Spec.HolderIdentity = ptr.To(c.identity)
+		lease.Spec.AcquireTime = &now
+	}
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(c.ttl.Seconds()))
+	lease.Spec.RenewTime = &now
+	if err := c.client.Update(ctx, lease); err != nil {
+		if apierrors.IsConflict(err) {
+			// Another cluster took over the expired claim at the same time
+			return false, "", nil
+		}
+		return false, "", fmt.Errorf("failed to renew job claim: %w", err)
+	}
+	return true, c.identity, nil
+}
+
+// Cleanup deletes claims that expired more than the ttl ago
+// It runs at most once per ttl, so it can be called every poll
+func (c *Claimer) Cleanup(ctx context.Context) error {
+	if c.now().Before(c.cleanedUp.Add(c.ttl)) {
+		return nil
+	}
+	c.cleanedUp = c.now()
+
+	leases := &coordinationv1.LeaseList{}
+	if err := c.client.List(ctx, leases, client.InNamespace(c.namespace), client.MatchingLabels{ClaimLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list job claims: %w", err)
+	}
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if !c.expired(lease, c.ttl) {
+			continue
+		}
+		// The precondition keeps claims taken over since the list
+		err := c.client.Delete(ctx, lease, client.Preconditions{ResourceVersion: ptr.To(lease.ResourceVersion)})
+		if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			return fmt.Errorf("failed to delete job claim %s: %w", lease.Name, err)
+		}
+	}
+	return nil
+}
+
+// expired reports whether the lease expired more than grace ago
+func (c *Claimer) expired(lease *coordinationv1.Lease, grace time.Duration) bool {
+	if lease.Spec.RenewTime == nil {
+		return true
+	}
+	duration := time.Duration(ptr.Deref(lease.Spec.LeaseDurationSeconds, 0)) * time.Second
+	return c.now().After(lease.Spec.RenewTime.Add(duration + grace))
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobclaim
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Claimer", func() {
+	const server, org = "https://forgejo.example.com", "acme"
+
+	var (
+		ctx        context.Context
+		k8sClient  client.Client
+		now        time.Time
+		east, west *Claimer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(coordinationv1.AddToScheme(scheme)).To(Succeed())
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+		now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		clock := func() time.Time { return now }
+		east = New(k8sClient, "claims", "east", time.Minute)
+		east.now = clock
+		west = New(k8sClient, "claims", "west", time.Minute)
+		west.now = clock
+	})
+
+	It("lets only the first cluster claim a job", func() {
+		claimed, holder, err := east.Claim(ctx, server, org, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claimed).To(BeTrue())
+		Expect(holder).To(Equal("east"))
+
+		claimed, holder, err = west.Claim(ctx, server, org, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claimed).To(BeFalse())
+		Expect(holder).To(Equal("east"))
+
+		// Renewing an own claim succeeds, other jobs are independent
+		claimed, _, err = east.Claim(ctx, server+"/", org, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claimed).To(BeTrue())
+		claimed, _, err = west.Claim(ctx, server, org, 43)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claimed).To(BeTrue())
+	})
+
+	It("takes over expired claims", func() {
+		claimed, _, err := east.Claim(ctx, server, org, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claimed).To(BeTrue())
+
+		now = now.Add(2 * time.Minute)
+		claimed, holder, err := west.Claim(ctx, server, org, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claimed).To(BeTrue())
+		Expect(holder).To(Equal("west"))
+
+		claimed, holder, err = east.Claim(ctx, server, org, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claimed).To(BeFalse())
+		Expect(holder).To(Equal("west"))
+	})
+
+	It("deletes claims expired for longer than the ttl", func() {
+		_, _, err := east.Claim(ctx, server, org, 42)
+		Expect(err).NotTo(HaveOccurred())
+		now = now.Add(90 * time.Second)
+		_, _, err = east.Claim(ctx, server, org, 43)
+		Expect(err).NotTo(HaveOccurred())
+
+		now = now.Add(40 * time.Second)
+		Expect(east.Cleanup(ctx)).To(Succeed())
+
+		leases := &coordinationv1.LeaseList{}
+		Expect(k8sClient.List(ctx, leases)).To(Succeed())
+		Expect(leases.Items).To(HaveLen(1))
+		Expect(leases.Items[0].Name).To(Equal(LeaseName(server, org, 43)))
+		Expect(leases.Items[0].Annotations).To(HaveKeyWithValue(JobIDAnnotation, "43"))
+	})
+})
@@ -0,0 +1,240 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jobledger persists how many runners the listener created per job, so a restarted listener doesn't take
+// a job that reappears as waiting for a new one after its ActRunners were deleted
+// It also counts the runners that executed another job, which don't count against the job's retries
+// Entries are keyed by job ID and run attempt, so a re-run of a job still counts as a new execution
+// The ledger is a ConfigMap per ActDeployment holding the most recently updated entries
+package jobledger
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DataKey is the ConfigMap key holding the entries as JSON
+	DataKey = "ledger.json"
+
+	// DefaultSize is the number of entries kept; older ones are dropped on save
+	DefaultSize = 1000
+)
+
+// ConfigMapName returns the name of the ledger ConfigMap of an ActDeployment
+func ConfigMapName(actDeploymentName string) string {
+	return actDeploymentName + "-job-ledger"
+}
+
+// ledgerEntry records the runners created for one attempt of a job
+type ledgerEntry struct {
+	JobID   int64 `json:"jobID"`
+	Attempt int64 `json:"attempt,omitempty"`
+	Runners int32 `json:"runners"`
+	// Mismatches is the number of the runners that executed another job than this one
+	Mismatches int32     `json:"mismatches,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+type key struct {
+	jobID   int64
+	attempt int64
+}
+
+// Ledger is the in-memory copy of a ledger ConfigMap
+type Ledger struct {
+	client    client.Client
+	namespace string
+	name      string
+	size      int
+	now       func() time.Time
+
+	entries map[key]ledgerEntry
+	loaded  bool
+	dirty   bool
+}
+
+// New returns a ledger stored in the ConfigMap namespace/name, keeping size entries
+func New(c client.Client, namespace, name string, size int) *Ledger {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Ledger{client: c, namespace: namespace, name: name, size: size, now: time.Now, entries: map[key]ledgerEntry{}}
+}
+
+// Load reads the ConfigMap once; later calls do nothing
+// A missing ConfigMap is an empty ledger
+func (l *Ledger) Load(ctx context.Context) error {
+	if l.loaded {
+		return nil
+	}
+	configMap := &corev1.ConfigMap{}
+	err := l.client.Get(ctx, client.ObjectKey{Namespace: l.namespace, Name: l.name}, configMap)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get job ledger: %w", err)
+	}
+	if err == nil {
+		if err := l.merge(configMap); err != nil {
+			return err
+		}
+	}
+	l.loaded = true
+	return nil
+}
+
+// Runners returns the number of runners recorded for the attempt of the job
+func (l *Ledger) Runners(jobID, attempt int64) int32 {
+	return l.entries[key{jobID, attempt}].Runners
+}
+
+// Mismatches returns the number of runners recorded as having executed another job than the attempt of the job
+func (l *Ledger) Mismatches(jobID, attempt int64) int32 {
+	return l.entries[key{jobID, attempt}].Mismatches
+}
+
+// Record sets the number of runners created for the attempt of the job; lower counts than recorded are ignored
+func (l *Ledger) Record(jobID, attempt int64, runners int32) {
+	entry := l.entries[key{jobID, attempt}]
+	if entry.Runners >= runners {
+		return
+	}
+	entry.Runners = runners
+	l.update(jobID, attempt, entry)
+}
+
+// RecordMismatches sets the number of runners that executed another job than the attempt of the job
+// Lower counts than recorded are ignored
+func (l *Ledger) RecordMismatches(jobID, attempt int64, mismatches int32) {
+	entry := l.entries[key{jobID, attempt}]
+	if entry.Mismatches >= mismatches {
+		return
+	}
+	entry.Mismatches = mismatches
+	l.update(jobID, attempt, entry)
+}
+
+func (l *Ledger) update(jobID, attempt int64, entry ledgerEntry) {
+	entry.JobID = jobID
+	entry.Attempt = attempt
+	entry.UpdatedAt = l.now().UTC().Truncate(time.Second)
+	l.entries[key{jobID, attempt}] = entry
+	l.dirty = true
+}
+
+// Save writes the ledger if it changed, merged with the stored entries
+// The ConfigMap is created with the owner references, so it is deleted along with its ActDeployment
+func (l *Ledger) Save(ctx context.Context, ownerRefs []metav1.OwnerReference) error {
+	if !l.dirty {
+		return nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := l.client.Get(ctx, client.ObjectKey{Namespace: l.namespace, Name: l.name}, configMap)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get job ledger: %w", err)
+	}
+	exists := err == nil
+	if exists {
+		// Keep entries written by a previous listener that this one hasn't loaded
+		if err := l.merge(configMap); err != nil {
+			return err
+		}
+	} else {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: l.name, Namespace: l.namespace, OwnerReferences: ownerRefs},
+		}
+	}
+
+	l.trim()
+	data, err := json.Marshal(l.sorted())
+	if err != nil {
+		return fmt.Errorf("failed to encode job ledger: %w", err)
+	}
+	configMap.Data = map[string]string{DataKey: string(data)}
+
+	if exists {
+		err = l.client.Update(ctx, configMap)
+	} else {
+		err = l.client.Create(ctx, configMap)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save job ledger: %w", err)
+	}
+	l.dirty = false
+	return nil
+}
+
+// merge adds the entries of the ConfigMap, keeping the higher counts of entries in both
+func (l *Ledger) merge(configMap *corev1.ConfigMap) error {
+	data := configMap.Data[DataKey]
+	if data == "" {
+		return nil
+	}
+	var stored []ledgerEntry
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return fmt.Errorf("failed to decode job ledger %s/%s: %w", configMap.Namespace, configMap.Name, err)
+	}
+	for _, entry := range stored {
+		k := key{entry.JobID, entry.Attempt}
+		if current, ok := l.entries[k]; ok {
+			entry.Runners = max(entry.Runners, current.Runners)
+			entry.Mismatches = max(entry.Mismatches, current.Mismatches)
+			if current.UpdatedAt.After(entry.UpdatedAt) {
+				entry.UpdatedAt = current.UpdatedAt
+			}
+		}
+		l.entries[k] = entry
+	}
+	return nil
+}
+
+// trim drops the least recently updated entries beyond the size
+func (l *Ledger) trim() {
+	if len(l.entries) <= l.size {
+		return
+	}
+	entries := make([]ledgerEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b ledgerEntry) int {
+		return cmp.Or(b.UpdatedAt.Compare(a.UpdatedAt), cmp.Compare(b.JobID, a.JobID), cmp.Compare(b.Attempt, a.Attempt))
+	})
+	for _, entry := range entries[l.size:] {
+		delete(l.entries, key{entry.JobID, entry.Attempt})
+	}
+}
+
+// sorted returns the entries ordered by job ID and attempt, so unchanged ledgers encode the same
+func (l *Ledger) sorted() []ledgerEntry {
+	entries := make([]ledgerEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b ledgerEntry) int {
+		return cmp.Or(cmp.Compare(a.JobID, b.JobID), cmp.Compare(a.Attempt, b.Attempt))
+	})
+	return entries
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobledger
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Ledger", func() {
+	var (
+		ctx       context.Context
+		k8sClient client.Client
+		now       time.Time
+		owner     []metav1.OwnerReference
+	)
+
+	newLedger := func(size int) *Ledger {
+		ledger := New(k8sClient, "ci", ConfigMapName("runners"), size)
+		ledger.now = func() time.Time { return now }
+		return ledger
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+		now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		owner = []metav1.OwnerReference{{APIVersion: "forgejo.actions.io/v1alpha1", Kind: "ActDeployment", Name: "runners", UID: "uid"}}
+	})
+
+	It("keeps the runner counts across listener restarts", func() {
+		ledger := newLedger(0)
+		Expect(ledger.Load(ctx)).To(Succeed())
+		Expect(ledger.Runners(42, 1)).To(BeZero())
+		ledger.Record(42, 1, 2)
+		Expect(ledger.Save(ctx, owner)).To(Succeed())
+
+		restarted := newLedger(0)
+		Expect(restarted.Load(ctx)).To(Succeed())
+		Expect(restarted.Runners(42, 1)).To(Equal(int32(2)))
+
+		configMap := &corev1.ConfigMap{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "ci", Name: "runners-job-ledger"}, configMap)).To(Succeed())
+		Expect(configMap.OwnerReferences).To(Equal(owner))
+	})
+
+	It("tells attempts of a job apart", func() {
+		ledger := newLedger(0)
+		ledger.Record(42, 1, 1)
+		Expect(ledger.Runners(42, 1)).To(Equal(int32(1)))
+		Expect(ledger.Runners(42, 2)).To(BeZero())
+	})
+
+	It("ignores lower runner counts", func() {
+		ledger := newLedger(0)
+		ledger.Record(42, 1, 3)
+		ledger.Record(42, 1, 1)
+		Expect(ledger.Runners(42, 1)).To(Equal(int32(3)))
+	})
+
+	It("counts mismatches apart from the runners", func() {
+		ledger := newLedger(0)
+		ledger.Record(42, 1, 2)
+		ledger.RecordMismatches(42, 1, 1)
+		ledger.RecordMismatches(42, 1, 0)
+		ledger.Record(42, 1, 3)
+		Expect(ledger.Save(ctx, owner)).To(Succeed())
+
+		restarted := newLedger(0)
+		Expect(restarted.Load(ctx)).To(Succeed())
+		Expect(restarted.Runners(42, 1)).To(Equal(int32(3)))
+		Expect(restarted.Mismatches(42, 1)).To(Equal(int32(1)))
+	})
+
+	It("merges the entries saved by another listener", func() {
+		first := newLedger(0)
+		first.Record(1, 0, 1)
+		Expect(first.Save(ctx, owner)).To(Succeed())
+
+		// Loaded before the first one saved
+		second := newLedger(0)
+		second.loaded = true
+		second.Record(2, 0, 1)
+		Expect(second.Save(ctx, owner)).To(Succeed())
+
+		restarted := newLedger(0)
+		Expect(restarted.Load(ctx)).To(Succeed())
+		Expect(restarted.Runners(1, 0)).To(Equal(int32(1)))
+		Expect(restarted.Runners(2, 0)).To(Equal(int32(1)))
+	})
+
+	It("drops the least recently updated entries beyond its size", func() {
+		ledger := newLedger(2)
+		ledger.Record(1, 0, 1)
+		now = now.Add(time.Minute)
+		ledger.Record(2, 0, 1)
+		now = now.Add(time.Minute)
+		ledger.Record(3, 0, 1)
+		Expect(ledger.Save(ctx, owner)).To(Succeed())
+
+		restarted := newLedger(2)
+		Expect(restarted.Load(ctx)).To(Succeed())
+		Expect(restarted.Runners(1, 0)).To(BeZero())
+		Expect(restarted.Runners(2, 0)).To(Equal(int32(1)))
+		Expect(restarted.Runners(3, 0)).To(Equal(int32(1)))
+	})
+
+	It("only writes changed ledgers", func() {
+		ledger := newLedger(0)
+		Expect(ledger.Save(ctx, owner)).To(Succeed())
+		configMap := &corev1.ConfigMap{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "ci", Name: "runners-job-ledger"}, configMap)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,120 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jobqueue selects which of many waiting jobs a listener handles in one poll, with bounded memory
+package jobqueue
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+)
+
+// Order is the order in which selected jobs are handled
+type Order string
+
+const (
+	// Oldest handles the jobs with the lowest IDs first
+	Oldest Order = "Oldest"
+
+	// RepoFair takes turns between repositories, each oldest first
+	RepoFair Order = "RepoFair"
+)
+
+// Queue keeps the jobs that make the cut while the waiting jobs are streamed in
+// With a limit it holds at most limit jobs, or limit jobs per repository for RepoFair
+type Queue struct {
+	limit  int
+	order  Order
+	total  int
+	groups map[int64]*newestFirst
+}
+
+// New returns a queue that selects up to limit jobs in the given order; a limit of 0 or less selects all jobs
+func New(limit int, order Order) *Queue {
+	if order != RepoFair {
+		order = Oldest
+	}
+	return &Queue{limit: limit, order: order, groups: map[int64]*newestFirst{}}
+}
+
+// Add offers a waiting job; when the queue is full the newest job is dropped
+func (q *Queue) Add(job forgejo.Job) {
+	q.total++
+
+	key := int64(0)
+	if q.order == RepoFair {
+		key = job.RepoID
+	}
+	group := q.groups[key]
+	if group == nil {
+		group = &newestFirst{}
+		q.groups[key] = group
+	}
+	heap.Push(group, job)
+	if q.limit > 0 && group.Len() > q.limit {
+		heap.Pop(group)
+	}
+}
+
+// Total is the number of jobs offered, including the ones that didn't make the cut
+func (q *Queue) Total() int {
+	return q.total
+}
+
+// Jobs returns the selected jobs in the order they should be handled
+func (q *Queue) Jobs() []forgejo.Job {
+	groups := make([][]forgejo.Job, 0, len(q.groups))
+	rounds := 0
+	for _, group := range q.groups {
+		jobs := append([]forgejo.Job(nil), (*group)...)
+		sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+		groups = append(groups, jobs)
+		rounds = max(rounds, len(jobs))
+	}
+	// The repository with the oldest job goes first in each round
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0].ID < groups[j][0].ID })
+
+	var selected []forgejo.Job
+	for round := 0; round < rounds; round++ {
+		for _, jobs := range groups {
+			if round < len(jobs) {
+				selected = append(selected, jobs[round])
+			}
+		}
+	}
+	if q.limit > 0 && len(selected) > q.limit {
+		selected = selected[:q.limit]
+	}
+	return selected
+}
+
+// newestFirst is a heap of jobs with the highest ID on top, so the newest job is dropped first
+type newestFirst []forgejo.Job
+
+func (h newestFirst) Len() int           { return len(h) }
+func (h newestFirst) Less(i, j int) bool { return h[i].ID > h[j].ID }
+func (h newestFirst) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *newestFirst) Push(x any) { *h = append(*h, x.(forgejo.Job)) }
+
+func (h *newestFirst) Pop() any {
+	old := *h
+	job := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return job
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobqueue
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+)
+
+func ids(jobs []forgejo.Job) []int64 {
+	var result []int64
+	for _, job := range jobs {
+		result = append(result, job.ID)
+	}
+	return result
+}
+
+var _ = Describe("Queue", func() {
+	It("selects all jobs oldest first without a limit", func() {
+		queue := New(0, "")
+		for _, id := range []int64{5, 2, 9, 1} {
+			queue.Add(forgejo.Job{ID: id})
+		}
+		Expect(ids(queue.Jobs())).To(Equal([]int64{1, 2, 5, 9}))
+		Expect(queue.Total()).To(Equal(4))
+	})
+
+	It("keeps only the oldest jobs up to the limit", func() {
+		queue := New(3, Oldest)
+		for _, id := range []int64{7, 3, 10, 1, 8, 2} {
+			queue.Add(forgejo.Job{ID: id})
+		}
+		Expect(ids(queue.Jobs())).To(Equal([]int64{1, 2, 3}))
+		Expect(queue.Total()).To(Equal(6))
+	})
+
+	It("takes turns between repositories", func() {
+		queue := New(4, RepoFair)
+		for id := int64(1); id <= 6; id++ {
+			queue.Add(forgejo.Job{ID: id, RepoID: 1})
+		}
+		queue.Add(forgejo.Job{ID: 8, RepoID: 2})
+		queue.Add(forgejo.Job{ID: 7, RepoID: 2})
+		queue.Add(forgejo.Job{ID: 9, RepoID: 3})
+
+		Expect(ids(queue.Jobs())).To(Equal([]int64{1, 7, 9, 2}))
+	})
+})
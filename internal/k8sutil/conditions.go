@@ -0,0 +1,35 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sutil
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ObserveGeneration records that the conditions were evaluated for the given generation and reports whether any changed
+// Only ObservedGeneration is touched; meta.SetStatusCondition already keeps LastTransitionTime unless the status flips,
+// so tools like kstatus and Argo CD can tell stale conditions from current ones without seeing spurious transitions
+func ObserveGeneration(conditions []metav1.Condition, generation int64) bool {
+	changed := false
+	for i := range conditions {
+		if conditions[i].ObservedGeneration != generation {
+			conditions[i].ObservedGeneration = generation
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sutil
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ObserveGeneration", func() {
+	It("updates the observed generation without touching transition times", func() {
+		transition := metav1.NewTime(time.Now().Add(-time.Hour))
+		conditions := []metav1.Condition{
+			{Type: "Ready", Status: metav1.ConditionTrue, ObservedGeneration: 1, LastTransitionTime: transition},
+			{Type: "Failed", Status: metav1.ConditionFalse, ObservedGeneration: 2, LastTransitionTime: transition},
+		}
+
+		Expect(ObserveGeneration(conditions, 2)).To(BeTrue())
+		for _, condition := range conditions {
+			Expect(condition.ObservedGeneration).To(BeEquivalentTo(2))
+			Expect(condition.LastTransitionTime).To(Equal(transition))
+		}
+	})
+
+	It("reports no change when all conditions are current", func() {
+		conditions := []metav1.Condition{{Type: "Ready", ObservedGeneration: 3}}
+		Expect(ObserveGeneration(conditions, 3)).To(BeFalse())
+		Expect(ObserveGeneration(nil, 3)).To(BeFalse())
+	})
+})
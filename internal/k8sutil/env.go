@@ -0,0 +1,52 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sutil
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// MergeEnv returns env with the variables of overrides set, replacing variables of the same name
+// A replaced variable keeps the position of its first occurrence and later duplicates are dropped, so merging the
+// same overrides again yields the same list; env itself is not modified
+func MergeEnv(env []corev1.EnvVar, overrides ...corev1.EnvVar) []corev1.EnvVar {
+	index := make(map[string]int, len(overrides))
+	for i, override := range overrides {
+		index[override.Name] = i
+	}
+
+	merged := make([]corev1.EnvVar, 0, len(env)+len(overrides))
+	set := make(map[string]bool, len(overrides))
+	for _, e := range env {
+		i, ok := index[e.Name]
+		if !ok {
+			merged = append(merged, *e.DeepCopy())
+			continue
+		}
+		if !set[e.Name] {
+			merged = append(merged, *overrides[i].DeepCopy())
+			set[e.Name] = true
+		}
+	}
+	for _, override := range overrides {
+		if !set[override.Name] {
+			merged = append(merged, *override.DeepCopy())
+			set[override.Name] = true
+		}
+	}
+	return merged
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sutil
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("MergeEnv", func() {
+	overrides := []corev1.EnvVar{
+		{Name: "NAMESPACE", Value: "default"},
+		{Name: "POLL_INTERVAL", Value: "10s"},
+	}
+
+	It("appends new variables after the existing ones", func() {
+		env := []corev1.EnvVar{{Name: "LOG_VERBOSITY", Value: "1"}}
+		Expect(MergeEnv(env, overrides...)).To(Equal([]corev1.EnvVar{
+			{Name: "LOG_VERBOSITY", Value: "1"},
+			{Name: "NAMESPACE", Value: "default"},
+			{Name: "POLL_INTERVAL", Value: "10s"},
+		}))
+	})
+
+	It("replaces variables of the same name in place and drops their duplicates", func() {
+		env := []corev1.EnvVar{
+			{Name: "POLL_INTERVAL", Value: "1m"},
+			{Name: "LOG_VERBOSITY", Value: "1"},
+			{Name: "POLL_INTERVAL", Value: "5m"},
+		}
+		Expect(MergeEnv(env, overrides...)).To(Equal([]corev1.EnvVar{
+			{Name: "POLL_INTERVAL", Value: "10s"},
+			{Name: "LOG_VERBOSITY", Value: "1"},
+			{Name: "NAMESPACE", Value: "default"},
+		}))
+	})
+
+	It("yields the same list when merged again", func() {
+		env := []corev1.EnvVar{{Name: "LOG_VERBOSITY", Value: "1"}}
+		merged := MergeEnv(env, overrides...)
+		Expect(MergeEnv(merged, overrides...)).To(Equal(merged))
+	})
+
+	It("doesn't modify the given env", func() {
+		env := []corev1.EnvVar{{Name: "NAMESPACE", Value: "other"}, {Name: "LOG_VERBOSITY", Value: "1"}}
+		MergeEnv(env, overrides...)
+		Expect(env).To(Equal([]corev1.EnvVar{{Name: "NAMESPACE", Value: "other"}, {Name: "LOG_VERBOSITY", Value: "1"}}))
+	})
+})
@@ -0,0 +1,42 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package k8sutil holds helpers for writing Kubernetes objects shared by the controllers and the listener
+package k8sutil
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PatchStatus writes the status changes made to obj since original as a JSON merge patch
+// The patch carries no resourceVersion, so writers of other status fields (e.g., the listener and the controller)
+// don't drop each other's changes the way a full Status().Update of a stale object does
+// Conflicts the API server still reports under contention are retried
+func PatchStatus(ctx context.Context, c client.Client, obj, original client.Object) error {
+	base, ok := original.DeepCopyObject().(client.Object)
+	if !ok {
+		return fmt.Errorf("failed to copy %T", original)
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		base.SetResourceVersion(obj.GetResourceVersion())
+		return c.Status().Patch(ctx, obj, client.MergeFrom(base))
+	})
+}
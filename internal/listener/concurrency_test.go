@@ -0,0 +1,108 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+var _ = Describe("Concurrency groups", func() {
+	const (
+		organization = "concurrency-org"
+		namespace    = "runners"
+	)
+
+	var (
+		ctx           context.Context
+		server        *fake.Server
+		c             client.Client
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+	)
+
+	// addJob queues a job of the run in the repository, whose run is in the deploy concurrency group
+	addJob := func(repo forgejo.Repository, jobID, runID int64) {
+		server.AddJob(organization, forgejo.Job{ID: jobID, RepoID: repo.ID, RunsOn: []string{"docker"}})
+		server.AddJobRun(organization, repo.Name, jobID, forgejo.Run{ID: runID, ConcurrencyGroup: "deploy"})
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = fake.NewServer()
+		c = clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithStatusSubresource(&forgejoactionsiov1alpha1.ActRunner{}).Build()
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "concurrency", Namespace: namespace, UID: "concurrency-uid"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:       server.URL(),
+				Organization:        organization,
+				Labels:              "docker",
+				TokenSecretRef:      corev1.SecretReference{Name: "forgejo-token"},
+				RunnerImage:         "runner:test",
+				DockerInDockerImage: "dind:test",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// poll runs a poll and returns the job IDs with an ActRunner
+	poll := func() []int64 {
+		forgejoClient := forgejo.NewClient(server.URL(), "token")
+		config := listener.Config{Organization: organization, Labels: "docker", Namespace: namespace}
+		Expect(listener.NewPoller(GinkgoLogr, c, forgejoClient, record.NewFakeRecorder(10), listener.NewJobState(), config).PollAndCreateActRunners(ctx, actDeployment)).To(Succeed())
+		actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+		Expect(c.List(ctx, actRunners, client.InNamespace(namespace))).To(Succeed())
+		var jobIDs []int64
+		for _, ar := range actRunners.Items {
+			jobIDs = append(jobIDs, ar.Spec.ForgejoJobID)
+		}
+		return jobIDs
+	}
+
+	It("serializes runs of a group per repository and lets the jobs of the holding run through", func() {
+		app := forgejo.Repository{ID: 1, Name: "app", FullName: organization + "/app"}
+		docs := forgejo.Repository{ID: 2, Name: "docs", FullName: organization + "/docs"}
+		server.AddRepository(organization, app)
+		server.AddRepository(organization, docs)
+
+		// Jobs 11 and 12 belong to the same run, job 13 to a later run of the group in the same repository
+		addJob(app, 11, 11)
+		addJob(app, 12, 11)
+		addJob(app, 13, 13)
+		// A group of the same name in another repository is a different group
+		addJob(docs, 14, 14)
+
+		Expect(poll()).To(ConsistOf(int64(11), int64(12), int64(14)))
+
+		// The runners' status holds the group on the next poll
+		Expect(poll()).To(ConsistOf(int64(11), int64(12), int64(14)))
+	})
+})
@@ -0,0 +1,138 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/mock"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+var _ = Describe("Idle runner deregistration", func() {
+	const (
+		organization = "idle-org"
+		namespace    = "runners"
+	)
+
+	var (
+		ctx           context.Context
+		server        *fake.Server
+		c             client.Client
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = fake.NewServer()
+		c = clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithStatusSubresource(&forgejoactionsiov1alpha1.ActRunner{}).Build()
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "idle", Namespace: namespace, UID: "idle-uid"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:       server.URL(),
+				Organization:        organization,
+				Labels:              "docker",
+				TokenSecretRef:      corev1.SecretReference{Name: "forgejo-token"},
+				RunnerImage:         "runner:test",
+				DockerInDockerImage: "dind:test",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// createRunner creates a finished ActRunner of the ActDeployment whose runner container exited with the exit code
+	createRunner := func(name string, exitCode int32, runnerID int64) {
+		ar := &forgejoactionsiov1alpha1.ActRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: forgejoactionsiov1alpha1.GroupVersion.String(), Kind: "ActDeployment",
+					Name: actDeployment.Name, UID: actDeployment.UID, Controller: ptr.To(true),
+				}},
+			},
+			Spec: forgejoactionsiov1alpha1.ActRunnerSpec{ForgejoServer: server.URL(), Organization: organization, ForgejoJobID: 1},
+		}
+		Expect(c.Create(ctx, ar)).To(Succeed())
+		ar.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded
+		ar.Status.RunnerName = "runner-" + name
+		ar.Status.RunnerID = runnerID
+		ar.Status.RunnerContainer = &forgejoactionsiov1alpha1.ContainerTermination{ExitCode: exitCode}
+		Expect(c.Status().Update(ctx, ar)).To(Succeed())
+	}
+
+	poll := func(forgejoClient forgejo.API) {
+		config := listener.Config{Organization: organization, Labels: "docker", Namespace: namespace}
+		Expect(listener.NewPoller(GinkgoLogr, c, forgejoClient, record.NewFakeRecorder(10), listener.NewJobState(), config).PollAndCreateActRunners(ctx, actDeployment)).To(Succeed())
+	}
+
+	It("removes the runners of idle-timed-out ActRunners from Forgejo once", func() {
+		server.AddRunner(organization, forgejo.Runner{ID: 3, Name: "runner-recorded"})
+		server.AddRunner(organization, forgejo.Runner{ID: 4, Name: "runner-unrecorded"})
+		server.AddRunner(organization, forgejo.Runner{ID: 5, Name: "runner-finished"})
+		// The runner with a recorded ID is deleted by it, the other one is looked up by name
+		createRunner("recorded", forgejoactionsiov1alpha1.IdleTimeoutExitCode, 3)
+		createRunner("unrecorded", forgejoactionsiov1alpha1.IdleTimeoutExitCode, 0)
+		createRunner("finished", 0, 5)
+
+		forgejoClient := forgejo.NewClient(server.URL(), "token")
+		poll(forgejoClient)
+		Expect(server.Runners(organization)).To(ConsistOf(forgejo.Runner{ID: 5, Name: "runner-finished"}))
+		for _, name := range []string{"recorded", "unrecorded"} {
+			ar := &forgejoactionsiov1alpha1.ActRunner{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, ar)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(ar.Status.Conditions, forgejoactionsiov1alpha1.ConditionDeregistered)).To(BeTrue())
+		}
+
+		// Deregistered runners are not deleted again
+		server.AddRunner(organization, forgejo.Runner{ID: 3, Name: "runner-recorded"})
+		poll(forgejoClient)
+		Expect(server.Runners(organization)).To(ContainElement(forgejo.Runner{ID: 3, Name: "runner-recorded"}))
+	})
+
+	It("retries a failed deregistration on the next poll", func() {
+		createRunner("recorded", forgejoactionsiov1alpha1.IdleTimeoutExitCode, 3)
+		forgejoClient := &mock.API{DeleteRunnerFunc: func(context.Context, string, int64) error { return forgejo.ErrServerError }}
+
+		poll(forgejoClient)
+		ar := &forgejoactionsiov1alpha1.ActRunner{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "recorded"}, ar)).To(Succeed())
+		Expect(meta.FindStatusCondition(ar.Status.Conditions, forgejoactionsiov1alpha1.ConditionDeregistered)).To(BeNil())
+
+		forgejoClient.DeleteRunnerFunc = nil
+		poll(forgejoClient)
+		Expect(forgejoClient.CallCount("DeleteRunner")).To(Equal(2))
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "recorded"}, ar)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(ar.Status.Conditions, forgejoactionsiov1alpha1.ConditionDeregistered)).To(BeTrue())
+	})
+})
@@ -0,0 +1,108 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/jobbus"
+)
+
+// Config is what the controller passes to a listener loop embedded in the manager,
+// the same values it sets as environment variables of a dedicated listener Deployment
+type Config struct {
+	ForgejoServer     string
+	Organization      string
+	Labels            string
+	TokenSecretName   string
+	TokenSecretKey    string
+	Namespace         string
+	ActDeploymentName string
+	PollInterval      time.Duration
+
+	// ClientCertSecretName names a kubernetes.io/tls secret presented as client certificate to Forgejo
+	ClientCertSecretName string
+
+	// APIHeaders are sent with every Forgejo API request, with secret values already resolved
+	APIHeaders map[string]string
+
+	APIQPS   float64
+	APIBurst int
+
+	// DefaultRunnerImage and DefaultDinDImage are the ActOrg images used when the ActDeployment sets none
+	DefaultRunnerImage string
+	DefaultDinDImage   string
+
+	// SkipTLSVerify and JobBusURL are SKIP_TLS_VERIFY and JOB_BUS_URL of the listenerTemplate
+	SkipTLSVerify bool
+	JobBusURL     string
+}
+
+// defaults are the values applied to the ActDeployment when its spec leaves them empty
+func (c Config) defaults() deploymentDefaults {
+	return deploymentDefaults{
+		forgejoServer:       c.ForgejoServer,
+		organization:        c.Organization,
+		tokenSecretName:     c.TokenSecretName,
+		namespace:           c.Namespace,
+		runnerImage:         c.DefaultRunnerImage,
+		dockerInDockerImage: c.DefaultDinDImage,
+	}
+}
+
+// Run polls Forgejo and creates ActRunners for the ActDeployment of config until ctx is cancelled
+// It is the run command of the listener binary without job claims
+func Run(ctx context.Context, logger logr.Logger, k8sClient client.Client, recorder record.EventRecorder, config Config) error {
+	// The job bus subscription ends with this run, not with the loop that restarts it
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var notifications <-chan *jobbus.Message
+	if config.JobBusURL != "" {
+		var err error
+		notifications, err = jobbus.Subscribe(ctx, logger.WithName("jobbus"), config.JobBusURL)
+		if err != nil {
+			return fmt.Errorf("invalid job bus URL: %w", err)
+		}
+	}
+
+	return runListener(ctx, logger, k8sClient, recorder, options{
+		Config:        config,
+		connOptions:   forgejo.DefaultConnectionOptions(),
+		retryOptions:  forgejo.DefaultRetryOptions(),
+		notifications: notifications,
+	})
+}
+
+// RegisterMetrics registers the listener metrics with registry, so embedded listener loops are reported by the manager
+// The metrics are shared by all embedded loops
+func RegisterMetrics(registry prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{pollsTotal, pollDuration, pendingJobs, actRunnersCreated} {
+		if err := registry.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
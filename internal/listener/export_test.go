@@ -0,0 +1,74 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/jobledger"
+)
+
+// The envtest suite is an external test package, since it also drives the controllers, which import the listener
+var (
+	Scheme            = scheme
+	LoadActDeployment = loadActDeployment
+	NewJobState       = newJobState
+	PollAndCreateJobs = pollAndCreateJobs
+	UnfinishedNeeds   = unfinishedNeeds
+
+	UpdateExistingActRunners = updateExistingActRunners
+
+	ExpireRegistrationSecrets    = expireRegistrationSecrets
+	ReconcileRegistrationSecrets = reconcileRegistrationSecrets
+	RecordShutdown               = recordShutdown
+
+	PendingJobs       = pendingJobs
+	ActRunnersCreated = actRunnersCreated
+)
+
+// NewJobStateWithLedger returns job state that persists its counts in the ActDeployment's job ledger
+func NewJobStateWithLedger(c client.Client, namespace, actDeploymentName string) *jobState {
+	state := newJobState()
+	state.ledger = jobledger.New(c, namespace, jobledger.ConfigMapName(actDeploymentName), jobledger.DefaultSize)
+	return state
+}
+
+// NewPoller returns the poller of a listener loop without job claims
+func NewPoller(logger logr.Logger, c client.Client, forgejoClient forgejo.API, recorder record.EventRecorder, state *jobState, config Config) *poller {
+	return &poller{logger: logger, k8sClient: c, forgejoClient: forgejoClient, recorder: recorder, state: state, config: config}
+}
+
+// PollAndCreateActRunners creates an ActRunner for each waiting job that needs one
+func (p *poller) PollAndCreateActRunners(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	return p.pollAndCreateActRunners(ctx, actDeployment)
+}
+
+// NewPollHealth returns the poll health of a listener loop that hasn't recorded a poll yet
+func NewPollHealth() *pollHealth {
+	return &pollHealth{}
+}
+
+// Record stores the outcome of a poll in the ActDeployment status
+func (h *pollHealth) Record(ctx context.Context, logger logr.Logger, c client.Client, namespace, actDeploymentName string, pollErr error) {
+	h.record(ctx, logger, c, namespace, actDeploymentName, pollErr)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/controller"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+var _ = Describe("Job lifecycle", func() {
+	const (
+		namespace      = "default"
+		deploymentName = "lifecycle"
+		organization   = "org"
+		jobID          = int64(101)
+	)
+
+	var server *fake.Server
+
+	BeforeEach(func() {
+		server = fake.NewServer()
+		job := forgejo.Job{ID: jobID, RepoID: 1, Name: "build", RunsOn: []string{"docker"}}
+		server.AddRepository(organization, forgejo.Repository{ID: 1, Name: "app", FullName: organization + "/app"})
+		server.AddJob(organization, job)
+		server.AddRun(organization, "app", forgejo.Run{ID: jobID, Title: "build", TriggerEvent: "push"}, []forgejo.Job{job})
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: deploymentName, Namespace: namespace},
+		}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "forgejo-token", Namespace: namespace},
+		}))).To(Succeed())
+	})
+
+	It("takes a job from Forgejo through a runner pod to cleanup", func() {
+		By("creating the token secret and ActDeployment")
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "forgejo-token", Namespace: namespace},
+			Data:       map[string][]byte{"token": []byte("token")},
+		})).To(Succeed())
+		Expect(k8sClient.Create(ctx, &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: deploymentName, Namespace: namespace},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:       server.URL(),
+				Organization:        organization,
+				Labels:              "docker",
+				TokenSecretRef:      corev1.SecretReference{Name: "forgejo-token"},
+				RunnerImage:         "runner:test",
+				DockerInDockerImage: "dind:test",
+			},
+		})).To(Succeed())
+
+		By("reconciling the ActDeployment into a listener Deployment")
+		deploymentReconciler := &controller.ActDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		_, err := deploymentReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: deploymentName}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: deploymentName + "-listener"}, &appsv1.Deployment{})).To(Succeed())
+
+		By("polling the fake Forgejo server")
+		actDeployment, err := listener.LoadActDeployment(ctx, GinkgoLogr, k8sClient, namespace, deploymentName)
+		Expect(err).NotTo(HaveOccurred())
+		forgejoClient := forgejo.NewClient(server.URL(), "token")
+		state := listener.NewJobState()
+		Expect(listener.NewPoller(GinkgoLogr, k8sClient, forgejoClient, record.NewFakeRecorder(10), state, listener.Config{Organization: organization, Labels: "docker", Namespace: namespace}).PollAndCreateActRunners(ctx, actDeployment)).To(Succeed())
+
+		actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+		Expect(k8sClient.List(ctx, actRunners, client.InNamespace(namespace))).To(Succeed())
+		Expect(actRunners.Items).To(HaveLen(1))
+		actRunner := &actRunners.Items[0]
+		Expect(actRunner.Spec.ForgejoJobID).To(Equal(jobID))
+		Expect(actRunner.Status.RepositoryFullName).To(Equal(organization + "/app"))
+
+		registrationSecret := &corev1.Secret{}
+		registrationKey := types.NamespacedName{Namespace: namespace, Name: actRunner.Spec.RegistrationTokenSecretRef.Name}
+		Expect(k8sClient.Get(ctx, registrationKey, registrationSecret)).To(Succeed())
+		Expect(string(registrationSecret.Data["token"])).To(Equal(fake.RegistrationToken))
+		Expect(metav1.IsControlledBy(registrationSecret, actRunner)).To(BeTrue())
+		Expect(registrationSecret.Annotations).To(HaveKey("forgejo.actions.io/expires-at"))
+
+		By("reconciling the ActRunner into a runner pod")
+		runnerReconciler := &controller.ActRunnerReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		runnerKey := client.ObjectKeyFromObject(actRunner)
+		_, err = runnerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: runnerKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, runnerKey, actRunner)).To(Succeed())
+		Expect(actRunner.Status.KubernetesJobName).NotTo(BeEmpty())
+
+		pod := &corev1.Pod{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: actRunner.Status.KubernetesJobName}, pod)).To(Succeed())
+		Expect(pod.Spec.Containers[0].Image).To(Equal("runner:test"))
+
+		By("completing the runner pod")
+		server.SetJobStatus(organization, jobID, "success")
+		pod.Status.Phase = corev1.PodSucceeded
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  pod.Spec.Containers[0].Name,
+			Image: pod.Spec.Containers[0].Image,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}},
+		}}
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+		_, err = runnerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: runnerKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, runnerKey, actRunner)).To(Succeed())
+		Expect(actRunner.Status.Phase).To(Equal(forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded))
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, registrationKey, &corev1.Secret{}))).To(BeTrue())
+
+		By("not creating another runner for the finished job")
+		Expect(listener.NewPoller(GinkgoLogr, k8sClient, forgejoClient, record.NewFakeRecorder(10), state, listener.Config{Organization: organization, Labels: "docker", Namespace: namespace}).PollAndCreateActRunners(ctx, actDeployment)).To(Succeed())
+		Expect(k8sClient.List(ctx, actRunners, client.InNamespace(namespace))).To(Succeed())
+		Expect(actRunners.Items).To(HaveLen(1))
+
+		By("deleting the ActRunner after the cleanup delay")
+		completedAt := metav1.NewTime(time.Now().Add(-5 * time.Minute))
+		actRunner.Status.CompletedAt = &completedAt
+		Expect(k8sClient.Status().Update(ctx, actRunner)).To(Succeed())
+		_, err = runnerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: runnerKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, runnerKey, &forgejoactionsiov1alpha1.ActRunner{}))).To(BeTrue())
+	})
+})
@@ -14,7 +14,9 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package main
+// Package listener polls Forgejo for waiting jobs and creates an ActRunner for each of them
+// It runs as its own binary in the listener Deployment of an ActDeployment, or inside the manager with --mode=all
+package listener
 
 import (
 	"context"
@@ -23,39 +25,100 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/diagnostics"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/eventagg"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/faultinject"
 	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/heartbeat"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/jobbus"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/jobclaim"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/jobledger"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/k8sutil"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/operatorconfig"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/podbuilder"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/podcompliance"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/podlint"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/rollout"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerlabels"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerspec"
 )
 
 var (
 	scheme = runtime.NewScheme()
+
+	// metricsRegistry holds the listener metrics served on --metrics-bind-address
+	metricsRegistry = prometheus.NewRegistry()
+
+	pollsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "forgejo_listener_polls_total",
+		Help: "Number of Forgejo polls by result",
+	}, []string{"result"})
+	pollDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "forgejo_listener_poll_duration_seconds",
+		Help:    "Duration of a Forgejo poll including ActRunner creation",
+		Buckets: prometheus.DefBuckets,
+	})
+	pendingJobs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "forgejo_listener_pending_jobs",
+		Help: "Number of waiting jobs matching the listener's labels in the last poll",
+	})
+	actRunnersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "forgejo_listener_actrunners_created_total",
+		Help: "Number of ActRunners created by the listener",
+	})
 )
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(forgejoactionsiov1alpha1.AddToScheme(scheme))
+
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		pollsTotal, pollDuration, pendingJobs, actRunnersCreated,
+	)
 }
 
-func main() {
+// Main runs the listener command line; cmd/listener is a thin wrapper around it
+func Main() {
 	// Helper to get value from env or use default
 	getEnvOrEmpty := func(key string) string {
 		if val := os.Getenv(key); val != "" {
@@ -85,7 +148,14 @@ func main() {
 		tokenSecretKey    = flag.String("token-secret-key", getEnvOrDefault("TOKEN_SECRET_KEY", "token"), "Key in the secret containing the token (can also be set via TOKEN_SECRET_KEY env var)")
 		namespace         = flag.String("namespace", getEnvOrEmpty("NAMESPACE"), "Kubernetes namespace (required, can also be set via NAMESPACE env var)")
 		actDeploymentName = flag.String("act-deployment-name", getEnvOrEmpty("ACT_DEPLOYMENT_NAME"), "Name of the ActDeployment resource (required, can also be set via ACT_DEPLOYMENT_NAME env var)")
+		clientCertSecret  = flag.String("client-cert-secret-name", getEnvOrEmpty("CLIENT_CERT_SECRET_NAME"), "Name of a kubernetes.io/tls secret presented as client certificate to Forgejo (can also be set via CLIENT_CERT_SECRET_NAME env var)")
+		fakeForgejo       = flag.Bool("fake-forgejo", getEnvOrBool("FAKE_FORGEJO", false), "Development mode: poll an in-process fake Forgejo server that queues a demo job periodically instead of --forgejo-server (can also be set via FAKE_FORGEJO env var)")
+		metricsAddr       = flag.String("metrics-bind-address", getEnvOrDefault("METRICS_BIND_ADDRESS", "0"), "Address the Prometheus metrics endpoint binds to, 0 to disable (can also be set via METRICS_BIND_ADDRESS env var)")
+		pprofAddr         = flag.String("pprof-bind-address", getEnvOrDefault("PPROF_BIND_ADDRESS", "0"), "Address serving pprof profiles under /debug/pprof/ and a JSON state dump under /debug/state, 0 to disable (can also be set via PPROF_BIND_ADDRESS env var)")
+		apiDebug          = flag.Bool("api-debug", getEnvOrBool("API_DEBUG", false), "Log Forgejo API requests with status codes, latencies and truncated response bodies (can also be set via API_DEBUG env var)")
 		skipTLSVerify     = flag.Bool("skip-tls-verify", getEnvOrBool("SKIP_TLS_VERIFY", false), "Skip TLS certificate verification (can also be set via SKIP_TLS_VERIFY env var)")
+		defaultRunner     = flag.String("default-runner-image", getEnvOrEmpty("DEFAULT_RUNNER_IMAGE"), "Runner image used when the ActDeployment sets none, e.g. from an ActOrg (can also be set via DEFAULT_RUNNER_IMAGE env var)")
+		defaultDinD       = flag.String("default-dind-image", getEnvOrEmpty("DEFAULT_DIND_IMAGE"), "Docker-in-Docker image used when the ActDeployment sets none, e.g. from an ActOrg (can also be set via DEFAULT_DIND_IMAGE env var)")
 	)
 
 	// Handle poll-interval separately since it's a duration
@@ -95,20 +165,118 @@ func main() {
 		pollIntervalDefault = 10 * time.Second
 	}
 	pollIntervalFlag := flag.Duration("poll-interval", pollIntervalDefault, "Polling interval (can also be set via POLL_INTERVAL env var)")
+	claimTTLDefault, err := time.ParseDuration(getEnvOrDefault("CLAIM_TTL", "10m"))
+	if err != nil {
+		claimTTLDefault = 10 * time.Minute
+	}
+	claimTTL := flag.Duration("claim-ttl", claimTTLDefault, "Time a job claim is held without renewal (can also be set via CLAIM_TTL env var)")
+	fakeJobInterval := flag.Duration("fake-forgejo-job-interval", time.Minute, "Interval at which the fake Forgejo server queues demo jobs")
 
-	flag.Parse()
+	// Handle the Forgejo API rate limit separately since it's numeric
+	apiQPSDefault, err := strconv.ParseFloat(getEnvOrDefault("FORGEJO_API_QPS", "0"), 64)
+	if err != nil {
+		apiQPSDefault = 0
+	}
+	apiBurstDefault, err := strconv.Atoi(getEnvOrDefault("FORGEJO_API_BURST", "0"))
+	if err != nil {
+		apiBurstDefault = 0
+	}
+	apiQPS := flag.Float64("forgejo-api-qps", apiQPSDefault, "Forgejo API requests per second, 0 for unlimited (can also be set via FORGEJO_API_QPS env var)")
+	apiBurst := flag.Int("forgejo-api-burst", apiBurstDefault, "Burst size for the Forgejo API rate limit (can also be set via FORGEJO_API_BURST env var)")
+
+	// Handle Forgejo API connection pool and retry tuning separately since they're numeric
+	connOptions := forgejo.DefaultConnectionOptions()
+	retryOptions := forgejo.DefaultRetryOptions()
+	getEnvOrInt := func(key string, defaultValue int) int {
+		if val, err := strconv.Atoi(os.Getenv(key)); err == nil {
+			return val
+		}
+		return defaultValue
+	}
+	if val, err := time.ParseDuration(os.Getenv("FORGEJO_IDLE_CONN_TIMEOUT")); err == nil {
+		connOptions.IdleConnTimeout = val
+	}
+	flag.IntVar(&connOptions.MaxIdleConns, "forgejo-max-idle-conns", getEnvOrInt("FORGEJO_MAX_IDLE_CONNS", connOptions.MaxIdleConns), "Maximum idle connections kept open (can also be set via FORGEJO_MAX_IDLE_CONNS env var)")
+	flag.IntVar(&connOptions.MaxIdleConnsPerHost, "forgejo-max-idle-conns-per-host", getEnvOrInt("FORGEJO_MAX_IDLE_CONNS_PER_HOST", connOptions.MaxIdleConnsPerHost), "Maximum idle connections kept open to the Forgejo server (can also be set via FORGEJO_MAX_IDLE_CONNS_PER_HOST env var)")
+	flag.IntVar(&connOptions.MaxConnsPerHost, "forgejo-max-conns-per-host", getEnvOrInt("FORGEJO_MAX_CONNS_PER_HOST", connOptions.MaxConnsPerHost), "Maximum connections to the Forgejo server, 0 for unlimited (can also be set via FORGEJO_MAX_CONNS_PER_HOST env var)")
+	flag.DurationVar(&connOptions.IdleConnTimeout, "forgejo-idle-conn-timeout", connOptions.IdleConnTimeout, "Time after which idle connections are closed (can also be set via FORGEJO_IDLE_CONN_TIMEOUT env var)")
+	podTemplateFile := flag.String("pod-template", getEnvOrEmpty("POD_TEMPLATE_FILE"), "Standalone mode: YAML file with the pod template of runner Jobs (required for standalone, can also be set via POD_TEMPLATE_FILE env var)")
+	claimNamespace := flag.String("claim-namespace", getEnvOrEmpty("CLAIM_NAMESPACE"), "Namespace of the job claim Leases shared by the clusters serving the organization, empty to disable claims (can also be set via CLAIM_NAMESPACE env var)")
+	claimKubeconfig := flag.String("claim-kubeconfig", getEnvOrEmpty("CLAIM_KUBECONFIG"), "Kubeconfig file of the cluster holding the job claims, empty for this cluster (can also be set via CLAIM_KUBECONFIG env var)")
+	clusterName := flag.String("cluster-name", getEnvOrEmpty("CLUSTER_NAME"), "Name identifying this cluster in job claims (required with --claim-namespace, can also be set via CLUSTER_NAME env var)")
+	jobBusURL := flag.String("job-bus", getEnvOrEmpty("JOB_BUS_URL"), "nats://host:port/subject[?queue=group] URL of job notifications that trigger a poll right away, e.g. from a webhook relay (can also be set via JOB_BUS_URL env var)")
+	logVerbosity := flag.Int("log-verbosity", getEnvOrInt("LOG_VERBOSITY", 0), "Log verbosity: 1 adds per-job details to the poll summaries, 2 adds Forgejo API requests (can also be set via LOG_VERBOSITY env var)")
+	maxRunners := flag.Int("max-runners", getEnvOrInt("MAX_RUNNERS", 0), "Standalone mode: maximum number of unfinished runner Jobs, 0 for unlimited (can also be set via MAX_RUNNERS env var)")
+	lintFile := flag.String("f", "", "Lint: ActDeployment YAML file to render a runner pod for")
+	lintRunsOn := flag.String("runs-on", "", "Lint: comma-separated runs-on labels of the sample job, default the first runner label")
+	apiFaults := faultinject.APIFaults{}
+	apiFaults.BindFlags(flag.CommandLine)
+	flag.IntVar(&retryOptions.MaxRetries, "forgejo-api-retries", getEnvOrInt("FORGEJO_API_RETRIES", retryOptions.MaxRetries), "Retries of Forgejo API requests failing with network errors or 502/503/504, 0 to disable (can also be set via FORGEJO_API_RETRIES env var)")
+
+	// The first argument selects a subcommand: run (default), validate, once, standalone or lint
+	command, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), `Usage: %s [run|validate|once|standalone|lint] [flags]
+
+Commands:
+  run         poll Forgejo and create ActRunners until stopped (default)
+  validate    check the token, organization access, labels and registration token permission, then exit
+  once        run a single poll cycle, then exit
+  standalone  poll Forgejo and create plain Jobs from --pod-template, without CRDs
+  lint        render the runner pod of the ActDeployment in -f for a sample job and check it, without a cluster
+
+Flags:
+`, os.Args[0])
+		faultinject.PrintDefaults(flag.CommandLine)
+	}
+	_ = flag.CommandLine.Parse(args)
+	if !slices.Contains([]string{"run", "validate", "once", "standalone", "lint"}, command) {
+		fmt.Fprintf(flag.CommandLine.Output(), "unknown command %q\n", command)
+		flag.Usage()
+		os.Exit(2)
+	}
 
 	// Use the flag value (which may have been overridden from env var or command line)
 	pollInterval := *pollIntervalFlag
 
+	// Extra API headers are only passed via env, so secret values never show up in the pod spec
+	apiHeaders := apiHeadersFromEnv()
+
 	// Set up logger
-	zapLog, err := zap.NewProduction()
+	// --api-debug lowers the level so the Forgejo client's V(2) request logs are written
+	verbosity := max(*logVerbosity, 0)
+	if *apiDebug {
+		verbosity = max(verbosity, 2)
+	}
+	zapConfig := zap.NewProductionConfig()
+	zapConfig.Level = zap.NewAtomicLevelAt(zapcore.Level(-verbosity))
+	zapLog, err := zapConfig.Build()
 	if err != nil {
 		panic(fmt.Sprintf("failed to initialize logger: %v", err))
 	}
 	logger := zapr.NewLogger(zapLog)
 
-	if *forgejoServer == "" || *organization == "" || *labels == "" || *tokenSecretName == "" || *namespace == "" || *actDeploymentName == "" {
+	// lint works offline, so it needs none of the connection flags
+	if command == "lint" {
+		defaults := deploymentDefaults{runnerImage: *defaultRunner, dockerInDockerImage: *defaultDinD}
+		if err := lintActDeployment(os.Stdout, os.Stderr, *lintFile, *lintRunsOn, defaults); err != nil {
+			logger.Error(err, "lint failed")
+			os.Exit(1)
+		}
+		return
+	}
+	if apiFaults.Enabled() {
+		logger.Info("injecting Forgejo API faults", "errorRate", apiFaults.ErrorRate, "latency", apiFaults.Latency)
+		connOptions.WrapTransport = apiFaults.Transport
+	}
+
+	// validate doesn't need an ActDeployment, but checks it if one is named; standalone mode has none
+	needsActDeployment := command == "run" || command == "once"
+	if *forgejoServer == "" || *organization == "" || *labels == "" || *tokenSecretName == "" || *namespace == "" ||
+		(*actDeploymentName == "" && needsActDeployment) || (*podTemplateFile == "" && command == "standalone") {
 		logger.Error(fmt.Errorf("missing required flags"), "missing required flags")
 		flag.Usage()
 		os.Exit(1)
@@ -122,6 +290,18 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Record events on the ActDeployment, so problems show up in kubectl describe
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		logger.Error(err, "failed to create Kubernetes clientset")
+		os.Exit(1)
+	}
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events(*namespace)})
+	defer eventBroadcaster.Shutdown()
+	// Recurring warnings, e.g. on every failed poll, are emitted at most once per interval
+	recorder := eventagg.New(eventBroadcaster.NewRecorder(scheme, corev1.EventSource{Component: "forgejo-listener"}), eventagg.DefaultInterval)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -137,8 +317,128 @@ func main() {
 		cancel()
 	}()
 
+	// Serve Prometheus metrics
+	if *metricsAddr != "0" {
+		metricsServer := &http.Server{
+			Addr:              *metricsAddr,
+			Handler:           promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error(err, "metrics server failed")
+			}
+		}()
+		defer metricsServer.Close()
+		logger.Info("serving metrics", "address", *metricsAddr)
+	}
+
+	// Serve live profiles and the listener's configuration and poll counters
+	if *pprofAddr != "0" {
+		diagnosticsServer := &diagnostics.Server{
+			Addr: *pprofAddr,
+			State: func(context.Context) (any, error) {
+				metrics, err := diagnostics.Metrics(metricsRegistry, "forgejo_listener_")
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{
+					"command":       command,
+					"organization":  *organization,
+					"labels":        *labels,
+					"namespace":     *namespace,
+					"actDeployment": *actDeploymentName,
+					"pollInterval":  pollInterval.String(),
+					"metrics":       metrics,
+				}, nil
+			},
+			Logger: logger,
+		}
+		go func() {
+			if err := diagnosticsServer.Start(ctx); err != nil {
+				logger.Error(err, "diagnostics server failed")
+			}
+		}()
+	}
+
+	// In development mode, poll a fake Forgejo server instead of the real one
+	// The token secret must still exist, but any token is accepted
+	if *fakeForgejo {
+		runnerLabels, err := runnerlabels.Parse(*labels)
+		if err != nil {
+			logger.Error(err, "failed to parse runner labels")
+			os.Exit(1)
+		}
+		fakeServer := fake.NewServer()
+		defer fakeServer.Close()
+		go fakeServer.RunDemo(ctx, *organization, strings.Split(runnerlabels.Names(runnerLabels), ","), *fakeJobInterval)
+		logger.Info("using fake Forgejo server", "url", fakeServer.URL(), "jobInterval", *fakeJobInterval)
+		*forgejoServer = fakeServer.URL()
+	}
+
+	opts := options{
+		Config: Config{
+			ForgejoServer:        *forgejoServer,
+			Organization:         *organization,
+			Labels:               *labels,
+			TokenSecretName:      *tokenSecretName,
+			TokenSecretKey:       *tokenSecretKey,
+			Namespace:            *namespace,
+			ActDeploymentName:    *actDeploymentName,
+			PollInterval:         pollInterval,
+			ClientCertSecretName: *clientCertSecret,
+			APIHeaders:           apiHeaders,
+			APIQPS:               *apiQPS,
+			APIBurst:             *apiBurst,
+			DefaultRunnerImage:   *defaultRunner,
+			DefaultDinDImage:     *defaultDinD,
+			SkipTLSVerify:        *skipTLSVerify,
+			JobBusURL:            *jobBusURL,
+		},
+		connOptions:  connOptions,
+		retryOptions: retryOptions,
+		once:         command == "once",
+		maxRunners:   *maxRunners,
+	}
+
+	if command == "validate" {
+		if err := validateListener(ctx, logger, k8sClient, os.Stdout, opts); err != nil {
+			logger.Error(err, "validation failed")
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == "standalone" {
+		opts.podTemplate, err = loadPodTemplate(*podTemplateFile)
+		if err != nil {
+			logger.Error(err, "failed to load pod template")
+			os.Exit(1)
+		}
+		if err := runStandalone(ctx, logger, k8sClient, opts); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error(err, "listener failed")
+			os.Exit(1)
+		}
+		logger.Info("listener stopped")
+		return
+	}
+
 	// Run the listener
-	if err := runListener(ctx, logger, k8sClient, *forgejoServer, *organization, *labels, *tokenSecretName, *tokenSecretKey, *namespace, *actDeploymentName, pollInterval, *skipTLSVerify); err != nil {
+	if *jobBusURL != "" && command == "run" {
+		opts.notifications, err = jobbus.Subscribe(ctx, logger.WithName("jobbus"), *jobBusURL)
+		if err != nil {
+			logger.Error(err, "invalid job bus URL")
+			os.Exit(1)
+		}
+	}
+	if *claimNamespace != "" {
+		opts.claimer, err = newClaimer(cfg, scheme, *claimKubeconfig, *claimNamespace, *clusterName, *claimTTL)
+		if err != nil {
+			logger.Error(err, "failed to set up job claims")
+			os.Exit(1)
+		}
+	}
+	if err := runListener(ctx, logger, k8sClient, recorder, opts); err != nil {
 		// Check if error is due to context cancellation (graceful shutdown)
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			logger.Info("listener stopped gracefully")
@@ -151,9 +451,448 @@ func main() {
 	logger.Info("listener stopped")
 }
 
-func runListener(ctx context.Context, logger logr.Logger, k8sClient client.Client, forgejoServer, organization, labels, tokenSecretName, tokenSecretKey, namespace, actDeploymentName string, pollInterval time.Duration, skipTLSVerify bool) error {
+// lintActDeployment renders the runner pod the controller would create for a sample job of the ActDeployment in path,
+// writes it to out and the problems found in it to errOut
+// It returns an error if the file doesn't match the schema or the pod has problems
+func lintActDeployment(out, errOut io.Writer, path, runsOn string, defaults deploymentDefaults) error {
+	if path == "" {
+		return errors.New("-f is required")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read ActDeployment: %w", err)
+	}
+	actDeployment := &forgejoactionsiov1alpha1.ActDeployment{}
+	if err := yaml.UnmarshalStrict(data, actDeployment); err != nil {
+		return fmt.Errorf("failed to parse ActDeployment: %w", err)
+	}
+	if actDeployment.Kind != "" && actDeployment.Kind != "ActDeployment" {
+		return fmt.Errorf("%s is a %s, not an ActDeployment", path, actDeployment.Kind)
+	}
+	if actDeployment.Namespace == "" {
+		actDeployment.Namespace = "default"
+	}
+	defaults.apply(actDeployment)
+
+	runnerLabels, err := runnerlabels.FromSpec(&actDeployment.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to parse runner labels: %w", err)
+	}
+	if len(runnerLabels) == 0 {
+		return errors.New("the ActDeployment has no runner labels")
+	}
+	job := forgejo.Job{ID: 1, Name: "lint", RunsOn: []string{runnerLabels[0].Name}}
+	if runsOn != "" {
+		job.RunsOn = strings.Split(runsOn, ",")
+	}
+
+	actRunner := runnerspec.ForJob(actDeployment, job, runnerspec.Name(actDeployment.Spec.ForgejoServer, actDeployment.Spec.Organization, job.ID, job.Attempt),
+		actDeployment.Namespace, fmt.Sprintf("actrunner-reg-%d-lint", job.ID), runnerLabels)
+	actRunner.APIVersion = forgejoactionsiov1alpha1.GroupVersion.String()
+	actRunner.Kind = "ActRunner"
+	pod, _ := podbuilder.Build(actRunner, operatorconfig.Defaults())
+	pod.APIVersion = "v1"
+	pod.Kind = "Pod"
+	rendered, err := yaml.Marshal(pod)
+	if err != nil {
+		return fmt.Errorf("failed to render pod: %w", err)
+	}
+	if _, err := out.Write(rendered); err != nil {
+		return err
+	}
+
+	findings := podlint.Check(pod)
+	for _, violation := range podcompliance.Violations(pod, actDeployment.Spec.PodCompliance) {
+		findings = append(findings, "podCompliance: "+violation)
+	}
+	for _, finding := range findings {
+		fmt.Fprintln(errOut, finding)
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("%d problems found", len(findings))
+	}
+	return nil
+}
+
+// standaloneLabel marks the Jobs created in standalone mode
+const standaloneLabel = "forgejo.actions.io/standalone"
+
+// loadPodTemplate reads the pod template of standalone mode from a YAML or JSON file
+func loadPodTemplate(path string) (*corev1.PodTemplateSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pod template: %w", err)
+	}
+	template := &corev1.PodTemplateSpec{}
+	if err := yaml.UnmarshalStrict(data, template); err != nil {
+		return nil, fmt.Errorf("failed to parse pod template %s: %w", path, err)
+	}
+	if len(template.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("pod template %s has no containers; the first container runs forgejo-runner", path)
+	}
+	return template, nil
+}
+
+// runStandalone polls Forgejo and creates a batch/v1 Job from the pod template for each waiting job
+// It needs no CRDs: there is no ActDeployment or ActRunner, and the Jobs clean up after themselves
+func runStandalone(ctx context.Context, logger logr.Logger, k8sClient client.Client, opts options) error {
+	runnerLabels, err := runnerlabels.Parse(opts.Labels)
+	if err != nil {
+		return fmt.Errorf("failed to parse runner labels: %w", err)
+	}
+
+	token, err := loadTokenWithRetry(ctx, logger, k8sClient, opts.Namespace, opts.TokenSecretName, opts.TokenSecretKey)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return fmt.Errorf("failed to load token: %w", err)
+	}
+	forgejoClient, err := newForgejoClient(ctx, logger, k8sClient, token, opts)
+	if err != nil {
+		return err
+	}
+	forgejoClient.SetRateLimit(opts.APIQPS, opts.APIBurst)
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	logger.Info("starting standalone listener", "server", opts.ForgejoServer, "org", opts.Organization, "labels", opts.Labels, "interval", opts.PollInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("shutdown requested, stopping listener")
+			return nil
+		case <-ticker.C:
+			pollStart := time.Now()
+			err := pollAndCreateJobs(ctx, logger, k8sClient, forgejoClient, opts.podTemplate, opts.ForgejoServer, opts.Organization, opts.Namespace, runnerLabels, opts.maxRunners)
+			pollDuration.Observe(time.Since(pollStart).Seconds())
+			if err == nil {
+				pollsTotal.WithLabelValues("success").Inc()
+				continue
+			}
+			pollsTotal.WithLabelValues("error").Inc()
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.Error(err, "error polling or creating Jobs")
+			if errors.Is(err, forgejo.ErrUnauthorized) {
+				// The token may have been rotated; pick up the current one from the secret
+				if newToken, tokenErr := loadToken(ctx, k8sClient, opts.Namespace, opts.TokenSecretName, opts.TokenSecretKey); tokenErr == nil && newToken != token {
+					token = newToken
+					forgejoClient.SetToken(token)
+					logger.Info("reloaded token after unauthorized response", "secret", opts.TokenSecretName)
+				}
+			}
+		}
+	}
+}
+
+// pollAndCreateJobs creates a Job for each waiting job that doesn't have an unfinished Job yet
+func pollAndCreateJobs(ctx context.Context, logger logr.Logger, k8sClient client.Client, forgejoClient forgejo.API, template *corev1.PodTemplateSpec, forgejoServer, organization, namespace string, runnerLabels []forgejoactionsiov1alpha1.RunnerLabel, maxRunners int) error {
+	jobs, err := forgejoClient.GetPendingJobs(ctx, organization, runnerlabels.Names(runnerLabels))
+	if err != nil {
+		return fmt.Errorf("failed to get pending jobs: %w", err)
+	}
+	pendingJobs.Set(float64(len(jobs)))
+
+	if err := deleteOrphanedStandaloneSecrets(ctx, logger, k8sClient, namespace, time.Now()); err != nil {
+		logger.Error(err, "failed to delete orphaned registration token secrets")
+	}
+
+	existing := &batchv1.JobList{}
+	if err := k8sClient.List(ctx, existing, client.InNamespace(namespace), client.MatchingLabels{standaloneLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list Jobs: %w", err)
+	}
+	unfinished := map[string]bool{}
+	for _, k8sJob := range existing.Items {
+		if k8sJob.Status.Succeeded == 0 && k8sJob.Status.Failed == 0 {
+			unfinished[k8sJob.Labels["forgejo.actions.io/job-id"]] = true
+		}
+	}
+
+	for _, job := range jobs {
+		if unfinished[strconv.FormatInt(job.ID, 10)] {
+			continue
+		}
+		if maxRunners > 0 && len(unfinished) >= maxRunners {
+			logger.Info("max runners reached, deferring remaining jobs", "maxRunners", maxRunners)
+			return nil
+		}
+
+		registrationToken, err := forgejoClient.GetRegistrationToken(ctx, organization)
+		if err != nil {
+			logger.Error(err, "failed to get registration token", "jobID", job.ID)
+			continue
+		}
+
+		randomBytes := make([]byte, 4)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return fmt.Errorf("failed to generate random bytes for Job name: %w", err)
+		}
+		name := fmt.Sprintf("forgejo-runner-%d-%s", job.ID, hex.EncodeToString(randomBytes))
+
+		// The secret is created first, as the Job's pod can't start without it
+		// It is then owned by the Job, so both are removed once the Job's TTL expires
+		registrationSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"forgejo.actions.io/job-id":             strconv.FormatInt(job.ID, 10),
+					"forgejo.actions.io/registration-token": "true",
+					standaloneLabel:                         "true",
+				},
+			},
+			Data: map[string][]byte{"token": []byte(registrationToken)},
+		}
+		if err := k8sClient.Create(ctx, registrationSecret); err != nil {
+			return fmt.Errorf("failed to create registration token secret for job %d: %w", job.ID, err)
+		}
+
+		k8sJob := buildStandaloneJob(template, job, name, namespace, forgejoServer, organization, runnerlabels.ForJob(runnerLabels, job.RunsOn))
+		if err := k8sClient.Create(ctx, k8sJob); err != nil {
+			logger.Error(err, "failed to create Job", "jobID", job.ID)
+			if err := client.IgnoreNotFound(k8sClient.Delete(ctx, registrationSecret)); err != nil {
+				logger.Error(err, "failed to delete unused registration token secret", "jobID", job.ID, "secretName", name)
+			}
+			continue
+		}
+
+		patch := client.MergeFrom(registrationSecret.DeepCopy())
+		registrationSecret.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+			Kind:       "Job",
+			Name:       k8sJob.Name,
+			UID:        k8sJob.UID,
+		}}
+		if err := k8sClient.Patch(ctx, registrationSecret, patch); err != nil {
+			return fmt.Errorf("failed to hand registration token secret %s over to Job: %w", name, err)
+		}
+
+		unfinished[strconv.FormatInt(job.ID, 10)] = true
+		logger.Info("created Job", "jobID", job.ID, "job", k8sJob.Name)
+	}
+	return nil
+}
+
+// deleteOrphanedStandaloneSecrets deletes the registration token secrets of standalone runners that were never
+// handed over to their Job, e.g. because the listener crashed in between; secrets owned by a Job go with the Job
+func deleteOrphanedStandaloneSecrets(ctx context.Context, logger logr.Logger, k8sClient client.Client, namespace string, now time.Time) error {
+	secrets := &corev1.SecretList{}
+	if err := k8sClient.List(ctx, secrets, client.InNamespace(namespace),
+		client.MatchingLabels{"forgejo.actions.io/registration-token": "true", standaloneLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list registration token secrets: %w", err)
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if len(secret.OwnerReferences) > 0 || now.Sub(secret.CreationTimestamp.Time) < orphanedSecretMinAge {
+			continue
+		}
+		if err := client.IgnoreNotFound(k8sClient.Delete(ctx, secret)); err != nil {
+			return fmt.Errorf("failed to delete registration token secret %s: %w", secret.Name, err)
+		}
+		logger.Info("deleted orphaned registration token secret", "secretName", secret.Name, "jobID", secret.Labels["forgejo.actions.io/job-id"])
+	}
+	return nil
+}
+
+// buildStandaloneJob builds the Job running a single runner for the Forgejo job from the pod template
+// The first container of the template runs forgejo-runner and gets the registration env vars the runner image expects
+func buildStandaloneJob(template *corev1.PodTemplateSpec, job forgejo.Job, name, namespace, forgejoServer, organization, labels string) *batchv1.Job {
+	podTemplate := template.DeepCopy()
+	if podTemplate.Labels == nil {
+		podTemplate.Labels = map[string]string{}
+	}
+	podTemplate.Labels["forgejo.actions.io/job-id"] = strconv.FormatInt(job.ID, 10)
+	podTemplate.Labels[standaloneLabel] = "true"
+	if podTemplate.Spec.RestartPolicy == "" || podTemplate.Spec.RestartPolicy == corev1.RestartPolicyAlways {
+		podTemplate.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+
+	runnerContainer := &podTemplate.Spec.Containers[0]
+	runnerContainer.Env = append(runnerContainer.Env,
+		corev1.EnvVar{
+			Name: "TOKEN",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: name},
+					Key:                  "token",
+				},
+			},
+		},
+		corev1.EnvVar{Name: "FORGEJO_SERVER", Value: forgejoServer},
+		corev1.EnvVar{Name: "FORGEJO_ORG", Value: organization},
+		corev1.EnvVar{Name: "FORGEJO_LABELS", Value: labels},
+		corev1.EnvVar{Name: "FORGEJO_JOB_ID", Value: strconv.FormatInt(job.ID, 10)},
+	)
+
+	backoffLimit := int32(0)
+	ttl := int32(180)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"forgejo.actions.io/job-id": strconv.FormatInt(job.ID, 10),
+				standaloneLabel:             "true",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template:                *podTemplate,
+		},
+	}
+}
+
+// apiHeadersFromEnv reads the extra API headers set by the controller
+// EXTRA_API_HEADER_NAMES lists the header names, and EXTRA_API_HEADER_<i> holds the value of the i-th header
+func apiHeadersFromEnv() map[string]string {
+	names := os.Getenv("EXTRA_API_HEADER_NAMES")
+	if names == "" {
+		return nil
+	}
+
+	headers := map[string]string{}
+	for i, name := range strings.Split(names, ",") {
+		headers[name] = strings.TrimSpace(os.Getenv("EXTRA_API_HEADER_" + strconv.Itoa(i)))
+	}
+	return headers
+}
+
+// jobState is per-job bookkeeping the listener keeps between polls
+type jobState struct {
+	// reportedUnserved tracks jobs already reported as unserved, so each job gets a single event
+	reportedUnserved map[jobKey]bool
+
+	// reportedExhausted tracks jobs already reported as out of retries, so each job gets a single event
+	reportedExhausted map[jobKey]bool
+
+	// created counts the ActRunners created for each job
+	created map[jobKey]int32
+
+	// mismatches counts the ActRunners of each job that executed another job, which aren't attempts at the job
+	mismatches map[jobKey]int32
+
+	// ledger persists the counts across listener restarts; nil without an ActDeployment
+	ledger *jobledger.Ledger
+}
+
+// jobKey identifies one run attempt of a job; each re-run of a job is a new execution with its own runners
+type jobKey struct {
+	id      int64
+	attempt int64
+}
+
+func keyOf(job forgejo.Job) jobKey {
+	return jobKey{id: job.ID, attempt: job.Attempt}
+}
+
+func newJobState() *jobState {
+	return &jobState{
+		reportedUnserved:  map[jobKey]bool{},
+		reportedExhausted: map[jobKey]bool{},
+		created:           map[jobKey]int32{},
+		mismatches:        map[jobKey]int32{},
+	}
+}
+
+// remembers reports whether the state holds bookkeeping for the job
+func (s *jobState) remembers(key jobKey) bool {
+	_, attempted := s.created[key]
+	return attempted || s.reportedUnserved[key] || s.reportedExhausted[key]
+}
+
+// forgetFinishedJobs drops the bookkeeping of jobs that are no longer waiting
+// waiting holds the waiting jobs the state remembers, which keeps it small with long queues
+func (s *jobState) forgetFinishedJobs(waiting map[jobKey]bool) {
+	for key := range s.reportedUnserved {
+		if !waiting[key] {
+			delete(s.reportedUnserved, key)
+		}
+	}
+	for key := range s.reportedExhausted {
+		if !waiting[key] {
+			delete(s.reportedExhausted, key)
+		}
+	}
+	for key := range s.created {
+		if !waiting[key] {
+			delete(s.created, key)
+		}
+	}
+	for key := range s.mismatches {
+		if !waiting[key] {
+			delete(s.mismatches, key)
+		}
+	}
+}
+
+// deploymentDefaults are values the listener applies to the loaded ActDeployment when the spec leaves them empty
+// They come from the listener configuration, which the controller resolves (e.g., from a referenced ActOrg)
+type deploymentDefaults struct {
+	forgejoServer       string
+	organization        string
+	tokenSecretName     string
+	namespace           string
+	runnerImage         string
+	dockerInDockerImage string
+}
+
+// apply fills empty connection and image fields of the ActDeployment spec in memory
+func (d deploymentDefaults) apply(actDeployment *forgejoactionsiov1alpha1.ActDeployment) {
+	if actDeployment.Spec.ForgejoServer == "" || actDeployment.Spec.ActOrgRef != nil {
+		actDeployment.Spec.ForgejoServer = d.forgejoServer
+	}
+	if actDeployment.Spec.Organization == "" || actDeployment.Spec.ActOrgRef != nil {
+		actDeployment.Spec.Organization = d.organization
+	}
+	if actDeployment.Spec.TokenSecretRef.Name == "" || actDeployment.Spec.ActOrgRef != nil {
+		actDeployment.Spec.TokenSecretRef = corev1.SecretReference{Name: d.tokenSecretName, Namespace: d.namespace}
+	}
+	// A tracked runner image uses the digest the operator resolved, or the tag until it is resolved
+	if source := actDeployment.Spec.RunnerImageFrom; source != nil {
+		actDeployment.Spec.RunnerImage = source.Image
+		if actDeployment.Status.ResolvedRunnerImage != "" {
+			actDeployment.Spec.RunnerImage = actDeployment.Status.ResolvedRunnerImage
+		}
+	}
+	if actDeployment.Spec.RunnerImage == "" {
+		actDeployment.Spec.RunnerImage = d.runnerImage
+	}
+	if actDeployment.Spec.DockerInDockerImage == "" {
+		actDeployment.Spec.DockerInDockerImage = d.dockerInDockerImage
+	}
+}
+
+// options configure a listener loop: the Config the controller passes to embedded loops, and what only the
+// listener binary sets
+type options struct {
+	Config
+
+	connOptions  forgejo.ConnectionOptions
+	retryOptions forgejo.RetryOptions
+
+	// once runs a single poll cycle right away
+	once bool
+	// notifications trigger an additional poll right away; nil disables them
+	notifications <-chan *jobbus.Message
+	// claimer restricts runner creation to the jobs this cluster claimed; nil disables claims
+	claimer *jobclaim.Claimer
+
+	// podTemplate and maxRunners configure the Jobs of standalone mode
+	podTemplate *corev1.PodTemplateSpec
+	maxRunners  int
+}
+
+// runListener polls Forgejo every pollInterval and creates ActRunners for waiting jobs until the context is cancelled
+// With once set, it runs a single poll cycle right away and returns its error
+func runListener(ctx context.Context, logger logr.Logger, k8sClient client.Client, recorder record.EventRecorder, opts options) error {
 	// Load token from secret (with retries)
-	token, err := loadTokenWithRetry(ctx, logger, k8sClient, namespace, tokenSecretName, tokenSecretKey)
+	token, err := loadTokenWithRetry(ctx, logger, k8sClient, opts.Namespace, opts.TokenSecretName, opts.TokenSecretKey)
 	if err != nil {
 		// Don't wrap context cancellation errors
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
@@ -162,43 +901,401 @@ func runListener(ctx context.Context, logger logr.Logger, k8sClient client.Clien
 		return fmt.Errorf("failed to load token: %w", err)
 	}
 
-	// Create Forgejo client
-	forgejoClient := forgejo.NewClientWithTLS(forgejoServer, token, skipTLSVerify)
+	forgejoClient, err := newForgejoClient(ctx, logger, k8sClient, token, opts)
+	if err != nil {
+		return err
+	}
+	forgejoClient.SetRateLimit(opts.APIQPS, opts.APIBurst)
+
+	p := &poller{
+		logger:        logger,
+		k8sClient:     k8sClient,
+		forgejoClient: forgejoClient,
+		recorder:      recorder,
+		state:         newJobState(),
+		claimer:       opts.claimer,
+		config:        opts.Config,
+	}
+	if opts.ActDeploymentName != "" {
+		p.state.ledger = jobledger.New(k8sClient, opts.Namespace, jobledger.ConfigMapName(opts.ActDeploymentName), jobledger.DefaultSize)
+	}
+
+	// A listener killed while creating a runner leaves its registration token secret behind
+	if err := reconcileRegistrationSecrets(ctx, logger, k8sClient, opts.Namespace, time.Now()); err != nil {
+		logger.Error(err, "failed to delete orphaned registration token secrets")
+	}
+
+	if opts.once {
+		logger.Info("running a single poll", "server", opts.ForgejoServer, "org", opts.Organization, "labels", opts.Labels)
+		activeWindow, err := p.pollCycle(ctx)
+		if activeWindow != nil {
+			logger.Info("inside a maintenance window, no runners created", "schedule", activeWindow.Window.Schedule, "until", activeWindow.End)
+		}
+		return err
+	}
 
+	pollInterval := opts.PollInterval
 	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
-	logger.Info("starting listener", "server", forgejoServer, "org", organization, "labels", labels, "interval", pollInterval)
-	logger.Info("connected successfully", "server", forgejoServer, "org", organization)
+	logger.Info("starting listener", "server", opts.ForgejoServer, "org", opts.Organization, "labels", opts.Labels, "interval", pollInterval)
+	logger.Info("connected successfully", "server", opts.ForgejoServer, "org", opts.Organization)
+
+	// Tracks whether the previous poll was inside a maintenance window, to log transitions only once
+	inMaintenance := false
+
+	// Polls are skipped until this time after Forgejo rate limited the listener
+	var pausedUntil time.Time
+
+	// Reports the outcome of polls in the ActDeployment status
+	health := &pollHealth{}
+
+	// The heartbeat is renewed after every poll, so the controller can tell a wedged loop from a live one
+	identity, err := os.Hostname()
+	if err != nil {
+		identity = opts.ActDeploymentName
+	}
+	beater := heartbeat.New(k8sClient, opts.Namespace, opts.ActDeploymentName, identity, heartbeat.Duration(pollInterval))
+	beat := func() {
+		if err := beater.Beat(ctx); err != nil && ctx.Err() == nil {
+			logger.Error(err, "failed to renew heartbeat")
+		}
+	}
+	beat()
+
+	// poll runs a poll cycle unless polls are paused, and returns the error of the cycle
+	poll := func() error {
+		if time.Now().Before(pausedUntil) {
+			return nil
+		}
+
+		activeWindow, err := p.pollCycle(ctx)
+		if ctx.Err() == nil {
+			health.record(ctx, logger, k8sClient, opts.Namespace, opts.ActDeploymentName, err)
+		}
+		if activeWindow != nil {
+			if !inMaintenance {
+				logger.Info("maintenance window started, pausing runner creation", "schedule", activeWindow.Window.Schedule, "until", activeWindow.End)
+				inMaintenance = true
+			}
+			return nil
+		}
+		if inMaintenance {
+			logger.Info("maintenance window ended, resuming runner creation")
+			inMaintenance = false
+		}
+
+		if err != nil {
+			// Don't log errors if context was cancelled
+			if ctx.Err() != nil {
+				return err
+			}
+			logger.Error(err, "error polling or creating ActRunners")
+
+			var rateLimited *forgejo.ErrRateLimited
+			switch {
+			case errors.As(err, &rateLimited):
+				// Back off for the requested time instead of hammering the server every poll
+				pausedUntil = time.Now().Add(max(rateLimited.RetryAfter, pollInterval))
+				logger.Info("rate limited by Forgejo, pausing polls", "until", pausedUntil)
+			case errors.Is(err, forgejo.ErrUnauthorized):
+				// The token may have been rotated; pick up the current one from the secret
+				newToken, tokenErr := loadToken(ctx, k8sClient, opts.Namespace, opts.TokenSecretName, opts.TokenSecretKey)
+				if tokenErr != nil {
+					logger.Error(tokenErr, "failed to reload token after unauthorized response")
+				} else if newToken != token {
+					token = newToken
+					forgejoClient.SetToken(token)
+					logger.Info("reloaded token after unauthorized response", "secret", opts.TokenSecretName)
+				}
+			}
+		}
+		return err
+	}
 
+	notifications := opts.notifications
 	for {
 		select {
 		case <-ctx.Done():
 			logger.Info("shutdown requested, stopping listener")
+			recordShutdown(ctx, logger, k8sClient, opts.Namespace, opts.ActDeploymentName)
 			return nil
 		case <-ticker.C:
-			// Reload ActDeployment on each poll to pick up changes (e.g., runnerImage updates)
-			actDeployment, err := loadActDeployment(ctx, logger, k8sClient, namespace, actDeploymentName)
-			if err != nil {
-				logger.Error(err, "failed to load ActDeployment, skipping poll")
+			if err := poll(); err != nil && ctx.Err() != nil {
+				return nil
+			}
+			beat()
+		case message, ok := <-notifications:
+			if !ok {
+				notifications = nil
 				continue
 			}
+			// Coalesce a burst of notifications into a single poll
+			batch := []*jobbus.Message{message}
+			for drained := false; !drained; {
+				select {
+				case message, ok := <-notifications:
+					if ok {
+						batch = append(batch, message)
+					} else {
+						drained = true
+					}
+				default:
+					drained = true
+				}
+			}
+			logger.V(1).Info("job notification received, polling", "messages", len(batch), "payload", string(message.Data))
 
-			// Update existing ActRunner resources if ActDeployment spec has changed
-			if err := updateExistingActRunners(ctx, logger, k8sClient, namespace, actDeployment); err != nil {
-				logger.Error(err, "failed to update existing ActRunners")
-				// Continue anyway - we can still create new ones
+			pollErr := poll()
+			if pollErr != nil && ctx.Err() != nil {
+				return nil
+			}
+			// Notifications of failed polls are redelivered after the poll interval; everything else was handled by
+			// this poll or is picked up by the periodic poll once a pause or maintenance window ends
+			for _, message := range batch {
+				var err error
+				if pollErr != nil {
+					err = message.Nak(pollInterval)
+				} else {
+					err = message.Ack()
+				}
+				if err != nil {
+					logger.Error(err, "failed to acknowledge job notification")
+				}
 			}
+			ticker.Reset(pollInterval)
+			beat()
+		}
+	}
+}
+
+// pollStatusInterval is how often successful polls are written to the ActDeployment status
+// Failed polls and the first success after a failure are written right away
+const pollStatusInterval = time.Minute
+
+// maxPollErrorLength bounds the poll error stored in the status, e.g. for errors quoting a response body
+const maxPollErrorLength = 1024
+
+// pollHealth writes the outcome of polls into the ActDeployment status
+type pollHealth struct {
+	failing      bool
+	lastRecorded time.Time
+}
+
+// record stores the time of a successful poll, or the error and failure count of a failed one
+// The failure count is read from the status, so it survives listener restarts
+func (h *pollHealth) record(ctx context.Context, logger logr.Logger, k8sClient client.Client, namespace, actDeploymentName string, pollErr error) {
+	now := time.Now()
+	if pollErr == nil && !h.failing && now.Sub(h.lastRecorded) < pollStatusInterval {
+		return
+	}
+
+	actDeployment := &forgejoactionsiov1alpha1.ActDeployment{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: actDeploymentName}, actDeployment); err != nil {
+		logger.Error(err, "failed to record poll status")
+		return
+	}
+	original := actDeployment.DeepCopy()
+	if pollErr != nil {
+		message := pollErr.Error()
+		if len(message) > maxPollErrorLength {
+			message = message[:maxPollErrorLength]
+		}
+		actDeployment.Status.LastPollError = message
+		actDeployment.Status.ConsecutivePollFailures++
+	} else {
+		pollTime := metav1.NewTime(now)
+		actDeployment.Status.LastPollTime = &pollTime
+		actDeployment.Status.LastPollError = ""
+		actDeployment.Status.ConsecutivePollFailures = 0
+	}
+	if err := k8sutil.PatchStatus(ctx, k8sClient, actDeployment, original); err != nil {
+		logger.Error(err, "failed to record poll status")
+		return
+	}
+	h.failing = pollErr != nil
+	h.lastRecorded = now
+}
+
+// newClaimer creates the job claimer, using the cluster of the kubeconfig file if set
+func newClaimer(cfg *rest.Config, scheme *runtime.Scheme, kubeconfig, namespace, clusterName string, ttl time.Duration) (*jobclaim.Claimer, error) {
+	if clusterName == "" {
+		return nil, errors.New("--cluster-name is required with --claim-namespace")
+	}
+	if kubeconfig != "" {
+		var err error
+		if cfg, err = clientcmd.BuildConfigFromFlags("", kubeconfig); err != nil {
+			return nil, fmt.Errorf("failed to load claim kubeconfig: %w", err)
+		}
+	}
+	claimClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create claim client: %w", err)
+	}
+	return jobclaim.New(claimClient, namespace, clusterName, ttl), nil
+}
 
-			if err := pollAndCreateActRunners(ctx, logger, k8sClient, forgejoClient, organization, labels, namespace, actDeployment); err != nil {
-				// Don't log errors if context was cancelled
-				if ctx.Err() != nil {
-					return nil
+// orphanedSecretMinAge protects registration token secrets of runners that are being created right now, e.g. by
+// the listener of another ActDeployment in the namespace
+const orphanedSecretMinAge = 5 * time.Minute
+
+// reconcileRegistrationSecrets deletes registration token secrets that no ActRunner references, and hands the
+// others over to their ActRunners, e.g. secrets created before they had owners or by a listener that crashed
+// Secrets of standalone runners are owned by their Jobs and left to deleteOrphanedStandaloneSecrets
+func reconcileRegistrationSecrets(ctx context.Context, logger logr.Logger, k8sClient client.Client, namespace string, now time.Time) error {
+	secrets := &corev1.SecretList{}
+	if err := k8sClient.List(ctx, secrets, client.InNamespace(namespace), client.MatchingLabels{"forgejo.actions.io/registration-token": "true"}); err != nil {
+		return fmt.Errorf("failed to list registration token secrets: %w", err)
+	}
+	actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+	if err := k8sClient.List(ctx, actRunners, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list ActRunners: %w", err)
+	}
+	referenced := map[string]*forgejoactionsiov1alpha1.ActRunner{}
+	for i := range actRunners.Items {
+		referenced[actRunners.Items[i].Spec.RegistrationTokenSecretRef.Name] = &actRunners.Items[i]
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Labels[standaloneLabel] == "true" {
+			continue
+		}
+		if actRunner := referenced[secret.Name]; actRunner != nil {
+			if !metav1.IsControlledBy(secret, actRunner) {
+				if err := adoptRegistrationSecret(ctx, k8sClient, actRunner, secret); err != nil {
+					return err
 				}
-				logger.Error(err, "error polling or creating ActRunners")
+				logger.Info("adopted registration token secret", "secretName", secret.Name, "actRunner", actRunner.Name)
 			}
+			continue
+		}
+		// Secrets still owned by an ActDeployment may belong to an ActRunner that is being created right now
+		if now.Sub(secret.CreationTimestamp.Time) < orphanedSecretMinAge {
+			continue
+		}
+		if err := client.IgnoreNotFound(k8sClient.Delete(ctx, secret)); err != nil {
+			return fmt.Errorf("failed to delete registration token secret %s: %w", secret.Name, err)
+		}
+		logger.Info("deleted orphaned registration token secret", "secretName", secret.Name, "jobID", secret.Labels["forgejo.actions.io/job-id"])
+	}
+	return nil
+}
+
+// actDeploymentOwnerReference returns the owner reference of objects the listener creates for an ActDeployment
+// It is not a controller reference, so changes to them don't trigger ActDeployment reconciles
+func actDeploymentOwnerReference(actDeployment *forgejoactionsiov1alpha1.ActDeployment) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: forgejoactionsiov1alpha1.GroupVersion.String(),
+		Kind:       "ActDeployment",
+		Name:       actDeployment.Name,
+		UID:        actDeployment.UID,
+	}
+}
+
+// adoptRegistrationSecret makes the ActRunner the controller of its registration token secret, replacing any
+// other owners, so the secret is garbage collected along with the ActRunner
+func adoptRegistrationSecret(ctx context.Context, k8sClient client.Client, actRunner *forgejoactionsiov1alpha1.ActRunner, secret *corev1.Secret) error {
+	if metav1.IsControlledBy(secret, actRunner) {
+		return nil
+	}
+	patch := client.MergeFrom(secret.DeepCopy())
+	secret.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: forgejoactionsiov1alpha1.GroupVersion.String(),
+		Kind:       "ActRunner",
+		Name:       actRunner.Name,
+		UID:        actRunner.UID,
+		Controller: ptr.To(true),
+	}}
+	if err := k8sClient.Patch(ctx, secret, patch); err != nil {
+		return fmt.Errorf("failed to adopt registration token secret %s: %w", secret.Name, err)
+	}
+	return nil
+}
+
+// recordShutdown stores the time of a graceful shutdown in the ActDeployment status
+// The context is already cancelled on shutdown, so the patch gets its own deadline
+func recordShutdown(ctx context.Context, logger logr.Logger, k8sClient client.Client, namespace, actDeploymentName string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	actDeployment := &forgejoactionsiov1alpha1.ActDeployment{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: actDeploymentName}, actDeployment); err != nil {
+		logger.Error(err, "failed to record shutdown")
+		return
+	}
+	original := actDeployment.DeepCopy()
+	now := metav1.Now()
+	actDeployment.Status.LastShutdown = &now
+	if err := k8sutil.PatchStatus(ctx, k8sClient, actDeployment, original); err != nil {
+		logger.Error(err, "failed to record shutdown")
+	}
+}
+
+// newForgejoClient creates the Forgejo API client with the listener's TLS, header and connection settings
+func newForgejoClient(ctx context.Context, logger logr.Logger, k8sClient client.Client, token string, opts options) (*forgejo.Client, error) {
+	forgejoClient := forgejo.NewClientWithTLS(opts.ForgejoServer, token, opts.SkipTLSVerify)
+	if opts.ClientCertSecretName != "" {
+		certPEM, keyPEM, err := loadClientCertificate(ctx, k8sClient, opts.Namespace, opts.ClientCertSecretName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		if err := forgejoClient.SetClientCertificate(certPEM, keyPEM); err != nil {
+			return nil, err
 		}
+		logger.Info("using client certificate for Forgejo API", "secret", opts.ClientCertSecretName)
 	}
+	forgejoClient.SetHeaders(opts.APIHeaders)
+	forgejoClient.SetLogger(logger.WithName("forgejo"))
+	forgejoClient.SetConnectionOptions(opts.connOptions)
+	forgejoClient.SetRetryOptions(opts.retryOptions)
+	return forgejoClient, nil
+}
+
+// validateListener checks the listener configuration against Kubernetes and Forgejo and prints one line per check
+// It returns an error if any check failed
+func validateListener(ctx context.Context, logger logr.Logger, k8sClient client.Client, out io.Writer, opts options) error {
+	failed := 0
+	report := func(check string, err error, detail string) {
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL  %s: %v\n", check, err)
+			return
+		}
+		fmt.Fprintf(out, "ok    %s: %s\n", check, detail)
+	}
+
+	runnerLabels, err := runnerlabels.Parse(opts.Labels)
+	report("labels", err, fmt.Sprintf("%d labels (%s)", len(runnerLabels), runnerlabels.Names(runnerLabels)))
+
+	if opts.ActDeploymentName != "" {
+		_, err := loadActDeployment(ctx, logger, k8sClient, opts.Namespace, opts.ActDeploymentName)
+		report("ActDeployment", err, fmt.Sprintf("%s/%s exists", opts.Namespace, opts.ActDeploymentName))
+	}
+
+	token, err := loadToken(ctx, k8sClient, opts.Namespace, opts.TokenSecretName, opts.TokenSecretKey)
+	report("token", err, fmt.Sprintf("loaded from secret %s/%s", opts.Namespace, opts.TokenSecretName))
+	if err != nil {
+		return fmt.Errorf("%d checks failed, Forgejo checks skipped without a token", failed)
+	}
+
+	forgejoClient, err := newForgejoClient(ctx, logger, k8sClient, token, opts)
+	report("Forgejo client", err, opts.ForgejoServer)
+	if err != nil {
+		return fmt.Errorf("%d checks failed, Forgejo checks skipped without a client", failed)
+	}
+
+	jobs, err := forgejoClient.GetPendingJobs(ctx, opts.Organization, runnerlabels.Names(runnerLabels))
+	report("organization access", err, fmt.Sprintf("%d waiting jobs for %s", len(jobs), opts.Organization))
+
+	// The registration token itself is a secret and never printed
+	_, err = forgejoClient.GetRegistrationToken(ctx, opts.Organization)
+	report("registration token", err, "token may create runner registration tokens")
+
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
 }
 
 func loadTokenWithRetry(ctx context.Context, logger logr.Logger, k8sClient client.Client, namespace, secretName, key string) (string, error) {
@@ -293,6 +1390,7 @@ func updateExistingActRunners(ctx context.Context, logger logr.Logger, k8sClient
 		return fmt.Errorf("failed to list ActRunners: %w", err)
 	}
 
+	templateHash := rollout.TemplateHash(&actDeployment.Spec, actDeployment.Status.ResourceRecommendations)
 	updatedCount := 0
 	for i := range actRunners.Items {
 		ar := &actRunners.Items[i]
@@ -309,48 +1407,55 @@ func updateExistingActRunners(ctx context.Context, logger logr.Logger, k8sClient
 			continue
 		}
 
-		// Check if spec needs updating
-		needsUpdate := false
-		if ar.Spec.RunnerImage != actDeployment.Spec.RunnerImage {
-			ar.Spec.RunnerImage = actDeployment.Spec.RunnerImage
-			needsUpdate = true
-		}
-		if ar.Spec.DockerInDockerImage != actDeployment.Spec.DockerInDockerImage {
-			ar.Spec.DockerInDockerImage = actDeployment.Spec.DockerInDockerImage
-			needsUpdate = true
-		}
-		// Update DockerConfigMapRef if changed (compare pointers)
-		if (ar.Spec.DockerConfigMapRef == nil) != (actDeployment.Spec.DockerConfigMapRef == nil) ||
-			(ar.Spec.DockerConfigMapRef != nil && actDeployment.Spec.DockerConfigMapRef != nil &&
-				ar.Spec.DockerConfigMapRef.Name != actDeployment.Spec.DockerConfigMapRef.Name) {
-			ar.Spec.DockerConfigMapRef = actDeployment.Spec.DockerConfigMapRef
-			needsUpdate = true
-		}
+		// Rebuild the spec the runner would get for its job now, keeping what identifies the job and its registration
+		// The job data is carried over as a whole, so the job only needs its ID and labels here
+		job := forgejo.Job{ID: ar.Spec.ForgejoJobID, RunsOn: ar.Spec.JobData.RunsOn}
+		spec := runnerspec.ForJob(actDeployment, job, ar.Name, namespace, ar.Spec.RegistrationTokenSecretRef.Name, ar.Spec.RunnerLabels).Spec
+		spec.ForgejoServer = ar.Spec.ForgejoServer
+		spec.Organization = ar.Spec.Organization
+		spec.TokenSecretRef = ar.Spec.TokenSecretRef
+		spec.RegistrationTokenSecretRef = ar.Spec.RegistrationTokenSecretRef
+		spec.JobData = ar.Spec.JobData
 
 		// For Pending runners (no pod created yet), also update JobTemplate to ensure they get latest RunnerTemplate
 		// This ensures pending runners pick up any changes to RunnerTemplate (e.g., dnsPolicy, hostAliases, etc.)
+		// The template hash annotation tells whether the runner already reflects the current configuration,
+		// so unchanged runners aren't rewritten on every poll
 		isPending := ar.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhasePending || ar.Status.KubernetesJobName == ""
-		if isPending {
-			// Update JobTemplate from RunnerTemplate for pending runners
-			// This ensures they get the latest configuration even if other fields didn't change
-			ar.Spec.JobTemplate = *actDeployment.Spec.RunnerTemplate.DeepCopy()
-			needsUpdate = true
-		}
-
-		if needsUpdate {
-			// For Pending runners, update the spec and the controller will create pods with new config
-			// For Running/Completed runners, we skip updates (they should finish with current configuration)
-			if isPending {
-				logger.Info("updating ActRunner spec", "actRunner", ar.Name, "phase", ar.Status.Phase, "runnerImage", actDeployment.Spec.RunnerImage)
-				if err := k8sClient.Update(ctx, ar); err != nil {
-					logger.Error(err, "failed to update ActRunner", "actRunner", ar.Name)
-					continue
-				}
-				updatedCount++
-			} else {
-				logger.V(1).Info("skipping ActRunner update (pod already exists)", "actRunner", ar.Name, "phase", ar.Status.Phase, "pod", ar.Status.KubernetesJobName)
+		templateChanged := isPending && ar.Annotations[rollout.TemplateHashAnnotation] != templateHash
+		if !templateChanged {
+			spec.JobTemplate = ar.Spec.JobTemplate
+		}
+		// The backend can only change before the runner's workload exists
+		if ar.Status.KubernetesJobName != "" {
+			spec.Backend = ar.Spec.Backend
+			spec.VirtualMachine = ar.Spec.VirtualMachine
+		}
+
+		if !templateChanged && equality.Semantic.DeepEqual(ar.Spec, spec) {
+			continue
+		}
+		// For Running/Completed runners, we skip updates (they should finish with current configuration)
+		if !isPending {
+			logger.V(1).Info("skipping ActRunner update (pod already exists)", "actRunner", ar.Name, "phase", ar.Status.Phase, "pod", ar.Status.KubernetesJobName)
+			continue
+		}
+
+		// For Pending runners, update the spec and the controller will create pods with new config
+		ar.Spec = spec
+		if templateChanged {
+			// Record which configuration the spec now reflects, so the rollout can be tracked
+			if ar.Annotations == nil {
+				ar.Annotations = map[string]string{}
 			}
+			ar.Annotations[rollout.TemplateHashAnnotation] = templateHash
 		}
+		logger.Info("updating ActRunner spec", "actRunner", ar.Name, "phase", ar.Status.Phase, "runnerImage", actDeployment.Spec.RunnerImage)
+		if err := k8sClient.Update(ctx, ar); err != nil {
+			logger.Error(err, "failed to update ActRunner", "actRunner", ar.Name)
+			continue
+		}
+		updatedCount++
 	}
 
 	if updatedCount > 0 {
@@ -360,6 +1465,20 @@ func updateExistingActRunners(ctx context.Context, logger logr.Logger, k8sClient
 	return nil
 }
 
+// loadClientCertificate reads the certificate and key of a kubernetes.io/tls secret
+func loadClientCertificate(ctx context.Context, k8sClient client.Client, namespace, secretName string) ([]byte, []byte, error) {
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: secretName}, secret); err != nil {
+		return nil, nil, err
+	}
+
+	certPEM, keyPEM := secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return nil, nil, fmt.Errorf("secret %s/%s must contain %s and %s", namespace, secretName, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+	return certPEM, keyPEM, nil
+}
+
 func loadToken(ctx context.Context, k8sClient client.Client, namespace, secretName, key string) (string, error) {
 	secret := &corev1.Secret{}
 	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: secretName}, secret); err != nil {
@@ -377,251 +1496,3 @@ func loadToken(ctx context.Context, k8sClient client.Client, namespace, secretNa
 
 	return string(tokenBytes), nil
 }
-
-func pollAndCreateActRunners(ctx context.Context, logger logr.Logger, k8sClient client.Client, forgejoClient *forgejo.Client, organization, labels, namespace string, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
-	// Poll Forgejo for pending jobs
-	jobs, err := forgejoClient.GetPendingJobs(ctx, organization, labels)
-	if err != nil {
-		return fmt.Errorf("failed to get pending jobs: %w", err)
-	}
-
-	logger.V(1).Info("polled Forgejo", "jobCount", len(jobs))
-
-	// Get all existing ActRunners in the namespace to check limits
-	existingActRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
-	if err := k8sClient.List(ctx, existingActRunners, client.InNamespace(namespace)); err != nil {
-		logger.Error(err, "failed to list ActRunners")
-		return fmt.Errorf("failed to list ActRunners: %w", err)
-	}
-
-	// Count ActRunners owned by this ActDeployment
-	var actDeploymentOwnedRunners []forgejoactionsiov1alpha1.ActRunner
-	for _, ar := range existingActRunners.Items {
-		for _, ownerRef := range ar.OwnerReferences {
-			if ownerRef.Kind == "ActDeployment" && ownerRef.Name == actDeployment.Name && ownerRef.UID == actDeployment.UID {
-				actDeploymentOwnedRunners = append(actDeploymentOwnedRunners, ar)
-				break
-			}
-		}
-	}
-	currentRunnerCount := int32(len(actDeploymentOwnedRunners))
-
-	// Check MaxRunners limit
-	maxRunners := int32(0) // 0 means unlimited
-	if actDeployment.Spec.MaxRunners != nil && *actDeployment.Spec.MaxRunners > 0 {
-		maxRunners = *actDeployment.Spec.MaxRunners
-	}
-
-	for _, job := range jobs {
-		// Check if ActRunner for this job ID already exists
-		found := false
-		for _, ar := range actDeploymentOwnedRunners {
-			if ar.Spec.ForgejoJobID == job.ID {
-				logger.V(1).Info("ActRunner already exists for job", "jobID", job.ID, "actRunner", ar.Name)
-				found = true
-				break
-			}
-		}
-
-		if found {
-			// Found existing ActRunner, skip
-			continue
-		}
-
-		// Check MaxRunners limit before creating (re-check in case we've created runners in this loop)
-		if maxRunners > 0 && currentRunnerCount >= maxRunners {
-			logger.V(1).Info("maximum runner count reached, skipping remaining jobs", "currentCount", currentRunnerCount, "maxRunners", maxRunners)
-			break
-		}
-
-		// Log that we detected a pending job that needs a runner
-		logger.Info("detected pending job requiring runner", "jobID", job.ID, "jobName", job.Name, "repoID", job.RepoID)
-
-		// Fetch repository information (non-blocking - continue even if it fails)
-		var repo *forgejo.Repository
-		var run *forgejo.Run
-		repo, repoErr := forgejoClient.GetRepository(ctx, organization, job.RepoID)
-		if repoErr != nil {
-			logger.Error(repoErr, "failed to get repository", "jobID", job.ID, "repoID", job.RepoID)
-		} else {
-			// Parse repository full_name to get owner and repo name
-			// full_name format is "owner/repo"
-			owner := organization // Default to organization
-			repoName := repo.Name
-			if parts := strings.Split(repo.FullName, "/"); len(parts) == 2 {
-				owner = parts[0]
-				repoName = parts[1]
-			}
-
-			// Fetch run information (job ID should correspond to run ID)
-			var runErr error
-			run, runErr = forgejoClient.GetRun(ctx, owner, repoName, job.ID)
-			if runErr != nil {
-				logger.Error(runErr, "failed to get run details", "jobID", job.ID, "owner", owner, "repo", repoName)
-				// Continue anyway - we'll just have empty status fields
-			}
-		}
-
-		// Fetch registration token for this runner
-		registrationToken, err := forgejoClient.GetRegistrationToken(ctx, organization)
-		if err != nil {
-			logger.Error(err, "failed to get registration token", "jobID", job.ID)
-			continue
-		}
-
-		// Generate a unique secret name with random component
-		randomBytes := make([]byte, 4)
-		if _, err := rand.Read(randomBytes); err != nil {
-			logger.Error(err, "failed to generate random bytes for secret name", "jobID", job.ID)
-			continue
-		}
-		randomSuffix := hex.EncodeToString(randomBytes)
-		registrationSecretName := fmt.Sprintf("actrunner-reg-%d-%s", job.ID, randomSuffix)
-		if len(registrationSecretName) > 63 {
-			registrationSecretName = registrationSecretName[:63]
-		}
-
-		// Create or update the secret (handle already exists gracefully)
-		registrationSecret := &corev1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      registrationSecretName,
-				Namespace: namespace,
-				Labels: map[string]string{
-					"forgejo.actions.io/job-id":             fmt.Sprintf("%d", job.ID),
-					"forgejo.actions.io/registration-token": "true",
-				},
-			},
-			Data: map[string][]byte{
-				"token": []byte(registrationToken),
-			},
-		}
-
-		createErr := k8sClient.Create(ctx, registrationSecret)
-		if createErr != nil && apierrors.IsAlreadyExists(createErr) {
-			// Secret already exists, update it with new token
-			existingSecret := &corev1.Secret{}
-			if getErr := k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: registrationSecretName}, existingSecret); getErr != nil {
-				logger.Error(getErr, "failed to get existing registration token secret", "jobID", job.ID, "secretName", registrationSecretName)
-				continue
-			}
-			existingSecret.Data = registrationSecret.Data
-			if updateErr := k8sClient.Update(ctx, existingSecret); updateErr != nil {
-				logger.Error(updateErr, "failed to update registration token secret", "jobID", job.ID, "secretName", registrationSecretName)
-				continue
-			}
-			logger.Info("updated existing registration token secret", "jobID", job.ID, "secretName", registrationSecretName)
-		} else if createErr != nil {
-			logger.Error(createErr, "failed to create registration token secret", "jobID", job.ID)
-			continue
-		} else {
-			logger.Info("created registration token secret", "jobID", job.ID, "secretName", registrationSecretName)
-		}
-
-		// Get proper API version and kind for OwnerReference
-		apiVersion := actDeployment.APIVersion
-		if apiVersion == "" {
-			apiVersion = forgejoactionsiov1alpha1.GroupVersion.String()
-		}
-		kind := actDeployment.Kind
-		if kind == "" {
-			kind = "ActDeployment"
-		}
-
-		// Ensure JobTemplate has at least one container
-		jobTemplate := actDeployment.Spec.RunnerTemplate.DeepCopy()
-		if len(jobTemplate.Spec.Containers) == 0 {
-			jobTemplate.Spec.Containers = []corev1.Container{
-				{
-					Name:  "runner",
-					Image: "runner-image:latest", // Should be set by user in RunnerTemplate
-				},
-			}
-		}
-
-		// Create new ActRunner
-		actRunner := &forgejoactionsiov1alpha1.ActRunner{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      fmt.Sprintf("actrunner-%d-%s", job.ID, generateShortHash(job.ID)),
-				Namespace: namespace,
-				Labels: map[string]string{
-					"forgejo.actions.io/job-id": fmt.Sprintf("%d", job.ID),
-				},
-				OwnerReferences: []metav1.OwnerReference{
-					{
-						APIVersion: apiVersion,
-						Kind:       kind,
-						Name:       actDeployment.Name,
-						UID:        actDeployment.UID,
-						Controller: func() *bool { b := true; return &b }(),
-					},
-				},
-			},
-			Spec: forgejoactionsiov1alpha1.ActRunnerSpec{
-				ForgejoJobID:   job.ID,
-				ForgejoServer:  actDeployment.Spec.ForgejoServer,
-				Organization:   actDeployment.Spec.Organization,
-				TokenSecretRef: actDeployment.Spec.TokenSecretRef,
-				RegistrationTokenSecretRef: corev1.SecretReference{
-					Name:      registrationSecretName,
-					Namespace: namespace,
-				},
-				RunnerImage:         actDeployment.Spec.RunnerImage,
-				DockerInDockerImage: actDeployment.Spec.DockerInDockerImage,
-				DockerConfigMapRef:  actDeployment.Spec.DockerConfigMapRef,
-				JobData: forgejoactionsiov1alpha1.JobData{
-					ID:      job.ID,
-					RepoID:  job.RepoID,
-					OwnerID: job.OwnerID,
-					Name:    job.Name,
-					Needs:   job.Needs,
-					RunsOn:  job.RunsOn,
-					TaskID:  job.TaskID,
-					Status:  job.Status,
-				},
-				JobTemplate: *jobTemplate,
-			},
-			Status: forgejoactionsiov1alpha1.ActRunnerStatus{
-				Phase: forgejoactionsiov1alpha1.ActRunnerPhasePending,
-			},
-		}
-
-		// Set repository and run information in status if available
-		if repo != nil {
-			actRunner.Status.RepositoryFullName = repo.FullName
-		}
-		if run != nil {
-			actRunner.Status.TriggerUser = run.TriggerUser.Login
-			actRunner.Status.PrettyRef = run.PrettyRef
-			actRunner.Status.TriggerEvent = run.TriggerEvent
-		}
-
-		if err := k8sClient.Create(ctx, actRunner); err != nil {
-			logger.Error(err, "failed to create ActRunner", "jobID", job.ID)
-			continue
-		}
-
-		// Update status with repository and run information
-		if repo != nil || run != nil {
-			if err := k8sClient.Status().Update(ctx, actRunner); err != nil {
-				logger.Error(err, "failed to update ActRunner status", "jobID", job.ID)
-				// Continue - this is not critical
-			}
-		}
-
-		logger.Info("created ActRunner", "jobID", job.ID, "actRunner", actRunner.Name, "currentRunnerCount", currentRunnerCount+1, "maxRunners", maxRunners)
-
-		// Increment count for next iteration
-		currentRunnerCount++
-	}
-
-	return nil
-}
-
-func generateShortHash(id int64) string {
-	// Simple hash function to generate a short identifier
-	hash := id % 10000
-	if hash < 0 {
-		hash = -hash
-	}
-	return fmt.Sprintf("%04d", hash)
-}
@@ -0,0 +1,71 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+var _ = Describe("Listener metrics", func() {
+	const (
+		organization = "metrics-org"
+		namespace    = "runners"
+	)
+
+	It("reports the waiting jobs and counts the ActRunners created for them", func() {
+		server := fake.NewServer()
+		defer server.Close()
+		server.AddJob(organization, forgejo.Job{ID: 1, RunsOn: []string{"docker"}})
+		server.AddJob(organization, forgejo.Job{ID: 2, RunsOn: []string{"docker"}})
+
+		c := clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithStatusSubresource(&forgejoactionsiov1alpha1.ActRunner{}).Build()
+		actDeployment := &forgejoactionsiov1alpha1.ActDeployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: forgejoactionsiov1alpha1.GroupVersion.String(), Kind: "ActDeployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "metrics", Namespace: namespace, UID: "metrics-uid"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:       server.URL(),
+				Organization:        organization,
+				Labels:              "docker",
+				RunnerImage:         "runner:test",
+				DockerInDockerImage: "dind:test",
+			},
+		}
+		config := listener.Config{Organization: organization, Labels: "docker", Namespace: namespace}
+		poller := listener.NewPoller(GinkgoLogr, c, forgejo.NewClient(server.URL(), "token"), record.NewFakeRecorder(10), listener.NewJobState(), config)
+
+		created := testutil.ToFloat64(listener.ActRunnersCreated)
+		Expect(poller.PollAndCreateActRunners(context.Background(), actDeployment)).To(Succeed())
+		Expect(testutil.ToFloat64(listener.PendingJobs)).To(Equal(2.0))
+		Expect(testutil.ToFloat64(listener.ActRunnersCreated)).To(Equal(created + 2))
+
+		By("polling jobs that already have ActRunners")
+		Expect(poller.PollAndCreateActRunners(context.Background(), actDeployment)).To(Succeed())
+		Expect(testutil.ToFloat64(listener.ActRunnersCreated)).To(Equal(created + 2))
+	})
+})
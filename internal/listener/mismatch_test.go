@@ -0,0 +1,167 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+var _ = Describe("Job mismatches", func() {
+	const (
+		organization = "mismatch-org"
+		namespace    = "runners"
+		jobID        = int64(7)
+	)
+
+	var (
+		ctx           context.Context
+		server        *fake.Server
+		forgejoClient *forgejo.Client
+		c             client.Client
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = fake.NewServer()
+		server.AddJob(organization, forgejo.Job{ID: jobID, RunsOn: []string{"docker"}})
+		forgejoClient = forgejo.NewClient(server.URL(), "token")
+		c = clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithStatusSubresource(&forgejoactionsiov1alpha1.ActRunner{}).Build()
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "mismatch", Namespace: namespace, UID: "mismatch-uid"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:       server.URL(),
+				Organization:        organization,
+				Labels:              "docker",
+				TokenSecretRef:      corev1.SecretReference{Name: "forgejo-token"},
+				RunnerImage:         "runner:test",
+				DockerInDockerImage: "dind:test",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// pollWith runs a poll of a freshly started listener, so only the job ledger carries counts between polls
+	pollWith := func(ledger bool) []forgejoactionsiov1alpha1.ActRunner {
+		state := listener.NewJobState()
+		if ledger {
+			state = listener.NewJobStateWithLedger(c, namespace, actDeployment.Name)
+		}
+		Expect(listener.NewPoller(GinkgoLogr, c, forgejoClient, record.NewFakeRecorder(10), state, listener.Config{Organization: organization, Labels: "docker", Namespace: namespace}).PollAndCreateActRunners(ctx, actDeployment)).To(Succeed())
+		actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+		Expect(c.List(ctx, actRunners, client.InNamespace(namespace))).To(Succeed())
+		return actRunners.Items
+	}
+	poll := func() []forgejoactionsiov1alpha1.ActRunner { return pollWith(true) }
+
+	// executeOtherJob finishes the runner as if it had picked up another job
+	executeOtherJob := func(ar *forgejoactionsiov1alpha1.ActRunner) {
+		ar.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded
+		ar.Status.RunnerContainer = &forgejoactionsiov1alpha1.ContainerTermination{Message: "executed task 99 repo is other/app"}
+		Expect(c.Status().Update(ctx, ar)).To(Succeed())
+	}
+
+	It("creates a runner for a job after two consecutive runners executed other jobs", func() {
+		actRunners := poll()
+		Expect(actRunners).To(HaveLen(1))
+		first := actRunners[0]
+		executeOtherJob(&first)
+
+		actRunners = poll()
+		Expect(actRunners).To(HaveLen(2))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(&first), &first)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(first.Status.Conditions, forgejoactionsiov1alpha1.ConditionJobMismatch)).To(BeTrue())
+
+		// Finished runners are deleted after a while; the ledger still remembers them
+		Expect(c.Delete(ctx, &first)).To(Succeed())
+		actRunners = poll()
+		Expect(actRunners).To(HaveLen(1))
+		second := actRunners[0]
+		executeOtherJob(&second)
+
+		actRunners = poll()
+		Expect(actRunners).To(HaveLen(2))
+		for _, ar := range actRunners {
+			Expect(ar.Spec.ForgejoJobID).To(Equal(jobID))
+		}
+	})
+
+	It("counts idle-timed-out runners as attempts at their job", func() {
+		idleOut := func(ar *forgejoactionsiov1alpha1.ActRunner) {
+			ar.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded
+			ar.Status.RunnerContainer = &forgejoactionsiov1alpha1.ContainerTermination{ExitCode: forgejoactionsiov1alpha1.IdleTimeoutExitCode}
+			Expect(c.Status().Update(ctx, ar)).To(Succeed())
+		}
+
+		actRunners := poll()
+		Expect(actRunners).To(HaveLen(1))
+		idleOut(&actRunners[0])
+
+		// The default retry policy allows one replacement
+		actRunners = poll()
+		Expect(actRunners).To(HaveLen(2))
+		for i := range actRunners {
+			Expect(meta.IsStatusConditionTrue(actRunners[i].Status.Conditions, forgejoactionsiov1alpha1.ConditionJobMismatch)).To(BeFalse())
+			if actRunners[i].Status.Phase != forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded {
+				idleOut(&actRunners[i])
+			}
+		}
+
+		Expect(poll()).To(HaveLen(2))
+		Expect(poll()).To(HaveLen(2))
+	})
+
+	It("names a replacement runner after the runners that already exist when their count was lost", func() {
+		actRunners := pollWith(false)
+		Expect(actRunners).To(HaveLen(1))
+		first := actRunners[0]
+		executeOtherJob(&first)
+
+		actRunners = pollWith(false)
+		Expect(actRunners).To(HaveLen(2))
+		Expect(c.Delete(ctx, &first)).To(Succeed())
+		second := actRunners[1]
+		if second.Name == first.Name {
+			second = actRunners[0]
+		}
+		Expect(second.Name).To(Equal(first.Name + "-1"))
+		executeOtherJob(&second)
+
+		// Only the second runner is left, so the count suggests the name it took
+		actRunners = pollWith(false)
+		Expect(actRunners).To(HaveLen(2))
+		names := []string{actRunners[0].Name, actRunners[1].Name}
+		Expect(names).To(ConsistOf(first.Name+"-1", first.Name+"-2"))
+	})
+})
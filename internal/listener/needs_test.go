@@ -0,0 +1,51 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+var _ = Describe("Job dependencies", func() {
+	// A run whose jobs have custom display names, as reported by servers with job keys
+	runJobs := []forgejo.Job{
+		{ID: 1, JobID: "build", Name: "Build (linux)", Status: "success"},
+		{ID: 2, JobID: "lint", Name: "Lint sources", Status: "running"},
+		{ID: 3, JobID: "integration", Name: "Integration tests", Status: "waiting"},
+		{ID: 4, JobID: "docs", Name: "docs", Status: "skipped"},
+	}
+
+	DescribeTable("report the prerequisites that have not finished",
+		func(needs []string, runJobs []forgejo.Job, pending []string) {
+			job := forgejo.Job{ID: 10, JobID: "deploy", Name: "Deploy", Needs: needs}
+			Expect(listener.UnfinishedNeeds(job, runJobs)).To(Equal(pending))
+		},
+		Entry("no needs", nil, runJobs, nil),
+		Entry("finished prerequisites with custom names", []string{"build", "docs"}, runJobs, nil),
+		Entry("unfinished prerequisites with custom names", []string{"build", "lint", "integration"}, runJobs, []string{"lint", "integration"}),
+		Entry("a prerequisite missing from the run", []string{"build", "release"}, runJobs, nil),
+		Entry("a display name instead of the key", []string{"Lint sources"}, runJobs, nil),
+		Entry("servers without job keys, by name",
+			[]string{"build", "test"},
+			[]forgejo.Job{{ID: 1, Name: "build", Status: "failure"}, {ID: 2, Name: "test", Status: "blocked"}},
+			[]string{"test"}),
+	)
+})
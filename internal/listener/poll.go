@@ -0,0 +1,866 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/jobclaim"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/jobqueue"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/k8sutil"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/maintenance"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/resourceprofile"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerlabels"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerphase"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerspec"
+)
+
+// poller runs the poll cycles of a listener loop
+type poller struct {
+	logger        logr.Logger
+	k8sClient     client.Client
+	forgejoClient forgejo.API
+	recorder      record.EventRecorder
+
+	// state is the per-job bookkeeping that has to survive between polls
+	state *jobState
+
+	// claimer restricts runner creation to the jobs this cluster claimed; nil disables claims
+	claimer *jobclaim.Claimer
+
+	config Config
+}
+
+// pollCycle runs one poll: it reloads the ActDeployment, updates existing ActRunners and creates ActRunners for waiting jobs
+// During a maintenance window no runners are created, and the active window is returned
+func (p *poller) pollCycle(ctx context.Context) (*maintenance.ActiveWindow, error) {
+	// Reload ActDeployment on each poll to pick up changes (e.g., runnerImage updates)
+	actDeployment, err := loadActDeployment(ctx, p.logger, p.k8sClient, p.config.Namespace, p.config.ActDeploymentName)
+	if err != nil {
+		return nil, err
+	}
+	p.config.defaults().apply(actDeployment)
+
+	// Update existing ActRunner resources if ActDeployment spec has changed
+	if err := updateExistingActRunners(ctx, p.logger, p.k8sClient, p.config.Namespace, actDeployment); err != nil {
+		p.logger.Error(err, "failed to update existing ActRunners")
+		// Continue anyway - we can still create new ones
+	}
+
+	// Registration tokens are only kept while a runner may still need them
+	if err := expireRegistrationSecrets(ctx, p.logger, p.k8sClient, p.forgejoClient, p.config.Organization, p.config.Namespace, actDeployment, time.Now()); err != nil {
+		p.logger.Error(err, "failed to expire registration token secrets")
+	}
+
+	// Don't create new runners during a maintenance window - existing runners are left to drain
+	activeWindow, windowErr := maintenance.Active(actDeployment.Spec.MaintenanceWindows, time.Now())
+	if windowErr != nil {
+		p.logger.Error(windowErr, "invalid maintenance window, ignoring it")
+	}
+	if activeWindow != nil {
+		return activeWindow, nil
+	}
+
+	pollStart := time.Now()
+	err = p.pollAndCreateActRunners(ctx, actDeployment)
+	pollDuration.Observe(time.Since(pollStart).Seconds())
+	if p.claimer != nil {
+		if err := p.claimer.Cleanup(ctx); err != nil {
+			p.logger.Error(err, "failed to clean up expired job claims")
+		}
+	}
+	if err != nil {
+		pollsTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	pollsTotal.WithLabelValues("success").Inc()
+	return nil, nil
+}
+
+// pollRound is what a poll knows about the ActDeployment and its runners while it walks the waiting jobs
+type pollRound struct {
+	actDeployment *forgejoactionsiov1alpha1.ActDeployment
+	runnerLabels  []forgejoactionsiov1alpha1.RunnerLabel
+
+	// jobRunners are the ActRunners of the ActDeployment's Forgejo server and organization in the namespace
+	// ActDeployments with overlapping labels see the same jobs, so a job's runners are looked up among all of them
+	jobRunners []forgejoactionsiov1alpha1.ActRunner
+
+	// busyConcurrencyGroups holds the runs with an unfinished runner of each concurrency group
+	busyConcurrencyGroups map[concurrencyGroup]map[int64]bool
+
+	// runnerCount is the number of ActRunners of the ActDeployment, including those created by this poll
+	runnerCount int32
+	// maxRunners limits runnerCount, 0 means unlimited
+	maxRunners int32
+	maxRetries int32
+
+	summary *pollSummary
+}
+
+// concurrencyGroup identifies a workflow concurrency group, which is scoped to its repository
+type concurrencyGroup struct {
+	repository string
+	group      string
+}
+
+// hold records that the run has an unfinished runner in the group
+func (r *pollRound) hold(group concurrencyGroup, runID int64) {
+	if r.busyConcurrencyGroups[group] == nil {
+		r.busyConcurrencyGroups[group] = map[int64]bool{}
+	}
+	r.busyConcurrencyGroups[group][runID] = true
+}
+
+// heldByOtherRun reports whether another run than runID has an unfinished runner in the group
+func (r *pollRound) heldByOtherRun(group concurrencyGroup, runID int64) bool {
+	for holder := range r.busyConcurrencyGroups[group] {
+		if holder != runID || runID == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pollAndCreateActRunners creates an ActRunner for each waiting job that needs one
+func (p *poller) pollAndCreateActRunners(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment) error {
+	// Prefer the ActDeployment's labels over the flag, so label changes apply without a listener restart
+	runnerLabels, err := runnerlabels.FromSpec(&actDeployment.Spec)
+	if err != nil || len(runnerLabels) == 0 {
+		if runnerLabels, err = runnerlabels.Parse(p.config.Labels); err != nil {
+			return fmt.Errorf("failed to parse runner labels: %w", err)
+		}
+	}
+
+	queue, waiting, err := p.waitingJobs(ctx, actDeployment, runnerLabels)
+	if err != nil {
+		return err
+	}
+	jobs := queue.Jobs()
+	pendingJobs.Set(float64(queue.Total()))
+
+	// Per-job details are logged at V(1); each poll ends with one summary line
+	summary := &pollSummary{seen: queue.Total(), selected: len(jobs)}
+	defer summary.log(p.logger)
+
+	round, err := p.startRound(ctx, actDeployment, runnerLabels, waiting, summary)
+	if err != nil {
+		return err
+	}
+
+	for i, job := range jobs {
+		if !p.provisionJob(ctx, round, job) {
+			summary.skippedMaxRunners = len(jobs) - i
+			break
+		}
+	}
+
+	if p.state.ledger != nil {
+		owner := []metav1.OwnerReference{actDeploymentOwnerReference(actDeployment)}
+		if err := p.state.ledger.Save(ctx, owner); err != nil {
+			p.logger.Error(err, "failed to save job ledger")
+		}
+	}
+	return nil
+}
+
+// waitingJobs polls Forgejo for the jobs waiting for the runner labels and returns the queue of jobs selected for
+// this poll, along with the keys of all waiting jobs
+// Only the jobs that make the cut are kept, so a queue of thousands doesn't grow the listener's memory
+func (p *poller) waitingJobs(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, runnerLabels []forgejoactionsiov1alpha1.RunnerLabel) (*jobqueue.Queue, map[jobKey]bool, error) {
+	queue := jobqueue.New(0, jobqueue.Oldest)
+	if spec := actDeployment.Spec.JobQueue; spec != nil {
+		queue = jobqueue.New(int(spec.MaxJobsPerPoll), jobqueue.Order(spec.Order))
+	}
+	remembered := map[jobKey]bool{}
+	waiting := map[jobKey]bool{}
+	err := p.forgejoClient.EachPendingJob(ctx, p.config.Organization, runnerlabels.Names(runnerLabels), func(job forgejo.Job) error {
+		queue.Add(job)
+		waiting[keyOf(job)] = true
+		if p.state.remembers(keyOf(job)) {
+			remembered[keyOf(job)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get pending jobs: %w", err)
+	}
+
+	p.state.forgetFinishedJobs(remembered)
+	return queue, waiting, nil
+}
+
+// startRound lists the existing ActRunners and settles what earlier runners tell about the waiting jobs
+func (p *poller) startRound(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, runnerLabels []forgejoactionsiov1alpha1.RunnerLabel, waiting map[jobKey]bool, summary *pollSummary) (*pollRound, error) {
+	existingActRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+	if err := p.k8sClient.List(ctx, existingActRunners, client.InNamespace(p.config.Namespace)); err != nil {
+		p.logger.Error(err, "failed to list ActRunners")
+		return nil, fmt.Errorf("failed to list ActRunners: %w", err)
+	}
+
+	round := &pollRound{
+		actDeployment:         actDeployment,
+		runnerLabels:          runnerLabels,
+		busyConcurrencyGroups: map[concurrencyGroup]map[int64]bool{},
+		maxRetries:            1,
+		summary:               summary,
+	}
+
+	// Count ActRunners owned by this ActDeployment
+	var ownedRunners []forgejoactionsiov1alpha1.ActRunner
+	for _, ar := range existingActRunners.Items {
+		for _, ownerRef := range ar.OwnerReferences {
+			if ownerRef.Kind == "ActDeployment" && ownerRef.Name == actDeployment.Name && ownerRef.UID == actDeployment.UID {
+				ownedRunners = append(ownedRunners, ar)
+				break
+			}
+		}
+	}
+	round.runnerCount = int32(len(ownedRunners))
+
+	// Correlate newly registered runners with Forgejo's runner list
+	recordRunnerIDs(ctx, p.logger, p.k8sClient, p.forgejoClient, p.config.Organization, ownedRunners)
+
+	// Runners that idled out stay registered as offline runners until removed
+	deregisterIdleRunners(ctx, p.logger, p.k8sClient, p.forgejoClient, p.config.Organization, ownedRunners)
+
+	// The ledger remembers the runners of jobs whose ActRunners were deleted before a listener restart
+	if p.state.ledger != nil {
+		if err := p.state.ledger.Load(ctx); err != nil {
+			p.logger.Error(err, "failed to load job ledger")
+		}
+	}
+
+	// Runners that executed another job than their own don't count as attempts at their job, which gets a new runner
+	markMismatchedRunners(ctx, p.logger, p.k8sClient, p.recorder, p.state, actDeployment, ownedRunners, waiting)
+
+	for _, ar := range existingActRunners.Items {
+		if sameForgejoServer(ar.Spec.ForgejoServer, actDeployment.Spec.ForgejoServer) && ar.Spec.Organization == actDeployment.Spec.Organization {
+			round.jobRunners = append(round.jobRunners, ar)
+		}
+	}
+
+	if actDeployment.Spec.MaxRunners != nil && *actDeployment.Spec.MaxRunners > 0 {
+		round.maxRunners = *actDeployment.Spec.MaxRunners
+	}
+
+	// Forgejo blocks further runs of a group until the in-flight one finishes, so creating
+	// runners for them early just leaves registered runners idling
+	for _, ar := range existingActRunners.Items {
+		if ar.Status.ConcurrencyGroup != "" && !runnerphase.Finished(ar.Status.Phase) {
+			round.hold(concurrencyGroup{repository: ar.Status.RepositoryFullName, group: ar.Status.ConcurrencyGroup}, ar.Status.RunID)
+		}
+	}
+
+	if actDeployment.Spec.RetryPolicy != nil && actDeployment.Spec.RetryPolicy.MaxRetries != nil {
+		round.maxRetries = *actDeployment.Spec.RetryPolicy.MaxRetries
+	}
+	return round, nil
+}
+
+// provisionJob creates a runner for the job unless it already has one, is out of retries or has to wait
+// It returns false once the ActDeployment reached its maximum runner count, which ends the poll
+func (p *poller) provisionJob(ctx context.Context, round *pollRound, job forgejo.Job) bool {
+	actDeployment, summary := round.actDeployment, round.summary
+
+	found, created, attempts := p.jobRunnerCounts(round, job)
+	if !found && attempts > round.maxRetries {
+		summary.skippedRetries++
+		if !p.state.reportedExhausted[keyOf(job)] {
+			p.state.reportedExhausted[keyOf(job)] = true
+			p.logger.V(1).Info("job is still waiting but its runners are out of retries", "jobID", job.ID, "attempts", attempts)
+			p.recorder.Eventf(actDeployment, corev1.EventTypeWarning, "RunnerRetriesExhausted",
+				"job %d (%s) is still waiting after %d runners finished without picking it up", job.ID, job.Name, attempts)
+		}
+		return true
+	}
+
+	if found {
+		summary.skippedExisting++
+		// Keep the job's claim while the runner has not picked it up yet
+		if p.claimer != nil {
+			if _, _, err := p.claimer.Claim(ctx, actDeployment.Spec.ForgejoServer, p.config.Organization, job.ID); err != nil {
+				p.logger.Error(err, "failed to renew job claim", "jobID", job.ID)
+			}
+		}
+		return true
+	}
+
+	if !p.servesJob(round, job) {
+		summary.skippedLabels++
+		return true
+	}
+
+	// Re-checked for every job, as this poll creates runners as well
+	if round.maxRunners > 0 && round.runnerCount >= round.maxRunners {
+		p.logger.V(1).Info("maximum runner count reached, skipping remaining jobs", "currentCount", round.runnerCount, "maxRunners", round.maxRunners)
+		return false
+	}
+
+	p.logger.V(1).Info("detected pending job requiring runner", "jobID", job.ID, "jobName", job.Name, "repoID", job.RepoID)
+
+	repo, run, ready := p.jobDetails(ctx, job)
+	if !ready {
+		summary.deferred++
+		return true
+	}
+
+	// Serialize runner creation within a workflow concurrency group
+	// With cancel-in-progress the new run supersedes the old one, so it gets a runner right away
+	// The other jobs of the run holding the group run alongside it
+	if run != nil && run.ConcurrencyGroup != "" && !run.ConcurrencyCancel && round.heldByOtherRun(concurrencyGroup{repository: repo.FullName, group: run.ConcurrencyGroup}, run.ID) {
+		p.logger.V(1).Info("deferring runner creation, concurrency group has an unfinished runner", "jobID", job.ID, "concurrencyGroup", run.ConcurrencyGroup)
+		summary.deferred++
+		return true
+	}
+
+	// With several clusters serving the organization, only the cluster holding the job's claim provisions it
+	if p.claimer != nil {
+		claimed, holder, err := p.claimer.Claim(ctx, actDeployment.Spec.ForgejoServer, p.config.Organization, job.ID)
+		if err != nil {
+			p.logger.Error(err, "failed to claim job", "jobID", job.ID)
+			summary.errors++
+			return true
+		}
+		if !claimed {
+			p.logger.V(1).Info("job is claimed by another cluster, skipping", "jobID", job.ID, "holder", holder)
+			summary.skippedClaimed++
+			return true
+		}
+	}
+
+	p.createActRunner(ctx, round, job, created, attempts, repo, run)
+	return true
+}
+
+// jobRunnerCounts reports whether the job has an unfinished runner, how many runners it had so far and how many
+// of them were attempts at the job
+// Finished runners mean the runner died (or took another job) before picking the job up, since a picked-up job is
+// no longer waiting
+func (p *poller) jobRunnerCounts(round *pollRound, job forgejo.Job) (bool, int32, int32) {
+	created, mismatches := int32(0), int32(0)
+	for _, ar := range round.jobRunners {
+		// Runners of earlier attempts finished with their run, so a re-run starts without attempts
+		if ar.Spec.ForgejoJobID != job.ID || ar.Spec.JobData.Attempt != job.Attempt {
+			continue
+		}
+		created++
+		if meta.IsStatusConditionTrue(ar.Status.Conditions, forgejoactionsiov1alpha1.ConditionJobMismatch) {
+			mismatches++
+			continue
+		}
+		if !runnerphase.Finished(ar.Status.Phase) {
+			if owner := metav1.GetControllerOf(&ar); owner != nil && owner.UID != round.actDeployment.UID {
+				p.logger.V(1).Info("job already has an ActRunner of another ActDeployment", "jobID", job.ID, "actRunner", ar.Name, "actDeployment", owner.Name)
+			} else {
+				p.logger.V(1).Info("ActRunner already exists for job", "jobID", job.ID, "actRunner", ar.Name)
+			}
+			return true, created, 0
+		}
+	}
+
+	// Finished ActRunners are deleted after a while, so also count the runners remembered from earlier polls
+	// and, with a ledger, from before a listener restart
+	key := keyOf(job)
+	created = max(created, p.state.created[key])
+	mismatches = max(mismatches, p.state.mismatches[key])
+	if p.state.ledger != nil {
+		created = max(created, p.state.ledger.Runners(job.ID, job.Attempt))
+		mismatches = max(mismatches, p.state.ledger.Mismatches(job.ID, job.Attempt))
+	}
+	// Runners that executed another job weren't attempts at this one
+	return false, created, max(created-mismatches, 0)
+}
+
+// servesJob reports whether the ActDeployment's runners can take the job
+// The API filter matches any of the labels; a runner can only take the job if it has all of them
+// Jobs are reported once, so a job stuck waiting for a label nobody serves is diagnosable
+func (p *poller) servesJob(round *pollRound, job forgejo.Job) bool {
+	// Runners with registration labels register with exactly those, so they have to cover the job as well
+	// Resource profile labels are served along with the runner labels
+	servingLabels := append(slices.Clone(round.runnerLabels), resourceprofile.SelectorLabels(round.actDeployment.Spec.ResourceProfiles)...)
+	if registration := round.actDeployment.Spec.RegistrationLabels; len(registration) > 0 && runnerlabels.Matches(servingLabels, job.RunsOn) {
+		servingLabels = registration
+	}
+	if runnerlabels.Matches(servingLabels, job.RunsOn) {
+		return true
+	}
+
+	p.logger.V(1).Info("job runs-on labels not all provided by this ActDeployment, skipping", "jobID", job.ID, "runsOn", job.RunsOn)
+	if !p.state.reportedUnserved[keyOf(job)] {
+		p.state.reportedUnserved[keyOf(job)] = true
+		p.recorder.Eventf(round.actDeployment, corev1.EventTypeWarning, "UnservedJobLabels",
+			"job %d (%s) requests labels %v that this ActDeployment does not serve: %s",
+			job.ID, job.Name, job.RunsOn, strings.Join(runnerlabels.Missing(servingLabels, job.RunsOn), ","))
+	}
+	return false
+}
+
+// jobDetails fetches the repository and run of the job, which are nil if Forgejo can't provide them
+// It reports false while prerequisite jobs (needs) haven't finished, since the runner would register and sit idle
+// until they complete
+func (p *poller) jobDetails(ctx context.Context, job forgejo.Job) (*forgejo.Repository, *forgejo.Run, bool) {
+	repo, err := p.forgejoClient.GetRepository(ctx, p.config.Organization, job.RepoID)
+	if err != nil {
+		p.logger.Error(err, "failed to get repository", "jobID", job.ID, "repoID", job.RepoID)
+		return nil, nil, true
+	}
+
+	// full_name format is "owner/repo"
+	owner := p.config.Organization
+	repoName := repo.Name
+	if parts := strings.Split(repo.FullName, "/"); len(parts) == 2 {
+		owner = parts[0]
+		repoName = parts[1]
+	}
+
+	// Fetch run information (job ID should correspond to run ID)
+	// Without it the runner's status fields stay empty
+	run, err := p.forgejoClient.GetRun(ctx, owner, repoName, job.ID)
+	if err != nil {
+		p.logger.Error(err, "failed to get run details", "jobID", job.ID, "owner", owner, "repo", repoName)
+	}
+
+	if len(job.Needs) == 0 {
+		return repo, run, true
+	}
+	runID := job.ID
+	if run != nil && run.ID != 0 {
+		runID = run.ID
+	}
+	runJobs, err := p.forgejoClient.GetRunJobs(ctx, owner, repoName, runID)
+	if err != nil {
+		// Fall back to creating the runner - an idle runner is better than a stranded job
+		p.logger.Error(err, "failed to get run jobs, not checking job dependencies", "jobID", job.ID, "runID", runID)
+		return repo, run, true
+	}
+	if pending := unfinishedNeeds(job, runJobs); len(pending) > 0 {
+		p.logger.V(1).Info("deferring runner creation until job dependencies finish", "jobID", job.ID, "jobName", job.Name, "pendingNeeds", pending)
+		return repo, run, false
+	}
+	return repo, run, true
+}
+
+// createActRunner creates the registration token secret and the ActRunner of the job
+// created is the number of runners the job had so far, which names the new one
+func (p *poller) createActRunner(ctx context.Context, round *pollRound, job forgejo.Job, created, attempts int32, repo *forgejo.Repository, run *forgejo.Run) {
+	actDeployment, summary := round.actDeployment, round.summary
+
+	registrationSecret, err := p.createRegistrationSecret(ctx, actDeployment, job)
+	if err != nil {
+		p.logger.Error(err, "failed to create registration token secret", "jobID", job.ID)
+		summary.errors++
+		return
+	}
+
+	// The name is derived from the job, so when several ActDeployments race for it only one create succeeds
+	// Replacement runners get the number of runners created before as suffix, so they don't collide with
+	// finished ones, including those that executed another job
+	// The count only grows, but can lag behind the existing runners when the ledger was lost, so taken names are skipped
+	baseName := runnerspec.Name(actDeployment.Spec.ForgejoServer, actDeployment.Spec.Organization, job.ID, job.Attempt)
+	actRunnerName := replacementName(baseName, created)
+	for suffix := created + 1; slices.ContainsFunc(round.jobRunners, func(ar forgejoactionsiov1alpha1.ActRunner) bool { return ar.Name == actRunnerName }); suffix++ {
+		actRunnerName = replacementName(baseName, suffix)
+	}
+	if actRunnerName != baseName {
+		p.logger.V(1).Info("previous runner finished without picking up the job, creating a replacement", "jobID", job.ID, "attempt", attempts+1, "actRunner", actRunnerName)
+	}
+
+	actRunner := runnerspec.ForJob(actDeployment, job, actRunnerName, p.config.Namespace, registrationSecret.Name, round.runnerLabels)
+
+	// Set repository and run information in status if available
+	if repo != nil {
+		actRunner.Status.RepositoryFullName = repo.FullName
+	}
+	if run != nil {
+		actRunner.Status.TriggerUser = run.TriggerUser.Login
+		actRunner.Status.PrettyRef = run.PrettyRef
+		actRunner.Status.TriggerEvent = run.TriggerEvent
+		actRunner.Status.ConcurrencyGroup = run.ConcurrencyGroup
+		actRunner.Status.RunID = run.ID
+	}
+
+	// Create drops the status, so it is written separately afterwards
+	status := actRunner.Status
+	if err := p.k8sClient.Create(ctx, actRunner); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			// The name wasn't among the listed runners, so it was created since, by another ActDeployment racing for the job
+			p.logger.V(1).Info("another ActDeployment created the runner for this job first", "jobID", job.ID, "actRunner", actRunnerName)
+			summary.skippedExisting++
+		} else {
+			p.logger.Error(err, "failed to create ActRunner", "jobID", job.ID)
+			summary.errors++
+		}
+		// Nothing references the registration token now
+		// The create may have failed because the listener is shutting down, so the delete doesn't use ctx
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		if err := client.IgnoreNotFound(p.k8sClient.Delete(cleanupCtx, registrationSecret)); err != nil {
+			p.logger.Error(err, "failed to delete unused registration token secret", "jobID", job.ID, "secretName", registrationSecret.Name)
+		}
+		cancel()
+		return
+	}
+	p.state.created[keyOf(job)] = created + 1
+	if p.state.ledger != nil {
+		p.state.ledger.Record(job.ID, job.Attempt, created+1)
+	}
+	actRunnersCreated.Inc()
+
+	// The secret now lives as long as the ActRunner; a failed handover is retried on the next listener start
+	if err := adoptRegistrationSecret(ctx, p.k8sClient, actRunner, registrationSecret); err != nil {
+		p.logger.Error(err, "failed to hand the registration token secret over to the ActRunner", "jobID", job.ID, "secretName", registrationSecret.Name)
+	}
+
+	// Update status with repository and run information
+	if repo != nil || run != nil {
+		original := actRunner.DeepCopy()
+		actRunner.Status = status
+		if err := k8sutil.PatchStatus(ctx, p.k8sClient, actRunner, original); err != nil {
+			p.logger.Error(err, "failed to update ActRunner status", "jobID", job.ID)
+			// Continue - this is not critical
+		}
+	}
+
+	if run != nil && run.ConcurrencyGroup != "" {
+		round.hold(concurrencyGroup{repository: repo.FullName, group: run.ConcurrencyGroup}, run.ID)
+	}
+
+	p.logger.V(1).Info("created ActRunner", "jobID", job.ID, "actRunner", actRunner.Name, "currentRunnerCount", round.runnerCount+1, "maxRunners", round.maxRunners)
+	summary.created++
+	round.runnerCount++
+}
+
+// createRegistrationSecret fetches a registration token for a runner of the job and stores it in a new secret
+// It is owned by the ActDeployment until the ActRunner exists, so a listener crash in between doesn't leak it
+func (p *poller) createRegistrationSecret(ctx context.Context, actDeployment *forgejoactionsiov1alpha1.ActDeployment, job forgejo.Job) (*corev1.Secret, error) {
+	registrationToken, err := p.forgejoClient.GetRegistrationToken(ctx, p.config.Organization)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registration token: %w", err)
+	}
+
+	// Generate a unique secret name with random component
+	randomBytes := make([]byte, 4)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes for secret name: %w", err)
+	}
+	name := fmt.Sprintf("actrunner-reg-%d-%s", job.ID, hex.EncodeToString(randomBytes))
+	if len(name) > 63 {
+		name = name[:63]
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: p.config.Namespace,
+			Labels: map[string]string{
+				"forgejo.actions.io/job-id":             fmt.Sprintf("%d", job.ID),
+				"forgejo.actions.io/registration-token": "true",
+			},
+			Annotations:     map[string]string{registrationSecretExpiresAnnotation: registrationSecretExpiry(actDeployment, time.Now())},
+			OwnerReferences: []metav1.OwnerReference{actDeploymentOwnerReference(actDeployment)},
+		},
+		Data: map[string][]byte{
+			"token": []byte(registrationToken),
+		},
+	}
+
+	createErr := p.k8sClient.Create(ctx, secret)
+	if createErr == nil {
+		p.logger.V(1).Info("created registration token secret", "jobID", job.ID, "secretName", name)
+		return secret, nil
+	}
+	if !apierrors.IsAlreadyExists(createErr) {
+		return nil, createErr
+	}
+
+	// Secret already exists, update it with new token
+	existing := &corev1.Secret{}
+	if err := p.k8sClient.Get(ctx, types.NamespacedName{Namespace: p.config.Namespace, Name: name}, existing); err != nil {
+		return nil, fmt.Errorf("failed to get existing registration token secret %s: %w", name, err)
+	}
+	existing.Data = secret.Data
+	existing.OwnerReferences = secret.OwnerReferences
+	if existing.Annotations == nil {
+		existing.Annotations = map[string]string{}
+	}
+	existing.Annotations[registrationSecretExpiresAnnotation] = secret.Annotations[registrationSecretExpiresAnnotation]
+	if err := p.k8sClient.Update(ctx, existing); err != nil {
+		return nil, fmt.Errorf("failed to update registration token secret %s: %w", name, err)
+	}
+	p.logger.V(1).Info("updated existing registration token secret", "jobID", job.ID, "secretName", name)
+	return existing, nil
+}
+
+// pollSummary counts what a poll did with the waiting jobs
+type pollSummary struct {
+	seen              int
+	selected          int
+	created           int
+	skippedExisting   int
+	skippedMaxRunners int
+	skippedRetries    int
+	skippedLabels     int
+	skippedClaimed    int
+	deferred          int
+	errors            int
+}
+
+// log writes the summary at Info, or at V(1) for an idle poll, so an idle listener stays quiet
+func (s *pollSummary) log(logger logr.Logger) {
+	if s.seen == 0 && s.errors == 0 {
+		logger = logger.V(1)
+	}
+	logger.Info("poll summary", "jobsSeen", s.seen, "jobsSelected", s.selected, "created", s.created,
+		"skippedExisting", s.skippedExisting, "skippedMaxRunners", s.skippedMaxRunners, "skippedRetries", s.skippedRetries,
+		"skippedLabels", s.skippedLabels, "skippedClaimed", s.skippedClaimed, "deferred", s.deferred, "errors", s.errors)
+}
+
+// recordRunnerIDs stores the Forgejo runner IDs of running ActRunners that don't have one yet
+// Runners are matched by the name they registered with; the next poll retries runners that are not listed yet
+func recordRunnerIDs(ctx context.Context, logger logr.Logger, k8sClient client.Client, forgejoClient forgejo.API, organization string, actRunners []forgejoactionsiov1alpha1.ActRunner) {
+	var unresolved []*forgejoactionsiov1alpha1.ActRunner
+	for i := range actRunners {
+		ar := &actRunners[i]
+		if ar.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhaseRunning && ar.Status.RunnerName != "" && ar.Status.RunnerID == 0 {
+			unresolved = append(unresolved, ar)
+		}
+	}
+	if len(unresolved) == 0 {
+		return
+	}
+
+	runners, err := forgejoClient.ListRunners(ctx, organization)
+	if errors.Is(err, forgejo.ErrNotFound) {
+		// Older Forgejo versions have no runner list API
+		logger.V(1).Info("Forgejo does not list runners, not recording runner IDs")
+		return
+	}
+	if err != nil {
+		logger.Error(err, "failed to list Forgejo runners, not recording runner IDs")
+		return
+	}
+	// A replaced workload registers again under the same name; the newest registration has the highest ID
+	ids := map[string]int64{}
+	for _, runner := range runners {
+		ids[runner.Name] = max(ids[runner.Name], runner.ID)
+	}
+
+	for _, ar := range unresolved {
+		id := ids[ar.Status.RunnerName]
+		if id == 0 {
+			continue
+		}
+		original := ar.DeepCopy()
+		ar.Status.RunnerID = id
+		if err := k8sutil.PatchStatus(ctx, k8sClient, ar, original); err != nil {
+			logger.Error(err, "failed to record runner ID", "actRunner", ar.Name)
+			continue
+		}
+		logger.V(1).Info("recorded Forgejo runner ID", "actRunner", ar.Name, "runnerName", ar.Status.RunnerName, "runnerID", id)
+	}
+}
+
+// idledOut reports whether the runner stopped because it received no task within its idle timeout
+func idledOut(ar *forgejoactionsiov1alpha1.ActRunner) bool {
+	return meta.IsStatusConditionTrue(ar.Status.Conditions, forgejoactionsiov1alpha1.ConditionIdleTimedOut) ||
+		(ar.Status.RunnerContainer != nil && ar.Status.RunnerContainer.ExitCode == forgejoactionsiov1alpha1.IdleTimeoutExitCode)
+}
+
+// deregisterIdleRunners removes the runners of idle-timed-out ActRunners from Forgejo and sets their Deregistered condition
+// Runners without a recorded ID are looked up by the name they registered with; failures are retried on the next poll
+func deregisterIdleRunners(ctx context.Context, logger logr.Logger, k8sClient client.Client, forgejoClient forgejo.API, organization string, actRunners []forgejoactionsiov1alpha1.ActRunner) {
+	var idle []*forgejoactionsiov1alpha1.ActRunner
+	lookup := false
+	for i := range actRunners {
+		ar := &actRunners[i]
+		if !idledOut(ar) || meta.IsStatusConditionTrue(ar.Status.Conditions, forgejoactionsiov1alpha1.ConditionDeregistered) {
+			continue
+		}
+		if ar.Status.RunnerID == 0 && ar.Status.RunnerName == "" {
+			continue
+		}
+		idle = append(idle, ar)
+		lookup = lookup || ar.Status.RunnerID == 0
+	}
+	if len(idle) == 0 {
+		return
+	}
+
+	ids := map[string]int64{}
+	if lookup {
+		runners, err := forgejoClient.ListRunners(ctx, organization)
+		if errors.Is(err, forgejo.ErrNotFound) {
+			// Older Forgejo versions have no runner API
+			logger.V(1).Info("Forgejo does not list runners, not deregistering idle runners")
+			return
+		}
+		if err != nil {
+			logger.Error(err, "failed to list Forgejo runners, not deregistering idle runners")
+			return
+		}
+		for _, runner := range runners {
+			ids[runner.Name] = max(ids[runner.Name], runner.ID)
+		}
+	}
+
+	for _, ar := range idle {
+		id := ar.Status.RunnerID
+		if id == 0 {
+			id = ids[ar.Status.RunnerName]
+		}
+		reason, message := "RunnerDeleted", fmt.Sprintf("runner %d was removed from Forgejo", id)
+		if id == 0 {
+			reason, message = "RunnerNotFound", fmt.Sprintf("no runner named %s is registered with Forgejo", ar.Status.RunnerName)
+		} else if err := forgejoClient.DeleteRunner(ctx, organization, id); errors.Is(err, forgejo.ErrNotFound) {
+			reason, message = "RunnerNotFound", fmt.Sprintf("runner %d is no longer registered with Forgejo", id)
+		} else if err != nil {
+			logger.Error(err, "failed to deregister idle runner", "actRunner", ar.Name, "runnerID", id)
+			continue
+		}
+
+		original := ar.DeepCopy()
+		meta.SetStatusCondition(&ar.Status.Conditions, metav1.Condition{
+			Type:               forgejoactionsiov1alpha1.ConditionDeregistered,
+			Status:             metav1.ConditionTrue,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: ar.Generation,
+		})
+		if err := k8sutil.PatchStatus(ctx, k8sClient, ar, original); err != nil {
+			logger.Error(err, "failed to record runner deregistration", "actRunner", ar.Name)
+			continue
+		}
+		logger.Info("deregistered idle runner", "actRunner", ar.Name, "runnerName", ar.Status.RunnerName, "runnerID", id)
+	}
+}
+
+// replacementName returns the name of a job's runner after the given number of earlier runners
+func replacementName(baseName string, created int32) string {
+	if created == 0 {
+		return baseName
+	}
+	return fmt.Sprintf("%s-%d", baseName, created)
+}
+
+// executedTaskPattern matches the task the runner image reports in its termination message
+var executedTaskPattern = regexp.MustCompile(`task (\d+) repo is (\S+)`)
+
+// markMismatchedRunners flags finished runners whose job is still waiting and that reported executing another task
+// Runners that reported no task, e.g. because they idled out, were attempts at their own job
+func markMismatchedRunners(ctx context.Context, logger logr.Logger, k8sClient client.Client, recorder record.EventRecorder, state *jobState, actDeployment *forgejoactionsiov1alpha1.ActDeployment, actRunners []forgejoactionsiov1alpha1.ActRunner, waiting map[jobKey]bool) {
+	for i := range actRunners {
+		ar := &actRunners[i]
+		key := jobKey{id: ar.Spec.ForgejoJobID, attempt: ar.Spec.JobData.Attempt}
+		if !waiting[key] || !runnerphase.Finished(ar.Status.Phase) || meta.IsStatusConditionTrue(ar.Status.Conditions, forgejoactionsiov1alpha1.ConditionJobMismatch) {
+			continue
+		}
+		// An idle-timed-out runner exits cleanly without a task, which is an attempt that counts towards the retries
+		if idledOut(ar) {
+			continue
+		}
+		var taskID int64
+		var repository string
+		if ar.Status.RunnerContainer != nil {
+			if match := executedTaskPattern.FindStringSubmatch(ar.Status.RunnerContainer.Message); match != nil {
+				taskID, _ = strconv.ParseInt(match[1], 10, 64)
+				repository = match[2]
+			}
+		}
+		if taskID == 0 || taskID == ar.Spec.ForgejoJobID {
+			continue
+		}
+
+		message := fmt.Sprintf("job %d is still waiting, the runner executed task %d of %s", ar.Spec.ForgejoJobID, taskID, repository)
+		original := ar.DeepCopy()
+		ar.Status.ExecutedTaskID = taskID
+		ar.Status.ExecutedRepository = repository
+		meta.SetStatusCondition(&ar.Status.Conditions, metav1.Condition{
+			Type:               forgejoactionsiov1alpha1.ConditionJobMismatch,
+			Status:             metav1.ConditionTrue,
+			Reason:             "DifferentJobExecuted",
+			Message:            message,
+			ObservedGeneration: ar.Generation,
+		})
+		if err := k8sutil.PatchStatus(ctx, k8sClient, ar, original); err != nil {
+			logger.Error(err, "failed to record job mismatch", "actRunner", ar.Name)
+			continue
+		}
+		logger.Info("runner executed another job than its own", "actRunner", ar.Name, "jobID", ar.Spec.ForgejoJobID, "taskID", taskID, "repository", repository)
+		recorder.Eventf(actDeployment, corev1.EventTypeWarning, "WrongJobPickedUp", "runner %s: %s", ar.Name, message)
+		// The runner doesn't count as an attempt at its job, which stays waiting
+		// The count is persisted, since the ledger keeps the runner in the job's created runners
+		mismatches := state.mismatches[key] + 1
+		if state.ledger != nil {
+			mismatches = max(mismatches, state.ledger.Mismatches(key.id, key.attempt)+1)
+			state.ledger.RecordMismatches(key.id, key.attempt, mismatches)
+		}
+		state.mismatches[key] = mismatches
+	}
+}
+
+// unfinishedNeeds returns the keys of the job's prerequisite jobs that have not finished yet
+// A need is satisfied once the prerequisite job reached a terminal status; Forgejo itself decides
+// whether the dependent job runs or is skipped after a failed prerequisite
+// Needs name the job keys of the workflow; servers that don't report the key only match jobs without a custom name
+func unfinishedNeeds(job forgejo.Job, runJobs []forgejo.Job) []string {
+	statusByKey := make(map[string]string, len(runJobs))
+	for _, runJob := range runJobs {
+		key := runJob.JobID
+		if key == "" {
+			key = runJob.Name
+		}
+		statusByKey[key] = runJob.Status
+	}
+
+	var pending []string
+	for _, need := range job.Needs {
+		status, ok := statusByKey[need]
+		if !ok {
+			// Unknown prerequisite (e.g., job list truncated) - don't block on it
+			continue
+		}
+		if !forgejo.IsJobFinished(status) {
+			pending = append(pending, need)
+		}
+	}
+	return pending
+}
+
+// sameForgejoServer reports whether two server URLs are the same, ignoring a trailing slash
+func sameForgejoServer(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
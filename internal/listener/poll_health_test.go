@@ -0,0 +1,107 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+var _ = Describe("Poll health", func() {
+	const (
+		namespace = "runners"
+		name      = "health"
+	)
+
+	var (
+		ctx     context.Context
+		c       client.Client
+		patches int
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		patches = 0
+		c = clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithStatusSubresource(&forgejoactionsiov1alpha1.ActDeployment{}).WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				patches++
+				return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+		Expect(c.Create(ctx, &forgejoactionsiov1alpha1.ActDeployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}})).To(Succeed())
+	})
+
+	getStatus := func() forgejoactionsiov1alpha1.ActDeploymentStatus {
+		actDeployment := &forgejoactionsiov1alpha1.ActDeployment{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, actDeployment)).To(Succeed())
+		return actDeployment.Status
+	}
+
+	It("counts consecutive failures and resets them on the next success", func() {
+		health := listener.NewPollHealth()
+		health.Record(ctx, GinkgoLogr, c, namespace, name, errors.New("connection refused"))
+		health.Record(ctx, GinkgoLogr, c, namespace, name, errors.New("bad gateway"))
+
+		status := getStatus()
+		Expect(status.ConsecutivePollFailures).To(Equal(int32(2)))
+		Expect(status.LastPollError).To(Equal("bad gateway"))
+		Expect(status.LastPollTime).To(BeNil())
+
+		health.Record(ctx, GinkgoLogr, c, namespace, name, nil)
+		status = getStatus()
+		Expect(status.ConsecutivePollFailures).To(BeZero())
+		Expect(status.LastPollError).To(BeEmpty())
+		Expect(status.LastPollTime).NotTo(BeNil())
+	})
+
+	It("continues counting the failures of a previous listener", func() {
+		listener.NewPollHealth().Record(ctx, GinkgoLogr, c, namespace, name, errors.New("connection refused"))
+		listener.NewPollHealth().Record(ctx, GinkgoLogr, c, namespace, name, errors.New("connection refused"))
+
+		Expect(getStatus().ConsecutivePollFailures).To(Equal(int32(2)))
+	})
+
+	It("writes successful polls at most once a minute, but failures and recoveries right away", func() {
+		health := listener.NewPollHealth()
+		health.Record(ctx, GinkgoLogr, c, namespace, name, nil)
+		health.Record(ctx, GinkgoLogr, c, namespace, name, nil)
+		Expect(patches).To(Equal(1))
+
+		health.Record(ctx, GinkgoLogr, c, namespace, name, errors.New("connection refused"))
+		health.Record(ctx, GinkgoLogr, c, namespace, name, nil)
+		Expect(patches).To(Equal(3))
+		Expect(getStatus().ConsecutivePollFailures).To(BeZero())
+	})
+
+	It("truncates long poll errors", func() {
+		listener.NewPollHealth().Record(ctx, GinkgoLogr, c, namespace, name, errors.New(strings.Repeat("x", 4096)))
+
+		Expect(getStatus().LastPollError).To(HaveLen(1024))
+	})
+})
@@ -0,0 +1,174 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+)
+
+// registrationSecretExpiresAnnotation holds the RFC 3339 time at which a registration token secret expires
+const registrationSecretExpiresAnnotation = "forgejo.actions.io/expires-at"
+
+// defaultRegistrationTokenTTL is how long registration token secrets are kept if the ActDeployment doesn't say
+const defaultRegistrationTokenTTL = time.Hour
+
+// registrationSecretExpiry returns the expiry annotation of a registration token secret created at now
+func registrationSecretExpiry(actDeployment *forgejoactionsiov1alpha1.ActDeployment, now time.Time) string {
+	ttl := defaultRegistrationTokenTTL
+	if actDeployment.Spec.RegistrationTokenTTL != nil && actDeployment.Spec.RegistrationTokenTTL.Duration > 0 {
+		ttl = actDeployment.Spec.RegistrationTokenTTL.Duration
+	}
+	return now.Add(ttl).UTC().Format(time.RFC3339)
+}
+
+// registrationSecretExpired reports whether the registration token secret expired
+// Secrets without a valid expiry, e.g. created by an older listener, expire the default TTL after their creation
+func registrationSecretExpired(secret *corev1.Secret, now time.Time) bool {
+	if expiresAt, err := time.Parse(time.RFC3339, secret.Annotations[registrationSecretExpiresAnnotation]); err == nil {
+		return !now.Before(expiresAt)
+	}
+	return !now.Before(secret.CreationTimestamp.Add(defaultRegistrationTokenTTL))
+}
+
+// needsRegistrationToken reports whether the runner may still read its registration token
+// The token is read once, when the runner container starts; until then the runner is Pending
+func needsRegistrationToken(actRunner *forgejoactionsiov1alpha1.ActRunner) bool {
+	return actRunner.Status.Phase == "" || actRunner.Status.Phase == forgejoactionsiov1alpha1.ActRunnerPhasePending
+}
+
+// expireRegistrationSecrets deletes the expired registration token secrets of the ActDeployment, and renews those
+// of runners still waiting for their pod
+// Pending runners whose secret was deleted, e.g. after a DinD crash replaced their pod, get a new one
+func expireRegistrationSecrets(ctx context.Context, logger logr.Logger, k8sClient client.Client, forgejoClient forgejo.API, organization, namespace string, actDeployment *forgejoactionsiov1alpha1.ActDeployment, now time.Time) error {
+	secrets := &corev1.SecretList{}
+	if err := k8sClient.List(ctx, secrets, client.InNamespace(namespace), client.MatchingLabels{"forgejo.actions.io/registration-token": "true"}); err != nil {
+		return fmt.Errorf("failed to list registration token secrets: %w", err)
+	}
+	actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+	if err := k8sClient.List(ctx, actRunners, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list ActRunners: %w", err)
+	}
+
+	runners := map[string]*forgejoactionsiov1alpha1.ActRunner{}
+	for i := range actRunners.Items {
+		ar := &actRunners.Items[i]
+		if metav1.IsControlledBy(ar, actDeployment) {
+			runners[ar.Spec.RegistrationTokenSecretRef.Name] = ar
+		}
+	}
+
+	existing := map[string]bool{}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		existing[secret.Name] = true
+		if secret.Labels[standaloneLabel] == "true" || !registrationSecretExpired(secret, now) {
+			continue
+		}
+
+		actRunner := runners[secret.Name]
+		switch {
+		case actRunner == nil && !ownedBy(secret, actDeployment.UID):
+			// Another ActDeployment's secret
+			continue
+		case actRunner != nil && needsRegistrationToken(actRunner):
+			if err := renewRegistrationSecret(ctx, k8sClient, forgejoClient, organization, actDeployment, actRunner, secret, now); err != nil {
+				return err
+			}
+			logger.V(1).Info("renewed expired registration token secret", "secretName", secret.Name, "actRunner", actRunner.Name)
+		default:
+			if err := client.IgnoreNotFound(k8sClient.Delete(ctx, secret)); err != nil {
+				return fmt.Errorf("failed to delete expired registration token secret %s: %w", secret.Name, err)
+			}
+			logger.V(1).Info("deleted expired registration token secret", "secretName", secret.Name, "jobID", secret.Labels["forgejo.actions.io/job-id"])
+		}
+	}
+
+	for name, actRunner := range runners {
+		if existing[name] || name == "" || !needsRegistrationToken(actRunner) {
+			continue
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"forgejo.actions.io/job-id":             fmt.Sprintf("%d", actRunner.Spec.ForgejoJobID),
+					"forgejo.actions.io/registration-token": "true",
+				},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: forgejoactionsiov1alpha1.GroupVersion.String(),
+					Kind:       "ActRunner",
+					Name:       actRunner.Name,
+					UID:        actRunner.UID,
+					Controller: ptr.To(true),
+				}},
+			},
+		}
+		if err := renewRegistrationSecret(ctx, k8sClient, forgejoClient, organization, actDeployment, actRunner, secret, now); err != nil {
+			return err
+		}
+		logger.Info("recreated registration token secret of pending runner", "secretName", name, "actRunner", actRunner.Name)
+	}
+	return nil
+}
+
+// renewRegistrationSecret stores a new registration token with a new expiry in the secret, creating it if it has no
+// resource version
+func renewRegistrationSecret(ctx context.Context, k8sClient client.Client, forgejoClient forgejo.API, organization string, actDeployment *forgejoactionsiov1alpha1.ActDeployment, actRunner *forgejoactionsiov1alpha1.ActRunner, secret *corev1.Secret, now time.Time) error {
+	token, err := forgejoClient.GetRegistrationToken(ctx, organization)
+	if err != nil {
+		return fmt.Errorf("failed to get registration token for ActRunner %s: %w", actRunner.Name, err)
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[registrationSecretExpiresAnnotation] = registrationSecretExpiry(actDeployment, now)
+	secret.Data = map[string][]byte{"token": []byte(token)}
+
+	if secret.ResourceVersion == "" {
+		if err := k8sClient.Create(ctx, secret); err != nil {
+			return fmt.Errorf("failed to create registration token secret %s: %w", secret.Name, err)
+		}
+		return nil
+	}
+	if err := k8sClient.Update(ctx, secret); err != nil {
+		return fmt.Errorf("failed to update registration token secret %s: %w", secret.Name, err)
+	}
+	return nil
+}
+
+// ownedBy reports whether the object has an owner reference to uid
+func ownedBy(obj metav1.Object, uid types.UID) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
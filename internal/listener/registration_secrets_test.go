@@ -0,0 +1,241 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+var _ = Describe("Registration token secrets", func() {
+	const (
+		organization = "secrets-org"
+		namespace    = "runners"
+		expiresAt    = "forgejo.actions.io/expires-at"
+	)
+
+	var (
+		ctx           context.Context
+		now           time.Time
+		server        *fake.Server
+		forgejoClient *forgejo.Client
+		c             client.Client
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Now()
+		server = fake.NewServer()
+		forgejoClient = forgejo.NewClient(server.URL(), "token")
+		c = clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithStatusSubresource(&forgejoactionsiov1alpha1.ActRunner{}).Build()
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: forgejoactionsiov1alpha1.GroupVersion.String(), Kind: "ActDeployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "secrets", Namespace: namespace, UID: "secrets-uid"},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	ownerReference := func(kind, name string, uid types.UID) metav1.OwnerReference {
+		return metav1.OwnerReference{APIVersion: forgejoactionsiov1alpha1.GroupVersion.String(), Kind: kind, Name: name, UID: uid, Controller: ptr.To(true)}
+	}
+
+	// createRunner creates an ActRunner of the ActDeployment in the phase, reading its token from the named secret
+	createRunner := func(name, secretName string, phase forgejoactionsiov1alpha1.ActRunnerPhase) *forgejoactionsiov1alpha1.ActRunner {
+		ar := &forgejoactionsiov1alpha1.ActRunner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       namespace,
+				UID:             types.UID(name + "-uid"),
+				OwnerReferences: []metav1.OwnerReference{ownerReference("ActDeployment", actDeployment.Name, actDeployment.UID)},
+			},
+			Spec: forgejoactionsiov1alpha1.ActRunnerSpec{
+				ForgejoJobID:               1,
+				RegistrationTokenSecretRef: corev1.SecretReference{Name: secretName},
+			},
+		}
+		Expect(c.Create(ctx, ar)).To(Succeed())
+		ar.Status.Phase = phase
+		Expect(c.Status().Update(ctx, ar)).To(Succeed())
+		return ar
+	}
+
+	// createSecret creates a registration token secret that expires at expiry
+	createSecret := func(name string, expiry time.Time, owner metav1.OwnerReference) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				Labels:            map[string]string{"forgejo.actions.io/registration-token": "true"},
+				Annotations:       map[string]string{expiresAt: expiry.UTC().Format(time.RFC3339)},
+				OwnerReferences:   []metav1.OwnerReference{owner},
+				CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour)),
+			},
+			Data: map[string][]byte{"token": []byte("old-token")},
+		}
+		Expect(c.Create(ctx, secret)).To(Succeed())
+		return secret
+	}
+
+	getSecret := func(name string) (*corev1.Secret, error) {
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret)
+		return secret, err
+	}
+
+	Describe("expiry", func() {
+		expire := func() {
+			Expect(listener.ExpireRegistrationSecrets(ctx, GinkgoLogr, c, forgejoClient, organization, namespace, actDeployment, now)).To(Succeed())
+		}
+
+		It("deletes the expired secret of a finished runner", func() {
+			ar := createRunner("finished", "finished-token", forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded)
+			createSecret("finished-token", now.Add(-time.Minute), ownerReference("ActRunner", ar.Name, ar.UID))
+			expire()
+
+			_, err := getSecret("finished-token")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("renews the expired secret of a pending runner", func() {
+			ar := createRunner("pending", "pending-token", forgejoactionsiov1alpha1.ActRunnerPhasePending)
+			createSecret("pending-token", now.Add(-time.Minute), ownerReference("ActRunner", ar.Name, ar.UID))
+			expire()
+
+			secret, err := getSecret("pending-token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(secret.Data["token"])).To(Equal(fake.RegistrationToken))
+			expiry, err := time.Parse(time.RFC3339, secret.Annotations[expiresAt])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expiry).To(BeTemporally(">", now))
+		})
+
+		It("leaves the expired secrets of other ActDeployments alone", func() {
+			createSecret("other-token", now.Add(-time.Minute), ownerReference("ActDeployment", "other", "other-uid"))
+			expire()
+
+			secret, err := getSecret("other-token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(secret.Data["token"])).To(Equal("old-token"))
+		})
+
+		It("keeps secrets that have not expired yet", func() {
+			ar := createRunner("finished", "finished-token", forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded)
+			createSecret("finished-token", now.Add(time.Minute), ownerReference("ActRunner", ar.Name, ar.UID))
+			expire()
+
+			_, err := getSecret("finished-token")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("recreates the missing secret of a pending runner, owned by the runner", func() {
+			ar := createRunner("pending", "pending-token", forgejoactionsiov1alpha1.ActRunnerPhasePending)
+			createRunner("running", "running-token", forgejoactionsiov1alpha1.ActRunnerPhaseRunning)
+			expire()
+
+			secret, err := getSecret("pending-token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(secret.Data["token"])).To(Equal(fake.RegistrationToken))
+			Expect(metav1.IsControlledBy(secret, ar)).To(BeTrue())
+			Expect(secret.Annotations).To(HaveKey(expiresAt))
+
+			// A running runner has read its token already
+			_, err = getSecret("running-token")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("adoption", func() {
+		reconcile := func() {
+			Expect(listener.ReconcileRegistrationSecrets(ctx, GinkgoLogr, c, namespace, now)).To(Succeed())
+		}
+
+		It("hands a secret left owned by the ActDeployment over to its ActRunner", func() {
+			ar := createRunner("pending", "pending-token", forgejoactionsiov1alpha1.ActRunnerPhasePending)
+			createSecret("pending-token", now.Add(time.Hour), ownerReference("ActDeployment", actDeployment.Name, actDeployment.UID))
+			reconcile()
+
+			secret, err := getSecret("pending-token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metav1.IsControlledBy(secret, ar)).To(BeTrue())
+			Expect(secret.OwnerReferences).To(HaveLen(1))
+		})
+
+		It("deletes unreferenced secrets once they are old enough to not belong to a runner being created", func() {
+			createSecret("orphaned-token", now.Add(time.Hour), ownerReference("ActDeployment", actDeployment.Name, actDeployment.UID))
+			young := createSecret("young-token", now.Add(time.Hour), ownerReference("ActDeployment", actDeployment.Name, actDeployment.UID))
+			young.CreationTimestamp = metav1.NewTime(now.Add(-time.Minute))
+			Expect(c.Update(ctx, young)).To(Succeed())
+			reconcile()
+
+			_, err := getSecret("orphaned-token")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			_, err = getSecret("young-token")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("leaves unreferenced standalone secrets to their Jobs", func() {
+			secret := createSecret("standalone-token", now.Add(time.Hour), metav1.OwnerReference{APIVersion: "batch/v1", Kind: "Job", Name: "standalone", UID: "standalone-uid"})
+			secret.Labels["forgejo.actions.io/standalone"] = "true"
+			Expect(c.Update(ctx, secret)).To(Succeed())
+			reconcile()
+
+			_, err := getSecret("standalone-token")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("makes a polled job's ActRunner the controller of its registration token secret", func() {
+			server.AddJob(organization, forgejo.Job{ID: 5, RunsOn: []string{"docker"}})
+			actDeployment.Spec = forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:       server.URL(),
+				Organization:        organization,
+				Labels:              "docker",
+				RunnerImage:         "runner:test",
+				DockerInDockerImage: "dind:test",
+			}
+			config := listener.Config{Organization: organization, Labels: "docker", Namespace: namespace}
+			Expect(listener.NewPoller(GinkgoLogr, c, forgejoClient, record.NewFakeRecorder(10), listener.NewJobState(), config).PollAndCreateActRunners(ctx, actDeployment)).To(Succeed())
+
+			actRunners := &forgejoactionsiov1alpha1.ActRunnerList{}
+			Expect(c.List(ctx, actRunners, client.InNamespace(namespace))).To(Succeed())
+			Expect(actRunners.Items).To(HaveLen(1))
+			secret, err := getSecret(actRunners.Items[0].Spec.RegistrationTokenSecretRef.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metav1.IsControlledBy(secret, &actRunners.Items[0])).To(BeTrue())
+			Expect(secret.OwnerReferences).To(HaveLen(1))
+		})
+	})
+})
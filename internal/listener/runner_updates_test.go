@@ -0,0 +1,126 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/rollout"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerspec"
+)
+
+var _ = Describe("Existing ActRunners", func() {
+	const namespace = "runners"
+
+	var (
+		ctx           context.Context
+		c             client.Client
+		actDeployment *forgejoactionsiov1alpha1.ActDeployment
+		labels        []forgejoactionsiov1alpha1.RunnerLabel
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithStatusSubresource(&forgejoactionsiov1alpha1.ActRunner{}).Build()
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "updates", Namespace: namespace, UID: "updates-uid"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:  "https://forgejo.example.com",
+				Organization:   "acme",
+				TokenSecretRef: corev1.SecretReference{Name: "forgejo-token"},
+				RunnerImage:    "runner:1",
+			},
+		}
+		labels = []forgejoactionsiov1alpha1.RunnerLabel{{Name: "docker"}}
+	})
+
+	// createRunner creates the ActRunner the listener creates for the job, with the workload if it has one
+	createRunner := func(jobID int64, workload string) *forgejoactionsiov1alpha1.ActRunner {
+		job := forgejo.Job{ID: jobID, Name: "build", RunsOn: []string{"docker"}, Handle: "handle"}
+		name := runnerspec.Name(actDeployment.Spec.ForgejoServer, actDeployment.Spec.Organization, jobID, 1)
+		actRunner := runnerspec.ForJob(actDeployment, job, name, namespace, name+"-token", labels)
+		Expect(c.Create(ctx, actRunner)).To(Succeed())
+		if workload != "" {
+			actRunner.Status.Phase = forgejoactionsiov1alpha1.ActRunnerPhaseRunning
+			actRunner.Status.KubernetesJobName = workload
+			Expect(c.Status().Update(ctx, actRunner)).To(Succeed())
+		}
+		return actRunner
+	}
+
+	get := func(actRunner *forgejoactionsiov1alpha1.ActRunner) *forgejoactionsiov1alpha1.ActRunner {
+		current := &forgejoactionsiov1alpha1.ActRunner{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(actRunner), current)).To(Succeed())
+		return current
+	}
+
+	It("gives pending runners the spec of the changed ActDeployment, keeping their job", func() {
+		pending := createRunner(1, "")
+		actDeployment.Spec.RunnerImage = "runner:2"
+		actDeployment.Spec.ForgejoServer = "https://git.example.com"
+		actDeployment.Spec.RunnerTemplate.Spec.NodeSelector = map[string]string{"pool": "ci"}
+		actDeployment.Spec.ExtraVolumes = []corev1.Volume{{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+		Expect(listener.UpdateExistingActRunners(ctx, GinkgoLogr, c, namespace, actDeployment)).To(Succeed())
+
+		updated := get(pending)
+		Expect(updated.Spec.RunnerImage).To(Equal("runner:2"))
+		Expect(updated.Spec.ExtraVolumes).To(Equal(actDeployment.Spec.ExtraVolumes))
+		Expect(updated.Spec.JobTemplate.Spec.NodeSelector).To(HaveKeyWithValue("pool", "ci"))
+		Expect(updated.Annotations).To(HaveKeyWithValue(rollout.TemplateHashAnnotation, rollout.TemplateHash(&actDeployment.Spec, nil)))
+
+		Expect(updated.Spec.ForgejoJobID).To(Equal(int64(1)))
+		Expect(updated.Spec.JobData).To(Equal(pending.Spec.JobData))
+		Expect(updated.Spec.RunnerLabels).To(Equal(labels))
+		Expect(updated.Spec.RegistrationTokenSecretRef).To(Equal(pending.Spec.RegistrationTokenSecretRef))
+		Expect(updated.Spec.ForgejoServer).To(Equal("https://forgejo.example.com"), "the runner stays with the server it registers with")
+	})
+
+	It("leaves runners whose workload exists alone", func() {
+		running := createRunner(2, "runner-pod")
+		actDeployment.Spec.RunnerImage = "runner:2"
+		actDeployment.Spec.Backend = forgejoactionsiov1alpha1.RunnerBackendJob
+		Expect(listener.UpdateExistingActRunners(ctx, GinkgoLogr, c, namespace, actDeployment)).To(Succeed())
+
+		Expect(get(running).Spec).To(Equal(running.Spec))
+	})
+
+	It("doesn't rewrite runners that already match the ActDeployment", func() {
+		pending := createRunner(3, "")
+		Expect(listener.UpdateExistingActRunners(ctx, GinkgoLogr, c, namespace, actDeployment)).To(Succeed())
+
+		Expect(get(pending).ResourceVersion).To(Equal(pending.ResourceVersion))
+	})
+
+	It("leaves the runners of other ActDeployments alone", func() {
+		other := createRunner(4, "")
+		actDeployment.UID = "other-uid"
+		actDeployment.Spec.RunnerImage = "runner:2"
+		Expect(listener.UpdateExistingActRunners(ctx, GinkgoLogr, c, namespace, actDeployment)).To(Succeed())
+
+		Expect(get(other).Spec.RunnerImage).To(Equal("runner:1"))
+	})
+})
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+var _ = Describe("Shutdown", func() {
+	const namespace = "runners"
+
+	var c client.Client
+
+	BeforeEach(func() {
+		// Like a real client, fail requests whose context is done
+		c = clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithStatusSubresource(&forgejoactionsiov1alpha1.ActDeployment{}).WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+		Expect(c.Create(context.Background(), &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "shutdown", Namespace: namespace},
+		})).To(Succeed())
+	})
+
+	It("records the shutdown in the ActDeployment status even though the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		before := time.Now().Truncate(time.Second)
+		listener.RecordShutdown(ctx, GinkgoLogr, c, namespace, "shutdown")
+
+		actDeployment := &forgejoactionsiov1alpha1.ActDeployment{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "shutdown"}, actDeployment)).To(Succeed())
+		Expect(actDeployment.Status.LastShutdown).NotTo(BeNil())
+		Expect(actDeployment.Status.LastShutdown.Time).To(BeTemporally(">=", before))
+	})
+
+	It("leaves other ActDeployments alone when its own is gone", func() {
+		listener.RecordShutdown(context.Background(), GinkgoLogr, c, namespace, "missing")
+
+		actDeployment := &forgejoactionsiov1alpha1.ActDeployment{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "shutdown"}, actDeployment)).To(Succeed())
+		Expect(actDeployment.Status.LastShutdown).To(BeNil())
+	})
+})
@@ -0,0 +1,150 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listener_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo/fake"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/listener"
+)
+
+var _ = Describe("Standalone listener", func() {
+	const (
+		organization = "standalone-org"
+		namespace    = "runners"
+	)
+
+	var (
+		ctx           context.Context
+		server        *fake.Server
+		forgejoClient *forgejo.Client
+		template      *corev1.PodTemplateSpec
+		runnerLabels  []forgejoactionsiov1alpha1.RunnerLabel
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = fake.NewServer()
+		server.AddJob(organization, forgejo.Job{ID: 42, RunsOn: []string{"docker"}})
+		forgejoClient = forgejo.NewClient(server.URL(), "token")
+		template = &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "runner", Image: "runner:test"}}}}
+		runnerLabels = []forgejoactionsiov1alpha1.RunnerLabel{{Name: "docker"}}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("creates the registration token secret before the Job and hands it over to the Job", func() {
+		c := clientfake.NewClientBuilder().WithScheme(listener.Scheme).Build()
+		Expect(listener.PollAndCreateJobs(ctx, GinkgoLogr, c, forgejoClient, template, server.URL(), organization, namespace, runnerLabels, 0)).To(Succeed())
+
+		jobs := &batchv1.JobList{}
+		Expect(c.List(ctx, jobs, client.InNamespace(namespace))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: jobs.Items[0].Name}, secret)).To(Succeed())
+		Expect(string(secret.Data["token"])).To(Equal(fake.RegistrationToken))
+		Expect(secret.OwnerReferences).To(HaveLen(1))
+		Expect(secret.OwnerReferences[0].Kind).To(Equal("Job"))
+		Expect(secret.OwnerReferences[0].Name).To(Equal(jobs.Items[0].Name))
+	})
+
+	It("creates no Job and returns the error when the registration token secret can't be created", func() {
+		c := clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if _, ok := obj.(*corev1.Secret); ok {
+					return errors.New("secrets are unavailable")
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).Build()
+		err := listener.PollAndCreateJobs(ctx, GinkgoLogr, c, forgejoClient, template, server.URL(), organization, namespace, runnerLabels, 0)
+		Expect(err).To(MatchError(ContainSubstring("secrets are unavailable")))
+
+		jobs := &batchv1.JobList{}
+		Expect(c.List(ctx, jobs, client.InNamespace(namespace))).To(Succeed())
+		Expect(jobs.Items).To(BeEmpty())
+	})
+
+	It("deletes the registration token secret when the Job can't be created", func() {
+		c := clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if _, ok := obj.(*batchv1.Job); ok {
+					return errors.New("jobs are unavailable")
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).Build()
+		Expect(listener.PollAndCreateJobs(ctx, GinkgoLogr, c, forgejoClient, template, server.URL(), organization, namespace, runnerLabels, 0)).To(Succeed())
+
+		secrets := &corev1.SecretList{}
+		Expect(c.List(ctx, secrets, client.InNamespace(namespace))).To(Succeed())
+		Expect(secrets.Items).To(BeEmpty())
+	})
+
+	It("deletes a registration token secret that was never handed over to its Job once it is old enough", func() {
+		handOverFails := true
+		c := clientfake.NewClientBuilder().WithScheme(listener.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+			// Like the API server, record when objects were created
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				obj.SetCreationTimestamp(metav1.Now())
+				return c.Create(ctx, obj, opts...)
+			},
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if _, ok := obj.(*corev1.Secret); ok && handOverFails {
+					return errors.New("listener crashed")
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+		Expect(listener.PollAndCreateJobs(ctx, GinkgoLogr, c, forgejoClient, template, server.URL(), organization, namespace, runnerLabels, 0)).NotTo(Succeed())
+		handOverFails = false
+
+		secrets := &corev1.SecretList{}
+		Expect(c.List(ctx, secrets, client.InNamespace(namespace))).To(Succeed())
+		Expect(secrets.Items).To(HaveLen(1))
+		Expect(secrets.Items[0].OwnerReferences).To(BeEmpty())
+
+		By("polling while the secret may still be handed over")
+		Expect(listener.PollAndCreateJobs(ctx, GinkgoLogr, c, forgejoClient, template, server.URL(), organization, namespace, runnerLabels, 0)).To(Succeed())
+		Expect(c.List(ctx, secrets, client.InNamespace(namespace))).To(Succeed())
+		Expect(secrets.Items).To(HaveLen(1))
+
+		By("polling once the secret is old enough")
+		orphaned := &secrets.Items[0]
+		orphaned.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+		Expect(c.Update(ctx, orphaned)).To(Succeed())
+		Expect(listener.PollAndCreateJobs(ctx, GinkgoLogr, c, forgejoClient, template, server.URL(), organization, namespace, runnerLabels, 0)).To(Succeed())
+		Expect(c.List(ctx, secrets, client.InNamespace(namespace))).To(Succeed())
+		Expect(secrets.Items).To(BeEmpty())
+	})
+})
@@ -0,0 +1,124 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package maintenance evaluates ActDeployment maintenance windows
+package maintenance
+
+import (
+	"fmt"
+	"time"
+	// Embed the time zone database; the listener runs in a scratch image without /usr/share/zoneinfo
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// ActiveWindow describes a maintenance window that is currently in effect
+type ActiveWindow struct {
+	// Window is the maintenance window definition from the ActDeployment spec
+	Window forgejoactionsiov1alpha1.MaintenanceWindow
+
+	// Start is when the current occurrence of the window started
+	Start time.Time
+
+	// End is when the current occurrence of the window ends
+	End time.Time
+}
+
+// Validate checks that a maintenance window has a parseable schedule, a known time zone and a positive duration
+func Validate(window forgejoactionsiov1alpha1.MaintenanceWindow) error {
+	_, err := parse(window)
+	return err
+}
+
+// Active returns the maintenance window in effect at the given time, or nil if none is
+// If several windows overlap, the one ending last is returned
+// Windows that fail validation are skipped and reported in the returned error
+func Active(windows []forgejoactionsiov1alpha1.MaintenanceWindow, now time.Time) (*ActiveWindow, error) {
+	var active *ActiveWindow
+	var invalidErr error
+
+	for _, window := range windows {
+		schedule, err := parse(window)
+		if err != nil {
+			if invalidErr == nil {
+				invalidErr = err
+			}
+			continue
+		}
+
+		// The window is active if an occurrence started within (now-duration, now]
+		// Next() returns the first start strictly after its argument
+		start := schedule.Next(now.Add(-window.Duration.Duration))
+		if start.IsZero() || start.After(now) {
+			continue
+		}
+
+		end := start.Add(window.Duration.Duration)
+		if active == nil || end.After(active.End) {
+			active = &ActiveWindow{Window: window, Start: start, End: end}
+		}
+	}
+
+	return active, invalidErr
+}
+
+// NextTransition returns when a window next starts or an active occurrence ends after the given time,
+// or the zero time if no valid window has another occurrence
+func NextTransition(windows []forgejoactionsiov1alpha1.MaintenanceWindow, now time.Time) time.Time {
+	var next time.Time
+	earlier := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	for _, window := range windows {
+		schedule, err := parse(window)
+		if err != nil {
+			continue
+		}
+		if start := schedule.Next(now.Add(-window.Duration.Duration)); !start.IsZero() && !start.After(now) {
+			earlier(start.Add(window.Duration.Duration))
+		}
+		earlier(schedule.Next(now))
+	}
+
+	return next
+}
+
+func parse(window forgejoactionsiov1alpha1.MaintenanceWindow) (cron.Schedule, error) {
+	if window.Duration.Duration <= 0 {
+		return nil, fmt.Errorf("maintenance window %q: duration must be positive", window.Schedule)
+	}
+
+	// Evaluate in UTC unless a time zone is given, independent of the container's local time
+	timeZone := window.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, fmt.Errorf("maintenance window %q: invalid time zone %q: %w", window.Schedule, timeZone, err)
+	}
+
+	schedule, err := cron.ParseStandard(fmt.Sprintf("CRON_TZ=%s %s", timeZone, window.Schedule))
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window %q: invalid schedule: %w", window.Schedule, err)
+	}
+	return schedule, nil
+}
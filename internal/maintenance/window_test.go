@@ -0,0 +1,109 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Maintenance windows", func() {
+	// Sunday 02:00 UTC for two hours
+	sundayNight := forgejoactionsiov1alpha1.MaintenanceWindow{
+		Schedule: "0 2 * * SUN",
+		Duration: metav1.Duration{Duration: 2 * time.Hour},
+	}
+
+	It("should report the window as active during an occurrence", func() {
+		now := time.Date(2025, 6, 1, 3, 30, 0, 0, time.UTC) // Sunday
+		active, err := Active([]forgejoactionsiov1alpha1.MaintenanceWindow{sundayNight}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).NotTo(BeNil())
+		Expect(active.Start).To(Equal(time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC)))
+		Expect(active.End).To(Equal(time.Date(2025, 6, 1, 4, 0, 0, 0, time.UTC)))
+	})
+
+	It("should not report the window as active outside an occurrence", func() {
+		now := time.Date(2025, 6, 1, 4, 0, 0, 0, time.UTC)
+		active, err := Active([]forgejoactionsiov1alpha1.MaintenanceWindow{sundayNight}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).To(BeNil())
+	})
+
+	It("should evaluate the schedule in the configured time zone", func() {
+		berlin := sundayNight
+		berlin.TimeZone = "Europe/Berlin"
+		// 02:30 in Berlin (CEST) is 00:30 UTC
+		now := time.Date(2025, 6, 1, 0, 30, 0, 0, time.UTC)
+		active, err := Active([]forgejoactionsiov1alpha1.MaintenanceWindow{berlin}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).NotTo(BeNil())
+	})
+
+	Describe("NextTransition", func() {
+		// Every day 12:00 UTC for 30 minutes
+		noon := forgejoactionsiov1alpha1.MaintenanceWindow{
+			Schedule: "0 12 * * *",
+			Duration: metav1.Duration{Duration: 30 * time.Minute},
+		}
+
+		It("should return the next start outside a window", func() {
+			now := time.Date(2025, 6, 1, 1, 0, 0, 0, time.UTC) // Sunday
+			Expect(NextTransition([]forgejoactionsiov1alpha1.MaintenanceWindow{sundayNight, noon}, now)).
+				To(Equal(time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC)))
+		})
+
+		It("should return the end of an active occurrence", func() {
+			now := time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC)
+			Expect(NextTransition([]forgejoactionsiov1alpha1.MaintenanceWindow{sundayNight, noon}, now)).
+				To(Equal(time.Date(2025, 6, 1, 4, 0, 0, 0, time.UTC)))
+		})
+
+		It("should return a start within an active occurrence of another window", func() {
+			overlapping := forgejoactionsiov1alpha1.MaintenanceWindow{
+				Schedule: "30 3 * * *",
+				Duration: metav1.Duration{Duration: time.Hour},
+			}
+			now := time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC)
+			Expect(NextTransition([]forgejoactionsiov1alpha1.MaintenanceWindow{sundayNight, overlapping}, now)).
+				To(Equal(time.Date(2025, 6, 1, 3, 30, 0, 0, time.UTC)))
+		})
+
+		It("should return the zero time without valid windows", func() {
+			invalid := forgejoactionsiov1alpha1.MaintenanceWindow{Schedule: "not a cron", Duration: metav1.Duration{Duration: time.Hour}}
+			Expect(NextTransition(nil, time.Now())).To(BeZero())
+			Expect(NextTransition([]forgejoactionsiov1alpha1.MaintenanceWindow{invalid}, time.Now())).To(BeZero())
+		})
+	})
+
+	It("should skip invalid windows and report them", func() {
+		invalid := forgejoactionsiov1alpha1.MaintenanceWindow{
+			Schedule: "not a cron",
+			Duration: metav1.Duration{Duration: time.Hour},
+		}
+		now := time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC)
+		active, err := Active([]forgejoactionsiov1alpha1.MaintenanceWindow{invalid, sundayNight}, now)
+		Expect(err).To(HaveOccurred())
+		Expect(active).NotTo(BeNil())
+		Expect(Validate(invalid)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,237 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package natsclient implements the subset of the plain-text NATS client protocol the operator needs:
+// connecting with user/password or token authentication, publishing and subscribing
+// TLS connections are not supported
+package natsclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configure a NATS connection
+type Options struct {
+	// Address is the host:port of the NATS server
+	Address string
+	// User and Password authenticate the client; a User without Password is sent as token
+	User     string
+	Password string
+	// Name identifies the client in the server's monitoring
+	Name string
+}
+
+// ParseURL splits a nats://[user[:password]@]host[:port]/subject URL into connection options and the subject
+func ParseURL(rawURL string) (Options, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Options{}, "", fmt.Errorf("invalid NATS URL: %w", err)
+	}
+	if u.Scheme != "nats" {
+		return Options{}, "", fmt.Errorf("unsupported NATS URL scheme %q", u.Scheme)
+	}
+	subject := strings.Trim(u.Path, "/")
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return Options{}, "", fmt.Errorf("NATS URL %s needs a subject as path (e.g., nats://nats:4222/forgejo.jobs)", u.Redacted())
+	}
+
+	options := Options{Address: u.Host}
+	if u.Port() == "" {
+		options.Address = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil {
+		options.User = u.User.Username()
+		options.Password, _ = u.User.Password()
+	}
+	return options, subject, nil
+}
+
+// Op is a protocol message received from the server
+type Op struct {
+	// Kind is the operation, e.g. MSG, PING, PONG, +OK or -ERR
+	Kind string
+	// Subject, Reply and Data are set for MSG
+	Subject string
+	Reply   string
+	Data    []byte
+	// Error is the message of a -ERR
+	Error string
+}
+
+// Conn is a NATS client connection
+// Writes are safe for concurrent use; reads must happen from a single goroutine
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMu sync.Mutex
+}
+
+// Dial connects to the server and authenticates
+// Authentication errors are reported by the server as -ERR on the next read
+func Dial(ctx context.Context, options Options) (*Conn, error) {
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", options.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	c := &Conn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if err := netConn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	if err := c.handshake(options); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	if err := netConn.SetDeadline(time.Time{}); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Conn) handshake(options Options) error {
+	info, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(info, "INFO ") {
+		return fmt.Errorf("unexpected NATS greeting %q", info)
+	}
+	var serverInfo struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(info, "INFO ")), &serverInfo); err == nil && serverInfo.TLSRequired {
+		return errors.New("NATS server requires TLS, which is not supported")
+	}
+
+	connect := struct {
+		Verbose  bool   `json:"verbose"`
+		Pedantic bool   `json:"pedantic"`
+		Name     string `json:"name,omitempty"`
+		User     string `json:"user,omitempty"`
+		Pass     string `json:"pass,omitempty"`
+		Token    string `json:"auth_token,omitempty"`
+	}{Name: options.Name}
+	if options.Password != "" {
+		connect.User, connect.Pass = options.User, options.Password
+	} else {
+		connect.Token = options.User
+	}
+	data, err := json.Marshal(connect)
+	if err != nil {
+		return err
+	}
+	return c.write(fmt.Sprintf("CONNECT %s\r\n", data))
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// SetDeadline sets the read and write deadline of the connection
+func (c *Conn) SetDeadline(deadline time.Time) error {
+	return c.conn.SetDeadline(deadline)
+}
+
+// Publish sends a message to the subject
+func (c *Conn) Publish(subject string, data []byte) error {
+	return c.write(fmt.Sprintf("PUB %s %d\r\n%s\r\n", subject, len(data), data))
+}
+
+// Subscribe subscribes to the subject; messages are delivered to subscribers of the same queue group in turn
+func (c *Conn) Subscribe(subject, queue, sid string) error {
+	if queue == "" {
+		return c.write(fmt.Sprintf("SUB %s %s\r\n", subject, sid))
+	}
+	return c.write(fmt.Sprintf("SUB %s %s %s\r\n", subject, queue, sid))
+}
+
+// Ping asks the server for a PONG; as the server processes messages in order, the PONG confirms
+// everything sent before
+func (c *Conn) Ping() error {
+	return c.write("PING\r\n")
+}
+
+// Pong answers a PING of the server
+func (c *Conn) Pong() error {
+	return c.write("PONG\r\n")
+}
+
+// ReadOp reads the next protocol message
+func (c *Conn) ReadOp() (*Op, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	kind, args, _ := strings.Cut(line, " ")
+	op := &Op{Kind: strings.ToUpper(kind)}
+	switch op.Kind {
+	case "-ERR":
+		op.Error = strings.Trim(strings.TrimSpace(args), "'")
+	case "MSG":
+		// MSG <subject> <sid> [reply-to] <#bytes>
+		fields := strings.Fields(args)
+		if len(fields) < 3 || len(fields) > 4 {
+			return nil, fmt.Errorf("malformed NATS message %q", line)
+		}
+		size, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("malformed NATS message size %q", line)
+		}
+		op.Subject = fields[0]
+		if len(fields) == 4 {
+			op.Reply = fields[2]
+		}
+		payload := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return nil, fmt.Errorf("failed to read from NATS: %w", err)
+		}
+		op.Data = payload[:size]
+	}
+	return op, nil
+}
+
+func (c *Conn) write(data string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := io.WriteString(c.conn, data); err != nil {
+		return fmt.Errorf("failed to write to NATS: %w", err)
+	}
+	return nil
+}
+
+func (c *Conn) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read from NATS: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
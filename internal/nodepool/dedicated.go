@@ -0,0 +1,93 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// DedicatedKey is the node label and taint key of dedicated node groups
+const DedicatedKey = "forgejo.actions.io/dedicated"
+
+// DedicatedGroup returns the dedicated node group of an ActDeployment, or "" without spec.dedicatedNodes
+// The default <namespace>.<name> is shortened with a hash to fit a label value
+func DedicatedGroup(actDeployment *forgejoactionsiov1alpha1.ActDeployment) string {
+	dedicated := actDeployment.Spec.DedicatedNodes
+	if dedicated == nil {
+		return ""
+	}
+	if dedicated.Group != "" {
+		return dedicated.Group
+	}
+	group := actDeployment.Namespace + "." + actDeployment.Name
+	if len(group) > 63 {
+		sum := sha256.Sum256([]byte(group))
+		group = group[:54] + "-" + hex.EncodeToString(sum[:])[:8]
+	}
+	return group
+}
+
+// dedicatedTaint returns the taint of the ActDeployment's dedicated node group
+func dedicatedTaint(actDeployment *forgejoactionsiov1alpha1.ActDeployment) corev1.Taint {
+	effect := actDeployment.Spec.DedicatedNodes.TaintEffect
+	if effect == "" {
+		effect = corev1.TaintEffectNoSchedule
+	}
+	return corev1.Taint{Key: DedicatedKey, Value: DedicatedGroup(actDeployment), Effect: effect}
+}
+
+// ApplyDedicated pins a pod spec to the ActDeployment's dedicated node group and tolerates its taint
+// The node selector entry replaces a template value, so runner pods can't leave the group
+func ApplyDedicated(podSpec *corev1.PodSpec, actDeployment *forgejoactionsiov1alpha1.ActDeployment) {
+	if actDeployment.Spec.DedicatedNodes == nil {
+		return
+	}
+
+	taint := dedicatedTaint(actDeployment)
+	if podSpec.NodeSelector == nil {
+		podSpec.NodeSelector = map[string]string{}
+	}
+	podSpec.NodeSelector[DedicatedKey] = taint.Value
+
+	toleration := corev1.Toleration{Key: taint.Key, Operator: corev1.TolerationOpEqual, Value: taint.Value, Effect: taint.Effect}
+	if !hasToleration(podSpec.Tolerations, toleration) {
+		podSpec.Tolerations = append(podSpec.Tolerations, toleration)
+	}
+}
+
+// DedicatedStatus returns the node configuration of the ActDeployment's dedicated node group
+func DedicatedStatus(actDeployment *forgejoactionsiov1alpha1.ActDeployment) *forgejoactionsiov1alpha1.DedicatedNodesStatus {
+	if actDeployment.Spec.DedicatedNodes == nil {
+		return nil
+	}
+
+	taint := dedicatedTaint(actDeployment)
+	return &forgejoactionsiov1alpha1.DedicatedNodesStatus{
+		NodeLabel: fmt.Sprintf("%s=%s", taint.Key, taint.Value),
+		NodeTaint: taint.ToString(),
+		AutoscalerTags: map[string]string{
+			"k8s.io/cluster-autoscaler/node-template/label/" + taint.Key: taint.Value,
+			"k8s.io/cluster-autoscaler/node-template/taint/" + taint.Key: fmt.Sprintf("%s:%s", taint.Value, taint.Effect),
+		},
+	}
+}
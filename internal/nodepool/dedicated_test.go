@@ -0,0 +1,133 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepool
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Dedicated nodes", func() {
+	var actDeployment *forgejoactionsiov1alpha1.ActDeployment
+
+	BeforeEach(func() {
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "gpu", Namespace: "runners"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				DedicatedNodes: &forgejoactionsiov1alpha1.DedicatedNodesSpec{},
+			},
+		}
+	})
+
+	Describe("DedicatedGroup", func() {
+		It("is empty without dedicated nodes", func() {
+			actDeployment.Spec.DedicatedNodes = nil
+			Expect(DedicatedGroup(actDeployment)).To(BeEmpty())
+		})
+
+		It("defaults to the namespace and name of the ActDeployment", func() {
+			Expect(DedicatedGroup(actDeployment)).To(Equal("runners.gpu"))
+		})
+
+		It("uses the configured group", func() {
+			actDeployment.Spec.DedicatedNodes.Group = "shared-gpu"
+			Expect(DedicatedGroup(actDeployment)).To(Equal("shared-gpu"))
+		})
+
+		It("shortens a long default with a hash to fit a label value", func() {
+			actDeployment.Name = strings.Repeat("a", 63)
+			group := DedicatedGroup(actDeployment)
+			Expect(group).To(HaveLen(63))
+			Expect(group).To(HavePrefix("runners.aaaa"))
+			Expect(validation.IsValidLabelValue(group)).To(BeEmpty())
+
+			// Names sharing the kept prefix still get groups of their own
+			actDeployment.Name = strings.Repeat("a", 62) + "b"
+			Expect(DedicatedGroup(actDeployment)).NotTo(Equal(group))
+		})
+	})
+
+	Describe("ApplyDedicated", func() {
+		toleration := corev1.Toleration{
+			Key:      DedicatedKey,
+			Operator: corev1.TolerationOpEqual,
+			Value:    "runners.gpu",
+			Effect:   corev1.TaintEffectNoSchedule,
+		}
+
+		It("leaves the pod spec alone without dedicated nodes", func() {
+			actDeployment.Spec.DedicatedNodes = nil
+			podSpec := &corev1.PodSpec{}
+			ApplyDedicated(podSpec, actDeployment)
+			Expect(podSpec).To(Equal(&corev1.PodSpec{}))
+		})
+
+		It("pins the pod to the group and tolerates its taint", func() {
+			podSpec := &corev1.PodSpec{NodeSelector: map[string]string{"disk": "ssd"}}
+			ApplyDedicated(podSpec, actDeployment)
+			Expect(podSpec.NodeSelector).To(Equal(map[string]string{"disk": "ssd", DedicatedKey: "runners.gpu"}))
+			Expect(podSpec.Tolerations).To(Equal([]corev1.Toleration{toleration}))
+		})
+
+		It("replaces a group set by the template so runner pods can't leave the group", func() {
+			podSpec := &corev1.PodSpec{NodeSelector: map[string]string{DedicatedKey: "other"}}
+			ApplyDedicated(podSpec, actDeployment)
+			Expect(podSpec.NodeSelector).To(HaveKeyWithValue(DedicatedKey, "runners.gpu"))
+		})
+
+		It("doesn't add the toleration twice", func() {
+			podSpec := &corev1.PodSpec{}
+			ApplyDedicated(podSpec, actDeployment)
+			ApplyDedicated(podSpec, actDeployment)
+			Expect(podSpec.Tolerations).To(Equal([]corev1.Toleration{toleration}))
+		})
+
+		It("tolerates the configured taint effect", func() {
+			actDeployment.Spec.DedicatedNodes.TaintEffect = corev1.TaintEffectNoExecute
+			podSpec := &corev1.PodSpec{}
+			ApplyDedicated(podSpec, actDeployment)
+			Expect(podSpec.Tolerations).To(HaveLen(1))
+			Expect(podSpec.Tolerations[0].Effect).To(Equal(corev1.TaintEffectNoExecute))
+		})
+	})
+
+	Describe("DedicatedStatus", func() {
+		It("is nil without dedicated nodes", func() {
+			actDeployment.Spec.DedicatedNodes = nil
+			Expect(DedicatedStatus(actDeployment)).To(BeNil())
+		})
+
+		It("describes the node label, taint and autoscaler tags of the group", func() {
+			actDeployment.Spec.DedicatedNodes.TaintEffect = corev1.TaintEffectPreferNoSchedule
+			Expect(DedicatedStatus(actDeployment)).To(Equal(&forgejoactionsiov1alpha1.DedicatedNodesStatus{
+				NodeLabel: "forgejo.actions.io/dedicated=runners.gpu",
+				NodeTaint: "forgejo.actions.io/dedicated=runners.gpu:PreferNoSchedule",
+				AutoscalerTags: map[string]string{
+					"k8s.io/cluster-autoscaler/node-template/label/forgejo.actions.io/dedicated": "runners.gpu",
+					"k8s.io/cluster-autoscaler/node-template/taint/forgejo.actions.io/dedicated": "runners.gpu:PreferNoSchedule",
+				},
+			}))
+		})
+	})
+})
@@ -0,0 +1,106 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nodepool translates ActDeployment node pool presets into pod scheduling fields
+package nodepool
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// presetLabelKeys maps each provider to the node label carrying the node pool name
+var presetLabelKeys = map[forgejoactionsiov1alpha1.NodePoolProvider]string{
+	forgejoactionsiov1alpha1.NodePoolProviderKarpenter: "karpenter.sh/nodepool",
+	forgejoactionsiov1alpha1.NodePoolProviderEKS:       "eks.amazonaws.com/nodegroup",
+	forgejoactionsiov1alpha1.NodePoolProviderGKE:       "cloud.google.com/gke-nodepool",
+	forgejoactionsiov1alpha1.NodePoolProviderAKS:       "kubernetes.azure.com/agentpool",
+}
+
+// NodeSelector returns the node selector for the node pool (preset label merged with the user selector)
+func NodeSelector(pool *forgejoactionsiov1alpha1.NodePoolSpec) map[string]string {
+	if pool == nil {
+		return nil
+	}
+
+	selector := map[string]string{}
+	if key, ok := presetLabelKeys[pool.Provider]; ok && pool.Name != "" {
+		selector[key] = pool.Name
+	}
+	for k, v := range pool.NodeSelector {
+		selector[k] = v
+	}
+	if len(selector) == 0 {
+		return nil
+	}
+	return selector
+}
+
+// ApplyToPodSpec merges the node pool scheduling fields into a pod spec
+// Existing nodeSelector entries from the template win over the preset, and tolerations are appended
+// unless an identical toleration is already present
+func ApplyToPodSpec(podSpec *corev1.PodSpec, pool *forgejoactionsiov1alpha1.NodePoolSpec) {
+	if pool == nil {
+		return
+	}
+
+	selector := NodeSelector(pool)
+	if len(selector) > 0 {
+		if podSpec.NodeSelector == nil {
+			podSpec.NodeSelector = map[string]string{}
+		}
+		for k, v := range selector {
+			if _, exists := podSpec.NodeSelector[k]; !exists {
+				podSpec.NodeSelector[k] = v
+			}
+		}
+	}
+
+	for _, toleration := range pool.Tolerations {
+		if !hasToleration(podSpec.Tolerations, toleration) {
+			podSpec.Tolerations = append(podSpec.Tolerations, toleration)
+		}
+	}
+}
+
+// ApplyExtendedResources adds the node pool's extended resources to a container's requests and limits
+// Extended resources cannot be overcommitted, so requests and limits are always set to the same value
+func ApplyExtendedResources(container *corev1.Container, pool *forgejoactionsiov1alpha1.NodePoolSpec) {
+	if pool == nil || len(pool.ExtendedResources) == 0 {
+		return
+	}
+
+	if container.Resources.Requests == nil {
+		container.Resources.Requests = corev1.ResourceList{}
+	}
+	if container.Resources.Limits == nil {
+		container.Resources.Limits = corev1.ResourceList{}
+	}
+	for name, quantity := range pool.ExtendedResources {
+		container.Resources.Requests[name] = quantity.DeepCopy()
+		container.Resources.Limits[name] = quantity.DeepCopy()
+	}
+}
+
+func hasToleration(tolerations []corev1.Toleration, toleration corev1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].MatchToleration(&toleration) {
+			return true
+		}
+	}
+	return false
+}
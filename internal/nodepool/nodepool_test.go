@@ -0,0 +1,134 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepool
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("NodeSelector", func() {
+	It("returns nil without a node pool", func() {
+		Expect(NodeSelector(nil)).To(BeNil())
+	})
+
+	DescribeTable("selects the pool by the provider's node label",
+		func(provider forgejoactionsiov1alpha1.NodePoolProvider, key string) {
+			pool := &forgejoactionsiov1alpha1.NodePoolSpec{Provider: provider, Name: "runners"}
+			Expect(NodeSelector(pool)).To(Equal(map[string]string{key: "runners"}))
+		},
+		Entry("karpenter", forgejoactionsiov1alpha1.NodePoolProviderKarpenter, "karpenter.sh/nodepool"),
+		Entry("eks", forgejoactionsiov1alpha1.NodePoolProviderEKS, "eks.amazonaws.com/nodegroup"),
+		Entry("gke", forgejoactionsiov1alpha1.NodePoolProviderGKE, "cloud.google.com/gke-nodepool"),
+		Entry("aks", forgejoactionsiov1alpha1.NodePoolProviderAKS, "kubernetes.azure.com/agentpool"),
+	)
+
+	It("adds no preset label for the custom provider", func() {
+		pool := &forgejoactionsiov1alpha1.NodePoolSpec{Provider: forgejoactionsiov1alpha1.NodePoolProviderCustom, Name: "runners"}
+		Expect(NodeSelector(pool)).To(BeNil())
+
+		pool.NodeSelector = map[string]string{"pool": "runners"}
+		Expect(NodeSelector(pool)).To(Equal(map[string]string{"pool": "runners"}))
+	})
+
+	It("lets the user selector override the preset label", func() {
+		pool := &forgejoactionsiov1alpha1.NodePoolSpec{
+			Provider:     forgejoactionsiov1alpha1.NodePoolProviderKarpenter,
+			Name:         "runners",
+			NodeSelector: map[string]string{"karpenter.sh/nodepool": "gpu", "kubernetes.io/arch": "arm64"},
+		}
+		Expect(NodeSelector(pool)).To(Equal(map[string]string{"karpenter.sh/nodepool": "gpu", "kubernetes.io/arch": "arm64"}))
+	})
+})
+
+var _ = Describe("ApplyToPodSpec", func() {
+	pool := &forgejoactionsiov1alpha1.NodePoolSpec{
+		Provider: forgejoactionsiov1alpha1.NodePoolProviderEKS,
+		Name:     "runners",
+		Tolerations: []corev1.Toleration{
+			{Key: "runners", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule},
+		},
+	}
+
+	It("leaves the pod spec alone without a node pool", func() {
+		podSpec := &corev1.PodSpec{NodeSelector: map[string]string{"disk": "ssd"}}
+		ApplyToPodSpec(podSpec, nil)
+		Expect(podSpec).To(Equal(&corev1.PodSpec{NodeSelector: map[string]string{"disk": "ssd"}}))
+	})
+
+	It("keeps node selector entries of the template", func() {
+		podSpec := &corev1.PodSpec{NodeSelector: map[string]string{"eks.amazonaws.com/nodegroup": "other", "disk": "ssd"}}
+		ApplyToPodSpec(podSpec, pool)
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"eks.amazonaws.com/nodegroup": "other", "disk": "ssd"}))
+
+		podSpec = &corev1.PodSpec{}
+		ApplyToPodSpec(podSpec, pool)
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"eks.amazonaws.com/nodegroup": "runners"}))
+	})
+
+	It("appends tolerations the pod spec doesn't tolerate yet", func() {
+		gpu := corev1.Toleration{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+		podSpec := &corev1.PodSpec{Tolerations: []corev1.Toleration{gpu, pool.Tolerations[0]}}
+		ApplyToPodSpec(podSpec, pool)
+		Expect(podSpec.Tolerations).To(Equal([]corev1.Toleration{gpu, pool.Tolerations[0]}))
+
+		podSpec = &corev1.PodSpec{Tolerations: []corev1.Toleration{gpu}}
+		ApplyToPodSpec(podSpec, pool)
+		Expect(podSpec.Tolerations).To(Equal([]corev1.Toleration{gpu, pool.Tolerations[0]}))
+	})
+})
+
+var _ = Describe("ApplyExtendedResources", func() {
+	gpu := corev1.ResourceName("nvidia.com/gpu")
+
+	It("leaves the container alone without extended resources", func() {
+		container := &corev1.Container{}
+		ApplyExtendedResources(container, &forgejoactionsiov1alpha1.NodePoolSpec{})
+		ApplyExtendedResources(container, nil)
+		Expect(container.Resources).To(Equal(corev1.ResourceRequirements{}))
+	})
+
+	It("requests and limits the extended resources alongside the container's own", func() {
+		container := &corev1.Container{Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		}}
+		ApplyExtendedResources(container, &forgejoactionsiov1alpha1.NodePoolSpec{
+			ExtendedResources: corev1.ResourceList{gpu: resource.MustParse("2")},
+		})
+		Expect(container.Resources.Requests).To(Equal(corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("1"),
+			gpu:                resource.MustParse("2"),
+		}))
+		Expect(container.Resources.Limits).To(Equal(corev1.ResourceList{gpu: resource.MustParse("2")}))
+	})
+
+	It("overrides a template request for the same resource, as extended resources can't be overcommitted", func() {
+		container := &corev1.Container{Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{gpu: resource.MustParse("1")},
+			Limits:   corev1.ResourceList{gpu: resource.MustParse("4")},
+		}}
+		ApplyExtendedResources(container, &forgejoactionsiov1alpha1.NodePoolSpec{
+			ExtendedResources: corev1.ResourceList{gpu: resource.MustParse("2")},
+		})
+		Expect(container.Resources.Requests[gpu].Equal(resource.MustParse("2"))).To(BeTrue())
+		Expect(container.Resources.Limits[gpu].Equal(resource.MustParse("2"))).To(BeTrue())
+	})
+})
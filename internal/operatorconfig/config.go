@@ -0,0 +1,228 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package operatorconfig loads the operator-wide configuration and reloads it when the file changes
+package operatorconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// Config holds operator-wide defaults and limits
+// Fields left empty in the file keep their compiled-in defaults
+type Config struct {
+	// ListenerImage is the listener image used when an ActDeployment's listenerTemplate sets no container
+	ListenerImage string `json:"listenerImage,omitempty"`
+
+	// RunnerImage is the runner image used when neither the runnerTemplate nor the spec sets one
+	RunnerImage string `json:"runnerImage,omitempty"`
+
+	// DockerInDockerImage is the Docker-in-Docker sidecar image used when the spec sets none
+	DockerInDockerImage string `json:"dockerInDockerImage,omitempty"`
+
+	// DockerInDockerImages overrides DockerInDockerImage for runner pods pinned to a node architecture,
+	// keyed by the kubernetes.io/arch value, e.g. arm64
+	DockerInDockerImages map[string]string `json:"dockerInDockerImages,omitempty"`
+
+	// SecurityProfile is the securityProfile of runner pods whose ActDeployment sets none
+	SecurityProfile forgejoactionsiov1alpha1.SecurityProfile `json:"securityProfile,omitempty"`
+
+	// MinPollInterval is the lowest poll interval a listener may use; shorter intervals are raised to it
+	MinPollInterval *metav1.Duration `json:"minPollInterval,omitempty"`
+
+	// ForgejoAPIQPS limits the Forgejo API requests per second of each listener (0 means unlimited)
+	ForgejoAPIQPS float64 `json:"forgejoAPIQPS,omitempty"`
+
+	// ForgejoAPIBurst is the burst size for ForgejoAPIQPS
+	ForgejoAPIBurst int `json:"forgejoAPIBurst,omitempty"`
+
+	// CompletedRunnerRetention is how long finished ActRunners are kept before they are deleted
+	CompletedRunnerRetention *metav1.Duration `json:"completedRunnerRetention,omitempty"`
+}
+
+// Defaults returns the compiled-in configuration
+func Defaults() Config {
+	return Config{
+		ListenerImage:            "harbor.cloud.danmanners.com/library/farc/listener:0.1.10",
+		RunnerImage:              "harbor.cloud.danmanners.com/library/farc/act-runner:0.0.4",
+		DockerInDockerImage:      "docker.io/library/docker:29.1.3-dind-alpine3.23",
+		SecurityProfile:          forgejoactionsiov1alpha1.SecurityProfilePrivileged,
+		CompletedRunnerRetention: &metav1.Duration{Duration: 3 * time.Minute},
+	}
+}
+
+// Parse reads a YAML configuration and fills unset fields from defaults
+func Parse(data []byte, defaults Config) (Config, error) {
+	config := Config{}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse operator config: %w", err)
+	}
+
+	if config.ListenerImage == "" {
+		config.ListenerImage = defaults.ListenerImage
+	}
+	if config.RunnerImage == "" {
+		config.RunnerImage = defaults.RunnerImage
+	}
+	if config.DockerInDockerImage == "" {
+		config.DockerInDockerImage = defaults.DockerInDockerImage
+	}
+	if config.DockerInDockerImages == nil {
+		config.DockerInDockerImages = defaults.DockerInDockerImages
+	}
+	if config.SecurityProfile == "" {
+		config.SecurityProfile = defaults.SecurityProfile
+	}
+	switch config.SecurityProfile {
+	case "", forgejoactionsiov1alpha1.SecurityProfilePrivileged, forgejoactionsiov1alpha1.SecurityProfileSysbox:
+	default:
+		return Config{}, fmt.Errorf("unknown securityProfile %q, must be %s or %s", config.SecurityProfile,
+			forgejoactionsiov1alpha1.SecurityProfilePrivileged, forgejoactionsiov1alpha1.SecurityProfileSysbox)
+	}
+	if config.MinPollInterval == nil {
+		config.MinPollInterval = defaults.MinPollInterval
+	}
+	if config.ForgejoAPIQPS == 0 {
+		config.ForgejoAPIQPS = defaults.ForgejoAPIQPS
+		config.ForgejoAPIBurst = defaults.ForgejoAPIBurst
+	}
+	if config.ForgejoAPIQPS < 0 || config.ForgejoAPIBurst < 0 {
+		return Config{}, fmt.Errorf("forgejoAPIQPS and forgejoAPIBurst must not be negative")
+	}
+	if config.CompletedRunnerRetention == nil {
+		config.CompletedRunnerRetention = defaults.CompletedRunnerRetention
+	}
+	if config.CompletedRunnerRetention != nil && config.CompletedRunnerRetention.Duration < 0 {
+		return Config{}, fmt.Errorf("completedRunnerRetention must not be negative")
+	}
+	if config.ForgejoAPIQPS > 0 && config.ForgejoAPIBurst == 0 {
+		config.ForgejoAPIBurst = 1
+	}
+	return config, nil
+}
+
+// DockerInDockerImageFor returns the Docker-in-Docker image for a node architecture, empty if unknown
+func (c Config) DockerInDockerImageFor(arch string) string {
+	if image := c.DockerInDockerImages[arch]; image != "" {
+		return image
+	}
+	return c.DockerInDockerImage
+}
+
+// Store holds the current configuration and reloads it from a file
+// A nil Store serves Defaults, and one without a path serves the defaults it was created with
+type Store struct {
+	path     string
+	interval time.Duration
+	defaults Config
+
+	mu      sync.RWMutex
+	config  Config
+	content []byte
+}
+
+// NewStore loads the configuration file at path on top of defaults; an empty path serves defaults
+// The file is usually a mounted ConfigMap, which the kubelet updates in place
+func NewStore(path string, defaults Config) (*Store, error) {
+	s := &Store{path: path, interval: 10 * time.Second, defaults: defaults, config: defaults}
+	if path == "" {
+		return s, nil
+	}
+	if _, err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the current configuration
+func (s *Store) Get() Config {
+	if s == nil {
+		return Defaults()
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// reload re-reads the file and reports whether the configuration changed
+// A file that fails to parse leaves the previous configuration in place
+func (s *Store) reload() (bool, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read operator config %s: %w", s.path, err)
+	}
+
+	s.mu.RLock()
+	unchanged := s.content != nil && bytes.Equal(data, s.content)
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	config, err := Parse(data, s.defaults)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.config = config
+	s.content = data
+	s.mu.Unlock()
+	return true, nil
+}
+
+// Start polls the configuration file until the context is cancelled
+// It implements manager.Runnable so the manager runs it alongside the controllers
+func (s *Store) Start(ctx context.Context) error {
+	if s.path == "" {
+		return nil
+	}
+	logger := logf.FromContext(ctx).WithName("operator-config")
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			changed, err := s.reload()
+			if err != nil {
+				logger.Error(err, "failed to reload operator config, keeping the previous configuration")
+				continue
+			}
+			if changed {
+				logger.Info("reloaded operator config", "path", s.path)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false so every manager replica keeps its configuration current
+func (s *Store) NeedLeaderElection() bool {
+	return false
+}
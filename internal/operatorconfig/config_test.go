@@ -0,0 +1,113 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Parse", func() {
+	It("keeps the defaults for unset fields", func() {
+		config, err := Parse([]byte("listenerImage: example.com/listener:v1\n"), Defaults())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.ListenerImage).To(Equal("example.com/listener:v1"))
+		Expect(config.DockerInDockerImage).To(Equal(Defaults().DockerInDockerImage))
+	})
+
+	It("parses rate limits and durations", func() {
+		config, err := Parse([]byte("forgejoAPIQPS: 2.5\nminPollInterval: 5s\n"), Defaults())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.ForgejoAPIQPS).To(Equal(2.5))
+		Expect(config.ForgejoAPIBurst).To(Equal(1))
+		Expect(config.MinPollInterval.Duration).To(Equal(5 * time.Second))
+		Expect(config.CompletedRunnerRetention.Duration).To(Equal(3 * time.Minute))
+	})
+
+	It("rejects a negative runner retention", func() {
+		_, err := Parse([]byte("completedRunnerRetention: -1m\n"), Defaults())
+		Expect(err).To(HaveOccurred())
+
+		config, err := Parse([]byte("completedRunnerRetention: 0s\n"), Defaults())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.CompletedRunnerRetention.Duration).To(BeZero())
+	})
+
+	It("picks the Docker-in-Docker image by node architecture", func() {
+		config, err := Parse([]byte("dockerInDockerImages:\n  arm64: example.com/dind:arm64\n"), Defaults())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.DockerInDockerImageFor("arm64")).To(Equal("example.com/dind:arm64"))
+		Expect(config.DockerInDockerImageFor("amd64")).To(Equal(Defaults().DockerInDockerImage))
+		Expect(config.DockerInDockerImageFor("")).To(Equal(Defaults().DockerInDockerImage))
+	})
+
+	It("defaults to privileged DinD and accepts the sysbox profile", func() {
+		Expect(Defaults().SecurityProfile).To(Equal(forgejoactionsiov1alpha1.SecurityProfilePrivileged))
+		config, err := Parse([]byte("runnerImage: runner:v1\n"), Defaults())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.SecurityProfile).To(Equal(forgejoactionsiov1alpha1.SecurityProfilePrivileged))
+
+		config, err = Parse([]byte("securityProfile: sysbox\n"), Defaults())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.SecurityProfile).To(Equal(forgejoactionsiov1alpha1.SecurityProfileSysbox))
+
+		_, err = Parse([]byte("securityProfile: gvisor\n"), Defaults())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects unknown fields", func() {
+		_, err := Parse([]byte("listnerImage: typo\n"), Defaults())
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Store", func() {
+	It("serves the defaults when nil or without a path", func() {
+		var nilStore *Store
+		Expect(nilStore.Get()).To(Equal(Defaults()))
+
+		store, err := NewStore("", Defaults())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Get()).To(Equal(Defaults()))
+	})
+
+	It("reloads a changed file and keeps the last good configuration", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte("runnerImage: runner:v1\n"), 0o600)).To(Succeed())
+
+		store, err := NewStore(path, Defaults())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Get().RunnerImage).To(Equal("runner:v1"))
+
+		Expect(os.WriteFile(path, []byte("runnerImage: runner:v2\n"), 0o600)).To(Succeed())
+		changed, err := store.reload()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(store.Get().RunnerImage).To(Equal("runner:v2"))
+
+		Expect(os.WriteFile(path, []byte("runnerImage: [\n"), 0o600)).To(Succeed())
+		_, err = store.reload()
+		Expect(err).To(HaveOccurred())
+		Expect(store.Get().RunnerImage).To(Equal("runner:v2"))
+	})
+})
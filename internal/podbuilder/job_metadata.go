@@ -0,0 +1,127 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podbuilder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+const (
+	// jobMetadataVolumeName is the downward API volume holding the job metadata files
+	jobMetadataVolumeName = "job-metadata"
+	// jobMetadataDir is where the job metadata files are mounted in the runner container
+	jobMetadataDir = "/etc/forgejo/job"
+	// jobMetadataAnnotationPrefix prefixes the runner pod annotations the metadata files and env vars are read from
+	jobMetadataAnnotationPrefix = "job.forgejo.actions.io/"
+)
+
+// jobMetadataField is one piece of job metadata exposed to the runner container
+// It is stored in the pod annotation job.forgejo.actions.io/<file>, mounted as <jobMetadataDir>/<file>
+// and set as env var <env>; the names are documented in docs/job-metadata.md and must stay stable
+type jobMetadataField struct {
+	file  string
+	env   string
+	value func(actRunner *forgejoactionsiov1alpha1.ActRunner) string
+}
+
+var jobMetadataFields = []jobMetadataField{
+	{file: "id", env: "FORGEJO_JOB_ID", value: func(ar *forgejoactionsiov1alpha1.ActRunner) string {
+		return strconv.FormatInt(ar.Spec.ForgejoJobID, 10)
+	}},
+	{file: "name", env: "FORGEJO_JOB_NAME", value: func(ar *forgejoactionsiov1alpha1.ActRunner) string {
+		return ar.Spec.JobData.Name
+	}},
+	{file: "runs-on", env: "FORGEJO_JOB_RUNS_ON", value: func(ar *forgejoactionsiov1alpha1.ActRunner) string {
+		return strings.Join(ar.Spec.JobData.RunsOn, ",")
+	}},
+	{file: "repository-id", env: "FORGEJO_JOB_REPOSITORY_ID", value: func(ar *forgejoactionsiov1alpha1.ActRunner) string {
+		if ar.Spec.JobData.RepoID == 0 {
+			return ""
+		}
+		return strconv.FormatInt(ar.Spec.JobData.RepoID, 10)
+	}},
+	{file: "repository", env: "FORGEJO_JOB_REPOSITORY", value: func(ar *forgejoactionsiov1alpha1.ActRunner) string {
+		return ar.Status.RepositoryFullName
+	}},
+	{file: "ref", env: "FORGEJO_JOB_REF", value: func(ar *forgejoactionsiov1alpha1.ActRunner) string {
+		return ar.Status.PrettyRef
+	}},
+	{file: "event", env: "FORGEJO_JOB_EVENT", value: func(ar *forgejoactionsiov1alpha1.ActRunner) string {
+		return ar.Status.TriggerEvent
+	}},
+	{file: "trigger-user", env: "FORGEJO_JOB_TRIGGER_USER", value: func(ar *forgejoactionsiov1alpha1.ActRunner) string {
+		return ar.Status.TriggerUser
+	}},
+}
+
+// jobMetadataAnnotations returns the runner pod annotations carrying the job metadata
+// Fields whose value is not known are omitted
+func jobMetadataAnnotations(actRunner *forgejoactionsiov1alpha1.ActRunner) map[string]string {
+	annotations := make(map[string]string, len(jobMetadataFields))
+	for _, field := range jobMetadataFields {
+		if value := field.value(actRunner); value != "" {
+			annotations[jobMetadataAnnotationPrefix+field.file] = value
+		}
+	}
+	return annotations
+}
+
+// applyJobMetadata exposes the job metadata annotations to the runner container through the downward API,
+// as files in jobMetadataDir and as FORGEJO_JOB_* env vars
+// Env vars and mounts already set by the runnerTemplate are kept
+func applyJobMetadata(podSpec *corev1.PodSpec, container *corev1.Container) {
+	items := make([]corev1.DownwardAPIVolumeFile, 0, len(jobMetadataFields))
+	for _, field := range jobMetadataFields {
+		fieldPath := fmt.Sprintf("metadata.annotations['%s%s']", jobMetadataAnnotationPrefix, field.file)
+		items = append(items, corev1.DownwardAPIVolumeFile{
+			Path:     field.file,
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: fieldPath},
+		})
+		if !hasEnv(container.Env, field.env) {
+			container.Env = append(container.Env, corev1.EnvVar{
+				Name:      field.env,
+				ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: fieldPath}},
+			})
+		}
+	}
+	if !hasEnv(container.Env, "FORGEJO_JOB_METADATA_DIR") {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "FORGEJO_JOB_METADATA_DIR", Value: jobMetadataDir})
+	}
+
+	for _, volume := range podSpec.Volumes {
+		if volume.Name == jobMetadataVolumeName {
+			return
+		}
+	}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: jobMetadataVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			DownwardAPI: &corev1.DownwardAPIVolumeSource{Items: items},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      jobMetadataVolumeName,
+		MountPath: jobMetadataDir,
+		ReadOnly:  true,
+	})
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podbuilder
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Job metadata", func() {
+	newActRunner := func() *forgejoactionsiov1alpha1.ActRunner {
+		return &forgejoactionsiov1alpha1.ActRunner{
+			Spec: forgejoactionsiov1alpha1.ActRunnerSpec{
+				ForgejoJobID: 42,
+				JobData:      forgejoactionsiov1alpha1.JobData{Name: "build", RunsOn: []string{"docker", "amd64"}, RepoID: 7},
+			},
+			Status: forgejoactionsiov1alpha1.ActRunnerStatus{
+				RepositoryFullName: "acme/app",
+				PrettyRef:          "main",
+				TriggerEvent:       "push",
+				TriggerUser:        "alice",
+			},
+		}
+	}
+
+	It("stores the job metadata in pod annotations", func() {
+		Expect(jobMetadataAnnotations(newActRunner())).To(Equal(map[string]string{
+			"job.forgejo.actions.io/id":            "42",
+			"job.forgejo.actions.io/name":          "build",
+			"job.forgejo.actions.io/runs-on":       "docker,amd64",
+			"job.forgejo.actions.io/repository-id": "7",
+			"job.forgejo.actions.io/repository":    "acme/app",
+			"job.forgejo.actions.io/ref":           "main",
+			"job.forgejo.actions.io/event":         "push",
+			"job.forgejo.actions.io/trigger-user":  "alice",
+		}))
+	})
+
+	It("leaves out the metadata that is not known yet", func() {
+		actRunner := &forgejoactionsiov1alpha1.ActRunner{Spec: forgejoactionsiov1alpha1.ActRunnerSpec{ForgejoJobID: 42}}
+		Expect(jobMetadataAnnotations(actRunner)).To(Equal(map[string]string{"job.forgejo.actions.io/id": "42"}))
+	})
+
+	It("exposes every field as a file and an env var read from its annotation", func() {
+		spec := &corev1.PodSpec{}
+		container := &corev1.Container{Name: "runner"}
+		applyJobMetadata(spec, container)
+
+		Expect(spec.Volumes).To(HaveLen(1))
+		Expect(spec.Volumes[0].Name).To(Equal("job-metadata"))
+		items := spec.Volumes[0].DownwardAPI.Items
+		Expect(items).To(HaveLen(len(jobMetadataFields)))
+		Expect(items).To(ContainElement(corev1.DownwardAPIVolumeFile{
+			Path:     "trigger-user",
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations['job.forgejo.actions.io/trigger-user']"},
+		}))
+		Expect(container.VolumeMounts).To(ConsistOf(corev1.VolumeMount{Name: "job-metadata", MountPath: "/etc/forgejo/job", ReadOnly: true}))
+
+		Expect(container.Env).To(HaveLen(len(jobMetadataFields) + 1))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{
+			Name:      "FORGEJO_JOB_ID",
+			ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations['job.forgejo.actions.io/id']"}},
+		}))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "FORGEJO_JOB_METADATA_DIR", Value: "/etc/forgejo/job"}))
+	})
+
+	It("keeps the env vars and volume the runnerTemplate sets", func() {
+		templateVolume := corev1.Volume{Name: "job-metadata", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+		spec := &corev1.PodSpec{Volumes: []corev1.Volume{templateVolume}}
+		container := &corev1.Container{Name: "runner", Env: []corev1.EnvVar{
+			{Name: "FORGEJO_JOB_NAME", Value: "fixed"},
+			{Name: "FORGEJO_JOB_METADATA_DIR", Value: "/job"},
+		}}
+		applyJobMetadata(spec, container)
+
+		Expect(spec.Volumes).To(ConsistOf(templateVolume))
+		Expect(container.VolumeMounts).To(BeEmpty())
+		Expect(container.Env).To(HaveLen(len(jobMetadataFields) + 1))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "FORGEJO_JOB_NAME", Value: "fixed"}))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "FORGEJO_JOB_METADATA_DIR", Value: "/job"}))
+	})
+
+	It("annotates the runner pod and exposes the metadata to the runner and workspace init containers", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "default.actrunner.yaml"))
+		actRunner.Status.RepositoryFullName = "acme/app"
+		actRunner.Spec.WorkspaceInit = &forgejoactionsiov1alpha1.WorkspaceInitSpec{Image: "alpine/git"}
+		pod, _ := Build(actRunner, goldenConfig())
+
+		Expect(pod.Annotations).To(HaveKeyWithValue("job.forgejo.actions.io/repository", "acme/app"))
+		metadataMount := corev1.VolumeMount{Name: "job-metadata", MountPath: "/etc/forgejo/job", ReadOnly: true}
+		runner := pod.Spec.Containers[0]
+		Expect(runner.VolumeMounts).To(ContainElement(metadataMount))
+		Expect(runner.Env).To(ContainElement(HaveField("Name", "FORGEJO_JOB_REPOSITORY")))
+		initContainer := pod.Spec.InitContainers[len(pod.Spec.InitContainers)-1]
+		Expect(initContainer.VolumeMounts).To(ContainElement(metadataMount))
+		Expect(initContainer.Env).To(ContainElement(HaveField("Name", "FORGEJO_JOB_REPOSITORY")))
+	})
+})
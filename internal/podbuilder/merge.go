@@ -0,0 +1,112 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podbuilder
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// setEnv sets an env var of the container, replacing any with the same name
+func setEnv(container *corev1.Container, env corev1.EnvVar) {
+	kept := container.Env[:0]
+	for _, e := range container.Env {
+		if e.Name != env.Name {
+			kept = append(kept, e)
+		}
+	}
+	container.Env = append(kept, env)
+}
+
+// setVolume adds a volume to the pod, replacing any with the same name
+func setVolume(podSpec *corev1.PodSpec, volume corev1.Volume) {
+	kept := podSpec.Volumes[:0]
+	for _, v := range podSpec.Volumes {
+		if v.Name != volume.Name {
+			kept = append(kept, v)
+		}
+	}
+	podSpec.Volumes = append(kept, volume)
+}
+
+// setVolumeMount mounts a volume in the container, replacing mounts of the same volume or at the same path
+func setVolumeMount(container *corev1.Container, mount corev1.VolumeMount) {
+	kept := container.VolumeMounts[:0]
+	for _, m := range container.VolumeMounts {
+		if m.Name != mount.Name && m.MountPath != mount.MountPath {
+			kept = append(kept, m)
+		}
+	}
+	container.VolumeMounts = append(kept, mount)
+}
+
+// addVolume adds a volume to the pod unless one with the same name exists
+func addVolume(podSpec *corev1.PodSpec, volume corev1.Volume) {
+	for _, v := range podSpec.Volumes {
+		if v.Name == volume.Name {
+			return
+		}
+	}
+	podSpec.Volumes = append(podSpec.Volumes, *volume.DeepCopy())
+}
+
+// addVolumeMount mounts a volume in the container unless something is mounted at the path already
+func addVolumeMount(container *corev1.Container, mount corev1.VolumeMount) {
+	for _, m := range container.VolumeMounts {
+		if m.MountPath == mount.MountPath {
+			return
+		}
+	}
+	container.VolumeMounts = append(container.VolumeMounts, *mount.DeepCopy())
+}
+
+// removeContainer drops the containers with the given name from the pod
+func removeContainer(podSpec *corev1.PodSpec, name string) {
+	kept := podSpec.Containers[:0]
+	for _, c := range podSpec.Containers {
+		if c.Name != name {
+			kept = append(kept, c)
+		}
+	}
+	podSpec.Containers = kept
+}
+
+// removeInitContainer drops the init containers with the given name from the pod
+func removeInitContainer(podSpec *corev1.PodSpec, name string) {
+	kept := podSpec.InitContainers[:0]
+	for _, c := range podSpec.InitContainers {
+		if c.Name != name {
+			kept = append(kept, c)
+		}
+	}
+	podSpec.InitContainers = kept
+}
+
+func hasEnv(env []corev1.EnvVar, name string) bool {
+	_, ok := envValue(env, name)
+	return ok
+}
+
+// envValue returns the literal value of an environment variable and whether it is set
+// Values from a valueFrom source are returned empty
+func envValue(env []corev1.EnvVar, name string) (string, bool) {
+	for _, e := range env {
+		if e.Name == name {
+			return e.Value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podbuilder
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("addVolume", func() {
+	It("keeps a volume of the same name", func() {
+		spec := &corev1.PodSpec{Volumes: []corev1.Volume{
+			{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		}}
+		addVolume(spec, corev1.Volume{Name: "cache", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/cache"}}})
+		Expect(spec.Volumes).To(HaveLen(1))
+		Expect(spec.Volumes[0].EmptyDir).NotTo(BeNil())
+
+		addVolume(spec, corev1.Volume{Name: "ca-bundle"})
+		Expect(spec.Volumes).To(HaveLen(2))
+	})
+})
+
+var _ = Describe("addVolumeMount", func() {
+	It("keeps a mount at the same path", func() {
+		container := &corev1.Container{VolumeMounts: []corev1.VolumeMount{{Name: "workspace", MountPath: "/workspace"}}}
+		addVolumeMount(container, corev1.VolumeMount{Name: "cache", MountPath: "/workspace"})
+		Expect(container.VolumeMounts).To(ConsistOf(corev1.VolumeMount{Name: "workspace", MountPath: "/workspace"}))
+
+		addVolumeMount(container, corev1.VolumeMount{Name: "cache", MountPath: "/cache"})
+		Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "cache", MountPath: "/cache"}))
+	})
+})
+
+var _ = Describe("extraVolumes", func() {
+	It("mounts extra volumes in the runner by default and in the listed containers otherwise", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "extra-volumes.actrunner.yaml"))
+		pod, _ := Build(actRunner, goldenConfig())
+		runner, dind := pod.Spec.Containers[0], pod.Spec.Containers[1]
+
+		caBundle := corev1.VolumeMount{Name: "ca-bundle", MountPath: "/etc/ssl/certs/corporate-ca.pem", SubPath: "ca.pem", ReadOnly: true}
+		Expect(runner.VolumeMounts).To(ContainElements(caBundle, corev1.VolumeMount{Name: "cache", MountPath: "/cache"}))
+		Expect(dind.VolumeMounts).To(ContainElement(caBundle))
+		Expect(dind.VolumeMounts).NotTo(ContainElement(HaveField("MountPath", "/cache")))
+	})
+
+	It("leaves the template's and the operator's volumes and mount paths alone", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "extra-volumes.actrunner.yaml"))
+		pod, _ := Build(actRunner, goldenConfig())
+		runner, dind := pod.Spec.Containers[0], pod.Spec.Containers[1]
+
+		// The template mounts its workspace volume and DinD its socket volume at the paths of extra mounts
+		Expect(runner.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "workspace", MountPath: "/workspace"}))
+		Expect(runner.VolumeMounts).NotTo(ContainElement(corev1.VolumeMount{Name: "cache", MountPath: "/workspace"}))
+		Expect(dind.VolumeMounts).NotTo(ContainElement(HaveField("Name", "cache")))
+
+		// The docker-socket extra volume doesn't replace the operator's socket volume
+		Expect(pod.Spec.Volumes).NotTo(ContainElement(HaveField("HostPath", Not(BeNil()))))
+	})
+
+	It("doesn't share the extra volumes with the ActRunner", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "extra-volumes.actrunner.yaml"))
+		pod, _ := Build(actRunner, goldenConfig())
+		for i := range pod.Spec.Volumes {
+			if pod.Spec.Volumes[i].Name == "cache" {
+				pod.Spec.Volumes[i].PersistentVolumeClaim.ClaimName = "changed"
+			}
+		}
+		Expect(actRunner.Spec.ExtraVolumes[1].PersistentVolumeClaim.ClaimName).To(Equal("ci-cache"))
+	})
+})
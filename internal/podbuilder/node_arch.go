@@ -0,0 +1,49 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podbuilder
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// nodeArch returns the node architecture a pod is pinned to by its nodeSelector or required node affinity,
+// empty if it may run on several
+func nodeArch(podSpec *corev1.PodSpec) string {
+	if arch := podSpec.NodeSelector[corev1.LabelArchStable]; arch != "" {
+		return arch
+	}
+	if podSpec.Affinity == nil || podSpec.Affinity.NodeAffinity == nil ||
+		podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+
+	// Terms are ORed, so every term must pin the same single architecture
+	arch := ""
+	for _, term := range podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		termArch := ""
+		for _, expr := range term.MatchExpressions {
+			if expr.Key == corev1.LabelArchStable && expr.Operator == corev1.NodeSelectorOpIn && len(expr.Values) == 1 {
+				termArch = expr.Values[0]
+			}
+		}
+		if termArch == "" || (arch != "" && termArch != arch) {
+			return ""
+		}
+		arch = termArch
+	}
+	return arch
+}
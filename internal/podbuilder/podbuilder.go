@@ -0,0 +1,543 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podbuilder renders the runner pod of an ActRunner
+//
+// The pod starts from spec.jobTemplate, the ActDeployment's runnerTemplate, and the builder merges its own
+// settings into it by these rules:
+//
+//   - Controller-owned settings replace template values of the same name: the env vars TOKEN, FORGEJO_SERVER,
+//     FORGEJO_ORG, FORGEJO_LABELS, DOCKER_HOST, DOCKER_CONFIG and the ones derived from the job, the
+//     docker-socket and docker-config volumes and mounts, the dind and workspace-init containers, the job-id,
+//     actrunner and act-deployment labels, the job metadata, safe-to-evict and image annotations
+//   - Template values win over defaults: FORGEJO_RUNNER_NAME, FORGEJO_JOB_HANDLE, FORGEJO_JOB_* env vars, the
+//     job-metadata volume, the extra and workspace volumes and mounts, terminationMessagePolicy,
+//     terminationGracePeriodSeconds, restartPolicy, dnsPolicy, runtimeClassName, the runner preStop hook and the
+//     Istio and Sysbox annotations
+//   - Everything else in the template is kept as is
+//
+// The first template container is the runner container; one is added if the template has none.
+// Build reads no cluster state, so the controller, the runner pod preview and `listener lint` render the same pod
+package podbuilder
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/operatorconfig"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/podcompliance"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/runnerlabels"
+)
+
+const (
+	// dockerSocketVolumeName is the emptyDir shared by the runner and the DinD sidecar
+	dockerSocketVolumeName = "docker-socket"
+	// dockerSocketDir is where the docker-socket volume is mounted in both containers
+	dockerSocketDir = "/var/docker"
+	// dockerHost is the DOCKER_HOST of the runner container
+	dockerHost = "unix:///var/docker/docker.sock"
+	// dockerConfigVolumeName holds the Docker config.json from spec.dockerConfigSecretRef or spec.dockerConfigMapRef
+	dockerConfigVolumeName = "docker-config"
+	// dindDaemonConfigVolumeName holds the daemon.json from spec.dindDaemonConfig
+	dindDaemonConfigVolumeName = "dind-daemon-config"
+	// dindDaemonConfigDir is where the dind-daemon-config volume is mounted in the DinD container
+	dindDaemonConfigDir = "/etc/docker/forgejo"
+	// workspaceInitContainerName is the name of the spec.workspaceInit init container
+	workspaceInitContainerName = "workspace-init"
+	// rootDockerConfigDir is where root's docker CLI looks for config.json
+	rootDockerConfigDir = "/root/.docker"
+
+	// sysboxRuntimeClassName is the RuntimeClass the Sysbox installer creates
+	sysboxRuntimeClassName = "sysbox-runc"
+	// sysboxUsernsAnnotation requests a user namespace for the pod from CRI-O
+	sysboxUsernsAnnotation = "io.kubernetes.cri-o.userns-mode"
+
+	// RunnerImageAnnotation records the runner container image on the runner pod
+	RunnerImageAnnotation = "forgejo.actions.io/runner-image"
+	// DinDImageAnnotation records the Docker-in-Docker sidecar image on the runner pod
+	DinDImageAnnotation = "forgejo.actions.io/dind-image"
+
+	// drainReserveSeconds is the part of the grace period left for the containers to shut down after draining
+	drainReserveSeconds = 10
+)
+
+// istioPodAnnotations make runner pods work with Istio sidecar injection
+var istioPodAnnotations = map[string]string{
+	// Start the runner only once the proxy is ready, so registration doesn't fail on missing network
+	"proxy.istio.io/config": `{"holdApplicationUntilProxyStarts": true}`,
+	// Keep Docker daemon API traffic of the DinD sidecar out of the proxy
+	"traffic.sidecar.istio.io/excludeOutboundPorts": "2375,2376",
+}
+
+// Build renders the runner pod for an ActRunner and returns it with the name the runner registers under
+// Violations of spec.podCompliance that can't be enforced are left for the caller to report
+func Build(actRunner *forgejoactionsiov1alpha1.ActRunner, config operatorconfig.Config) (*corev1.Pod, string) {
+	podName := fmt.Sprintf("runner-%d-%s", actRunner.Spec.ForgejoJobID, actRunner.Name)
+	if len(podName) > 63 {
+		podName = podName[:63]
+	}
+
+	// The runnerTemplate may only set pod-level fields (dnsPolicy, hostAliases, ...) without a containers section
+	podTemplate := actRunner.Spec.JobTemplate.DeepCopy()
+	spec := &podTemplate.Spec
+	if len(spec.Containers) == 0 {
+		spec.Containers = []corev1.Container{{}}
+	}
+	profile := actRunner.Spec.SecurityProfile
+	if profile == "" {
+		profile = config.SecurityProfile
+	}
+	sysbox := profile == forgejoactionsiov1alpha1.SecurityProfileSysbox
+
+	// The runner container pointer is only valid until the DinD sidecar is appended
+	runner := &spec.Containers[0]
+	runnerName := configureRunner(runner, actRunner, podName)
+	if runner.Image == "" {
+		runner.Image = config.RunnerImage
+	}
+	// Expose the job metadata annotations as files and FORGEJO_JOB_* env vars (see docs/job-metadata.md)
+	applyJobMetadata(spec, runner)
+	setEnv(runner, corev1.EnvVar{Name: "DOCKER_HOST", Value: dockerHost})
+
+	setVolume(spec, corev1.Volume{
+		Name:         dockerSocketVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	setVolumeMount(runner, corev1.VolumeMount{Name: dockerSocketVolumeName, MountPath: dockerSocketDir})
+
+	dind := dindContainer(actRunner, config.DockerInDockerImageFor(nodeArch(spec)), sysbox)
+	if sysbox && spec.RuntimeClassName == nil {
+		runtimeClass := sysboxRuntimeClassName
+		spec.RuntimeClassName = &runtimeClass
+	}
+
+	// Let an in-flight job finish within the grace period before the containers are stopped
+	if grace := actRunner.Spec.TerminationGracePeriodSeconds; grace != nil {
+		if spec.TerminationGracePeriodSeconds == nil {
+			spec.TerminationGracePeriodSeconds = grace
+		}
+		drain := drainHook(*spec.TerminationGracePeriodSeconds)
+		if runner.Lifecycle == nil {
+			runner.Lifecycle = &corev1.Lifecycle{}
+		}
+		if runner.Lifecycle.PreStop == nil {
+			runner.Lifecycle.PreStop = drain
+		}
+		dind.Lifecycle = &corev1.Lifecycle{PreStop: drain.DeepCopy()}
+	}
+
+	// Mount Docker config.json from the generated Secret or the ConfigMap where the runner user's docker CLI looks
+	// for it, and point DOCKER_CONFIG there; the DinD sidecar runs as root
+	if actRunner.Spec.DockerConfigSecretRef != nil && actRunner.Spec.DockerConfigSecretRef.Name != "" {
+		setVolume(spec, corev1.Volume{
+			Name: dockerConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: actRunner.Spec.DockerConfigSecretRef.Name,
+					Items:      []corev1.KeyToPath{{Key: corev1.DockerConfigJsonKey, Path: "config.json"}},
+				},
+			},
+		})
+		mountDockerConfig(runner, actRunner)
+		setVolumeMount(&dind, corev1.VolumeMount{Name: dockerConfigVolumeName, MountPath: rootDockerConfigDir, ReadOnly: true})
+	} else if actRunner.Spec.DockerConfigMapRef != nil && actRunner.Spec.DockerConfigMapRef.Name != "" {
+		setVolume(spec, corev1.Volume{
+			Name: dockerConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: *actRunner.Spec.DockerConfigMapRef,
+					Items:                []corev1.KeyToPath{{Key: "config.json", Path: "config.json"}},
+				},
+			},
+		})
+		mountDockerConfig(runner, actRunner)
+	}
+
+	// Mount the daemon.json the DinD daemon is started with
+	if source := actRunner.Spec.DinDDaemonConfig; source != nil {
+		setVolume(spec, dindDaemonConfigVolume(source))
+		setVolumeMount(&dind, corev1.VolumeMount{Name: dindDaemonConfigVolumeName, MountPath: dindDaemonConfigDir, ReadOnly: true})
+	}
+
+	// Add the extra volumes and mounts where the template and the settings above left room
+	for _, volume := range actRunner.Spec.ExtraVolumes {
+		addVolume(spec, volume)
+	}
+	for _, mount := range actRunner.Spec.ExtraVolumeMounts {
+		if len(mount.Containers) == 0 || slices.Contains(mount.Containers, runner.Name) {
+			addVolumeMount(runner, mount.VolumeMount)
+		}
+		if slices.Contains(mount.Containers, dind.Name) {
+			addVolumeMount(&dind, mount.VolumeMount)
+		}
+	}
+
+	// Prepare the work volume in an init container, after the template's init containers
+	if workspaceInit := actRunner.Spec.WorkspaceInit; workspaceInit != nil {
+		volumeName, mountPath := workspaceVolume(workspaceInit)
+		addVolume(spec, corev1.Volume{Name: volumeName, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}})
+		addVolumeMount(runner, corev1.VolumeMount{Name: volumeName, MountPath: mountPath})
+
+		initContainer := workspaceInitContainer(workspaceInit)
+		applyJobMetadata(spec, &initContainer)
+		addVolumeMount(&initContainer, corev1.VolumeMount{Name: jobMetadataVolumeName, MountPath: jobMetadataDir, ReadOnly: true})
+		removeInitContainer(spec, initContainer.Name)
+		spec.InitContainers = append(spec.InitContainers, initContainer)
+	}
+
+	// Add the DinD sidecar last, replacing one from the template
+	removeContainer(spec, dind.Name)
+	spec.Containers = append(spec.Containers, dind)
+
+	if spec.RestartPolicy == "" {
+		spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+
+	// Pods on the host network only resolve cluster names with ClusterFirstWithHostNet
+	if network := actRunner.Spec.Network; network != nil && network.HostNetwork {
+		spec.HostNetwork = true
+		if spec.DNSPolicy == "" {
+			spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+		}
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        podName,
+			Namespace:   actRunner.Namespace,
+			Labels:      podLabels(actRunner, podTemplate.Labels),
+			Annotations: podAnnotations(actRunner, podTemplate.Annotations, sysbox),
+			// Not taken from the runner's TypeMeta, which a status patch in the same reconcile clears
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: forgejoactionsiov1alpha1.GroupVersion.String(),
+					Kind:       "ActRunner",
+					Name:       actRunner.Name,
+					UID:        actRunner.UID,
+					Controller: func() *bool { b := true; return &b }(),
+				},
+			},
+		},
+		Spec: *spec,
+	}
+
+	// Enforce the compliance settings; violations that are left are reported by the caller
+	podcompliance.Enforce(pod, actRunner.Spec.PodCompliance)
+
+	// Record the images the defaulting above settled on, so they needn't be worked out from the specs
+	for _, container := range pod.Spec.Containers {
+		switch container.Name {
+		case "runner":
+			pod.Annotations[RunnerImageAnnotation] = container.Image
+		case "dind":
+			pod.Annotations[DinDImageAnnotation] = container.Image
+		}
+	}
+	return pod, runnerName
+}
+
+// mountDockerConfig mounts the docker-config volume in the runner container and sets DOCKER_CONFIG to it
+func mountDockerConfig(runner *corev1.Container, actRunner *forgejoactionsiov1alpha1.ActRunner) {
+	dir := dockerConfigDir(runner, actRunner)
+	setVolumeMount(runner, corev1.VolumeMount{Name: dockerConfigVolumeName, MountPath: dir, ReadOnly: true})
+	setEnv(runner, corev1.EnvVar{Name: "DOCKER_CONFIG", Value: dir})
+}
+
+// dockerConfigDir returns the directory the runner's Docker config is mounted at: spec.dockerConfigMountPath,
+// the template's DOCKER_CONFIG, $HOME/.docker of the template's HOME, or /root/.docker
+func dockerConfigDir(runner *corev1.Container, actRunner *forgejoactionsiov1alpha1.ActRunner) string {
+	if actRunner.Spec.DockerConfigMountPath != "" {
+		return actRunner.Spec.DockerConfigMountPath
+	}
+	if dir, ok := envValue(runner.Env, "DOCKER_CONFIG"); ok && dir != "" {
+		return dir
+	}
+	if home, ok := envValue(runner.Env, "HOME"); ok && home != "" {
+		return path.Join(home, ".docker")
+	}
+	return rootDockerConfigDir
+}
+
+// configureRunner sets the runner container's name, image and env, and returns the name the runner registers under
+func configureRunner(runner *corev1.Container, actRunner *forgejoactionsiov1alpha1.ActRunner, podName string) string {
+	runner.Name = "runner"
+	if actRunner.Spec.RunnerImage != "" {
+		runner.Image = actRunner.Spec.RunnerImage
+	}
+	// Fall back to the log tail as termination message, so registration errors of any runner image are reported
+	if runner.TerminationMessagePolicy == "" {
+		runner.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	}
+
+	// The registration token only reaches the runner through the secretKeyRef
+	setEnv(runner, corev1.EnvVar{
+		Name: "TOKEN",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: actRunner.Spec.RegistrationTokenSecretRef.Name},
+				Key:                  "token",
+			},
+		},
+	})
+	setEnv(runner, corev1.EnvVar{Name: "FORGEJO_SERVER", Value: actRunner.Spec.ForgejoServer})
+	setEnv(runner, corev1.EnvVar{Name: "FORGEJO_ORG", Value: actRunner.Spec.Organization})
+	// Registration labels, or the job's runs-on with the schema and default container of the matching label definitions
+	setEnv(runner, corev1.EnvVar{
+		Name:  "FORGEJO_LABELS",
+		Value: runnerlabels.ForRunner(actRunner.Spec.RunnerLabels, actRunner.Spec.RegistrationLabels, actRunner.Spec.JobData.RunsOn),
+	})
+
+	// Register the runner under the workload name, so it can be found in Forgejo's runner list
+	runnerName := podName
+	if value, ok := envValue(runner.Env, "FORGEJO_RUNNER_NAME"); ok {
+		runnerName = value
+	} else {
+		runner.Env = append(runner.Env, corev1.EnvVar{Name: "FORGEJO_RUNNER_NAME", Value: runnerName})
+	}
+
+	// Pin the runner to the job it was created for when Forgejo reports a job handle. Setting
+	// FORGEJO_JOB_HANDLE in the runnerTemplate (even to "") overrides this
+	if !hasEnv(runner.Env, "FORGEJO_JOB_HANDLE") && actRunner.Spec.JobData.Handle != "" {
+		runner.Env = append(runner.Env, corev1.EnvVar{Name: "FORGEJO_JOB_HANDLE", Value: actRunner.Spec.JobData.Handle})
+	}
+
+	// The runner image's startup script stops the runner if no task arrives within the idle timeout
+	if actRunner.Spec.IdleTimeout != nil && actRunner.Spec.IdleTimeout.Duration > 0 {
+		setEnv(runner, corev1.EnvVar{Name: "FORGEJO_IDLE_TIMEOUT", Value: strconv.Itoa(int(actRunner.Spec.IdleTimeout.Seconds()))})
+	}
+	// In an Istio mesh, the runner image's startup script stops the envoy sidecar when the runner exits
+	if isIstioMesh(actRunner) {
+		setEnv(runner, corev1.EnvVar{Name: "ISTIO_QUIT_ON_EXIT", Value: "true"})
+	}
+
+	for _, env := range []corev1.EnvVar{
+		{Name: "FORGEJO_REPOSITORY", Value: actRunner.Status.RepositoryFullName},
+		{Name: "FORGEJO_TRIGGER_USER", Value: actRunner.Status.TriggerUser},
+		{Name: "FORGEJO_REF", Value: actRunner.Status.PrettyRef},
+		{Name: "FORGEJO_TRIGGER_EVENT", Value: actRunner.Status.TriggerEvent},
+	} {
+		if env.Value != "" {
+			setEnv(runner, env)
+		}
+	}
+	return runnerName
+}
+
+// dindContainer returns the Docker-in-Docker sidecar serving the runner through the docker-socket volume
+func dindContainer(actRunner *forgejoactionsiov1alpha1.ActRunner, defaultImage string, sysbox bool) corev1.Container {
+	image := actRunner.Spec.DockerInDockerImage
+	if image == "" {
+		image = defaultImage
+	}
+
+	// Sysbox virtualizes the container, so dockerd runs unprivileged and can use overlay2 instead of vfs
+	storageDriver := "vfs"
+	securityContext := &corev1.SecurityContext{
+		Privileged: func() *bool { b := true; return &b }(),
+	}
+	if sysbox {
+		storageDriver = "overlay2"
+		securityContext = nil
+	}
+
+	// Point the DinD daemon at internal registry mirrors (air-gapped ActDeployments)
+	dockerdArgs := "--host=" + dockerHost + " --storage-driver=" + storageDriver
+	for _, mirror := range actRunner.Spec.RegistryMirrors {
+		dockerdArgs += " --registry-mirror=" + mirror
+	}
+	dockerdArgs += dockerdNetworkArgs(actRunner.Spec.Network)
+	if actRunner.Spec.DinDDaemonConfig != nil {
+		dockerdArgs += " --config-file=" + dindDaemonConfigDir + "/daemon.json"
+	}
+
+	return corev1.Container{
+		Name:            "dind",
+		Image:           image,
+		SecurityContext: securityContext,
+		Env:             []corev1.EnvVar{{Name: "DOCKER_TLS_CERTDIR", Value: ""}},
+		Command:         []string{"/bin/sh"},
+		Args: []string{
+			"-c",
+			// Start dockerd in the background, wait for the socket and open it up for the runner user,
+			// whose docker group GID may differ from the sidecar's
+			"dockerd " + dockerdArgs + " & " +
+				"DOCKER_PID=$! && " +
+				"until [ -S /var/docker/docker.sock ]; do sleep 0.1; done && " +
+				"chmod 666 /var/docker/docker.sock && " +
+				"wait $DOCKER_PID",
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: dockerSocketVolumeName, MountPath: dockerSocketDir}},
+	}
+}
+
+// workspaceVolume returns the name and mount path of the volume spec.workspaceInit populates
+func workspaceVolume(workspaceInit *forgejoactionsiov1alpha1.WorkspaceInitSpec) (string, string) {
+	volumeName, mountPath := workspaceInit.VolumeName, workspaceInit.MountPath
+	if volumeName == "" {
+		volumeName = "workspace"
+	}
+	if mountPath == "" {
+		mountPath = "/workspace"
+	}
+	return volumeName, mountPath
+}
+
+// workspaceInitContainer returns the init container of spec.workspaceInit with the work volume mounted
+func workspaceInitContainer(workspaceInit *forgejoactionsiov1alpha1.WorkspaceInitSpec) corev1.Container {
+	volumeName, mountPath := workspaceVolume(workspaceInit)
+	container := corev1.Container{
+		Name:         workspaceInitContainerName,
+		Image:        workspaceInit.Image,
+		Command:      slices.Clone(workspaceInit.Command),
+		Args:         slices.Clone(workspaceInit.Args),
+		VolumeMounts: []corev1.VolumeMount{{Name: volumeName, MountPath: mountPath}},
+		// Report clone and fetch errors in the pod status like the runner's registration errors
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	}
+	for _, env := range workspaceInit.Env {
+		container.Env = append(container.Env, *env.DeepCopy())
+	}
+	if workspaceInit.Resources != nil {
+		container.Resources = *workspaceInit.Resources.DeepCopy()
+	}
+	return container
+}
+
+// dindDaemonConfigVolume returns the volume projecting the daemon.json of the ConfigMap or Secret as daemon.json
+func dindDaemonConfigVolume(source *forgejoactionsiov1alpha1.DinDDaemonConfigSource) corev1.Volume {
+	key := source.Key
+	if key == "" {
+		key = "daemon.json"
+	}
+	items := []corev1.KeyToPath{{Key: key, Path: "daemon.json"}}
+
+	volume := corev1.Volume{Name: dindDaemonConfigVolumeName}
+	if source.SecretRef != nil {
+		volume.Secret = &corev1.SecretVolumeSource{SecretName: source.SecretRef.Name, Items: items}
+	} else if source.ConfigMapRef != nil {
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{LocalObjectReference: *source.ConfigMapRef, Items: items}
+	}
+	return volume
+}
+
+// dockerdNetworkArgs returns the dockerd flags of spec.network's bridge, IPv6 and TCP listener settings
+func dockerdNetworkArgs(network *forgejoactionsiov1alpha1.RunnerNetworkSpec) string {
+	if network == nil {
+		return ""
+	}
+	var args string
+	if network.DinDTCPPort != 0 {
+		// dockerd refuses an unauthenticated TCP listener unless TLS is explicitly off
+		args += fmt.Sprintf(" --host=tcp://127.0.0.1:%d --tls=false", network.DinDTCPPort)
+	}
+	if network.BridgeIP != "" {
+		args += " --bip=" + network.BridgeIP
+	}
+	if network.MTU != 0 {
+		args += fmt.Sprintf(" --mtu=%d", network.MTU)
+	}
+	for _, pool := range network.DefaultAddressPools {
+		args += fmt.Sprintf(" --default-address-pool=base=%s,size=%d", pool.Base, pool.Size)
+	}
+	if network.IPv6 {
+		args += " --ipv6"
+		if network.FixedCIDRv6 != "" {
+			args += " --fixed-cidr-v6=" + network.FixedCIDRv6
+		}
+	}
+	return args
+}
+
+// podLabels returns the template labels with the controller-managed labels set
+func podLabels(actRunner *forgejoactionsiov1alpha1.ActRunner, template map[string]string) map[string]string {
+	labels := make(map[string]string, len(template)+3)
+	for k, v := range template {
+		labels[k] = v
+	}
+	labels["forgejo.actions.io/job-id"] = strconv.FormatInt(actRunner.Spec.ForgejoJobID, 10)
+	labels["forgejo.actions.io/actrunner"] = actRunner.Name
+	// The act-deployment label lets the ActDeployment's PodDisruptionBudget select its runner pods
+	if deploymentName := actDeploymentName(actRunner); deploymentName != "" {
+		labels["forgejo.actions.io/act-deployment"] = deploymentName
+	}
+	return labels
+}
+
+// podAnnotations returns the template annotations with the Istio, Sysbox, disruption and job metadata annotations
+func podAnnotations(actRunner *forgejoactionsiov1alpha1.ActRunner, template map[string]string, sysbox bool) map[string]string {
+	annotations := map[string]string{}
+	for k, v := range template {
+		annotations[k] = v
+	}
+	if isIstioMesh(actRunner) {
+		for k, v := range istioPodAnnotations {
+			if _, exists := annotations[k]; !exists {
+				annotations[k] = v
+			}
+		}
+	}
+	// CRI-O only runs sysbox pods in a user namespace with this annotation
+	if sysbox {
+		if _, exists := annotations[sysboxUsernsAnnotation]; !exists {
+			annotations[sysboxUsernsAnnotation] = "auto:size=65536"
+		}
+	}
+	if actRunner.Spec.DisruptionProtection != nil && actRunner.Spec.DisruptionProtection.SafeToEvict != nil {
+		annotations["cluster-autoscaler.kubernetes.io/safe-to-evict"] = strconv.FormatBool(*actRunner.Spec.DisruptionProtection.SafeToEvict)
+	}
+	for k, v := range jobMetadataAnnotations(actRunner) {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+func isIstioMesh(actRunner *forgejoactionsiov1alpha1.ActRunner) bool {
+	return actRunner.Spec.Mesh != nil && actRunner.Spec.Mesh.Istio
+}
+
+// actDeploymentName returns the name of the ActDeployment that created the ActRunner
+// It prefers the act-deployment label and falls back to the ActDeployment owner reference
+func actDeploymentName(actRunner *forgejoactionsiov1alpha1.ActRunner) string {
+	if name := actRunner.Labels["forgejo.actions.io/act-deployment"]; name != "" {
+		return name
+	}
+	for _, ownerRef := range actRunner.OwnerReferences {
+		if ownerRef.Kind == "ActDeployment" {
+			return ownerRef.Name
+		}
+	}
+	return ""
+}
+
+// drainHook returns a preStop hook waiting until the job's containers in the DinD daemon are gone
+// It gives up drainReserveSeconds before the grace period ends, and right away if the daemon is unreachable
+func drainHook(gracePeriodSeconds int64) *corev1.LifecycleHandler {
+	wait := max(gracePeriodSeconds-drainReserveSeconds, 0)
+	return &corev1.LifecycleHandler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"/bin/sh", "-c", fmt.Sprintf(
+				`end=$(( $(date +%%s) + %d )); `+
+					`while [ "$(date +%%s)" -lt "$end" ] && [ -n "$(docker -H unix:///var/docker/docker.sock ps -q 2>/dev/null)" ]; do sleep 2; done`,
+				wait)},
+		},
+	}
+}
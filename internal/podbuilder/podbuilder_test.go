@@ -0,0 +1,372 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podbuilder
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/operatorconfig"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/podlint"
+)
+
+// update rewrites the golden files: go test ./internal/podbuilder -args -update
+var update = flag.Bool("update", false, "rewrite the golden pods in testdata")
+
+func goldenConfig() operatorconfig.Config {
+	config := operatorconfig.Defaults()
+	config.DockerInDockerImages = map[string]string{"arm64": "registry.example.com/docker:dind-arm64"}
+	return config
+}
+
+func readActRunner(path string) *forgejoactionsiov1alpha1.ActRunner {
+	data, err := os.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+	actRunner := &forgejoactionsiov1alpha1.ActRunner{}
+	Expect(yaml.UnmarshalStrict(data, actRunner)).To(Succeed())
+	return actRunner
+}
+
+var _ = Describe("Build", func() {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.actrunner.yaml"))
+	if err != nil || len(inputs) == 0 {
+		panic("no golden test inputs in testdata")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".actrunner.yaml")
+		golden := filepath.Join("testdata", name+".pod.yaml")
+
+		It("renders the "+name+" runner pod", func() {
+			pod, _ := Build(readActRunner(input), goldenConfig())
+			pod.APIVersion = "v1"
+			pod.Kind = "Pod"
+			rendered, err := yaml.Marshal(pod)
+			Expect(err).NotTo(HaveOccurred())
+
+			if *update {
+				Expect(os.WriteFile(golden, rendered, 0o644)).To(Succeed())
+			}
+			expected, err := os.ReadFile(golden)
+			Expect(err).NotTo(HaveOccurred(), "run go test with -args -update to create the golden file")
+			Expect(string(rendered)).To(Equal(string(expected)))
+		})
+
+		It("renders a lint-clean "+name+" runner pod", func() {
+			pod, _ := Build(readActRunner(input), goldenConfig())
+			Expect(podlint.Check(pod)).To(BeEmpty())
+		})
+	}
+
+	It("makes the runner the pod's controller without relying on its TypeMeta", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "default.actrunner.yaml"))
+		actRunner.TypeMeta = metav1.TypeMeta{}
+		pod, _ := Build(actRunner, goldenConfig())
+		Expect(metav1.GetControllerOf(pod)).To(HaveField("Kind", "ActRunner"))
+		Expect(metav1.GetControllerOf(pod)).To(HaveField("APIVersion", forgejoactionsiov1alpha1.GroupVersion.String()))
+	})
+
+	It("registers the runner under the pod name unless the template names it", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "default.actrunner.yaml"))
+		pod, runnerName := Build(actRunner, goldenConfig())
+		Expect(runnerName).To(Equal(pod.Name))
+
+		actRunner = readActRunner(filepath.Join("testdata", "template-overrides.actrunner.yaml"))
+		_, runnerName = Build(actRunner, goldenConfig())
+		Expect(runnerName).To(Equal("custom-runner"))
+	})
+
+	It("uses the operator's securityProfile and runner image unless the ActRunner sets them", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "default.actrunner.yaml"))
+		actRunner.Spec.RunnerImage = ""
+		config := goldenConfig()
+		config.SecurityProfile = forgejoactionsiov1alpha1.SecurityProfileSysbox
+		pod, _ := Build(actRunner, config)
+		Expect(pod.Spec.RuntimeClassName).To(HaveValue(Equal("sysbox-runc")))
+		Expect(pod.Spec.Containers[0].Image).To(Equal(config.RunnerImage))
+
+		actRunner.Spec.SecurityProfile = forgejoactionsiov1alpha1.SecurityProfilePrivileged
+		pod, _ = Build(actRunner, config)
+		Expect(pod.Spec.RuntimeClassName).To(BeNil())
+		Expect(*pod.Spec.Containers[1].SecurityContext.Privileged).To(BeTrue())
+	})
+
+	It("does not modify the ActRunner", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "template-overrides.actrunner.yaml"))
+		original := actRunner.DeepCopy()
+		Build(actRunner, goldenConfig())
+		Expect(actRunner).To(Equal(original))
+	})
+
+	It("adds the managed env vars and mounts once when building from a built pod's spec", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "default.actrunner.yaml"))
+		pod, _ := Build(actRunner, goldenConfig())
+		actRunner.Spec.JobTemplate.Spec = pod.Spec
+		rebuilt, _ := Build(actRunner, goldenConfig())
+
+		Expect(rebuilt.Spec.Containers).To(HaveLen(2))
+		Expect(rebuilt.Spec.Volumes).To(HaveLen(len(pod.Spec.Volumes)))
+		Expect(rebuilt.Spec.Containers[0].Env).To(ConsistOf(pod.Spec.Containers[0].Env))
+		Expect(rebuilt.Spec.Containers[0].VolumeMounts).To(ConsistOf(pod.Spec.Containers[0].VolumeMounts))
+		Expect(podlint.Check(rebuilt)).To(BeEmpty())
+	})
+	It("runs host network pods with the cluster DNS unless the template sets a DNS policy", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "network.actrunner.yaml"))
+		pod, _ := Build(actRunner, goldenConfig())
+		Expect(pod.Spec.HostNetwork).To(BeTrue())
+		Expect(pod.Spec.DNSPolicy).To(Equal(corev1.DNSClusterFirstWithHostNet))
+
+		actRunner.Spec.JobTemplate.Spec.DNSPolicy = corev1.DNSDefault
+		pod, _ = Build(actRunner, goldenConfig())
+		Expect(pod.Spec.HostNetwork).To(BeTrue())
+		Expect(pod.Spec.DNSPolicy).To(Equal(corev1.DNSDefault))
+
+		actRunner.Spec.Network.HostNetwork = false
+		actRunner.Spec.JobTemplate.Spec.DNSPolicy = ""
+		pod, _ = Build(actRunner, goldenConfig())
+		Expect(pod.Spec.HostNetwork).To(BeFalse())
+		Expect(pod.Spec.DNSPolicy).To(BeEmpty())
+	})
+
+	It("passes the network settings to the DinD daemon", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "network.actrunner.yaml"))
+		pod, _ := Build(actRunner, goldenConfig())
+		Expect(pod.Spec.Containers[1].Name).To(Equal("dind"))
+		Expect(pod.Spec.Containers[1].Args[1]).To(ContainSubstring(dockerdNetworkArgs(actRunner.Spec.Network) + " & "))
+	})
+})
+
+var _ = Describe("dockerdNetworkArgs", func() {
+	DescribeTable("renders the dockerd flags of the runner network",
+		func(network *forgejoactionsiov1alpha1.RunnerNetworkSpec, expected string) {
+			Expect(dockerdNetworkArgs(network)).To(Equal(expected))
+		},
+		Entry("without a network", nil, ""),
+		Entry("with the defaults", &forgejoactionsiov1alpha1.RunnerNetworkSpec{}, ""),
+		Entry("on the host network", &forgejoactionsiov1alpha1.RunnerNetworkSpec{HostNetwork: true}, ""),
+		Entry("with IPv6", &forgejoactionsiov1alpha1.RunnerNetworkSpec{IPv6: true}, " --ipv6"),
+		Entry("with IPv6 and a fixed IPv6 subnet",
+			&forgejoactionsiov1alpha1.RunnerNetworkSpec{IPv6: true, FixedCIDRv6: "fd00:dead:beef::/64"},
+			" --ipv6 --fixed-cidr-v6=fd00:dead:beef::/64"),
+		Entry("with a fixed IPv6 subnet but no IPv6",
+			&forgejoactionsiov1alpha1.RunnerNetworkSpec{FixedCIDRv6: "fd00:dead:beef::/64"}, ""),
+		Entry("with a bridge IP", &forgejoactionsiov1alpha1.RunnerNetworkSpec{BridgeIP: "172.31.0.1/24"}, " --bip=172.31.0.1/24"),
+		Entry("with default address pools",
+			&forgejoactionsiov1alpha1.RunnerNetworkSpec{DefaultAddressPools: []forgejoactionsiov1alpha1.DinDAddressPool{
+				{Base: "172.30.0.0/16", Size: 24},
+				{Base: "fd01::/48", Size: 64},
+			}},
+			" --default-address-pool=base=172.30.0.0/16,size=24 --default-address-pool=base=fd01::/48,size=64"),
+		Entry("with an MTU", &forgejoactionsiov1alpha1.RunnerNetworkSpec{MTU: 1450}, " --mtu=1450"),
+		Entry("with a TCP port", &forgejoactionsiov1alpha1.RunnerNetworkSpec{DinDTCPPort: 2375},
+			" --host=tcp://127.0.0.1:2375 --tls=false"),
+		Entry("with everything",
+			&forgejoactionsiov1alpha1.RunnerNetworkSpec{
+				IPv6:                true,
+				FixedCIDRv6:         "fd00:dead:beef::/64",
+				BridgeIP:            "172.31.0.1/24",
+				DefaultAddressPools: []forgejoactionsiov1alpha1.DinDAddressPool{{Base: "172.30.0.0/16", Size: 24}},
+				MTU:                 1450,
+				DinDTCPPort:         2375,
+			},
+			" --host=tcp://127.0.0.1:2375 --tls=false --bip=172.31.0.1/24 --mtu=1450"+
+				" --default-address-pool=base=172.30.0.0/16,size=24 --ipv6 --fixed-cidr-v6=fd00:dead:beef::/64"),
+	)
+})
+
+var _ = Describe("dindDaemonConfig", func() {
+	DescribeTable("projects the daemon.json of the ConfigMap or Secret",
+		func(source *forgejoactionsiov1alpha1.DinDDaemonConfigSource, expected corev1.VolumeSource) {
+			volume := dindDaemonConfigVolume(source)
+			Expect(volume.Name).To(Equal(dindDaemonConfigVolumeName))
+			Expect(volume.VolumeSource).To(Equal(expected))
+		},
+		Entry("from a ConfigMap under the default key",
+			&forgejoactionsiov1alpha1.DinDDaemonConfigSource{ConfigMapRef: &corev1.LocalObjectReference{Name: "dind"}},
+			corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "dind"},
+				Items:                []corev1.KeyToPath{{Key: "daemon.json", Path: "daemon.json"}},
+			}}),
+		Entry("from a Secret under a custom key",
+			&forgejoactionsiov1alpha1.DinDDaemonConfigSource{SecretRef: &corev1.LocalObjectReference{Name: "dind"}, Key: "daemon-ci.json"},
+			corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+				SecretName: "dind",
+				Items:      []corev1.KeyToPath{{Key: "daemon-ci.json", Path: "daemon.json"}},
+			}}),
+	)
+
+	It("starts the DinD daemon with the mounted daemon.json", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "daemon-config.actrunner.yaml"))
+		pod, _ := Build(actRunner, goldenConfig())
+		runner, dind := pod.Spec.Containers[0], pod.Spec.Containers[1]
+		Expect(pod.Spec.Volumes).To(ContainElement(HaveField("Name", dindDaemonConfigVolumeName)))
+		Expect(dind.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: dindDaemonConfigVolumeName, MountPath: dindDaemonConfigDir, ReadOnly: true}))
+		Expect(dind.Args[1]).To(ContainSubstring(" --config-file=" + dindDaemonConfigDir + "/daemon.json"))
+		Expect(runner.VolumeMounts).NotTo(ContainElement(HaveField("Name", dindDaemonConfigVolumeName)))
+
+		actRunner.Spec.DinDDaemonConfig = nil
+		pod, _ = Build(actRunner, goldenConfig())
+		Expect(pod.Spec.Volumes).NotTo(ContainElement(HaveField("Name", dindDaemonConfigVolumeName)))
+		Expect(pod.Spec.Containers[1].Args[1]).NotTo(ContainSubstring("--config-file"))
+	})
+
+	It("replaces a template volume of the same name", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "daemon-config.actrunner.yaml"))
+		actRunner.Spec.JobTemplate.Spec.Volumes = []corev1.Volume{
+			{Name: dindDaemonConfigVolumeName, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		}
+		pod, _ := Build(actRunner, goldenConfig())
+		Expect(pod.Spec.Volumes).To(ContainElement(dindDaemonConfigVolume(actRunner.Spec.DinDDaemonConfig)))
+		Expect(pod.Spec.Volumes).NotTo(ContainElement(actRunner.Spec.JobTemplate.Spec.Volumes[0]))
+	})
+})
+
+var _ = Describe("workspaceInit", func() {
+	It("defaults the volume and mount path", func() {
+		volumeName, mountPath := workspaceVolume(&forgejoactionsiov1alpha1.WorkspaceInitSpec{})
+		Expect(volumeName).To(Equal("workspace"))
+		Expect(mountPath).To(Equal("/workspace"))
+
+		volumeName, mountPath = workspaceVolume(&forgejoactionsiov1alpha1.WorkspaceInitSpec{VolumeName: "cache", MountPath: "/cache"})
+		Expect(volumeName).To(Equal("cache"))
+		Expect(mountPath).To(Equal("/cache"))
+	})
+
+	It("copies the command, env and resources into the init container", func() {
+		workspaceInit := &forgejoactionsiov1alpha1.WorkspaceInitSpec{
+			Image:     "alpine/git:2.47",
+			Command:   []string{"/bin/sh", "-c"},
+			Args:      []string{"git clone --mirror $REPO /cache/mirror"},
+			Env:       []corev1.EnvVar{{Name: "GIT_TERMINAL_PROMPT", Value: "0"}},
+			Resources: &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+			MountPath: "/cache",
+		}
+		container := workspaceInitContainer(workspaceInit)
+		Expect(container.Name).To(Equal(workspaceInitContainerName))
+		Expect(container.Image).To(Equal("alpine/git:2.47"))
+		Expect(container.Command).To(Equal(workspaceInit.Command))
+		Expect(container.Args).To(Equal(workspaceInit.Args))
+		Expect(container.Env).To(Equal(workspaceInit.Env))
+		Expect(container.Resources).To(Equal(*workspaceInit.Resources))
+		Expect(container.VolumeMounts).To(ConsistOf(corev1.VolumeMount{Name: "workspace", MountPath: "/cache"}))
+		Expect(container.TerminationMessagePolicy).To(Equal(corev1.TerminationMessageFallbackToLogsOnError))
+
+		By("not sharing the slices and resources of the spec")
+		container.Command[0] = "/bin/bash"
+		container.Env[0].Value = "1"
+		container.Resources.Requests[corev1.ResourceCPU] = resource.MustParse("1")
+		Expect(workspaceInit.Command[0]).To(Equal("/bin/sh"))
+		Expect(workspaceInit.Env[0].Value).To(Equal("0"))
+		Expect(workspaceInit.Resources.Requests.Cpu().String()).To(Equal("500m"))
+	})
+
+	It("runs after the template's init containers and before the runner and DinD containers", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "workspace-init.actrunner.yaml"))
+		actRunner.Spec.JobTemplate.Spec.InitContainers = []corev1.Container{
+			{Name: "certificates", Image: "certificates:test"},
+			{Name: workspaceInitContainerName, Image: "outdated:test"},
+		}
+		pod, _ := Build(actRunner, goldenConfig())
+
+		Expect(pod.Spec.InitContainers).To(HaveLen(2))
+		Expect(pod.Spec.InitContainers[0].Name).To(Equal("certificates"))
+		Expect(pod.Spec.InitContainers[1].Name).To(Equal(workspaceInitContainerName))
+		Expect(pod.Spec.InitContainers[1].Image).To(Equal(actRunner.Spec.WorkspaceInit.Image))
+		Expect(pod.Spec.Containers).To(HaveLen(2))
+		Expect(pod.Spec.Containers[0].Name).To(Equal("runner"))
+		Expect(pod.Spec.Containers[1].Name).To(Equal("dind"))
+	})
+
+	It("mounts the work volume in the runner and only adds an emptyDir if the pod has no such volume", func() {
+		actRunner := readActRunner(filepath.Join("testdata", "workspace-init.actrunner.yaml"))
+		pod, _ := Build(actRunner, goldenConfig())
+		Expect(pod.Spec.Volumes).To(ContainElement(corev1.Volume{
+			Name: "workspace", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "workspace", MountPath: "/workspace"}))
+
+		cache := corev1.Volume{
+			Name:         "cache",
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "mirror-cache"}},
+		}
+		actRunner.Spec.ExtraVolumes = []corev1.Volume{cache}
+		actRunner.Spec.WorkspaceInit.VolumeName = "cache"
+		actRunner.Spec.WorkspaceInit.MountPath = "/cache"
+		pod, _ = Build(actRunner, goldenConfig())
+		Expect(pod.Spec.Volumes).To(ContainElement(cache))
+		Expect(pod.Spec.Volumes).NotTo(ContainElement(HaveField("Name", "workspace")))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "cache", MountPath: "/cache"}))
+		Expect(pod.Spec.InitContainers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "cache", MountPath: "/cache"}))
+	})
+})
+
+var _ = Describe("drainHook", func() {
+	It("keeps drainReserveSeconds of the grace period for shutdown", func() {
+		Expect(drainHook(300).Exec.Command[2]).To(ContainSubstring("+ 290 ))"))
+		Expect(drainHook(5).Exec.Command[2]).To(ContainSubstring("+ 0 ))"))
+	})
+})
+
+var _ = Describe("dockerConfigDir", func() {
+	It("prefers dockerConfigMountPath, then DOCKER_CONFIG, then $HOME/.docker", func() {
+		actRunner := &forgejoactionsiov1alpha1.ActRunner{}
+		runner := &corev1.Container{}
+		Expect(dockerConfigDir(runner, actRunner)).To(Equal("/root/.docker"))
+
+		runner.Env = []corev1.EnvVar{{Name: "HOME", Value: "/home/runner/"}}
+		Expect(dockerConfigDir(runner, actRunner)).To(Equal("/home/runner/.docker"))
+
+		runner.Env = append(runner.Env, corev1.EnvVar{Name: "DOCKER_CONFIG", Value: "/data/.docker"})
+		Expect(dockerConfigDir(runner, actRunner)).To(Equal("/data/.docker"))
+
+		actRunner.Spec.DockerConfigMountPath = "/config/docker"
+		Expect(dockerConfigDir(runner, actRunner)).To(Equal("/config/docker"))
+	})
+})
+
+var _ = Describe("nodeArch", func() {
+	It("reads the nodeSelector", func() {
+		Expect(nodeArch(&corev1.PodSpec{NodeSelector: map[string]string{corev1.LabelArchStable: "arm64"}})).To(Equal("arm64"))
+	})
+
+	It("reads a required node affinity pinning a single architecture", func() {
+		term := func(values ...string) corev1.NodeSelectorTerm {
+			return corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+				{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: values},
+			}}
+		}
+		spec := func(terms ...corev1.NodeSelectorTerm) *corev1.PodSpec {
+			return &corev1.PodSpec{Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+			}}}
+		}
+		Expect(nodeArch(spec(term("arm64"), term("arm64")))).To(Equal("arm64"))
+		Expect(nodeArch(spec(term("arm64"), term("amd64")))).To(BeEmpty())
+		Expect(nodeArch(spec(term("arm64", "amd64")))).To(BeEmpty())
+		Expect(nodeArch(&corev1.PodSpec{})).To(BeEmpty())
+	})
+})
@@ -0,0 +1,142 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podcompliance enforces and checks the admission policies of spec.podCompliance on runner pods
+package podcompliance
+
+import (
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// Enforce sets the fields of the compliance security context on every container of the pod
+func Enforce(pod *corev1.Pod, spec *forgejoactionsiov1alpha1.PodComplianceSpec) {
+	if spec == nil || spec.SecurityContext == nil {
+		return
+	}
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			if containers[i].SecurityContext == nil {
+				containers[i].SecurityContext = &corev1.SecurityContext{}
+			}
+			mergeSecurityContext(containers[i].SecurityContext, spec.SecurityContext)
+		}
+	}
+}
+
+// Violations returns what of the pod violates the compliance spec, one message per violation
+func Violations(pod *corev1.Pod, spec *forgejoactionsiov1alpha1.PodComplianceSpec) []string {
+	if spec == nil {
+		return nil
+	}
+	var violations []string
+	for _, key := range spec.RequiredLabels {
+		if _, ok := pod.Labels[key]; !ok {
+			violations = append(violations, fmt.Sprintf("label %s is missing", key))
+		}
+	}
+
+	forbidden := func(field forgejoactionsiov1alpha1.PodComplianceField) bool {
+		return slices.Contains(spec.ForbiddenFields, field)
+	}
+	if forbidden(forgejoactionsiov1alpha1.PodComplianceFieldHostNetwork) && pod.Spec.HostNetwork {
+		violations = append(violations, "hostNetwork is set")
+	}
+	if forbidden(forgejoactionsiov1alpha1.PodComplianceFieldHostPID) && pod.Spec.HostPID {
+		violations = append(violations, "hostPID is set")
+	}
+	if forbidden(forgejoactionsiov1alpha1.PodComplianceFieldHostIPC) && pod.Spec.HostIPC {
+		violations = append(violations, "hostIPC is set")
+	}
+	if forbidden(forgejoactionsiov1alpha1.PodComplianceFieldHostPathVolumes) {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.HostPath != nil {
+				violations = append(violations, fmt.Sprintf("volume %s is a hostPath volume", volume.Name))
+			}
+		}
+	}
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			violations = append(violations, containerViolations(container, forbidden)...)
+		}
+	}
+	return violations
+}
+
+func containerViolations(container corev1.Container, forbidden func(forgejoactionsiov1alpha1.PodComplianceField) bool) []string {
+	var violations []string
+	if forbidden(forgejoactionsiov1alpha1.PodComplianceFieldHostPorts) {
+		for _, port := range container.Ports {
+			if port.HostPort != 0 {
+				violations = append(violations, fmt.Sprintf("container %s binds host port %d", container.Name, port.HostPort))
+			}
+		}
+	}
+	securityContext := container.SecurityContext
+	if forbidden(forgejoactionsiov1alpha1.PodComplianceFieldPrivilegedContainers) &&
+		securityContext != nil && securityContext.Privileged != nil && *securityContext.Privileged {
+		violations = append(violations, fmt.Sprintf("container %s is privileged", container.Name))
+	}
+	if forbidden(forgejoactionsiov1alpha1.PodComplianceFieldPrivilegeEscalation) &&
+		(securityContext == nil || securityContext.AllowPrivilegeEscalation == nil || *securityContext.AllowPrivilegeEscalation) {
+		violations = append(violations, fmt.Sprintf("container %s does not set allowPrivilegeEscalation to false", container.Name))
+	}
+	return violations
+}
+
+// mergeSecurityContext copies the fields the enforced security context sets onto the container's
+func mergeSecurityContext(target, enforced *corev1.SecurityContext) {
+	if enforced.Capabilities != nil {
+		target.Capabilities = enforced.Capabilities.DeepCopy()
+	}
+	if enforced.Privileged != nil {
+		target.Privileged = enforced.Privileged
+	}
+	if enforced.SELinuxOptions != nil {
+		target.SELinuxOptions = enforced.SELinuxOptions.DeepCopy()
+	}
+	if enforced.WindowsOptions != nil {
+		target.WindowsOptions = enforced.WindowsOptions.DeepCopy()
+	}
+	if enforced.RunAsUser != nil {
+		target.RunAsUser = enforced.RunAsUser
+	}
+	if enforced.RunAsGroup != nil {
+		target.RunAsGroup = enforced.RunAsGroup
+	}
+	if enforced.RunAsNonRoot != nil {
+		target.RunAsNonRoot = enforced.RunAsNonRoot
+	}
+	if enforced.ReadOnlyRootFilesystem != nil {
+		target.ReadOnlyRootFilesystem = enforced.ReadOnlyRootFilesystem
+	}
+	if enforced.AllowPrivilegeEscalation != nil {
+		target.AllowPrivilegeEscalation = enforced.AllowPrivilegeEscalation
+	}
+	if enforced.ProcMount != nil {
+		target.ProcMount = enforced.ProcMount
+	}
+	if enforced.SeccompProfile != nil {
+		target.SeccompProfile = enforced.SeccompProfile.DeepCopy()
+	}
+	if enforced.AppArmorProfile != nil {
+		target.AppArmorProfile = enforced.AppArmorProfile.DeepCopy()
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podcompliance
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+func runnerPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "ci"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "runner"},
+				{Name: "dind", SecurityContext: &corev1.SecurityContext{Privileged: ptr.To(true)}},
+			},
+			Volumes: []corev1.Volume{{Name: "cache", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/cache"}}}},
+		},
+	}
+}
+
+var _ = Describe("Pod compliance", func() {
+	It("accepts any pod without a compliance spec", func() {
+		Expect(Violations(runnerPod(), nil)).To(BeEmpty())
+	})
+
+	It("reports missing labels and forbidden fields", func() {
+		spec := &forgejoactionsiov1alpha1.PodComplianceSpec{
+			RequiredLabels: []string{"team", "cost-center"},
+			ForbiddenFields: []forgejoactionsiov1alpha1.PodComplianceField{
+				forgejoactionsiov1alpha1.PodComplianceFieldHostPathVolumes,
+				forgejoactionsiov1alpha1.PodComplianceFieldPrivilegedContainers,
+				forgejoactionsiov1alpha1.PodComplianceFieldHostNetwork,
+			},
+		}
+		Expect(Violations(runnerPod(), spec)).To(Equal([]string{
+			"label cost-center is missing",
+			"volume cache is a hostPath volume",
+			"container dind is privileged",
+		}))
+	})
+
+	It("enforces the security context on every container", func() {
+		spec := &forgejoactionsiov1alpha1.PodComplianceSpec{
+			ForbiddenFields: []forgejoactionsiov1alpha1.PodComplianceField{forgejoactionsiov1alpha1.PodComplianceFieldPrivilegeEscalation},
+			SecurityContext: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: ptr.To(false),
+				SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			},
+		}
+		pod := runnerPod()
+		pod.Spec.InitContainers = []corev1.Container{{Name: "setup"}}
+		Expect(Violations(pod, spec)).To(HaveLen(3))
+
+		Enforce(pod, spec)
+		Expect(Violations(pod, spec)).To(BeEmpty())
+		Expect(*pod.Spec.Containers[1].SecurityContext.Privileged).To(BeTrue())
+		Expect(pod.Spec.InitContainers[0].SecurityContext.SeccompProfile.Type).To(Equal(corev1.SeccompProfileTypeRuntimeDefault))
+	})
+})
@@ -0,0 +1,88 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podlint finds mistakes in rendered runner pods that the API server would reject or silently resolve
+package podlint
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Check returns one finding per problem of the pod, empty if it has none
+func Check(pod *corev1.Pod) []string {
+	var findings []string
+
+	volumes := map[string]bool{}
+	for _, volume := range pod.Spec.Volumes {
+		if volumes[volume.Name] {
+			findings = append(findings, fmt.Sprintf("volume %s is defined twice", volume.Name))
+		}
+		volumes[volume.Name] = true
+	}
+
+	names := map[string]bool{}
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			if names[container.Name] {
+				findings = append(findings, fmt.Sprintf("container %s is defined twice", container.Name))
+			}
+			names[container.Name] = true
+			findings = append(findings, containerFindings(container, volumes)...)
+		}
+	}
+	return findings
+}
+
+// containerFindings checks a single container against the pod's volumes
+func containerFindings(container corev1.Container, volumes map[string]bool) []string {
+	var findings []string
+	if container.Image == "" {
+		findings = append(findings, fmt.Sprintf("container %s has no image", container.Name))
+	}
+
+	mountPaths := map[string]bool{}
+	for _, mount := range container.VolumeMounts {
+		if !volumes[mount.Name] {
+			findings = append(findings, fmt.Sprintf("container %s mounts undefined volume %s", container.Name, mount.Name))
+		}
+		if mountPaths[mount.MountPath] {
+			findings = append(findings, fmt.Sprintf("container %s mounts two volumes at %s", container.Name, mount.MountPath))
+		}
+		mountPaths[mount.MountPath] = true
+	}
+
+	// Kubernetes accepts duplicate variables and keeps the last one, which hides overrides that don't apply
+	var env []string
+	for _, e := range container.Env {
+		if slices.Contains(env, e.Name) {
+			findings = append(findings, fmt.Sprintf("container %s sets env %s more than once", container.Name, e.Name))
+			continue
+		}
+		env = append(env, e.Name)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(container.Resources.Requests)) {
+		request := container.Resources.Requests[name]
+		if limit, ok := container.Resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			findings = append(findings, fmt.Sprintf("container %s requests more %s than its limit", container.Name, name))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podlint
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var _ = Describe("Check", func() {
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		pod = &corev1.Pod{Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{Name: "docker-socket"}},
+			Containers: []corev1.Container{
+				{
+					Name:         "runner",
+					Image:        "runner:v1",
+					Env:          []corev1.EnvVar{{Name: "DOCKER_HOST", Value: "unix:///var/docker/docker.sock"}},
+					VolumeMounts: []corev1.VolumeMount{{Name: "docker-socket", MountPath: "/var/docker"}},
+				},
+				{Name: "dind", Image: "docker:dind"},
+			},
+		}}
+	})
+
+	It("accepts a valid pod", func() {
+		Expect(Check(pod)).To(BeEmpty())
+	})
+
+	It("reports duplicate names, env vars and mount paths", func() {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{Name: "docker-socket"})
+		pod.Spec.InitContainers = []corev1.Container{{Name: "dind", Image: "busybox"}}
+		runner := &pod.Spec.Containers[0]
+		runner.Env = append(runner.Env, corev1.EnvVar{Name: "DOCKER_HOST", Value: "tcp://localhost:2375"})
+		runner.VolumeMounts = append(runner.VolumeMounts, corev1.VolumeMount{Name: "cache", MountPath: "/var/docker"})
+
+		Expect(Check(pod)).To(ConsistOf(
+			"volume docker-socket is defined twice",
+			"container dind is defined twice",
+			"container runner sets env DOCKER_HOST more than once",
+			"container runner mounts undefined volume cache",
+			"container runner mounts two volumes at /var/docker",
+		))
+	})
+
+	It("reports missing images and requests above limits", func() {
+		pod.Spec.Containers[1].Image = ""
+		pod.Spec.Containers[0].Resources = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		}
+		Expect(Check(pod)).To(ConsistOf(
+			"container dind has no image",
+			"container runner requests more memory than its limit",
+		))
+	})
+})
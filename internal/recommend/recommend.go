@@ -0,0 +1,154 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package recommend derives runner pod resource requests from the peak usage of finished runners
+package recommend
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+const (
+	// margin is added on top of the observed peaks
+	margin = 1.15
+
+	// decay is how far a lower peak pulls the recommendation down, so one light run doesn't undersize the next
+	decay = 0.2
+
+	// MaxLabelSets bounds the number of recommendations kept; the least recently updated ones are dropped
+	MaxLabelSets = 50
+
+	cpuStepMilli = 10
+	memoryStep   = 1 << 20
+)
+
+// Key identifies the label set of a job's runs-on entries
+func Key(runsOn []string) string {
+	labels := slices.Clone(runsOn)
+	slices.Sort(labels)
+	return strings.Join(slices.Compact(labels), ",")
+}
+
+// Fold updates the recommendation of the label set with the peak usage of a finished runner
+// A higher peak raises the recommendation right away, a lower one lowers it gradually
+func Fold(recommendations []forgejoactionsiov1alpha1.ResourceRecommendation, key string, peak forgejoactionsiov1alpha1.ResourceUsage, now metav1.Time) []forgejoactionsiov1alpha1.ResourceRecommendation {
+	cpu := roundUp(scale(peak.CPU.MilliValue()), cpuStepMilli)
+	memory := roundUp(scale(peak.Memory.Value()), memoryStep)
+
+	i := slices.IndexFunc(recommendations, func(r forgejoactionsiov1alpha1.ResourceRecommendation) bool { return r.Labels == key })
+	if i < 0 {
+		recommendations = append(recommendations, forgejoactionsiov1alpha1.ResourceRecommendation{
+			Labels:    key,
+			CPU:       *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+			Memory:    *resource.NewQuantity(memory, resource.BinarySI),
+			Samples:   1,
+			UpdatedAt: now,
+		})
+	} else {
+		r := &recommendations[i]
+		r.CPU = *resource.NewMilliQuantity(roundUp(follow(r.CPU.MilliValue(), cpu), cpuStepMilli), resource.DecimalSI)
+		r.Memory = *resource.NewQuantity(roundUp(follow(r.Memory.Value(), memory), memoryStep), resource.BinarySI)
+		r.Samples++
+		r.UpdatedAt = now
+	}
+
+	if len(recommendations) > MaxLabelSets {
+		sort.SliceStable(recommendations, func(a, b int) bool {
+			return recommendations[a].UpdatedAt.After(recommendations[b].UpdatedAt.Time)
+		})
+		recommendations = recommendations[:MaxLabelSets]
+	}
+	sort.SliceStable(recommendations, func(a, b int) bool { return recommendations[a].Labels < recommendations[b].Labels })
+	return recommendations
+}
+
+// MinSamples returns how many finished runners a recommendation has to be based on before it is applied
+func MinSamples(spec *forgejoactionsiov1alpha1.ResourceRecommendationsSpec) int32 {
+	if spec != nil && spec.MinSamples != nil {
+		return *spec.MinSamples
+	}
+	return 3
+}
+
+// Applied returns the recommendations new runner pods request, without the sample bookkeeping
+// It is empty unless spec.resourceRecommendations.apply is set
+func Applied(spec *forgejoactionsiov1alpha1.ResourceRecommendationsSpec, recommendations []forgejoactionsiov1alpha1.ResourceRecommendation) []forgejoactionsiov1alpha1.ResourceRecommendation {
+	if spec == nil || !spec.Apply {
+		return nil
+	}
+	minSamples := MinSamples(spec)
+	var applied []forgejoactionsiov1alpha1.ResourceRecommendation
+	for _, r := range recommendations {
+		if r.Samples >= minSamples {
+			applied = append(applied, forgejoactionsiov1alpha1.ResourceRecommendation{Labels: r.Labels, CPU: r.CPU, Memory: r.Memory})
+		}
+	}
+	return applied
+}
+
+// For returns the recommendation of the label set if it is based on at least minSamples runners
+func For(recommendations []forgejoactionsiov1alpha1.ResourceRecommendation, key string, minSamples int32) *forgejoactionsiov1alpha1.ResourceRecommendation {
+	for i := range recommendations {
+		if recommendations[i].Labels == key && recommendations[i].Samples >= minSamples {
+			return &recommendations[i]
+		}
+	}
+	return nil
+}
+
+// Apply sets the container's CPU and memory requests to the recommendation, capped at the container's limits
+func Apply(container *corev1.Container, recommendation forgejoactionsiov1alpha1.ResourceRecommendation) {
+	if container.Resources.Requests == nil {
+		container.Resources.Requests = corev1.ResourceList{}
+	}
+	for name, value := range map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceCPU:    recommendation.CPU,
+		corev1.ResourceMemory: recommendation.Memory,
+	} {
+		if limit, ok := container.Resources.Limits[name]; ok && value.Cmp(limit) > 0 {
+			value = limit
+		}
+		container.Resources.Requests[name] = value
+	}
+}
+
+// follow moves the current value to a higher target at once and towards a lower one by the decay
+func follow(current, target int64) int64 {
+	if target >= current {
+		return target
+	}
+	return current - int64(float64(current-target)*decay)
+}
+
+func scale(value int64) int64 {
+	return int64(math.Ceil(float64(value) * margin))
+}
+
+func roundUp(value, step int64) int64 {
+	if value < step {
+		return step
+	}
+	return (value + step - 1) / step * step
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommend
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+func usage(cpu, memory string) forgejoactionsiov1alpha1.ResourceUsage {
+	return forgejoactionsiov1alpha1.ResourceUsage{CPU: resource.MustParse(cpu), Memory: resource.MustParse(memory)}
+}
+
+var _ = Describe("Key", func() {
+	It("sorts and deduplicates the labels", func() {
+		Expect(Key([]string{"ubuntu-22.04", "docker", "ubuntu-22.04"})).To(Equal("docker,ubuntu-22.04"))
+	})
+})
+
+var _ = Describe("Fold", func() {
+	now := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	It("adds a margin to the first peak", func() {
+		recommendations := Fold(nil, "docker", usage("1", "1Gi"), now)
+		Expect(recommendations).To(HaveLen(1))
+		Expect(recommendations[0].CPU.String()).To(Equal("1150m"))
+		Expect(recommendations[0].Memory.String()).To(Equal("1178Mi"))
+		Expect(recommendations[0].Samples).To(Equal(int32(1)))
+	})
+
+	It("follows higher peaks at once and lower peaks gradually", func() {
+		recommendations := Fold(nil, "docker", usage("1", "1Gi"), now)
+		recommendations = Fold(recommendations, "docker", usage("2", "1Gi"), now)
+		Expect(recommendations[0].CPU.String()).To(Equal("2300m"))
+
+		recommendations = Fold(recommendations, "docker", usage("100m", "1Gi"), now)
+		Expect(recommendations[0].CPU.MilliValue()).To(BeNumerically("<", 2300))
+		Expect(recommendations[0].CPU.MilliValue()).To(BeNumerically(">", 1500))
+		Expect(recommendations[0].Samples).To(Equal(int32(3)))
+	})
+
+	It("rounds tiny peaks up to the minimum step", func() {
+		recommendations := Fold(nil, "docker", usage("1m", "100Ki"), now)
+		Expect(recommendations[0].CPU.String()).To(Equal("10m"))
+		Expect(recommendations[0].Memory.String()).To(Equal("1Mi"))
+	})
+
+	It("drops the least recently updated label sets", func() {
+		var recommendations []forgejoactionsiov1alpha1.ResourceRecommendation
+		for i := range MaxLabelSets + 1 {
+			recommendations = Fold(recommendations, fmt.Sprintf("label-%02d", i), usage("1", "1Gi"), metav1.NewTime(now.Add(time.Duration(i)*time.Minute)))
+		}
+		Expect(recommendations).To(HaveLen(MaxLabelSets))
+		Expect(For(recommendations, "label-00", 1)).To(BeNil())
+		Expect(For(recommendations, fmt.Sprintf("label-%02d", MaxLabelSets), 1)).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("For", func() {
+	It("requires the minimum number of samples", func() {
+		recommendations := Fold(nil, "docker", usage("1", "1Gi"), metav1.Now())
+		Expect(For(recommendations, "docker", 2)).To(BeNil())
+		Expect(For(recommendations, "docker", 1)).NotTo(BeNil())
+		Expect(For(recommendations, "other", 1)).To(BeNil())
+	})
+})
+
+var _ = Describe("Applied", func() {
+	now := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	recommendations := []forgejoactionsiov1alpha1.ResourceRecommendation{
+		{Labels: "docker", CPU: resource.MustParse("1"), Memory: resource.MustParse("1Gi"), Samples: 4, UpdatedAt: now},
+		{Labels: "gpu", CPU: resource.MustParse("2"), Memory: resource.MustParse("4Gi"), Samples: 1, UpdatedAt: now},
+	}
+
+	It("is empty unless recommendations are applied", func() {
+		Expect(Applied(nil, recommendations)).To(BeEmpty())
+		Expect(Applied(&forgejoactionsiov1alpha1.ResourceRecommendationsSpec{}, recommendations)).To(BeEmpty())
+	})
+
+	It("keeps the recommendations with enough samples, without the bookkeeping", func() {
+		Expect(Applied(&forgejoactionsiov1alpha1.ResourceRecommendationsSpec{Apply: true}, recommendations)).To(Equal([]forgejoactionsiov1alpha1.ResourceRecommendation{
+			{Labels: "docker", CPU: resource.MustParse("1"), Memory: resource.MustParse("1Gi")},
+		}))
+
+		minSamples := int32(1)
+		Expect(Applied(&forgejoactionsiov1alpha1.ResourceRecommendationsSpec{Apply: true, MinSamples: &minSamples}, recommendations)).To(HaveLen(2))
+	})
+})
+
+var _ = Describe("Apply", func() {
+	It("sets the requests capped at the limits", func() {
+		container := &corev1.Container{Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		}}
+		Apply(container, forgejoactionsiov1alpha1.ResourceRecommendation{CPU: resource.MustParse("1500m"), Memory: resource.MustParse("1Gi")})
+		Expect(container.Resources.Requests.Cpu().String()).To(Equal("1500m"))
+		Expect(container.Resources.Requests.Memory().String()).To(Equal("512Mi"))
+	})
+})
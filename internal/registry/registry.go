@@ -0,0 +1,286 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registry resolves image tags to digests through the OCI distribution API
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// dockerHubRegistry is the API host of Docker Hub, which image references without a registry refer to
+const dockerHubRegistry = "registry-1.docker.io"
+
+// manifestMediaTypes are accepted for manifests, so multi-arch images resolve to their index digest
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed image reference
+type Reference struct {
+	// Registry is the registry host, e.g. "ghcr.io" or "registry-1.docker.io"
+	Registry string
+	// Repository is the repository path, e.g. "library/alpine"
+	Repository string
+	// Tag is the tracked tag, "latest" if the reference has none
+	Tag string
+	// Name is the reference without tag or digest, as used in image fields
+	Name string
+}
+
+// ParseReference parses an image reference with an optional tag
+// References with a digest are rejected, as there is nothing to track
+func ParseReference(image string) (Reference, error) {
+	if image == "" || strings.Contains(image, "@") {
+		return Reference{}, fmt.Errorf("invalid image reference %q: must be a name with an optional tag", image)
+	}
+
+	name, tag := image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, tag = image[:i], image[i+1:]
+	}
+	if name == "" || tag == "" {
+		return Reference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+
+	ref := Reference{Tag: tag, Name: name}
+	host, path, found := strings.Cut(name, "/")
+	if found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		ref.Registry, ref.Repository = host, path
+	} else {
+		ref.Registry, ref.Repository = dockerHubRegistry, name
+		if !found {
+			ref.Repository = "library/" + name
+		}
+	}
+	if ref.Registry == "docker.io" || ref.Registry == "index.docker.io" {
+		ref.Registry = dockerHubRegistry
+	}
+	return ref, nil
+}
+
+// Client resolves tags against registries, anonymously or with basic credentials
+type Client struct {
+	httpClient *http.Client
+	username   string
+	password   string
+}
+
+// NewClient creates a registry client; httpClient may be nil
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{httpClient: httpClient}
+}
+
+// SetCredentials sets the credentials used for basic auth and to request bearer tokens
+func (c *Client) SetCredentials(username, password string) {
+	c.username = username
+	c.password = password
+}
+
+// Digest returns the digest the tag of the reference currently points to
+func (c *Client) Digest(ctx context.Context, ref Reference) (string, error) {
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Registry, ref.Repository, ref.Tag)
+
+	resp, err := c.request(ctx, http.MethodHead, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	// Token and basic auth are negotiated through the challenge of the first response
+	authorization := ""
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err = c.authorize(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", err
+		}
+		resp, err = c.request(ctx, http.MethodHead, manifestURL, authorization)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve %s/%s:%s: unexpected status code %d", ref.Registry, ref.Repository, ref.Tag, resp.StatusCode)
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+	// Some registries don't report the digest on HEAD requests; hash the manifest instead
+	return c.manifestDigest(ctx, manifestURL, authorization)
+}
+
+func (c *Client) request(ctx context.Context, method, url, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request %s: %w", url, err)
+	}
+	return resp, nil
+}
+
+// manifestDigest downloads the manifest and returns its sha256 digest
+func (c *Client) manifestDigest(ctx context.Context, manifestURL, authorization string) (string, error) {
+	resp, err := c.request(ctx, http.MethodGet, manifestURL, authorization)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get manifest %s: unexpected status code %d", manifestURL, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// authorize answers a WWW-Authenticate challenge with an Authorization header value
+func (c *Client) authorize(ctx context.Context, challenge string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.username == "" {
+			return "", fmt.Errorf("registry requires credentials")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password)), nil
+	case "bearer":
+		return c.bearerToken(ctx, params)
+	default:
+		return "", fmt.Errorf("unsupported registry authentication challenge %q", challenge)
+	}
+}
+
+// bearerToken requests a token from the realm of a bearer challenge
+func (c *Client) bearerToken(ctx context.Context, params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid bearer challenge realm %q", params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to request registry token: unexpected status code %d", resp.StatusCode)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode registry token: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// parseChallenge splits a WWW-Authenticate header into its scheme and parameters
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return scheme, params
+}
+
+// CredentialsFromDockerConfig returns the credentials for the registry from a .dockerconfigjson document
+func CredentialsFromDockerConfig(data []byte, registry string) (string, string, bool) {
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", "", false
+	}
+
+	hosts := []string{registry}
+	if registry == dockerHubRegistry {
+		hosts = append(hosts, "docker.io", "index.docker.io", "https://index.docker.io/v1/")
+	}
+	for key, entry := range config.Auths {
+		host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://"), "/")
+		for _, candidate := range hosts {
+			if key != candidate && host != candidate {
+				continue
+			}
+			if entry.Username != "" {
+				return entry.Username, entry.Password, true
+			}
+			if decoded, err := base64.StdEncoding.DecodeString(entry.Auth); err == nil {
+				if username, password, ok := strings.Cut(string(decoded), ":"); ok {
+					return username, password, true
+				}
+			}
+		}
+	}
+	return "", "", false
+}
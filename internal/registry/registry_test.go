@@ -0,0 +1,119 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseReference", func() {
+	DescribeTable("parses references",
+		func(image string, expected Reference) {
+			Expect(ParseReference(image)).To(Equal(expected))
+		},
+		Entry("official image", "alpine", Reference{Registry: "registry-1.docker.io", Repository: "library/alpine", Tag: "latest", Name: "alpine"}),
+		Entry("Docker Hub user image", "user/runner:v1", Reference{Registry: "registry-1.docker.io", Repository: "user/runner", Tag: "v1", Name: "user/runner"}),
+		Entry("registry with port", "localhost:5000/ci/runner:edge", Reference{Registry: "localhost:5000", Repository: "ci/runner", Tag: "edge", Name: "localhost:5000/ci/runner"}),
+		Entry("registry host", "ghcr.io/org/runner", Reference{Registry: "ghcr.io", Repository: "org/runner", Tag: "latest", Name: "ghcr.io/org/runner"}),
+		Entry("docker.io prefix", "docker.io/library/alpine:3", Reference{Registry: "registry-1.docker.io", Repository: "library/alpine", Tag: "3", Name: "docker.io/library/alpine"}),
+	)
+
+	It("rejects digests and empty references", func() {
+		_, err := ParseReference("alpine@sha256:abc")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseReference("")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Digest", func() {
+	const digest = "sha256:0123456789abcdef"
+	var server *httptest.Server
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	reference := func() Reference {
+		ref, err := ParseReference(strings.TrimPrefix(server.URL, "https://") + "/ci/runner:latest")
+		Expect(err).NotTo(HaveOccurred())
+		return ref
+	}
+
+	It("resolves a tag with a bearer token", func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/token":
+				Expect(r.URL.Query().Get("scope")).To(Equal("repository:ci/runner:pull"))
+				user, password, _ := r.BasicAuth()
+				Expect(user + ":" + password).To(Equal("bot:secret"))
+				_, _ = w.Write([]byte(`{"token":"abc"}`))
+			case r.Header.Get("Authorization") != "Bearer abc":
+				w.Header().Set("WWW-Authenticate", `Bearer realm="https://`+r.Host+`/token",service="registry",scope="repository:ci/runner:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				Expect(r.URL.Path).To(Equal("/v2/ci/runner/manifests/latest"))
+				w.Header().Set("Docker-Content-Digest", digest)
+			}
+		}))
+		client := NewClient(server.Client())
+		client.SetCredentials("bot", "secret")
+
+		Expect(client.Digest(context.Background(), reference())).To(Equal(digest))
+	})
+
+	It("hashes the manifest if the registry reports no digest", func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("{}"))
+		}))
+
+		Expect(NewClient(server.Client()).Digest(context.Background(), reference())).To(
+			Equal("sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"))
+	})
+
+	It("reports missing tags", func() {
+		server = httptest.NewTLSServer(http.NotFoundHandler())
+
+		_, err := NewClient(server.Client()).Digest(context.Background(), reference())
+		Expect(err).To(MatchError(ContainSubstring("404")))
+	})
+})
+
+var _ = Describe("CredentialsFromDockerConfig", func() {
+	It("finds credentials by registry", func() {
+		auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+		config := []byte(`{"auths":{"https://index.docker.io/v1/":{"auth":"` + auth + `"},"ghcr.io":{"username":"bot","password":"token"}}}`)
+
+		username, password, ok := CredentialsFromDockerConfig(config, "registry-1.docker.io")
+		Expect(ok).To(BeTrue())
+		Expect(username + ":" + password).To(Equal("user:pass"))
+
+		username, password, ok = CredentialsFromDockerConfig(config, "ghcr.io")
+		Expect(ok).To(BeTrue())
+		Expect(username + ":" + password).To(Equal("bot:token"))
+
+		_, _, ok = CredentialsFromDockerConfig(config, "quay.io")
+		Expect(ok).To(BeFalse())
+	})
+})
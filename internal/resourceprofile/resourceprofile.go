@@ -0,0 +1,92 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resourceprofile selects and applies the runner size a job requests with a runs-on label
+package resourceprofile
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// SelectorLabel returns the runs-on label selecting the profile
+func SelectorLabel(profile forgejoactionsiov1alpha1.ResourceProfile) string {
+	if profile.Label != "" {
+		return profile.Label
+	}
+	return "size-" + profile.Name
+}
+
+// SelectorLabels returns the profile labels as runner labels, so jobs selecting a profile are served
+func SelectorLabels(profiles []forgejoactionsiov1alpha1.ResourceProfile) []forgejoactionsiov1alpha1.RunnerLabel {
+	labels := make([]forgejoactionsiov1alpha1.RunnerLabel, 0, len(profiles))
+	for _, profile := range profiles {
+		labels = append(labels, forgejoactionsiov1alpha1.RunnerLabel{Name: SelectorLabel(profile)})
+	}
+	return labels
+}
+
+// Select returns the profile a job's runs-on entries select, or the default profile if they select none
+// With several profile labels, the profile listed first in the spec wins
+func Select(spec *forgejoactionsiov1alpha1.ActDeploymentSpec, runsOn []string) *forgejoactionsiov1alpha1.ResourceProfile {
+	for i := range spec.ResourceProfiles {
+		if slices.Contains(runsOn, SelectorLabel(spec.ResourceProfiles[i])) {
+			return &spec.ResourceProfiles[i]
+		}
+	}
+	for i := range spec.ResourceProfiles {
+		if spec.ResourceProfiles[i].Name == spec.DefaultResourceProfile {
+			return &spec.ResourceProfiles[i]
+		}
+	}
+	return nil
+}
+
+// Apply sets the profile's resources on the runner container (the first one) and its node selector and
+// tolerations on the pod
+func Apply(podSpec *corev1.PodSpec, profile *forgejoactionsiov1alpha1.ResourceProfile) {
+	if profile == nil || len(podSpec.Containers) == 0 {
+		return
+	}
+	resources := &podSpec.Containers[0].Resources
+	for name, value := range profile.Resources.Requests {
+		if resources.Requests == nil {
+			resources.Requests = corev1.ResourceList{}
+		}
+		resources.Requests[name] = value
+	}
+	for name, value := range profile.Resources.Limits {
+		if resources.Limits == nil {
+			resources.Limits = corev1.ResourceList{}
+		}
+		resources.Limits[name] = value
+	}
+
+	for key, value := range profile.NodeSelector {
+		if podSpec.NodeSelector == nil {
+			podSpec.NodeSelector = map[string]string{}
+		}
+		podSpec.NodeSelector[key] = value
+	}
+	for _, toleration := range profile.Tolerations {
+		if !slices.ContainsFunc(podSpec.Tolerations, func(t corev1.Toleration) bool { return t.MatchToleration(&toleration) }) {
+			podSpec.Tolerations = append(podSpec.Tolerations, toleration)
+		}
+	}
+}
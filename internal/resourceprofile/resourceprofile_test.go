@@ -0,0 +1,83 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceprofile
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Resource profiles", func() {
+	spec := &forgejoactionsiov1alpha1.ActDeploymentSpec{
+		ResourceProfiles: []forgejoactionsiov1alpha1.ResourceProfile{
+			{
+				Name: "small",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				},
+			},
+			{
+				Name:  "large",
+				Label: "big-box",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("8Gi")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+				},
+				NodeSelector: map[string]string{"pool": "large"},
+				Tolerations:  []corev1.Toleration{{Key: "pool", Operator: corev1.TolerationOpEqual, Value: "large", Effect: corev1.TaintEffectNoSchedule}},
+			},
+		},
+		DefaultResourceProfile: "small",
+	}
+
+	It("derives the selecting labels", func() {
+		Expect(SelectorLabels(spec.ResourceProfiles)).To(Equal([]forgejoactionsiov1alpha1.RunnerLabel{{Name: "size-small"}, {Name: "big-box"}}))
+	})
+
+	It("selects the profile by runs-on label and falls back to the default", func() {
+		Expect(Select(spec, []string{"ubuntu-22.04", "big-box"}).Name).To(Equal("large"))
+		Expect(Select(spec, []string{"ubuntu-22.04", "size-small"}).Name).To(Equal("small"))
+		Expect(Select(spec, []string{"ubuntu-22.04"}).Name).To(Equal("small"))
+		Expect(Select(&forgejoactionsiov1alpha1.ActDeploymentSpec{ResourceProfiles: spec.ResourceProfiles}, []string{"ubuntu-22.04"})).To(BeNil())
+	})
+
+	It("applies resources, node selector and tolerations", func() {
+		podSpec := &corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "runner",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceEphemeralStorage: resource.MustParse("10Gi")},
+				},
+			}},
+			NodeSelector: map[string]string{"pool": "default", "zone": "a"},
+		}
+		Apply(podSpec, Select(spec, []string{"big-box"}))
+		Apply(podSpec, Select(spec, []string{"big-box"}))
+
+		requests := podSpec.Containers[0].Resources.Requests
+		Expect(requests.Cpu().String()).To(Equal("4"))
+		Expect(requests.Memory().String()).To(Equal("8Gi"))
+		Expect(requests.StorageEphemeral().String()).To(Equal("10Gi"))
+		Expect(podSpec.Containers[0].Resources.Limits.Memory().String()).To(Equal("8Gi"))
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"pool": "large", "zone": "a"}))
+		Expect(podSpec.Tolerations).To(HaveLen(1))
+	})
+})
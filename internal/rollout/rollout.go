@@ -0,0 +1,119 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rollout identifies which runner configuration of an ActDeployment an ActRunner was created from
+package rollout
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/recommend"
+)
+
+// TemplateHashAnnotation holds the template hash of the ActDeployment configuration an ActRunner spec was built from
+const TemplateHashAnnotation = "forgejo.actions.io/runner-template-hash"
+
+// TemplateHash hashes the fields of an ActDeployment spec that shape its runners, and the resource
+// recommendations applied to them
+// The spec must have the runner and DinD image defaults applied, so the listener and the
+// controller compute the same hash for the same effective configuration
+func TemplateHash(spec *forgejoactionsiov1alpha1.ActDeploymentSpec, recommendations []forgejoactionsiov1alpha1.ResourceRecommendation) string {
+	// Left out while none are applied, so the hash of other runners stays the same
+	var applied any
+	if r := recommend.Applied(spec.ResourceRecommendations, recommendations); len(r) > 0 {
+		applied = r
+	}
+	data, err := json.Marshal(struct {
+		RunnerTemplate        any `json:"runnerTemplate"`
+		RunnerImage           any `json:"runnerImage"`
+		DockerInDockerImage   any `json:"dockerInDockerImage"`
+		DockerConfigMapRef    any `json:"dockerConfigMapRef"`
+		RegistryAuth          any `json:"registryAuth"`
+		DockerConfigMountPath any `json:"dockerConfigMountPath"`
+		Labels                any `json:"labels"`
+		RunnerLabels          any `json:"runnerLabels"`
+		RegistrationLabels    any `json:"registrationLabels"`
+		RunnerPodLabels       any `json:"runnerPodLabels"`
+		RunnerPodAnnotations  any `json:"runnerPodAnnotations"`
+		DNS                   any `json:"dns"`
+		AirGapped             any `json:"airGapped"`
+		Mesh                  any `json:"mesh"`
+		Network               any `json:"network"`
+		DinDDaemonConfig      any `json:"dindDaemonConfig"`
+		ExtraVolumes          any `json:"extraVolumes"`
+		ExtraVolumeMounts     any `json:"extraVolumeMounts"`
+		WorkspaceInit         any `json:"workspaceInit"`
+		SecurityProfile       any `json:"securityProfile"`
+		Scheduling            any `json:"scheduling"`
+		DisruptionProtection  any `json:"disruptionProtection"`
+		NodePool              any `json:"nodePool"`
+		DedicatedNodes        any `json:"dedicatedNodes"`
+		IdleTimeout           any `json:"idleTimeout"`
+		Backend               any `json:"backend"`
+		VirtualMachine        any `json:"virtualMachine"`
+		Priority              any `json:"priority"`
+		TerminationGrace      any `json:"terminationGracePeriodSeconds"`
+		ResourceProfiles      any `json:"resourceProfiles"`
+		DefaultProfile        any `json:"defaultResourceProfile"`
+		PodCompliance         any `json:"podCompliance"`
+		CloudIdentity         any `json:"cloudIdentity"`
+		Recommendations       any `json:"resourceRecommendations,omitempty"`
+	}{
+		RunnerTemplate:        spec.RunnerTemplate,
+		RunnerImage:           spec.RunnerImage,
+		DockerInDockerImage:   spec.DockerInDockerImage,
+		DockerConfigMapRef:    spec.DockerConfigMapRef,
+		RegistryAuth:          spec.RegistryAuth,
+		DockerConfigMountPath: spec.DockerConfigMountPath,
+		Labels:                spec.Labels,
+		RunnerLabels:          spec.RunnerLabels,
+		RegistrationLabels:    spec.RegistrationLabels,
+		RunnerPodLabels:       spec.RunnerPodLabels,
+		RunnerPodAnnotations:  spec.RunnerPodAnnotations,
+		DNS:                   spec.DNS,
+		AirGapped:             spec.AirGapped,
+		Mesh:                  spec.Mesh,
+		Network:               spec.Network,
+		DinDDaemonConfig:      spec.DinDDaemonConfig,
+		ExtraVolumes:          spec.ExtraVolumes,
+		ExtraVolumeMounts:     spec.ExtraVolumeMounts,
+		WorkspaceInit:         spec.WorkspaceInit,
+		SecurityProfile:       spec.SecurityProfile,
+		Scheduling:            spec.Scheduling,
+		DisruptionProtection:  spec.DisruptionProtection,
+		NodePool:              spec.NodePool,
+		DedicatedNodes:        spec.DedicatedNodes,
+		IdleTimeout:           spec.IdleTimeout,
+		Backend:               spec.Backend,
+		VirtualMachine:        spec.VirtualMachine,
+		Priority:              spec.Priority,
+		TerminationGrace:      spec.TerminationGracePeriodSeconds,
+		ResourceProfiles:      spec.ResourceProfiles,
+		DefaultProfile:        spec.DefaultResourceProfile,
+		PodCompliance:         spec.PodCompliance,
+		CloudIdentity:         spec.CloudIdentity,
+		Recommendations:       applied,
+	})
+	if err != nil {
+		// The spec only contains JSON-serializable API types
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("TemplateHash", func() {
+	newSpec := func() *forgejoactionsiov1alpha1.ActDeploymentSpec {
+		return &forgejoactionsiov1alpha1.ActDeploymentSpec{
+			Organization: "acme",
+			RunnerImage:  "code.forgejo.org/forgejo/runner:6",
+			RunnerTemplate: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{NodeSelector: map[string]string{"pool": "ci"}},
+			},
+		}
+	}
+
+	It("is stable for the same spec", func() {
+		Expect(TemplateHash(newSpec(), nil)).To(Equal(TemplateHash(newSpec(), nil)))
+		Expect(TemplateHash(newSpec(), nil)).To(HaveLen(16))
+	})
+
+	It("changes when the runner template or image changes", func() {
+		base := TemplateHash(newSpec(), nil)
+
+		spec := newSpec()
+		spec.RunnerTemplate.Spec.NodeSelector["pool"] = "gpu"
+		Expect(TemplateHash(spec, nil)).NotTo(Equal(base))
+
+		spec = newSpec()
+		spec.RunnerImage = "code.forgejo.org/forgejo/runner:7"
+		Expect(TemplateHash(spec, nil)).NotTo(Equal(base))
+	})
+
+	It("changes when the DinD daemon config changes", func() {
+		base := TemplateHash(newSpec(), nil)
+
+		spec := newSpec()
+		spec.DinDDaemonConfig = &forgejoactionsiov1alpha1.DinDDaemonConfigSource{ConfigMapRef: &corev1.LocalObjectReference{Name: "dind"}}
+		configMap := TemplateHash(spec, nil)
+		Expect(configMap).NotTo(Equal(base))
+
+		spec.DinDDaemonConfig.Key = "daemon-ci.json"
+		Expect(TemplateHash(spec, nil)).NotTo(Equal(configMap))
+	})
+
+	It("changes when the applied resource recommendations change", func() {
+		recommendation := func(cpu string, samples int32) []forgejoactionsiov1alpha1.ResourceRecommendation {
+			return []forgejoactionsiov1alpha1.ResourceRecommendation{
+				{Labels: "docker", CPU: resource.MustParse(cpu), Memory: resource.MustParse("1Gi"), Samples: samples},
+			}
+		}
+		base := TemplateHash(newSpec(), recommendation("500m", 5))
+		Expect(base).To(Equal(TemplateHash(newSpec(), nil)), "recommendations are only hashed once applied")
+
+		spec := newSpec()
+		spec.ResourceRecommendations = &forgejoactionsiov1alpha1.ResourceRecommendationsSpec{Apply: true}
+		applied := TemplateHash(spec, recommendation("500m", 5))
+		Expect(applied).NotTo(Equal(base))
+
+		By("receiving a new recommendation")
+		Expect(TemplateHash(spec, recommendation("750m", 6))).NotTo(Equal(applied))
+
+		By("adding samples without changing the recommendation")
+		Expect(TemplateHash(spec, recommendation("500m", 9))).To(Equal(applied))
+
+		By("having too few samples to apply it")
+		Expect(TemplateHash(spec, recommendation("500m", 2))).To(Equal(base))
+	})
+
+	It("ignores fields that do not shape runners", func() {
+		base := TemplateHash(newSpec(), nil)
+
+		spec := newSpec()
+		spec.Organization = "other"
+		spec.MaxRunners = new(int32)
+		spec.SecretAudit = true
+		Expect(TemplateHash(spec, nil)).To(Equal(base))
+	})
+})
@@ -0,0 +1,145 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package runnerlabels converts between ActDeployment runner labels and the Forgejo runner label format
+package runnerlabels
+
+import (
+	"fmt"
+	"strings"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// Parse parses comma-separated labels in the runner format ("name", "name:host" or "name:docker://image")
+func Parse(labels string) ([]forgejoactionsiov1alpha1.RunnerLabel, error) {
+	var parsed []forgejoactionsiov1alpha1.RunnerLabel
+	for _, entry := range strings.Split(labels, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rest, _ := strings.Cut(entry, ":")
+		label := forgejoactionsiov1alpha1.RunnerLabel{Name: name}
+		if rest != "" {
+			schema, container, _ := strings.Cut(rest, "://")
+			switch forgejoactionsiov1alpha1.RunnerLabelSchema(schema) {
+			case forgejoactionsiov1alpha1.RunnerLabelSchemaDocker, forgejoactionsiov1alpha1.RunnerLabelSchemaHost, forgejoactionsiov1alpha1.RunnerLabelSchemaLXC:
+				label.Schema = forgejoactionsiov1alpha1.RunnerLabelSchema(schema)
+				label.Container = container
+			default:
+				return nil, fmt.Errorf("label %q has unknown schema %q", entry, schema)
+			}
+		}
+		if label.Name == "" {
+			return nil, fmt.Errorf("label %q has no name", entry)
+		}
+		parsed = append(parsed, label)
+	}
+	return parsed, nil
+}
+
+// FromSpec returns the runner labels of an ActDeployment, preferring RunnerLabels over the Labels string
+func FromSpec(spec *forgejoactionsiov1alpha1.ActDeploymentSpec) ([]forgejoactionsiov1alpha1.RunnerLabel, error) {
+	if len(spec.RunnerLabels) > 0 {
+		return spec.RunnerLabels, nil
+	}
+	return Parse(spec.Labels)
+}
+
+// Format renders a label in the runner format
+func Format(label forgejoactionsiov1alpha1.RunnerLabel) string {
+	switch {
+	case label.Schema == forgejoactionsiov1alpha1.RunnerLabelSchemaHost:
+		return label.Name + ":host"
+	case label.Container != "":
+		schema := label.Schema
+		if schema == "" {
+			schema = forgejoactionsiov1alpha1.RunnerLabelSchemaDocker
+		}
+		return fmt.Sprintf("%s:%s://%s", label.Name, schema, label.Container)
+	default:
+		return label.Name
+	}
+}
+
+// FormatAll renders labels comma-separated in the runner format
+func FormatAll(labels []forgejoactionsiov1alpha1.RunnerLabel) string {
+	formatted := make([]string, 0, len(labels))
+	for _, label := range labels {
+		formatted = append(formatted, Format(label))
+	}
+	return strings.Join(formatted, ",")
+}
+
+// Names returns the comma-separated label names, as used by the Forgejo jobs API filter
+func Names(labels []forgejoactionsiov1alpha1.RunnerLabel) string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// Matches reports whether every runs-on entry of a job is one of the label names
+// A job without runs-on entries matches nothing
+func Matches(labels []forgejoactionsiov1alpha1.RunnerLabel, runsOn []string) bool {
+	return len(runsOn) > 0 && len(Missing(labels, runsOn)) == 0
+}
+
+// Missing returns the runs-on entries of a job that are not among the label names
+func Missing(labels []forgejoactionsiov1alpha1.RunnerLabel, runsOn []string) []string {
+	var missing []string
+	for _, want := range runsOn {
+		if find(labels, want) == nil {
+			missing = append(missing, want)
+		}
+	}
+	return missing
+}
+
+// ForJob returns the runner-format labels for a job's runs-on entries
+// Entries with a label definition carry its schema and default container; others are passed by name
+func ForJob(labels []forgejoactionsiov1alpha1.RunnerLabel, runsOn []string) string {
+	formatted := make([]string, 0, len(runsOn))
+	for _, name := range runsOn {
+		if label := find(labels, name); label != nil {
+			formatted = append(formatted, Format(*label))
+		} else {
+			formatted = append(formatted, name)
+		}
+	}
+	return strings.Join(formatted, ",")
+}
+
+// ForRunner returns the runner-format labels a runner registers with
+// Registration labels are used as they are; without them the labels are derived from the job's runs-on entries
+func ForRunner(labels, registrationLabels []forgejoactionsiov1alpha1.RunnerLabel, runsOn []string) string {
+	if len(registrationLabels) > 0 {
+		return FormatAll(registrationLabels)
+	}
+	return ForJob(labels, runsOn)
+}
+
+func find(labels []forgejoactionsiov1alpha1.RunnerLabel, name string) *forgejoactionsiov1alpha1.RunnerLabel {
+	for i := range labels {
+		if labels[i].Name == name {
+			return &labels[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runnerlabels
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("Parse", func() {
+	It("parses names, schemas and containers", func() {
+		labels, err := Parse("docker, ubuntu-22.04:docker://node:20-bullseye,shell:host")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(labels).To(Equal([]forgejoactionsiov1alpha1.RunnerLabel{
+			{Name: "docker"},
+			{Name: "ubuntu-22.04", Schema: forgejoactionsiov1alpha1.RunnerLabelSchemaDocker, Container: "node:20-bullseye"},
+			{Name: "shell", Schema: forgejoactionsiov1alpha1.RunnerLabelSchemaHost},
+		}))
+	})
+
+	It("rejects unknown schemas", func() {
+		_, err := Parse("ubuntu:vm://image")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("round-trips through FormatAll", func() {
+		labels, err := Parse("ubuntu-22.04:docker://node:20-bullseye,shell:host,docker")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(FormatAll(labels)).To(Equal("ubuntu-22.04:docker://node:20-bullseye,shell:host,docker"))
+		Expect(Names(labels)).To(Equal("ubuntu-22.04,shell,docker"))
+	})
+})
+
+var _ = Describe("Matches", func() {
+	labels := []forgejoactionsiov1alpha1.RunnerLabel{{Name: "docker"}, {Name: "ubuntu-22.04", Container: "node:20"}}
+
+	It("requires every runs-on entry to match", func() {
+		Expect(Matches(labels, []string{"docker"})).To(BeTrue())
+		Expect(Matches(labels, []string{"docker", "ubuntu-22.04"})).To(BeTrue())
+		Expect(Matches(labels, []string{"docker", "gpu"})).To(BeFalse())
+		Expect(Matches(labels, nil)).To(BeFalse())
+		Expect(Missing(labels, []string{"docker", "gpu", "arm64"})).To(Equal([]string{"gpu", "arm64"}))
+	})
+
+	It("formats the job's labels with their definitions", func() {
+		Expect(ForJob(labels, []string{"ubuntu-22.04", "gpu"})).To(Equal("ubuntu-22.04:docker://node:20,gpu"))
+	})
+
+	It("registers runners with the registration labels when set", func() {
+		labels := []forgejoactionsiov1alpha1.RunnerLabel{{Name: "docker"}}
+		registration := []forgejoactionsiov1alpha1.RunnerLabel{{Name: "docker"}, {Name: "self-hosted-large", Schema: forgejoactionsiov1alpha1.RunnerLabelSchemaHost}}
+		Expect(ForRunner(labels, registration, []string{"docker"})).To(Equal("docker,self-hosted-large:host"))
+		Expect(ForRunner(labels, nil, []string{"docker"})).To(Equal("docker"))
+	})
+})
@@ -0,0 +1,105 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package runnerphase defines the state machine of ActRunner phases
+// Pending, Running, Terminating and Unknown are active phases; Succeeded, Failed and Cancelled are final
+package runnerphase
+
+import (
+	"slices"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// phases lists all phases, active ones first
+var phases = []forgejoactionsiov1alpha1.ActRunnerPhase{
+	forgejoactionsiov1alpha1.ActRunnerPhasePending,
+	forgejoactionsiov1alpha1.ActRunnerPhaseRunning,
+	forgejoactionsiov1alpha1.ActRunnerPhaseTerminating,
+	forgejoactionsiov1alpha1.ActRunnerPhaseUnknown,
+	forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded,
+	forgejoactionsiov1alpha1.ActRunnerPhaseFailed,
+	forgejoactionsiov1alpha1.ActRunnerPhaseCancelled,
+}
+
+// transitions lists the phases each active phase can move to; finished phases have none
+// A new ActRunner has no phase yet and can start in any phase
+// An active runner goes back to Pending when its workload is replaced (DinD retries, rollouts, lost pods),
+// and a terminating runner can't run again
+var transitions = map[forgejoactionsiov1alpha1.ActRunnerPhase][]forgejoactionsiov1alpha1.ActRunnerPhase{
+	"": phases,
+	forgejoactionsiov1alpha1.ActRunnerPhasePending: {
+		forgejoactionsiov1alpha1.ActRunnerPhaseRunning,
+		forgejoactionsiov1alpha1.ActRunnerPhaseTerminating,
+		forgejoactionsiov1alpha1.ActRunnerPhaseUnknown,
+		forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded,
+		forgejoactionsiov1alpha1.ActRunnerPhaseFailed,
+		forgejoactionsiov1alpha1.ActRunnerPhaseCancelled,
+	},
+	forgejoactionsiov1alpha1.ActRunnerPhaseRunning: {
+		forgejoactionsiov1alpha1.ActRunnerPhasePending,
+		forgejoactionsiov1alpha1.ActRunnerPhaseTerminating,
+		forgejoactionsiov1alpha1.ActRunnerPhaseUnknown,
+		forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded,
+		forgejoactionsiov1alpha1.ActRunnerPhaseFailed,
+		forgejoactionsiov1alpha1.ActRunnerPhaseCancelled,
+	},
+	forgejoactionsiov1alpha1.ActRunnerPhaseTerminating: {
+		forgejoactionsiov1alpha1.ActRunnerPhasePending,
+		forgejoactionsiov1alpha1.ActRunnerPhaseUnknown,
+		forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded,
+		forgejoactionsiov1alpha1.ActRunnerPhaseFailed,
+		forgejoactionsiov1alpha1.ActRunnerPhaseCancelled,
+	},
+	forgejoactionsiov1alpha1.ActRunnerPhaseUnknown: {
+		forgejoactionsiov1alpha1.ActRunnerPhasePending,
+		forgejoactionsiov1alpha1.ActRunnerPhaseRunning,
+		forgejoactionsiov1alpha1.ActRunnerPhaseTerminating,
+		forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded,
+		forgejoactionsiov1alpha1.ActRunnerPhaseFailed,
+		forgejoactionsiov1alpha1.ActRunnerPhaseCancelled,
+	},
+}
+
+// Phases returns all phases, active ones first
+func Phases() []forgejoactionsiov1alpha1.ActRunnerPhase {
+	return slices.Clone(phases)
+}
+
+// Finished reports whether the phase is final, i.e. the runner won't run a job anymore
+func Finished(phase forgejoactionsiov1alpha1.ActRunnerPhase) bool {
+	switch phase {
+	case forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded, forgejoactionsiov1alpha1.ActRunnerPhaseFailed, forgejoactionsiov1alpha1.ActRunnerPhaseCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanTransition reports whether a runner can move from one phase to another
+// Staying in the same phase is always allowed; finished phases can't be left
+func CanTransition(from, to forgejoactionsiov1alpha1.ActRunnerPhase) bool {
+	return from == to || slices.Contains(transitions[from], to)
+}
+
+// Next returns the phase a runner in the current phase moves to when its workload reports observed
+// A transition that isn't allowed keeps the current phase, so a finished runner stays finished when its pod goes away
+func Next(current, observed forgejoactionsiov1alpha1.ActRunnerPhase) forgejoactionsiov1alpha1.ActRunnerPhase {
+	if CanTransition(current, observed) {
+		return observed
+	}
+	return current
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runnerphase
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+const (
+	pending     = forgejoactionsiov1alpha1.ActRunnerPhasePending
+	running     = forgejoactionsiov1alpha1.ActRunnerPhaseRunning
+	terminating = forgejoactionsiov1alpha1.ActRunnerPhaseTerminating
+	unknown     = forgejoactionsiov1alpha1.ActRunnerPhaseUnknown
+	succeeded   = forgejoactionsiov1alpha1.ActRunnerPhaseSucceeded
+	failed      = forgejoactionsiov1alpha1.ActRunnerPhaseFailed
+	cancelled   = forgejoactionsiov1alpha1.ActRunnerPhaseCancelled
+)
+
+var _ = Describe("Finished", func() {
+	It("treats only Succeeded, Failed and Cancelled as finished", func() {
+		for _, phase := range Phases() {
+			Expect(Finished(phase)).To(Equal(phase == succeeded || phase == failed || phase == cancelled), string(phase))
+		}
+		Expect(Finished("")).To(BeFalse())
+	})
+})
+
+var _ = Describe("CanTransition", func() {
+	It("never leaves a finished phase", func() {
+		for _, from := range []forgejoactionsiov1alpha1.ActRunnerPhase{succeeded, failed, cancelled} {
+			for _, to := range Phases() {
+				Expect(CanTransition(from, to)).To(Equal(from == to), string(from)+" -> "+string(to))
+			}
+		}
+	})
+
+	It("lets a new runner start in any phase", func() {
+		for _, to := range Phases() {
+			Expect(CanTransition("", to)).To(BeTrue(), string(to))
+		}
+	})
+
+	It("lets a terminating runner finish or get a new workload, but not run again", func() {
+		Expect(CanTransition(terminating, cancelled)).To(BeTrue())
+		Expect(CanTransition(terminating, succeeded)).To(BeTrue())
+		Expect(CanTransition(terminating, pending)).To(BeTrue())
+		Expect(CanTransition(terminating, running)).To(BeFalse())
+	})
+
+	It("lets a runner in an unknown state recover", func() {
+		Expect(CanTransition(running, unknown)).To(BeTrue())
+		Expect(CanTransition(unknown, running)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Next", func() {
+	It("moves to the observed phase when allowed", func() {
+		Expect(Next(pending, running)).To(Equal(running))
+		Expect(Next(running, terminating)).To(Equal(terminating))
+	})
+
+	It("keeps a finished phase", func() {
+		Expect(Next(succeeded, pending)).To(Equal(succeeded))
+		Expect(Next(failed, running)).To(Equal(failed))
+		Expect(Next(cancelled, failed)).To(Equal(cancelled))
+	})
+})
@@ -0,0 +1,282 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package runnerspec builds the ActRunner the listener creates for a job from its ActDeployment
+// The operator uses it too, to render the runner pod of an ActDeployment without a job
+package runnerspec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/nodepool"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/recommend"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/resourceprofile"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/rollout"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/scheduling"
+)
+
+// Name returns the name of the first ActRunner for an attempt of a job, unique per Forgejo server and organization
+// The first attempt keeps the name used before attempts were tracked
+func Name(forgejoServer, organization string, jobID, attempt int64) string {
+	if attempt <= 1 {
+		sum := sha256.Sum256(fmt.Appendf(nil, "%s/%s/%d", strings.TrimSuffix(forgejoServer, "/"), organization, jobID))
+		return fmt.Sprintf("actrunner-%d-%s", jobID, hex.EncodeToString(sum[:4]))
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s/%s/%d/%d", strings.TrimSuffix(forgejoServer, "/"), organization, jobID, attempt))
+	return fmt.Sprintf("actrunner-%d-a%d-%s", jobID, attempt, hex.EncodeToString(sum[:4]))
+}
+
+// ForJob returns the ActRunner for a job, in the Pending phase and without repository and run details
+func ForJob(actDeployment *forgejoactionsiov1alpha1.ActDeployment, job forgejo.Job, name, namespace, registrationSecretName string, runnerLabels []forgejoactionsiov1alpha1.RunnerLabel) *forgejoactionsiov1alpha1.ActRunner {
+	// Get proper API version and kind for OwnerReference
+	apiVersion := actDeployment.APIVersion
+	if apiVersion == "" {
+		apiVersion = forgejoactionsiov1alpha1.GroupVersion.String()
+	}
+	kind := actDeployment.Kind
+	if kind == "" {
+		kind = "ActDeployment"
+	}
+
+	return &forgejoactionsiov1alpha1.ActRunner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"forgejo.actions.io/job-id":         fmt.Sprintf("%d", job.ID),
+				"forgejo.actions.io/act-deployment": actDeployment.Name,
+			},
+			Annotations: map[string]string{
+				rollout.TemplateHashAnnotation: rollout.TemplateHash(&actDeployment.Spec, actDeployment.Status.ResourceRecommendations),
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: apiVersion,
+					Kind:       kind,
+					Name:       actDeployment.Name,
+					UID:        actDeployment.UID,
+					Controller: func() *bool { b := true; return &b }(),
+				},
+			},
+		},
+		Spec: forgejoactionsiov1alpha1.ActRunnerSpec{
+			ForgejoJobID:   job.ID,
+			ForgejoServer:  actDeployment.Spec.ForgejoServer,
+			Organization:   actDeployment.Spec.Organization,
+			TokenSecretRef: actDeployment.Spec.TokenSecretRef,
+			RegistrationTokenSecretRef: corev1.SecretReference{
+				Name:      registrationSecretName,
+				Namespace: namespace,
+			},
+			RunnerImage:                   actDeployment.Spec.RunnerImage,
+			DockerInDockerImage:           actDeployment.Spec.DockerInDockerImage,
+			DockerConfigMapRef:            DockerConfigMapRef(actDeployment),
+			DockerConfigSecretRef:         DockerConfigSecretRef(actDeployment),
+			DockerConfigMountPath:         actDeployment.Spec.DockerConfigMountPath,
+			DisruptionProtection:          actDeployment.Spec.DisruptionProtection.DeepCopy(),
+			IdleTimeout:                   actDeployment.Spec.IdleTimeout.DeepCopy(),
+			PendingTimeout:                actDeployment.Spec.PendingTimeout.DeepCopy(),
+			TerminationGracePeriodSeconds: actDeployment.Spec.TerminationGracePeriodSeconds,
+			Backend:                       actDeployment.Spec.Backend,
+			VirtualMachine:                actDeployment.Spec.VirtualMachine.DeepCopy(),
+			RolloutStrategy:               actDeployment.Spec.RolloutStrategy,
+			Priority:                      actDeployment.Spec.Priority,
+			Mesh:                          actDeployment.Spec.Mesh.DeepCopy(),
+			Network:                       actDeployment.Spec.Network.DeepCopy(),
+			DinDDaemonConfig:              actDeployment.Spec.DinDDaemonConfig.DeepCopy(),
+			ExtraVolumes:                  ExtraVolumes(actDeployment),
+			ExtraVolumeMounts:             ExtraVolumeMounts(actDeployment),
+			WorkspaceInit:                 actDeployment.Spec.WorkspaceInit.DeepCopy(),
+			SecurityProfile:               actDeployment.Spec.SecurityProfile,
+			RegistryMirrors:               RegistryMirrors(actDeployment),
+			RunnerLabels:                  runnerLabels,
+			RegistrationLabels:            actDeployment.Spec.RegistrationLabels,
+			LogArchive:                    actDeployment.Spec.LogArchive.DeepCopy(),
+			PodRetention:                  actDeployment.Spec.PodRetention.DeepCopy(),
+			RecordUsage:                   actDeployment.Spec.ResourceRecommendations != nil,
+			PodCompliance:                 actDeployment.Spec.PodCompliance.DeepCopy(),
+			JobData: forgejoactionsiov1alpha1.JobData{
+				ID:      job.ID,
+				RepoID:  job.RepoID,
+				OwnerID: job.OwnerID,
+				Name:    job.Name,
+				Needs:   job.Needs,
+				RunsOn:  job.RunsOn,
+				TaskID:  job.TaskID,
+				Status:  job.Status,
+				Handle:  job.Handle,
+				Attempt: job.Attempt,
+			},
+			JobTemplate: JobTemplate(actDeployment, job.RunsOn),
+		},
+		Status: forgejoactionsiov1alpha1.ActRunnerStatus{
+			Phase: forgejoactionsiov1alpha1.ActRunnerPhasePending,
+		},
+	}
+}
+
+// JobTemplate builds the runner pod template for a job
+// The resource profile the job selects is applied first; with spec.resourceRecommendations.apply, the runner
+// container then requests the recommendation for the job's labels, capped at the profile's limits
+func JobTemplate(actDeployment *forgejoactionsiov1alpha1.ActDeployment, runsOn []string) corev1.PodTemplateSpec {
+	jobTemplate := buildJobTemplate(actDeployment)
+	resourceprofile.Apply(&jobTemplate.Spec, resourceprofile.Select(&actDeployment.Spec, runsOn))
+	spec := actDeployment.Spec.ResourceRecommendations
+	if spec == nil || !spec.Apply {
+		return jobTemplate
+	}
+	if recommendation := recommend.For(actDeployment.Status.ResourceRecommendations, recommend.Key(runsOn), recommend.MinSamples(spec)); recommendation != nil {
+		recommend.Apply(&jobTemplate.Spec.Containers[0], *recommendation)
+	}
+	return jobTemplate
+}
+
+// buildJobTemplate renders the JobTemplate for ActRunners of the ActDeployment
+// It starts from RunnerTemplate and merges in scheduling fields derived from the node pool preset
+func buildJobTemplate(actDeployment *forgejoactionsiov1alpha1.ActDeployment) corev1.PodTemplateSpec {
+	// Ensure JobTemplate has at least one container
+	jobTemplate := actDeployment.Spec.RunnerTemplate.DeepCopy()
+	if len(jobTemplate.Spec.Containers) == 0 {
+		jobTemplate.Spec.Containers = []corev1.Container{
+			// The image is the spec's runnerImage, or the operator default when the runner pod is built
+			{Name: "runner"},
+		}
+	}
+
+	// Pass runner pod labels and annotations through the template metadata
+	for k, v := range actDeployment.Spec.RunnerPodLabels {
+		if jobTemplate.Labels == nil {
+			jobTemplate.Labels = map[string]string{}
+		}
+		jobTemplate.Labels[k] = v
+	}
+	for k, v := range actDeployment.Spec.RunnerPodAnnotations {
+		if jobTemplate.Annotations == nil {
+			jobTemplate.Annotations = map[string]string{}
+		}
+		jobTemplate.Annotations[k] = v
+	}
+
+	// Apply spec.scheduling (nodeSelector, tolerations, affinity, topology spread constraints)
+	scheduling.ApplyToPodSpec(&jobTemplate.Spec, actDeployment.Spec.Scheduling)
+	scheduling.ApplyDNS(&jobTemplate.Spec, actDeployment.Spec.DNS)
+	scheduling.ApplyPriority(&jobTemplate.Spec, actDeployment.Spec.Priority)
+
+	// Apply node pool preset (nodeSelector, tolerations and extended resources for the runner container)
+	nodepool.ApplyToPodSpec(&jobTemplate.Spec, actDeployment.Spec.NodePool)
+	nodepool.ApplyExtendedResources(&jobTemplate.Spec.Containers[0], actDeployment.Spec.NodePool)
+	nodepool.ApplyDedicated(&jobTemplate.Spec, actDeployment)
+
+	// Run under the ServiceAccount carrying the cloud identity, unless the RunnerTemplate picks one
+	if actDeployment.Spec.CloudIdentity != nil && jobTemplate.Spec.ServiceAccountName == "" {
+		jobTemplate.Spec.ServiceAccountName = ServiceAccountName(actDeployment.Name)
+	}
+
+	return *jobTemplate
+}
+
+// ServiceAccountName returns the name of the ServiceAccount the operator creates for the runner pods of an ActDeployment
+func ServiceAccountName(actDeploymentName string) string {
+	return actDeploymentName + "-runner"
+}
+
+// DockerConfigName returns the name of the Docker config the operator generates for the runner pods of an ActDeployment
+func DockerConfigName(actDeploymentName string) string {
+	return actDeploymentName + "-docker-config"
+}
+
+// CredentialHelpers returns the Docker credential helper of each cloud registry of the ActDeployment's cloud identity
+func CredentialHelpers(actDeployment *forgejoactionsiov1alpha1.ActDeployment) map[string]string {
+	identity := actDeployment.Spec.CloudIdentity
+	if identity == nil {
+		return nil
+	}
+	helpers := map[string]string{}
+	if identity.AWS != nil {
+		for _, registry := range identity.AWS.ECRRegistries {
+			helpers[registry] = "ecr-login"
+		}
+	}
+	if identity.GCP != nil {
+		for _, registry := range identity.GCP.ArtifactRegistries {
+			helpers[registry] = "gcloud"
+		}
+	}
+	return helpers
+}
+
+// GeneratesDockerConfig reports whether the operator generates the runners' Docker config from registryAuth,
+// the cloud registries' credential helpers and spec.dockerConfigMapRef
+func GeneratesDockerConfig(actDeployment *forgejoactionsiov1alpha1.ActDeployment) bool {
+	return len(actDeployment.Spec.RegistryAuth) > 0 || len(CredentialHelpers(actDeployment)) > 0
+}
+
+// DockerConfigMapRef returns the ConfigMap holding the runners' Docker config.json, if the operator doesn't generate it
+func DockerConfigMapRef(actDeployment *forgejoactionsiov1alpha1.ActDeployment) *corev1.LocalObjectReference {
+	if GeneratesDockerConfig(actDeployment) {
+		return nil
+	}
+	return actDeployment.Spec.DockerConfigMapRef
+}
+
+// DockerConfigSecretRef returns the Secret holding the Docker config the operator generates for the runners
+func DockerConfigSecretRef(actDeployment *forgejoactionsiov1alpha1.ActDeployment) *corev1.LocalObjectReference {
+	if !GeneratesDockerConfig(actDeployment) {
+		return nil
+	}
+	return &corev1.LocalObjectReference{Name: DockerConfigName(actDeployment.Name)}
+}
+
+// ExtraVolumes returns a copy of the ActDeployment's extra volumes
+func ExtraVolumes(actDeployment *forgejoactionsiov1alpha1.ActDeployment) []corev1.Volume {
+	if len(actDeployment.Spec.ExtraVolumes) == 0 {
+		return nil
+	}
+	volumes := make([]corev1.Volume, len(actDeployment.Spec.ExtraVolumes))
+	for i := range actDeployment.Spec.ExtraVolumes {
+		actDeployment.Spec.ExtraVolumes[i].DeepCopyInto(&volumes[i])
+	}
+	return volumes
+}
+
+// ExtraVolumeMounts returns a copy of the ActDeployment's extra volume mounts
+func ExtraVolumeMounts(actDeployment *forgejoactionsiov1alpha1.ActDeployment) []forgejoactionsiov1alpha1.ExtraVolumeMount {
+	if len(actDeployment.Spec.ExtraVolumeMounts) == 0 {
+		return nil
+	}
+	mounts := make([]forgejoactionsiov1alpha1.ExtraVolumeMount, len(actDeployment.Spec.ExtraVolumeMounts))
+	for i := range actDeployment.Spec.ExtraVolumeMounts {
+		actDeployment.Spec.ExtraVolumeMounts[i].DeepCopyInto(&mounts[i])
+	}
+	return mounts
+}
+
+// RegistryMirrors returns the DinD registry mirrors of an air-gapped ActDeployment
+func RegistryMirrors(actDeployment *forgejoactionsiov1alpha1.ActDeployment) []string {
+	if actDeployment.Spec.AirGapped == nil {
+		return nil
+	}
+	return slices.Clone(actDeployment.Spec.AirGapped.RegistryMirrors)
+}
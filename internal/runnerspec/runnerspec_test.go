@@ -0,0 +1,221 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runnerspec
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/forgejo"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/nodepool"
+	"github.com/goodmannershosting/forgejo-act-runner-controller/internal/rollout"
+)
+
+var _ = Describe("Name", func() {
+	It("ignores a trailing slash of the server", func() {
+		Expect(Name("https://forgejo.example.com/", "org", 42, 0)).To(Equal(Name("https://forgejo.example.com", "org", 42, 0)))
+		Expect(Name("https://forgejo.example.com", "org", 42, 0)).To(HavePrefix("actrunner-42-"))
+		Expect(Name("https://forgejo.example.com", "other", 42, 0)).NotTo(Equal(Name("https://forgejo.example.com", "org", 42, 0)))
+	})
+
+	It("names re-run attempts apart and keeps the name of the first attempt", func() {
+		Expect(Name("https://forgejo.example.com", "org", 42, 1)).To(Equal(Name("https://forgejo.example.com", "org", 42, 0)))
+		Expect(Name("https://forgejo.example.com", "org", 42, 2)).To(HavePrefix("actrunner-42-a2-"))
+		Expect(Name("https://forgejo.example.com", "org", 42, 3)).NotTo(Equal(Name("https://forgejo.example.com", "org", 42, 2)))
+	})
+})
+
+var _ = Describe("ForJob", func() {
+	var actDeployment *forgejoactionsiov1alpha1.ActDeployment
+
+	BeforeEach(func() {
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "ci", UID: "uid"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				ForgejoServer:   "https://forgejo.example.com",
+				Organization:    "org",
+				Labels:          "docker",
+				RunnerImage:     "runner:v1",
+				RunnerPodLabels: map[string]string{"team": "ci"},
+				AirGapped:       &forgejoactionsiov1alpha1.AirGappedSpec{RegistryMirrors: []string{"https://mirror.example.com"}},
+			},
+		}
+	})
+
+	It("copies the ActDeployment settings and the job", func() {
+		job := forgejo.Job{ID: 7, Name: "build", RunsOn: []string{"docker"}}
+		actRunner := ForJob(actDeployment, job, "runner", "ci", "reg-secret", nil)
+
+		Expect(actRunner.Name).To(Equal("runner"))
+		Expect(actRunner.Labels).To(HaveKeyWithValue("forgejo.actions.io/act-deployment", "deploy"))
+		Expect(actRunner.Annotations).To(HaveKeyWithValue(rollout.TemplateHashAnnotation, rollout.TemplateHash(&actDeployment.Spec, nil)))
+		Expect(actRunner.OwnerReferences).To(HaveLen(1))
+		Expect(actRunner.OwnerReferences[0].Kind).To(Equal("ActDeployment"))
+		Expect(actRunner.Spec.RegistrationTokenSecretRef.Name).To(Equal("reg-secret"))
+		Expect(actRunner.Spec.RunnerImage).To(Equal("runner:v1"))
+		Expect(actRunner.Spec.RegistryMirrors).To(Equal([]string{"https://mirror.example.com"}))
+		Expect(actRunner.Spec.JobData.Name).To(Equal("build"))
+		Expect(actRunner.Spec.JobTemplate.Labels).To(HaveKeyWithValue("team", "ci"))
+		Expect(actRunner.Status.Phase).To(Equal(forgejoactionsiov1alpha1.ActRunnerPhasePending))
+	})
+
+	It("copies the DinD daemon config", func() {
+		actDeployment.Spec.DinDDaemonConfig = &forgejoactionsiov1alpha1.DinDDaemonConfigSource{
+			SecretRef: &corev1.LocalObjectReference{Name: "dind"},
+			Key:       "daemon-ci.json",
+		}
+		actRunner := ForJob(actDeployment, forgejo.Job{ID: 7}, "runner", "ci", "reg-secret", nil)
+		Expect(actRunner.Spec.DinDDaemonConfig).To(Equal(actDeployment.Spec.DinDDaemonConfig))
+
+		actDeployment.Spec.DinDDaemonConfig.SecretRef.Name = "changed"
+		Expect(actRunner.Spec.DinDDaemonConfig.SecretRef.Name).To(Equal("dind"))
+	})
+
+	It("copies the extra volumes and mounts", func() {
+		actDeployment.Spec.ExtraVolumes = []corev1.Volume{{
+			Name:         "cache",
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "ci-cache"}},
+		}}
+		actDeployment.Spec.ExtraVolumeMounts = []forgejoactionsiov1alpha1.ExtraVolumeMount{{
+			VolumeMount: corev1.VolumeMount{Name: "cache", MountPath: "/cache"},
+			Containers:  []string{"runner", "dind"},
+		}}
+		actRunner := ForJob(actDeployment, forgejo.Job{ID: 7}, "runner", "ci", "reg-secret", nil)
+		Expect(actRunner.Spec.ExtraVolumes).To(Equal(actDeployment.Spec.ExtraVolumes))
+		Expect(actRunner.Spec.ExtraVolumeMounts).To(Equal(actDeployment.Spec.ExtraVolumeMounts))
+
+		actDeployment.Spec.ExtraVolumes[0].PersistentVolumeClaim.ClaimName = "changed"
+		actDeployment.Spec.ExtraVolumeMounts[0].Containers[1] = "changed"
+		Expect(actRunner.Spec.ExtraVolumes[0].PersistentVolumeClaim.ClaimName).To(Equal("ci-cache"))
+		Expect(actRunner.Spec.ExtraVolumeMounts[0].Containers).To(Equal([]string{"runner", "dind"}))
+
+		Expect(ExtraVolumes(&forgejoactionsiov1alpha1.ActDeployment{})).To(BeNil())
+		Expect(ExtraVolumeMounts(&forgejoactionsiov1alpha1.ActDeployment{})).To(BeNil())
+	})
+
+	It("adds a runner container to an empty runnerTemplate", func() {
+		template := JobTemplate(actDeployment, []string{"docker"})
+		Expect(template.Spec.Containers).To(HaveLen(1))
+		Expect(template.Spec.Containers[0].Name).To(Equal("runner"))
+
+		actDeployment.Spec.RunnerTemplate.Spec.Containers = []corev1.Container{{Name: "custom", Image: "custom:v1"}}
+		template = JobTemplate(actDeployment, []string{"docker"})
+		Expect(template.Spec.Containers[0].Image).To(Equal("custom:v1"))
+	})
+})
+
+var _ = Describe("CloudIdentity", func() {
+	var actDeployment *forgejoactionsiov1alpha1.ActDeployment
+
+	BeforeEach(func() {
+		actDeployment = &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "ci"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				DockerConfigMapRef: &corev1.LocalObjectReference{Name: "user-config"},
+			},
+		}
+	})
+
+	It("keeps the user's Docker config and service account without a cloud identity", func() {
+		Expect(CredentialHelpers(actDeployment)).To(BeEmpty())
+		Expect(DockerConfigMapRef(actDeployment).Name).To(Equal("user-config"))
+		Expect(DockerConfigSecretRef(actDeployment)).To(BeNil())
+		Expect(JobTemplate(actDeployment, nil).Spec.ServiceAccountName).To(BeEmpty())
+	})
+
+	It("maps the cloud registries to their credential helpers", func() {
+		actDeployment.Spec.CloudIdentity = &forgejoactionsiov1alpha1.CloudIdentitySpec{
+			AWS: &forgejoactionsiov1alpha1.AWSIdentitySpec{
+				RoleARN:       "arn:aws:iam::123456789012:role/ci",
+				ECRRegistries: []string{"123456789012.dkr.ecr.eu-west-1.amazonaws.com"},
+			},
+			GCP: &forgejoactionsiov1alpha1.GCPIdentitySpec{
+				ServiceAccount:     "ci@project.iam.gserviceaccount.com",
+				ArtifactRegistries: []string{"europe-docker.pkg.dev"},
+			},
+		}
+
+		Expect(CredentialHelpers(actDeployment)).To(Equal(map[string]string{
+			"123456789012.dkr.ecr.eu-west-1.amazonaws.com": "ecr-login",
+			"europe-docker.pkg.dev":                        "gcloud",
+		}))
+		Expect(DockerConfigMapRef(actDeployment)).To(BeNil())
+		Expect(DockerConfigSecretRef(actDeployment).Name).To(Equal("deploy-docker-config"))
+		Expect(JobTemplate(actDeployment, nil).Spec.ServiceAccountName).To(Equal("deploy-runner"))
+
+		actDeployment.Spec.RunnerTemplate.Spec.ServiceAccountName = "custom"
+		Expect(JobTemplate(actDeployment, nil).Spec.ServiceAccountName).To(Equal("custom"))
+	})
+})
+
+var _ = Describe("RegistryAuth", func() {
+	It("mounts the generated Docker config instead of the user's ConfigMap", func() {
+		actDeployment := &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "ci"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				DockerConfigMapRef: &corev1.LocalObjectReference{Name: "user-config"},
+				RegistryAuth: []forgejoactionsiov1alpha1.RegistryAuthSpec{
+					{SecretRef: corev1.LocalObjectReference{Name: "ghcr"}},
+				},
+			},
+		}
+		Expect(GeneratesDockerConfig(actDeployment)).To(BeTrue())
+
+		actRunner := ForJob(actDeployment, forgejo.Job{ID: 7}, "runner", "ci", "reg-secret", nil)
+		Expect(actRunner.Spec.DockerConfigMapRef).To(BeNil())
+		Expect(actRunner.Spec.DockerConfigSecretRef.Name).To(Equal("deploy-docker-config"))
+	})
+})
+
+var _ = Describe("DedicatedNodes", func() {
+	It("pins runner pods to the ActDeployment's node group and tolerates its taint", func() {
+		actDeployment := &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "ci"},
+			Spec: forgejoactionsiov1alpha1.ActDeploymentSpec{
+				DedicatedNodes: &forgejoactionsiov1alpha1.DedicatedNodesSpec{},
+			},
+		}
+		actDeployment.Spec.RunnerTemplate.Spec.NodeSelector = map[string]string{nodepool.DedicatedKey: "production"}
+
+		template := JobTemplate(actDeployment, nil)
+		Expect(template.Spec.NodeSelector).To(HaveKeyWithValue(nodepool.DedicatedKey, "ci.deploy"))
+		Expect(template.Spec.Tolerations).To(ConsistOf(corev1.Toleration{
+			Key: nodepool.DedicatedKey, Operator: corev1.TolerationOpEqual, Value: "ci.deploy", Effect: corev1.TaintEffectNoSchedule,
+		}))
+
+		status := nodepool.DedicatedStatus(actDeployment)
+		Expect(status.NodeLabel).To(Equal("forgejo.actions.io/dedicated=ci.deploy"))
+		Expect(status.NodeTaint).To(Equal("forgejo.actions.io/dedicated=ci.deploy:NoSchedule"))
+		Expect(status.AutoscalerTags).To(HaveKeyWithValue("k8s.io/cluster-autoscaler/node-template/taint/forgejo.actions.io/dedicated", "ci.deploy:NoSchedule"))
+	})
+
+	It("shortens long default groups to a label value", func() {
+		actDeployment := &forgejoactionsiov1alpha1.ActDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 60), Namespace: "ci"},
+			Spec:       forgejoactionsiov1alpha1.ActDeploymentSpec{DedicatedNodes: &forgejoactionsiov1alpha1.DedicatedNodesSpec{}},
+		}
+		Expect(nodepool.DedicatedGroup(actDeployment)).To(HaveLen(63))
+
+		actDeployment.Spec.DedicatedNodes.Group = "ci-large"
+		Expect(nodepool.DedicatedGroup(actDeployment)).To(Equal("ci-large"))
+	})
+})
@@ -0,0 +1,123 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package scheduling merges ActDeployment scheduling and DNS fields into runner pod specs
+package scheduling
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+// ApplyToPodSpec merges the scheduling fields into a pod spec
+// Fields already set in the pod spec win: existing nodeSelector keys and affinity are kept, and
+// tolerations and topology spread constraints are only appended if not already present
+func ApplyToPodSpec(podSpec *corev1.PodSpec, scheduling *forgejoactionsiov1alpha1.SchedulingSpec) {
+	if scheduling == nil {
+		return
+	}
+
+	if len(scheduling.NodeSelector) > 0 {
+		if podSpec.NodeSelector == nil {
+			podSpec.NodeSelector = map[string]string{}
+		}
+		for k, v := range scheduling.NodeSelector {
+			if _, exists := podSpec.NodeSelector[k]; !exists {
+				podSpec.NodeSelector[k] = v
+			}
+		}
+	}
+
+	for _, toleration := range scheduling.Tolerations {
+		if !hasToleration(podSpec.Tolerations, toleration) {
+			podSpec.Tolerations = append(podSpec.Tolerations, toleration)
+		}
+	}
+
+	if podSpec.Affinity == nil && scheduling.Affinity != nil {
+		podSpec.Affinity = scheduling.Affinity.DeepCopy()
+	}
+
+	for _, constraint := range scheduling.TopologySpreadConstraints {
+		if !hasTopologySpreadConstraint(podSpec.TopologySpreadConstraints, constraint) {
+			podSpec.TopologySpreadConstraints = append(podSpec.TopologySpreadConstraints, *constraint.DeepCopy())
+		}
+	}
+}
+
+// PriorityClassName returns the name of the PriorityClass of runners with the priority
+func PriorityClassName(priority int32) string {
+	return fmt.Sprintf("forgejo-runner-priority-%d", priority)
+}
+
+// ApplyPriority sets the PriorityClass of the priority on a pod spec that has none
+func ApplyPriority(podSpec *corev1.PodSpec, priority *int32) {
+	if priority == nil || podSpec.PriorityClassName != "" {
+		return
+	}
+	podSpec.PriorityClassName = PriorityClassName(*priority)
+}
+
+// ApplyDNS sets the DNS fields on a pod spec
+// The dnsPolicy and dnsConfig of the pod spec win, and host aliases are only added for IPs it doesn't list yet
+func ApplyDNS(podSpec *corev1.PodSpec, dns *forgejoactionsiov1alpha1.DNSSpec) {
+	if dns == nil {
+		return
+	}
+
+	if podSpec.DNSPolicy == "" {
+		podSpec.DNSPolicy = dns.Policy
+	}
+	if podSpec.DNSConfig == nil && dns.Config != nil {
+		podSpec.DNSConfig = dns.Config.DeepCopy()
+	}
+	for _, alias := range dns.HostAliases {
+		if !hasHostAlias(podSpec.HostAliases, alias.IP) {
+			podSpec.HostAliases = append(podSpec.HostAliases, *alias.DeepCopy())
+		}
+	}
+}
+
+func hasHostAlias(aliases []corev1.HostAlias, ip string) bool {
+	for i := range aliases {
+		if aliases[i].IP == ip {
+			return true
+		}
+	}
+	return false
+}
+
+func hasToleration(tolerations []corev1.Toleration, toleration corev1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].MatchToleration(&toleration) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTopologySpreadConstraint matches constraints by topology key and whenUnsatisfiable, like the API server's uniqueness check
+func hasTopologySpreadConstraint(constraints []corev1.TopologySpreadConstraint, constraint corev1.TopologySpreadConstraint) bool {
+	for i := range constraints {
+		if constraints[i].TopologyKey == constraint.TopologyKey && constraints[i].WhenUnsatisfiable == constraint.WhenUnsatisfiable {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduling
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	forgejoactionsiov1alpha1 "github.com/goodmannershosting/forgejo-act-runner-controller/api/v1alpha1"
+)
+
+var _ = Describe("ApplyToPodSpec", func() {
+	zoneSpread := corev1.TopologySpreadConstraint{
+		MaxSkew:           1,
+		TopologyKey:       "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+		LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "runner"}},
+	}
+
+	It("leaves the pod spec alone without scheduling", func() {
+		podSpec := &corev1.PodSpec{NodeSelector: map[string]string{"disk": "ssd"}}
+		ApplyToPodSpec(podSpec, nil)
+		Expect(podSpec).To(Equal(&corev1.PodSpec{NodeSelector: map[string]string{"disk": "ssd"}}))
+	})
+
+	It("adds node selector keys the pod spec doesn't set", func() {
+		podSpec := &corev1.PodSpec{NodeSelector: map[string]string{"disk": "ssd"}}
+		ApplyToPodSpec(podSpec, &forgejoactionsiov1alpha1.SchedulingSpec{
+			NodeSelector: map[string]string{"disk": "hdd", "kubernetes.io/arch": "arm64"},
+		})
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"disk": "ssd", "kubernetes.io/arch": "arm64"}))
+	})
+
+	It("appends tolerations the pod spec doesn't tolerate yet", func() {
+		runners := corev1.Toleration{Key: "runners", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule}
+		gpu := corev1.Toleration{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+		podSpec := &corev1.PodSpec{Tolerations: []corev1.Toleration{runners}}
+		ApplyToPodSpec(podSpec, &forgejoactionsiov1alpha1.SchedulingSpec{Tolerations: []corev1.Toleration{runners, gpu}})
+		Expect(podSpec.Tolerations).To(Equal([]corev1.Toleration{runners, gpu}))
+	})
+
+	It("keeps the affinity of the pod spec", func() {
+		own := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{}}
+		other := &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{}}
+
+		podSpec := &corev1.PodSpec{Affinity: own}
+		ApplyToPodSpec(podSpec, &forgejoactionsiov1alpha1.SchedulingSpec{Affinity: other})
+		Expect(podSpec.Affinity).To(BeIdenticalTo(own))
+
+		podSpec = &corev1.PodSpec{}
+		ApplyToPodSpec(podSpec, &forgejoactionsiov1alpha1.SchedulingSpec{Affinity: other})
+		Expect(podSpec.Affinity).To(Equal(other))
+		Expect(podSpec.Affinity).NotTo(BeIdenticalTo(other))
+	})
+
+	Describe("topology spread constraints", func() {
+		It("appends constraints for topology keys the pod spec doesn't spread over", func() {
+			hostSpread := corev1.TopologySpreadConstraint{MaxSkew: 2, TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: corev1.DoNotSchedule}
+			podSpec := &corev1.PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{hostSpread}}
+			ApplyToPodSpec(podSpec, &forgejoactionsiov1alpha1.SchedulingSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{zoneSpread}})
+			Expect(podSpec.TopologySpreadConstraints).To(Equal([]corev1.TopologySpreadConstraint{hostSpread, zoneSpread}))
+		})
+
+		It("keeps the pod spec's constraint for the same topology key and whenUnsatisfiable", func() {
+			own := zoneSpread
+			own.MaxSkew = 3
+			podSpec := &corev1.PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{own}}
+			ApplyToPodSpec(podSpec, &forgejoactionsiov1alpha1.SchedulingSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{zoneSpread}})
+			Expect(podSpec.TopologySpreadConstraints).To(Equal([]corev1.TopologySpreadConstraint{own}))
+		})
+
+		It("adds a constraint for the same topology key with another whenUnsatisfiable", func() {
+			strict := zoneSpread
+			strict.WhenUnsatisfiable = corev1.DoNotSchedule
+			podSpec := &corev1.PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{strict}}
+			ApplyToPodSpec(podSpec, &forgejoactionsiov1alpha1.SchedulingSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{zoneSpread}})
+			Expect(podSpec.TopologySpreadConstraints).To(Equal([]corev1.TopologySpreadConstraint{strict, zoneSpread}))
+		})
+
+		It("copies the constraints instead of sharing their label selectors", func() {
+			scheduling := &forgejoactionsiov1alpha1.SchedulingSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{zoneSpread}}
+			podSpec := &corev1.PodSpec{}
+			ApplyToPodSpec(podSpec, scheduling)
+			podSpec.TopologySpreadConstraints[0].LabelSelector.MatchLabels["app"] = "changed"
+			Expect(scheduling.TopologySpreadConstraints[0].LabelSelector.MatchLabels).To(HaveKeyWithValue("app", "runner"))
+		})
+	})
+})
+
+var _ = Describe("ApplyDNS", func() {
+	It("sets the DNS policy and config the pod spec leaves empty", func() {
+		config := &corev1.PodDNSConfig{Nameservers: []string{"10.0.0.10"}}
+		podSpec := &corev1.PodSpec{}
+		ApplyDNS(podSpec, &forgejoactionsiov1alpha1.DNSSpec{Policy: corev1.DNSNone, Config: config})
+		Expect(podSpec.DNSPolicy).To(Equal(corev1.DNSNone))
+		Expect(podSpec.DNSConfig).To(Equal(config))
+		Expect(podSpec.DNSConfig).NotTo(BeIdenticalTo(config))
+	})
+
+	It("keeps the DNS policy and config of the pod spec", func() {
+		own := &corev1.PodDNSConfig{Searches: []string{"ci.svc.cluster.local"}}
+		podSpec := &corev1.PodSpec{DNSPolicy: corev1.DNSClusterFirst, DNSConfig: own}
+		ApplyDNS(podSpec, &forgejoactionsiov1alpha1.DNSSpec{Policy: corev1.DNSNone, Config: &corev1.PodDNSConfig{Nameservers: []string{"10.0.0.10"}}})
+		Expect(podSpec.DNSPolicy).To(Equal(corev1.DNSClusterFirst))
+		Expect(podSpec.DNSConfig).To(BeIdenticalTo(own))
+	})
+
+	It("adds host aliases for IPs the pod spec doesn't list", func() {
+		registry := corev1.HostAlias{IP: "10.0.0.5", Hostnames: []string{"registry.internal"}}
+		podSpec := &corev1.PodSpec{HostAliases: []corev1.HostAlias{registry}}
+		ApplyDNS(podSpec, &forgejoactionsiov1alpha1.DNSSpec{HostAliases: []corev1.HostAlias{
+			{IP: "10.0.0.5", Hostnames: []string{"other.internal"}},
+			{IP: "10.0.0.6", Hostnames: []string{"git.internal"}},
+		}})
+		Expect(podSpec.HostAliases).To(Equal([]corev1.HostAlias{registry, {IP: "10.0.0.6", Hostnames: []string{"git.internal"}}}))
+	})
+})
+
+var _ = Describe("ApplyPriority", func() {
+	It("sets the PriorityClass of the priority on a pod spec without one", func() {
+		podSpec := &corev1.PodSpec{}
+		ApplyPriority(podSpec, ptr.To(int32(100)))
+		Expect(podSpec.PriorityClassName).To(Equal("forgejo-runner-priority-100"))
+	})
+
+	It("keeps the PriorityClass of the pod spec", func() {
+		podSpec := &corev1.PodSpec{PriorityClassName: "system-cluster-critical"}
+		ApplyPriority(podSpec, ptr.To(int32(100)))
+		Expect(podSpec.PriorityClassName).To(Equal("system-cluster-critical"))
+	})
+
+	It("leaves the pod spec alone without a priority", func() {
+		podSpec := &corev1.PodSpec{}
+		ApplyPriority(podSpec, nil)
+		Expect(podSpec.PriorityClassName).To(BeEmpty())
+	})
+})
@@ -0,0 +1,107 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package secretguard keeps secret values out of places every user with read access can see:
+// pod labels, annotations, container args, events and logs
+package secretguard
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Redacted replaces secret values
+const Redacted = "[REDACTED]"
+
+// minSecretLength is the shortest value treated as a secret; shorter values match too much by accident
+const minSecretLength = 8
+
+// credentialFields matches JSON string fields that carry credentials, like the registration token response
+var credentialFields = regexp.MustCompile(`(?i)("(?:token|access_token|refresh_token|password|secret|sha1)"\s*:\s*")[^"]*(")`)
+
+// Redact replaces every occurrence of the secret values in s
+func Redact(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if len(secret) < minSecretLength {
+			continue
+		}
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+// SanitizeBody redacts credential fields in a JSON API response body and the given secret values
+// The body doesn't have to be valid JSON, so truncated bodies are sanitized as well
+func SanitizeBody(body string, secrets ...string) string {
+	return Redact(credentialFields.ReplaceAllString(body, "${1}"+Redacted+"${2}"), secrets...)
+}
+
+// FindInPodTemplate returns the places in the pod template that contain one of the secret values
+// Env vars are only reported for literal values; secretKeyRef is the intended way to pass secrets
+func FindInPodTemplate(template *corev1.PodTemplateSpec, secrets ...string) []string {
+	var leaks []string
+	leaks = append(leaks, FindInMap("metadata.labels", template.Labels, secrets...)...)
+	leaks = append(leaks, FindInMap("metadata.annotations", template.Annotations, secrets...)...)
+
+	check := func(prefix string, containers []corev1.Container) {
+		for _, container := range containers {
+			path := fmt.Sprintf("%s[%s]", prefix, container.Name)
+			for i, arg := range container.Command {
+				if contains(arg, secrets) {
+					leaks = append(leaks, fmt.Sprintf("%s.command[%d]", path, i))
+				}
+			}
+			for i, arg := range container.Args {
+				if contains(arg, secrets) {
+					leaks = append(leaks, fmt.Sprintf("%s.args[%d]", path, i))
+				}
+			}
+			for _, env := range container.Env {
+				if contains(env.Value, secrets) {
+					leaks = append(leaks, fmt.Sprintf("%s.env[%s]", path, env.Name))
+				}
+			}
+		}
+	}
+	check("spec.initContainers", template.Spec.InitContainers)
+	check("spec.containers", template.Spec.Containers)
+	return leaks
+}
+
+// FindInMap returns the keys of labels or annotations whose key or value contains one of the secret values
+func FindInMap(prefix string, values map[string]string, secrets ...string) []string {
+	var leaks []string
+	for key, value := range values {
+		if contains(key, secrets) || contains(value, secrets) {
+			leaks = append(leaks, fmt.Sprintf("%s[%s]", prefix, key))
+		}
+	}
+	sort.Strings(leaks)
+	return leaks
+}
+
+func contains(s string, secrets []string) bool {
+	for _, secret := range secrets {
+		if len(secret) >= minSecretLength && strings.Contains(s, secret) {
+			return true
+		}
+	}
+	return false
+}
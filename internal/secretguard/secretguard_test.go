@@ -0,0 +1,77 @@
+/*
+Copyright 2025 Dan Manners.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretguard
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const token = "0123456789abcdef"
+
+var _ = Describe("Redact", func() {
+	It("replaces secret values", func() {
+		Expect(Redact("token="+token+" end", token)).To(Equal("token=[REDACTED] end"))
+	})
+
+	It("ignores short and empty values", func() {
+		Expect(Redact("abc", "", "b")).To(Equal("abc"))
+	})
+})
+
+var _ = Describe("SanitizeBody", func() {
+	It("redacts credential fields", func() {
+		Expect(SanitizeBody(`{"token": "abc", "name":"x"}`)).To(Equal(`{"token": "[REDACTED]", "name":"x"}`))
+		Expect(SanitizeBody(`[{"Password":"hunter2"`)).To(Equal(`[{"Password":"[REDACTED]"`))
+	})
+
+	It("redacts the given secrets anywhere in the body", func() {
+		Expect(SanitizeBody("invalid token "+token, token)).To(Equal("invalid token [REDACTED]"))
+	})
+})
+
+var _ = Describe("FindInPodTemplate", func() {
+	It("reports labels, annotations, args and literal env values containing a secret", func() {
+		template := &corev1.PodTemplateSpec{}
+		template.Labels = map[string]string{"token": token, "app": "runner"}
+		template.Annotations = map[string]string{"note": "uses " + token}
+		template.Spec.InitContainers = []corev1.Container{{Name: "init", Command: []string{"sh", "-c", "echo " + token}}}
+		template.Spec.Containers = []corev1.Container{{
+			Name: "runner",
+			Args: []string{"--token", token},
+			Env: []corev1.EnvVar{
+				{Name: "TOKEN", Value: token},
+				{Name: "FROM_SECRET", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{Key: "token"}}},
+			},
+		}}
+
+		Expect(FindInPodTemplate(template, token)).To(Equal([]string{
+			"metadata.labels[token]",
+			"metadata.annotations[note]",
+			"spec.initContainers[init].command[2]",
+			"spec.containers[runner].args[1]",
+			"spec.containers[runner].env[TOKEN]",
+		}))
+	})
+
+	It("reports nothing for a clean template", func() {
+		template := &corev1.PodTemplateSpec{}
+		template.Spec.Containers = []corev1.Container{{Name: "runner", Args: []string{"--verbose"}}}
+		Expect(FindInPodTemplate(template, token)).To(BeEmpty())
+	})
+})